@@ -0,0 +1,329 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/gazed/vu/math/lin"
+)
+
+// Actions maps named game actions to keys, mouse buttons, and gamepad
+// controls so that the game logic does not depend on the physical input.
+// Button actions are pressed by any of their bindings. Axis actions
+// range from -1 to 1 and combine key pairs with gamepad sticks, ie:
+//     acts := vu.NewActions()
+//     acts.Bind("jump", vu.KSpace).BindPad("jump", vu.PadA)
+//     acts.BindAxis("move-x", vu.KA, vu.KD).BindPadAxis("move-x", vu.PadLx, 1)
+//     ...
+//     acts.Update(in) // in App.Update.
+//     if acts.Pressed("jump") { ... }
+//     dx := acts.Axis("move-x")
+// Bindings can be saved and loaded so that players can remap controls.
+type Actions interface {
+	Bind(action string, keys ...int) Actions       // Add key or mouse buttons.
+	BindPad(action string, buttons ...int) Actions // Add gamepad buttons.
+
+	// BindAxis adds a key pair to an axis action where the negative
+	// key gives -1 and the positive key gives 1.
+	BindAxis(action string, neg, pos int) Actions
+
+	// BindPadAxis adds a gamepad stick or trigger to an axis action.
+	// The axis is one of PadLx, PadLy, PadRx, PadRy, PadLt, PadRt.
+	// Scale is normally 1, or -1 to invert the axis.
+	BindPadAxis(action string, axis int, scale float64) Actions
+	Clear(action string) Actions // Remove all bindings for the action.
+	SetPad(slot int) Actions     // Read a single gamepad slot. Default -1 is all.
+	UseScan(scan bool) Actions   // Match keys by location, see Input.Scan.
+	Names() []string             // Bound action names in sorted order.
+
+	// Update refreshes the action states from the user input.
+	// Expected to be called each App.Update before querying actions.
+	Update(in *Input)
+	Down(action string) int     // Down duration ticks, 0 if not pressed.
+	Pressed(action string) bool // True on the update the action is pressed.
+	Released(action string) bool
+	Axis(action string) float64 // Axis value -1 to 1.
+
+	// Rebind replaces the key or gamepad button bindings of the action
+	// with the first key, mouse button, or gamepad button newly pressed
+	// this update. Returns true if the action was rebound. Expected to be
+	// called each update while waiting for the player to pick a control.
+	Rebind(action string, in *Input) bool
+
+	Save(w io.Writer) error // Write the bindings as JSON.
+	Load(r io.Reader) error // Replace the bindings from JSON.
+}
+
+// NewActions creates an empty set of input actions.
+func NewActions() Actions { return &actions{acts: map[string]*action{}, slot: -1} }
+
+// Actions
+// =============================================================================
+// actions implements Actions.
+
+// actions implements Actions by checking the action bindings against
+// the latest input.
+type actions struct {
+	acts map[string]*action // Bindings by action name.
+	slot int                // Gamepad slot, or -1 for all slots.
+	scan bool               // True to match keys by location.
+}
+
+// action tracks the bindings and current state of one named action.
+type action struct {
+	keys  []int     // Key and mouse button codes.
+	pads  []int     // Gamepad button codes.
+	pairs [][2]int  // Negative and positive key codes.
+	axes  []padAxis // Gamepad sticks and triggers.
+	down  int       // Down duration ticks.
+	prev  int       // Down duration on the previous update.
+	value float64   // Current axis value.
+}
+
+// padAxis binds a gamepad stick or trigger to an axis action.
+type padAxis struct {
+	axis  int     // Index into the Pad stick and trigger values.
+	scale float64 // Axis multiplier.
+}
+
+// get returns the named action, creating it if necessary.
+func (a *actions) get(name string) *action {
+	act, ok := a.acts[name]
+	if !ok {
+		act = &action{}
+		a.acts[name] = act
+	}
+	return act
+}
+
+// Implement Actions.
+func (a *actions) Bind(name string, keys ...int) Actions {
+	act := a.get(name)
+	act.keys = append(act.keys, keys...)
+	return a
+}
+func (a *actions) BindPad(name string, buttons ...int) Actions {
+	act := a.get(name)
+	act.pads = append(act.pads, buttons...)
+	return a
+}
+func (a *actions) BindAxis(name string, neg, pos int) Actions {
+	act := a.get(name)
+	act.pairs = append(act.pairs, [2]int{neg, pos})
+	return a
+}
+func (a *actions) BindPadAxis(name string, axis int, scale float64) Actions {
+	if axis >= PadLx && axis <= PadRt {
+		act := a.get(name)
+		act.axes = append(act.axes, padAxis{axis: axis, scale: scale})
+	}
+	return a
+}
+func (a *actions) Clear(name string) Actions { delete(a.acts, name); return a }
+func (a *actions) SetPad(slot int) Actions   { a.slot = slot; return a }
+func (a *actions) UseScan(scan bool) Actions { a.scan = scan; return a }
+func (a *actions) Names() []string {
+	names := make([]string, 0, len(a.acts))
+	for name := range a.acts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Update checks each action against the current input. An action is
+// down for as long as its longest held binding.
+func (a *actions) Update(in *Input) {
+	down := a.keys(in)
+	for _, act := range a.acts {
+		act.prev, act.down, act.value = act.down, 0, 0
+		for _, key := range act.keys {
+			act.down = held(act.down, down[key])
+		}
+		for _, pair := range act.pairs {
+			if down[pair[0]] > 0 {
+				act.value--
+			}
+			if down[pair[1]] > 0 {
+				act.value++
+			}
+		}
+		for cnt := range in.Pads {
+			pad := &in.Pads[cnt]
+			if !pad.Connected || (a.slot >= 0 && a.slot != cnt) {
+				continue
+			}
+			for _, button := range act.pads {
+				act.down = held(act.down, pad.Down[button])
+			}
+			for _, pa := range act.axes {
+				act.value += pa.scale * pad.axis(pa.axis)
+			}
+		}
+		act.value = lin.Clamp(act.value, -1, 1)
+	}
+}
+
+// keys returns the key down durations by label or by location.
+func (a *actions) keys(in *Input) map[int]int {
+	if a.scan {
+		return in.Scan
+	}
+	return in.Down
+}
+
+// held returns the longest down duration ignoring released keys.
+func held(down, duration int) int {
+	if duration > down {
+		return duration
+	}
+	return down
+}
+
+// Implement Actions.
+func (a *actions) Down(name string) int {
+	if act, ok := a.acts[name]; ok {
+		return act.down
+	}
+	return 0
+}
+func (a *actions) Pressed(name string) bool {
+	act, ok := a.acts[name]
+	return ok && act.down > 0 && act.prev == 0
+}
+func (a *actions) Released(name string) bool {
+	act, ok := a.acts[name]
+	return ok && act.down == 0 && act.prev > 0
+}
+func (a *actions) Axis(name string) float64 {
+	if act, ok := a.acts[name]; ok {
+		return act.value
+	}
+	return 0
+}
+
+// Rebind looks for a key, mouse button, or gamepad button that was
+// pressed this update, ie: has a down duration of 1 tick.
+func (a *actions) Rebind(name string, in *Input) bool {
+	for key, down := range a.keys(in) {
+		if down == 1 {
+			act := a.get(name)
+			act.keys = append(act.keys[:0], key)
+			return true
+		}
+	}
+	for cnt := range in.Pads {
+		if a.slot >= 0 && a.slot != cnt {
+			continue
+		}
+		for button, down := range in.Pads[cnt].Down {
+			if down == 1 {
+				act := a.get(name)
+				act.pads = append(act.pads[:0], button)
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Save writes the bindings as JSON. Keys are saved using their Keysym
+// runes since the key codes differ between platforms.
+func (a *actions) Save(w io.Writer) error {
+	file := map[string]*actionFile{}
+	for name, act := range a.acts {
+		af := &actionFile{Pads: act.pads}
+		for _, key := range act.keys {
+			if Keysym(key) == 0 {
+				return fmt.Errorf("Actions.Save: no symbol for key %d in %s", key, name)
+			}
+			af.Keys = append(af.Keys, string(Keysym(key)))
+		}
+		for _, pair := range act.pairs {
+			if Keysym(pair[0]) == 0 || Keysym(pair[1]) == 0 {
+				return fmt.Errorf("Actions.Save: no symbol for keys %v in %s", pair, name)
+			}
+			af.Pairs = append(af.Pairs, [2]string{string(Keysym(pair[0])), string(Keysym(pair[1]))})
+		}
+		for _, pa := range act.axes {
+			af.Axes = append(af.Axes, actionAxis{Axis: pa.axis, Scale: pa.scale})
+		}
+		file[name] = af
+	}
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Actions.Save: %s", err)
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// Load replaces the current bindings with those read from JSON.
+// The existing bindings are kept if there is an error.
+func (a *actions) Load(r io.Reader) error {
+	file := map[string]*actionFile{}
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return fmt.Errorf("Actions.Load: %s", err)
+	}
+	acts := map[string]*action{}
+	for name, af := range file {
+		act := &action{pads: af.Pads}
+		for _, sym := range af.Keys {
+			key, ok := keycode(sym)
+			if !ok {
+				return fmt.Errorf("Actions.Load: unknown key %q for %s", sym, name)
+			}
+			act.keys = append(act.keys, key)
+		}
+		for _, pair := range af.Pairs {
+			neg, nok := keycode(pair[0])
+			pos, pok := keycode(pair[1])
+			if !nok || !pok {
+				return fmt.Errorf("Actions.Load: unknown key pair %q for %s", pair, name)
+			}
+			act.pairs = append(act.pairs, [2]int{neg, pos})
+		}
+		for _, ax := range af.Axes {
+			if ax.Axis < PadLx || ax.Axis > PadRt {
+				return fmt.Errorf("Actions.Load: unknown axis %d for %s", ax.Axis, name)
+			}
+			act.axes = append(act.axes, padAxis{axis: ax.Axis, scale: ax.Scale})
+		}
+		acts[name] = act
+	}
+	a.acts = acts
+	return nil
+}
+
+// keycode returns the key code for the given Keysym rune.
+func keycode(sym string) (code int, ok bool) {
+	runes := []rune(sym)
+	if len(runes) != 1 {
+		return 0, false
+	}
+	for code, symbol := range keysym {
+		if rune(symbol) == runes[0] {
+			return code, true
+		}
+	}
+	return 0, false
+}
+
+// actionFile is the saved form of one action's bindings.
+type actionFile struct {
+	Keys  []string     `json:",omitempty"` // Key and mouse button symbols.
+	Pads  []int        `json:",omitempty"` // Gamepad buttons.
+	Pairs [][2]string  `json:",omitempty"` // Negative, positive key symbols.
+	Axes  []actionAxis `json:",omitempty"` // Gamepad sticks and triggers.
+}
+
+// actionAxis is the saved form of a gamepad axis binding.
+type actionAxis struct {
+	Axis  int
+	Scale float64
+}
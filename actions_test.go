@@ -0,0 +1,107 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestActions(t *testing.T) {
+	acts := NewActions()
+	acts.Bind("jump", KSpace).BindPad("jump", PadA)
+	acts.BindAxis("move-x", KA, KD).BindPadAxis("move-x", PadLx, 1)
+	in := &Input{Down: map[int]int{}, Pads: []Pad{{Down: map[int]int{}}, {Down: map[int]int{}}}}
+
+	// pressed on the first update only, then released.
+	in.Down[KSpace] = 1
+	if acts.Update(in); !acts.Pressed("jump") || acts.Down("jump") != 1 {
+		t.Errorf("expected jump pressed")
+	}
+	in.Down[KSpace] = 2
+	if acts.Update(in); acts.Pressed("jump") || acts.Down("jump") != 2 {
+		t.Errorf("expected jump held")
+	}
+	in.Down[KSpace] = 2 + KeyReleased
+	if acts.Update(in); !acts.Released("jump") || acts.Down("jump") != 0 {
+		t.Errorf("expected jump released")
+	}
+
+	// gamepad buttons and axes only count for connected pads.
+	delete(in.Down, KSpace)
+	in.Pads[1].Down[PadA], in.Pads[1].Lx = 1, -0.5
+	if acts.Update(in); acts.Down("jump") != 0 || acts.Axis("move-x") != 0 {
+		t.Errorf("expected disconnected pad to be ignored")
+	}
+	in.Pads[1].Connected = true
+	if acts.Update(in); !acts.Pressed("jump") || acts.Axis("move-x") != -0.5 {
+		t.Errorf("expected pad jump and axis %f", acts.Axis("move-x"))
+	}
+	if acts.SetPad(0).Update(in); acts.Down("jump") != 0 {
+		t.Errorf("expected pad slot 1 to be ignored")
+	}
+	acts.SetPad(-1)
+	in.Down[KD] = 4
+	if acts.Update(in); acts.Axis("move-x") != 0.5 {
+		t.Errorf("expected combined axis, got %f", acts.Axis("move-x"))
+	}
+	in.Pads[1].Lx = 1
+	if acts.Update(in); acts.Axis("move-x") != 1 {
+		t.Errorf("expected clamped axis, got %f", acts.Axis("move-x"))
+	}
+}
+
+func TestRebindActions(t *testing.T) {
+	acts := NewActions().Bind("fire", KLm, KF)
+	in := &Input{Down: map[int]int{KLm: 5}}
+	if acts.Rebind("fire", in) {
+		t.Errorf("expected held keys to be ignored")
+	}
+	in.Down[KG] = 1
+	if !acts.Rebind("fire", in) {
+		t.Fatalf("expected rebind")
+	}
+	if acts.Update(in); acts.Down("fire") != 1 {
+		t.Errorf("expected fire only bound to G")
+	}
+
+	// saved bindings restore the same actions.
+	acts.BindAxis("move-x", KLa, KRa).BindPadAxis("move-x", PadRx, -1).BindPad("fire", PadRb)
+	buf := &bytes.Buffer{}
+	if err := acts.Save(buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded := NewActions().Bind("old", KO)
+	if err := loaded.Load(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	if names := loaded.Names(); len(names) != 2 || names[0] != "fire" || names[1] != "move-x" {
+		t.Errorf("expected loaded names, got %v", names)
+	}
+	in = &Input{Down: map[int]int{KLa: 1}, Pads: []Pad{{Connected: true, Down: map[int]int{PadRb: 1}, Rx: 0.25}}}
+	if loaded.Update(in); loaded.Down("fire") != 1 || loaded.Axis("move-x") != -1 {
+		t.Errorf("expected loaded bindings %d %f", loaded.Down("fire"), loaded.Axis("move-x"))
+	}
+	if err := loaded.Load(bytes.NewBufferString(`{"bad":{"Keys":["no"]}}`)); err == nil {
+		t.Errorf("expected unknown key error")
+	}
+}
+
+// Check that scan actions match keys by location, ie: the key
+// labelled Z on an AZERTY keyboard is in the W location.
+func TestScanActions(t *testing.T) {
+	in := &Input{Down: map[int]int{KZ: 1}, Scan: map[int]int{KW: 1}}
+	acts := NewActions().Bind("forward", KW)
+	if acts.Update(in); acts.Pressed("forward") {
+		t.Errorf("Expected labelled keys by default")
+	}
+	if acts.UseScan(true).Update(in); !acts.Pressed("forward") {
+		t.Errorf("Expected key location to press action")
+	}
+	eng := newEngine(nil)
+	defer eng.Shutdown()
+	if name := eng.KeyName(KW); name != "W" {
+		t.Errorf("Expected key symbol without a device, got %q", name)
+	}
+}
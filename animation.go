@@ -25,6 +25,8 @@ type animation struct {
 	jointCnt int        // number of joints.
 	frames   []lin.M4   // nFrames*nPoses transform bone positions.
 	joints   []int32    // joint parent indicies.
+	jnames   []string   // joint names for attachment points.
+	bases    []lin.M4   // joint base pose model transforms.
 	moves    []movement // frames where animations start and end.
 	mnames   []string   // movement names for easy reference.
 	loaded   bool       // True if data has been set.
@@ -70,6 +72,27 @@ func (a *animation) setData(frames []*lin.M4, joints []int32, movements []moveme
 	a.loaded = true
 }
 
+// setJoints sets the joint names and base pose transforms used
+// to attach other objects to the animated joints.
+func (a *animation) setJoints(names []string, bases []*lin.M4) {
+	a.jnames = append(a.jnames[:0], names...)
+	a.bases = make([]lin.M4, len(bases))
+	for cnt, base := range bases {
+		a.bases[cnt].Set(base)
+	}
+}
+
+// joint returns the index of the named joint, or -1 if there
+// is no such joint.
+func (a *animation) joint(name string) int {
+	for cnt, jname := range a.jnames {
+		if jname == name {
+			return cnt
+		}
+	}
+	return -1
+}
+
 // setRate changes the number of frames per second for the given
 // animation movement.
 //    movement: the affected animation movement, indexed from 0 up.
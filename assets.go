@@ -0,0 +1,132 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"sync"
+
+	"github.com/gazed/vu/render"
+)
+
+// Memory assets are meshes and textures created from application data,
+// ie: procedurally generated or downloaded content. Memory assets are
+// used by name like asset files, but are found before any asset files:
+//     eng.AddMesh("tri", &vu.MeshData{V: v, N: n, F: []uint16{0, 1, 2}})
+//     eng.AddTexture("noise", img)
+//     p.NewModel("uv").LoadMesh("tri").AddTex("noise")
+// Loaded assets are cached and shared, so adding an asset with the name
+// of a loaded asset does not change the models that already use it.
+// Use Model.SetMeshData or Model.SetImg to change a single model.
+
+// MeshData is the vertex and face data for a memory mesh. Vertex
+// data follows the Model.InitMesh conventions where V and F are
+// required and the other vertex data is optional.
+type MeshData struct {
+	V []float32   // Vertex positions.    Arranged as [][3]float32
+	N []float32   // Vertex normals.      Arranged as [][3]float32
+	T []float32   // Texture coordinates. Arranged as [][2]float32
+	C []float32   // Vertex colors.       Arranged as [][4]float32
+	F interface{} // Triangle faces: []uint16, or []uint32 for large meshes.
+}
+
+// Implement Eng interface.
+func (eng *engine) AddMesh(name string, md *MeshData) error {
+	verts := len(md.V) / 3
+	switch {
+	case verts == 0 || len(md.V)%3 != 0:
+		return fmt.Errorf("AddMesh %s: need vertex positions", name)
+	case len(md.N) > 0 && len(md.N) != verts*3:
+		return fmt.Errorf("AddMesh %s: need a normal for each vertex", name)
+	case len(md.T) > 0 && len(md.T) != verts*2:
+		return fmt.Errorf("AddMesh %s: need tex coords for each vertex", name)
+	case len(md.C) > 0 && len(md.C) != verts*4:
+		return fmt.Errorf("AddMesh %s: need a color for each vertex", name)
+	}
+	switch f := md.F.(type) {
+	case []uint16:
+		if len(f) == 0 || len(f)%3 != 0 {
+			return fmt.Errorf("AddMesh %s: need triangle faces", name)
+		}
+	case []uint32:
+		if len(f) == 0 || len(f)%3 != 0 {
+			return fmt.Errorf("AddMesh %s: need triangle faces", name)
+		}
+	default:
+		return fmt.Errorf("AddMesh %s: invalid faces %T", name, md.F)
+	}
+	eng.loader.mem.addMesh(name, md)
+	return nil
+}
+func (eng *engine) AddTexture(name string, img image.Image) {
+	eng.loader.mem.addImg(name, img)
+}
+func (eng *engine) AddTextureData(name string, data []byte) error {
+	img, _, err := image.Decode(bytes.NewReader(data)) // png or jpeg.
+	if err != nil {
+		return fmt.Errorf("AddTextureData %s: %s", name, err)
+	}
+	eng.loader.mem.addImg(name, img)
+	return nil
+}
+
+// memory assets
+// =============================================================================
+// memAssets is shared by the engine and loader goroutines.
+
+// memAssets holds the application created assets until they are loaded.
+type memAssets struct {
+	lock   sync.Mutex
+	meshes map[string]*MeshData   // Meshes by name.
+	imgs   map[string]image.Image // Texture images by name.
+}
+
+// newMemAssets creates an empty memory asset store.
+func newMemAssets() *memAssets {
+	return &memAssets{meshes: map[string]*MeshData{}, imgs: map[string]image.Image{}}
+}
+
+// addMesh and addImg are called from the engine goroutine.
+func (ma *memAssets) addMesh(name string, md *MeshData) {
+	ma.lock.Lock()
+	defer ma.lock.Unlock()
+	ma.meshes[name] = md
+}
+func (ma *memAssets) addImg(name string, img image.Image) {
+	ma.lock.Lock()
+	defer ma.lock.Unlock()
+	ma.imgs[name] = img
+}
+
+// mesh sets the mesh data from a memory mesh, returning
+// false if there is no such mesh. Called from the loader goroutine.
+func (ma *memAssets) mesh(m *mesh) bool {
+	ma.lock.Lock()
+	md, ok := ma.meshes[m.name]
+	ma.lock.Unlock()
+	if ok {
+		m.initData(0, 3, render.StaticDraw, false).setData(0, md.V)
+		if len(md.N) > 0 {
+			m.initData(1, 3, render.StaticDraw, false).setData(1, md.N)
+		}
+		if len(md.T) > 0 {
+			m.initData(2, 2, render.StaticDraw, false).setData(2, md.T)
+		}
+		if len(md.C) > 0 {
+			m.initData(3, 4, render.StaticDraw, false).setData(3, md.C)
+		}
+		m.initFaces(render.StaticDraw).setFaces(md.F)
+	}
+	return ok
+}
+
+// img returns the named memory texture image, or nil if there is
+// no such image. Called from the loader goroutine.
+func (ma *memAssets) img(name string) image.Image {
+	ma.lock.Lock()
+	defer ma.lock.Unlock()
+	return ma.imgs[name]
+}
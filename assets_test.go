@@ -0,0 +1,46 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"testing"
+)
+
+// Check that memory assets are used instead of asset files.
+func TestMemoryAssets(t *testing.T) {
+	eng := newEngine(nil)
+	defer eng.Shutdown()
+	if err := eng.AddMesh("bad", &MeshData{V: []float32{0, 0, 0}, N: []float32{0}, F: []uint16{0, 0, 0}}); err == nil {
+		t.Errorf("Expected error for missing normals")
+	}
+	if err := eng.AddMesh("bad", &MeshData{V: []float32{0, 0, 0}, F: []int{0, 0, 0}}); err == nil {
+		t.Errorf("Expected error for invalid faces")
+	}
+	tri := &MeshData{V: []float32{0, 0, 0, 1, 0, 0, 0, 1, 0}, T: []float32{0, 0, 1, 0, 0, 1}, F: []uint32{0, 1, 2}}
+	if err := eng.AddMesh("tri", tri); err != nil {
+		t.Fatal(err)
+	}
+	m := newMesh("tri")
+	if err := eng.loader.importMesh(m); err != nil || !m.loaded || len(m.vdata) != 2 || m.vdata[2] == nil {
+		t.Errorf("Expected memory mesh, got %s %d", err, len(m.vdata))
+	}
+
+	// textures can be images or encoded image data.
+	eng.AddTexture("gen", image.NewNRGBA(image.Rect(0, 0, 2, 2)))
+	pic := &bytes.Buffer{}
+	png.Encode(pic, image.NewNRGBA(image.Rect(0, 0, 4, 4)))
+	if err := eng.AddTextureData("pic", pic.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if err := eng.AddTextureData("bad", []byte("png")); err == nil {
+		t.Errorf("Expected error for invalid image data")
+	}
+	gen, pt := newTexture("gen"), newTexture("pic")
+	if eng.loader.importTexture(gen) != nil || eng.loader.importTexture(pt) != nil || pt.img.Bounds().Dx() != 4 || !gen.loaded {
+		t.Errorf("Expected memory textures")
+	}
+}
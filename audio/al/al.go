@@ -13,6 +13,9 @@ package al
 // These bindings were based on the OpenAL header files found at:
 //   http://repo.or.cz/w/openal-soft.git/blob/6dab9d54d1719105e0183f941a2b3dd36e9ba902:/include/AL/al.h
 //   http://repo.or.cz/w/openal-soft.git/blob/6dab9d54d1719105e0183f941a2b3dd36e9ba902:/include/AL/alc.h
+//   http://repo.or.cz/w/openal-soft.git/blob/6dab9d54d1719105e0183f941a2b3dd36e9ba902:/include/AL/efx.h
+// The efx.h effects extension functions are not available on all
+// platforms, ie: OSX. Check EFX before using them.
 // Check information available at openal.org.
 
 // #cgo darwin  LDFLAGS: -framework OpenAL
@@ -246,6 +249,40 @@ package al
 // AL_API void          AL_APIENTRY wrap_alSpeedOfSound( float value ) { (*pfn_alSpeedOfSound)( value ); }
 // AL_API void          AL_APIENTRY wrap_alDistanceModel( int distanceModel ) { (*pfn_alDistanceModel)( distanceModel ); }
 //
+// // AL/efx.h pointers to extension functions bound to the OS specific library.
+// void           (AL_APIENTRY *pfn_alGenEffects)( ALsizei n, ALuint* effects );
+// void           (AL_APIENTRY *pfn_alDeleteEffects)( ALsizei n, const ALuint* effects );
+// void           (AL_APIENTRY *pfn_alEffecti)( ALuint eid, ALenum param, ALint value );
+// void           (AL_APIENTRY *pfn_alEffectf)( ALuint eid, ALenum param, ALfloat value );
+// void           (AL_APIENTRY *pfn_alGenFilters)( ALsizei n, ALuint* filters );
+// void           (AL_APIENTRY *pfn_alDeleteFilters)( ALsizei n, const ALuint* filters );
+// void           (AL_APIENTRY *pfn_alFilteri)( ALuint fid, ALenum param, ALint value );
+// void           (AL_APIENTRY *pfn_alFilterf)( ALuint fid, ALenum param, ALfloat value );
+// void           (AL_APIENTRY *pfn_alGenAuxiliaryEffectSlots)( ALsizei n, ALuint* slots );
+// void           (AL_APIENTRY *pfn_alDeleteAuxiliaryEffectSlots)( ALsizei n, const ALuint* slots );
+// void           (AL_APIENTRY *pfn_alAuxiliaryEffectSloti)( ALuint slot, ALenum param, ALint value );
+// void           (AL_APIENTRY *pfn_alAuxiliaryEffectSlotf)( ALuint slot, ALenum param, ALfloat value );
+//
+// // AL/alext.h ALC_SOFT_reopen_device pointer bound to the OS specific library.
+// ALCboolean     (ALC_APIENTRY *pfn_alcReopenDeviceSOFT)( ALCdevice *device, const ALCchar *deviceName, const ALCint *attribs );
+//
+// // AL/efx.h wrappers for the go bindings.
+// AL_API void          AL_APIENTRY wrap_alGenEffects( int n, unsigned int* effects ) { (*pfn_alGenEffects)( n, effects ); }
+// AL_API void          AL_APIENTRY wrap_alDeleteEffects( int n, const unsigned int* effects ) { (*pfn_alDeleteEffects)( n, effects ); }
+// AL_API void          AL_APIENTRY wrap_alEffecti( unsigned int eid, int param, int value ) { (*pfn_alEffecti)( eid, param, value ); }
+// AL_API void          AL_APIENTRY wrap_alEffectf( unsigned int eid, int param, float value ) { (*pfn_alEffectf)( eid, param, value ); }
+// AL_API void          AL_APIENTRY wrap_alGenFilters( int n, unsigned int* filters ) { (*pfn_alGenFilters)( n, filters ); }
+// AL_API void          AL_APIENTRY wrap_alDeleteFilters( int n, const unsigned int* filters ) { (*pfn_alDeleteFilters)( n, filters ); }
+// AL_API void          AL_APIENTRY wrap_alFilteri( unsigned int fid, int param, int value ) { (*pfn_alFilteri)( fid, param, value ); }
+// AL_API void          AL_APIENTRY wrap_alFilterf( unsigned int fid, int param, float value ) { (*pfn_alFilterf)( fid, param, value ); }
+// AL_API void          AL_APIENTRY wrap_alGenAuxiliaryEffectSlots( int n, unsigned int* slots ) { (*pfn_alGenAuxiliaryEffectSlots)( n, slots ); }
+// AL_API void          AL_APIENTRY wrap_alDeleteAuxiliaryEffectSlots( int n, const unsigned int* slots ) { (*pfn_alDeleteAuxiliaryEffectSlots)( n, slots ); }
+// AL_API void          AL_APIENTRY wrap_alAuxiliaryEffectSloti( unsigned int slot, int param, int value ) { (*pfn_alAuxiliaryEffectSloti)( slot, param, value ); }
+// AL_API void          AL_APIENTRY wrap_alAuxiliaryEffectSlotf( unsigned int slot, int param, float value ) { (*pfn_alAuxiliaryEffectSlotf)( slot, param, value ); }
+//
+// // AL/alext.h wrapper for the go bindings.
+// ALC_API ALCboolean   ALC_APIENTRY wrap_alcReopenDeviceSOFT( uintptr_t device, const char *deviceName, const int *attribs ) { return (*pfn_alcReopenDeviceSOFT)( (ALCdevice *)device, deviceName, attribs ); }
+//
 // // AL/alc.h pointers to functions bound to the OS specific library.
 // ALCcontext *   (ALC_APIENTRY *pfn_alcCreateContext) (ALCdevice *device, const ALCint *attrlist);
 // ALCboolean     (ALC_APIENTRY *pfn_alcMakeContextCurrent)( ALCcontext *context );
@@ -387,6 +424,23 @@ package al
 //    pfn_alcCaptureStart           = bindMethod("alcCaptureStart");
 //    pfn_alcCaptureStop            = bindMethod("alcCaptureStop");
 //    pfn_alcCaptureSamples         = bindMethod("alcCaptureSamples");
+//
+//    // AL/efx.h
+//    pfn_alGenEffects                  = bindMethod("alGenEffects");
+//    pfn_alDeleteEffects               = bindMethod("alDeleteEffects");
+//    pfn_alEffecti                     = bindMethod("alEffecti");
+//    pfn_alEffectf                     = bindMethod("alEffectf");
+//    pfn_alGenFilters                  = bindMethod("alGenFilters");
+//    pfn_alDeleteFilters               = bindMethod("alDeleteFilters");
+//    pfn_alFilteri                     = bindMethod("alFilteri");
+//    pfn_alFilterf                     = bindMethod("alFilterf");
+//    pfn_alGenAuxiliaryEffectSlots     = bindMethod("alGenAuxiliaryEffectSlots");
+//    pfn_alDeleteAuxiliaryEffectSlots  = bindMethod("alDeleteAuxiliaryEffectSlots");
+//    pfn_alAuxiliaryEffectSloti        = bindMethod("alAuxiliaryEffectSloti");
+//    pfn_alAuxiliaryEffectSlotf        = bindMethod("alAuxiliaryEffectSlotf");
+//
+//    // AL/alext.h
+//    pfn_alcReopenDeviceSOFT           = bindMethod("alcReopenDeviceSOFT");
 // }
 //
 import "C"
@@ -487,6 +541,45 @@ const (
 	C_CAPTURE_DEVICE_SPECIFIER         = 0x310
 	C_CAPTURE_DEFAULT_DEVICE_SPECIFIER = 0x311
 	C_CAPTURE_SAMPLES                  = 0x312
+	C_CONNECTED                        = 0x313  // ALC_EXT_disconnect
+	C_DEFAULT_ALL_DEVICES_SPECIFIER    = 0x1012 // ALC_ENUMERATE_ALL_EXT
+	C_ALL_DEVICES_SPECIFIER            = 0x1013 // ALC_ENUMERATE_ALL_EXT
+)
+
+// AL/efx.h constants (with AL_ removed). Refer to the original header for constant documentation.
+const (
+	DIRECT_FILTER                = 0x20005
+	AUXILIARY_SEND_FILTER        = 0x20006
+	EFFECTSLOT_EFFECT            = 0x0001
+	EFFECTSLOT_GAIN              = 0x0002
+	EFFECTSLOT_AUXILIARY_SEND    = 0x0003
+	EFFECT_TYPE                  = 0x8001
+	EFFECT_NULL                  = 0x0000
+	EFFECT_REVERB                = 0x0001
+	EFFECT_ECHO                  = 0x0004
+	FILTER_TYPE                  = 0x8001
+	FILTER_NULL                  = 0x0000
+	FILTER_LOWPASS               = 0x0001
+	LOWPASS_GAIN                 = 0x0001
+	LOWPASS_GAINHF               = 0x0002
+	REVERB_DENSITY               = 0x0001
+	REVERB_DIFFUSION             = 0x0002
+	REVERB_GAIN                  = 0x0003
+	REVERB_GAINHF                = 0x0004
+	REVERB_DECAY_TIME            = 0x0005
+	REVERB_DECAY_HFRATIO         = 0x0006
+	REVERB_REFLECTIONS_GAIN      = 0x0007
+	REVERB_REFLECTIONS_DELAY     = 0x0008
+	REVERB_LATE_REVERB_GAIN      = 0x0009
+	REVERB_LATE_REVERB_DELAY     = 0x000A
+	REVERB_AIR_ABSORPTION_GAINHF = 0x000B
+	REVERB_ROOM_ROLLOFF_FACTOR   = 0x000C
+	REVERB_DECAY_HFLIMIT         = 0x000D
+	ECHO_DELAY                   = 0x0001
+	ECHO_LRDELAY                 = 0x0002
+	ECHO_DAMPING                 = 0x0003
+	ECHO_FEEDBACK                = 0x0004
+	ECHO_SPREAD                  = 0x0005
 )
 
 // bind the methods to the function pointers
@@ -545,8 +638,10 @@ func Listeneri(param int32, value int32)      { C.wrap_alListeneri(C.int(param),
 func Listener3i(param int32, value1, value2, value3 int32) {
 	C.wrap_alListener3i(C.int(param), C.int(value1), C.int(value2), C.int(value3))
 }
-func Listeneriv(param int32, values *int32)    { C.wrap_alListeneriv(C.int(param), (*C.int)(values)) }
-func GetListenerf(param int32, value *float32) { C.wrap_alGetListenerf(C.int(param), (*C.float)(value)) }
+func Listeneriv(param int32, values *int32) { C.wrap_alListeneriv(C.int(param), (*C.int)(values)) }
+func GetListenerf(param int32, value *float32) {
+	C.wrap_alGetListenerf(C.int(param), (*C.float)(value))
+}
 func GetListener3f(param int32, value1, value2, value3 *float32) {
 	C.wrap_alGetListener3f(C.int(param), (*C.float)(value1), (*C.float)(value2), (*C.float)(value3))
 }
@@ -557,10 +652,12 @@ func GetListeneri(param int32, value *int32) { C.wrap_alGetListeneri(C.int(param
 func GetListener3i(param int32, value1, value2, value3 *int32) {
 	C.wrap_alGetListener3i(C.int(param), (*C.int)(value1), (*C.int)(value2), (*C.int)(value3))
 }
-func GetListeneriv(param int32, values *int32) { C.wrap_alGetListeneriv(C.int(param), (*C.int)(values)) }
-func GenSources(n int32, sources *uint32)      { C.wrap_alGenSources(C.int(n), (*C.uint)(sources)) }
-func DeleteSources(n int32, sources *uint32)   { C.wrap_alDeleteSources(C.int(n), (*C.uint)(sources)) }
-func IsSource(sid uint32) bool                 { return cbool(uint(C.wrap_alIsSource(C.uint(sid)))) }
+func GetListeneriv(param int32, values *int32) {
+	C.wrap_alGetListeneriv(C.int(param), (*C.int)(values))
+}
+func GenSources(n int32, sources *uint32)    { C.wrap_alGenSources(C.int(n), (*C.uint)(sources)) }
+func DeleteSources(n int32, sources *uint32) { C.wrap_alDeleteSources(C.int(n), (*C.uint)(sources)) }
+func IsSource(sid uint32) bool               { return cbool(uint(C.wrap_alIsSource(C.uint(sid)))) }
 func Sourcef(sid uint32, param int32, value float32) {
 	C.wrap_alSourcef(C.uint(sid), C.int(param), C.float(value))
 }
@@ -679,7 +776,7 @@ func GetContextsDevice(context Context) Device {
 	return (Device)(C.wrap_alcGetContextsDevice((C.uintptr_t)(context)))
 }
 func OpenDevice(devicename string) Device {
-	if devicename != "" {
+	if devicename == "" {
 		return (Device)(C.wrap_alcOpenDevice(nil))
 	}
 	cstr := C.CString(devicename)
@@ -713,6 +810,37 @@ func GetDeviceString(device Device, param int32) string {
 func GetDeviceIntegerv(device Device, param int32, size int32, data *int32) {
 	C.wrap_alcGetIntegerv((C.uintptr_t)(device), C.int(param), C.int(size), (*C.int)(data))
 }
+
+// GetDeviceStrings returns the strings of a list that is separated by
+// nulls and ends with two nulls, ie: for C_ALL_DEVICES_SPECIFIER.
+func GetDeviceStrings(device Device, param int32) (list []string) {
+	cstr := unsafe.Pointer(C.wrap_alcGetString((C.uintptr_t)(device), C.int(param)))
+	for cstr != nil && *(*byte)(cstr) != 0 {
+		str := C.GoString((*C.char)(cstr))
+		list = append(list, str)
+		cstr = unsafe.Pointer(uintptr(cstr) + uintptr(len(str)+1))
+	}
+	return list
+}
+
+// ReopenDevice moves the device, and its contexts, to the named
+// device or to the default device for "". Returns false if the
+// device can't be reopened, or if ALC_SOFT_reopen_device isn't
+// supported. See ReopenDeviceBound.
+func ReopenDevice(device Device, devicename string) bool {
+	if C.pfn_alcReopenDeviceSOFT == nil {
+		return false
+	}
+	if devicename == "" {
+		return cbool(uint(C.wrap_alcReopenDeviceSOFT((C.uintptr_t)(device), nil, nil)))
+	}
+	cstr := C.CString(devicename)
+	defer C.free(unsafe.Pointer(cstr))
+	return cbool(uint(C.wrap_alcReopenDeviceSOFT((C.uintptr_t)(device), cstr, nil)))
+}
+
+// ReopenDeviceBound returns true if ReopenDevice is supported.
+func ReopenDeviceBound() bool { return C.pfn_alcReopenDeviceSOFT != nil }
 func CaptureOpenDevice(devicename string, frequency uint32, format int32, buffersize int32) Device {
 	cstr := C.CString(devicename)
 	defer C.free(unsafe.Pointer(cstr))
@@ -727,6 +855,47 @@ func CaptureSamples(device Device, buffer Pointer, samples int) {
 	C.wrap_alcCaptureSamples((C.uintptr_t)(device), unsafe.Pointer(buffer), C.int(samples))
 }
 
+// AL/efx.h go bindings
+func GenEffects(n int32, effects *uint32)    { C.wrap_alGenEffects(C.int(n), (*C.uint)(effects)) }
+func DeleteEffects(n int32, effects *uint32) { C.wrap_alDeleteEffects(C.int(n), (*C.uint)(effects)) }
+func Effecti(eid uint32, param int32, value int32) {
+	C.wrap_alEffecti(C.uint(eid), C.int(param), C.int(value))
+}
+func Effectf(eid uint32, param int32, value float32) {
+	C.wrap_alEffectf(C.uint(eid), C.int(param), C.float(value))
+}
+func GenFilters(n int32, filters *uint32)    { C.wrap_alGenFilters(C.int(n), (*C.uint)(filters)) }
+func DeleteFilters(n int32, filters *uint32) { C.wrap_alDeleteFilters(C.int(n), (*C.uint)(filters)) }
+func Filteri(fid uint32, param int32, value int32) {
+	C.wrap_alFilteri(C.uint(fid), C.int(param), C.int(value))
+}
+func Filterf(fid uint32, param int32, value float32) {
+	C.wrap_alFilterf(C.uint(fid), C.int(param), C.float(value))
+}
+func GenAuxiliaryEffectSlots(n int32, slots *uint32) {
+	C.wrap_alGenAuxiliaryEffectSlots(C.int(n), (*C.uint)(slots))
+}
+func DeleteAuxiliaryEffectSlots(n int32, slots *uint32) {
+	C.wrap_alDeleteAuxiliaryEffectSlots(C.int(n), (*C.uint)(slots))
+}
+func AuxiliaryEffectSloti(slot uint32, param int32, value int32) {
+	C.wrap_alAuxiliaryEffectSloti(C.uint(slot), C.int(param), C.int(value))
+}
+func AuxiliaryEffectSlotf(slot uint32, param int32, value float32) {
+	C.wrap_alAuxiliaryEffectSlotf(C.uint(slot), C.int(param), C.float(value))
+}
+
+// EFX returns true if all the effects extension functions are bound.
+// The effects functions must not be called when EFX is false.
+func EFX() bool {
+	return C.pfn_alGenEffects != nil && C.pfn_alDeleteEffects != nil &&
+		C.pfn_alEffecti != nil && C.pfn_alEffectf != nil &&
+		C.pfn_alGenFilters != nil && C.pfn_alDeleteFilters != nil &&
+		C.pfn_alFilteri != nil && C.pfn_alFilterf != nil &&
+		C.pfn_alGenAuxiliaryEffectSlots != nil && C.pfn_alDeleteAuxiliaryEffectSlots != nil &&
+		C.pfn_alAuxiliaryEffectSloti != nil && C.pfn_alAuxiliaryEffectSlotf != nil
+}
+
 // Show which function pointers are bound [+] or not bound [-].
 // Expected to be used as a sanity check to see if the OpenAL libraries exist.
 func BindingReport() (report []string) {
@@ -830,6 +999,25 @@ func BindingReport() (report []string) {
 	report = append(report, isBound(unsafe.Pointer(C.pfn_alcCaptureStart), "alcCaptureStart"))
 	report = append(report, isBound(unsafe.Pointer(C.pfn_alcCaptureStop), "alcCaptureStop"))
 	report = append(report, isBound(unsafe.Pointer(C.pfn_alcCaptureSamples), "alcCaptureSamples"))
+
+	// AL/efx.h
+	report = append(report, "EFX")
+	report = append(report, isBound(unsafe.Pointer(C.pfn_alGenEffects), "alGenEffects"))
+	report = append(report, isBound(unsafe.Pointer(C.pfn_alDeleteEffects), "alDeleteEffects"))
+	report = append(report, isBound(unsafe.Pointer(C.pfn_alEffecti), "alEffecti"))
+	report = append(report, isBound(unsafe.Pointer(C.pfn_alEffectf), "alEffectf"))
+	report = append(report, isBound(unsafe.Pointer(C.pfn_alGenFilters), "alGenFilters"))
+	report = append(report, isBound(unsafe.Pointer(C.pfn_alDeleteFilters), "alDeleteFilters"))
+	report = append(report, isBound(unsafe.Pointer(C.pfn_alFilteri), "alFilteri"))
+	report = append(report, isBound(unsafe.Pointer(C.pfn_alFilterf), "alFilterf"))
+	report = append(report, isBound(unsafe.Pointer(C.pfn_alGenAuxiliaryEffectSlots), "alGenAuxiliaryEffectSlots"))
+	report = append(report, isBound(unsafe.Pointer(C.pfn_alDeleteAuxiliaryEffectSlots), "alDeleteAuxiliaryEffectSlots"))
+	report = append(report, isBound(unsafe.Pointer(C.pfn_alAuxiliaryEffectSloti), "alAuxiliaryEffectSloti"))
+	report = append(report, isBound(unsafe.Pointer(C.pfn_alAuxiliaryEffectSlotf), "alAuxiliaryEffectSlotf"))
+
+	// AL/alext.h
+	report = append(report, "ALEXT")
+	report = append(report, isBound(unsafe.Pointer(C.pfn_alcReopenDeviceSOFT), "alcReopenDeviceSOFT"))
 	return
 }
 
@@ -8,6 +8,10 @@
 // Package audio is provided as part of the vu (virtual universe) 3D engine.
 package audio
 
+import (
+	"math"
+)
+
 // Audio interacts with the underlying audio layer which in turn interfaces
 // to the sound drivers and hardware. Audio must be initialized once before
 // sounds can be bound and played.
@@ -16,19 +20,114 @@ type Audio interface {
 	Dispose()             // Closes and cleans up the audio layer.
 	SetGain(gain float64) // Volume control: valid values are 0->1.
 
+	// Sounds play on the system default output device until another
+	// device is chosen. Bound sounds are kept, and playing sounds keep
+	// playing, when switching devices. CheckDevice follows the default
+	// device as it changes, ie: when headphones are plugged in, and
+	// moves to the default device when the chosen device is unplugged.
+	// SetDevice returns an error where devices can't be switched.
+	Devices() []string           // Names of the output devices.
+	Device() string              // Name of the current device.
+	SetDevice(name string) error // "" for the default device.
+	CheckDevice() (changed bool) // Expected to be called regularly.
+
 	// BindSound copies the sound data to the sound card and returns
 	// references that can be used to dispose of the sound with ReleaseSound.
+	// Data without AudioData binds a stream, see QueueSound.
 	//     sound : updated reference to the bound sound.
 	//     buff  : updated reference to the sound data buffer.
 	//     d     : sound data bytes and settings to be bound.
 	BindSound(sound, buff *uint64, d *Data) error
 	ReleaseSound(sound uint64)
 
+	// Streams play sound data that is queued while the stream plays.
+	// Data is in the format of the bound stream Data. Streams that run
+	// out of data are silent until more data is queued.
+	QueueSound(sound uint64, data []byte) // Play data after queued data.
+	Queued(sound uint64) int              // Queued data not yet played.
+
 	// Control sounds by setting the x,y,z locations for a listener
 	// and the played sounds. While there is only ever one listener,
 	// there can be many sounds.
 	PlaceListener(x, y, z float64)           // Only ever one listener.
 	PlaySound(sound uint64, x, y, z float64) // Play the bound sound.
+
+	// Spatialize sounds using the listener orientation to pan sounds
+	// between the speakers and, when doppler is on, the listener and
+	// sound velocities to shift the pitch. Only mono sounds are
+	// spatialized. Other sounds play as recorded.
+	OrientListener(fx, fy, fz, ux, uy, uz float64)        // Facing and up.
+	SetListenerVelocity(vx, vy, vz float64)               // Listener motion.
+	PlaceSound(sound uint64, x, y, z, vx, vy, vz float64) // Move a sound.
+	SetDistance(sound uint64, min, max, rolloff float64)  // See Attenuate.
+	SetRelative(sound uint64, relative bool)              // Move with listener.
+	SetAttenuation(model int)                             // All sounds.
+
+	// SetDoppler sets the strength of the doppler pitch shift, where
+	// 0 is off and 1 is realistic, and the speed of sound in world
+	// units per second. Doppler is off by default.
+	SetDoppler(factor, speedOfSound float64)
+
+	// Mix sounds using a gain for each sound that is applied along
+	// with the overall SetGain. Playing sounds can be paused and later
+	// resumed from where they were paused.
+	SetSoundGain(sound uint64, gain float64) // Valid values are 0->1.
+	PauseSound(sound uint64)                 // Ignored if not playing.
+	ResumeSound(sound uint64)                // Ignored if not paused.
+
+	// Control played sounds.
+	SetLooping(sound uint64, loop bool)   // Repeat until stopped.
+	SetPitch(sound uint64, pitch float64) // 1 is normal. Must be above 0.
+	StopSound(sound uint64)               // Stop playing.
+	IsPlaying(sound uint64) bool          // True if playing or paused.
+
+	// Effects, like reverb, are bound once and can then be applied to
+	// any number of sounds. Binding an already bound effect updates the
+	// effect for all sounds using it. Each sound has one effect and one
+	// low-pass filter. Effects and filters are ignored, and BindEffect
+	// returns an error, where the audio layer does not support them.
+	//     effect : updated reference to the bound effect.
+	//     fx     : a Reverb or Echo.
+	BindEffect(effect *uint64, fx Effect) error
+	ReleaseEffect(effect uint64)
+	SetSoundEffect(sound, effect uint64)     // Effect 0 removes the effect.
+	SetLowPass(sound uint64, gainHF float64) // High frequency volume 0->1.
+}
+
+// Distance attenuation models used by SetAttenuation.
+const (
+	NoAttenuation = iota // Sounds do not fade with distance.
+	Linear               // Sounds fade linearly to silent at max distance.
+	Inverse              // Sounds fade with the inverse of distance. Default.
+	Exponential          // Sounds fade exponentially with distance.
+)
+
+// Attenuate returns the gain, from 1 to 0, of a sound at the given
+// distance from the listener for the given attenuation model. Sounds
+// closer than min are full volume and sounds fade no more past max.
+// The rolloff scales how quickly sounds fade, where 1 is normal.
+// This matches the clamped distance models of the audio layer:
+//    Linear     : 1 - rolloff*(distance-min)/(max-min)
+//    Inverse    : min / (min + rolloff*(distance-min))
+//    Exponential: (distance/min)^-rolloff
+func Attenuate(model int, distance, min, max, rolloff float64) float64 {
+	distance = math.Max(min, math.Min(max, distance))
+	gain := 1.0
+	switch model {
+	case Linear:
+		if max > min {
+			gain = 1 - rolloff*(distance-min)/(max-min)
+		}
+	case Inverse:
+		if d := min + rolloff*(distance-min); d > 0 {
+			gain = min / d
+		}
+	case Exponential:
+		if min > 0 {
+			gain = math.Pow(distance/min, -rolloff)
+		}
+	}
+	return math.Max(0, math.Min(1, gain))
 }
 
 // Audio
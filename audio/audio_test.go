@@ -4,6 +4,7 @@
 package audio
 
 import (
+	"math"
 	"testing"
 	//	"time"
 
@@ -32,3 +33,27 @@ func TestAudio(t *testing.T) {
 	// 	time.Sleep(1000 * time.Millisecond)
 	a.Dispose()
 }
+
+// Check the distance attenuation formulas.
+func TestAttenuate(t *testing.T) {
+	tests := []struct {
+		model         int
+		distance, min float64
+		max, rolloff  float64
+		gain          float64
+	}{
+		{NoAttenuation, 100, 1, 10, 1, 1},
+		{Linear, 0.5, 1, 11, 1, 1}, // closer than min.
+		{Linear, 6, 1, 11, 1, 0.5}, // half way.
+		{Linear, 20, 1, 11, 1, 0},  // past max.
+		{Inverse, 3, 1, 100, 1, 1.0 / 3},
+		{Inverse, 3, 1, 100, 0.5, 0.5},
+		{Inverse, 300, 1, 100, 1, 0.01}, // stops at max.
+		{Exponential, 4, 2, 100, 2, 0.25},
+	}
+	for cnt, test := range tests {
+		if gain := Attenuate(test.model, test.distance, test.min, test.max, test.rolloff); math.Abs(gain-test.gain) > 1e-9 {
+			t.Errorf("%d expected gain %f got %f", cnt, test.gain, gain)
+		}
+	}
+}
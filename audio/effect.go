@@ -0,0 +1,53 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package audio
+
+// Effect changes how a sound is heard. Effects are Reverb or Echo
+// values that are bound using Audio.BindEffect. Effect values are
+// comparable so equal effects can share a binding.
+type Effect interface {
+	effect() // Only audio package effects are supported.
+}
+
+// Reverb simulates the sound reflections of a room or space.
+// See the reverb presets for typical settings.
+type Reverb struct {
+	Density          float64 // Modal density: 0 to 1.
+	Diffusion        float64 // Echo density: 0 to 1.
+	Gain             float64 // Reverb volume: 0 to 1.
+	GainHF           float64 // High frequency volume: 0 to 1.
+	DecayTime        float64 // Seconds: 0.1 to 20.
+	DecayHFRatio     float64 // High frequency decay: 0.1 to 2.
+	ReflectionsGain  float64 // Early reflections volume: 0 to 3.16.
+	ReflectionsDelay float64 // Seconds: 0 to 0.3.
+	LateGain         float64 // Late reverb volume: 0 to 10.
+	LateDelay        float64 // Seconds: 0 to 0.1.
+}
+
+// Reverb presets from the EFX reverb presets.
+var (
+	GenericReverb    = Reverb{1, 1, 0.3162, 0.8913, 1.49, 0.83, 0.05, 0.007, 1.2589, 0.011}
+	RoomReverb       = Reverb{0.4287, 1, 0.3162, 0.5929, 0.4, 0.83, 0.1503, 0.002, 1.0629, 0.003}
+	HallwayReverb    = Reverb{0.3645, 1, 0.3162, 0.7079, 1.49, 0.59, 0.2458, 0.007, 1.6615, 0.011}
+	HallReverb       = Reverb{1, 1, 0.3162, 0.5623, 3.92, 0.7, 0.2427, 0.02, 0.9977, 0.029}
+	CaveReverb       = Reverb{1, 1, 0.3162, 1, 2.91, 1.3, 0.5, 0.015, 0.7063, 0.022}
+	UnderwaterReverb = Reverb{0.3645, 1, 0.3162, 0.01, 1.49, 0.1, 0.5963, 0.007, 7.0795, 0.011}
+)
+
+// Echo repeats a sound with a delay, alternating between the left
+// and right speakers.
+type Echo struct {
+	Delay    float64 // Seconds to the first echo: 0 to 0.207.
+	LRDelay  float64 // Seconds to the second echo: 0 to 0.404.
+	Damping  float64 // High frequency damping: 0 to 0.99.
+	Feedback float64 // Echo repeats: 0 to 1.
+	Spread   float64 // Left to right spread: -1 to 1.
+}
+
+// DefaultEcho has the EFX default echo settings.
+var DefaultEcho = Echo{0.1, 0.1, 0.5, 0.5, -1}
+
+// Implement Effect.
+func (r Reverb) effect() {}
+func (e Echo) effect()   {}
@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"log"
 	"strings"
+	"time"
 
 	"github.com/gazed/vu/audio/al"
 )
@@ -23,13 +24,40 @@ import (
 // of the underlying OpenAL audio library as well as providing some sound
 // utility methods.
 type openal struct {
-	dev al.Device  // created on initialization.
-	ctx al.Context // created on initialization.
+	dev    al.Device  // created on initialization.
+	ctx    al.Context // created on initialization.
+	orient [6]float32 // scratch listener orientation.
+
+	// Devices can be switched without rebinding sounds where
+	// ALC_SOFT_reopen_device is supported.
+	device  string    // Requested device. "" for the default device.
+	opened  string    // Name of the current device.
+	dflt    string    // Last known default device.
+	checked time.Time // Last time the device was checked.
+
+	// Effects need the OpenAL effects extension.
+	efx     bool              // True if effects are supported.
+	effects map[uint64]uint32 // Effect for each bound effect slot.
+	filters map[uint64]uint32 // Low-pass filter for each sound.
+
+	// Streams queue buffers of data on their sound.
+	streams map[uint64]*stream // Stream sounds.
+}
+
+// stream tracks the data buffers queued on a stream sound.
+type stream struct {
+	format  int32    // Data format.
+	freq    int32    // Data frequency.
+	playing bool     // True from play until stopped.
+	buffs   []uint32 // All stream buffers.
+	free    []uint32 // Buffers that can be reused.
 }
 
 // audioWrapper gets a reference to the underlying audio wrapper.
 // Compiling ensures there will only be one that matches.
-func audioWrapper() Audio { return &openal{} }
+func audioWrapper() Audio {
+	return &openal{effects: map[uint64]uint32{}, filters: map[uint64]uint32{}, streams: map[uint64]*stream{}}
+}
 
 // Init runs the one time openal library initialization. It is expected to
 // be called once by the engine on startup.
@@ -43,6 +71,9 @@ func (a *openal) Init() (err error) {
 	if a.dev = al.OpenDevice(""); a.dev != 0 {
 		if a.ctx = al.CreateContext(a.dev, nil); a.ctx != 0 {
 			al.MakeContextCurrent(a.ctx)
+			al.DopplerFactor(0) // off until requested.
+			a.efx = al.EFX() && al.IsDeviceExtensionPresent(a.dev, "ALC_EXT_EFX")
+			a.opened, a.dflt = a.name(), a.defaultDevice()
 			return // success
 		}
 	}
@@ -53,6 +84,9 @@ func (a *openal) Init() (err error) {
 func (a *openal) validate() error {
 	if report := al.BindingReport(); len(report) > 0 {
 		for _, line := range report {
+			if line == "EFX" {
+				break // extensions are optional.
+			}
 			if strings.Contains(line, "[-]") {
 				return fmt.Errorf("OpenAL uninitialized")
 			}
@@ -75,6 +109,73 @@ func (a *openal) Dispose() {
 	}
 }
 
+// Devices lists the output devices using ALC_ENUMERATE_ALL_EXT
+// where available since it includes all the outputs of each device.
+func (a *openal) Devices() []string {
+	if al.IsDeviceExtensionPresent(0, "ALC_ENUMERATE_ALL_EXT") {
+		return al.GetDeviceStrings(0, al.C_ALL_DEVICES_SPECIFIER)
+	}
+	return al.GetDeviceStrings(0, al.C_DEVICE_SPECIFIER)
+}
+
+// Implement Audio.
+func (a *openal) Device() string { return a.opened }
+
+// SetDevice keeps the bound sounds and playing sounds. The device
+// is only changed where ALC_SOFT_reopen_device is supported.
+func (a *openal) SetDevice(name string) error {
+	if !al.ReopenDeviceBound() || !al.IsDeviceExtensionPresent(a.dev, "ALC_SOFT_reopen_device") {
+		return fmt.Errorf("openal: can't switch audio devices")
+	}
+	if !al.ReopenDevice(a.dev, name) {
+		return fmt.Errorf("openal: can't open audio device %s", name)
+	}
+	a.device, a.opened = name, a.name()
+	return nil
+}
+
+// CheckDevice follows the default device when it changes. It moves to
+// the default device if the current device is disconnected. Checks are
+// done at most once a second since finding devices can be slow.
+func (a *openal) CheckDevice() (changed bool) {
+	if time.Since(a.checked) < time.Second || !al.ReopenDeviceBound() {
+		return false
+	}
+	opened := a.opened
+	a.checked = time.Now()
+	connected := int32(al.TRUE)
+	if al.IsDeviceExtensionPresent(a.dev, "ALC_EXT_disconnect") {
+		al.GetDeviceIntegerv(a.dev, al.C_CONNECTED, 1, &connected)
+	}
+	dflt := a.defaultDevice()
+	switch {
+	case connected == al.FALSE:
+		if a.SetDevice(a.device) != nil {
+			a.SetDevice("") // device is gone.
+		}
+	case a.device == "" && dflt != a.dflt:
+		a.SetDevice("")
+	}
+	a.dflt = dflt
+	return a.opened != opened
+}
+
+// name returns the name of the current device.
+func (a *openal) name() string {
+	if al.IsDeviceExtensionPresent(0, "ALC_ENUMERATE_ALL_EXT") {
+		return al.GetDeviceString(a.dev, al.C_ALL_DEVICES_SPECIFIER)
+	}
+	return al.GetDeviceString(a.dev, al.C_DEVICE_SPECIFIER)
+}
+
+// defaultDevice returns the name of the system default device.
+func (a *openal) defaultDevice() string {
+	if al.IsDeviceExtensionPresent(0, "ALC_ENUMERATE_ALL_EXT") {
+		return al.GetDeviceString(0, al.C_DEFAULT_ALL_DEVICES_SPECIFIER)
+	}
+	return al.GetDeviceString(0, al.C_DEFAULT_DEVICE_SPECIFIER)
+}
+
 // SetGain sets the listener gain to a value between 0 and 1.
 // Values outside the 0 to 1 range are ignored.
 func (a *openal) SetGain(zeroToOne float64) {
@@ -94,7 +195,11 @@ func (a *openal) BindSound(snd, buff *uint64, d *Data) (err error) {
 	// create the sound buffer and copy the audio data into the buffer
 	var buff32, snd32 uint32
 	var format int32
-	if format, err = a.format(d); err == nil {
+	if format, err = a.format(d); err == nil && len(d.AudioData) == 0 {
+		al.GenSources(1, &snd32)
+		*snd = uint64(snd32)
+		a.streams[*snd] = &stream{format: format, freq: int32(d.Frequency)}
+	} else if err == nil {
 		al.GenBuffers(1, &buff32)
 		al.BufferData(buff32, format, al.Pointer(&(d.AudioData[0])), int32(d.DataSize), int32(d.Frequency))
 		*buff = uint64(buff32)
@@ -114,16 +219,286 @@ func (a *openal) PlaceListener(x, y, z float64) {
 	al.Listener3f(al.POSITION, float32(x), float32(y), float32(z))
 }
 
-// Implement Audio.
+// PlaySound restarts sounds that are playing. Streams that are
+// playing keep playing their queued data.
 func (a *openal) PlaySound(snd uint64, x, y, z float64) {
 	al.Source3f(uint32(snd), al.POSITION, float32(x), float32(y), float32(z))
+	if s, ok := a.streams[snd]; ok {
+		s.playing = true
+		if a.state(snd) == al.PLAYING {
+			return
+		}
+		a.unqueue(snd, s) // don't replay played data.
+	}
 	al.SourcePlay(uint32(snd))
 }
 
+// Implement Audio.
+func (a *openal) OrientListener(fx, fy, fz, ux, uy, uz float64) {
+	a.orient = [6]float32{float32(fx), float32(fy), float32(fz), float32(ux), float32(uy), float32(uz)}
+	al.Listenerfv(al.ORIENTATION, &a.orient[0])
+}
+
+// Implement Audio.
+func (a *openal) SetListenerVelocity(vx, vy, vz float64) {
+	al.Listener3f(al.VELOCITY, float32(vx), float32(vy), float32(vz))
+}
+
+// Implement Audio.
+func (a *openal) PlaceSound(snd uint64, x, y, z, vx, vy, vz float64) {
+	al.Source3f(uint32(snd), al.POSITION, float32(x), float32(y), float32(z))
+	al.Source3f(uint32(snd), al.VELOCITY, float32(vx), float32(vy), float32(vz))
+}
+
+// Implement Audio.
+func (a *openal) SetDistance(snd uint64, min, max, rolloff float64) {
+	al.Sourcef(uint32(snd), al.REFERENCE_DISTANCE, float32(min))
+	al.Sourcef(uint32(snd), al.MAX_DISTANCE, float32(max))
+	al.Sourcef(uint32(snd), al.ROLLOFF_FACTOR, float32(rolloff))
+}
+
+// SetRelative places the sound relative to the listener so that
+// a sound at 0,0,0 is heard as recorded wherever the listener is.
+func (a *openal) SetRelative(snd uint64, relative bool) {
+	rel := int32(al.FALSE)
+	if relative {
+		rel = al.TRUE
+	}
+	al.Sourcei(uint32(snd), al.SOURCE_RELATIVE, rel)
+}
+
+// SetAttenuation uses the clamped OpenAL distance models so that
+// sounds closer than their min distance are not louder than normal.
+// Unknown models are ignored.
+func (a *openal) SetAttenuation(model int) {
+	switch model {
+	case NoAttenuation:
+		al.DistanceModel(al.NONE)
+	case Linear:
+		al.DistanceModel(al.LINEAR_DISTANCE_CLAMPED)
+	case Inverse:
+		al.DistanceModel(al.INVERSE_DISTANCE_CLAMPED)
+	case Exponential:
+		al.DistanceModel(al.EXPONENT_DISTANCE_CLAMPED)
+	}
+}
+
+// SetDoppler ignores invalid values.
+func (a *openal) SetDoppler(factor, speedOfSound float64) {
+	if factor >= 0 {
+		al.DopplerFactor(float32(factor))
+	}
+	if speedOfSound > 0 {
+		al.SpeedOfSound(float32(speedOfSound))
+	}
+}
+
+// SetSoundGain ignores values outside the 0 to 1 range.
+func (a *openal) SetSoundGain(snd uint64, zeroToOne float64) {
+	if zeroToOne >= 0 && zeroToOne <= 1 {
+		al.Sourcef(uint32(snd), al.GAIN, float32(zeroToOne))
+	}
+}
+
+// Implement Audio.
+func (a *openal) PauseSound(snd uint64) {
+	if a.state(snd) == al.PLAYING {
+		al.SourcePause(uint32(snd))
+	}
+}
+
+// Implement Audio.
+func (a *openal) ResumeSound(snd uint64) {
+	if a.state(snd) == al.PAUSED {
+		al.SourcePlay(uint32(snd))
+	}
+}
+
+// Implement Audio.
+func (a *openal) SetLooping(snd uint64, loop bool) {
+	looping := int32(al.FALSE)
+	if loop {
+		looping = al.TRUE
+	}
+	al.Sourcei(uint32(snd), al.LOOPING, looping)
+}
+
+// SetPitch ignores values that are not above 0.
+func (a *openal) SetPitch(snd uint64, pitch float64) {
+	if pitch > 0 {
+		al.Sourcef(uint32(snd), al.PITCH, float32(pitch))
+	}
+}
+
+// Implement Audio.
+func (a *openal) StopSound(snd uint64) {
+	if s, ok := a.streams[snd]; ok {
+		s.playing = false
+	}
+	al.SourceStop(uint32(snd))
+}
+
+// IsPlaying is true for streams that are out of data
+// until they are stopped.
+func (a *openal) IsPlaying(snd uint64) bool {
+	if s, ok := a.streams[snd]; ok {
+		return s.playing
+	}
+	state := a.state(snd)
+	return state == al.PLAYING || state == al.PAUSED
+}
+
+// QueueSound restarts playing streams that ran out of data.
+// Ignored for sounds that are not streams.
+func (a *openal) QueueSound(snd uint64, data []byte) {
+	s, ok := a.streams[snd]
+	if !ok || len(data) == 0 {
+		return
+	}
+	a.unqueue(snd, s)
+	var buff uint32
+	if last := len(s.free) - 1; last >= 0 {
+		buff, s.free = s.free[last], s.free[:last]
+	} else {
+		al.GenBuffers(1, &buff)
+		s.buffs = append(s.buffs, buff)
+	}
+	al.BufferData(buff, s.format, al.Pointer(&data[0]), int32(len(data)), s.freq)
+	al.SourceQueueBuffers(uint32(snd), 1, &buff)
+	if state := a.state(snd); s.playing && state != al.PLAYING && state != al.PAUSED {
+		al.SourcePlay(uint32(snd))
+	}
+}
+
+// Queued returns the number of queued buffers. Each QueueSound
+// call queues one buffer.
+func (a *openal) Queued(snd uint64) int {
+	var queued, played int32
+	if _, ok := a.streams[snd]; ok {
+		al.GetSourcei(uint32(snd), al.BUFFERS_QUEUED, &queued)
+		al.GetSourcei(uint32(snd), al.BUFFERS_PROCESSED, &played)
+	}
+	return int(queued - played)
+}
+
+// unqueue frees the stream buffers that have been played.
+func (a *openal) unqueue(snd uint64, s *stream) {
+	var played int32
+	al.GetSourcei(uint32(snd), al.BUFFERS_PROCESSED, &played)
+	for ; played > 0; played-- {
+		var buff uint32
+		al.SourceUnqueueBuffers(uint32(snd), 1, &buff)
+		s.free = append(s.free, buff)
+	}
+}
+
+// state returns the OpenAL source state: INITIAL, PLAYING,
+// PAUSED, or STOPPED.
+func (a *openal) state(snd uint64) (state int32) {
+	al.GetSourcei(uint32(snd), al.SOURCE_STATE, &state)
+	return state
+}
+
+// BindEffect creates an effect slot holding the effect. The slot
+// is the effect reference used by sounds.
+func (a *openal) BindEffect(effect *uint64, fx Effect) error {
+	if !a.efx {
+		return fmt.Errorf("openal effects unsupported")
+	}
+	switch fx.(type) {
+	case Reverb, Echo:
+	default:
+		return fmt.Errorf("openal cannot recognize effect %T", fx)
+	}
+	eid, ok := a.effects[*effect]
+	if !ok {
+		var slot uint32
+		al.GenAuxiliaryEffectSlots(1, &slot)
+		al.GenEffects(1, &eid)
+		*effect, a.effects[uint64(slot)] = uint64(slot), eid
+	}
+	switch e := fx.(type) {
+	case Reverb:
+		al.Effecti(eid, al.EFFECT_TYPE, al.EFFECT_REVERB)
+		al.Effectf(eid, al.REVERB_DENSITY, float32(e.Density))
+		al.Effectf(eid, al.REVERB_DIFFUSION, float32(e.Diffusion))
+		al.Effectf(eid, al.REVERB_GAIN, float32(e.Gain))
+		al.Effectf(eid, al.REVERB_GAINHF, float32(e.GainHF))
+		al.Effectf(eid, al.REVERB_DECAY_TIME, float32(e.DecayTime))
+		al.Effectf(eid, al.REVERB_DECAY_HFRATIO, float32(e.DecayHFRatio))
+		al.Effectf(eid, al.REVERB_REFLECTIONS_GAIN, float32(e.ReflectionsGain))
+		al.Effectf(eid, al.REVERB_REFLECTIONS_DELAY, float32(e.ReflectionsDelay))
+		al.Effectf(eid, al.REVERB_LATE_REVERB_GAIN, float32(e.LateGain))
+		al.Effectf(eid, al.REVERB_LATE_REVERB_DELAY, float32(e.LateDelay))
+	case Echo:
+		al.Effecti(eid, al.EFFECT_TYPE, al.EFFECT_ECHO)
+		al.Effectf(eid, al.ECHO_DELAY, float32(e.Delay))
+		al.Effectf(eid, al.ECHO_LRDELAY, float32(e.LRDelay))
+		al.Effectf(eid, al.ECHO_DAMPING, float32(e.Damping))
+		al.Effectf(eid, al.ECHO_FEEDBACK, float32(e.Feedback))
+		al.Effectf(eid, al.ECHO_SPREAD, float32(e.Spread))
+	}
+
+	// the slot copies the effect, so the effect is attached after
+	// each change.
+	al.AuxiliaryEffectSloti(uint32(*effect), al.EFFECTSLOT_EFFECT, int32(eid))
+	if alerr := al.GetError(); alerr != al.NO_ERROR {
+		return fmt.Errorf("Failed binding effect %X", alerr)
+	}
+	return nil
+}
+
+// Implement Audio.
+func (a *openal) ReleaseEffect(effect uint64) {
+	if eid, ok := a.effects[effect]; ok {
+		slot := uint32(effect)
+		al.DeleteAuxiliaryEffectSlots(1, &slot)
+		al.DeleteEffects(1, &eid)
+		delete(a.effects, effect)
+	}
+}
+
+// SetSoundEffect uses the first auxiliary send of the sound.
+func (a *openal) SetSoundEffect(snd, effect uint64) {
+	if a.efx {
+		al.Source3i(uint32(snd), al.AUXILIARY_SEND_FILTER, int32(effect), 0, al.FILTER_NULL)
+	}
+}
+
+// SetLowPass filters the direct path of the sound. The sound copies
+// the filter, so the filter is reapplied after each change. A gain
+// of 1 removes the filter. Values outside the 0 to 1 range are ignored.
+func (a *openal) SetLowPass(snd uint64, gainHF float64) {
+	if !a.efx || gainHF < 0 || gainHF > 1 {
+		return
+	}
+	if gainHF == 1 {
+		al.Sourcei(uint32(snd), al.DIRECT_FILTER, al.FILTER_NULL)
+		return
+	}
+	fid, ok := a.filters[snd]
+	if !ok {
+		al.GenFilters(1, &fid)
+		al.Filteri(fid, al.FILTER_TYPE, al.FILTER_LOWPASS)
+		a.filters[snd] = fid
+	}
+	al.Filterf(fid, al.LOWPASS_GAIN, 1)
+	al.Filterf(fid, al.LOWPASS_GAINHF, float32(gainHF))
+	al.Sourcei(uint32(snd), al.DIRECT_FILTER, int32(fid))
+}
+
 // Implement Audio.
 func (a *openal) ReleaseSound(snd uint64) {
 	snd32 := uint32(snd)
 	al.DeleteSources(1, &snd32)
+	if s, ok := a.streams[snd]; ok && len(s.buffs) > 0 {
+		al.DeleteBuffers(int32(len(s.buffs)), &s.buffs[0])
+	}
+	delete(a.streams, snd)
+	if fid, ok := a.filters[snd]; ok {
+		al.DeleteFilters(1, &fid)
+		delete(a.filters, snd)
+	}
 }
 
 // format figures out which of the OpenAL formats to use based on the
@@ -0,0 +1,128 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"math"
+
+	"github.com/gazed/vu/math/lin"
+	"github.com/gazed/vu/physics"
+)
+
+// Bounds are world space axis aligned boxes that contain the models
+// and bodies of a Pov and its children. Bounds are recalculated with
+// the model transforms each update, and only for the parts of the
+// hierarchy that have changed. Mesh bounds are found from the mesh
+// vertex locations as the data is set. Bodies without bounds, ie:
+// planes and rays, are ignored.
+
+// bounds tracks the local model bounds of a mesh.
+type bounds struct {
+	box   physics.Abox // Local space vertex bounds.
+	ok    bool         // True if box contains any verticies.
+	span  uint32       // Vertex location span, usually 3.
+	stamp int          // Incremented when the bounds change.
+}
+
+// addVerts grows the bounds to include the given vertex locations.
+// The bounds are first cleared if reset is true.
+func (b *bounds) addVerts(data interface{}, reset bool) {
+	verts, ok := data.([]float32)
+	if !ok || b.span < 2 {
+		return
+	}
+	if reset {
+		b.ok = false
+	}
+	span, bx := int(b.span), &b.box
+	for cnt := 0; cnt+span <= len(verts); cnt += span {
+		x, y, z := float64(verts[cnt]), float64(verts[cnt+1]), 0.0
+		if span > 2 {
+			z = float64(verts[cnt+2])
+		}
+		if !b.ok {
+			bx.Sx, bx.Sy, bx.Sz, bx.Lx, bx.Ly, bx.Lz = x, y, z, x, y, z
+			b.ok = true
+			continue
+		}
+		bx.Sx, bx.Sy, bx.Sz = math.Min(bx.Sx, x), math.Min(bx.Sy, y), math.Min(bx.Sz, z)
+		bx.Lx, bx.Ly, bx.Lz = math.Max(bx.Lx, x), math.Max(bx.Ly, y), math.Max(bx.Lz, z)
+	}
+	b.stamp++
+}
+
+// originT is the identity transform used to get local shape bounds.
+var originT = &lin.T{Loc: &lin.V3{}, Rot: &lin.Q{W: 1}}
+
+// transformBox sets out to the axis aligned box that contains
+// the local box after it is transformed by model matrix mm.
+func transformBox(local *physics.Abox, mm *lin.M4, out *physics.Abox) *physics.Abox {
+	cx, cy, cz := (local.Sx+local.Lx)*0.5, (local.Sy+local.Ly)*0.5, (local.Sz+local.Lz)*0.5
+	ex, ey, ez := (local.Lx-local.Sx)*0.5, (local.Ly-local.Sy)*0.5, (local.Lz-local.Sz)*0.5
+
+	// transform the center as a row vector. Extents use the absolute
+	// values of the rotation and scale.
+	wx := cx*mm.Xx + cy*mm.Yx + cz*mm.Zx + mm.Wx
+	wy := cx*mm.Xy + cy*mm.Yy + cz*mm.Zy + mm.Wy
+	wz := cx*mm.Xz + cy*mm.Yz + cz*mm.Zz + mm.Wz
+	rx := ex*math.Abs(mm.Xx) + ey*math.Abs(mm.Yx) + ez*math.Abs(mm.Zx)
+	ry := ex*math.Abs(mm.Xy) + ey*math.Abs(mm.Yy) + ez*math.Abs(mm.Zy)
+	rz := ex*math.Abs(mm.Xz) + ey*math.Abs(mm.Yz) + ez*math.Abs(mm.Zz)
+	out.Sx, out.Sy, out.Sz = wx-rx, wy-ry, wz-rz
+	out.Lx, out.Ly, out.Lz = wx+rx, wy+ry, wz+rz
+	return out
+}
+
+// growBox expands box a to include box b.
+func growBox(a, b *physics.Abox) {
+	a.Sx, a.Sy, a.Sz = math.Min(a.Sx, b.Sx), math.Min(a.Sy, b.Sy), math.Min(a.Sz, b.Sz)
+	a.Lx, a.Ly, a.Lz = math.Max(a.Lx, b.Lx), math.Max(a.Ly, b.Ly), math.Max(a.Lz, b.Lz)
+}
+
+// boundsChanged returns true if the mesh bounds of the pov model
+// have changed since the pov bounds were last calculated.
+func (eng *engine) boundsChanged(p *pov) bool {
+	if m, ok := eng.models.model(p.eid); ok && m.msh != nil {
+		return m.msh.bounds.stamp != p.mstamp || m.msh != p.mbound
+	}
+	return p.mbound != nil
+}
+
+// placeBounds recalculates the world bounds of p from its model, body,
+// and the bounds of its children. Expected to be called after the
+// children have been placed.
+func (eng *engine) placeBounds(p *pov) {
+	p.bounded, p.mbound, p.mstamp = false, nil, 0
+	ab := &eng.ab
+	if m, ok := eng.models.model(p.eid); ok && m.msh != nil {
+		p.mbound, p.mstamp = m.msh, m.msh.bounds.stamp
+		if m.msh.bounds.ok {
+			p.include(transformBox(&m.msh.bounds.box, p.mm, ab))
+		}
+	}
+	if b := p.Body(); b != nil {
+		if box := b.Shape().Aabb(originT, ab, 0); box != nil {
+			p.include(transformBox(box, p.mm, box))
+		}
+	}
+	own := p.bounded // has model or body bounds.
+	for _, child := range p.children {
+		if child.bounded && child.enabled {
+			p.include(&child.bb)
+		}
+	}
+	eng.index.place(p, own)
+}
+
+// include grows the pov bounds to include the given box.
+func (p *pov) include(box *physics.Abox) {
+	if !p.bounded {
+		p.bb, p.bounded = *box, true
+		return
+	}
+	growBox(&p.bb, box)
+}
+
+// Implement Pov.
+func (p *pov) Bounds() (box physics.Abox, ok bool) { return p.bb, p.bounded }
@@ -0,0 +1,56 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"testing"
+
+	"github.com/gazed/vu/math/lin"
+	"github.com/gazed/vu/physics"
+)
+
+// TestBounds checks that bounds combine models, bodies, and children.
+func TestBounds(t *testing.T) {
+	eng := newEngine(nil)
+	defer eng.Shutdown()
+	ship := eng.Root().NewPov().SetLocation(10, 0, 0)
+	hull := ship.NewPov().SetScale(2, 1, 1)
+	m := hull.NewModel("uv").(*model)
+	m.msh = newMesh("hull")
+	m.msh.initData(0, 3, 0, false).setData(0, []float32{-1, -1, -1, 1, 1, 1})
+	gun := ship.NewPov().SetLocation(0, 4, 0)
+	gun.NewBody(physics.NewBody(physics.NewSphere(0.5)))
+	eng.placeModels(eng.root(), lin.M4I)
+
+	if _, ok := ship.Bounds(); !ok {
+		t.Fatalf("Expected ship bounds")
+	}
+	want := physics.Abox{Sx: 8, Sy: -1, Sz: -1, Lx: 12, Ly: 1, Lz: 1}
+	if box, ok := hull.Bounds(); !ok || !aeqBox(&box, &want) {
+		t.Errorf("Expected hull %v got %v", want, box)
+	}
+	want = physics.Abox{Sx: 8, Sy: -1, Sz: -1, Lx: 12, Ly: 4.5, Lz: 1}
+	if box, _ := ship.Bounds(); !aeqBox(&box, &want) {
+		t.Errorf("Expected ship %v got %v", want, box)
+	}
+
+	// disabled and disposed children are removed from the bounds.
+	hull.SetEnabled(false)
+	eng.placeModels(eng.root(), lin.M4I)
+	want = physics.Abox{Sx: 9.5, Sy: 3.5, Sz: -0.5, Lx: 10.5, Ly: 4.5, Lz: 0.5}
+	if box, _ := ship.Bounds(); !aeqBox(&box, &want) {
+		t.Errorf("Expected gun only %v got %v", want, box)
+	}
+	gun.Dispose(PovNode)
+	eng.placeModels(eng.root(), lin.M4I)
+	if _, ok := ship.Bounds(); ok {
+		t.Errorf("Expected no bounds")
+	}
+}
+
+// aeqBox returns true if the boxes are almost equal.
+func aeqBox(a, b *physics.Abox) bool {
+	return lin.Aeq(a.Sx, b.Sx) && lin.Aeq(a.Sy, b.Sy) && lin.Aeq(a.Sz, b.Sz) &&
+		lin.Aeq(a.Lx, b.Lx) && lin.Aeq(a.Ly, b.Ly) && lin.Aeq(a.Lz, b.Lz)
+}
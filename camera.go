@@ -10,7 +10,10 @@ package vu
 // http://udn.epicgames.com/Three/CameraTechnicalGuide.html
 
 import (
+	"math"
+
 	"github.com/gazed/vu/math/lin"
+	"github.com/gazed/vu/physics"
 	"github.com/gazed/vu/render"
 )
 
@@ -43,6 +46,17 @@ type Camera interface {
 	SetDepth(enabled bool) // True for 3D camera. 2D cams ignore depth.
 	SetLast(index int)     // For sequencing UI cameras. Higher is later.
 	SetUI()                // UI camera: 2D, no depth, drawn last.
+	SetMask(mask uint32)   // Only draw Pov's with layers in mask. Default all.
+
+	// Viewports are the window area drawn by the camera, given as
+	// fractions of the window from the lower left, ie: 0, 0, 0.5, 1
+	// is the left half for split screen. Cameras are drawn in order,
+	// lowest first, so that higher ordered viewports, ie: picture in
+	// picture, are drawn over lower ones. Partial viewports are cleared
+	// before drawing. The projection aspect ratio is not changed.
+	Viewport() (x, y, w, h float64) // Get, or
+	SetViewport(x, y, w, h float64) // ...Set the window area. Default full.
+	SetOrder(order int)             // Set viewport draw order. Default 0.
 
 	// Set one of the possible view transfrom algorithms. This affects
 	// the view portion of model-view-projection.
@@ -53,17 +67,65 @@ type Camera interface {
 	SetPerspective(fov, ratio, near, far float64)                // 3D.
 	SetOrthographic(left, right, bottom, top, near, far float64) // 2D.
 
-	// Ray applies inverse transforms to derive world space coordinates for
-	// a ray projected from the camera through the mouse's mx,my screen
-	// position given window width and height ww,wh.
-	Ray(mx, my, ww, wh int) (x, y, z float64)
-
-	// Screen calculates screen coordinates sx,sy for world coordinates
-	// wx,wy,wz and window width and height ww,wh.
-	Screen(wx, wy, wz float64, ww, wh int) (sx, sy int)
+	// SetLens sets a perspective projection from physical camera
+	// settings. Lens returns the settings, if any. See cameralens.go.
+	SetLens(l Lens)
+	Lens() (l Lens, ok bool)
+
+	// SetOrthoSize sets an orthographic projection that shows height
+	// world units vertically and keeps the viewport aspect ratio as the
+	// window is resized, ie: for 2.5D or isometric world cameras that
+	// use the default view transform. Zoom scales the height so that
+	// zooming by 2 shows half the height. Zoom only affects cameras
+	// using SetOrthoSize. Picking and frustum culling follow the
+	// zoomed projection.
+	SetOrthoSize(height, near, far float64)
+	Zoom() float64                   // Get, or
+	SetZoom(zoom float64)            // ...Set the zoom. Default 1.
+	ZoomAt(mx, my int, zoom float64) // Zoom keeping the world under mx,my.
+
+	// SetProjection uses a copy of the given projection matrix for
+	// special effects. Rays assume a perspective like projection.
+	SetProjection(custom *lin.M4)
+
+	// SetClipPlane replaces the near plane of the projection with the
+	// given world space plane, ie: to only draw what is above the water
+	// for planar reflections, or beyond a portal. Points where
+	// a*x + b*y + c*z + d >= 0 are drawn. The camera is expected to be
+	// behind the plane. All zeros turns off the clip plane.
+	SetClipPlane(a, b, c, d float64)
+
+	// Ray returns the world space origin and unit direction of the ray
+	// from the camera through the mouse's mx,my screen position. Rays for
+	// perspective projections start at the camera. Rays for orthographic
+	// projections start at the near plane and are parallel to the view.
+	// The direction is zero if mx,my is outside the window.
+	Ray(mx, my int) (ox, oy, oz, dx, dy, dz float64)
+
+	// Screen calculates the window pixel coordinates sx,sy for world
+	// coordinates wx,wy,wz. Visible is false for points that are outside
+	// the window, or in front of the near or behind the far plane.
+	// The coordinates are still returned for points in front of the
+	// camera, ie: to point at something that is off screen.
+	Screen(wx, wy, wz float64) (sx, sy int, visible bool)
 
 	// Distance returns the distance squared of the camera to the given point.
 	Distance(px, py, pz float64) float64
+
+	// Frustum returns the world space planes of the camera view volume
+	// in the order left, right, bottom, top, near, far. Each plane is
+	// a, b, c, d with the unit normal a, b, c facing into the volume so
+	// that a*x + b*y + c*z + d is the distance of x, y, z inside the
+	// plane. The planes are all zero until there is a projection.
+	Frustum() (planes [6][4]float64)
+
+	// Contains are true for things that are at least partly inside the
+	// view volume, ie: to only run effects or AI that could be seen.
+	// Boxes near the corners of the volume may be reported as inside
+	// even if they are just outside.
+	ContainsPoint(x, y, z float64) bool
+	ContainsSphere(x, y, z, radius float64) bool
+	ContainsBox(box *physics.Abox) bool
 }
 
 // Camera
@@ -84,35 +146,53 @@ type camera struct {
 	cull    Cull          // Set by application.
 	overlay int           // Set render bucket with OVERLAY or greater.
 	target  uint32        // render layer target. Default 0.
+	mask    uint32        // Pov layers that are drawn. Default all.
+	ui      bool          // True after SetUI.
+	proj    []float64     // Last perspective (4), orthographic (6), or custom (16).
+	clip    []float64     // Optional world space near clip plane.
+	osize   []float64     // Ortho height, near, far from SetOrthoSize.
+	zoom    float64       // Ortho size zoom. Default 1.
+	lens    *Lens         // Optional physical camera settings.
+	ww, wh  int           // Window size in pixels. Set by the engine.
+	view    [4]float64    // Viewport x, y, w, h as window fractions.
+	order   int           // Viewport draw order, lowest first.
+	shake   *lin.T        // Optional view offset, see camerashake.go.
+	planes  [6][4]float64 // World space frustum planes.
 
 	// Track the view, projection matricies and their inverses.
 	vm  *lin.M4 // View part of MVP matrix.
 	ivm *lin.M4 // Inverse view matrix.
 	pm  *lin.M4 // Projection part of MVP matrix.
+	bpm *lin.M4 // Projection matrix without the clip plane.
 	ipm *lin.M4 // Inverse projection matrix.
+	vpm *lin.M4 // View projection matrix for the frustum planes.
 
 	// Scratch variables needed each update.
 	q0  *lin.Q  // Scratch for camera transform calculations.
 	qx  *lin.Q  // Scratch for camera transform calculations.
 	v0  *lin.V4 // Scratch for pick ray calculations.
 	ray *lin.V3 // Scratch for pick ray calculations.
+	st  *lin.T  // Scratch for the shaken camera transform.
 }
 
 // newCamera creates a default rendering field that is looking down
 // the positive Z axis with positive Y up.
 func newCamera() *camera {
-	c := &camera{depth: true}
+	c := &camera{depth: true, mask: ^uint32(0), view: [4]float64{0, 0, 1, 1}, zoom: 1}
 	c.vt = VP
 	c.at = lin.NewT()
 	c.vm = &lin.M4{}
 	c.ivm = (&lin.M4{}).Set(lin.M4I)
 	c.pm = &lin.M4{}
+	c.bpm = &lin.M4{}
 	c.ipm = &lin.M4{}
+	c.vpm = &lin.M4{}
 	c.q0 = &lin.Q{}
 	c.xrot = lin.NewQ().SetAa(1, 0, 0, 0)
 	c.yrot = lin.NewQ().SetAa(0, 1, 0, 0)
 	c.v0 = &lin.V4{}
 	c.ray = &lin.V3{}
+	c.st = lin.NewT()
 	return c
 }
 
@@ -121,7 +201,17 @@ func (c *camera) SetView(vt ViewTransform) { c.vt = vt }
 
 // transform applies the view transform to the scene camera
 // and returns the result. The input matrix is not changed.
-func (c *camera) transform(vm *lin.M4) *lin.M4 { return c.vt(c.at, c.q0, vm) }
+func (c *camera) transform(vm *lin.M4) *lin.M4 { return c.vt(c.viewAt(), c.q0, vm) }
+
+// viewAt returns the camera transform with any shake offset.
+func (c *camera) viewAt() *lin.T {
+	if c.shake == nil {
+		return c.at
+	}
+	c.st.Loc.Add(c.at.Loc, c.shake.Loc)
+	c.st.Rot.Mult(c.shake.Rot, c.at.Rot)
+	return c.st
+}
 
 // isCulled applies the camera cull algorithm to the given location.
 func (c *camera) isCulled(px, py, pz float64) bool {
@@ -135,8 +225,10 @@ func (c *camera) isCulled(px, py, pz float64) bool {
 // kept in sync each time the camera moves. Calculating once per move should
 // be quicker than calculating later for each object in the scene.
 func (c *camera) updateTransform() {
-	c.transform(c.vm)            // view transform.
-	ivp(c.at, c.qx, c.q0, c.ivm) // inverse view transform.
+	c.transform(c.vm)                  // view transform.
+	ivp(c.viewAt(), c.qx, c.q0, c.ivm) // inverse view transform.
+	c.clipNear()
+	c.updateFrustum()
 }
 func (c *camera) Location() (x, y, z float64) {
 	return c.at.Loc.X, c.at.Loc.Y, c.at.Loc.Z
@@ -204,7 +296,51 @@ func (c *camera) Distance(px, py, pz float64) float64 {
 func (c *camera) SetDepth(enabled bool) { c.depth = enabled }
 func (c *camera) SetCull(cull Cull)     { c.cull = cull }
 func (c *camera) SetLast(index int)     { c.overlay = render.Overlay + index }
+func (c *camera) SetMask(mask uint32)   { c.mask = mask }
+func (c *camera) SetOrder(order int)    { c.order = order }
+func (c *camera) Viewport() (x, y, w, h float64) {
+	return c.view[0], c.view[1], c.view[2], c.view[3]
+}
+func (c *camera) SetViewport(x, y, w, h float64) {
+	if w > 0 && h > 0 {
+		c.view = [4]float64{x, y, w, h}
+		c.sizeOrtho()
+	}
+}
+
+// lookAngles returns the camera yaw and pitch, in degrees, that look
+// along the given direction. The camera looks along -Z. Returns false
+// for a zero direction.
+func lookAngles(dx, dy, dz float64) (yaw, pitch float64, ok bool) {
+	dist := math.Sqrt(dx*dx + dy*dy + dz*dz)
+	if dist == 0 {
+		return 0, 0, false
+	}
+	yaw = lin.Deg(math.Atan2(-dx, -dz))
+	pitch = lin.Deg(math.Asin(lin.Clamp(dy/dist, -1, 1)))
+	return yaw, pitch, true
+}
+
+// setSize is called by the engine with the window size.
+func (c *camera) setSize(ww, wh int) {
+	c.ww, c.wh = ww, wh
+	c.sizeOrtho()
+}
+
+// axis returns the world direction of the given camera space direction,
+// ie: 0, 0, -1 is the direction the camera is looking.
+func (c *camera) axis(x, y, z float64) (wx, wy, wz float64) {
+	v := c.v0.SetS(x, y, z, 0).MultvM(c.v0, c.ivm)
+	return v.X, v.Y, v.Z
+}
+
+// viewSize returns the viewport lower left corner and size in pixels.
+func (c *camera) viewSize() (vx, vy, vw, vh float64) {
+	ww, wh := float64(c.ww), float64(c.wh)
+	return c.view[0] * ww, c.view[1] * wh, c.view[2] * ww, c.view[3] * wh
+}
 func (c *camera) SetUI() {
+	c.ui = true
 	c.overlay = render.Overlay // Draw last.
 	c.depth = false            // 2D rendering.
 	c.SetView(VO)              // orthographic view transform.
@@ -212,34 +348,139 @@ func (c *camera) SetUI() {
 
 // SetPerspective makes the camera use a 3D projection.
 func (c *camera) SetPerspective(fov, ratio, near, far float64) {
-	c.pm.Persp(fov, ratio, near, far)
+	c.osize, c.lens = c.osize[:0], nil
+	c.proj = append(c.proj[:0], fov, ratio, near, far)
+	c.bpm.Persp(fov, ratio, near, far)
 	c.ipm.PerspInv(fov, ratio, near, far)
 	c.updateTransform()
 }
 
 // SetOrthographic makes the camera use a 2D projection.
 func (c *camera) SetOrthographic(left, right, bottom, top, near, far float64) {
-	c.pm.Ortho(left, right, bottom, top, near, far)
+	c.osize, c.lens = c.osize[:0], nil
+	c.ortho(left, right, bottom, top, near, far)
+}
+
+// ortho sets an orthographic projection.
+func (c *camera) ortho(left, right, bottom, top, near, far float64) {
+	c.proj = append(c.proj[:0], left, right, bottom, top, near, far)
+	c.bpm.Ortho(left, right, bottom, top, near, far)
 	c.transform(c.vm)
+	c.clipNear()
+	c.updateFrustum()
 
 	// Inverse matrix currently ignored for Orthographic.
 	// Ortho views are expected to match the screen pixel sizes.
 	c.ipm.Set(lin.M4I)
 }
 
+// SetOrthoSize makes the camera use a world sized 2D projection.
+func (c *camera) SetOrthoSize(height, near, far float64) {
+	c.lens = nil
+	c.osize = append(c.osize[:0], height, near, far)
+	c.sizeOrtho()
+}
+
+// sizeOrtho updates the SetOrthoSize projection for the current
+// zoom and viewport aspect ratio.
+func (c *camera) sizeOrtho() {
+	if len(c.osize) != 3 {
+		return
+	}
+	aspect := 1.0
+	if _, _, vw, vh := c.viewSize(); vw > 0 && vh > 0 {
+		aspect = vw / vh
+	}
+	hh := c.osize[0] * 0.5 / c.zoom
+	c.ortho(-hh*aspect, hh*aspect, -hh, hh, c.osize[1], c.osize[2])
+}
+
+// Zoom and SetZoom get and set the ortho size zoom.
+func (c *camera) Zoom() float64 { return c.zoom }
+func (c *camera) SetZoom(zoom float64) {
+	if zoom > 0 {
+		c.zoom = zoom
+		c.sizeOrtho()
+	}
+}
+
+// ZoomAt zooms then moves the camera so that the world location
+// under mx,my is at the same screen location.
+func (c *camera) ZoomAt(mx, my int, zoom float64) {
+	x0, y0, z0, _, _, _ := c.Ray(mx, my)
+	c.SetZoom(zoom)
+	if len(c.osize) == 3 {
+		x1, y1, z1, _, _, _ := c.Ray(mx, my)
+		l := c.at.Loc
+		c.SetLocation(l.X+x0-x1, l.Y+y0-y1, l.Z+z0-z1)
+	}
+}
+
+// SetProjection makes the camera use the given projection.
+func (c *camera) SetProjection(custom *lin.M4) {
+	c.osize, c.lens = c.osize[:0], nil
+	m := custom
+	c.proj = append(c.proj[:0],
+		m.Xx, m.Xy, m.Xz, m.Xw, m.Yx, m.Yy, m.Yz, m.Yw,
+		m.Zx, m.Zy, m.Zz, m.Zw, m.Wx, m.Wy, m.Wz, m.Ww)
+	c.bpm.Set(custom)
+	c.ipm.Inv(custom)
+	c.updateTransform()
+}
+
+// SetClipPlane sets or clears the near clip plane. The inverse
+// projection is not changed so that rays still start at the camera.
+func (c *camera) SetClipPlane(a, b, cc, d float64) {
+	c.clip = c.clip[:0]
+	if a != 0 || b != 0 || cc != 0 || d != 0 {
+		c.clip = append(c.clip, a, b, cc, d)
+	}
+	c.clipNear()
+	c.updateFrustum()
+}
+
+// clipNear sets the projection from the projection without the clip
+// plane, using the clip plane, in view space, as the near plane.
+func (c *camera) clipNear() {
+	c.pm.Set(c.bpm)
+	if len(c.clip) == 4 {
+		// points are moved to view space with the view matrix so
+		// planes are moved with the transpose of the inverse.
+		a, b, cc, d, m := c.clip[0], c.clip[1], c.clip[2], c.clip[3], c.ivm
+		ObliqueNear(c.pm,
+			m.Xx*a+m.Xy*b+m.Xz*cc+m.Xw*d, m.Yx*a+m.Yy*b+m.Yz*cc+m.Yw*d,
+			m.Zx*a+m.Zy*b+m.Zz*cc+m.Zw*d, m.Wx*a+m.Wy*b+m.Wz*cc+m.Ww*d)
+	}
+}
+
 // Ray applies inverse transforms to derive world space coordinates for
-// a ray projected from the camera through the mouse's screen position. See:
+// a ray projected from the camera through the mouse's screen position.
+// Orthographic rays are found from the projection values since the
+// inverse projection is not kept for orthographic projections. See:
 //     http://bookofhook.com/mousepick.pdf
 //     http://antongerdelan.net/opengl/raycasting.html
 //     http://schabby.de/picking-opengl-ray-tracing/
 //     (opengl FAQ Picking 20.0.010)
 //     http://www.opengl.org/archives/resources/faq/technical/selection.htm
 //     http://www.codeproject.com/Articles/625787/Pick-Selection-with-OpenGL-and-OpenCL
-func (c *camera) Ray(mx, my, ww, wh int) (x, y, z float64) {
+func (c *camera) Ray(mx, my int) (ox, oy, oz, dx, dy, dz float64) {
+	vx, vy, vw, vh := c.viewSize()
+	px, py := float64(mx)-vx, float64(my)-vy // relative to the viewport.
 	c.ray.SetS(0, 0, 0)
-	if mx >= 0 && mx <= ww && my >= 0 && my <= wh {
-		clipx := float64(2*mx)/float64(ww) - 1 // mx to range -1:1
-		clipy := float64(2*my)/float64(wh) - 1 // my to range -1:1
+	origin := c.v0.SetS(0, 0, 0, 1).MultvM(c.v0, c.ivm) // camera location.
+	ox, oy, oz = origin.X, origin.Y, origin.Z
+	if px >= 0 && px <= vw && py >= 0 && py <= vh && vw > 0 && vh > 0 {
+		clipx := 2*px/vw - 1 // mx to range -1:1
+		clipy := 2*py/vh - 1 // my to range -1:1
+		if len(c.proj) == 6 {
+			l, r, b, t, n := c.proj[0], c.proj[1], c.proj[2], c.proj[3], c.proj[4]
+			near := c.v0.SetS(l+(clipx+1)*0.5*(r-l), b+(clipy+1)*0.5*(t-b), -n, 1)
+			near.MultvM(near, c.ivm) // eye to world coordinates.
+			ox, oy, oz = near.X, near.Y, near.Z
+			dir := c.v0.SetS(0, 0, -1, 0).MultvM(c.v0, c.ivm) // view direction.
+			c.ray.SetS(dir.X, dir.Y, dir.Z).Unit()
+			return ox, oy, oz, c.ray.X, c.ray.Y, c.ray.Z
+		}
 		clip := c.v0.SetS(clipx, clipy, -1, 1)
 
 		// Use the inverse perspective to go from clip to eye (view) coordinates.
@@ -252,26 +493,93 @@ func (c *camera) Ray(mx, my, ww, wh int) (x, y, z float64) {
 		c.ray.SetS(world.X, world.Y, world.Z) // ignore the W component.
 		c.ray.Unit()                          // ensure that a unit vector is returned.
 	}
-	return c.ray.X, c.ray.Y, c.ray.Z
+	return ox, oy, oz, c.ray.X, c.ray.Y, c.ray.Z
 }
 
 // Screen applies the camera transform on a 3D point in world space wx,wy,wz
 // and returns the 2D screen coordinate sx,sy. The window width and height
-// ww,wh are also needed. Essentially the reverse of the Ray method and
-// duplicating what is done in the rendering pipeline.
-func (c *camera) Screen(wx, wy, wz float64, ww, wh int) (sx, sy int) {
+// ww,wh are kept by the engine. Visible points are within the camera
+// viewport. Essentially the reverse of the Ray method and duplicating
+// what is done in the rendering pipeline.
+func (c *camera) Screen(wx, wy, wz float64) (sx, sy int, visible bool) {
 	vec := c.v0.SetS(wx, wy, wz, 1)
-	vec.MultvM(vec, c.vm)          // apply view matrix.
-	vec.MultvM(vec, c.pm)          // apply projection matrix.
+	vec.MultvM(vec, c.vm) // apply view matrix.
+	vec.MultvM(vec, c.pm) // apply projection matrix.
+	if vec.W <= 0 {
+		return -1, -1, false // behind the camera.
+	}
 	clipx := vec.X*0.5/vec.W + 0.5 // convert to range 0:1
 	clipy := vec.Y*0.5/vec.W + 0.5 // convert to range 0:1
 	clipz := vec.Z*0.5/vec.W + 0.5 // convert to range 0:1
-	if clipx < 0 || clipx > 1 || clipy < 0 || clipy > 1 || clipz < 0 || clipz > 1 {
-		return -1, -1 // outside the screen area.
+	vx, vy, vw, vh := c.viewSize()
+	sx = int(lin.Round(vx+clipx*vw, 0))
+	sy = int(lin.Round(vy+clipy*vh, 0))
+	visible = clipx >= 0 && clipx <= 1 && clipy >= 0 && clipy <= 1 && clipz >= 0 && clipz <= 1
+	return sx, sy, visible
+}
+
+// updateFrustum calculates the world space frustum planes from the
+// columns of the view projection matrix, as described by Gribb and
+// Hartmann. For example the left plane is where clip x equals -w,
+// so points inside have clip w+x >= 0.
+func (c *camera) updateFrustum() {
+	m := c.vpm.Mult(c.vm, c.pm)
+	cols := [4][4]float64{
+		{m.Xx, m.Yx, m.Zx, m.Wx},
+		{m.Xy, m.Yy, m.Zy, m.Wy},
+		{m.Xz, m.Yz, m.Zz, m.Wz},
+		{m.Xw, m.Yw, m.Zw, m.Ww},
+	}
+	for cnt := range c.planes {
+		col, sign := cols[cnt/2], float64(1-2*(cnt%2)) // w+col, then w-col.
+		pl := &c.planes[cnt]
+		for i := range pl {
+			pl[i] = cols[3][i] + sign*col[i]
+		}
+		if l := math.Sqrt(pl[0]*pl[0] + pl[1]*pl[1] + pl[2]*pl[2]); l > 0 {
+			pl[0], pl[1], pl[2], pl[3] = pl[0]/l, pl[1]/l, pl[2]/l, pl[3]/l
+		}
 	}
-	sx = int(lin.Round(clipx*float64(ww), 0))
-	sy = int(lin.Round(clipy*float64(wh), 0))
-	return sx, sy
+}
+
+// Frustum returns a copy of the frustum planes.
+func (c *camera) Frustum() (planes [6][4]float64) { return c.planes }
+
+// ContainsPoint is true if the point is inside all the planes.
+func (c *camera) ContainsPoint(x, y, z float64) bool {
+	return c.ContainsSphere(x, y, z, 0)
+}
+
+// ContainsSphere is true if the sphere is not completely
+// outside any of the planes.
+func (c *camera) ContainsSphere(x, y, z, radius float64) bool {
+	for _, pl := range c.planes {
+		if pl[0]*x+pl[1]*y+pl[2]*z+pl[3] < -radius {
+			return false
+		}
+	}
+	return true
+}
+
+// ContainsBox is true if the box corner furthest along each
+// plane normal is inside the plane.
+func (c *camera) ContainsBox(box *physics.Abox) bool {
+	for _, pl := range c.planes {
+		x, y, z := box.Sx, box.Sy, box.Sz
+		if pl[0] > 0 {
+			x = box.Lx
+		}
+		if pl[1] > 0 {
+			y = box.Ly
+		}
+		if pl[2] > 0 {
+			z = box.Lz
+		}
+		if pl[0]*x+pl[1]*y+pl[2]*z+pl[3] < 0 {
+			return false
+		}
+	}
+	return true
 }
 
 // camera
@@ -291,6 +599,32 @@ func VP(at *lin.T, scr *lin.Q, vm *lin.M4) *lin.M4 {
 	return vm.TranslateTM(-at.Loc.X, -at.Loc.Y, -at.Loc.Z)
 }
 
+// ObliqueNear updates projection matrix pm so that its near plane is
+// the given view space plane, keeping points where a*x+b*y+c*z+d >= 0.
+// The far plane is moved to include the original view volume. See:
+//     http://www.terathon.com/lengyel/Lengyel-Oblique.pdf
+// The updated matrix pm is returned.
+func ObliqueNear(pm *lin.M4, a, b, c, d float64) *lin.M4 {
+	var ipm lin.M4
+	sign := func(v float64) float64 {
+		if v < 0 {
+			return -1
+		}
+		return 1
+	}
+
+	// q is the view volume corner opposite the plane.
+	q := &lin.V4{X: sign(a), Y: sign(b), Z: 1, W: 1}
+	q.MultvM(q, ipm.Inv(pm))
+	dot := a*q.X + b*q.Y + c*q.Z + d*q.W
+	if dot == 0 {
+		return pm
+	}
+	s := 2 / dot
+	pm.Xz, pm.Yz, pm.Zz, pm.Wz = a*s-pm.Xw, b*s-pm.Yw, c*s-pm.Zw, d*s-pm.Ww
+	return pm
+}
+
 // VO orthographic projection transform.
 func VO(pov *lin.T, scr *lin.Q, vm *lin.M4) *lin.M4 {
 	return vm.Set(lin.M4I).ScaleMS(1, 1, 0)
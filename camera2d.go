@@ -0,0 +1,204 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"math"
+
+	"github.com/gazed/vu/math/lin"
+)
+
+// Cam2D controls one or more orthographic cameras as a single 2D camera.
+// It is intended for side scrollers and top down games that render
+// using the orthographic overlay. Cam2D tracks a world location, zoom,
+// and rotation and applies them to each of its layer cameras. Each layer
+// camera has a parallax factor where 1 moves with the camera, values less
+// than 1 move slower (background), and values greater than 1 move faster
+// (foreground). The layer cameras are regular Pov cameras, ie:
+//     c2d := vu.NewCam2D()
+//     c2d.AddLayer(eng.Root().NewPov().NewCam(), 0.5) // background.
+//     c2d.AddLayer(eng.Root().NewPov().NewCam(), 1.0) // playfield.
+// Cam2D changes are applied to the layer cameras when Update is called.
+type Cam2D interface {
+	Location() (x, y float64)        // Get, or
+	SetLocation(x, y float64) Cam2D  // ...Set the camera center.
+	Zoom() (zoom float64)            // Get, or
+	SetZoom(zoom float64) Cam2D      // ...Set zoom. 1 is pixel per unit.
+	Rotation() (deg float64)         // Get, or
+	SetRotation(deg float64) Cam2D   // ...Set rotation about the Z axis.
+	SetClip(near, far float64) Cam2D // Depth range. Default 0, 100.
+
+	// AddLayer registers a camera to be controlled by this Cam2D.
+	// The camera is moved by the Cam2D location scaled by parallax.
+	AddLayer(cam Camera, parallax float64) Cam2D
+	SetParallax(cam Camera, parallax float64) // Change a layers factor.
+
+	// SetDeadZone sets the area, in pixels, around the camera center
+	// where a followed target can move without moving the camera.
+	SetDeadZone(w, h float64) Cam2D
+	Follow(x, y float64) // Move the camera to keep x,y in the dead zone.
+
+	// SetBounds limits the camera so the view does not show anything
+	// outside the given world area. Zero sized bounds disable clamping.
+	SetBounds(left, bottom, right, top float64) Cam2D
+
+	// Update applies the location, zoom, rotation, and parallax
+	// to all layer cameras for a window of size ww, wh.
+	Update(ww, wh int)
+
+	// World returns the world location of window pixel sx, sy
+	// for parallax layer 1 and a window of size ww, wh.
+	World(sx, sy, ww, wh int) (wx, wy float64)
+}
+
+// NewCam2D creates a 2D camera centered at the origin
+// with a zoom of 1 and no rotation.
+func NewCam2D() Cam2D { return newCam2D() }
+
+// Cam2D
+// =============================================================================
+// cam2D implements Cam2D.
+
+// cam2D implements Cam2D by driving the location, rotation,
+// and orthographic projection of its layer cameras.
+type cam2D struct {
+	x, y      float64 // Camera center in world units.
+	zoom      float64 // Pixels per world unit.
+	deg       float64 // Rotation about Z in degrees.
+	near, far float64 // Orthographic depth range.
+	dzw, dzh  float64 // Dead zone in pixels.
+	bounds    bool    // True if the camera is clamped.
+	bl, bb    float64 // Bounds left and bottom.
+	br, bt    float64 // Bounds right and top.
+	ww, wh    int     // Last window size from Update.
+	layers    []*layer2D
+}
+
+// layer2D associates a camera with a parallax factor.
+type layer2D struct {
+	cam      *camera // Camera controlled by cam2D.
+	parallax float64 // Location scaling.
+}
+
+// newCam2D allocates and initializes a 2D camera.
+func newCam2D() *cam2D {
+	return &cam2D{zoom: 1, near: 0, far: 100}
+}
+
+// Implement Cam2D.
+func (c *cam2D) Location() (x, y float64) { return c.x, c.y }
+func (c *cam2D) SetLocation(x, y float64) Cam2D {
+	c.x, c.y = x, y
+	c.clamp()
+	return c
+}
+func (c *cam2D) Zoom() (zoom float64) { return c.zoom }
+func (c *cam2D) SetZoom(zoom float64) Cam2D {
+	if zoom > 0 {
+		c.zoom = zoom
+		c.clamp()
+	}
+	return c
+}
+func (c *cam2D) Rotation() (deg float64) { return c.deg }
+func (c *cam2D) SetRotation(deg float64) Cam2D {
+	c.deg = deg
+	return c
+}
+func (c *cam2D) SetClip(near, far float64) Cam2D {
+	c.near, c.far = near, far
+	return c
+}
+func (c *cam2D) SetDeadZone(w, h float64) Cam2D {
+	c.dzw, c.dzh = math.Max(w, 0), math.Max(h, 0)
+	return c
+}
+func (c *cam2D) SetBounds(left, bottom, right, top float64) Cam2D {
+	c.bl, c.bb, c.br, c.bt = left, bottom, right, top
+	c.bounds = right > left && top > bottom
+	c.clamp()
+	return c
+}
+
+// AddLayer implements Cam2D. Cameras that are not engine
+// cameras are ignored.
+func (c *cam2D) AddLayer(cam Camera, parallax float64) Cam2D {
+	if lc, ok := cam.(*camera); ok {
+		lc.SetDepth(false) // 2D cameras ignore depth.
+		c.layers = append(c.layers, &layer2D{cam: lc, parallax: parallax})
+	}
+	return c
+}
+
+// SetParallax implements Cam2D.
+func (c *cam2D) SetParallax(cam Camera, parallax float64) {
+	for _, l := range c.layers {
+		if l.cam == cam {
+			l.parallax = parallax
+		}
+	}
+}
+
+// Follow moves the camera the minimum amount needed to keep
+// the target location x,y inside the dead zone.
+func (c *cam2D) Follow(x, y float64) {
+	hw, hh := c.dzw*0.5/c.zoom, c.dzh*0.5/c.zoom // half dead zone in world units.
+	switch {
+	case x > c.x+hw:
+		c.x = x - hw
+	case x < c.x-hw:
+		c.x = x + hw
+	}
+	switch {
+	case y > c.y+hh:
+		c.y = y - hh
+	case y < c.y-hh:
+		c.y = y + hh
+	}
+	c.clamp()
+}
+
+// clamp keeps the view inside the bounds. The view is centered
+// on the bounds along any axis where the bounds are smaller than
+// the view. Rotation is not considered.
+func (c *cam2D) clamp() {
+	if !c.bounds || c.ww <= 0 || c.wh <= 0 {
+		return
+	}
+	hw, hh := float64(c.ww)*0.5/c.zoom, float64(c.wh)*0.5/c.zoom
+	if c.br-c.bl < 2*hw {
+		c.x = (c.bl + c.br) * 0.5
+	} else {
+		c.x = lin.Clamp(c.x, c.bl+hw, c.br-hw)
+	}
+	if c.bt-c.bb < 2*hh {
+		c.y = (c.bb + c.bt) * 0.5
+	} else {
+		c.y = lin.Clamp(c.y, c.bb+hh, c.bt-hh)
+	}
+}
+
+// Update implements Cam2D. Each layer camera gets an orthographic
+// projection centered on the origin and sized by the zoom. The layer
+// camera location is the parallax scaled camera location.
+func (c *cam2D) Update(ww, wh int) {
+	c.ww, c.wh = ww, wh
+	c.clamp()
+	hw, hh := float64(ww)*0.5/c.zoom, float64(wh)*0.5/c.zoom
+	for _, l := range c.layers {
+		l.cam.at.Loc.SetS(c.x*l.parallax, c.y*l.parallax, 0)
+		l.cam.at.Rot.SetAa(0, 0, 1, lin.Rad(c.deg))
+		l.cam.ww, l.cam.wh = ww, wh
+		l.cam.SetView(VP)
+		l.cam.SetOrthographic(-hw, hw, -hh, hh, c.near, c.far)
+	}
+}
+
+// World implements Cam2D.
+func (c *cam2D) World(sx, sy, ww, wh int) (wx, wy float64) {
+	dx := (float64(sx) - float64(ww)*0.5) / c.zoom
+	dy := (float64(sy) - float64(wh)*0.5) / c.zoom
+	sin, cos := math.Sincos(lin.Rad(c.deg))
+	return c.x + dx*cos - dy*sin, c.y + dx*sin + dy*cos
+}
@@ -0,0 +1,59 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"testing"
+)
+
+// Check that the screen location of the 2D world point
+// matches the original screen point.
+func TestCam2DWorld(t *testing.T) {
+	cam, ww, wh := newCamera(), 800, 600
+	c2d := newCam2D()
+	c2d.AddLayer(cam, 1)
+	c2d.SetLocation(100, 50).SetZoom(2).SetRotation(30)
+	c2d.Update(ww, wh)
+	wx, wy := c2d.World(600, 200, ww, wh)
+	if sx, sy, visible := cam.Screen(wx, wy, -10); sx != 600 || sy != 200 || !visible {
+		t.Errorf("Expected visible 600 200, got %d %d %t", sx, sy, visible)
+	}
+}
+
+// Check that parallax layers move at their own rates.
+func TestCam2DParallax(t *testing.T) {
+	bg, fg := newCamera(), newCamera()
+	c2d := newCam2D().AddLayer(bg, 0.5).AddLayer(fg, 1)
+	c2d.SetLocation(100, -40).Update(800, 600)
+	if x, y, _ := bg.Location(); x != 50 || y != -20 {
+		t.Errorf("Expected background at 50 -20, got %f %f", x, y)
+	}
+	if x, y, _ := fg.Location(); x != 100 || y != -40 {
+		t.Errorf("Expected foreground at 100 -40, got %f %f", x, y)
+	}
+}
+
+// Check that following only moves the camera when the
+// target leaves the dead zone.
+func TestCam2DFollow(t *testing.T) {
+	c2d := newCam2D().SetDeadZone(100, 100)
+	c2d.Follow(40, -40)
+	if x, y := c2d.Location(); x != 0 || y != 0 {
+		t.Errorf("Expected no move, got %f %f", x, y)
+	}
+	c2d.Follow(80, -60)
+	if x, y := c2d.Location(); x != 30 || y != -10 {
+		t.Errorf("Expected 30 -10, got %f %f", x, y)
+	}
+}
+
+// Check that the view is kept within the bounds.
+func TestCam2DBounds(t *testing.T) {
+	c2d := newCam2D()
+	c2d.Update(200, 100)
+	c2d.SetBounds(0, 0, 1000, 80).SetLocation(-50, 500)
+	if x, y := c2d.Location(); x != 100 || y != 40 {
+		t.Errorf("Expected 100 40, got %f %f", x, y)
+	}
+}
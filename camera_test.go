@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/gazed/vu/math/lin"
+	"github.com/gazed/vu/physics"
 )
 
 // Test a ray cast with simple perspective and view inverses.
@@ -16,11 +17,29 @@ import (
 func TestRay(t *testing.T) {
 	cam, ww, wh := initScene()
 	cam.Move(0, 0, 15, cam.Lookat())
-	rx, ry, rz := cam.Ray(ww/2, wh/2, ww, wh) // center of screen.
+	ox, oy, oz, rx, ry, rz := cam.Ray(ww/2, wh/2) // center of screen.
 	ex, ey, ez := 0.0, 0.0, -1.0
 	if rx != ex || ry != ey || rz != ez {
 		t.Errorf("Expected %f %f %f got %f %f %f", ex, ey, ez, rx, ry, rz)
 	}
+	if cx, cy, cz := cam.Location(); !lin.Aeq(ox, cx) || !lin.Aeq(oy, cy) || !lin.Aeq(oz, cz) {
+		t.Errorf("Expected ray origin at the camera got %f %f %f", ox, oy, oz)
+	}
+}
+
+// Test that orthographic rays start under the mouse and are parallel.
+func TestOrthoRay(t *testing.T) {
+	cam := newCamera()
+	cam.ww, cam.wh = 1280, 800
+	cam.SetOrthographic(0, 1280, 0, 800, 0, 10)
+	cam.SetLocation(10, 20, 0)
+	ox, oy, oz, dx, dy, dz := cam.Ray(100, 200)
+	if !lin.Aeq(ox, 110) || !lin.Aeq(oy, 220) || !lin.Aeq(oz, 0) || dx != 0 || dy != 0 || dz != -1 {
+		t.Errorf("Expected ray from 110 220 0 along -Z got %f %f %f %f %f %f", ox, oy, oz, dx, dy, dz)
+	}
+	if _, _, _, dx, dy, dz = cam.Ray(-1, 0); dx != 0 || dy != 0 || dz != 0 {
+		t.Errorf("Expected no direction outside the window")
+	}
 }
 
 // Test a ray cast with perspective inverse and angled view inverse.
@@ -28,7 +47,7 @@ func TestAngledRay(t *testing.T) {
 	cam, ww, wh := initScene()
 	cam.AdjustPitch(45)
 	cam.SetLocation(0, -15, 15)
-	rx, ry, rz := cam.Ray(ww/2, wh/2, ww, wh) // center of screen.
+	_, _, _, rx, ry, rz := cam.Ray(ww/2, wh/2) // center of screen.
 	ex, ey, ez := 0.0, 0.7071068, -0.7071068
 	if !lin.Aeq(rx, ex) || !lin.Aeq(ry, ey) || !lin.Aeq(rz, ez) {
 		t.Errorf("Expected %f %f %f got %f %f %f", ex, ey, ez, rx, ry, rz)
@@ -41,8 +60,8 @@ func TestRayRatios(t *testing.T) {
 	cam.Move(0, 0, 15, cam.Lookat())
 
 	// shoot and check opposing corner rays.
-	blx, bly, _ := cam.Ray(0, 0, ww, wh)
-	trx, try, _ := cam.Ray(ww, wh, ww, wh)
+	_, _, _, blx, bly, _ := cam.Ray(0, 0)
+	_, _, _, trx, try, _ := cam.Ray(ww, wh)
 	gotRatio := (try - bly) / (trx - blx)
 	expectedRatio := float64(wh) / float64(ww)
 	if expectedRatio != gotRatio {
@@ -112,7 +131,7 @@ func TestRayWithSpin(t *testing.T) {
 	plane := NewPlane(0, 0, -1)
 
 	ww, wh := 1280, 800
-	rx, ry, rz := cam.Ray(0, 0, ww, wh)
+	_, _, _, rx, ry, rz := cam.Ray(0, 0)
 	ray := NewRay(rx, ry, rz)
 	ray.World().SetLoc(cx, cy, cz)
 	hit, hx, hy, hz := Cast(ray, plane)
@@ -121,7 +140,7 @@ func TestRayWithSpin(t *testing.T) {
 		t.Errorf("Hit %t %f %f %f, expected %f %f %f", hit, hx, hy, hz, ex, ey, ez)
 	}
 
-	rx, ry, rz = cam.Ray(0, wh, ww, wh)
+	_, _, _, rx, ry, rz = cam.Ray(0, wh)
 	ray = NewRay(rx, ry, rz)
 	ray.World().SetLoc(cx, cy, cz)
 	hit, hx, hy, hz = Cast(ray, plane)
@@ -130,7 +149,7 @@ func TestRayWithSpin(t *testing.T) {
 		t.Errorf("Hit %t %f %f %f, expected %f %f %f", hit, hx, hy, hz, ex, ey, ez)
 	}
 
-	rx, ry, rz = cam.Ray(ww, 0, ww, wh)
+	_, _, _, rx, ry, rz = cam.Ray(ww, 0)
 	ray = NewRay(rx, ry, rz)
 	ray.World().SetLoc(cx, cy, cz)
 	hit, hx, hy, hz = Cast(ray, plane)
@@ -139,7 +158,7 @@ func TestRayWithSpin(t *testing.T) {
 		t.Errorf("Hit %t %f %f %f, expected %f %f %f", hit, hx, hy, hz, ex, ey, ez)
 	}
 
-	rx, ry, rz = cam.Ray(ww, wh, ww, wh)
+	_, _, _, rx, ry, rz = cam.Ray(ww, wh)
 	ray = NewRay(rx, ry, rz)
 	ray.World().SetLoc(cx, cy, cz)
 	hit, hx, hy, hz = Cast(ray, plane)
@@ -156,8 +175,116 @@ func TestScreen(t *testing.T) {
 
 	// center of the world should give the center of the screen.
 	px, py, pz := 0.0, 0.0, 0.0
-	if x, y := cam.Screen(px, py, pz, 1280, 800); x != 640 || y != 400 {
-		t.Errorf("got point %d %d, expected 640, 400", x, y)
+	if x, y, visible := cam.Screen(px, py, pz); x != 640 || y != 400 || !visible {
+		t.Errorf("got point %d %d %t, expected 640, 400 visible", x, y, visible)
+	}
+
+	// off screen points are not visible, but have screen coordinates
+	// when they are in front of the camera.
+	if x, _, visible := cam.Screen(100, 0, 0); x <= 1280 || visible {
+		t.Errorf("got point %d %t, expected right of the screen and not visible", x, visible)
+	}
+	if _, _, visible := cam.Screen(0, 0, 20); visible {
+		t.Errorf("expected point behind the camera to not be visible")
+	}
+}
+
+// Check that picking and screen locations are within the viewport.
+func TestViewport(t *testing.T) {
+	cam, _, _ := initScene()
+	cam.SetLocation(0, 0, 14)
+	cam.SetViewport(0.5, 0, 0.5, 1) // right half.
+	if x, y, visible := cam.Screen(0, 0, 0); x != 960 || y != 400 || !visible {
+		t.Errorf("got point %d %d %t, expected 960, 400 visible", x, y, visible)
+	}
+	if _, _, _, dx, dy, dz := cam.Ray(960, 400); !lin.Aeq(dx, 0) || !lin.Aeq(dy, 0) || !lin.Aeq(dz, -1) {
+		t.Errorf("Expected viewport center ray along -Z got %f %f %f", dx, dy, dz)
+	}
+	if _, _, _, dx, _, _ := cam.Ray(100, 400); dx != 0 {
+		t.Errorf("Expected no ray outside the viewport")
+	}
+}
+
+// Check the frustum planes and containment tests against a camera
+// at 0,0,14 looking down -Z with a near plane of 0.1 and far of 500.
+func TestFrustum(t *testing.T) {
+	cam, _, _ := initScene()
+	cam.SetLocation(0, 0, 14)
+	if near := cam.Frustum()[4]; !lin.Aeq(near[2], -1) || !lin.Aeq(near[3], 13.9) {
+		t.Errorf("Expected near plane facing -Z, got %v", near)
+	}
+	if !cam.ContainsPoint(0, 0, 0) || cam.ContainsPoint(0, 0, 20) || cam.ContainsPoint(0, 0, -500) {
+		t.Errorf("Expected only the point in front to be contained")
+	}
+	if cam.ContainsPoint(10, 0, 0) || !cam.ContainsSphere(10, 0, 0, 8) {
+		t.Errorf("Expected sphere partly in view to be contained")
+	}
+	box := &physics.Abox{Sx: -20, Sy: -1, Sz: -1, Lx: -10, Ly: 1, Lz: 1}
+	if cam.ContainsBox(box) {
+		t.Errorf("Expected box left of the view to be outside")
+	}
+	box.Lx = -1
+	if !cam.ContainsBox(box) {
+		t.Errorf("Expected box overlapping the view to be inside")
+	}
+}
+
+// Check that a custom projection matches the same perspective projection
+// and that a clip plane replaces the near plane.
+func TestProjection(t *testing.T) {
+	cam, _, _ := initScene()
+	_, _, _, dx, dy, dz := cam.Ray(100, 200)
+	cam.SetProjection(lin.NewM4().Persp(30, 1280.0/800.0, 0.1, 500))
+	if _, _, _, x, y, z := cam.Ray(100, 200); !lin.Aeq(x, dx) || !lin.Aeq(y, dy) || !lin.Aeq(z, dz) {
+		t.Errorf("Expected same ray, got %f %f %f want %f %f %f", x, y, z, dx, dy, dz)
+	}
+
+	// only draw beyond z = -5. The camera is at the origin looking down -Z.
+	cam.SetClipPlane(0, 0, -1, -5)
+	if _, _, visible := cam.Screen(0, 0, -3); visible {
+		t.Errorf("Expected point in front of the clip plane to be hidden")
+	}
+	if _, _, visible := cam.Screen(0, 0, -10); !visible {
+		t.Errorf("Expected point beyond the clip plane to be visible")
+	}
+	if near := cam.Frustum()[4]; !lin.Aeq(near[2], -1) || !lin.Aeq(near[3], -5) {
+		t.Errorf("Expected clip plane as the near plane, got %v", near)
+	}
+	cam.SetClipPlane(0, 0, 0, 0)
+	if _, _, visible := cam.Screen(0, 0, -3); !visible {
+		t.Errorf("Expected point visible without the clip plane")
+	}
+}
+
+// Check that a world sized ortho camera picks and culls as it zooms.
+func TestOrthoZoom(t *testing.T) {
+	cam, _, _ := initScene()
+	cam.SetOrthoSize(10, 0.1, 100) // 16 by 10 world units.
+	cam.SetLocation(0, 0, 20)
+	if ox, oy, _, _, _, dz := cam.Ray(1280, 800); !lin.Aeq(ox, 8) || !lin.Aeq(oy, 5) || !lin.Aeq(dz, -1) {
+		t.Errorf("Expected ray from the top right corner, got %f %f %f", ox, oy, dz)
+	}
+	cull := NewFrustumCull(0.5)
+	if cull.Culled(cam, 7, 0, 0) || !cull.Culled(cam, 9, 0, 0) {
+		t.Errorf("Expected culling at the view edge")
+	}
+
+	// zoom in on the top right corner keeping it in place.
+	cam.ZoomAt(1280, 800, 2)
+	if ox, oy, _, _, _, _ := cam.Ray(1280, 800); !lin.Aeq(ox, 8) || !lin.Aeq(oy, 5) {
+		t.Errorf("Expected same corner after zoom, got %f %f", ox, oy)
+	}
+	if x, y, _ := cam.Location(); !lin.Aeq(x, 4) || !lin.Aeq(y, 2.5) {
+		t.Errorf("Expected camera moved toward the corner, got %f %f", x, y)
+	}
+	if !cull.Culled(cam, -1, 0, 0) {
+		t.Errorf("Expected zoomed view to cull the old center")
+	}
+
+	// resize keeps the height and changes the width.
+	cam.setSize(800, 800)
+	if l, r := cam.proj[0], cam.proj[1]; !lin.Aeq(l, -2.5) || !lin.Aeq(r, 2.5) {
+		t.Errorf("Expected square view after resize, got %f %f", l, r)
 	}
 }
 
@@ -168,6 +295,7 @@ func TestScreen(t *testing.T) {
 func initScene() (c *camera, ww, wh int) {
 	c = newCamera()
 	ww, wh = 1280, 800
+	c.ww, c.wh = ww, wh
 	fov, ratio, near, far := 30.0, float64(ww)/float64(wh), 0.1, 500.0
 	c.SetPerspective(fov, ratio, near, far)
 	return
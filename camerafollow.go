@@ -0,0 +1,160 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"math"
+
+	"github.com/gazed/vu/math/lin"
+)
+
+// Follow is a third person camera controller that keeps a camera behind
+// a target Pov, ie: a player character, and looking at it, ie:
+//     follow := vu.NewFollow(cam).SetTarget(player).SetOffset(0, 2, 6)
+//     follow.SetCollision(eng, sceneryLayers, 0.3)
+//     ...
+//     follow.Update(in.Dt) // in App.Update after moving the player.
+// The offset is in the target's frame so the camera swings around as
+// the target turns. Movement is smoothed so that the camera lags a
+// little behind the target. With collision on, a ray is cast from the
+// target to the camera and the camera is pulled in, without smoothing,
+// in front of anything in the way. Only physics bodies are checked so
+// the target's own body should not be in the collision layers.
+type Follow interface {
+	SetTarget(p Pov) Follow           // Pov to follow.
+	SetOffset(x, y, z float64) Follow // Camera location. Default 0, 2, 6.
+	SetAim(x, y, z float64) Follow    // Point looked at. Default 0, 1, 0.
+
+	// SetSmoothing sets the seconds to close most of the distance to the
+	// wanted location and view direction. 0 is no smoothing.
+	// Defaults are 0.2 and 0.1.
+	SetSmoothing(move, turn float64) Follow
+
+	// SetCollision keeps the camera radius in front of bodies with
+	// layers in mask between the camera and the aim point.
+	// A mask of 0 turns off collision. Default off.
+	SetCollision(eng Eng, mask uint32, radius float64) Follow
+
+	// Update moves the camera for the elapsed seconds.
+	Update(dt float64)
+}
+
+// NewFollow creates a follow controller for the given camera.
+// Returns nil for cameras that are not engine cameras.
+func NewFollow(cam Camera) Follow {
+	if c, ok := cam.(*camera); ok && c != nil {
+		return newFollow(c)
+	}
+	return nil
+}
+
+// Follow
+// =============================================================================
+// follow implements Follow.
+
+// follow implements Follow by setting the camera location, pitch, and yaw.
+type follow struct {
+	cam        *camera
+	target     Pov
+	off        lin.V3  // Camera offset in the target frame.
+	aim        lin.V3  // Look at offset in the target frame.
+	move, turn float64 // Smoothing times in seconds.
+	eng        Eng     // Optional collision checking.
+	mask       uint32  // Collision layers.
+	radius     float64 // Distance kept from collisions.
+	placed     bool    // False until the first update.
+}
+
+// newFollow allocates a follow controller.
+func newFollow(c *camera) *follow {
+	f := &follow{cam: c, move: 0.2, turn: 0.1}
+	f.off.SetS(0, 2, 6)
+	f.aim.SetS(0, 1, 0)
+	return f
+}
+
+// Implement Follow.
+func (f *follow) SetTarget(p Pov) Follow {
+	f.target, f.placed = p, false
+	return f
+}
+func (f *follow) SetOffset(x, y, z float64) Follow {
+	f.off.SetS(x, y, z)
+	return f
+}
+func (f *follow) SetAim(x, y, z float64) Follow {
+	f.aim.SetS(x, y, z)
+	return f
+}
+func (f *follow) SetSmoothing(move, turn float64) Follow {
+	f.move, f.turn = math.Max(move, 0), math.Max(turn, 0)
+	return f
+}
+func (f *follow) SetCollision(eng Eng, mask uint32, radius float64) Follow {
+	f.eng, f.mask, f.radius = eng, mask, radius
+	return f
+}
+
+// smooth returns the fraction of the remaining change to apply
+// for the elapsed time and smoothing time.
+func smooth(dt, seconds float64) float64 {
+	if seconds <= 0 || dt <= 0 {
+		return 1
+	}
+	return 1 - math.Exp(-dt/seconds)
+}
+
+// Update moves the camera toward the offset location and turns it
+// toward the aim point. The first update places the camera directly.
+func (f *follow) Update(dt float64) {
+	if f.target == nil {
+		return
+	}
+	c, rot := f.cam, f.target.WorldRotation()
+	tx, ty, tz := f.target.WorldLocation()
+	ax, ay, az := lin.MultSQ(f.aim.X, f.aim.Y, f.aim.Z, rot)
+	ax, ay, az = tx+ax, ty+ay, tz+az
+	ox, oy, oz := lin.MultSQ(f.off.X, f.off.Y, f.off.Z, rot)
+	wx, wy, wz := tx+ox, ty+oy, tz+oz
+
+	// move toward the wanted location then check for collisions.
+	mf, tf := smooth(dt, f.move), smooth(dt, f.turn)
+	if !f.placed {
+		mf, tf, f.placed = 1, 1, true
+	}
+	l := c.at.Loc
+	l.SetS(lerp(l.X, wx, mf), lerp(l.Y, wy, mf), lerp(l.Z, wz, mf))
+	if f.eng != nil && f.mask != 0 {
+		f.collide(ax, ay, az)
+	}
+
+	// turn toward the aim point. The camera looks along -Z.
+	dx, dy, dz := ax-l.X, ay-l.Y, az-l.Z
+	if yaw, pitch, ok := lookAngles(dx, dy, dz); ok {
+		turn := math.Mod(yaw-c.ydeg+540, 360) - 180 // shortest way around.
+		c.SetYaw(c.ydeg + turn*tf)
+		c.SetPitch(lerp(c.xdeg, pitch, tf))
+	}
+	c.updateTransform()
+}
+
+// collide pulls the camera toward the aim point if there are bodies
+// between the aim point and the camera.
+func (f *follow) collide(ax, ay, az float64) {
+	l := f.cam.at.Loc
+	dx, dy, dz := l.X-ax, l.Y-ay, l.Z-az
+	dist := math.Sqrt(dx*dx + dy*dy + dz*dz)
+	if dist <= 0 {
+		return
+	}
+	ray := NewRay(dx, dy, dz)
+	ray.World().SetLoc(ax, ay, az)
+	if p, hx, hy, hz := f.eng.Raycast(ray, f.mask); p != nil {
+		hx, hy, hz = hx-ax, hy-ay, hz-az
+		if hit := math.Sqrt(hx*hx + hy*hy + hz*hz); hit < dist+f.radius {
+			scale := math.Max(hit-f.radius, 0) / dist
+			l.SetS(ax+dx*scale, ay+dy*scale, az+dz*scale)
+		}
+	}
+}
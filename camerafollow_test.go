@@ -0,0 +1,43 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gazed/vu/math/lin"
+)
+
+// Check that the follow camera swings behind the target, looks at
+// the target, and is pulled in front of blocking bodies.
+func TestFollow(t *testing.T) {
+	eng := newEngine(nil)
+	defer eng.Shutdown()
+	player := eng.Root().NewPov().SetLocation(1, 0, 1)
+	cam, _, _ := initScene()
+	f := NewFollow(cam).SetTarget(player).SetOffset(0, 0, 6).SetAim(0, 0, 0)
+	f.Update(0.1) // first update is not smoothed.
+	if x, y, z := cam.Location(); !lin.Aeq(x, 1) || !lin.Aeq(y, 0) || !lin.Aeq(z, 7) {
+		t.Errorf("Expected camera behind player, got %f %f %f", x, y, z)
+	}
+
+	// turn the player around. The camera moves part way.
+	player.SetRotation(lin.NewQ().SetAa(0, 1, 0, lin.Rad(180)))
+	f.Update(0.1)
+	if _, _, z := cam.Location(); z >= 7 || z <= -5 {
+		t.Errorf("Expected camera part way around, got %f", z)
+	}
+	f.SetSmoothing(0, 0).Update(0.1)
+	if _, _, z := cam.Location(); !lin.Aeq(z, -5) || !lin.Aeq(math.Abs(cam.Yaw()), 180) {
+		t.Errorf("Expected camera behind turned player, got %f %f", z, cam.Yaw())
+	}
+
+	// add a wall between the player and the camera.
+	eng.Root().NewPov().SetLocation(0, 0, -2).SetLayers(2).NewBody(NewPlane(0, 0, -1))
+	f.SetCollision(eng, 2, 0.5).Update(0.1)
+	if _, _, z := cam.Location(); !lin.Aeq(z, -1.5) {
+		t.Errorf("Expected camera pulled in front of the wall, got %f", z)
+	}
+}
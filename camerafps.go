@@ -0,0 +1,178 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"math"
+
+	"github.com/gazed/vu/math/lin"
+)
+
+// FPS is a first person camera controller. The mouse turns the camera
+// and the movement keys walk along the XZ plane in the direction the
+// camera is facing, ie:
+//     fps := vu.NewFPS(cam).SetGround(vu.RayGround(eng, 1, 0.5))
+//     ...
+//     fps.Update(in) // in App.Update.
+// Without a ground function the camera flies at its current height.
+// With a ground function the camera falls with gravity and is kept
+// the eye height above the ground.
+type FPS interface {
+	SetKeys(forward, back, left, right int) FPS // Default W, S, A, D.
+	SetLook(key int) FPS                        // Mouse look while key is down. Default 0 is always.
+
+	// SetSpeeds sets the movement in units per second and the turn in
+	// degrees per pixel of mouse movement. Defaults are 5 and 0.2.
+	SetSpeeds(move, look float64) FPS
+	SetPitchLimits(min, max float64) FPS // Look up/down. Default -89, 89.
+
+	// SetGround sets the function used to find the ground height.
+	// A nil ground turns off gravity.
+	SetGround(ground GroundFunc) FPS
+	SetEyeHeight(height float64) FPS // Camera above ground. Default 1.7.
+	SetGravity(g float64) FPS        // Fall acceleration. Default 9.8.
+	Grounded() bool                  // True if on the ground.
+	Jump(speed float64)              // Upward speed if on the ground.
+
+	// Update turns and moves the camera using the input.
+	Update(in *Input)
+}
+
+// GroundFunc returns the ground height below the world location x,z
+// for feet at height y. Returns false if there is no ground.
+type GroundFunc func(x, y, z float64) (ground float64, ok bool)
+
+// RayGround returns a GroundFunc that casts a ray down from step units
+// above the feet against the physics bodies in the given layers. The
+// step allows walking up small ledges.
+func RayGround(eng Eng, mask uint32, step float64) GroundFunc {
+	ray := NewRay(0, -1, 0)
+	return func(x, y, z float64) (ground float64, ok bool) {
+		ray.World().SetLoc(x, y+step, z)
+		if p, _, hy, _ := eng.Raycast(ray, mask); p != nil {
+			return hy, true
+		}
+		return 0, false
+	}
+}
+
+// NewFPS creates a first person controller for the given camera.
+// Returns nil for cameras that are not engine cameras.
+func NewFPS(cam Camera) FPS {
+	if c, ok := cam.(*camera); ok && c != nil {
+		return newFPS(c)
+	}
+	return nil
+}
+
+// FPS
+// =============================================================================
+// fps implements FPS.
+
+// fps implements FPS by adjusting the camera pitch, yaw, and location.
+type fps struct {
+	cam        *camera
+	keys       [4]int     // Forward, back, left, right.
+	look       int        // Mouse look key, or 0.
+	move, turn float64    // Movement and turn speeds.
+	pmin, pmax float64    // Pitch limits.
+	ground     GroundFunc // Optional ground height.
+	eye        float64    // Camera height above ground.
+	gravity    float64    // Fall acceleration.
+	vy         float64    // Vertical speed.
+	grounded   bool       // True if on the ground.
+	mx, my     int        // Last mouse location while looking.
+	looking    bool       // True if the last update was looking.
+}
+
+// newFPS allocates a first person controller.
+func newFPS(c *camera) *fps {
+	f := &fps{cam: c, keys: [4]int{KW, KS, KA, KD}, move: 5, turn: 0.2}
+	f.pmin, f.pmax, f.eye, f.gravity = -89, 89, 1.7, 9.8
+	return f
+}
+
+// Implement FPS.
+func (f *fps) SetKeys(forward, back, left, right int) FPS {
+	f.keys = [4]int{forward, back, left, right}
+	return f
+}
+func (f *fps) SetLook(key int) FPS {
+	f.look = key
+	return f
+}
+func (f *fps) SetSpeeds(move, look float64) FPS {
+	f.move, f.turn = move, look
+	return f
+}
+func (f *fps) SetPitchLimits(min, max float64) FPS {
+	if min <= max {
+		f.pmin, f.pmax = min, max
+	}
+	return f
+}
+func (f *fps) SetGround(ground GroundFunc) FPS {
+	f.ground, f.vy, f.grounded = ground, 0, false
+	return f
+}
+func (f *fps) SetEyeHeight(height float64) FPS {
+	f.eye = height
+	return f
+}
+func (f *fps) SetGravity(g float64) FPS {
+	f.gravity = g
+	return f
+}
+func (f *fps) Grounded() bool { return f.grounded }
+func (f *fps) Jump(speed float64) {
+	if f.grounded {
+		f.vy, f.grounded = speed, false
+	}
+}
+
+// Update applies the mouse look, then the movement, and then gravity.
+// A captured mouse uses the relative mouse motion so that turning is
+// not stopped by the window edges.
+func (f *fps) Update(in *Input) {
+	if in == nil {
+		return
+	}
+	c := f.cam
+	_, held := in.Down[f.look]
+	if looking := f.look == 0 || held; looking && f.looking {
+		dx, dy := in.Mx-f.mx, in.My-f.my
+		if in.Capture {
+			dx, dy = in.Dx, in.Dy
+		}
+		c.SetYaw(c.ydeg - float64(dx)*f.turn)
+		c.SetPitch(lin.Clamp(c.xdeg+float64(dy)*f.turn, f.pmin, f.pmax))
+	}
+	f.looking, f.mx, f.my = f.look == 0 || held, in.Mx, in.My
+
+	// walk along XZ. Diagonal movement is not faster.
+	dx, dz := 0.0, 0.0
+	for cnt, dir := range [4][2]float64{{0, -1}, {0, 1}, {-1, 0}, {1, 0}} {
+		if _, down := in.Down[f.keys[cnt]]; down {
+			dx, dz = dx+dir[0], dz+dir[1]
+		}
+	}
+	if l := math.Sqrt(dx*dx + dz*dz); l > 0 {
+		step := f.move * in.Dt / l
+		x, _, z := lin.MultSQ(dx*step, 0, dz*step, c.Lookxz())
+		c.at.Loc.X, c.at.Loc.Z = c.at.Loc.X+x, c.at.Loc.Z+z
+	}
+
+	// fall with gravity and stand on the ground.
+	if f.ground != nil {
+		last := c.at.Loc.Y - f.eye
+		f.vy -= f.gravity * in.Dt
+		feet := last + f.vy*in.Dt
+		f.grounded = false // check from the highest feet to not fall through.
+		if gy, ok := f.ground(c.at.Loc.X, math.Max(last, feet), c.at.Loc.Z); ok && feet <= gy {
+			feet, f.vy, f.grounded = gy, 0, true
+		}
+		c.at.Loc.Y = feet + f.eye
+	}
+	c.updateTransform()
+}
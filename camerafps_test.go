@@ -0,0 +1,62 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"testing"
+
+	"github.com/gazed/vu/math/lin"
+)
+
+// Check that the FPS camera walks where it is facing
+// and turns with the mouse.
+func TestFPS(t *testing.T) {
+	cam, _, _ := initScene()
+	f := NewFPS(cam).SetSpeeds(2, 0.5)
+	in := &Input{Down: map[int]int{KW: 1, KD: 1}, Mx: 100, My: 100, Dt: 1}
+	f.Update(in)
+	if x, y, z := cam.Location(); !lin.Aeq(x, 1.4142136) || y != 0 || !lin.Aeq(z, -1.4142136) {
+		t.Errorf("Expected diagonal move, got %f %f %f", x, y, z)
+	}
+	in.Down, in.Mx, in.My = map[int]int{}, 80, 300
+	f.Update(in)
+	if cam.Yaw() != 10 || cam.Pitch() != 89 {
+		t.Errorf("Expected turn left and clamped look up, got %f %f", cam.Yaw(), cam.Pitch())
+	}
+}
+
+// Check that the FPS camera falls to, and stands on, the ground.
+func TestFPSGround(t *testing.T) {
+	eng := newEngine(nil)
+	defer eng.Shutdown()
+	eng.Root().NewPov().SetLocation(0, 1, 0).NewBody(NewPlane(0, -1, 0)) // faces the ray.
+	cam := eng.Root().NewPov().NewCam()
+	cam.SetLocation(3, 10, 3)
+	f := NewFPS(cam).SetGround(RayGround(eng, ^uint32(0), 0.5)).SetEyeHeight(2)
+	in := &Input{Down: map[int]int{}, Dt: 0.1}
+	for cnt := 0; cnt < 50 && !f.Grounded(); cnt++ {
+		f.Update(in)
+	}
+	if _, y, _ := cam.Location(); !f.Grounded() || !lin.Aeq(y, 3) {
+		t.Errorf("Expected to stand on the ground, got %f %t", y, f.Grounded())
+	}
+	f.Jump(5)
+	f.Update(in)
+	if _, y, _ := cam.Location(); f.Grounded() || y <= 3 {
+		t.Errorf("Expected to jump, got %f", y)
+	}
+}
+
+// Check that a captured mouse turns using the relative mouse motion.
+func TestFPSCapture(t *testing.T) {
+	cam, _, _ := initScene()
+	f := NewFPS(cam).SetSpeeds(2, 0.5)
+	in := &Input{Down: map[int]int{}, Mx: 100, My: 100, Capture: true}
+	f.Update(in)
+	in.Dx, in.Dy = -20, 10 // the cursor does not move while captured.
+	f.Update(in)
+	if cam.Yaw() != 10 || cam.Pitch() != 5 {
+		t.Errorf("Expected relative turn, got %f %f", cam.Yaw(), cam.Pitch())
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"math"
+
+	"github.com/gazed/vu/math/lin"
+	"github.com/gazed/vu/render"
+)
+
+// Lens describes a camera using real camera settings so that camera
+// setups can be matched to photographs or to cameras from modelling
+// tools, ie:
+//     cam.SetLens(vu.Lens{Focal: 35, SensorW: 36, SensorH: 24,
+//         FStop: 2.8, Shutter: 1.0 / 125, ISO: 100, Focus: 4,
+//         Near: 0.1, Far: 500})
+// The vertical field of view is found from the focal length and the
+// sensor height. The aspect ratio is the sensor width over height.
+// World units are expected to be meters.
+//
+// The exposure and depth of field values are given to shaders that
+// declare the uniforms:
+//     uniform float exposure; // multiply linear scene colors.
+//     uniform vec3  dof;      // focus, near and far sharp distances.
+// Shaders get an exposure of 1 and a zero dof for cameras without a lens.
+// The dof is also zero when the Quality PostEffects are off.
+type Lens struct {
+	Focal   float64 // Focal length in mm, ie: 50.
+	SensorW float64 // Sensor width in mm, ie: 36 for full frame.
+	SensorH float64 // Sensor height in mm, ie: 24 for full frame.
+	FStop   float64 // Aperture f-number, ie: 2.8.
+	Shutter float64 // Exposure time in seconds, ie: 1/125.
+	ISO     float64 // Sensor sensitivity, ie: 100.
+	Focus   float64 // Focus distance in meters.
+	CoC     float64 // Circle of confusion in mm. 0 uses sensor diagonal/1500.
+	Near    float64 // Near clip distance.
+	Far     float64 // Far clip distance.
+}
+
+// FOV returns the vertical field of view in degrees.
+func (l Lens) FOV() float64 {
+	if l.Focal <= 0 {
+		return 0
+	}
+	return 2 * lin.Deg(math.Atan(l.SensorH/(2*l.Focal)))
+}
+
+// Exposure returns the multiplier for linear scene colors that matches
+// the aperture, shutter, and ISO. The exposure value (EV100) is
+//     log2(fstop*fstop/shutter * 100/ISO)
+// and the multiplier is the amount that keeps the brightest, non-clipped
+// scene value at 1, see:
+//     https://seblagarde.files.wordpress.com/2015/07/course_notes_moving_frostbite_to_pbr_v32.pdf
+func (l Lens) Exposure() float64 {
+	if l.FStop <= 0 || l.Shutter <= 0 || l.ISO <= 0 {
+		return 1
+	}
+	ev100 := math.Log2(l.FStop * l.FStop / l.Shutter * 100 / l.ISO)
+	return 1 / (1.2 * math.Pow(2, ev100))
+}
+
+// DepthOfField returns the nearest and furthest distances, in meters,
+// that appear sharp when focused at the focus distance. Far is
+// infinite when focused at or beyond the hyperfocal distance.
+func (l Lens) DepthOfField() (near, far float64) {
+	coc := l.CoC
+	if coc <= 0 {
+		coc = math.Sqrt(l.SensorW*l.SensorW+l.SensorH*l.SensorH) / 1500
+	}
+	if l.Focal <= 0 || l.FStop <= 0 || coc <= 0 || l.Focus <= 0 {
+		return 0, math.Inf(1) // no depth of field.
+	}
+	f, s := l.Focal/1000, l.Focus   // meters.
+	h := f*f/(l.FStop*coc/1000) + f // hyperfocal distance.
+	near = s * (h - f) / (h + s - 2*f)
+	if s >= h {
+		return near, math.Inf(1)
+	}
+	return near, s * (h - f) / (h - s)
+}
+
+// Implement Camera.
+func (c *camera) SetLens(l Lens) {
+	ratio := 1.0
+	if l.SensorH > 0 {
+		ratio = l.SensorW / l.SensorH
+	}
+	c.SetPerspective(l.FOV(), ratio, l.Near, l.Far)
+	c.lens = &l
+}
+func (c *camera) Lens() (l Lens, ok bool) {
+	if c.lens == nil {
+		return Lens{}, false
+	}
+	return *c.lens, true
+}
+
+// lensUniforms sets the lens shader uniforms, if the shader uses them.
+// The depth of field is only given when post effects are on.
+func (c *camera) lensUniforms(d render.Draw, uniforms map[string]int32, post bool) {
+	if _, ok := uniforms["exposure"]; ok {
+		exposure := 1.0
+		if c.lens != nil {
+			exposure = c.lens.Exposure()
+		}
+		d.SetFloats("exposure", float32(exposure))
+	}
+	if _, ok := uniforms["dof"]; ok {
+		if c.lens == nil || !post {
+			d.SetFloats("dof", 0, 0, 0)
+			return
+		}
+		near, far := c.lens.DepthOfField()
+		d.SetFloats("dof", float32(c.lens.Focus), float32(near), float32(far))
+	}
+}
@@ -0,0 +1,38 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"math"
+	"testing"
+)
+
+// Check the values derived from physical camera settings.
+func TestLens(t *testing.T) {
+	l := Lens{Focal: 50, SensorW: 36, SensorH: 24, FStop: 8, CoC: 0.03, Focus: 5, Near: 0.1, Far: 100}
+	if fov := l.FOV(); math.Abs(fov-26.9915) > 0.001 {
+		t.Errorf("Expected 50mm field of view, got %f", fov)
+	}
+	if near, far := l.DepthOfField(); math.Abs(near-3.3894) > 0.001 || math.Abs(far-9.5274) > 0.001 {
+		t.Errorf("Expected depth of field, got %f %f", near, far)
+	}
+	l.Focus = 20
+	if _, far := l.DepthOfField(); !math.IsInf(far, 1) {
+		t.Errorf("Expected infinite far focus beyond the hyperfocal distance")
+	}
+	l.FStop, l.Shutter, l.ISO = 1, 1, 100 // EV100 of 0.
+	if e := l.Exposure(); math.Abs(e-1/1.2) > 1e-9 {
+		t.Errorf("Expected exposure for EV 0, got %f", e)
+	}
+
+	// the lens sets the camera projection.
+	cam, _, _ := initScene()
+	cam.SetLens(l)
+	if got, ok := cam.Lens(); !ok || got != l || cam.proj[0] != l.FOV() || cam.proj[1] != 1.5 {
+		t.Errorf("Expected lens perspective, got %v", cam.proj)
+	}
+	if cam.SetPerspective(30, 1, 0.1, 50); cam.lens != nil {
+		t.Errorf("Expected perspective to replace the lens")
+	}
+}
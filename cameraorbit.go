@@ -0,0 +1,177 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"math"
+
+	"github.com/gazed/vu/math/lin"
+)
+
+// Orbit moves a camera around a target point. It is intended for model
+// viewers and editors where the camera looks at, and circles, something
+// of interest. Orbit tracks the target, the distance from the target,
+// and the yaw and pitch angles around the target, ie:
+//     orbit := vu.NewOrbit(cam).SetTarget(0, 1, 0).SetDistance(10)
+//     ...
+//     orbit.Update(in) // in App.Update.
+// Update uses the mouse: left drag rotates, right drag pans, and the
+// scroll wheel zooms. Applications with their own controls can call
+// Rotate, Pan, and Zoom instead. Changes are applied to the camera
+// immediately.
+type Orbit interface {
+	Target() (x, y, z float64)          // Get, or
+	SetTarget(x, y, z float64) Orbit    // ...Set the point being viewed.
+	Distance() float64                  // Get, or
+	SetDistance(d float64) Orbit        // ...Set the distance to the target.
+	Angles() (yaw, pitch float64)       // Get, or
+	SetAngles(yaw, pitch float64) Orbit // ...Set degrees around and above.
+
+	// Limits clamp the pitch, in degrees, and the distance.
+	// Defaults are -89 to 89 degrees, and 0.5 to 1000 units.
+	SetPitchLimits(min, max float64) Orbit
+	SetZoomLimits(min, max float64) Orbit
+
+	// SetSpeeds sets the degrees rotated per pixel dragged, the pan
+	// per pixel dragged as a fraction of the distance, and the fraction
+	// of the distance zoomed per scroll step.
+	// Defaults are 0.25, 0.002, and 0.1.
+	SetSpeeds(rotate, pan, zoom float64) Orbit
+
+	Rotate(yaw, pitch float64) // Add degrees to the angles.
+	Pan(dx, dy float64)        // Move the target by screen pixels.
+	Zoom(steps float64)        // Positive moves closer.
+	Update(in *Input)          // Apply mouse drag and scroll input.
+}
+
+// NewOrbit creates an orbit controller for the given camera looking
+// at the origin from 10 units away. Returns nil for cameras that
+// are not engine cameras.
+func NewOrbit(cam Camera) Orbit {
+	if c, ok := cam.(*camera); ok && c != nil {
+		return newOrbit(c)
+	}
+	return nil
+}
+
+// Orbit
+// =============================================================================
+// orbit implements Orbit.
+
+// orbit implements Orbit by setting the camera pitch, yaw, and location.
+type orbit struct {
+	cam        *camera
+	tx, ty, tz float64 // Target location.
+	dist       float64 // Distance from the target.
+	yaw, pitch float64 // Degrees around Y and above the target.
+	pmin, pmax float64 // Pitch limits.
+	dmin, dmax float64 // Distance limits.
+	rs, ps, zs float64 // Rotate, pan, and zoom speeds.
+	mx, my     int     // Last mouse location while dragging.
+	drag       bool    // True while a mouse button is down.
+}
+
+// newOrbit allocates and places an orbit camera.
+func newOrbit(c *camera) *orbit {
+	o := &orbit{cam: c, dist: 10, pmin: -89, pmax: 89, dmin: 0.5, dmax: 1000}
+	o.rs, o.ps, o.zs = 0.25, 0.002, 0.1
+	o.place()
+	return o
+}
+
+// Implement Orbit.
+func (o *orbit) Target() (x, y, z float64) { return o.tx, o.ty, o.tz }
+func (o *orbit) SetTarget(x, y, z float64) Orbit {
+	o.tx, o.ty, o.tz = x, y, z
+	o.place()
+	return o
+}
+func (o *orbit) Distance() float64 { return o.dist }
+func (o *orbit) SetDistance(d float64) Orbit {
+	o.dist = d
+	o.place()
+	return o
+}
+func (o *orbit) Angles() (yaw, pitch float64) { return o.yaw, o.pitch }
+func (o *orbit) SetAngles(yaw, pitch float64) Orbit {
+	o.yaw, o.pitch = yaw, pitch
+	o.place()
+	return o
+}
+func (o *orbit) SetPitchLimits(min, max float64) Orbit {
+	if min <= max {
+		o.pmin, o.pmax = min, max
+		o.place()
+	}
+	return o
+}
+func (o *orbit) SetZoomLimits(min, max float64) Orbit {
+	if min > 0 && min <= max {
+		o.dmin, o.dmax = min, max
+		o.place()
+	}
+	return o
+}
+func (o *orbit) SetSpeeds(rotate, pan, zoom float64) Orbit {
+	o.rs, o.ps, o.zs = rotate, pan, zoom
+	return o
+}
+func (o *orbit) Rotate(yaw, pitch float64) {
+	o.yaw, o.pitch = o.yaw+yaw, o.pitch+pitch
+	o.place()
+}
+func (o *orbit) Zoom(steps float64) {
+	o.dist *= math.Pow(1-o.zs, steps)
+	o.place()
+}
+
+// Pan moves the target across the view. The pan is scaled by
+// the distance so that the target moves with the mouse.
+func (o *orbit) Pan(dx, dy float64) {
+	scale := o.dist * o.ps
+	rx, ry, rz := o.cam.axis(1, 0, 0)
+	ux, uy, uz := o.cam.axis(0, 1, 0)
+	o.tx -= (rx*dx + ux*dy) * scale
+	o.ty -= (ry*dx + uy*dy) * scale
+	o.tz -= (rz*dx + uz*dy) * scale
+	o.place()
+}
+
+// Update applies the mouse input. Mouse drags are measured from the
+// mouse location of the previous update.
+func (o *orbit) Update(in *Input) {
+	if in == nil {
+		return
+	}
+	_, left := in.Down[KLm]
+	_, right := in.Down[KRm]
+	if (left || right) && o.drag {
+		dx, dy := float64(in.Mx-o.mx), float64(in.My-o.my)
+		if left {
+			o.Rotate(-dx*o.rs, -dy*o.rs)
+		} else {
+			o.Pan(dx, dy)
+		}
+	}
+	o.drag, o.mx, o.my = left || right, in.Mx, in.My
+	scroll := in.ScrollY // prefer the precise trackpad scrolling.
+	if scroll == 0 {
+		scroll = float64(in.Scroll)
+	}
+	if scroll != 0 {
+		o.Zoom(scroll)
+	}
+}
+
+// place clamps the orbit values and moves the camera. A positive
+// orbit pitch looks down on the target which is a negative camera
+// pitch.
+func (o *orbit) place() {
+	o.pitch = lin.Clamp(o.pitch, o.pmin, o.pmax)
+	o.dist = lin.Clamp(o.dist, o.dmin, o.dmax)
+	o.cam.SetPitch(-o.pitch)
+	o.cam.SetYaw(o.yaw)
+	bx, by, bz := o.cam.axis(0, 0, 1) // away from the view direction.
+	o.cam.SetLocation(o.tx+bx*o.dist, o.ty+by*o.dist, o.tz+bz*o.dist)
+}
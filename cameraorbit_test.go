@@ -0,0 +1,68 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"testing"
+
+	"github.com/gazed/vu/math/lin"
+)
+
+// Check that the orbit camera looks at the target from
+// above and that limits are applied.
+func TestOrbit(t *testing.T) {
+	cam, ww, wh := initScene()
+	o := NewOrbit(cam).SetTarget(1, 2, 3).SetDistance(10).SetAngles(30, 45)
+	if x, y, visible := cam.Screen(1, 2, 3); x != ww/2 || y != wh/2 || !visible {
+		t.Errorf("Expected target at screen center, got %d %d %t", x, y, visible)
+	}
+	if _, y, _ := cam.Location(); !lin.Aeq(y, 2+10*0.7071068) {
+		t.Errorf("Expected camera above the target, got %f", y)
+	}
+	o.SetZoomLimits(2, 20).Zoom(100)
+	o.Rotate(0, 90)
+	if yaw, pitch := o.Angles(); o.Distance() != 2 || pitch != 89 || yaw != 30 {
+		t.Errorf("Expected clamped distance and pitch, got %f %f", o.Distance(), pitch)
+	}
+}
+
+// Check that mouse drags rotate and pan.
+func TestOrbitUpdate(t *testing.T) {
+	cam, _, _ := initScene()
+	o := NewOrbit(cam)
+	in := &Input{Down: map[int]int{}, Mx: 100, My: 100}
+	o.Update(in) // no drag.
+	in.Down[KLm], in.Mx = 1, 140
+	o.Update(in) // drag starts.
+	in.Mx = 180
+	o.Update(in)
+	if yaw, _ := o.Angles(); yaw != -10 {
+		t.Errorf("Expected yaw -10, got %f", yaw)
+	}
+	delete(in.Down, KLm)
+	o.Update(in)
+	in.Down[KRm], in.My = 1, 100
+	o.Update(in)
+	in.My = 150
+	o.Update(in)
+	if x, y, z := o.Target(); lin.Aeq(x, 0) && lin.Aeq(y, 0) && lin.Aeq(z, 0) {
+		t.Errorf("Expected target to be panned")
+	}
+
+	// precise scrolling zooms by fractional steps.
+	in.Down, in.Scroll, in.ScrollY = map[int]int{}, 0, 0.5
+	dist := o.Distance()
+	o.Update(in)
+	if in.ScrollY, in.Scroll = 0, 1; o.Distance() >= dist {
+		t.Errorf("Expected fractional zoom in, got %f", o.Distance())
+	}
+	half := o.Distance()
+	o.Update(in)
+	if step := dist / half; !lin.Aeq(half/o.Distance(), step*step) {
+		t.Errorf("Expected half step zoom %f %f %f", dist, half, o.Distance())
+	}
+	if NewOrbit(nil) != nil {
+		t.Errorf("Expected nil for nil camera")
+	}
+}
@@ -0,0 +1,231 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/gazed/vu/math/lin"
+)
+
+// Camera paths move a camera smoothly through location and look at
+// keys, ie: for cutscenes or benchmark fly-throughs, ie:
+//     path := eng.CameraPath(cam).Ease(vu.EaseInOut)
+//     path.Key(0, 0, 20, 50, 0, 0, 0)
+//     path.Key(5*time.Second, 40, 30, 0, 0, 0, 0)
+//     path.Key(9*time.Second, 0, 10, -40, 0, 5, 0)
+//     path.Done(func() { log.Printf("fly-through %s", time.Since(start)) }).Play()
+// The camera location and the looked at target each follow a
+// Catmull-Rom spline through the keys. Playing paths are updated each
+// update after App.Update, and after the tweens, starting the update
+// after Play. Keeping the camera above terrain uses the same ground
+// functions as the FPS controller, ie:
+//     path.SetGround(vu.RayGround(eng, terrainLayers, 500), 2)
+
+// Path is a timed camera path.
+type Path interface {
+	// Key adds a camera location x, y, z and look at target tx, ty, tz
+	// at the given time from the start of the path.
+	Key(at time.Duration, x, y, z, tx, ty, tz float64) Path
+	Ease(e Easing) Path  // Timing over the whole path. Default Linear.
+	Loop(loop bool) Path // Restart at the end. Default false.
+	Done(fn func()) Path // Called when a path that does not loop ends.
+
+	// SetGround keeps the camera at least clearance above the ground.
+	// A nil ground turns off ground checks.
+	SetGround(ground GroundFunc, clearance float64) Path
+
+	// At returns the location and target at the given path time.
+	At(at time.Duration) (x, y, z, tx, ty, tz float64)
+	Play()         // Start, or restart, from the first key.
+	Stop()         // Stop without calling done.
+	Playing() bool // True while playing.
+}
+
+// Implement Eng interface.
+func (eng *engine) CameraPath(cam Camera) Path {
+	c, _ := cam.(*camera)
+	return &path{eng: eng, cam: c, ease: Linear}
+}
+
+// Path
+// =============================================================================
+// path implements Path.
+
+// path implements Path.
+type path struct {
+	eng     *engine
+	cam     *camera    // Moved camera.
+	keys    []pathKey  // Keys in time order.
+	ease    Easing     // Change over the path time.
+	loop    bool       // True to restart at the end.
+	done    func()     // Optional finished callback.
+	ground  GroundFunc // Optional ground height.
+	clear   float64    // Height kept above the ground.
+	at      float64    // Elapsed time in seconds.
+	playing bool       // True while updated by the engine.
+}
+
+// pathKey is one camera location and target.
+type pathKey struct {
+	at          float64 // Seconds from the path start.
+	loc, target lin.V3
+}
+
+// Implement Path.
+func (p *path) Key(at time.Duration, x, y, z, tx, ty, tz float64) Path {
+	k := pathKey{at: at.Seconds()}
+	k.loc.SetS(x, y, z)
+	k.target.SetS(tx, ty, tz)
+	index := sort.Search(len(p.keys), func(i int) bool { return p.keys[i].at > k.at })
+	p.keys = append(p.keys, pathKey{})
+	copy(p.keys[index+1:], p.keys[index:])
+	p.keys[index] = k
+	return p
+}
+func (p *path) Ease(e Easing) Path {
+	if e != nil {
+		p.ease = e
+	}
+	return p
+}
+func (p *path) Loop(loop bool) Path {
+	p.loop = loop
+	return p
+}
+func (p *path) Done(fn func()) Path {
+	p.done = fn
+	return p
+}
+func (p *path) SetGround(ground GroundFunc, clearance float64) Path {
+	p.ground, p.clear = ground, clearance
+	return p
+}
+func (p *path) Play() {
+	p.at = 0
+	if !p.playing {
+		p.playing = true
+		p.eng.paths = append(p.eng.paths, p)
+	}
+}
+func (p *path) Stop()         { p.playing = false }
+func (p *path) Playing() bool { return p.playing }
+
+// At samples the location and target splines without easing.
+func (p *path) At(at time.Duration) (x, y, z, tx, ty, tz float64) {
+	return p.sample(at.Seconds())
+}
+
+// duration returns the path time in seconds.
+func (p *path) duration() float64 {
+	if len(p.keys) == 0 {
+		return 0
+	}
+	return p.keys[len(p.keys)-1].at
+}
+
+// sample returns the spline values at the given seconds.
+// Times outside the keys use the first or last key.
+func (p *path) sample(at float64) (x, y, z, tx, ty, tz float64) {
+	n := len(p.keys)
+	switch {
+	case n == 0:
+		return 0, 0, 0, 0, 0, -1
+	case at <= p.keys[0].at || n == 1:
+		k := &p.keys[0]
+		return k.loc.X, k.loc.Y, k.loc.Z, k.target.X, k.target.Y, k.target.Z
+	case at >= p.keys[n-1].at:
+		k := &p.keys[n-1]
+		return k.loc.X, k.loc.Y, k.loc.Z, k.target.X, k.target.Y, k.target.Z
+	}
+
+	// segment k1 to k2. Missing end neighbours
+	// continue in a line from the end keys.
+	i := sort.Search(n, func(i int) bool { return p.keys[i].at > at }) - 1
+	k1, k2 := p.keys[i].values(), p.keys[i+1].values()
+	var k0, k3, v [6]float64
+	for cnt := range v {
+		k0[cnt], k3[cnt] = 2*k1[cnt]-k2[cnt], 2*k2[cnt]-k1[cnt]
+	}
+	if i > 0 {
+		k0 = p.keys[i-1].values()
+	}
+	if i+2 < n {
+		k3 = p.keys[i+2].values()
+	}
+	u := (at - p.keys[i].at) / (p.keys[i+1].at - p.keys[i].at)
+	for cnt := range v {
+		v[cnt] = catmullRom(k0[cnt], k1[cnt], k2[cnt], k3[cnt], u)
+	}
+	return v[0], v[1], v[2], v[3], v[4], v[5]
+}
+
+// values returns the key location and target.
+func (k *pathKey) values() [6]float64 {
+	return [6]float64{k.loc.X, k.loc.Y, k.loc.Z, k.target.X, k.target.Y, k.target.Z}
+}
+
+// catmullRom returns the value the fraction u between p1 and p2 on
+// the spline through p0, p1, p2, p3.
+func catmullRom(p0, p1, p2, p3, u float64) float64 {
+	u2, u3 := u*u, u*u*u
+	return 0.5 * (2*p1 + (p2-p0)*u + (2*p0-5*p1+4*p2-p3)*u2 + (3*p1-p0-3*p2+p3)*u3)
+}
+
+// update moves the path forward by the given seconds and places the
+// camera, returning true if the path has finished.
+func (p *path) update(dts float64) bool {
+	if !p.playing || p.cam == nil {
+		return true
+	}
+	p.at += dts
+	total, finished := p.duration(), false
+	if p.at >= total {
+		if p.loop && total > 0 {
+			p.at = math.Mod(p.at, total)
+		} else {
+			p.at, finished = total, true
+		}
+	}
+	at := total
+	if total > 0 {
+		at = p.ease(p.at/total) * total
+	}
+	x, y, z, tx, ty, tz := p.sample(at)
+	if p.ground != nil {
+		if ground, ok := p.ground(x, y, z); ok && y < ground+p.clear {
+			y = ground + p.clear
+		}
+	}
+	c := p.cam
+	c.at.Loc.SetS(x, y, z)
+	if yaw, pitch, ok := lookAngles(tx-x, ty-y, tz-z); ok {
+		c.SetYaw(yaw)
+		c.SetPitch(pitch)
+	}
+	c.updateTransform()
+	if finished {
+		p.playing = false
+		if p.done != nil {
+			p.done()
+		}
+	}
+	return finished
+}
+
+// updatePaths moves the playing camera paths forward by the given seconds.
+func (eng *engine) updatePaths(dts float64) {
+	playing := eng.paths[:0]
+	for _, p := range eng.paths {
+		if !p.update(dts) {
+			playing = append(playing, p)
+		}
+	}
+	for cnt := len(playing); cnt < len(eng.paths); cnt++ {
+		eng.paths[cnt] = nil // release for garbage collection.
+	}
+	eng.paths = playing
+}
@@ -0,0 +1,55 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gazed/vu/math/lin"
+)
+
+// Check that camera paths pass through the keys and look at the targets.
+func TestCameraPath(t *testing.T) {
+	eng := newEngine(nil)
+	defer eng.Shutdown()
+	cam := eng.Root().NewPov().NewCam()
+	path := eng.CameraPath(cam)
+	path.Key(2*time.Second, 20, 0, 0, 20, 0, -10) // added out of order.
+	path.Key(0, 0, 0, 0, 0, 0, -10).Key(time.Second, 10, 0, 0, 10, 0, -10)
+	if x, _, _, _, _, _ := path.At(time.Second); !lin.Aeq(x, 10) {
+		t.Errorf("Expected path through key, got %f", x)
+	}
+	if x, _, _, _, _, _ := path.At(1500 * time.Millisecond); !lin.Aeq(x, 15) {
+		t.Errorf("Expected evenly spaced keys to move evenly, got %f", x)
+	}
+
+	// play the path keeping above the ground.
+	finished := false
+	path.SetGround(func(x, y, z float64) (float64, bool) { return 1, true }, 0.5)
+	path.Done(func() { finished = true }).Play()
+	for cnt := 0; cnt < 100 && path.Playing(); cnt++ {
+		eng.updatePaths(0.05)
+	}
+	if x, y, z := cam.Location(); !finished || !lin.Aeq(x, 20) || !lin.Aeq(y, 1.5) || z != 0 {
+		t.Errorf("Expected finished path above the ground, got %t %f %f %f", finished, x, y, z)
+	}
+	if !lin.Aeq(cam.Pitch(), lin.Deg(-0.1488899476)) || !lin.Aeq(cam.Yaw(), 0) {
+		t.Errorf("Expected camera looking at the target, got %f %f", cam.Pitch(), cam.Yaw())
+	}
+
+	// looping paths keep playing.
+	path.Loop(true).Play()
+	for cnt := 0; cnt < 100; cnt++ {
+		eng.updatePaths(0.05)
+	}
+	if !path.Playing() || len(eng.paths) != 1 {
+		t.Errorf("Expected looping path to keep playing")
+	}
+	path.Stop()
+	eng.updatePaths(0.05)
+	if len(eng.paths) != 0 {
+		t.Errorf("Expected stopped path to be removed")
+	}
+}
@@ -0,0 +1,175 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"math"
+
+	"github.com/gazed/vu/math/lin"
+)
+
+// Shake adds trauma based camera shake on top of whatever else is
+// moving the camera, ie:
+//     shake := vu.NewShake(cam).Listen(eng)
+//     ...
+//     eng.Publish(vu.ShakeEvent, vu.Jolt{X: x, Y: y, Z: z, Trauma: 0.8, Radius: 30})
+//     ...
+//     orbit.Update(in)  // any camera controller.
+//     shake.Update(in.Dt)
+// Trauma, from 0 to 1, is added by gameplay events and decays over time.
+// The shake is the square of the trauma so that small amounts of trauma
+// are barely noticed. The camera is offset by smooth noise, up to the
+// maximum movement and turn, only when drawing. The camera location
+// and direction, and so the controllers, are not affected.
+
+// Shake offsets a camera by an amount that depends on the trauma.
+type Shake interface {
+	AddTrauma(amount float64) Shake // Trauma is kept from 0 to 1.
+	Trauma() float64                // Current trauma.
+
+	// AddTraumaAt adds trauma that falls off with distance from the
+	// given world location to the camera. Nothing is added beyond the
+	// radius. A radius of 0 adds the full amount.
+	AddTraumaAt(x, y, z, amount, radius float64) Shake
+
+	SetDecay(perSecond float64) Shake // Trauma lost per second. Default 1.
+	SetMax(move, turn float64) Shake  // Default 0.25 units and 3 degrees.
+	SetFrequency(hertz float64) Shake // Shake speed. Default 12.
+	Listen(eng Eng) Shake             // Add ShakeEvent trauma. Nil stops.
+	Update(dt float64)                // Update after the camera moves.
+}
+
+// Jolt is the ShakeEvent data. A Radius of 0 shakes from anywhere.
+type Jolt struct {
+	X, Y, Z float64 // World location.
+	Trauma  float64 // Trauma added at the location.
+	Radius  float64 // Distance where the trauma drops to 0.
+}
+
+// NewShake creates a camera shake for the given camera.
+// Returns nil for cameras that are not engine cameras.
+func NewShake(cam Camera) Shake {
+	if c, ok := cam.(*camera); ok && c != nil {
+		return newShake(c)
+	}
+	return nil
+}
+
+// Shake
+// =============================================================================
+// shake implements Shake.
+
+// shake implements Shake by setting the camera shake offset.
+type shake struct {
+	cam        *camera
+	trauma     float64 // From 0 to 1.
+	decay      float64 // Trauma lost per second.
+	move, turn float64 // Maximum offsets at full trauma.
+	freq       float64 // Noise samples per second.
+	time       float64 // Noise time in seconds.
+	eng        Eng     // Set while listening for events.
+	sub        int     // ShakeEvent subscriber id.
+	off        *lin.T  // Camera offset.
+	q0, q1     *lin.Q  // Scratch for the offset rotation.
+}
+
+// newShake allocates a camera shake.
+func newShake(c *camera) *shake {
+	return &shake{cam: c, decay: 1, move: 0.25, turn: 3, freq: 12,
+		off: lin.NewT(), q0: &lin.Q{}, q1: &lin.Q{}}
+}
+
+// Implement Shake.
+func (s *shake) AddTrauma(amount float64) Shake {
+	s.trauma = lin.Clamp(s.trauma+amount, 0, 1)
+	return s
+}
+func (s *shake) Trauma() float64 { return s.trauma }
+func (s *shake) AddTraumaAt(x, y, z, amount, radius float64) Shake {
+	if radius > 0 {
+		l := s.cam.at.Loc
+		dx, dy, dz := x-l.X, y-l.Y, z-l.Z
+		amount *= math.Max(0, 1-math.Sqrt(dx*dx+dy*dy+dz*dz)/radius)
+	}
+	return s.AddTrauma(amount)
+}
+func (s *shake) SetDecay(perSecond float64) Shake {
+	s.decay = math.Max(perSecond, 0)
+	return s
+}
+func (s *shake) SetMax(move, turn float64) Shake {
+	s.move, s.turn = move, turn
+	return s
+}
+func (s *shake) SetFrequency(hertz float64) Shake {
+	s.freq = hertz
+	return s
+}
+func (s *shake) Listen(eng Eng) Shake {
+	if s.eng != nil {
+		s.eng.Unsubscribe(s.sub)
+		s.eng, s.sub = nil, 0
+	}
+	if eng != nil {
+		s.eng = eng
+		s.sub = eng.Subscribe(ShakeEvent, s.jolt)
+	}
+	return s
+}
+
+// jolt handles ShakeEvent events.
+func (s *shake) jolt(topic string, data interface{}) {
+	switch j := data.(type) {
+	case Jolt:
+		s.AddTraumaAt(j.X, j.Y, j.Z, j.Trauma, j.Radius)
+	case *Jolt:
+		s.AddTraumaAt(j.X, j.Y, j.Z, j.Trauma, j.Radius)
+	}
+}
+
+// Update decays the trauma and sets the camera offset for the elapsed
+// seconds. The offset is removed once the trauma is gone.
+func (s *shake) Update(dt float64) {
+	c := s.cam
+	s.trauma = math.Max(0, s.trauma-s.decay*dt)
+	s.time += dt
+	c.shake = nil
+	c.updateTransform()
+	if s.trauma <= 0 {
+		return
+	}
+
+	// noise channels 0-2 move, and 3-5 turn, the camera.
+	amount, t := s.trauma*s.trauma, s.time*s.freq
+	mx, my, mz := wobble(0, t), wobble(1, t), wobble(2, t)
+	wx, wy, wz := c.axis(mx*s.move*amount, my*s.move*amount, mz*s.move*amount)
+	s.off.Loc.SetS(wx, wy, wz)
+	turn := lin.Rad(s.turn * amount)
+	s.q0.SetAa(1, 0, 0, wobble(3, t)*turn)
+	s.q1.SetAa(0, 1, 0, wobble(4, t)*turn)
+	s.off.Rot.Mult(s.q0, s.q1)
+	s.off.Rot.Mult(s.q1.SetAa(0, 0, 1, wobble(5, t)*turn), s.off.Rot)
+	c.shake = s.off
+	c.updateTransform()
+}
+
+// wobble returns smooth noise, from -1 to 1, for the given channel
+// at time t. Random values at whole times are smoothly blended.
+func wobble(channel int, t float64) float64 {
+	at := math.Floor(t)
+	f := t - at
+	a, b := noiseAt(channel, int64(at)), noiseAt(channel, int64(at)+1)
+	return lerp(a, b, f*f*(3-2*f))
+}
+
+// noiseAt returns a repeatable random value from -1 to 1.
+func noiseAt(channel int, at int64) float64 {
+	h := uint32(at)*0x9e3779b1 ^ uint32(channel+1)*0x85ebca77
+	h ^= h >> 15
+	h *= 0x2c1b3c6d
+	h ^= h >> 12
+	h *= 0x297a2d39
+	h ^= h >> 15
+	return float64(h)/math.MaxUint32*2 - 1
+}
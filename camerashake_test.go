@@ -0,0 +1,62 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"testing"
+
+	"github.com/gazed/vu/math/lin"
+)
+
+// Check that shake moves the view, but not the camera,
+// and that the view returns once the trauma decays.
+func TestShake(t *testing.T) {
+	cam, _, _ := initScene()
+	cam.SetLocation(0, 0, 10)
+	want := (&lin.M4{}).Set(cam.vm)
+	s := NewShake(cam).AddTrauma(0.5).AddTrauma(0.8)
+	if s.Trauma() != 1 {
+		t.Errorf("Expected trauma capped at 1, got %f", s.Trauma())
+	}
+	s.Update(0.1)
+	if x, y, z := cam.Location(); x != 0 || y != 0 || z != 10 {
+		t.Errorf("Expected unchanged camera location, got %f %f %f", x, y, z)
+	}
+	if cam.vm.Aeq(want) {
+		t.Errorf("Expected shaken view")
+	}
+	for cnt := 0; cnt < 10; cnt++ {
+		s.Update(0.1)
+	}
+	if s.Trauma() != 0 || !cam.vm.Aeq(want) {
+		t.Errorf("Expected view restored, trauma %f", s.Trauma())
+	}
+
+	// trauma from far away events is reduced.
+	if s.AddTraumaAt(0, 0, 5, 1, 10).Trauma() != 0.5 {
+		t.Errorf("Expected half trauma, got %f", s.Trauma())
+	}
+	if s.AddTraumaAt(0, 0, 50, 1, 10).Trauma() != 0.5 {
+		t.Errorf("Expected no added trauma, got %f", s.Trauma())
+	}
+}
+
+// Check that shake listens for events.
+func TestShakeEvent(t *testing.T) {
+	eng := newEngine(nil)
+	defer eng.Shutdown()
+	cam := eng.Root().NewPov().NewCam()
+	s := NewShake(cam).Listen(eng)
+	eng.Publish(ShakeEvent, Jolt{Trauma: 0.4})
+	eng.events.dispatch()
+	if !lin.Aeq(s.Trauma(), 0.4) {
+		t.Errorf("Expected event trauma, got %f", s.Trauma())
+	}
+	s.Listen(nil)
+	eng.Publish(ShakeEvent, &Jolt{Trauma: 0.4})
+	eng.events.dispatch()
+	if !lin.Aeq(s.Trauma(), 0.4) {
+		t.Errorf("Expected no trauma after listen stopped, got %f", s.Trauma())
+	}
+}
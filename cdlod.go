@@ -0,0 +1,234 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"image"
+	"math"
+
+	"github.com/gazed/vu/render"
+)
+
+// Cdlod renders a Surface height map using continuous distance dependent
+// level of detail (CDLOD). The height map is covered by a quadtree of
+// patches and each update selects the patches needed around the camera.
+// Every patch draws the same shared grid mesh. The vertex shader places
+// the grid over the patch, reads the heights from a height map texture,
+// and morphs vertices towards the next coarser level as they near the
+// end of their level range, so there are no popping or cracks between
+// levels and no meshes are rebuilt on the CPU, ie:
+//     c := vu.NewCdlod(pov, "cdlod", surface, 16)
+//     c.Update(cam) // each update: select the patches around the camera.
+// The surface is expected to have 2^n+1 points along each side. Heights
+// are copied to the GPU when the Cdlod is created and on SetHeights.
+// The patches lie in the x,y plane of the Cdlod Pov which is expected to
+// be a child of the root. See eg/source/cdlod.vsh for the shader uniforms.
+type Cdlod interface {
+	// SetRange sets the distance covered by full resolution patches.
+	// Each coarser level covers twice the distance of the previous level.
+	// The default is twice the grid size. Quality.LodBias scales the
+	// ranges by a power of two.
+	SetRange(near float64) Cdlod
+
+	// SetModel registers a callback that configures each new patch
+	// model, ie: adding a material. The height map is always texture 0.
+	SetModel(setup func(m Model)) Cdlod
+	SetHeights()       // Recopy the surface heights, ie: after edits.
+	Update(cam Camera) // Select the patches around the camera.
+	Patches() int      // Number of patches selected by the last Update.
+}
+
+// NewCdlod creates a quadtree renderer for the given surface heights
+// using a shared grid of grid-by-grid quads. The grid size is expected
+// to be an even number less than 256. Patches are rendered as child
+// Pov's of p using the given shader.
+func NewCdlod(p Pov, shader string, s Surface, grid int) Cdlod {
+	return newCdlod(p, shader, s, grid)
+}
+
+// Cdlod
+// =============================================================================
+// cdlod implements Cdlod.
+
+// cdlod selects quadtree patches and positions a pool of patch models.
+// The patch models are reused between updates and are hidden when
+// they are not needed.
+type cdlod struct {
+	pov    *pov          // Height map location, orientation, scale.
+	shader string        // Patch model shader.
+	s      *surface      // Height map data.
+	grid   int           // Quads along each side of the shared grid.
+	levels int           // Number of quadtree levels.
+	near   float64       // Full resolution range.
+	setup  func(m Model) // Optional patch model configuration.
+	msh    *mesh         // Shared grid mesh.
+	hmap   *texture      // Shared height map texture.
+	hmin   float64       // Lowest scaled height.
+	hmax   float64       // Highest scaled height.
+	pool   []*pov        // Patch models. Visible ones are in use.
+	picks  []patch       // Patches selected by the last Update.
+}
+
+// patch is a selected quadtree node. The patch covers the height map
+// from x, y to x+size, y+size and uses the detail for the given level.
+type patch struct{ x, y, size, level int }
+
+// newCdlod allocates and initializes a quadtree renderer.
+func newCdlod(p Pov, shader string, s Surface, grid int) *cdlod {
+	c := &cdlod{shader: shader}
+	c.pov, _ = p.(*pov)
+	c.s, _ = s.(*surface)
+	if grid < 2 || grid > 254 || grid%2 != 0 {
+		grid = 16
+	}
+	c.grid, c.near = grid, float64(2*grid)
+	c.msh = newGrid(grid)
+	c.hmap = newTexture("cdlod")
+	if c.s != nil {
+		for c.levels = 1; grid<<uint(c.levels-1) < len(c.s.pts)-1; c.levels++ {
+		}
+		c.SetHeights()
+	}
+	return c
+}
+
+// newGrid creates the grid mesh shared by all patches. The grid
+// vertices range from 0 to 1 and are scaled by the shader.
+func newGrid(grid int) *mesh {
+	vb := make([]float32, 0, (grid+1)*(grid+1)*2)
+	for y := 0; y <= grid; y++ {
+		for x := 0; x <= grid; x++ {
+			vb = append(vb, float32(x)/float32(grid), float32(y)/float32(grid))
+		}
+	}
+	fb := make([]uint16, 0, grid*grid*6)
+	for y := 0; y < grid; y++ {
+		for x := 0; x < grid; x++ {
+			i0 := uint16(y*(grid+1) + x)
+			i1, i2, i3 := i0+1, i0+uint16(grid+1), i0+uint16(grid+2)
+			fb = append(fb, i0, i1, i3, i0, i3, i2)
+		}
+	}
+	m := newMesh("cdlod")
+	m.initData(0, 2, render.StaticDraw, false).setData(0, vb)
+	m.initFaces(render.StaticDraw).setFaces(fb)
+	return m
+}
+
+// Implement Cdlod.
+func (c *cdlod) SetRange(near float64) Cdlod {
+	if near > 0 {
+		c.near = near
+	}
+	return c
+}
+func (c *cdlod) SetModel(setup func(m Model)) Cdlod {
+	c.setup = setup
+	return c
+}
+func (c *cdlod) Patches() int { return len(c.picks) }
+
+// SetHeights implements Cdlod. The scaled heights are normalized to
+// the height range and stored as 16 bits in the red and green channels.
+func (c *cdlod) SetHeights() {
+	if c.s == nil {
+		return
+	}
+	var img *image.NRGBA
+	img, c.hmin, c.hmax = heightMap(c.s.pts, float64(c.s.scale), nil)
+	c.hmap.set(img)
+}
+
+// Update implements Cdlod. The quadtree is walked from the root and
+// the selected patches are mapped onto the patch model pool.
+func (c *cdlod) Update(cam Camera) {
+	if c.pov == nil || c.s == nil || cam == nil {
+		return
+	}
+	lx, ly, lz := localCam(c.pov, cam)
+	c.picks = c.picks[:0]
+	c.choose(0, 0, c.levels-1, lx, ly, lz)
+	for cnt, pk := range c.picks {
+		if cnt >= len(c.pool) {
+			c.pool = append(c.pool, c.newPatch())
+		}
+		p := c.pool[cnt]
+		half := float64(pk.size) * 0.5
+		p.SetLocation(float64(pk.x)+half, float64(pk.y)+half, 0)
+		p.SetVisible(true)
+		start, end := c.morph(pk.level)
+		m := p.Model()
+		m.SetUniform("node", pk.x, pk.y, pk.size, c.grid)
+		m.SetUniform("morph", start, end)
+		m.SetUniform("eye", lx, ly, lz)
+		m.SetUniform("hrange", c.hmin, c.hmax)
+	}
+	for _, p := range c.pool[len(c.picks):] {
+		p.SetVisible(false)
+	}
+}
+
+// choose selects the patches for the quadtree node at x, y. Nodes within
+// range of the next finer level are split into their children. Children
+// that are out of the finer range are drawn fully morphed to match this
+// level. Returns false if the node is outside its own level range.
+func (c *cdlod) choose(x, y, level int, lx, ly, lz float64) bool {
+	size, last := c.grid<<uint(level), len(c.s.pts)-1
+	if x >= last || y >= last {
+		return true // node is outside the height map. Nothing to draw.
+	}
+	dist := c.distance(x, y, size, lx, ly, lz)
+	if level < c.levels-1 && dist > c.rangeOf(level) {
+		return false
+	}
+	if level == 0 || dist > c.rangeOf(level-1) {
+		c.picks = append(c.picks, patch{x, y, size, level})
+		return true
+	}
+	half := size / 2
+	for _, child := range [4][2]int{{x, y}, {x + half, y}, {x, y + half}, {x + half, y + half}} {
+		if !c.choose(child[0], child[1], level-1, lx, ly, lz) {
+			c.picks = append(c.picks, patch{child[0], child[1], half, level - 1})
+		}
+	}
+	return true
+}
+
+// rangeOf returns the furthest distance drawn using the given level.
+// Each Quality.LodBias level halves, or doubles, the ranges.
+func (c *cdlod) rangeOf(level int) float64 {
+	return math.Ldexp(c.near, level-c.pov.eng.quality.LodBias)
+}
+
+// morph returns the distances where the given level starts and finishes
+// morphing into the next coarser level. The coarsest level never morphs.
+func (c *cdlod) morph(level int) (start, end float64) {
+	if level >= c.levels-1 {
+		return math.MaxFloat32 / 2, math.MaxFloat32
+	}
+	end = c.rangeOf(level)
+	return end * 0.7, end
+}
+
+// distance returns the distance from the camera to the closest point
+// of a node bounding box. The box uses the full map height range.
+func (c *cdlod) distance(x, y, size int, lx, ly, lz float64) float64 {
+	dx := math.Max(0, math.Max(float64(x)-lx, lx-float64(x+size)))
+	dy := math.Max(0, math.Max(float64(y)-ly, ly-float64(y+size)))
+	dz := math.Max(0, math.Max(c.hmin-lz, lz-c.hmax))
+	return math.Sqrt(dx*dx + dy*dy + dz*dz)
+}
+
+// newPatch creates a patch model that shares the grid mesh
+// and height map texture.
+func (c *cdlod) newPatch() *pov {
+	p := c.pov.NewPov().(*pov)
+	m := p.NewModel(c.shader).(*model)
+	m.msh = c.msh
+	m.texs = append(m.texs, c.hmap)
+	if c.setup != nil {
+		c.setup(m)
+	}
+	return p
+}
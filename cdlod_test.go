@@ -0,0 +1,53 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"testing"
+)
+
+// Check that patches near the camera use full detail, that distant
+// patches are coarser, and that the selected patches cover the map.
+func TestCdlodSelect(t *testing.T) {
+	eng := newEngine(nil)
+	defer eng.Shutdown()
+	cam := eng.Root().NewPov().NewCam()
+	c := newCdlod(eng.Root().NewPov(), "cdlod", newSurface(129, 129, 1, 1, 1), 8)
+	if c.levels != 5 {
+		t.Fatalf("Expected 5 levels for 128 quads, got %d", c.levels)
+	}
+	cam.SetLocation(4, 4, 1) // over the first leaf patch.
+	c.Update(cam)
+	area, fine, coarse := 0, patch{x: -1}, patch{x: -1}
+	for _, pk := range c.picks {
+		area += pk.size * pk.size
+		switch {
+		case pk.x == 0 && pk.y == 0:
+			fine = pk
+		case pk.x == 64 && pk.y == 64:
+			coarse = pk
+		}
+	}
+	if area != 128*128 {
+		t.Errorf("Expected patches to cover the map, got area %d", area)
+	}
+	if fine.level != 0 || coarse.level != 3 {
+		t.Errorf("Expected near level 0 and far level 3, got %v %v", fine, coarse)
+	}
+
+	// patches share the grid mesh and height map.
+	if c.Patches() != len(c.pool) || c.Patches() < 2 {
+		t.Fatalf("Expected one model per patch, got %d %d", c.Patches(), len(c.pool))
+	}
+	m0, m1 := c.pool[0].Model().(*model), c.pool[1].Model().(*model)
+	if m0.msh != m1.msh || m0.texs[0] != c.hmap || m1.texs[0] != c.hmap {
+		t.Errorf("Expected patches to share the grid mesh and height map")
+	}
+
+	// a distant camera needs fewer patches and hides the unused models.
+	cam.SetLocation(1000, 1000, 1)
+	if c.Update(cam); c.Patches() != 1 || c.pool[1].Visible() || c.picks[0].level != 4 {
+		t.Errorf("Expected only the root patch, got %d", c.Patches())
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+// Component is application behaviour attached to a Pov. Components keep
+// per-entity logic with the entity rather than in one large App.Update,
+// ie:
+//     type spinner struct{ p vu.Pov }
+//     func (s *spinner) OnAttach(p vu.Pov)     { s.p = p }
+//     func (s *spinner) Update(dt float64)     { s.p.Spin(0, 90*dt, 0) }
+//     func (s *spinner) OnDispose()            {}
+//     pov.AddComponent(&spinner{})
+// The engine calls Update once per update tick after App.Update.
+// OnDispose is called when the component is removed or its Pov is
+// disposed. Components are compared by value so they are expected
+// to be pointers.
+type Component interface {
+	OnAttach(p Pov)    // Called once when added to p.
+	Update(dt float64) // Called each update with the delta time in seconds.
+	OnDispose()        // Called once when removed.
+}
+
+// addComponent attaches c to p. Components can only be attached once.
+func (eng *engine) addComponent(p *pov, c Component) {
+	if c == nil || attached(p, c) {
+		return
+	}
+	if len(p.comps) == 0 {
+		eng.comped = append(eng.comped, p)
+	}
+	p.comps = append(p.comps, c)
+	c.OnAttach(p)
+}
+
+// removeComponent detaches c from p.
+func (eng *engine) removeComponent(p *pov, c Component) {
+	for index, pc := range p.comps {
+		if pc == c {
+			p.comps = append(p.comps[:index], p.comps[index+1:]...)
+			if len(p.comps) == 0 {
+				for cnt, cp := range eng.comped {
+					if cp == p {
+						eng.comped = append(eng.comped[:cnt], eng.comped[cnt+1:]...)
+						break
+					}
+				}
+			}
+			c.OnDispose()
+			return
+		}
+	}
+}
+
+// updateComponents calls Update on the components of enabled Pov's.
+// Components added during the update are first updated next tick.
+// Components removed during the update are not updated.
+func (eng *engine) updateComponents(dts float64) {
+	eng.cpovs = append(eng.cpovs[:0], eng.comped...)
+	for _, p := range eng.cpovs {
+		if !p.active() {
+			continue
+		}
+		eng.comps = append(eng.comps[:0], p.comps...)
+		for _, c := range eng.comps {
+			if attached(p, c) {
+				c.Update(dts)
+			}
+		}
+	}
+}
+
+// attached returns true if c is one of the components of p.
+func attached(p *pov, c Component) bool {
+	for _, pc := range p.comps {
+		if pc == c {
+			return true
+		}
+	}
+	return false
+}
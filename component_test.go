@@ -0,0 +1,61 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestComponents checks the component lifecycle callbacks.
+func TestComponents(t *testing.T) {
+	eng := newEngine(nil)
+	defer eng.Shutdown()
+	log := []string{}
+	p0, p1 := eng.Root().NewPov(), eng.Root().NewPov()
+	c0, c1, c2 := &logComp{name: "c0", log: &log}, &logComp{name: "c1", log: &log}, &logComp{name: "c2", log: &log}
+	p0.AddComponent(c0)
+	p0.AddComponent(c0) // ignored: already attached.
+	p1.AddComponent(c1)
+	p0.AddComponent(c2)
+	eng.updateComponents(0.02)
+	if got := strings.Join(log, ","); got != "c0 attach,c1 attach,c2 attach,c0 update,c2 update,c1 update" {
+		t.Errorf("Unexpected callbacks %s", got)
+	}
+
+	// removing and disabling stops updates.
+	log = log[:0]
+	c0.remove, c0.pov = c2, p0
+	p1.SetEnabled(false)
+	eng.updateComponents(0.02)
+	if got := strings.Join(log, ","); got != "c0 update,c2 dispose" {
+		t.Errorf("Unexpected callbacks %s", got)
+	}
+	log = log[:0]
+	p0.Dispose(PovNode)
+	p1.Dispose(PovNode)
+	if got := strings.Join(log, ","); got != "c0 dispose,c1 dispose" || len(eng.comped) != 0 {
+		t.Errorf("Unexpected dispose callbacks %s", got)
+	}
+}
+
+// logComp records its callbacks. It optionally removes another
+// component on update.
+type logComp struct {
+	name   string
+	log    *[]string
+	pov    Pov
+	remove Component
+}
+
+func (c *logComp) OnAttach(p Pov) { *c.log = append(*c.log, c.name+" attach") }
+func (c *logComp) OnDispose()     { *c.log = append(*c.log, c.name+" dispose") }
+func (c *logComp) Update(dt float64) {
+	*c.log = append(*c.log, c.name+" update")
+	if c.remove != nil {
+		c.pov.RemoveComponent(c.remove)
+		c.remove = nil
+	}
+}
+
@@ -0,0 +1,143 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"math"
+
+	"github.com/gazed/vu/math/lin"
+)
+
+// Constraints adjust the rotation of a Pov each update so that it tracks
+// another Pov, ie: turrets and character heads following a target.
+//     turret.LookAt(player)               // face the player.
+//     head.Aim(player, 0, 0, 1)           // face the player, Z is up.
+//     shadow.CopyRotation(player)         // rotate with the player.
+//     turret.LookAt(nil)                  // stop tracking.
+// Constraints are evaluated after App.Update, physics, and animation,
+// and before the transforms are placed. They are evaluated in the order
+// they were set so a constraint can depend on an earlier constraint.
+// A Pov has at most one constraint. Setting a constraint replaces any
+// previous constraint. A constraint is removed when its target is
+// disposed. A Pov faces along its -Z axis.
+
+// Constraint kinds.
+const (
+	aimCon  = iota // Face -Z toward target with an up direction.
+	copyCon        // Match the world rotation of the target.
+)
+
+// constraint tracks a target Pov.
+type constraint struct {
+	kind   int    // aimCon, copyCon.
+	target *pov   // Tracked Pov.
+	up     lin.V3 // World up direction for aimCon.
+}
+
+// constrain sets, or with a nil target removes, the constraint of p.
+func (eng *engine) constrain(p *pov, kind int, target Pov, ux, uy, uz float64) {
+	t, _ := target.(*pov)
+	if t == nil || t == p {
+		if p.con != nil {
+			p.con = nil
+			eng.unconstrain(p)
+		}
+		return
+	}
+	if p.con == nil {
+		p.con = &constraint{}
+		eng.aims = append(eng.aims, p)
+	}
+	p.con.kind, p.con.target = kind, t
+	p.con.up.SetS(ux, uy, uz)
+}
+
+// unconstrain removes p from the list of constrained Pov's.
+func (eng *engine) unconstrain(p *pov) {
+	for index, cp := range eng.aims {
+		if cp == p {
+			eng.aims = append(eng.aims[:index], eng.aims[index+1:]...)
+			return
+		}
+	}
+}
+
+// updateConstraints applies the constraints in the order they were set.
+func (eng *engine) updateConstraints() {
+	for cnt := 0; cnt < len(eng.aims); cnt++ {
+		p := eng.aims[cnt]
+		c := p.con
+		if eng.povs[c.target.eid] != c.target { // target disposed.
+			p.con = nil
+			eng.unconstrain(p)
+			cnt--
+			continue
+		}
+		if !p.active() {
+			continue
+		}
+		switch c.kind {
+		case copyCon:
+			p.SetWorldRotation(c.target.WorldRotation())
+		case aimCon:
+			px, py, pz := p.WorldLocation()
+			tx, ty, tz := c.target.WorldLocation()
+			eng.v0.SetS(tx-px, ty-py, tz-pz)
+			if lookRotation(eng.q0, eng.v0, &c.up) {
+				p.SetWorldRotation(eng.q0)
+			}
+		}
+	}
+}
+
+// lookRotation sets q to the rotation that turns -Z to face along
+// direction dir with +Y turned toward up. Returns false, leaving q
+// unchanged, if dir has no length.
+func lookRotation(q *lin.Q, dir, up *lin.V3) bool {
+	if dir.AeqZ() {
+		return false
+	}
+	z := (&lin.V3{}).Scale(dir, -1).Unit() // -Z faces dir.
+	x := (&lin.V3{}).Cross(up, z)
+	if x.AeqZ() { // up is parallel to dir: any side will do.
+		x.Cross(&lin.V3{X: 1}, z)
+		if x.AeqZ() {
+			x.Cross(&lin.V3{Y: 1}, z)
+		}
+	}
+	x.Unit()
+	y := (&lin.V3{}).Cross(z, x)
+
+	// quaternion from the rotation matrix with columns x, y, z.
+	switch trace := x.X + y.Y + z.Z; {
+	case trace > 0:
+		s := math.Sqrt(trace+1) * 2
+		q.SetS((y.Z-z.Y)/s, (z.X-x.Z)/s, (x.Y-y.X)/s, 0.25*s)
+	case x.X > y.Y && x.X > z.Z:
+		s := math.Sqrt(1+x.X-y.Y-z.Z) * 2
+		q.SetS(0.25*s, (y.X+x.Y)/s, (z.X+x.Z)/s, (y.Z-z.Y)/s)
+	case y.Y > z.Z:
+		s := math.Sqrt(1+y.Y-x.X-z.Z) * 2
+		q.SetS((y.X+x.Y)/s, 0.25*s, (z.Y+y.Z)/s, (z.X-x.Z)/s)
+	default:
+		s := math.Sqrt(1+z.Z-x.X-y.Y) * 2
+		q.SetS((z.X+x.Z)/s, (z.Y+y.Z)/s, 0.25*s, (x.Y-y.X)/s)
+	}
+	q.Unit()
+	return true
+}
+
+// Implement Pov.
+func (p *pov) LookAt(target Pov) Pov {
+	p.eng.constrain(p, aimCon, target, 0, 1, 0)
+	return p
+}
+func (p *pov) Aim(target Pov, ux, uy, uz float64) Pov {
+	p.eng.constrain(p, aimCon, target, ux, uy, uz)
+	return p
+}
+func (p *pov) CopyRotation(target Pov) Pov {
+	p.eng.constrain(p, copyCon, target, 0, 0, 0)
+	return p
+}
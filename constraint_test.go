@@ -0,0 +1,59 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"testing"
+
+	"github.com/gazed/vu/math/lin"
+)
+
+// TestLookRotation checks that -Z faces the direction with up kept up.
+func TestLookRotation(t *testing.T) {
+	q, fwd, v := &lin.Q{}, &lin.V3{Z: -1}, &lin.V3{}
+	dirs := []*lin.V3{{X: 1}, {X: -1, Z: 1}, {Z: 1}, {Z: -1}, {Y: 1}, {X: 0.3, Y: -2, Z: 5}}
+	for _, dir := range dirs {
+		if !lookRotation(q, dir, &lin.V3{Y: 1}) {
+			t.Fatalf("Expected rotation for %v", dir)
+		}
+		want := (&lin.V3{}).Set(dir).Unit()
+		if !v.MultQ(fwd, q).Aeq(want) {
+			t.Errorf("Expected %v got %v", want, v)
+		}
+		if dir.Y == 0 && !v.MultQ(&lin.V3{Y: 1}, q).Aeq(&lin.V3{Y: 1}) {
+			t.Errorf("Expected up to stay up for %v got %v", dir, v)
+		}
+	}
+	if lookRotation(q, &lin.V3{}, &lin.V3{Y: 1}) {
+		t.Errorf("Expected no rotation for zero direction")
+	}
+}
+
+// TestConstraints checks target tracking and removal.
+func TestConstraints(t *testing.T) {
+	eng := newEngine(nil)
+	defer eng.Shutdown()
+	base := eng.Root().NewPov().SetLocation(1, 0, 0)
+	base.Spin(0, 45, 0)
+	turret := base.NewPov().LookAt(eng.Root().NewPov().SetLocation(6, 0, 0))
+	target := eng.Root().NewPov().SetLocation(1, 0, 5)
+	twin := eng.Root().NewPov().CopyRotation(turret)
+	turret.LookAt(target) // replaces previous constraint.
+	eng.updateConstraints()
+	v, fwd := &lin.V3{}, &lin.V3{Z: -1}
+	if !v.MultQ(fwd, turret.WorldRotation()).Aeq(&lin.V3{Z: 1}) {
+		t.Errorf("Expected turret to face +Z got %v", v)
+	}
+	if !v.MultQ(fwd, twin.Rotation()).Aeq(&lin.V3{Z: 1}) {
+		t.Errorf("Expected twin to copy turret got %v", v)
+	}
+	if len(eng.aims) != 2 {
+		t.Errorf("Expected 2 constraints got %d", len(eng.aims))
+	}
+	turret.Dispose(PovNode)
+	eng.updateConstraints()
+	if len(eng.aims) != 0 || twin.(*pov).con != nil {
+		t.Errorf("Expected constraints removed with target")
+	}
+}
@@ -68,3 +68,26 @@ func (rc *radiusCull) Culled(cam Camera, px, py, pz float64) bool {
 	toc := cam.Distance(px, py, pz)
 	return toc > rc.rr
 }
+
+// =============================================================================
+
+// NewFrustumCull returns a culler that removes objects that are outside
+// the camera view volume. Objects are treated as spheres of the given
+// radius. Works with perspective and orthographic projections.
+func NewFrustumCull(r float64) Cull {
+	if r < 0 {
+		r = 0
+	}
+	return &frustumCull{radius: r}
+}
+
+// frustumCull removes everything outside the camera frustum.
+type frustumCull struct {
+	radius float64 // object radius.
+}
+
+// Culler implmentation. True if the sphere at the given location
+// is completely outside the camera frustum.
+func (fc *frustumCull) Culled(cam Camera, px, py, pz float64) bool {
+	return !cam.ContainsSphere(px, py, pz, fc.radius)
+}
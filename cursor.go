@@ -0,0 +1,118 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+// Cursor is a software mouse pointer drawn by the engine as a textured
+// quad over everything else. It is for games that draw their own pointer
+// so that it can be animated, tinted, or scaled with the UI, ie:
+//     cursor := vu.NewCursor(eng, "pointer", 32, 32).SetHotspot(4, 4)
+//     ...
+//     cursor.Update(in, s) // in App.Update.
+// The OS cursor is hidden while the software cursor is visible. Use
+// Eng.SetCursor to change the OS cursor image instead when the pointer
+// does not need to be drawn by the engine.
+type Cursor interface {
+	SetImage(texture string) Cursor // Replace the pointer texture.
+	SetSize(w, h int) Cursor        // Pointer size in pixels.
+	SetColor(r, g, b float64) Cursor
+
+	// SetHotspot sets the pointer pixel, from the top left of
+	// the image, that is placed at the mouse location. Default 0, 0.
+	SetHotspot(x, y int) Cursor
+	SetVisible(visible bool) Cursor // Show or hide. Default visible.
+	Visible() bool                  // True if the pointer is shown.
+
+	// Update moves the pointer to the mouse location. Expected to be
+	// called each App.Update. The pointer is not drawn while the mouse
+	// is captured or the window does not have focus.
+	Update(in *Input, s *State)
+	Dispose() // Remove the pointer and restore the OS cursor.
+}
+
+// NewCursor creates a visible software cursor using the given
+// texture drawn at the given pixel size.
+func NewCursor(eng Eng, texture string, w, h int) Cursor {
+	return newCursor(eng, texture, w, h)
+}
+
+// cursorLast draws the cursor camera after the application UI cameras.
+const cursorLast = 1000
+
+// Cursor
+// =============================================================================
+// cursor implements Cursor.
+
+// cursor implements Cursor using a UI camera and a textured quad.
+type cursor struct {
+	eng     Eng
+	top     Pov    // Pointer scene root with the UI camera.
+	cam     Camera // Orthographic camera sized to the window.
+	ptr     Pov    // Textured pointer quad.
+	w, h    int    // Pointer size in pixels.
+	hx, hy  int    // Hot spot from the image top left.
+	ww, wh  int    // Window size used for the camera projection.
+	visible bool   // True if the pointer is shown.
+}
+
+// newCursor creates the pointer scene and hides the OS cursor.
+func newCursor(eng Eng, texture string, w, h int) *cursor {
+	c := &cursor{eng: eng, visible: true}
+	c.top = eng.Root().NewPov()
+	c.cam = c.top.NewCam()
+	c.cam.SetUI()
+	c.cam.SetLast(cursorLast)
+	c.ptr = c.top.NewPov()
+	c.ptr.NewModel("uv").LoadMesh("icon").AddTex(texture)
+	c.SetSize(w, h)
+	eng.ShowCursor(false)
+	return c
+}
+
+// Implement Cursor.
+func (c *cursor) SetImage(texture string) Cursor {
+	c.ptr.Model().SetTex(0, texture)
+	return c
+}
+func (c *cursor) SetSize(w, h int) Cursor {
+	c.w, c.h = w, h
+	c.ptr.SetScale(float64(w), float64(h), 1)
+	return c
+}
+func (c *cursor) SetColor(r, g, b float64) Cursor {
+	c.ptr.Model().SetColor(r, g, b)
+	return c
+}
+func (c *cursor) SetHotspot(x, y int) Cursor { c.hx, c.hy = x, y; return c }
+func (c *cursor) Visible() bool              { return c.visible }
+func (c *cursor) SetVisible(visible bool) Cursor {
+	if visible != c.visible {
+		c.visible = visible
+		c.top.SetVisible(visible)
+		c.eng.ShowCursor(!visible)
+	}
+	return c
+}
+
+// Update keeps the camera projection matched to the window and places
+// the pointer so the hot spot is at the mouse. The quad is centered on
+// its location and the mouse y is up from the bottom of the window.
+func (c *cursor) Update(in *Input, s *State) {
+	if s.W != c.ww || s.H != c.wh {
+		c.ww, c.wh = s.W, s.H
+		c.cam.SetOrthographic(0, float64(s.W), 0, float64(s.H), 0, 10)
+	}
+	c.ptr.SetVisible(in.Focus && !in.Capture)
+	x := float64(in.Mx-c.hx) + float64(c.w)*0.5
+	y := float64(in.My+c.hy) - float64(c.h)*0.5
+	c.ptr.SetLocation(x, y, 0)
+}
+
+// Dispose removes the pointer scene and shows the OS cursor.
+func (c *cursor) Dispose() {
+	if c.visible {
+		c.eng.ShowCursor(true)
+	}
+	c.top.Dispose(PovNode)
+	c.top, c.ptr, c.cam = nil, nil, nil
+}
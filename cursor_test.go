@@ -0,0 +1,34 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"testing"
+
+	"github.com/gazed/vu/math/lin"
+)
+
+// Check that the software cursor puts its hot spot at the mouse
+// and is hidden while the mouse is captured.
+func TestCursor(t *testing.T) {
+	eng := newEngine(nil)
+	defer eng.Shutdown()
+	c := newCursor(eng, "pointer", 32, 16)
+	c.SetHotspot(4, 2)
+	in, s := &Input{Mx: 100, My: 50, Focus: true}, &State{W: 800, H: 600}
+	c.Update(in, s)
+	if x, y, _ := c.ptr.Location(); !lin.Aeq(x, 112) || !lin.Aeq(y, 44) {
+		t.Errorf("Expected pointer at 112 44, got %f %f", x, y)
+	}
+	if c.ww != 800 || c.wh != 600 || !c.ptr.Visible() {
+		t.Errorf("Expected visible pointer in 800x600 window")
+	}
+	in.Capture = true
+	if c.Update(in, s); c.ptr.Visible() {
+		t.Errorf("Expected pointer hidden while the mouse is captured")
+	}
+	if c.SetVisible(false); c.Visible() || c.top.Visible() {
+		t.Errorf("Expected hidden cursor")
+	}
+}
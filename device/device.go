@@ -2,14 +2,18 @@
 // Use is governed by a BSD-style license found in the LICENSE file.
 
 // Package device provides minimal platform/os access to a 3D rendering context
-// and user input. Access to user keyboard and mouse input is provided through
-// the Update method and Pressed structure. The application is responsible
+// and user input. Access to user keyboard, mouse, and gamepad input is provided
+// through the Update method and Pressed structure. The application is responsible
 // for providing any windowing constructs like buttons, controls, dialogs,
 // sub-panels, text-boxes, etc.
 //
 // Package device is provided as part of the vu (virtual universe) 3D engine.
 package device
 
+import (
+	"image"
+)
+
 // Big thanks to GLFW (http://www.glfw.org) from which the minimalist API
 // philosophy was borrowed along with which OS specific API's mattered.
 //
@@ -34,7 +38,17 @@ type Device interface {
 	Open()                // Open the window and process events.
 	ShowCursor(show bool) // Displays or hides the cursor.
 	SetCursorAt(x, y int) // Places the cursor at the given window location.
-	Dispose()             // Release OS specific resources.
+
+	// SetCursor replaces the cursor image while it is over the window.
+	// The hot spot is the image pixel, from the top left, that marks
+	// the cursor location. A nil image restores the default cursor.
+	SetCursor(img *image.NRGBA, hotx, hoty int)
+
+	// CaptureMouse hides the cursor and locks it to the window. Pressed
+	// then reports the raw relative mouse motion which keeps working
+	// when the cursor would otherwise stop at the window edge.
+	CaptureMouse(capture bool)
+	Dispose() // Release OS specific resources.
 
 	// IsAlive returns true if the window is alive processing user input.
 	// Quitting the application window will cause IsAlive to return false.
@@ -55,6 +69,11 @@ type Device interface {
 	Copy() string   // Returns nil if no string on clipboard.
 	Paste(s string) // Paste the given string onto the clipboard.
 
+	// KeyName returns the label of the physical key, as used in
+	// Pressed.Scan, on the current keyboard layout. An empty string
+	// is returned for keys without a layout label.
+	KeyName(scan int) string
+
 	// Update returns the current (key/mouse) pressed state.
 	// The calling application is expected to:
 	//   1. Treat the pressed information as read only.
@@ -70,14 +89,54 @@ type Device interface {
 // A negative duration means that the key has been released since
 // the last poll. The total pressed duration prior to release can be
 // determined using the difference with KEY_RELEASED.
+//
+// Keys in Down are translated by the keyboard layout on Windows so
+// KA is the key labelled A. Keys in Scan are identified by their
+// location on a US keyboard so KA is the key right of caps lock on
+// all layouts. OSX reports key locations in both Down and Scan.
+// Mouse buttons and modifier keys are the same in Down and Scan.
 type Pressed struct {
 	Mx, My  int         // Current mouse location.
+	Dx, Dy  int         // Mouse motion since the last poll.
+	Capture bool        // True if the mouse is captured.
 	Scroll  int         // The amount of scrolling, if any.
+	ScrollX float64     // Precise horizontal scrolling in lines.
+	ScrollY float64     // Precise vertical scrolling in lines.
 	Down    map[int]int // Pressed keys and pressed duration.
+	Scan    map[int]int // Down with keys identified by location.
 	Focus   bool        // True if window has focus.
 	Resized bool        // True if window was resized or moved.
+	Pads    []Pad       // Game controllers, one for each of MaxPads slots.
+	Touches []Touch     // Current touch points, if any.
+	Text    string      // Characters typed since the last poll.
+	Compose string      // Text being composed by an input method (IME).
+	Drops   []string    // Paths of files dropped on the window.
 }
 
+// Touch is one finger touching a touchscreen or trackpad. The ID stays
+// the same while the finger is down. Locations are window pixels with
+// trackpad touches mapped onto the window area.
+type Touch struct {
+	ID   int     // Unique while the touch is active.
+	X, Y float64 // Window location, origin bottom left.
+}
+
+// MaxTouches is the most touch points reported at one time.
+const MaxTouches = 10
+
+// Pad is the state of one gamepad or joystick. Buttons are tracked in
+// the Down map the same way as keys. The stick and trigger values in Axes
+// are the raw controller values without any dead zone applied.
+type Pad struct {
+	Connected bool        // True if a controller is using this slot.
+	Plugged   int         // 1 connected, -1 disconnected since last poll.
+	Down      map[int]int // Pressed buttons and pressed duration.
+	Axes      [6]float64  // Indexed by PadLx, PadLy, PadRx, PadRy, PadLt, PadRt.
+}
+
+// MaxPads is the number of game controller slots that are polled.
+const MaxPads = 4
+
 // KeyReleased is used to indicate a key up event has occurred.
 // The total duration of a key press can be calculated by the difference
 // of Pressed.Down duration with KEY_RELEASED. A user would have to hold
@@ -139,6 +198,11 @@ func (d *device) SwapBuffers()                    { d.os.swapBuffers() }
 func (d *device) IsFullScreen() bool              { return d.os.isFullscreen() }
 func (d *device) ToggleFullScreen()               { d.os.toggleFullscreen() }
 func (d *device) SetCursorAt(x, y int)            { d.os.setCursorAt(x, y) }
+func (d *device) CaptureMouse(capture bool)       { d.input.capture(d.os, capture) }
 func (d *device) Copy() string                    { return d.os.copyClip() }
 func (d *device) Paste(s string)                  { d.os.pasteClip(s) }
+func (d *device) KeyName(scan int) string         { return d.os.keyName(scan) }
 func (d *device) Update() *Pressed                { return d.input.pollEvents(d.os) }
+func (d *device) SetCursor(img *image.NRGBA, hotx, hoty int) {
+	d.os.setCursor(img, hotx, hoty)
+}
@@ -11,30 +11,79 @@ package device
 // that can be polled as needed.
 type input struct {
 	in   *userInput // Input is processed in a map of pressed keys.
+	pads []padInput // Raw game controller state read each poll.
 	curr *Pressed   // Consolidates current user events into state.
 	down *Pressed   // Clone of curr that is shared with the application.
+	mx   int        // Mouse location at the last poll.
+	my   int        //   "
+	seen bool       // True once the mouse location has been read.
 }
 
 // newInput creates the memory needed to process user input events.
 func newInput() *input {
 	i := &input{}
 	i.in = &userInput{}
-	i.curr = &Pressed{Focus: true, Down: map[int]int{}}
-	i.down = &Pressed{Focus: true, Down: map[int]int{}}
+	i.pads = make([]padInput, MaxPads)
+	i.curr = &Pressed{Focus: true, Down: map[int]int{}, Scan: map[int]int{}, Pads: newPads()}
+	i.down = &Pressed{Focus: true, Down: map[int]int{}, Scan: map[int]int{}, Pads: newPads()}
+	i.curr.Touches = make([]Touch, 0, MaxTouches)
+	i.down.Touches = make([]Touch, 0, MaxTouches)
 	return i
 }
 
+// newPads allocates the state for each game controller slot.
+func newPads() []Pad {
+	pads := make([]Pad, MaxPads)
+	for cnt := range pads {
+		pads[cnt].Down = map[int]int{}
+	}
+	return pads
+}
+
 // pollEvents is called from the main thread as some OS's only allow
 // event processing from the main thread. The events are placed in
 // the processing queue.
 func (i *input) pollEvents(os *nativeOs) *Pressed {
+	focus := i.curr.Focus
 	i.processEvent(os.readDispatch(i.in)) // sample events at twice the update rate
 	i.processEvent(os.readDispatch(i.in)) // ...by reading 2 events each update.
+	if i.curr.Capture && focus != i.curr.Focus {
+		os.captureMouse(i.curr.Focus) // free the cursor while not in focus.
+	}
+	i.processMotion(os)
+	i.processPads(os.readPads(i.pads))
+	i.curr.Touches = os.readTouches(i.curr.Touches)
+	i.curr.Text, i.curr.Compose = os.readText()
+	i.curr.Drops = os.readDrops(i.curr.Drops[:0])
 	i.updateDurations()
 	i.clone(i.curr, i.down)
 	return i.down
 }
 
+// capture locks or releases the mouse. The mouse is only locked while
+// the window has focus.
+func (i *input) capture(os *nativeOs, capture bool) {
+	if capture != i.curr.Capture {
+		i.curr.Capture = capture
+		os.captureMouse(capture && i.curr.Focus)
+		os.readMotion() // discard motion from before the change.
+	}
+}
+
+// processMotion records the mouse motion since the last poll. Captured
+// mice report the raw motion, otherwise it is the change in location.
+func (i *input) processMotion(os *nativeOs) {
+	dx, dy := os.readMotion() // always read to clear the native motion.
+	if !i.curr.Capture {
+		dx, dy = 0, 0
+		if i.seen {
+			dx, dy = i.curr.Mx-i.mx, i.curr.My-i.my
+		}
+	}
+	i.curr.Dx, i.curr.Dy = dx, dy
+	i.mx, i.my, i.seen = i.curr.Mx, i.curr.My, true
+}
+
 // processEvents updates the current input event buffer essentially turning
 // the user input stream into a map of what is currently pressed. A duration
 // of how long each key has been pressed is recorded in update ticks.
@@ -42,6 +91,8 @@ func (i *input) pollEvents(os *nativeOs) *Pressed {
 func (i *input) processEvent(event *userInput) {
 	i.curr.Mx, i.curr.My = event.mouseX, event.mouseY
 	i.curr.Scroll += event.scroll
+	i.curr.ScrollX += event.scrollx
+	i.curr.ScrollY += event.scrolly
 
 	// turn key and mouse events into state
 	switch event.id {
@@ -53,57 +104,70 @@ func (i *input) processEvent(event *userInput) {
 		i.curr.Focus = false
 		i.releaseAll()
 	case clickedMouse:
-		i.recordPress(event.button)
+		i.recordPress(event.button, event.button)
 	case releasedMouse:
-		i.recordRelease(event.button)
+		i.recordRelease(event.button, event.button)
 	case pressedKey:
-		i.recordPress(event.key)
+		i.recordPress(event.key, event.scan)
 	case releasedKey:
-		i.recordRelease(event.key)
+		i.recordRelease(event.key, event.scan)
 	default:
 		// capture modifier key state.
 		if event.mods&shiftKeyMask != 0 {
-			i.recordPress(shiftKey)
+			i.recordPress(shiftKey, shiftKey)
 		} else {
-			i.recordRelease(shiftKey)
+			i.recordRelease(shiftKey, shiftKey)
 		}
 		if event.mods&controlKeyMask != 0 {
-			i.recordPress(controlKey)
+			i.recordPress(controlKey, controlKey)
 		} else {
-			i.recordRelease(controlKey)
+			i.recordRelease(controlKey, controlKey)
 		}
 		if event.mods&functionKeyMask != 0 {
-			i.recordPress(functionKey)
+			i.recordPress(functionKey, functionKey)
 		} else {
-			i.recordRelease(functionKey)
+			i.recordRelease(functionKey, functionKey)
 		}
 		if event.mods&commandKeyMask != 0 {
-			i.recordPress(commandKey)
+			i.recordPress(commandKey, commandKey)
 		} else {
-			i.recordRelease(commandKey)
+			i.recordRelease(commandKey, commandKey)
 		}
 		if event.mods&altKeyMask != 0 {
-			i.recordPress(altKey)
+			i.recordPress(altKey, altKey)
 		} else {
-			i.recordRelease(altKey)
+			i.recordRelease(altKey, altKey)
 		}
 	}
 }
 
-// recordPress tracks new key or mouse down user input events.
+// recordPress tracks new key or mouse down user input events using
+// both the translated key code and the key location scan code.
 // Ignore any key presses unless the window has focus.
-func (i *input) recordPress(code int) {
+func (i *input) recordPress(code, scan int) {
 	if code >= 0 && i.curr.Focus {
-		if _, ok := i.curr.Down[code]; !ok {
-			i.curr.Down[code] = 0
-		}
+		press(i.curr.Down, code)
+		press(i.curr.Scan, scan)
 	}
 }
 
 // recordRelease tracks key or mouse up user input events.
-func (i *input) recordRelease(code int) {
-	if _, ok := i.curr.Down[code]; ok {
-		i.curr.Down[code] = i.curr.Down[code] + KeyReleased
+func (i *input) recordRelease(code, scan int) {
+	release(i.curr.Down, code)
+	release(i.curr.Scan, scan)
+}
+
+// press starts tracking the down duration of a new key press.
+func press(down map[int]int, code int) {
+	if _, ok := down[code]; !ok {
+		down[code] = 0
+	}
+}
+
+// release marks a pressed key as released.
+func release(down map[int]int, code int) {
+	if _, ok := down[code]; ok {
+		down[code] = down[code] + KeyReleased
 	}
 }
 
@@ -113,14 +177,71 @@ func (i *input) releaseAll() {
 	for code, down := range i.curr.Down {
 		i.curr.Down[code] = down + KeyReleased
 	}
+	for code, down := range i.curr.Scan {
+		i.curr.Scan[code] = down + KeyReleased
+	}
+	for cnt := range i.curr.Pads {
+		releasePad(i.curr.Pads[cnt].Down)
+	}
+}
+
+// processPads turns the raw game controller state into pressed buttons.
+// Controllers that are plugged in or removed are flagged for one poll.
+// Button presses are ignored unless the window has focus.
+func (i *input) processPads(raw []padInput) {
+	for cnt := range raw {
+		rp, pad := &raw[cnt], &i.curr.Pads[cnt]
+		switch {
+		case rp.connected && !pad.Connected:
+			pad.Plugged = 1
+		case !rp.connected && pad.Connected:
+			pad.Plugged = -1
+		}
+		pad.Connected = rp.connected
+		if !rp.connected {
+			pad.Axes = [6]float64{}
+			releasePad(pad.Down)
+			continue
+		}
+		pad.Axes = rp.axes
+		for _, code := range padButtons {
+			val, ok := pad.Down[code]
+			switch {
+			case rp.buttons&code != 0 && i.curr.Focus:
+				if !ok {
+					pad.Down[code] = 0
+				}
+			case ok && val >= 0:
+				pad.Down[code] = val + KeyReleased
+			}
+		}
+	}
+}
+
+// releasePad marks all pressed controller buttons as released.
+func releasePad(down map[int]int) {
+	for code, val := range down {
+		if val >= 0 {
+			down[code] = val + KeyReleased
+		}
+	}
 }
 
 // updateDurations tracks how long keys have been pressed for.
 // Expected to be called each update. Ignore released keys.
 func (i *input) updateDurations() {
-	for key, val := range i.curr.Down {
+	tick(i.curr.Down)
+	tick(i.curr.Scan)
+	for cnt := range i.curr.Pads {
+		tick(i.curr.Pads[cnt].Down)
+	}
+}
+
+// tick adds an update to the duration of each held key.
+func tick(down map[int]int) {
+	for code, val := range down {
 		if val >= 0 {
-			i.curr.Down[key] = val + 1
+			down[code] = val + 1
 		}
 	}
 }
@@ -129,21 +250,41 @@ func (i *input) updateDurations() {
 // shared with the outside process. Remove any released keys from the map.
 // This method is expected to be called by i.pollEvents().
 func (i *input) clone(in, out *Pressed) {
-	for key := range out.Down {
-		delete(out.Down, key)
-	}
-	for key, val := range in.Down {
-		out.Down[key] = val
-		if val < 0 {
-			delete(in.Down, key) // remove released keys.
-		}
-	}
+	cloneDown(in.Down, out.Down)
+	cloneDown(in.Scan, out.Scan)
 	out.Mx, out.My = in.Mx, in.My
+	out.Dx, out.Dy = in.Dx, in.Dy
+	out.Capture = in.Capture
+	out.Touches = append(out.Touches[:0], in.Touches...)
+	out.Text, out.Compose = in.Text, in.Compose
+	out.Drops = append(out.Drops[:0], in.Drops...)
 	out.Focus = in.Focus
 	out.Resized = in.Resized
 	out.Scroll = in.Scroll
-	in.Scroll = 0      // remove previous scroll info.
+	out.ScrollX, out.ScrollY = in.ScrollX, in.ScrollY
+	in.Scroll = 0 // remove previous scroll info.
+	in.ScrollX, in.ScrollY = 0, 0
 	in.Resized = false // remove previous resized trigger.
+	for cnt := range in.Pads {
+		ip, op := &in.Pads[cnt], &out.Pads[cnt]
+		cloneDown(ip.Down, op.Down)
+		op.Connected, op.Plugged, op.Axes = ip.Connected, ip.Plugged, ip.Axes
+		ip.Plugged = 0 // remove previous hot-plug trigger.
+	}
+}
+
+// cloneDown replaces out with the key durations in, then
+// removes the released keys from in.
+func cloneDown(in, out map[int]int) {
+	for code := range out {
+		delete(out, code)
+	}
+	for code, val := range in {
+		out[code] = val
+		if val < 0 {
+			delete(in, code) // remove released keys.
+		}
+	}
 }
 
 // input
@@ -158,12 +299,34 @@ type userInput struct {
 	mouseY int // Current mouse Y position.
 	button int // Currently pressed mouse button (if any).
 	key    int // Current key pressed (if any).
+	scan   int // Location of the current key (if any).
 	mods   int // Mask of the current modifier keys (if any).
 	scroll int // Scroll amount (if any).
+
+	scrollx float64 // Precise horizontal scroll lines (if any).
+	scrolly float64 // Precise vertical scroll lines (if any).
 }
 
 // userInput
 // ===========================================================================
+// padInput
+
+// padInput is filled in by readPads. It is the raw state of one game
+// controller slot as reported by the native layer.
+type padInput struct {
+	connected bool       // True if a controller is using this slot.
+	buttons   int        // Mask of the currently pressed buttons.
+	axes      [6]float64 // Sticks -1 to 1, positive right and up. Triggers 0 to 1.
+}
+
+// padButtons lists each of the buttons in the padInput mask.
+var padButtons = []int{
+	PadUp, PadDown, PadLeft, PadRight, PadStart, PadBack, PadLs, PadRs,
+	PadLb, PadRb, PadGuide, PadA, PadB, PadX, PadY,
+}
+
+// padInput
+// ===========================================================================
 // internal event, key, key-code to string mappings.
 
 // The possible event id's are as follows.
@@ -306,3 +469,34 @@ const (
 	KCmd   = commandKey        //   "
 	KAlt   = altKey            //   "
 )
+
+// Gamepad buttons are reported per controller in Pad.Down. The values
+// follow the XInput button masks and are the same for each native layer.
+// Controllers without a guide button never report PadGuide.
+const (
+	PadUp    = 0x0001 // Directional pad.
+	PadDown  = 0x0002 //   "
+	PadLeft  = 0x0004 //   "
+	PadRight = 0x0008 //   "
+	PadStart = 0x0010 // Menu buttons.
+	PadBack  = 0x0020 //   "
+	PadLs    = 0x0040 // Stick buttons: pressing down on a stick.
+	PadRs    = 0x0080 //   "
+	PadLb    = 0x0100 // Shoulder bumpers.
+	PadRb    = 0x0200 //   "
+	PadGuide = 0x0400 // Branded center button.
+	PadA     = 0x1000 // Face buttons.
+	PadB     = 0x2000 //   "
+	PadX     = 0x4000 //   "
+	PadY     = 0x8000 //   "
+)
+
+// Indexes for the stick and trigger values in Pad.Axes.
+const (
+	PadLx = iota // Left stick -1 left to 1 right.
+	PadLy        // Left stick -1 down to 1 up.
+	PadRx        // Right stick -1 left to 1 right.
+	PadRy        // Right stick -1 down to 1 up.
+	PadLt        // Left trigger 0 released to 1 fully pressed.
+	PadRt        // Right trigger 0 released to 1 fully pressed.
+)
@@ -4,6 +4,7 @@
 package device
 
 import (
+	"image"
 	"log"
 )
 
@@ -38,6 +39,32 @@ type native interface {
 	// to process.
 	readDispatch(r *nrefs, in *userInput) *userInput
 
+	// readPads fills in the current state of each game controller slot.
+	// Slots are kept stable so a controller keeps its slot while connected.
+	//    osx: GCController extendedGamepad.
+	//    win: XInputGetState.
+	readPads(r *nrefs, pads []padInput)
+
+	// readTouches fills in the current touch points and returns
+	// the number of active touches.
+	//    osx: NSEvent touchesMatchingPhase:NSTouchPhaseTouching.
+	//    win: WM_TOUCH GetTouchInputInfo.
+	readTouches(r *nrefs, touches []Touch) int
+
+	// readText returns the unicode characters typed since the last call.
+	// Characters are reported after any input method has processed the key
+	// presses. The text still being composed by an input method is also
+	// returned. Control characters, like backspace, are not included.
+	//    osx: NSTextInputClient insertText, setMarkedText.
+	//    win: WM_CHAR, WM_IME_COMPOSITION.
+	readText(r *nrefs) (text, compose string)
+
+	// readDrop returns the next file path dropped on the window, or the
+	// empty string if there are no more dropped files.
+	//    osx: NSDraggingDestination performDragOperation.
+	//    win: WM_DROPFILES DragQueryFile.
+	readDrop(r *nrefs) string
+
 	// shell creates the "window" on the given display. In some cases this is
 	// a window and in others it holds device independent attributes. The supplied
 	// Shell structure's id is set to a reference of the underlying OS structure.
@@ -72,6 +99,24 @@ type native interface {
 	//    win: SetCursorPos(loc.x, loc.y);
 	setCursorAt(r *nrefs, x, y int)
 
+	// setCursor uses the RGBA pixels, rows from the top, as the cursor
+	// image. A zero width or height restores the default cursor.
+	//    osx: [[NSCursor alloc] initWithImage:image hotSpot:point];
+	//    win: CreateIconIndirect(&info); SetCursor(cursor);
+	setCursor(r *nrefs, rgba []byte, width, height, hotx, hoty int)
+
+	// captureMouse hides the cursor and locks it to the window so that
+	// mouse motion is not limited by the window or screen edges.
+	//    osx: CGAssociateMouseAndMouseCursorPosition(false);
+	//    win: ClipCursor(&rect); RegisterRawInputDevices(...);
+	captureMouse(r *nrefs, capture bool)
+
+	// readMotion returns the relative mouse motion accumulated since the
+	// last call. Positive y is up to match the window coordinates.
+	//    osx: [event deltaX], [event deltaY]
+	//    win: WM_INPUT RAWMOUSE lLastX, lLastY
+	readMotion(r *nrefs) (dx, dy int)
+
 	// context creates an OpenGL context and fills in the context field of the
 	// nrefs structure. For example the context field is:
 	//    osx: pointer to NSOpenGLContext
@@ -118,6 +163,12 @@ type native interface {
 	// pasteClip gets a string from the system clipboard and returns nil
 	// if there was no string on the system clipboard.
 	pasteClip(r *nrefs, s string)
+
+	// keyName returns the label of the key location on the current
+	// keyboard layout, or an empty string if there is none.
+	//    osx: UCKeyTranslate(layout, keyCode, kUCKeyActionDisplay, ...);
+	//    win: GetKeyNameTextW(scanCode << 16, name, size);
+	keyName(r *nrefs, scan int) string
 }
 
 // native
@@ -196,6 +247,27 @@ func (os *nativeOs) showCursor(show bool) { os.nl.showCursor(os.nr, show) }
 // setCursor places the cursor at the given screen coordinates.
 func (os *nativeOs) setCursorAt(x, y int) { os.nl.setCursorAt(os.nr, x, y) }
 
+// setCursor packs the image pixels into rows for the native cursor.
+func (os *nativeOs) setCursor(img *image.NRGBA, hotx, hoty int) {
+	if img == nil || img.Rect.Empty() {
+		os.nl.setCursor(os.nr, nil, 0, 0, 0, 0)
+		return
+	}
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	rgba := make([]byte, 0, w*h*4)
+	for y := 0; y < h; y++ {
+		start := img.PixOffset(img.Rect.Min.X, img.Rect.Min.Y+y)
+		rgba = append(rgba, img.Pix[start:start+w*4]...)
+	}
+	os.nl.setCursor(os.nr, rgba, w, h, hotx, hoty)
+}
+
+// captureMouse locks or releases the mouse.
+func (os *nativeOs) captureMouse(capture bool) { os.nl.captureMouse(os.nr, capture) }
+
+// readMotion returns the relative mouse motion since the last call.
+func (os *nativeOs) readMotion() (dx, dy int) { return os.nl.readMotion(os.nr) }
+
 // createContext makes and initializes the OpenGL context.
 func (os *nativeOs) createContext(depth, alpha int) {
 	os.nl.setDepthBufferSize(depth)
@@ -223,9 +295,34 @@ func (os *nativeOs) readDispatch(in *userInput) *userInput {
 	return os.nl.readDispatch(os.nr, in)
 }
 
+// readPads polls the game controllers from the native OS.
+func (os *nativeOs) readPads(pads []padInput) []padInput {
+	os.nl.readPads(os.nr, pads)
+	return pads
+}
+
+// readTouches polls the current touch points from the native OS.
+func (os *nativeOs) readTouches(touches []Touch) []Touch {
+	return touches[:os.nl.readTouches(os.nr, touches[:cap(touches)])]
+}
+
+// readText polls the typed characters from the native OS.
+func (os *nativeOs) readText() (text, compose string) { return os.nl.readText(os.nr) }
+
+// readDrops appends the file paths dropped since the last call.
+func (os *nativeOs) readDrops(drops []string) []string {
+	for path := os.nl.readDrop(os.nr); path != ""; path = os.nl.readDrop(os.nr) {
+		drops = append(drops, path)
+	}
+	return drops
+}
+
 // copyClip puts the given string on the system clipboard.
 func (os *nativeOs) copyClip() string { return os.nl.copyClip(os.nr) }
 
 // pasteClip gets a string from the system clipboard and returns nil
 // if there was no string on the system clipboard.
 func (os *nativeOs) pasteClip(s string) { os.nl.pasteClip(os.nr, s) }
+
+// keyName returns the layout label for the key location.
+func (os *nativeOs) keyName(scan int) string { return os.nl.keyName(os.nr, scan) }
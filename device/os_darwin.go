@@ -10,7 +10,7 @@ package device
 // // The following block is C code and cgo directvies.
 //
 // #cgo darwin CFLAGS: -x objective-c -fno-common
-// #cgo darwin LDFLAGS: -framework Cocoa -framework OpenGL
+// #cgo darwin LDFLAGS: -framework Cocoa -framework OpenGL -framework GameController -framework Carbon
 //
 // #include <stdlib.h>
 // #include "os_darwin.h"
@@ -24,6 +24,8 @@ import (
 // OS specific structure to differentiate it from the other native layers.
 type osx struct {
 	gsu *C.GSEvent
+	gsp [MaxPads]C.GSPad      // Game controller state reused each poll.
+	gst [MaxTouches]C.GSTouch // Touch points reused each poll.
 }
 
 // OSX specific. Otherwise the shell will freeze within seconds of creation.
@@ -56,6 +58,58 @@ func (o *osx) showCursor(r *nrefs, show bool) {
 	}
 	C.gs_show_cursor(C.uchar(trueFalse))
 }
+func (o *osx) setCursor(r *nrefs, rgba []byte, width, height, hotx, hoty int) {
+	var pixels *C.uchar
+	if len(rgba) > 0 {
+		pixels = (*C.uchar)(unsafe.Pointer(&rgba[0]))
+	}
+	C.gs_set_cursor(C.long(r.display), pixels, C.long(width), C.long(height), C.long(hotx), C.long(hoty))
+}
+
+// Implement native interface.
+func (o *osx) readTouches(r *nrefs, touches []Touch) int {
+	cnt := int(C.gs_read_touches(&o.gst[0], C.long(len(o.gst))))
+	if cnt > len(touches) {
+		cnt = len(touches)
+	}
+	for index := 0; index < cnt; index++ {
+		gt := &o.gst[index]
+		touches[index] = Touch{ID: int(gt.id), X: float64(gt.x), Y: float64(gt.y)}
+	}
+	return cnt
+}
+
+// Implement native interface: nrefs unused, needed by other platforms.
+func (o *osx) readText(r *nrefs) (text, compose string) {
+	return freeString(C.gs_read_text()), freeString(C.gs_read_compose())
+}
+func (o *osx) readDrop(r *nrefs) string { return freeString(C.gs_read_drop()) }
+func (o *osx) keyName(r *nrefs, scan int) string {
+	return freeString(C.gs_key_name(C.long(scan)))
+}
+
+// freeString makes a Go copy of the given C string and frees the C copy.
+func freeString(cstr *C.char) string {
+	if cstr == nil {
+		return ""
+	}
+	defer C.free(unsafe.Pointer(cstr))
+	return C.GoString(cstr)
+}
+
+// Implement native interface.
+func (o *osx) captureMouse(r *nrefs, capture bool) {
+	trueFalse := 0 // trueFalse needs to be 0 or 1.
+	if capture {
+		trueFalse = 1
+	}
+	C.gs_capture_mouse(C.long(r.display), C.uchar(trueFalse))
+}
+func (o *osx) readMotion(r *nrefs) (dx, dy int) {
+	var mx, my C.long
+	C.gs_read_motion(&mx, &my)
+	return int(mx), int(my)
+}
 
 // Implement native interface.
 func (o *osx) readDispatch(r *nrefs, in *userInput) *userInput {
@@ -64,6 +118,8 @@ func (o *osx) readDispatch(r *nrefs, in *userInput) *userInput {
 	o.gsu.mousey = -1
 	o.gsu.key = 0
 	o.gsu.scroll = 0
+	o.gsu.scrollx = 0
+	o.gsu.scrolly = 0
 
 	// o.gsu.mods retain the modifier key state between calls.
 	C.gs_read_dispatch(C.long(r.display), o.gsu)
@@ -73,9 +129,12 @@ func (o *osx) readDispatch(r *nrefs, in *userInput) *userInput {
 	if in.id != 0 {
 		in.button = mouseButtons[int(o.gsu.event)]
 		in.key = int(o.gsu.key)
+		in.scan = in.key // OSX key codes are key locations.
 		in.scroll = int(o.gsu.scroll)
+		in.scrollx, in.scrolly = float64(o.gsu.scrollx), float64(o.gsu.scrolly)
 	} else {
-		in.button, in.key, in.scroll = 0, 0, 0
+		in.button, in.key, in.scan, in.scroll = 0, 0, 0, 0
+		in.scrollx, in.scrolly = 0, 0
 	}
 	in.mods = int(o.gsu.mods) & (controlKeyMask | shiftKeyMask | functionKeyMask | commandKeyMask | altKeyMask)
 	in.mouseX = int(o.gsu.mousex)
@@ -83,6 +142,23 @@ func (o *osx) readDispatch(r *nrefs, in *userInput) *userInput {
 	return in
 }
 
+// Implement native interface: nrefs unused, needed by other platforms.
+func (o *osx) readPads(r *nrefs, pads []padInput) {
+	cnt := len(pads)
+	if cnt > len(o.gsp) {
+		cnt = len(o.gsp)
+	}
+	C.gs_read_pads(&o.gsp[0], C.long(cnt))
+	for index := 0; index < cnt; index++ {
+		gp, pad := &o.gsp[index], &pads[index]
+		pad.connected = gp.connected == 1
+		pad.buttons = int(gp.buttons)
+		for axis := range pad.axes {
+			pad.axes[axis] = float64(gp.axes[axis])
+		}
+	}
+}
+
 // Implement native interface.
 func (o *osx) size(r *nrefs) (x, y, w, h int) {
 	var winx, winy, width, height float32
@@ -22,8 +22,24 @@ typedef struct {
     long key;     // which key, or mouse button was affected, if any.
     long mods;    // which modifier keys are currently pressed, if any.
     long scroll;  // the scroll amount if any.
+    float scrollx; // precise horizontal scroll in lines, if any.
+    float scrolly; // precise vertical scroll in lines, if any.
 } GSEvent;
 
+// Used to pass back game controller state on each polling call.
+typedef struct {
+    long  connected; // 1 if a controller is using this slot.
+    long  buttons;   // mask of the currently pressed GS_Pad buttons.
+    float axes[6];   // lx, ly, rx, ry from -1 to 1, lt, rt from 0 to 1.
+} GSPad;
+
+// Used to pass back the current touch points on each polling call.
+typedef struct {
+    long  id;   // unique while the touch is active.
+    float x;    // window location with origin at the bottom left.
+    float y;    // window location with origin at the bottom left.
+} GSTouch;
+
 // Initialize the underlying Cocoa layer and create the default application.
 // Returns a reference to the shared NSApplication instance (display).
 long gs_display_init();
@@ -65,6 +81,43 @@ void gs_show_cursor(unsigned char show);
 // Set the cursor location to the given screen coordinates.
 void gs_set_cursor_location(long display, long x, long y);
 
+// Use the given RGBA image as the window cursor. The hot spot is the
+// image pixel, from the top left, that is the cursor location.
+// A zero width or height restores the default arrow cursor.
+void gs_set_cursor(long display, unsigned char *rgba, long width, long height, long hotx, long hoty);
+
+// Hide the cursor and stop it moving while capture is true.
+// Mouse motion is still reported while the mouse is captured.
+void gs_capture_mouse(long display, unsigned char capture);
+
+// Get and clear the relative mouse motion since the last call.
+void gs_read_motion(long *dx, long *dy);
+
+// Fill in the state of the first count game controller slots.
+// Controllers are read using the GameController framework and need
+// to support the extended gamepad profile.
+void gs_read_pads(GSPad *pads, long count);
+
+// Fill in up to count current touch points returning the number of touches.
+// Trackpad touches are mapped onto the window area.
+long gs_read_touches(GSTouch *touches, long count);
+
+// Get, and clear, the characters typed since the last call. Returns NULL
+// if nothing was typed. Also get the input method text being composed.
+// Returned strings must be freed by the caller.
+char* gs_read_text();
+char* gs_read_compose();
+
+// Get, and remove, the next file path dropped on the window.
+// Returns NULL if there are no dropped files. The returned string
+// must be freed by the caller.
+char* gs_read_drop();
+
+// Get the keyboard layout name of the key with the given key code.
+// Returns NULL if the key has no printable name. The returned string
+// must be freed by the caller.
+char* gs_key_name(long key);
+
 // Create an OpenGL context using the given shell. Subsequent calls will
 // return the current context (ignoring the input parameter).
 //
@@ -135,3 +188,23 @@ enum {
    GS_CommandKeyMask    = 1 << 20,  // NSCommandKeyMask
    GS_FunctionKeyMask   = 1 << 23,  // NSFunctionKeyMask
 };
+
+// Game controller button masks. These match the XInput button masks
+// and must be the same for each native layer.
+enum {
+    GS_PadUp    = 0x0001,
+    GS_PadDown  = 0x0002,
+    GS_PadLeft  = 0x0004,
+    GS_PadRight = 0x0008,
+    GS_PadStart = 0x0010,
+    GS_PadBack  = 0x0020,
+    GS_PadLs    = 0x0040,
+    GS_PadRs    = 0x0080,
+    GS_PadLb    = 0x0100,
+    GS_PadRb    = 0x0200,
+    GS_PadGuide = 0x0400,
+    GS_PadA     = 0x1000,
+    GS_PadB     = 0x2000,
+    GS_PadX     = 0x4000,
+    GS_PadY     = 0x8000
+};
@@ -10,6 +10,8 @@
 //    https://lists.apple.com/archives/Mac-opengl/2010/Mar/msg00078.html
 
 #import <Cocoa/Cocoa.h>
+#import <GameController/GameController.h>
+#import <Carbon/Carbon.h>
 #import "os_darwin.h"
 
 // Application defaults. Internal use only.
@@ -44,6 +46,73 @@ void gs_set_cursor_location(long display, long x, long y) {
     CGAssociateMouseAndMouseCursorPosition(true);
 }
 
+// Relative mouse motion accumulated from mouse move and drag events.
+static double gs_motion_x = 0;
+static double gs_motion_y = 0;
+static unsigned char gs_captured = 0;
+
+// Hide the cursor and stop it from moving. The mouse move events still
+// report the motion deltas. The cursor is first centered in the window
+// so that it reappears in the window when the capture is released.
+void gs_capture_mouse(long display, unsigned char capture) {
+    if (capture == gs_captured) {
+        return;
+    }
+    gs_captured = capture;
+    NSWindow *window = [(id)display mainWindow];
+    if (capture) {
+        NSRect content = [window contentRectForFrameRect:[window frame]];
+        gs_set_cursor_location(display, content.size.width/2, content.size.height/2);
+        [window setAcceptsMouseMovedEvents:YES];
+        [NSCursor hide];
+        CGAssociateMouseAndMouseCursorPosition(false);
+    } else {
+        CGAssociateMouseAndMouseCursorPosition(true);
+        [NSCursor unhide];
+    }
+}
+
+// Get and clear the accumulated mouse motion. Cocoa deltas are positive
+// down so they are flipped to match the window coordinates.
+void gs_read_motion(long *dx, long *dy) {
+    *dx = (long)gs_motion_x;
+    *dy = (long)-gs_motion_y;
+    gs_motion_x -= (double)*dx; // keep any fractional motion.
+    gs_motion_y += (double)*dy;
+}
+
+// Active touch points updated from trackpad touch events.
+static GSTouch gs_touches[10];
+static long gs_touch_count = 0;
+
+// Replace the touch points with those in the given touch event.
+// Trackpad positions are normalized so they are scaled to the view size.
+void gs_touch_update(long display, NSEvent *event) {
+    NSView *view = [[(id)display mainWindow] contentView];
+    NSSize size = [view bounds].size;
+    NSSet *touches = [event touchesMatchingPhase:NSTouchPhaseTouching inView:view];
+    gs_touch_count = 0;
+    for (NSTouch *touch in touches) {
+        if (gs_touch_count >= (long)(sizeof(gs_touches) / sizeof(gs_touches[0]))) {
+            break;
+        }
+        NSPoint pos = [touch normalizedPosition];
+        GSTouch *gt = &gs_touches[gs_touch_count++];
+        gt->id = (long)[[touch identity] hash];
+        gt->x = pos.x * size.width;
+        gt->y = pos.y * size.height;
+    }
+}
+
+// Copy out the current touch points.
+long gs_read_touches(GSTouch *touches, long count) {
+    long cnt;
+    for (cnt = 0; cnt < count && cnt < gs_touch_count; cnt++) {
+        touches[cnt] = gs_touches[cnt];
+    }
+    return cnt;
+}
+
 // Get the current scroll wheel value. This will be 0 if the last event was
 // not a scroll event.
 void gs_scroll(long display, float *x_delta, float *y_delta) {
@@ -67,6 +136,38 @@ void gs_show_cursor(unsigned char show) {
     }
 }
 
+// Custom window cursor, or nil for the default arrow. It is applied
+// to the window content through the EventDelegate cursor rectangle.
+static NSCursor *gs_cursor = nil;
+
+// Create a cursor from the RGBA pixels and use it for the window content.
+void gs_set_cursor(long display, unsigned char *rgba, long width, long height, long hotx, long hoty) {
+    [gs_cursor release];
+    gs_cursor = nil;
+    if (width > 0 && height > 0) {
+        NSBitmapImageRep *rep = [[NSBitmapImageRep alloc]
+            initWithBitmapDataPlanes:NULL
+                          pixelsWide:width
+                          pixelsHigh:height
+                       bitsPerSample:8
+                     samplesPerPixel:4
+                            hasAlpha:YES
+                            isPlanar:NO
+                      colorSpaceName:NSDeviceRGBColorSpace
+                         bytesPerRow:width*4
+                        bitsPerPixel:32];
+        memcpy([rep bitmapData], rgba, width*height*4);
+        NSImage *image = [[NSImage alloc] initWithSize:NSMakeSize(width, height)];
+        [image addRepresentation:rep];
+        gs_cursor = [[NSCursor alloc] initWithImage:image hotSpot:NSMakePoint(hotx, hoty)];
+        [image release];
+        [rep release];
+    }
+    NSWindow *window = [(id)display mainWindow];
+    [window invalidateCursorRectsForView:[window contentView]];
+    [(gs_cursor != nil ? gs_cursor : [NSCursor arrowCursor]) set];
+}
+
 // Called before running the application to create a few menu items.
 //
 // This uses a hidden API (setAppleMenu) so that the application menu can
@@ -116,8 +217,78 @@ long gs_win_alive = -1;
 // figure out what particular mouse clicks and drags mean.
 // These will be triggered as the underlying window processes the mouse moves and
 // clicks sent during the gs_read_dispatch calls.
-@interface EventDelegate : NSView <NSWindowDelegate> { }
+@interface EventDelegate : NSView <NSWindowDelegate, NSTextInputClient> { }
 @end
+
+// Typed characters and the current input method composition.
+// These are updated by the NSTextInputClient methods.
+static NSMutableString *gs_text = nil;
+static NSString *gs_compose = nil;
+
+// Return and clear the typed characters.
+// The returned string must be freed by the caller.
+char* gs_read_text() {
+    if (gs_text == nil || [gs_text length] == 0) {
+        return NULL;
+    }
+    char *text = strdup([gs_text UTF8String]);
+    [gs_text setString:@""];
+    return text;
+}
+
+// Return the input method composition.
+// The returned string must be freed by the caller.
+char* gs_read_compose() {
+    if (gs_compose == nil || [gs_compose length] == 0) {
+        return NULL;
+    }
+    return strdup([gs_compose UTF8String]);
+}
+
+// Translate the key code using the current keyboard layout
+// ignoring modifiers and dead keys.
+char* gs_key_name(long key) {
+    TISInputSourceRef source = TISCopyCurrentKeyboardLayoutInputSource();
+    if (source == NULL) {
+        return NULL;
+    }
+    char *name = NULL;
+    CFDataRef data = TISGetInputSourceProperty(source, kTISPropertyUnicodeKeyLayoutData);
+    if (data != NULL) {
+        const UCKeyboardLayout *layout = (const UCKeyboardLayout *)CFDataGetBytePtr(data);
+        UInt32 deadKeys = 0;
+        UniChar chars[4];
+        UniCharCount length = 0;
+        OSStatus status = UCKeyTranslate(layout, (UInt16)key, kUCKeyActionDisplay, 0, LMGetKbdType(),
+            kUCKeyTranslateNoDeadKeysBit, &deadKeys, 4, &length, chars);
+        if (status == noErr && length > 0 && chars[0] > 0x20 && chars[0] != 0x7F) {
+            NSString *label = [[NSString stringWithCharacters:chars length:length] uppercaseString];
+            name = strdup([label UTF8String]);
+        }
+    }
+    CFRelease(source);
+    return name;
+}
+
+// File paths dropped on the window.
+static NSMutableArray *gs_drops = nil;
+
+// Return, and remove, the oldest dropped file path.
+// The returned string must be freed by the caller.
+char* gs_read_drop() {
+    if (gs_drops == nil || [gs_drops count] == 0) {
+        return NULL;
+    }
+    char *path = strdup([[gs_drops objectAtIndex:0] fileSystemRepresentation]);
+    [gs_drops removeObjectAtIndex:0];
+    return path;
+}
+
+// Set the current input method composition, nil to clear it.
+void gs_set_compose(NSString *compose) {
+    [gs_compose release];
+    gs_compose = [compose copy];
+}
 @implementation EventDelegate
 -(void)windowWillClose:(NSNotification *)notification { gs_win_alive = -2; }
 -(void)windowDidResize:(NSNotification *)notification { winEvent = GS_WindowResized; }
@@ -130,8 +301,54 @@ long gs_win_alive = -1;
 // let OS know that this app handles keys in order to prevent beeping.
 -(BOOL)canBecomeKeyView { return YES; }
 -(BOOL)acceptsFirstResponder { return YES; }
--(void)keyUp:(NSNotification *)notification {  }
--(void)keyDown:(NSNotification *)notification { }
+-(void)keyUp:(NSEvent *)event {  }
+
+// use any custom cursor while the mouse is over the window content.
+-(void)resetCursorRects {
+    [self addCursorRect:[self bounds] cursor:(gs_cursor != nil ? gs_cursor : [NSCursor arrowCursor])];
+}
+
+// pass key presses through the input methods. Typed characters come back
+// through the NSTextInputClient methods below.
+-(void)keyDown:(NSEvent *)event { [self interpretKeyEvents:[NSArray arrayWithObject:event]]; }
+
+// Implement NSTextInputClient. Only the typed and composed text is kept.
+-(void)insertText:(id)string replacementRange:(NSRange)range {
+    NSString *text = [string isKindOfClass:[NSAttributedString class]] ? [string string] : string;
+    if (gs_text == nil) {
+        gs_text = [[NSMutableString alloc] init];
+    }
+    [gs_text appendString:text];
+    gs_set_compose(nil);
+}
+-(void)setMarkedText:(id)string selectedRange:(NSRange)selected replacementRange:(NSRange)range {
+    gs_set_compose([string isKindOfClass:[NSAttributedString class]] ? [string string] : string);
+}
+-(void)unmarkText { gs_set_compose(nil); }
+-(BOOL)hasMarkedText { return gs_compose != nil && [gs_compose length] > 0; }
+-(NSRange)markedRange {
+    return [self hasMarkedText] ? NSMakeRange(0, [gs_compose length]) : NSMakeRange(NSNotFound, 0);
+}
+-(NSRange)selectedRange { return NSMakeRange(0, 0); }
+-(NSArray *)validAttributesForMarkedText { return [NSArray array]; }
+-(NSAttributedString *)attributedSubstringForProposedRange:(NSRange)range actualRange:(NSRangePointer)actual { return nil; }
+-(NSUInteger)characterIndexForPoint:(NSPoint)point { return NSNotFound; }
+-(NSRect)firstRectForCharacterRange:(NSRange)range actualRange:(NSRangePointer)actual {
+    NSRect frame = [[self window] frame]; // place candidate windows at the window bottom left.
+    return NSMakeRect(frame.origin.x, frame.origin.y, 0, 0);
+}
+-(void)doCommandBySelector:(SEL)selector { } // ignore commands like backspace to prevent beeping.
+
+// Implement NSDraggingDestination to accept files dropped on the window.
+-(NSDragOperation)draggingEntered:(id <NSDraggingInfo>)sender { return NSDragOperationCopy; }
+-(BOOL)performDragOperation:(id <NSDraggingInfo>)sender {
+    NSArray *files = [[sender draggingPasteboard] propertyListForType:NSFilenamesPboardType];
+    if (gs_drops == nil) {
+        gs_drops = [[NSMutableArray alloc] init];
+    }
+    [gs_drops addObjectsFromArray:files];
+    return YES;
+}
 @end
 
 // Create the top level application (display).
@@ -166,6 +383,8 @@ long createShell(long display) {
     // Hook in the delegate.
     EventDelegate *delegate = [[[EventDelegate alloc] initWithFrame:frame] autorelease];
     [window setContentView:delegate];
+    [delegate setAcceptsTouchEvents:YES];
+    [delegate registerForDraggedTypes:[NSArray arrayWithObject:NSFilenamesPboardType]];
     [window setDelegate:delegate];
     [window makeKeyWindow];
     [window orderBack:nil];
@@ -234,6 +453,22 @@ void gs_read_dispatch(long display, GSEvent *urge) {
                         untilDate:nil
                            inMode:NSDefaultRunLoopMode
                           dequeue:YES];
+        if (nil != event) {
+            switch ([event type]) {
+            case NSMouseMoved:
+            case NSLeftMouseDragged:
+            case NSRightMouseDragged:
+            case NSOtherMouseDragged:
+                gs_motion_x += [event deltaX];
+                gs_motion_y += [event deltaY];
+                break;
+            case NSEventTypeGesture:
+                gs_touch_update(display, event);
+                break;
+            default:
+                break;
+            }
+        }
         if (nil != event && GS_MouseMoved != [event type]) {
             urge->event = (long) [event type];
             [(id)display sendEvent:event]; // could create a new winEvent.
@@ -245,6 +480,11 @@ void gs_read_dispatch(long display, GSEvent *urge) {
                 float dx, dy;
                 gs_scroll(display, &dx, &dy);
                 urge->scroll = (long)dy;
+
+                // trackpad deltas are in points rather than lines.
+                float lines = [event hasPreciseScrollingDeltas] ? 0.1 : 1.0;
+                urge->scrollx = [event scrollingDeltaX] * lines;
+                urge->scrolly = [event scrollingDeltaY] * lines;
             } else if (urge->event == GS_ModKeysChanged) {
                 urge->mods = (long) ([event modifierFlags] & NSDeviceIndependentModifierFlagsMask);
             }
@@ -395,3 +635,65 @@ void gs_clip_paste(const char* string) {
     [pb declareTypes:types owner:nil];
     [pb setString:[NSString stringWithUTF8String:string] forType:NSStringPboardType];
 }
+
+// Fill in the current game controller state. Each controller is given the
+// first free player index when it is first seen so that it keeps its slot
+// while other controllers are connected or removed.
+void gs_read_pads(GSPad *pads, long count) {
+    long cnt;
+    for (cnt = 0; cnt < count; cnt++) {
+        memset(&pads[cnt], 0, sizeof(GSPad));
+    }
+    NSArray *controllers = [GCController controllers];
+    for (GCController *controller in controllers) {
+        if ([controller playerIndex] == GCControllerPlayerIndexUnset) {
+            long slot, used;
+            for (slot = 0; slot < count && slot < 4; slot++) {
+                used = 0;
+                for (GCController *other in controllers) {
+                    if ([other playerIndex] == slot) { used = 1; }
+                }
+                if (!used) {
+                    [controller setPlayerIndex:(GCControllerPlayerIndex)slot];
+                    break;
+                }
+            }
+        }
+        long index = [controller playerIndex];
+        GCExtendedGamepad *gp = [controller extendedGamepad];
+        if (index < 0 || index >= count || gp == nil) {
+            continue; // no free slot or only a basic gamepad profile.
+        }
+        GSPad *pad = &pads[index];
+        pad->connected = 1;
+        if ([[gp dpad] up].pressed)      { pad->buttons |= GS_PadUp; }
+        if ([[gp dpad] down].pressed)    { pad->buttons |= GS_PadDown; }
+        if ([[gp dpad] left].pressed)    { pad->buttons |= GS_PadLeft; }
+        if ([[gp dpad] right].pressed)   { pad->buttons |= GS_PadRight; }
+        if ([gp leftShoulder].pressed)   { pad->buttons |= GS_PadLb; }
+        if ([gp rightShoulder].pressed)  { pad->buttons |= GS_PadRb; }
+        if ([gp buttonA].pressed)        { pad->buttons |= GS_PadA; }
+        if ([gp buttonB].pressed)        { pad->buttons |= GS_PadB; }
+        if ([gp buttonX].pressed)        { pad->buttons |= GS_PadX; }
+        if ([gp buttonY].pressed)        { pad->buttons |= GS_PadY; }
+
+        // The stick, menu, and guide buttons only exist on newer OS versions.
+        if ([gp respondsToSelector:@selector(leftThumbstickButton)]) {
+            if ([gp leftThumbstickButton].pressed)  { pad->buttons |= GS_PadLs; }
+            if ([gp rightThumbstickButton].pressed) { pad->buttons |= GS_PadRs; }
+        }
+        if ([gp respondsToSelector:@selector(buttonMenu)]) {
+            if ([gp buttonMenu].pressed)    { pad->buttons |= GS_PadStart; }
+            if ([gp buttonOptions].pressed) { pad->buttons |= GS_PadBack; }
+        }
+        if ([gp respondsToSelector:@selector(buttonHome)]) {
+            if ([gp buttonHome].pressed)    { pad->buttons |= GS_PadGuide; }
+        }
+        pad->axes[0] = [[gp leftThumbstick] xAxis].value;
+        pad->axes[1] = [[gp leftThumbstick] yAxis].value;
+        pad->axes[2] = [[gp rightThumbstick] xAxis].value;
+        pad->axes[3] = [[gp rightThumbstick] yAxis].value;
+        pad->axes[4] = [gp leftTrigger].value;
+        pad->axes[5] = [gp rightTrigger].value;
+    }
+}
@@ -5,6 +5,9 @@
 // This wraps the microsoft windowing API's (where the real work is done).
 
 #include "os_windows.h"
+#include <imm.h>
+#include <shellapi.h>
+#include <xinput.h>
 
 // Application defaults. Internal use only. Not really state per-se these are
 // consulted at startup for initial values. These are updated using the
@@ -45,6 +48,59 @@ static int gs_event_front = 0;
 static int gs_event_rear = 0;
 static int gs_event_size = sizeof(gs_events) / sizeof(gs_events[0]);
 
+// Active touch points updated from WM_TOUCH events.
+static GSTouch gs_touches[10];
+static long gs_touch_count = 0;
+static long gs_touch_max = sizeof(gs_touches) / sizeof(gs_touches[0]);
+
+// Add, move, or remove the touch point with the given id.
+void gs_touch_set(long id, unsigned char up, float x, float y)
+{
+    long cnt;
+    for (cnt = 0; cnt < gs_touch_count && gs_touches[cnt].id != id; cnt++) {}
+    if (up)
+    {
+        if (cnt < gs_touch_count)
+        {
+            gs_touch_count--;
+            gs_touches[cnt] = gs_touches[gs_touch_count];
+        }
+        return;
+    }
+    if (cnt == gs_touch_count)
+    {
+        if (gs_touch_count == gs_touch_max)
+        {
+            return; // ignore extra touches.
+        }
+        gs_touch_count++;
+    }
+    gs_touches[cnt].id = id;
+    gs_touches[cnt].x = x;
+    gs_touches[cnt].y = y;
+}
+
+// Typed characters from WM_CHAR events and the current input method
+// composition. Both are UTF-16 and null terminated when read.
+static WCHAR gs_text[256];
+static long gs_text_len = 0;
+static WCHAR gs_compose[256] = {0};
+static long gs_text_max = sizeof(gs_text) / sizeof(gs_text[0]) - 1;
+
+// Queue of UTF-8 file paths from WM_DROPFILES events.
+static char* gs_drops[64];
+static long gs_drop_count = 0;
+static long gs_drop_max = sizeof(gs_drops) / sizeof(gs_drops[0]);
+void gs_drop_files(HDROP drop);
+
+// Relative mouse motion accumulated from raw input events.
+static long gs_motion_x = 0;
+static long gs_motion_y = 0;
+static unsigned char gs_captured = 0;
+
+// Custom window cursor, or NULL for the default arrow.
+static HCURSOR gs_cursor = NULL;
+
 // Full screen toggle structure.
 static GSScreen gs_screen = {0, 0, 0, 0, {0, 0, 0, 0}};
 
@@ -54,12 +110,32 @@ void gs_write_urge(long eid, long key, long scroll)
     eve->event = eid;
     eve->key = key;
     eve->scroll = scroll;
+    eve->scrollx = 0;
+    eve->scrolly = 0;
+    eve->scan = 0;
     eve->mousex = -1;
     eve->mousey = -1;
     eve->mods = 0;
     gs_event_rear = (gs_event_rear + 1) % gs_event_size;
 }
 
+// Queue a scroll event with the precise scroll amounts.
+void gs_write_scroll(long eid, long scroll, float scrollx, float scrolly)
+{
+    GSEvent *eve = &(gs_events[gs_event_rear]);
+    gs_write_urge(eid, 0, scroll);
+    eve->scrollx = scrollx;
+    eve->scrolly = scrolly;
+}
+
+// Queue a key event with the key scan code.
+void gs_write_key(long eid, long key, long scan)
+{
+    GSEvent *eve = &(gs_events[gs_event_rear]);
+    gs_write_urge(eid, key, 0);
+    eve->scan = scan;
+}
+
 // Windows callback procedure. Handle a few events often returning 0 to mark
 // them as handled. This method is mostly microsoft magic as each event may
 // have its own behaviour and different return codes.
@@ -98,7 +174,14 @@ LRESULT CALLBACK gs_wnd_proc(HWND hwnd, UINT msg, WPARAM wParam, LPARAM lParam)
             if (key == VK_SHIFT || key == VK_CONTROL || key == VK_MENU || key == VK_LWIN || key == VK_RWIN) {
                 return 0;
             }
-            gs_write_urge(msg, key, 0);
+
+            // the scan code identifies the key location independent of the layout.
+            long scan = (lParam >> 16) & 0xFF;
+            if (lParam & 0x01000000)
+            {
+                scan |= 0xE000; // extended key.
+            }
+            gs_write_key(msg, key, scan);
             return 0;
         }
         case WM_MBUTTONDOWN:
@@ -117,13 +200,102 @@ LRESULT CALLBACK gs_wnd_proc(HWND hwnd, UINT msg, WPARAM wParam, LPARAM lParam)
             gs_write_urge(msg, 0, 0);
             return 0;
         }
+        case WM_INPUT:
+        {
+            // flip the raw motion so that positive y is up.
+            RAWINPUT raw;
+            UINT size = sizeof(raw);
+            if (GetRawInputData((HRAWINPUT)lParam, RID_INPUT, &raw, &size, sizeof(RAWINPUTHEADER)) != (UINT)-1 &&
+                raw.header.dwType == RIM_TYPEMOUSE && !(raw.data.mouse.usFlags & MOUSE_MOVE_ABSOLUTE))
+            {
+                gs_motion_x += raw.data.mouse.lLastX;
+                gs_motion_y -= raw.data.mouse.lLastY;
+            }
+            break; // DefWindowProc does the WM_INPUT cleanup.
+        }
+        case WM_TOUCH:
+        {
+            // touch locations are in hundredths of a screen pixel.
+            TOUCHINPUT inputs[10];
+            UINT count = LOWORD(wParam);
+            if (count > gs_touch_max)
+            {
+                count = gs_touch_max;
+            }
+            if (GetTouchInputInfo((HTOUCHINPUT)lParam, count, inputs, sizeof(TOUCHINPUT)))
+            {
+                RECT rect;
+                GetClientRect(hwnd, &rect);
+                UINT cnt;
+                for (cnt = 0; cnt < count; cnt++)
+                {
+                    POINT loc = {TOUCH_COORD_TO_PIXEL(inputs[cnt].x), TOUCH_COORD_TO_PIXEL(inputs[cnt].y)};
+                    ScreenToClient(hwnd, &loc);
+                    unsigned char up = (inputs[cnt].dwFlags & TOUCHEVENTF_UP) != 0;
+                    gs_touch_set(inputs[cnt].dwID, up, loc.x, rect.bottom - loc.y);
+                }
+                CloseTouchInputHandle((HTOUCHINPUT)lParam);
+                return 0;
+            }
+            break;
+        }
+        case WM_DROPFILES:
+        {
+            gs_drop_files((HDROP)wParam);
+            return 0;
+        }
+        case WM_CHAR:
+        {
+            // surrogate pairs arrive as two WM_CHAR messages.
+            WCHAR ch = (WCHAR)wParam;
+            if (ch >= 0x20 && ch != 0x7f && gs_text_len < gs_text_max)
+            {
+                gs_text[gs_text_len++] = ch;
+            }
+            return 0;
+        }
+        case WM_IME_COMPOSITION:
+        {
+            // the result string comes back as WM_CHAR from DefWindowProc.
+            if (lParam & GCS_COMPSTR)
+            {
+                HIMC himc = ImmGetContext(hwnd);
+                LONG bytes = ImmGetCompositionStringW(himc, GCS_COMPSTR, gs_compose, sizeof(gs_compose) - sizeof(WCHAR));
+                gs_compose[bytes > 0 ? bytes / sizeof(WCHAR) : 0] = 0;
+                ImmReleaseContext(hwnd, himc);
+            }
+            break;
+        }
+        case WM_IME_ENDCOMPOSITION:
+        {
+            gs_compose[0] = 0;
+            break;
+        }
         case WM_MOUSEWHEEL:
         {
             // flip scroll direction to match OSX.
             long scroll = -1 * (((int)wParam) >> 16) / WHEEL_DELTA;
-            gs_write_urge(msg, 0, scroll);
+            float lines = -1.0f * (float)GET_WHEEL_DELTA_WPARAM(wParam) / WHEEL_DELTA;
+            gs_write_scroll(msg, scroll, 0, lines);
+            return 0;
+        }
+        case WM_MOUSEHWHEEL:
+        {
+            // flip scroll direction to match OSX.
+            float lines = -1.0f * (float)GET_WHEEL_DELTA_WPARAM(wParam) / WHEEL_DELTA;
+            gs_write_scroll(WM_MOUSEWHEEL, 0, lines, 0);
             return 0;
         }
+        case WM_SETCURSOR:
+        {
+            // use any custom cursor while over the client area.
+            if (LOWORD(lParam) == HTCLIENT && gs_cursor)
+            {
+                SetCursor(gs_cursor);
+                return TRUE;
+            }
+            break;
+        }
         case WM_SIZE:
         {
             // TODO detect when window is restored from maximized.
@@ -172,7 +344,8 @@ void gs_read_dispatch(long display, GSEvent *gs_urge)
             gs_win_alive = -2;
             return;
         }
-        DispatchMessage( &msg ); // goes to wnd_proc
+        TranslateMessage( &msg ); // creates WM_CHAR from key presses.
+        DispatchMessage( &msg );  // goes to wnd_proc
 
         // message queue has been processed, return interesting stuff.
         if ( gs_event_front != gs_event_rear )
@@ -181,6 +354,9 @@ void gs_read_dispatch(long display, GSEvent *gs_urge)
             gs_urge->event = eve->event;
             gs_urge->key = eve->key;
             gs_urge->scroll = eve->scroll;
+            gs_urge->scrollx = eve->scrollx;
+            gs_urge->scrolly = eve->scrolly;
+            gs_urge->scan = eve->scan;
 	        gs_event_front = (gs_event_front + 1) % gs_event_size;
         }
     }
@@ -243,6 +419,8 @@ long gs_create_window(HMODULE hInstance, LPSTR className)
         hInstance,              // Module instance handle.
         NULL                    // Additional app data.
     );
+    RegisterTouchWindow(display, 0);
+    DragAcceptFiles(display, TRUE);
     return HandleToLong(display);
 }
 
@@ -395,6 +573,49 @@ void gs_size(long display, long *x, long *y, long *w, long *h)
     *y = desktop.bottom - rect.bottom;
 }
 
+// Hide the cursor and clip it to the window client area. Raw mouse input
+// is registered so that WM_INPUT reports motion even when the cursor is
+// held at the clip edge.
+void gs_capture_mouse(long display, unsigned char capture)
+{
+    if (capture == gs_captured)
+    {
+        return;
+    }
+    gs_captured = capture;
+    HWND hwnd = LongToHandle(display);
+    RAWINPUTDEVICE rid;
+    rid.usUsagePage = 0x01; // HID_USAGE_PAGE_GENERIC
+    rid.usUsage = 0x02;     // HID_USAGE_GENERIC_MOUSE
+    if (capture)
+    {
+        RECT rect;
+        GetClientRect(hwnd, &rect);
+        MapWindowPoints(hwnd, NULL, (POINT*)&rect, 2);
+        ClipCursor(&rect);
+        ShowCursor(FALSE);
+        rid.dwFlags = 0;
+        rid.hwndTarget = hwnd;
+    }
+    else
+    {
+        ClipCursor(NULL);
+        ShowCursor(TRUE);
+        rid.dwFlags = RIDEV_REMOVE;
+        rid.hwndTarget = NULL;
+    }
+    RegisterRawInputDevices(&rid, 1, sizeof(rid));
+}
+
+// Get and clear the accumulated raw mouse motion.
+void gs_read_motion(long *dx, long *dy)
+{
+    *dx = gs_motion_x;
+    *dy = gs_motion_y;
+    gs_motion_x = 0;
+    gs_motion_y = 0;
+}
+
 // Show or hide cursor. Lock it to the window if it is hidden.
 void gs_show_cursor(long display, unsigned char show)
 {
@@ -410,6 +631,60 @@ void gs_show_cursor(long display, unsigned char show)
     ShowCursor( show );
 }
 
+// Create a cursor from the RGBA pixels. The mask is unused since
+// the 32 bit color bitmap has an alpha channel.
+void gs_set_cursor(long display, unsigned char *rgba, long width, long height, long hotx, long hoty)
+{
+    HCURSOR old = gs_cursor;
+    gs_cursor = NULL;
+    if (width > 0 && height > 0)
+    {
+        BITMAPV5HEADER bi;
+        ZeroMemory(&bi, sizeof(bi));
+        bi.bV5Size = sizeof(bi);
+        bi.bV5Width = width;
+        bi.bV5Height = -height; // top down rows.
+        bi.bV5Planes = 1;
+        bi.bV5BitCount = 32;
+        bi.bV5Compression = BI_BITFIELDS;
+        bi.bV5RedMask = 0x00ff0000;
+        bi.bV5GreenMask = 0x0000ff00;
+        bi.bV5BlueMask = 0x000000ff;
+        bi.bV5AlphaMask = 0xff000000;
+        unsigned char *bits = NULL;
+        HDC dc = GetDC(NULL);
+        HBITMAP color = CreateDIBSection(dc, (BITMAPINFO*)&bi, DIB_RGB_COLORS, (void**)&bits, NULL, 0);
+        ReleaseDC(NULL, dc);
+        HBITMAP mask = CreateBitmap(width, height, 1, 1, NULL);
+        if (color && mask)
+        {
+            long cnt;
+            for (cnt = 0; cnt < width * height; cnt++)
+            {
+                // RGBA to BGRA.
+                bits[cnt*4+0] = rgba[cnt*4+2];
+                bits[cnt*4+1] = rgba[cnt*4+1];
+                bits[cnt*4+2] = rgba[cnt*4+0];
+                bits[cnt*4+3] = rgba[cnt*4+3];
+            }
+            ICONINFO info;
+            info.fIcon = FALSE;
+            info.xHotspot = hotx;
+            info.yHotspot = hoty;
+            info.hbmMask = mask;
+            info.hbmColor = color;
+            gs_cursor = (HCURSOR)CreateIconIndirect(&info);
+        }
+        if (color) { DeleteObject(color); }
+        if (mask) { DeleteObject(mask); }
+    }
+    SetCursor(gs_cursor ? gs_cursor : LoadCursor(NULL, IDC_ARROW));
+    if (old)
+    {
+        DestroyIcon((HICON)old);
+    }
+}
+
 // Set long attributes. Attributes only take effect if they are set before
 // they are used to create the window or rendering context.
 void gs_set_attr_l(long attr, long value)
@@ -533,3 +808,146 @@ char* wchar_utf8(const WCHAR* wide) {
     }
     return utf8; // needs to be freed by the caller.
 }
+
+// XInput is loaded when first needed so that applications still run
+// on systems without the XInput dll. Controllers that are not connected
+// are only rechecked every so often since XInputGetState is slow to
+// report an empty slot.
+typedef DWORD (WINAPI *gs_xinput_state)(DWORD index, XINPUT_STATE *state);
+static gs_xinput_state gs_xinput_get_state = NULL;
+static long gs_xinput_loaded = 0;
+static long gs_pad_recheck[XUSER_MAX_COUNT] = {0, 0, 0, 0};
+
+// Convert a raw stick value to the range -1 to 1.
+float gs_pad_stick(SHORT value) {
+    float stick = (float)value / 32767.0f;
+    return stick < -1.0f ? -1.0f : stick;
+}
+
+// Fill in the current game controller state. The XInput button mask
+// values are used directly as the GS_Pad button values.
+void gs_read_pads(GSPad *pads, long count) {
+    if (!gs_xinput_loaded) {
+        gs_xinput_loaded = 1;
+        HMODULE lib = LoadLibraryA("xinput1_4.dll");
+        if (!lib) {
+            lib = LoadLibraryA("xinput9_1_0.dll");
+        }
+        if (lib) {
+            gs_xinput_get_state = (gs_xinput_state)GetProcAddress(lib, "XInputGetState");
+        }
+    }
+    long cnt;
+    for (cnt = 0; cnt < count; cnt++) {
+        GSPad *pad = &pads[cnt];
+        if (!gs_xinput_get_state || cnt >= XUSER_MAX_COUNT) {
+            pad->connected = 0;
+            continue;
+        }
+        if (!pad->connected && gs_pad_recheck[cnt] > 0) {
+            gs_pad_recheck[cnt]--;
+            continue;
+        }
+        XINPUT_STATE state;
+        ZeroMemory(&state, sizeof(XINPUT_STATE));
+        if (gs_xinput_get_state(cnt, &state) != ERROR_SUCCESS) {
+            ZeroMemory(pad, sizeof(GSPad));
+            gs_pad_recheck[cnt] = 120; // about 2 seconds at 60 polls a second.
+            continue;
+        }
+        XINPUT_GAMEPAD *gp = &state.Gamepad;
+        pad->connected = 1;
+        pad->buttons = gp->wButtons;
+        pad->axes[0] = gs_pad_stick(gp->sThumbLX);
+        pad->axes[1] = gs_pad_stick(gp->sThumbLY);
+        pad->axes[2] = gs_pad_stick(gp->sThumbRX);
+        pad->axes[3] = gs_pad_stick(gp->sThumbRY);
+        pad->axes[4] = (float)gp->bLeftTrigger / 255.0f;
+        pad->axes[5] = (float)gp->bRightTrigger / 255.0f;
+    }
+}
+
+// Copy out the current touch points.
+long gs_read_touches(GSTouch *touches, long count)
+{
+    long cnt;
+    for (cnt = 0; cnt < count && cnt < gs_touch_count; cnt++)
+    {
+        touches[cnt] = gs_touches[cnt];
+    }
+    return cnt;
+}
+
+// Return and clear the typed characters as a UTF-8 string.
+// The returned string must be freed by the caller.
+char* gs_read_text()
+{
+    if (gs_text_len == 0)
+    {
+        return NULL;
+    }
+    gs_text[gs_text_len] = 0;
+    gs_text_len = 0;
+    return wchar_utf8(gs_text);
+}
+
+// Return the input method composition as a UTF-8 string.
+// The returned string must be freed by the caller.
+char* gs_read_compose()
+{
+    if (gs_compose[0] == 0)
+    {
+        return NULL;
+    }
+    return wchar_utf8(gs_compose);
+}
+
+// Queue the dropped file paths as UTF-8 strings.
+void gs_drop_files(HDROP drop)
+{
+    UINT count = DragQueryFileW(drop, 0xFFFFFFFF, NULL, 0);
+    UINT cnt;
+    for (cnt = 0; cnt < count && gs_drop_count < gs_drop_max; cnt++)
+    {
+        UINT length = DragQueryFileW(drop, cnt, NULL, 0);
+        WCHAR* path = calloc(length + 1, sizeof(WCHAR));
+        DragQueryFileW(drop, cnt, path, length + 1);
+        char* utf8 = wchar_utf8(path);
+        if (utf8)
+        {
+            gs_drops[gs_drop_count++] = utf8;
+        }
+        free(path);
+    }
+    DragFinish(drop);
+}
+
+// Return, and remove, the oldest dropped file path.
+// The returned string must be freed by the caller.
+char* gs_read_drop()
+{
+    if (gs_drop_count == 0)
+    {
+        return NULL;
+    }
+    char* path = gs_drops[0];
+    gs_drop_count--;
+    memmove(gs_drops, gs_drops + 1, gs_drop_count * sizeof(char*));
+    return path;
+}
+
+// Return the layout name for the key at the given scan code.
+char* gs_key_name(long scan)
+{
+    LONG lparam = (scan & 0xFF) << 16;
+    if (scan & 0xE000)
+    {
+        lparam |= 0x01000000; // extended key.
+    }
+    WCHAR name[64];
+    if (GetKeyNameTextW(lparam, name, sizeof(name) / sizeof(name[0])) == 0)
+    {
+        return NULL;
+    }
+    return wchar_utf8(name);
+}
@@ -9,8 +9,8 @@ package device
 // // This is C code and cgo directvies.
 //
 // #cgo windows CFLAGS: -m64
-// #cgo windows,!dx LDFLAGS: -lopengl32 -lgdi32
-// #cgo windows,dx LDFLAGS: -ld3d11
+// #cgo windows,!dx LDFLAGS: -lopengl32 -lgdi32 -limm32 -lshell32
+// #cgo windows,dx LDFLAGS: -ld3d11 -limm32 -lshell32
 // #cgo windows,dx CXXFLAGS: -std=c++11
 //
 // #include "os_windows.h"
@@ -26,6 +26,8 @@ import (
 // a new input structure on each readAndDispatch.
 type win struct {
 	gsu *C.GSEvent
+	gsp [MaxPads]C.GSPad      // Game controller state reused each poll.
+	gst [MaxTouches]C.GSTouch // Touch points reused each poll.
 }
 
 // OpenGL related, see: https://code.google.com/p/go-wiki/wiki/LockOSThread
@@ -60,6 +62,55 @@ func (w *win) showCursor(r *nrefs, show bool) {
 	}
 	C.gs_show_cursor(C.long(r.display), C.uchar(tf1))
 }
+func (w *win) setCursor(r *nrefs, rgba []byte, width, height, hotx, hoty int) {
+	var pixels *C.uchar
+	if len(rgba) > 0 {
+		pixels = (*C.uchar)(unsafe.Pointer(&rgba[0]))
+	}
+	C.gs_set_cursor(C.long(r.display), pixels, C.long(width), C.long(height), C.long(hotx), C.long(hoty))
+}
+
+// Implement native interface.
+func (w *win) readTouches(r *nrefs, touches []Touch) int {
+	cnt := int(C.gs_read_touches(&w.gst[0], C.long(len(w.gst))))
+	if cnt > len(touches) {
+		cnt = len(touches)
+	}
+	for index := 0; index < cnt; index++ {
+		gt := &w.gst[index]
+		touches[index] = Touch{ID: int(gt.id), X: float64(gt.x), Y: float64(gt.y)}
+	}
+	return cnt
+}
+
+// Implement native interface: nrefs unused, needed by other platforms.
+func (w *win) readText(r *nrefs) (text, compose string) {
+	return freeString(C.gs_read_text()), freeString(C.gs_read_compose())
+}
+func (w *win) readDrop(r *nrefs) string { return freeString(C.gs_read_drop()) }
+
+// freeString makes a Go copy of the given C string and frees the C copy.
+func freeString(cstr *C.char) string {
+	if cstr == nil {
+		return ""
+	}
+	defer C.free(unsafe.Pointer(cstr))
+	return C.GoString(cstr)
+}
+
+// Implement native interface.
+func (w *win) captureMouse(r *nrefs, capture bool) {
+	trueFalse := 0 // trueFalse needs to be 0 or 1.
+	if capture {
+		trueFalse = 1
+	}
+	C.gs_capture_mouse(C.long(r.display), C.uchar(trueFalse))
+}
+func (w *win) readMotion(r *nrefs) (dx, dy int) {
+	var mx, my C.long
+	C.gs_read_motion(&mx, &my)
+	return int(mx), int(my)
+}
 
 // Implement native interface.
 func (w *win) readDispatch(r *nrefs, in *userInput) *userInput {
@@ -69,6 +120,9 @@ func (w *win) readDispatch(r *nrefs, in *userInput) *userInput {
 	w.gsu.key = 0
 	w.gsu.mods = 0
 	w.gsu.scroll = 0
+	w.gsu.scrollx = 0
+	w.gsu.scrolly = 0
+	w.gsu.scan = 0
 	C.gs_read_dispatch(C.long(r.display), w.gsu)
 
 	// transfer/translate the native event into the input buffer.
@@ -76,9 +130,15 @@ func (w *win) readDispatch(r *nrefs, in *userInput) *userInput {
 	if in.id != 0 {
 		in.button = mouseButtons[int(w.gsu.event)]
 		in.key = int(w.gsu.key)
+		in.scan = in.key
+		if scan, ok := scancodes[int(w.gsu.scan)]; ok {
+			in.scan = scan
+		}
 		in.scroll = int(w.gsu.scroll)
+		in.scrollx, in.scrolly = float64(w.gsu.scrollx), float64(w.gsu.scrolly)
 	} else {
-		in.button, in.key, in.scroll = 0, 0, 0
+		in.button, in.key, in.scan, in.scroll = 0, 0, 0, 0
+		in.scrollx, in.scrolly = 0, 0
 	}
 	in.mods = int(w.gsu.mods)
 	in.mouseX = int(w.gsu.mousex)
@@ -86,6 +146,23 @@ func (w *win) readDispatch(r *nrefs, in *userInput) *userInput {
 	return in
 }
 
+// Implement native interface: nrefs unused, needed by other platforms.
+func (w *win) readPads(r *nrefs, pads []padInput) {
+	cnt := len(pads)
+	if cnt > len(w.gsp) {
+		cnt = len(w.gsp)
+	}
+	C.gs_read_pads(&w.gsp[0], C.long(cnt))
+	for index := 0; index < cnt; index++ {
+		gp, pad := &w.gsp[index], &pads[index]
+		pad.connected = gp.connected == 1
+		pad.buttons = int(gp.buttons)
+		for axis := range pad.axes {
+			pad.axes[axis] = float64(gp.axes[axis])
+		}
+	}
+}
+
 // Implement native interface.
 func (w *win) size(r *nrefs) (x int, y int, wx int, hy int) {
 	var winx, winy, width, height int32
@@ -118,6 +195,17 @@ func (w *win) copyClip(r *nrefs) string {
 	return ""
 }
 
+// Implement native interface. The key code is the US layout virtual
+// key of the key location.
+func (w *win) keyName(r *nrefs, scan int) string {
+	for code, key := range scancodes {
+		if key == scan {
+			return freeString(C.gs_key_name(C.long(code)))
+		}
+	}
+	return ""
+}
+
 // Implement native interface.
 func (w *win) pasteClip(r *nrefs, s string) {
 	cstr := C.CString(s)
@@ -275,3 +363,34 @@ const (
 	mouseMiddle       = 0x04 // VK_MBUTTON Middle mouse button (three-button mouse)
 	mouseRight        = 0x02 // VK_RBUTTON Right mouse button
 )
+
+// scancodes maps key scan codes to the key codes of the same key location
+// on a US keyboard. Extended keys have a 0xE0 prefix.
+// https://msdn.microsoft.com/en-us/library/windows/desktop/ms646306(v=vs.85).aspx
+var scancodes = map[int]int{
+	0x0B: key0, 0x02: key1, 0x03: key2, 0x04: key3, 0x05: key4,
+	0x06: key5, 0x07: key6, 0x08: key7, 0x09: key8, 0x0A: key9,
+	0x1E: keyA, 0x30: keyB, 0x2E: keyC, 0x20: keyD, 0x12: keyE,
+	0x21: keyF, 0x22: keyG, 0x23: keyH, 0x17: keyI, 0x24: keyJ,
+	0x25: keyK, 0x26: keyL, 0x32: keyM, 0x31: keyN, 0x18: keyO,
+	0x19: keyP, 0x10: keyQ, 0x13: keyR, 0x1F: keyS, 0x14: keyT,
+	0x16: keyU, 0x2F: keyV, 0x11: keyW, 0x2D: keyX, 0x15: keyY,
+	0x2C: keyZ,
+	0x3B: keyF1, 0x3C: keyF2, 0x3D: keyF3, 0x3E: keyF4, 0x3F: keyF5,
+	0x40: keyF6, 0x41: keyF7, 0x42: keyF8, 0x43: keyF9, 0x44: keyF10,
+	0x57: keyF11, 0x58: keyF12, 0x64: keyF13, 0x65: keyF14, 0x66: keyF15,
+	0x67: keyF16, 0x68: keyF17, 0x69: keyF18, 0x6A: keyF19,
+	0x52: keyKeypad0, 0x4F: keyKeypad1, 0x50: keyKeypad2, 0x51: keyKeypad3,
+	0x4B: keyKeypad4, 0x4C: keyKeypad5, 0x4D: keyKeypad6, 0x47: keyKeypad7,
+	0x48: keyKeypad8, 0x49: keyKeypad9, 0x53: keyKeypadDecimal,
+	0x37: keyKeypadMultiply, 0x4E: keyKeypadPlus, 0x45: keyKeypadClear,
+	0xE035: keyKeypadDivide, 0xE01C: keyKeypadEnter, 0x4A: keyKeypadMinus,
+	0x59: keyKeypadEquals,
+	0x0D: keyEqual, 0x0C: keyMinus, 0x1A: keyLeftBracket, 0x1B: keyRightBracket,
+	0x28: keyQuote, 0x27: keySemicolon, 0x2B: keyBackslash, 0x29: keyGrave,
+	0x35: keySlash, 0x33: keyComma, 0x34: keyPeriod,
+	0x1C: keyReturn, 0x0F: keyTab, 0x39: keySpace, 0x0E: keyDelete,
+	0xE053: keyForwardDelete, 0x01: keyEscape,
+	0xE047: keyHome, 0xE049: keyPageUp, 0xE051: keyPageDown, 0xE04F: keyEnd,
+	0xE04B: keyLeftArrow, 0xE04D: keyRightArrow, 0xE050: keyDownArrow, 0xE048: keyUpArrow,
+}
@@ -7,6 +7,9 @@
 // os_windows.h defines the method calls needed by os_windows.go native layer.
 
 #include <stdio.h>
+#ifndef _WIN32_WINNT
+#define _WIN32_WINNT 0x0601 // Windows 7 is needed for touch input.
+#endif
 #include <windows.h>
 
 // Used to pass back user input each on each polling call.
@@ -17,8 +20,25 @@ typedef struct {
     long key;     // which key is currently pressed, if any.
     long mods;    // which modifier keys are currently pressed, if any.
     long scroll;  // the scroll amount if any.
+    float scrollx; // precise horizontal scroll in lines, if any.
+    float scrolly; // precise vertical scroll in lines, if any.
+    long scan;     // key scan code, 0xE0 prefixed for extended keys, if any.
 } GSEvent;
 
+// Used to pass back game controller state on each polling call.
+typedef struct {
+    long  connected; // 1 if a controller is using this slot.
+    long  buttons;   // mask of the currently pressed GS_Pad buttons.
+    float axes[6];   // lx, ly, rx, ry from -1 to 1, lt, rt from 0 to 1.
+} GSPad;
+
+// Used to pass back the current touch points on each polling call.
+typedef struct {
+    long  id;   // unique while the touch is active.
+    float x;    // window location with origin at the bottom left.
+    float y;    // window location with origin at the bottom left.
+} GSTouch;
+
 // Used to toggle between full screen and windowed mode.
 typedef struct {
     unsigned char full;     // true when in full screen mode.
@@ -65,6 +85,42 @@ void gs_show_cursor(long display, unsigned char show);
 // Set the cursor location to the given screen coordinates.
 void gs_set_cursor_location(long display, long x, long y);
 
+// Use the given RGBA image as the window cursor. The hot spot is the
+// image pixel, from the top left, that is the cursor location.
+// A zero width or height restores the default arrow cursor.
+void gs_set_cursor(long display, unsigned char *rgba, long width, long height, long hotx, long hoty);
+
+// Hide the cursor and lock it to the window while capture is true.
+// Raw mouse motion is collected while the mouse is captured.
+void gs_capture_mouse(long display, unsigned char capture);
+
+// Get and clear the relative mouse motion since the last call.
+void gs_read_motion(long *dx, long *dy);
+
+// Fill in the state of the first count game controller slots.
+// Controllers are read using XInput which supports up to 4 controllers.
+void gs_read_pads(GSPad *pads, long count);
+
+// Fill in up to count current touch points returning the number of touches.
+// Touch input needs the window to be registered with RegisterTouchWindow.
+long gs_read_touches(GSTouch *touches, long count);
+
+// Get, and clear, the characters typed since the last call. Returns NULL
+// if nothing was typed. Also get the input method text being composed.
+// Returned strings must be freed by the caller.
+char* gs_read_text();
+char* gs_read_compose();
+
+// Get, and remove, the next file path dropped on the window.
+// Returns NULL if there are no dropped files. The returned string
+// must be freed by the caller.
+char* gs_read_drop();
+
+// Get the keyboard layout name of the key with the given scan code.
+// Returns NULL if the key has no name. The returned string must be
+// freed by the caller.
+char* gs_key_name(long scan);
+
 // Create an OpenGL context using the given shell. Subsequent calls
 // return the current context and ignoring the input parameters.
 //
@@ -156,4 +212,24 @@ enum {
    GS_AlternateKeyMask = 1 << 21,
 };
 
+// Game controller button masks. These match the XInput button masks
+// and must be the same for each native layer.
+enum {
+    GS_PadUp    = 0x0001,
+    GS_PadDown  = 0x0002,
+    GS_PadLeft  = 0x0004,
+    GS_PadRight = 0x0008,
+    GS_PadStart = 0x0010,
+    GS_PadBack  = 0x0020,
+    GS_PadLs    = 0x0040,
+    GS_PadRs    = 0x0080,
+    GS_PadLb    = 0x0100,
+    GS_PadRb    = 0x0200,
+    GS_PadGuide = 0x0400,
+    GS_PadA     = 0x1000,
+    GS_PadB     = 0x2000,
+    GS_PadX     = 0x4000,
+    GS_PadY     = 0x8000
+};
+
 #endif
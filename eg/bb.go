@@ -97,7 +97,7 @@ func (bb *bbtag) Update(eng vu.Eng, in *vu.Input, s *vu.State) {
 	}
 
 	// Use screen coordinates from world coordinates.
-	if sx, sy := bb.cam.Screen(5, 2, -15, s.W, s.H); sx == -1 {
+	if sx, sy, visible := bb.cam.Screen(5, 2, -15); !visible {
 		bb.screenText.SetVisible(false)
 	} else {
 		bb.screenText.SetVisible(true)
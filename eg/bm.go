@@ -0,0 +1,214 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gazed/vu"
+)
+
+// bm, benchmark, is a stress test that spawns a configurable number of
+// lights, animated models, particle effects, and terrain chunks. Frame
+// statistics are printed each second and summarized at the end so that
+// engine and driver performance can be compared across machines and
+// releases. The counts are set using name=value arguments, ie:
+//     eg bm lights=4 models=100 effects=20 chunks=2 seconds=30
+// The scene is generated from a fixed seed so that runs are comparable.
+func bm() {
+	bm := &bmtag{lights: 1, models: 50, effects: 10, chunks: 1, seconds: 20}
+	bm.args(os.Args)
+	if err := vu.New(bm, "Benchmark", 400, 100, 800, 600); err != nil {
+		log.Printf("bm: error starting engine %s", err)
+	}
+	defer catchErrors()
+}
+
+// Globally unique "tag" that encapsulates example specific data.
+type bmtag struct {
+	cam     vu.Camera      // 3D scene camera.
+	terrain vu.Terrain     // Optional terrain chunks.
+	random  *rand.Rand     // Fixed seed random numbers.
+	spin    []vu.Pov       // Animated models are also spun.
+	live    []*vu.Particle // Scratch particle list.
+
+	// Benchmark settings.
+	lights, models, effects, chunks, seconds int
+
+	// Frame statistics.
+	start   time.Time     // Benchmark start time.
+	elapsed time.Duration // Per second elapsed time.
+	update  time.Duration // Per second update time.
+	renders int           // Per second renders.
+	updates int           // Per second updates.
+	total   int           // Total renders.
+	minFps  float64       // Worst second.
+	maxFps  float64       // Best second.
+	done    bool          // True once the summary is reported.
+}
+
+// args sets the benchmark settings from name=value arguments.
+// Unknown names and invalid values are ignored.
+func (bm *bmtag) args(args []string) {
+	settings := map[string]*int{
+		"lights":  &bm.lights,
+		"models":  &bm.models,
+		"effects": &bm.effects,
+		"chunks":  &bm.chunks,
+		"seconds": &bm.seconds,
+	}
+	for _, arg := range args {
+		if kv := strings.SplitN(arg, "=", 2); len(kv) == 2 {
+			if val, err := strconv.Atoi(kv[1]); err == nil && val >= 0 {
+				if setting, ok := settings[kv[0]]; ok {
+					*setting = val
+				}
+			}
+		}
+	}
+}
+
+// Create is the engine callback for initial asset creation.
+func (bm *bmtag) Create(eng vu.Eng, s *vu.State) {
+	bm.random = rand.New(rand.NewSource(123))
+	top := eng.Root().NewPov()
+	bm.cam = top.NewCam()
+	bm.cam.SetPerspective(60, float64(s.W)/float64(s.H), 0.1, 200)
+	bm.cam.SetLocation(0, 10, 40)
+	bm.cam.SetPitch(15)
+
+	// lights are spread in a circle above the scene.
+	for cnt := 0; cnt < bm.lights; cnt++ {
+		angle := 2 * math.Pi * float64(cnt) / float64(bm.lights)
+		sun := top.NewPov().SetLocation(20*math.Cos(angle), 20, 20*math.Sin(angle))
+		sun.NewLight().SetColor(0.6, 0.6, 0.6)
+	}
+
+	// animated models are placed in a grid.
+	side := int(math.Ceil(math.Sqrt(float64(bm.models))))
+	for cnt := 0; cnt < bm.models; cnt++ {
+		x, z := float64(cnt%side-side/2)*3, float64(cnt/side-side/2)*3
+		pov := top.NewPov().SetLocation(x, 0, z).SetScale(-3, 3, 3)
+		pov.Spin(-90, 0, 0)
+		m := pov.NewModel("anim").LoadAnim("runner")
+		m.Animate(cnt%2, cnt) // vary actions and start frames.
+		bm.spin = append(bm.spin, pov)
+	}
+
+	// particle effects are placed randomly above the models.
+	for cnt := 0; cnt < bm.effects; cnt++ {
+		x, z := (bm.random.Float64()-0.5)*40, (bm.random.Float64()-0.5)*40
+		pov := top.NewPov().SetLocation(x, 4, z)
+		m := pov.NewModel("effect").AddTex("particle").SetDrawMode(vu.Points)
+		m.SetEffect(bm.fall, 250)
+	}
+
+	// terrain chunks are paged in around the camera.
+	if bm.chunks > 0 {
+		ground := top.NewPov().SetLocation(0, -2, 0)
+		ground.Spin(-90, 0, 0) // surface x,y to world x,z.
+		bm.terrain = vu.NewTerrain(ground, "land", 32, bm.fill)
+		bm.terrain.SetRange(bm.chunks).SetSurface(8, 0.25, 2)
+		bm.terrain.SetModel(func(m vu.Model) {
+			m.AddTex("land").LoadMat("tint").SetUniform("ratio", 0.25)
+		})
+		bm.terrain.Update(bm.cam)
+	}
+	eng.SetColor(0.15, 0.15, 0.15, 1)
+	bm.start = time.Now()
+}
+
+// Update is the recurring callback to update state and track statistics.
+func (bm *bmtag) Update(eng vu.Eng, in *vu.Input, s *vu.State) {
+	if in.Resized {
+		bm.cam.SetPerspective(60, float64(s.W)/float64(s.H), 0.1, 200)
+	}
+	for _, pov := range bm.spin {
+		pov.Spin(0, 0, in.Dt*45)
+	}
+	if bm.terrain != nil {
+		bm.cam.Move(0, 0, -in.Dt*5, bm.cam.Lookxz()) // keep paging terrain.
+		bm.terrain.Update(bm.cam)
+	}
+
+	// collect and report statistics once a second.
+	times := eng.Usage()
+	bm.elapsed += times.Elapsed
+	bm.update += times.Update
+	bm.renders += times.Renders
+	bm.updates++
+	if bm.elapsed >= time.Second {
+		bm.report(eng)
+	}
+	if !bm.done && time.Since(bm.start) >= time.Duration(bm.seconds)*time.Second {
+		bm.done = true
+		bm.summary()
+		eng.Shutdown()
+	}
+}
+
+// report prints the last seconds statistics and resets the counters.
+func (bm *bmtag) report(eng vu.Eng) {
+	fps := float64(bm.renders) / bm.elapsed.Seconds()
+	ums := bm.update.Seconds() * 1000 / float64(bm.updates)
+	models, verts := eng.Rendered()
+	fmt.Printf("fps:%6.1f update:%6.2fms models:%d verts:%d\n", fps, ums, models, verts)
+	if bm.total == 0 || fps < bm.minFps {
+		bm.minFps = fps
+	}
+	if fps > bm.maxFps {
+		bm.maxFps = fps
+	}
+	bm.total += bm.renders
+	bm.elapsed, bm.update, bm.renders, bm.updates = 0, 0, 0, 0
+}
+
+// summary prints the statistics for the whole run.
+func (bm *bmtag) summary() {
+	run := time.Since(bm.start).Seconds()
+	fmt.Printf("bm lights=%d models=%d effects=%d chunks=%d\n", bm.lights, bm.models, bm.effects, bm.chunks)
+	fmt.Printf("   average fps:%.1f min:%.1f max:%.1f over %.0fs\n", float64(bm.total)/run, bm.minFps, bm.maxFps, run)
+}
+
+// fill generates repeatable rolling hills for terrain chunk cx, cy.
+func (bm *bmtag) fill(cx, cy int, pts [][]vu.SurfacePoint) {
+	size := len(pts) - 1
+	for x := range pts {
+		for y := range pts[x] {
+			wx, wy := float64(cx*size+x), float64(cy*size+y)
+			pts[x][y].Height = float32(math.Sin(wx*0.1) * math.Cos(wy*0.13))
+			pts[x][y].Tindex = (x / 8) % 3
+		}
+	}
+}
+
+// fall is a CPU particle position updater. It lets particles drift
+// downwards. Particles that have passed their lifetime are removed.
+func (bm *bmtag) fall(all []*vu.Particle, dt float64) (live []*vu.Particle) {
+	emit := 2                        // max particles emitted each update.
+	lifespan := float32(1.0 / 200.0) // inverse number of updates to live.
+	bm.live = bm.live[:0]            // reset keeping memory.
+	for cnt, p := range all {
+		switch {
+		case p.Alive == 0 && emit > 0:
+			p.Alive, p.Index, emit = 1, float32(cnt), emit-1
+			p.X, p.Y, p.Z = bm.random.Float64()-0.5, 1, bm.random.Float64()-0.5
+			bm.live = append(bm.live, p)
+		case p.Alive > 0:
+			p.Alive, p.Index = p.Alive-lifespan, float32(cnt)
+			p.Y -= 0.01
+			bm.live = append(bm.live, p)
+		case p.Alive <= 0:
+			p.Alive = 0
+		}
+	}
+	return bm.live
+}
@@ -48,13 +48,18 @@ func (cr *crtag) Create(eng vu.Eng, s *vu.State) {
 	slab.NewModel("diffuse").LoadMesh("box").LoadMat("gray")
 
 	// create a single moving body.
+	// It is also the prefab for the block of bodies.
 	cr.striker = cr.top.NewPov()
-	cr.striker.SetLocation(15, 15, 0) // -5, 15, -3
-	useBalls := true                  // Flip to use boxes instead of spheres.
+	useBalls, prefab := true, "ball" // Flip to use boxes instead of spheres.
 	if useBalls {
 		cr.getBall(cr.striker)
 	} else {
+		prefab = "box"
 		cr.getBox(cr.striker)
+	}
+	eng.Prefab(prefab, cr.striker)
+	cr.striker.SetLocation(15, 15, 0) // -5, 15, -3
+	if !useBalls {
 		cr.striker.SetRotation(&lin.Q{X: 0.1825742, Y: 0.3651484, Z: 0.5477226, W: 0.7302967})
 	}
 	cr.striker.Model().SetColor(rand.Float64(), rand.Float64(), rand.Float64())
@@ -67,16 +72,10 @@ func (cr *crtag) Create(eng vu.Eng, s *vu.State) {
 	for k := 0; k < cubeSize; k++ {
 		for i := 0; i < cubeSize; i++ {
 			for j := 0; j < cubeSize; j++ {
-				bod := cr.top.NewPov()
 				lx := float64(2*i + startX)
 				ly := float64(20 + 2*k + startY)
 				lz := float64(2*j + startZ)
-				bod.SetLocation(lx, ly, lz)
-				if useBalls {
-					cr.getBall(bod)
-				} else {
-					cr.getBox(bod)
-				}
+				eng.Spawn(prefab, cr.top).SetLocation(lx, ly, lz)
 			}
 		}
 	}
@@ -105,6 +104,9 @@ func (cr *crtag) Update(eng vu.Eng, in *vu.Input, s *vu.State) {
 		case vu.KD:
 			cr.cam.AdjustYaw(dt * -spin)
 		case vu.KB:
+			if !in.Pressed(press) {
+				break // one ball for each key press.
+			}
 			ball := cr.top.NewPov()
 			ball.SetLocation(-2.5+rand.Float64(), 15, -1.5-rand.Float64())
 			ball.NewBody(vu.NewSphere(1))
@@ -112,6 +114,9 @@ func (cr *crtag) Update(eng vu.Eng, in *vu.Input, s *vu.State) {
 			m := ball.NewModel("gouraud").LoadMesh("sphere").LoadMat("red")
 			m.SetColor(rand.Float64(), rand.Float64(), rand.Float64())
 		case vu.KSpace:
+			if !in.Pressed(press) {
+				break // one push for each key press.
+			}
 			body := cr.striker.Body()
 			body.Push(-2.5, 0, -0.5)
 		}
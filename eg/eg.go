@@ -55,6 +55,7 @@ func main() {
 		{"rt", "rt: Ray Trace", rt},
 		{"tt", "tt: Render to Texture", tt},
 		{"sm", "sm: Shadow Map", sm},
+		{"bm", "bm: Benchmark", bm},
 	}
 
 	// run the first matching example.
@@ -31,7 +31,6 @@ type rctag struct {
 	ui     vu.Camera // 2D overlay camera.
 	fsize  float64   // floor size in world space units.
 	gsize  float64   // grid size: number visible/virtual tiles in floor image.
-	ww, wh int       // window dimensions.
 	floor  vu.Pov    // plane for raycast testing
 	hilite vu.Pov    // tracks which tile is currently selected.
 	s0, s1 vu.Pov    // spheres for raycast testing.
@@ -119,7 +118,6 @@ func (rc *rctag) Update(eng vu.Eng, in *vu.Input, s *vu.State) {
 
 // resize handles user screen/window changes.
 func (rc *rctag) resize(ww, wh int) {
-	rc.ww, rc.wh = ww, wh
 	fov, ratio, near, far := 60.0, float64(ww)/float64(wh), 0.1, 500.0
 	rc.cam.SetPerspective(fov, ratio, near, far)
 	rc.ui.SetOrthographic(0, float64(ww), 0, float64(wh), 0, 10)
@@ -129,9 +127,9 @@ func (rc *rctag) resize(ww, wh int) {
 // the picking ray direction and then intersect the ray against the
 // geometry in world space.
 func (rc *rctag) raycast(mx, my int) {
-	rx, ry, rz := rc.cam.Ray(mx, my, rc.ww, rc.wh)
+	ox, oy, oz, rx, ry, rz := rc.cam.Ray(mx, my)
 	ray := vu.NewRay(rx, ry, rz)
-	ray.World().SetLoc(rc.cam.Location()) // camera is ray origin.
+	ray.World().SetLoc(ox, oy, oz) // camera is ray origin.
 
 	// collide the ray with the plane and get the world-space contact point on hit.
 	if hit, x, y, z := vu.Cast(ray, rc.floor.Body()); hit {
@@ -167,9 +165,9 @@ func (rc *rctag) raycast(mx, my int) {
 // hovercast checks the sphere each update and turns the spheres a different
 // color when the mouse is over them.
 func (rc *rctag) hovercast(mx, my int) {
-	rx, ry, rz := rc.cam.Ray(mx, my, rc.ww, rc.wh)
+	ox, oy, oz, rx, ry, rz := rc.cam.Ray(mx, my)
 	ray := vu.NewRay(rx, ry, rz)
-	ray.World().SetLoc(rc.cam.Location())
+	ray.World().SetLoc(ox, oy, oz)
 	parts := []vu.Pov{rc.s0, rc.s1, rc.s2, rc.s3}
 	colors := []rgb{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}, {1, 1, 0}}
 	for cnt, p := range parts {
@@ -81,6 +81,7 @@ func (tm *tmtag) Create(eng vu.Eng, s *vu.State) {
 	tm.gm = tm.ground.NewModel("land").AddTex("land")
 	tm.gm.LoadMat("tint").SetUniform("ratio", textureRatio)
 	tm.gm.NewMesh("land")
+	tm.surface.BakeAO(8) // heights don't change so bake once.
 	tm.surface.Update(tm.gm, 0, 0)
 
 	// Add water planes.
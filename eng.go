@@ -4,10 +4,15 @@
 package vu
 
 import (
+	"image"
+	"image/draw"
+	"io"
 	"log"
 	"math"
+	"sort"
 	"time"
 
+	"github.com/gazed/vu/load"
 	"github.com/gazed/vu/math/lin"
 	"github.com/gazed/vu/physics"
 	"github.com/gazed/vu/render"
@@ -28,16 +33,157 @@ type Eng interface {
 	SetColor(r, g, b, a float32)      // Set background clear color.
 	ShowCursor(show bool)             // Hide or show the cursor.
 	SetCursorAt(x, y int)             // Put cursor at the window pixel x,y.
+	CaptureMouse(capture bool)        // Hide, lock cursor. Report Input.Dx, Dy.
+	Clipboard() string                // Text on the system clipboard, if any.
+	SetClipboard(text string)         // Put text on the system clipboard.
+	KeyName(key int) string           // Layout label of the Input.Scan key.
 	Enable(attr uint32, enabled bool) // Enable/disable render attributes.
 	ToggleFullScreen()                // Flips full screen and windowed mode.
-	Mute(mute bool)                   // Toggle sound volume.
-	SetVolume(zeroToOne float64)      // Set sound volume.
+	Mute(mute bool)                   // Toggle master sound volume.
+	SetVolume(zeroToOne float64)      // Set master sound volume.
+	SetAttenuation(model int)         // How sounds fade with distance.
+	SetDoppler(factor, speed float64) // Pitch shift moving sounds.
+	SetListener(p Pov)                // Pov, or its Camera, hears sounds.
+	Mixer(group int) Mixer            // Volume control for a sound group.
+	SetAudioDevice(name string)       // Sound output, "" for the default.
+	Listener() Pov                    // Pov that hears sounds.
+	Music() Music                     // Soundtrack crossfades and playlists.
 	SetGravity(g float64)             // Change the gravity constant.
+	SetQuality(q Quality)             // Change quality/speed settings.
+	Quality() Quality                 // Current quality settings.
+
+	// SetCursor uses the png image as the OS cursor over the window.
+	// The hot spot is the image pixel, from the top left, that marks the
+	// cursor location. An empty image name restores the default cursor.
+	// See Cursor for a pointer drawn by the engine.
+	SetCursor(image string, hotx, hoty int)
 
 	// Collide checks for collision between two bodies independent
 	// of the solver and without updating the the bodies locations.
 	Collide(a, b physics.Body) bool
 
+	// Contacts returns the physics contacts from the last update for
+	// body b, or for all bodies if b is nil. See physics.Contacts.
+	Contacts(b physics.Body, contacts []physics.Contact) []physics.Contact
+
+	// Raycast returns the Pov with the body closest to the ray origin
+	// that is hit by the ray. Only Pov's with layers in mask are checked.
+	// Equally close hits return the earliest created Pov.
+	// Returns nil if nothing was hit. The hit point x, y, z is in world
+	// coordinates.
+	Raycast(ray physics.Body, mask uint32) (p Pov, x, y, z float64)
+
+	// WithinSphere appends the Pov's, with models or bodies, whose bounds
+	// touch the sphere. AlongRay appends the Pov's whose bounds are hit
+	// by the ray, nearest first. Bounds are from the last update and
+	// include child Pov's. See spatial.go.
+	WithinSphere(x, y, z, radius float64, found []Pov) []Pov
+	AlongRay(ray physics.Body, found []Pov) []Pov
+
+	// Pick returns the Pov whose Model is drawn at the window pixel
+	// mx, my, or nil if there is none. See pick.go.
+	Pick(mx, my int) Pov
+
+	// Snapshot captures the state of the replicated Pov's for
+	// networking. See snapshot.go.
+	Snapshot(tick uint64) *Snapshot
+
+	// Scenes save and load a Pov hierarchy. The loaded hierarchy is added
+	// as a new child of parent. LoadScene finds "name.scn" with the model
+	// assets. See scenefile.go.
+	SaveScene(p Pov, w io.Writer) error
+	ReadScene(r io.Reader, parent Pov) (Pov, error)
+	LoadScene(name string, parent Pov) (Pov, error)
+
+	// Prefabs are reusable Pov hierarchies. Prefab records a copy of p
+	// and its children. Spawn adds a new copy of the named prefab as a
+	// child of parent, reading "name.scn" the first time for names that
+	// were not recorded. Spawn returns nil if there is no such prefab.
+	Prefab(name string, p Pov)
+	Spawn(name string, parent Pov) Pov
+
+	// Imported models are glTF node hierarchies with meshes, materials,
+	// and animations. The imported hierarchy is added as a new child of
+	// parent. LoadGltf finds "name.gltf", or "name.glb", with the model
+	// assets. See gltf.go.
+	LoadGltf(name string, parent Pov) (Pov, error)
+
+	// Imported meshes are Wavefront objects with the materials from their
+	// material library. The imported meshes are added as a new child of
+	// parent. LoadObj finds "name.obj" with the model assets. See obj.go.
+	LoadObj(name string, parent Pov) (Pov, error)
+
+	// Loads tracks the assets loading in the background for the models
+	// and noises in the hierarchy p, ie: to show new content once it
+	// has loaded. See loading.go.
+	Loads(p Pov) Loading
+
+	// Memory assets are meshes and textures created from application
+	// data. Models use them by name, see Model.LoadMesh and Model.AddTex,
+	// instead of asset files. AddTextureData decodes png or jpeg image
+	// data. See assets.go.
+	AddMesh(name string, md *MeshData) error
+	AddTexture(name string, img image.Image)
+	AddTextureData(name string, data []byte) error
+
+	// Scenes are named top level hierarchies that are switched or
+	// overlaid. NewScene creates an active scene, or returns the existing
+	// scene. Scene returns nil if there is no such scene. SwitchScene
+	// makes the named scene the only active scene. OverlayScene shows,
+	// or hides, the named scene over the other active scenes. See scenes.go.
+	NewScene(name string) Pov
+	Scene(name string) Pov
+	SwitchScene(name string)
+	OverlayScene(name string, show bool)
+
+	// Publish queues an event for the topic subscribers. Subscribe
+	// registers a handler for a topic, returning the id used to
+	// Unsubscribe. Events are delivered once per update before
+	// App.Update. See events.go for the engine topics.
+	Publish(topic string, data interface{})
+	Subscribe(topic string, h EventHandler) (id int)
+	Unsubscribe(id int)
+
+	// Tween changes the Pov, and its Model, values over the given time.
+	// See tween.go.
+	Tween(p Pov, d time.Duration) Tween
+
+	// CameraPath creates a path that moves the camera through timed
+	// location and look at keys. See camerapath.go.
+	CameraPath(cam Camera) Path
+
+	// After calls fn once after the wait. Every calls fn repeatedly at
+	// the given interval. Run calls step, resuming it after each wait
+	// until it is done, see Sequence. Functions are called on the update
+	// goroutine after App.Update. The returned id is used to Cancel.
+	// See scheduler.go.
+	After(wait time.Duration, fn func()) (id int)
+	Every(interval time.Duration, fn func()) (id int)
+	Run(step Step) (id int)
+	Cancel(id int)
+
+	// NewJournal starts recording Pov changes so that they can be
+	// undone, closing any previous journal. See journal.go.
+	NewJournal() Journal
+
+	// RecordInput writes the Input of each update until the recording
+	// is closed. PlayInput replaces the device input with the recorded
+	// input until it runs out or is closed. Starting either closes any
+	// previous recording or playback. See replay.go.
+	RecordInput(w io.Writer) Recording
+	PlayInput(r io.Reader) Recording
+
+	// Each calls visit, in creation order, for each Pov that has all
+	// of the given components: PovModel, PovBody, PovCam, PovNoise,
+	// PovLight, PovLayer, or PovComps. No components visits all Pov's.
+	Each(visit func(p Pov), kinds ...int)
+
+	// Find returns the Pov with the given name or nil if there is none.
+	// FindAllTagged returns the Pov's with the given tag in the order
+	// they were tagged.
+	Find(name string) Pov
+	FindAllTagged(tag string) []Pov
+
 	// Timing is updated each processing loop. The returned update
 	// times can flucuate and should be averaged over multiple calls.
 	Usage() *Timing                // Per update loop performance metrics.
@@ -86,22 +232,65 @@ type engine struct {
 	loaded chan []*loadReq // Receive loaded models and noises.
 
 	// Sounds are heard by the sound listener at an app set pov.
-	soundListener *pov    // Current location of the sound listener.
-	sx, sy, sz    float64 // Last location of the sound listener.
+	soundListener *pov                 // Current location of the sound listener.
+	heard         placeListener        // Last listener placement sent to audio.
+	listened      bool                 // True if heard is from the current listener.
+	mixers        [mixGroups]*mixGroup // Sound volume controls.
+	plays         map[uint64]*playback // Latest playback of each sound.
+	finished      []*playback          // Playbacks done since the last update.
+	pid           uint64               // Last playback id.
+	sids          []uint64             // Scratch sound ids.
+	music         *music               // Soundtrack player.
+	device        string               // Last sound output device.
+	occluder      physics.Body         // Scratch sound occlusion ray.
+	streams       []*stream            // Generated sounds.
 
 	// Group the application entities by component.
 	// All entities are Pov (location:orientation) based.
-	eid    uint64                  // Next entity id.
-	povs   map[uint64]*pov         // Entity transforms.
-	cams   map[uint64]*camera      // Camera components.
-	models map[uint64]*model       // Visible components.
-	lights map[uint64]*light       // Light components.
-	noises map[uint64]*noise       // Audible components.
-	layers map[uint64]*layer       // (Pre) Render pass components.
-	bodies map[uint64]physics.Body // Non-colliding physic components.
-	solids map[uint64]physics.Body // Colliding physic components.
-	bods   []physics.Body          // Set from solids each update.
-	times  *Timing                 // Loop timing statistics.
+	eid    uint64                    // Next entity id.
+	povs   map[uint64]*pov           // Entity transforms.
+	cams   map[uint64]*camera        // Camera components.
+	models *table                    // Visible components.
+	lights map[uint64]*light         // Light components.
+	noises *table                    // Audible components.
+	layers map[uint64]*layer         // (Pre) Render pass components.
+	bodies *table                    // Non-colliding physic components.
+	solids *table                    // Colliding physic components.
+	bods   []physics.Body            // Set from solids each update.
+	prefab map[string]*sceneNode     // Reusable hierarchies by name.
+	gltfs  map[string]*load.GltfData // Imported glTF data by name.
+	objs   map[string]*objFile       // Imported obj data by name.
+	loads  []*loading                // Asset load progress reports.
+	names  map[string]*pov           // Named entities.
+	scenes map[string]*pov           // Top level scene entities.
+	tags   map[string][]*pov         // Tagged entities.
+	comped []*pov                    // Entities with components in add order.
+	cpovs  []*pov                    // Scratch entities for updating components.
+	comps  []Component               // Scratch components for updating components.
+	events *bus                      // Queued events and subscribers.
+	keys   []int                     // Scratch new key presses.
+	lives  *lifetimes                // Pov's waiting to be disposed.
+	timers *scheduler                // Scheduled application functions.
+	xforms *xforms                   // Pov transform storage.
+	eids   []uint64                  // Scratch entity ids for snapshots.
+	each   [][]uint64                // Scratch entity ids for nested Each.
+	depth  int                       // Current Each nesting.
+	jt     *lin.M4                   // Scratch bone transform.
+	ab     physics.Abox              // Scratch bounding box.
+	v0     *lin.V3                   // Scratch constraint direction.
+	q0     *lin.Q                    // Scratch constraint rotation.
+	aims   []*pov                    // Entities with constraints in set order.
+	undo   *journal                  // Optional change journal.
+	replay *replay                   // Optional input recording or playback.
+	tweens []*tween                  // Active tweens.
+	index  *spatial                  // Entities by location.
+	picks  *picker                   // Created on first Pick.
+	added  []*tween                  // Tweens started next update.
+	paths  []*path                   // Playing camera paths.
+	times  *Timing                   // Loop timing statistics.
+
+	// Engine wide render quality settings.
+	quality Quality // Default QualityMedium.
 }
 
 // newEngine is expected to be called once on startup
@@ -110,7 +299,18 @@ func newEngine(machine chan msg) *engine {
 	eng := &engine{alive: true, machine: machine}
 	eng.data = newAppData()
 	eng.times = &Timing{}
+	eng.quality = QualityPreset(QualityMedium)
 	eng.frame = []render.Draw{}
+	eng.events = newBus()
+	for group := range eng.mixers {
+		eng.mixers[group] = newMixGroup(eng, group)
+	}
+	eng.plays = map[uint64]*playback{}
+	eng.music = &music{eng: eng}
+	eng.occluder = NewRay(0, 0, -1)
+	eng.xforms = &xforms{}
+	eng.jt = &lin.M4{}
+	eng.v0, eng.q0 = &lin.V3{}, &lin.Q{}
 	eng.Reset()
 
 	// helpers that create and update state.
@@ -221,8 +421,12 @@ func (eng *engine) communicate() {
 		for _, req := range loaded {
 			if req.err != nil {
 				log.Printf("load error: %s", req.err)
+				eng.loadFailed(req)
 				continue
 			}
+			if req.a != nil {
+				eng.events.publish(LoadedEvent, req.a.label())
+			}
 			switch a := req.a.(type) {
 			case *mesh:
 				if m, ok := req.data.(*model); ok {
@@ -285,6 +489,13 @@ func (eng *engine) communicate() {
 // update polls user input, runs physics, calls application update,
 // and finally refreshes all models resulting in updated transforms.
 // The transform hierarchy is now ready to generate a render frame.
+//
+// Updates are repeatable given the same inputs: nothing depends on map
+// iteration order. Physics bodies, models, noises, components,
+// constraints, and tweens are processed in the order they were added.
+// Events are delivered in publish order to subscribers in subscribe
+// order. Timers run in due order, then in creation order.
+// Pov's are placed depth first in child order.
 func (eng *engine) update(app App, dt time.Duration, ut uint64) {
 
 	// Fetch input from the device thread. Essentially a sequential call.
@@ -293,26 +504,72 @@ func (eng *engine) update(app App, dt time.Duration, ut uint64) {
 	input := eng.data.input // User input has been refreshed.
 	state := eng.data.state // Engine state has been refreshed.
 	dts := dt.Seconds()     // delta time as float.
+	input.Dt = dts          // how long to get back to here.
+	input.Ut = ut           // update ticks.
+	if eng.replay != nil {
+		eng.replay.update(input) // record or play back input.
+	}
+	input.updateTaps() // after playback so double taps repeat.
+	eng.endPlays(eng.data.ended)
+	eng.updateDevice(state)
+	if input.Resized {
+		for _, c := range eng.cams {
+			c.setSize(state.W, state.H) // for camera picking.
+		}
+	}
+
+	// Drop the components removed during the last update.
+	for _, t := range []*table{eng.models, eng.noises, eng.bodies, eng.solids} {
+		t.compact()
+	}
 
 	// Run physics on all the bodies; adjusting location and orientation.
+	// Bodies are stepped in the order they were made solid.
 	eng.bods = eng.bods[:0] // reset keeping capacity.
-	for _, bod := range eng.solids {
-		eng.bods = append(eng.bods, bod)
+	for index, eid := range eng.solids.eids {
+		if pv, ok := eng.povs[eid]; ok && pv.active() {
+			eng.bods = append(eng.bods, eng.solids.items[index].(physics.Body))
+		}
 	}
 	eng.physics.Step(eng.bods, dts)
+	if eng.events.subscribed(ContactEvent) {
+		eng.events.publish(ContactEvent, eng.physics.Contacts(nil, nil))
+	}
+	if eng.events.subscribed(KeyEvent) {
+		eng.keys = eng.keys[:0]
+		for key, down := range input.Down {
+			if down == 1 {
+				eng.keys = append(eng.keys, key)
+			}
+		}
+		sort.Ints(eng.keys) // publish in a consistent order.
+		for _, key := range eng.keys {
+			eng.events.publish(KeyEvent, key)
+		}
+	}
+	eng.events.dispatch() // deliver events before the application update.
+	eng.updateLoadings()  // report asset load progress.
 
 	// Have the application adjust any or all state before rendering.
-	input.Dt = dts                // how long to get back to here.
-	input.Ut = ut                 // update ticks.
 	app.Update(eng, input, state) // application to updates its own state.
+	eng.updateComponents(dts)     // application per-entity behaviours.
+	eng.timers.update(dts)        // scheduled application functions.
+	eng.updateTweens(dts)         // interpolated value changes.
+	eng.updatePaths(dts)          // camera paths.
+	eng.updateLifetimes(dts)      // dispose expired entities.
 
 	// update assets that the application changed or which need
 	// per tick processing. Per-ticks include animated models,
 	// particle effects, surfaces, phrases, ...
 	if eng.alive {
 		eng.updateModels(dts)                // load and bind updated data.
+		eng.updateConstraints()              // track targets after animation.
 		eng.placeModels(eng.root(), lin.M4I) // update all transforms.
 		eng.updateSoundListener()            // reposition sound listener.
+		eng.updateNoises()                   // move played sounds.
+		eng.music.update()                   // start music tracks.
+		eng.updateStreams(eng.data.queue)    // send generated sounds.
+		eng.updatePlays(dts)                 // start and control sounds.
 	}
 }
 
@@ -320,7 +577,11 @@ func (eng *engine) update(app App, dt time.Duration, ut uint64) {
 // and CPU particle effects. Any new models are sent off for loading
 // and any updated models generate data rebind requests.
 func (eng *engine) updateModels(dts float64) {
-	for eid, m := range eng.models {
+	for index, eid := range eng.models.eids {
+		if eid == 0 {
+			continue // removed.
+		}
+		m := eng.models.items[index].(*model)
 		if len(m.loads) > 0 { // load model assets if necessary.
 			eng.loader.queueLoads(m.loads)
 			m.loads = m.loads[:0]
@@ -329,11 +590,11 @@ func (eng *engine) updateModels(dts float64) {
 			// from effects, phrase updates, and animations.
 
 			// handle any data updates with rebind requests.
-			if pv, ok := eng.povs[eid]; ok && pv.visible {
+			if pv, ok := eng.povs[eid]; ok && pv.visible && pv.active() {
 				if m.effect != nil {
 					// udpate and rebind particle effects which can
 					// change mesh data.
-					m.effect.update(m, dt.Seconds())
+					m.effect.update(m, dt.Seconds(), eng.quality.Particles)
 				}
 				if !m.msh.bound {
 					eng.rebind(m.msh)
@@ -345,7 +606,11 @@ func (eng *engine) updateModels(dts float64) {
 						tex.bound = true
 					}
 				}
-				if m.anm != nil {
+				switch {
+				case m.rag != nil:
+					// ragdoll bodies replace the animation.
+					m.rag.update()
+				case m.anm != nil:
 					// animations update the bone position matricies.
 					// These are bound as uniforms at draw time.
 					m.animate(dts)
@@ -353,8 +618,8 @@ func (eng *engine) updateModels(dts float64) {
 			}
 		}
 	}
-	for _, n := range eng.noises {
-		if len(n.loads) > 0 { // load noise sounds if necessary.
+	for _, item := range eng.noises.items {
+		if n, ok := item.(*noise); ok && len(n.loads) > 0 { // load noise sounds if necessary.
 			eng.loader.queueLoads(n.loads)
 			n.loads = n.loads[:0]
 		}
@@ -362,28 +627,133 @@ func (eng *engine) updateModels(dts float64) {
 	eng.loader.loadQueued()
 }
 
-// placeModels walks the transform hierarchy updating all the model
+// placeModels walks the transform hierarchy updating the model
 // transforms. This is called before rendering passes are done.
 func (eng *engine) placeModels(p *pov, parent *lin.M4) {
-	p.mm.SetQ(p.rot.Inv(p.at.Rot)) // invert model rotation.
-	p.mm.ScaleSM(p.Scale())        // scale is applied first (on left of rotation)
-	l := p.at.Loc
-	p.mm.TranslateMT(l.X, l.Y, l.Z) // translate is applied last (on right of rotation).
-	p.mm.Mult(p.mm, parent)         // model transform + parent transform
+	eng.placePov(p, parent, false)
+}
+
+// placePov only updates the model transform if the local transform, or
+// a parent transform, has changed since the last update. Locations and
+// rotations can be changed directly, ie: by physics, so changes are found
+// by comparing with the previously placed values.
+func (eng *engine) placePov(p *pov, parent *lin.M4, dirty bool) (resized bool) {
+	if dirty = p.changed() || dirty || p.joint != ""; dirty { // bones move each update.
+		p.mm.SetQ(p.rot.Inv(p.at.Rot)) // invert model rotation.
+		p.mm.ScaleSM(p.Scale())        // scale is applied first (on left of rotation)
+		l := p.at.Loc
+		p.mm.TranslateMT(l.X, l.Y, l.Z) // translate is applied last (on right of rotation).
+		if p.joint != "" && p.parent != nil {
+			if m, ok := eng.models.model(p.parent.eid); ok {
+				if m.jointTransform(m.Joint(p.joint), eng.jt) {
+					p.mm.Mult(p.mm, eng.jt) // model transform + bone transform
+				}
+			}
+		}
+		p.mm.Mult(p.mm, parent) // model transform + parent transform
+	}
+	if p.moved = dirty; dirty && p.onMove != nil {
+		p.onMove(p)
+	}
+	resized, p.wasOn = dirty || p.enabled != p.wasOn, p.enabled
+	if !p.enabled {
+		p.pending = p.pending || dirty // update children when enabled.
+		return resized                 // disabled children are left as is.
+	}
+	dirty, p.pending = dirty || p.pending, false
 	for _, child := range p.children {
-		eng.placeModels(child, p.mm) // recursive traversal.
+		if eng.placePov(child, p.mm, dirty) { // recursive traversal.
+			resized = true
+		}
+	}
+	if resized = resized || eng.boundsChanged(p); resized {
+		eng.placeBounds(p) // after the children bounds.
 	}
+	return resized
 }
 
-// updateSoundListener checks and updates the sound listeners location.
+// updateSoundListener tracks the listener location, orientation, and
+// velocity each update. A listener Pov with a Camera hears from the
+// camera so that sounds follow the view. The listener faces along -Z
+// with +Y up. Listeners without a physics body get their velocity
+// from how far they moved since the last update.
 func (eng *engine) updateSoundListener() {
-	x, y, z := eng.soundListener.Location()
-	if x != eng.sx || y != eng.sy || z != eng.sz {
-		eng.sx, eng.sy, eng.sz = x, y, z
-		go func(x, y, z float64) {
-			eng.machine <- &placeListener{x: x, y: y, z: z}
-		}(x, y, z)
+	l, heard := eng.soundListener, placeListener{}
+	if c, ok := eng.cams[l.eid]; ok {
+		heard.at.x, heard.at.y, heard.at.z = c.Location()
+		heard.fx, heard.fy, heard.fz = c.axis(0, 0, -1)
+		heard.ux, heard.uy, heard.uz = c.axis(0, 1, 0)
+	} else {
+		rot := l.WorldRotation()
+		heard.at.x, heard.at.y, heard.at.z = l.WorldLocation()
+		heard.fx, heard.fy, heard.fz = lin.MultSQ(0, 0, -1, rot)
+		heard.ux, heard.uy, heard.uz = lin.MultSQ(0, 1, 0, rot)
+	}
+	if b := eng.body(l); b != nil {
+		heard.at.vx, heard.at.vy, heard.at.vz = b.Speed()
+	} else if eng.listened {
+		was, perSecond := eng.heard.at, 1/dt.Seconds()
+		heard.at.vx = (heard.at.x - was.x) * perSecond
+		heard.at.vy = (heard.at.y - was.y) * perSecond
+		heard.at.vz = (heard.at.z - was.z) * perSecond
 	}
+	eng.listened = true
+	if heard != eng.heard {
+		eng.heard = heard
+		go func(heard placeListener) { eng.machine <- &heard }(heard)
+	}
+}
+
+// updateNoises moves the played sounds with their Pov's.
+func (eng *engine) updateNoises() {
+	for index, eid := range eng.noises.eids {
+		n, _ := eng.noises.items[index].(*noise)
+		if p, ok := eng.povs[eid]; ok && p.active() && len(n.played) > 0 {
+			n.place(p)
+			if n.occMask != 0 {
+				n.occlude(eng.occluded(p, n.occMask))
+			}
+		}
+	}
+}
+
+// occluded returns true if a solid body in the mask layers is between
+// the sound listener and the Pov. The bodies of the listener and the
+// Pov don't block sounds.
+func (eng *engine) occluded(p *pov, mask uint32) bool {
+	at := eng.heard.at
+	x, y, z := p.WorldLocation()
+	dx, dy, dz := x-at.x, y-at.y, z-at.z
+	dist := dx*dx + dy*dy + dz*dz
+	if dist == 0 {
+		return false
+	}
+	physics.SetRay(eng.occluder, dx, dy, dz)
+	eng.occluder.World().SetLoc(at.x, at.y, at.z)
+	for index, eid := range eng.solids.eids {
+		b, _ := eng.solids.items[index].(physics.Body)
+		pv, ok := eng.povs[eid]
+		if !ok || eid == p.eid || eid == eng.soundListener.eid || pv.layers&mask == 0 || !pv.active() {
+			continue
+		}
+		if hit, hx, hy, hz := physics.Cast(eng.occluder, b); hit {
+			hx, hy, hz = hx-at.x, hy-at.y, hz-at.z
+			if hx*hx+hy*hy+hz*hz < dist {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// soundAt returns the world location of the Pov and
+// the velocity of its physics body, if any.
+func (eng *engine) soundAt(p *pov) (at soundAt) {
+	at.x, at.y, at.z = p.WorldLocation()
+	if b := eng.body(p); b != nil {
+		at.vx, at.vy, at.vz = b.Speed()
+	}
+	return at
 }
 
 // release sends a release resource request to the machine.
@@ -422,18 +792,35 @@ func (eng *engine) Shutdown() {
 // its initial state. This allows the application to put the
 // engine back in a clean state without restarting.
 func (eng *engine) Reset() {
+	if eng.undo != nil {
+		eng.undo.Close() // journal refers to the old entities.
+	}
 	eng.dispose(eng.root(), PovNode)
 	eng.povs = map[uint64]*pov{}
 	eng.cams = map[uint64]*camera{}
-	eng.models = map[uint64]*model{}
+	eng.models = newTable()
 	eng.lights = map[uint64]*light{}
 	eng.layers = map[uint64]*layer{}
-	eng.noises = map[uint64]*noise{}
-	eng.bodies = map[uint64]physics.Body{}
-	eng.solids = map[uint64]physics.Body{}
+	eng.noises = newTable()
+	eng.bodies = newTable()
+	eng.solids = newTable()
+	eng.prefab = map[string]*sceneNode{}
+	eng.gltfs = map[string]*load.GltfData{}
+	eng.objs = map[string]*objFile{}
+	eng.loads = nil
+	eng.names = map[string]*pov{}
+	eng.scenes = map[string]*pov{}
+	eng.lives = &lifetimes{}
+	eng.timers = newScheduler()
+	eng.tags = map[string][]*pov{}
+	eng.comped = nil
+	eng.aims = nil
+	eng.index = newSpatial()
+	eng.tweens, eng.added = nil, nil
+	eng.paths = nil
 	eng.eid = 1                              // 0 invalid, 1 used for root.
 	eng.povs[eng.eid] = newPov(eng, eng.eid) // root
-	eng.soundListener = eng.povs[eng.eid]
+	eng.soundListener, eng.listened = eng.povs[eng.eid], false
 }
 
 // State provides access to current engine state.
@@ -460,11 +847,62 @@ func (eng *engine) newPov(p Pov) Pov {
 		eng.povs[p.eid] = p
 		p.parent = parent
 		parent.children = append(parent.children, p)
+		if eng.undo != nil {
+			eng.undo.created(p)
+		}
 		return p
 	}
 	return nil
 }
 
+// setName replaces the pov name in the name lookup.
+func (eng *engine) setName(p *pov, name string) {
+	if eng.names[p.name] == p {
+		delete(eng.names, p.name)
+	}
+	if p.name = name; name != "" {
+		eng.names[name] = p
+	}
+}
+
+// setTag moves the pov from its old tag group to the new one.
+func (eng *engine) setTag(p *pov, tag string) {
+	if p.tag != "" {
+		group := eng.tags[p.tag]
+		for index, tp := range group {
+			if tp == p {
+				group = append(group[:index], group[index+1:]...)
+				break
+			}
+		}
+		if len(group) == 0 {
+			delete(eng.tags, p.tag)
+		} else {
+			eng.tags[p.tag] = group
+		}
+	}
+	if p.tag = tag; tag != "" {
+		eng.tags[tag] = append(eng.tags[tag], p)
+	}
+}
+
+// Implement Eng interface.
+func (eng *engine) Find(name string) Pov {
+	if p, ok := eng.names[name]; ok {
+		return p
+	}
+	return nil
+}
+
+// Implement Eng interface. Returns a new slice each call.
+func (eng *engine) FindAllTagged(tag string) []Pov {
+	found := []Pov{}
+	for _, p := range eng.tags[tag] {
+		found = append(found, p)
+	}
+	return found
+}
+
 // camera entities.
 func (eng *engine) cam(p Pov) Camera {
 	if pv, ok := p.(*pov); ok && pv != nil {
@@ -477,6 +915,7 @@ func (eng *engine) cam(p Pov) Camera {
 func (eng *engine) newCam(p Pov) Camera {
 	if pv, ok := p.(*pov); ok && pv != nil {
 		c := newCamera()
+		c.setSize(eng.data.state.W, eng.data.state.H)
 		eng.cams[pv.eid] = c
 		return c
 	}
@@ -486,7 +925,7 @@ func (eng *engine) newCam(p Pov) Camera {
 // model entities.
 func (eng *engine) model(p Pov) Model {
 	if pv, ok := p.(*pov); ok && pv != nil {
-		if model, ok := eng.models[pv.eid]; ok {
+		if model, ok := eng.models.model(pv.eid); ok {
 			return model
 		}
 	}
@@ -494,9 +933,9 @@ func (eng *engine) model(p Pov) Model {
 }
 func (eng *engine) newModel(p Pov, shader string) Model {
 	if pv, ok := p.(*pov); ok && pv != nil {
-		if _, ok := eng.models[pv.eid]; !ok {
+		if _, ok := eng.models.model(pv.eid); !ok {
 			m := newModel(shader)
-			eng.models[pv.eid] = m
+			eng.models.set(pv.eid, m)
 			return m
 		}
 	}
@@ -547,10 +986,10 @@ func (eng *engine) newLayer(p Pov, attr int) Layer {
 // body: physics entities.
 func (eng *engine) body(p Pov) physics.Body {
 	if pv, ok := p.(*pov); ok && pv != nil {
-		if body, ok := eng.bodies[pv.eid]; ok {
+		if body, ok := eng.bodies.body(pv.eid); ok {
 			return body
 		}
-		if body, ok := eng.solids[pv.eid]; ok {
+		if body, ok := eng.solids.body(pv.eid); ok {
 			return body
 		}
 	}
@@ -558,9 +997,11 @@ func (eng *engine) body(p Pov) physics.Body {
 }
 func (eng *engine) newBody(p Pov, b physics.Body) physics.Body {
 	if pv, ok := p.(*pov); ok && pv != nil {
-		if _, ok := eng.bodies[pv.eid]; !ok {
+		if _, ok := eng.bodies.body(pv.eid); !ok {
 			b.SetWorld(pv.at)
-			eng.bodies[pv.eid] = b
+			_, mask := b.Layers()
+			b.SetLayers(pv.layers, mask)
+			eng.bodies.set(pv.eid, b)
 			return b
 		}
 	}
@@ -570,10 +1011,10 @@ func (eng *engine) newBody(p Pov, b physics.Body) physics.Body {
 // solid: physics entities.
 func (eng *engine) setSolid(p Pov, mass, bounce float64) {
 	if pv, ok := p.(*pov); ok && pv != nil {
-		if b, okb := eng.bodies[pv.eid]; okb {
+		if b, okb := eng.bodies.body(pv.eid); okb {
 			b.SetMaterial(mass, bounce)
-			eng.solids[pv.eid] = b
-			delete(eng.bodies, pv.eid)
+			eng.solids.set(pv.eid, b)
+			eng.bodies.del(pv.eid)
 		}
 	}
 }
@@ -581,7 +1022,7 @@ func (eng *engine) setSolid(p Pov, mass, bounce float64) {
 // noise: audio entities.
 func (eng *engine) noise(p Pov) Noise {
 	if pv, ok := p.(*pov); ok && pv != nil {
-		if noise, ok := eng.noises[pv.eid]; ok {
+		if noise, ok := eng.noises.noise(pv.eid); ok {
 			return noise
 		}
 	}
@@ -589,9 +1030,9 @@ func (eng *engine) noise(p Pov) Noise {
 }
 func (eng *engine) newNoise(p Pov) Noise {
 	if pv, ok := p.(*pov); ok && pv != nil {
-		if _, ok := eng.noises[pv.eid]; !ok {
+		if _, ok := eng.noises.noise(pv.eid); !ok {
 			n := newNoise(eng, pv.eid)
-			eng.noises[pv.eid] = n
+			eng.noises.set(pv.eid, n)
 			return n
 		}
 	}
@@ -601,11 +1042,16 @@ func (eng *engine) newNoise(p Pov) Noise {
 // There is always only one listener. It is associated with the root pov
 // by default. This changes the listener location to the given pov.
 func (eng *engine) setListener(p Pov) {
-	if pv, ok := p.(*pov); ok && pv != nil {
+	if pv, ok := p.(*pov); ok && pv != nil && pv != eng.soundListener {
 		eng.soundListener = pv
+		eng.listened = false // don't measure velocity across the change.
 	}
 }
 
+// Implement Eng.
+func (eng *engine) SetListener(p Pov) { eng.setListener(p) }
+func (eng *engine) Listener() Pov     { return eng.soundListener }
+
 // FUTURE: cleaning up resources is not complete. Dispose currently means
 // removing entities from the Pov hierarchy and from the eng entity manager,
 // yet keeps them in the cache and bound on the GPU/Snd devices. Applications
@@ -623,19 +1069,19 @@ func (eng *engine) dispose(p Pov, component int) {
 	if pv, ok := p.(*pov); ok && pv != nil {
 		switch component {
 		case PovBody:
-			delete(eng.bodies, pv.eid)
-			delete(eng.solids, pv.eid)
+			eng.bodies.del(pv.eid)
+			eng.solids.del(pv.eid)
 		case PovCam:
 			delete(eng.cams, pv.eid)
 		case PovModel:
-			if m, ok := eng.models[pv.eid]; ok {
+			if m, ok := eng.models.model(pv.eid); ok {
 				eng.disposeModel(m)
-				delete(eng.models, pv.eid)
+				eng.models.del(pv.eid)
 			}
 		case PovNoise:
-			if n, ok := eng.noises[pv.eid]; ok {
+			if n, ok := eng.noises.noise(pv.eid); ok {
 				eng.disposeNoise(n)
-				delete(eng.noises, pv.eid)
+				eng.noises.del(pv.eid)
 			}
 		case PovLight:
 			delete(eng.lights, pv.eid)
@@ -644,7 +1090,14 @@ func (eng *engine) dispose(p Pov, component int) {
 				eng.disposeLayer(l)
 				delete(eng.layers, pv.eid)
 			}
+		case PovComps:
+			for len(pv.comps) > 0 {
+				eng.removeComponent(pv, pv.comps[len(pv.comps)-1])
+			}
 		case PovNode:
+			if eng.undo != nil {
+				eng.undo.disposed(pv)
+			}
 			eng.disposePov(pv)
 		}
 	}
@@ -654,10 +1107,28 @@ func (eng *engine) dispose(p Pov, component int) {
 // of the transform hierarchy. All associated objects are disposed.
 func (eng *engine) disposePov(pv *pov) {
 	delete(eng.povs, pv.eid)
+	eng.xforms.put(pv.x) // reused by new pov's.
+	eng.index.remove(pv)
+	if pv.con != nil {
+		pv.con = nil
+		eng.unconstrain(pv)
+	}
+	pv.setXform(&xform{})
+	eng.setName(pv, "")
+	eng.setTag(pv, "")
+	for name, p := range eng.scenes {
+		if p == pv {
+			delete(eng.scenes, name)
+		}
+	}
 	eng.dispose(pv, PovCam)
 	eng.dispose(pv, PovBody)
 	eng.dispose(pv, PovModel)
 	eng.dispose(pv, PovNoise)
+	eng.dispose(pv, PovComps)
+	if pv == eng.soundListener {
+		eng.setListener(eng.root()) // go back to the default listener.
+	}
 	if pv.parent != nil {
 		pv.parent.remChild(pv) // remove the one back reference that matters.
 	}
@@ -669,6 +1140,9 @@ func (eng *engine) disposePov(pv *pov) {
 
 // disposeModel releases any references to assets.
 func (eng *engine) disposeModel(m *model) {
+	if m.rag != nil {
+		m.rag.Dispose() // removes the ragdoll limbs.
+	}
 	m.msh = nil
 	m.shd = nil
 	m.anm = nil
@@ -679,6 +1153,7 @@ func (eng *engine) disposeModel(m *model) {
 
 // disposeNoise releases references to assets.
 func (eng *engine) disposeNoise(n *noise) {
+	eng.disposeStreams(n)
 	n.snds = []*sound{} // garbage collect the old sounds.
 }
 
@@ -697,9 +1172,9 @@ func (eng *engine) Usage() *Timing { return eng.times }
 // Modelled returns the total number of models and the total
 // number of verticies for all models.
 func (eng *engine) Modelled() (models, verts int) {
-	models = len(eng.models)
-	for _, m := range eng.models {
-		if m.msh != nil && len(m.msh.vdata) > 0 {
+	models = eng.models.size()
+	for _, item := range eng.models.items {
+		if m, ok := item.(*model); ok && m.msh != nil && len(m.msh.vdata) > 0 {
 			verts += m.msh.vdata[0].Len()
 		}
 	}
@@ -725,6 +1200,65 @@ func (eng *engine) ShowCursor(show bool) {
 func (eng *engine) SetCursorAt(x, y int) {
 	go func(x, y int) { eng.machine <- &setCursor{cx: x, cy: y} }(x, y)
 }
+
+// SetCursor reads the cursor image in a goroutine so that the
+// engine is not held up by the image load.
+func (eng *engine) SetCursor(image string, hotx, hoty int) {
+	go func(name string, hotx, hoty int) {
+		ci := &cursorImage{hotx: hotx, hoty: hoty}
+		if name != "" {
+			img, err := eng.loader.ld.Png(name)
+			if err != nil {
+				log.Printf("eng.SetCursor: could not load %s %s", name, err)
+				return
+			}
+			ci.img = toNRGBA(img)
+		}
+		eng.machine <- ci
+	}(image, hotx, hoty)
+}
+
+// toNRGBA returns the image as non-premultiplied RGBA pixels.
+func toNRGBA(img image.Image) *image.NRGBA {
+	if nrgba, ok := img.(*image.NRGBA); ok {
+		return nrgba
+	}
+	nrgba := image.NewNRGBA(img.Bounds())
+	draw.Draw(nrgba, nrgba.Bounds(), img, img.Bounds().Min, draw.Src)
+	return nrgba
+}
+func (eng *engine) CaptureMouse(capture bool) {
+	go func(capture bool) { eng.machine <- &captureMouse{enable: capture} }(capture)
+}
+func (eng *engine) SetClipboard(text string) {
+	go func(text string) { eng.machine <- &clipboard{text: text} }(text)
+}
+
+// Clipboard waits for the machine to read the system clipboard
+// since some platforms only allow access from the main thread.
+func (eng *engine) Clipboard() string {
+	if eng.machine == nil {
+		return ""
+	}
+	reply := make(chan string)
+	eng.machine <- &clipboard{reply: reply}
+	return <-reply
+}
+
+// KeyName asks the machine for the keyboard layout label of the key
+// location. The Keysym is used for keys without a layout label.
+func (eng *engine) KeyName(key int) string {
+	name := ""
+	if eng.machine != nil {
+		reply := make(chan string)
+		eng.machine <- &keyName{key: key, reply: reply}
+		name = <-reply
+	}
+	if sym := Keysym(key); name == "" && sym != 0 {
+		name = string(sym)
+	}
+	return name
+}
 func (eng *engine) Enable(attr uint32, enabled bool) {
 	go func(attr uint32, enabled bool) {
 		eng.machine <- &enableAttr{attr: attr, enable: enabled}
@@ -733,15 +1267,32 @@ func (eng *engine) Enable(attr uint32, enabled bool) {
 func (eng *engine) ToggleFullScreen() {
 	go func() { eng.machine <- &toggleScreen{} }()
 }
-func (eng *engine) Mute(mute bool) {
-	gain := 1.0
-	if mute {
-		gain = 0.0
+func (eng *engine) Mute(mute bool)              { eng.mixers[MixMaster].Mute(mute) }
+func (eng *engine) SetVolume(zeroToOne float64) { eng.mixers[MixMaster].SetVolume(zeroToOne) }
+func (eng *engine) SetAttenuation(model int) {
+	go func(model int) { eng.machine <- &setAttenuation{model: model} }(model)
+}
+
+// SetAudioDevice switches the sound output device, see State.AudioDevices.
+// The default device is followed as it changes, ie: when headphones are
+// plugged in. Unplugged devices switch to the default device. Switching
+// is ignored, and logged, where the audio layer doesn't support it.
+// AudioEvent is published when the device changes.
+func (eng *engine) SetAudioDevice(name string) {
+	go func(name string) { eng.machine <- &setAudioDevice{name: name} }(name)
+}
+
+// updateDevice publishes changes to the sound output device.
+func (eng *engine) updateDevice(state *State) {
+	if state.AudioDevice != eng.device {
+		if eng.device != "" { // not the startup device.
+			eng.events.publish(AudioEvent, state.AudioDevice)
+		}
+		eng.device = state.AudioDevice
 	}
-	go func(gain float64) { eng.machine <- &setVolume{gain: gain} }(gain)
 }
-func (eng *engine) SetVolume(zeroToOne float64) {
-	go func(gain float64) { eng.machine <- &setVolume{gain: zeroToOne} }(zeroToOne)
+func (eng *engine) SetDoppler(factor, speed float64) {
+	go func(factor, speed float64) { eng.machine <- &setDoppler{factor: factor, speed: speed} }(factor, speed)
 }
 
 // engine
@@ -756,6 +1307,36 @@ func (eng *engine) Collide(a, b physics.Body) bool {
 	return eng.physics.Collide(a, b)
 }
 
+// Contacts returns the contacts found by the last physics step.
+func (eng *engine) Contacts(b physics.Body, contacts []physics.Contact) []physics.Contact {
+	return eng.physics.Contacts(b, contacts)
+}
+
+// Implement Eng interface.
+func (eng *engine) Raycast(ray physics.Body, mask uint32) (p Pov, x, y, z float64) {
+	if ray == nil {
+		return nil, 0, 0, 0
+	}
+	o := ray.World().Loc
+	nearest, hitEid := math.MaxFloat64, uint64(0)
+	for _, bs := range []*table{eng.bodies, eng.solids} {
+		for index, eid := range bs.eids {
+			b, _ := bs.items[index].(physics.Body)
+			pv, ok := eng.povs[eid]
+			if !ok || b == ray || pv.layers&mask == 0 || !pv.active() {
+				continue
+			}
+			if hit, hx, hy, hz := physics.Cast(ray, b); hit {
+				dx, dy, dz := hx-o.X, hy-o.Y, hz-o.Z
+				if dist := dx*dx + dy*dy + dz*dz; dist < nearest || dist == nearest && eid < hitEid {
+					nearest, hitEid, p, x, y, z = dist, eid, pv, hx, hy, hz
+				}
+			}
+		}
+	}
+	return p, x, y, z
+}
+
 // NewBox creates a box shaped physics body located at the origin.
 // The box size is given by the half-extents so that actual size
 // is w=2*hx, h=2*hy, d=2*hz.
@@ -769,6 +1350,13 @@ func NewSphere(radius float64) physics.Body {
 	return physics.NewBody(physics.NewSphere(radius))
 }
 
+// NewCapsule creates a capsule shaped physics body located at the
+// origin. The capsule is a cylinder with rounded ends along the Y axis.
+// The capsule length is 2*halfHeight plus the end caps, 2*radius.
+func NewCapsule(radius, halfHeight float64) physics.Body {
+	return physics.NewBody(physics.NewCapsule(radius, halfHeight))
+}
+
 // NewRay creates a ray located at the origin and pointing in the
 // direction dx, dy, dz.
 func NewRay(dx, dy, dz float64) physics.Body {
@@ -4,6 +4,7 @@
 package vu
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/gazed/vu/math/lin"
@@ -26,3 +27,462 @@ func TestChildWorldTransform(t *testing.T) {
 		eng.Shutdown()
 	}
 }
+
+// TestFind checks the named and tagged Pov lookups.
+func TestFind(t *testing.T) {
+	eng := newEngine(nil)
+	defer eng.Shutdown()
+	player := eng.Root().NewPov().SetName("player")
+	enemies := eng.Root().NewPov()
+	e0 := enemies.NewPov().SetTag("enemy")
+	e1 := enemies.NewPov().SetTag("enemy")
+	e2 := enemies.NewPov().SetTag("enemy")
+	if eng.Find("player") != player || eng.Find("nobody") != nil {
+		t.Errorf("Expected to find the player")
+	}
+	if found := eng.FindAllTagged("enemy"); len(found) != 3 || found[0] != e0 || found[2] != e2 {
+		t.Errorf("Expected 3 enemies in tag order, got %d", len(found))
+	}
+	e1.SetTag("ally")
+	if found := eng.FindAllTagged("enemy"); len(found) != 2 || found[1] != e2 {
+		t.Errorf("Expected retagged Pov to be removed, got %d", len(found))
+	}
+	player.SetName("hero")
+	if eng.Find("player") != nil || eng.Find("hero") != player {
+		t.Errorf("Expected renamed player")
+	}
+	enemies.Dispose(PovNode)
+	player.Dispose(PovNode)
+	if len(eng.FindAllTagged("enemy")) != 0 || len(eng.FindAllTagged("ally")) != 0 || eng.Find("hero") != nil {
+		t.Errorf("Expected disposed Pov's to be removed")
+	}
+}
+
+// TestLayers checks that Pov layers filter camera drawing,
+// body collisions, and ray casts.
+func TestLayers(t *testing.T) {
+	eng := newEngine(nil)
+	defer eng.Shutdown()
+	top := eng.Root().NewPov()
+	cam := top.NewCam()
+	cam.SetMask(2)
+	ui := top.NewPov().SetLayers(4)
+	ui.NewModel("uv")
+	ball := ui.NewPov().SetLayers(2).SetLocation(0, 0, -10)
+	ball.NewModel("uv")
+	ball.NewBody(NewSphere(1))
+	ground := top.NewPov().SetLocation(0, -5, 0)
+	ground.NewBody(NewPlane(0, -1, 0)) // ray cast planes face away from rays.
+	ground.SetLayers(8)
+	if layers, mask := ground.Body().Layers(); layers != 8 || mask != ^uint32(0) {
+		t.Errorf("Expected body layer 8 with full mask, got %d %d", layers, mask)
+	}
+
+	// Only the ball is drawn, though its parent is skipped.
+	eng.placeModels(eng.root(), lin.M4I)
+	drawn := 0
+	for _, p := range eng.scene.updateScene(eng, 0, nil, eng.root(), nil) {
+		if _, ok := eng.models.model(p.eid); ok && p != ball {
+			t.Errorf("Expected only the ball model")
+		}
+		if p == ball {
+			drawn++
+		}
+	}
+	if drawn != 1 {
+		t.Errorf("Expected ball to be drawn")
+	}
+
+	// Rays only hit the masked layers.
+	ray := NewRay(0, -1, -1)
+	if p, _, _, _ := eng.Raycast(ray, ^uint32(0)); p != ground {
+		t.Errorf("Expected ray to hit the ground")
+	}
+	if p, _, _, _ := eng.Raycast(ray, 2); p != nil {
+		t.Errorf("Expected ray to miss the masked ground")
+	}
+	SetRay(ray, 0, 0, -1)
+	if p, _, _, z := eng.Raycast(ray, 2); p != ball || !lin.Aeq(z, -9) {
+		t.Errorf("Expected ray to hit the ball at z -9, got %f", z)
+	}
+}
+
+// TestRaycastTie checks that equally close hits return the first Pov.
+func TestRaycastTie(t *testing.T) {
+	eng := newEngine(nil)
+	defer eng.Shutdown()
+	solid := eng.Root().NewPov().SetLocation(0, 0, -5)
+	solid.NewBody(NewSphere(1))
+	solid.SetSolid(1, 0)
+	first := eng.Root().NewPov().SetLocation(0, 0, -5)
+	first.NewBody(NewSphere(1)) // non-colliding bodies are checked first.
+	if p, _, _, _ := eng.Raycast(NewRay(0, 0, -1), ^uint32(0)); p != solid {
+		t.Errorf("Expected the earliest created Pov")
+	}
+}
+
+// TestEnabled checks that disabling a Pov suspends its hierarchy.
+func TestEnabled(t *testing.T) {
+	eng := newEngine(nil)
+	defer eng.Shutdown()
+	screen := eng.Root().NewPov()
+	button := screen.NewPov().SetLocation(1, 0, 0)
+	button.NewModel("uv")
+	button.NewBody(NewSphere(1))
+	button.SetSolid(1, 0)
+	screen.SetEnabled(false)
+	if screen.Enabled() || !button.Enabled() || button.(*pov).active() {
+		t.Errorf("Expected disabled parent to suspend the child")
+	}
+	eng.placeModels(eng.root(), lin.M4I)
+	if x, _, _ := button.World(); x != 0 {
+		t.Errorf("Expected disabled child transform to be skipped")
+	}
+	for _, p := range eng.scene.updateScene(eng, 0, nil, eng.root(), nil) {
+		if p == screen || p == button {
+			t.Errorf("Expected disabled Pov's to be skipped")
+		}
+	}
+	if p, _, _, _ := eng.Raycast(NewRay(1, 0, 0), ^uint32(0)); p != nil {
+		t.Errorf("Expected disabled bodies to be ignored")
+	}
+	screen.SetEnabled(true)
+	eng.placeModels(eng.root(), lin.M4I)
+	if x, _, _ := button.World(); x != 1 {
+		t.Errorf("Expected enabled child transform, got %f", x)
+	}
+}
+
+// TestSetParent checks that reparenting can keep the world transform.
+func TestSetParent(t *testing.T) {
+	eng := newEngine(nil)
+	defer eng.Shutdown()
+	car := eng.Root().NewPov().SetLocation(10, 0, 5).SetScale(2, 2, 2)
+	car.Spin(0, 90, 0)
+	item := eng.Root().NewPov().SetLocation(1, 2, 3)
+	item.Spin(30, 0, 0)
+	eng.placeModels(eng.root(), lin.M4I)
+	wx, wy, wz := item.World()
+	probe := item.NewPov().SetLocation(0, 0, 1) // checks item orientation.
+	eng.placeModels(eng.root(), lin.M4I)
+	px, py, pz := probe.World()
+
+	item.SetParent(car, true)
+	if len(eng.root().children) != 1 || len(car.(*pov).children) != 1 {
+		t.Fatalf("Expected item to move to car")
+	}
+	eng.placeModels(eng.root(), lin.M4I)
+	if x, y, z := item.World(); !lin.Aeq(x, wx) || !lin.Aeq(y, wy) || !lin.Aeq(z, wz) {
+		t.Errorf("Expected world location %f %f %f, got %f %f %f", wx, wy, wz, x, y, z)
+	}
+	if x, y, z := probe.World(); !lin.Aeq(x, px) || !lin.Aeq(y, py) || !lin.Aeq(z, pz) {
+		t.Errorf("Expected world orientation %f %f %f, got %f %f %f", px, py, pz, x, y, z)
+	}
+	if sx, _, _ := item.Scale(); !lin.Aeq(sx, 0.5) {
+		t.Errorf("Expected local scale 0.5, got %f", sx)
+	}
+
+	// moving without keeping world uses the local transform as is.
+	lx, ly, lz := item.Location()
+	item.SetParent(eng.Root(), false)
+	if x, y, z := item.Location(); len(eng.root().children) != 2 || x != lx || y != ly || z != lz {
+		t.Errorf("Expected local transform to be kept")
+	}
+	item.SetParent(probe, true) // ignored: probe is a child of item.
+	if probe.(*pov).parent != item || item.(*pov).parent != eng.root() {
+		t.Errorf("Expected cyclic parent to be ignored")
+	}
+}
+
+// TestMoved checks that only changed transforms are recalculated.
+func TestMoved(t *testing.T) {
+	eng := newEngine(nil)
+	defer eng.Shutdown()
+	parent := eng.Root().NewPov()
+	child := parent.NewPov().SetLocation(1, 0, 0)
+	other := eng.Root().NewPov()
+	moves := 0
+	child.SetMoved(func(p Pov) { moves++ })
+	eng.placeModels(eng.root(), lin.M4I)
+	if !child.Moved() || !other.Moved() || moves != 1 {
+		t.Errorf("Expected initial placement to move everything")
+	}
+	eng.placeModels(eng.root(), lin.M4I)
+	if child.Moved() || other.Moved() || moves != 1 {
+		t.Errorf("Expected unchanged transforms to be skipped")
+	}
+	parent.Rotation().W = 0.5 // direct changes, ie: physics, are found.
+	parent.Rotation().Y = 0.8660254
+	eng.placeModels(eng.root(), lin.M4I)
+	if !parent.Moved() || !child.Moved() || other.Moved() || moves != 2 {
+		t.Errorf("Expected parent change to move child")
+	}
+
+	// changes under a disabled Pov are applied once it is enabled.
+	parent.SetEnabled(false)
+	parent.SetLocation(0, 5, 0)
+	eng.placeModels(eng.root(), lin.M4I)
+	parent.SetEnabled(true)
+	eng.placeModels(eng.root(), lin.M4I)
+	if _, y, _ := child.World(); !child.Moved() || !lin.Aeq(y, 5) {
+		t.Errorf("Expected child to follow enabled parent, got y %f", y)
+	}
+}
+
+// TestWorldTransforms checks the world space accessors against the
+// model matrix and that world setters back-solve the local transform.
+func TestWorldTransforms(t *testing.T) {
+	eng := newEngine(nil)
+	defer eng.Shutdown()
+	a := eng.Root().NewPov().SetLocation(3, 0, 0).SetScale(2, 2, 2)
+	a.Spin(0, 90, 0)
+	b := a.NewPov().SetLocation(0, 1, 1)
+	b.Spin(45, 0, 0)
+	eng.placeModels(eng.root(), lin.M4I)
+	mx, my, mz := b.World()
+	if x, y, z := b.WorldLocation(); !lin.Aeq(x, mx) || !lin.Aeq(y, my) || !lin.Aeq(z, mz) {
+		t.Errorf("Expected %f %f %f, got %f %f %f", mx, my, mz, x, y, z)
+	}
+	if sx, sy, sz := b.WorldScale(); sx != 2 || sy != 2 || sz != 2 {
+		t.Errorf("Expected world scale 2, got %f %f %f", sx, sy, sz)
+	}
+	if b.WorldMatrix() != b.(*pov).mm {
+		t.Errorf("Expected the model matrix")
+	}
+
+	// set a world transform and check it comes back.
+	q := lin.NewQ().SetAa(0, 0, 1, lin.Rad(30))
+	b.SetWorldLocation(-1, 2, 5).SetWorldRotation(q)
+	if x, y, z := b.WorldLocation(); !lin.Aeq(x, -1) || !lin.Aeq(y, 2) || !lin.Aeq(z, 5) {
+		t.Errorf("Expected world location -1 2 5, got %f %f %f", x, y, z)
+	}
+	if r := b.WorldRotation(); !lin.Aeq(r.X, q.X) || !lin.Aeq(r.Y, q.Y) || !lin.Aeq(r.Z, q.Z) || !lin.Aeq(r.W, q.W) {
+		t.Errorf("Expected world rotation %v, got %v", *q, *r)
+	}
+	eng.placeModels(eng.root(), lin.M4I)
+	if x, y, z := b.World(); !lin.Aeq(x, -1) || !lin.Aeq(y, 2) || !lin.Aeq(z, 5) {
+		t.Errorf("Expected model matrix location -1 2 5, got %f %f %f", x, y, z)
+	}
+}
+
+// TestVisit checks the depth first hierarchy walk.
+func TestVisit(t *testing.T) {
+	eng := newEngine(nil)
+	defer eng.Shutdown()
+	a := eng.Root().NewPov().SetName("a")
+	a.NewPov().SetName("b").NewPov().SetName("c")
+	a.NewPov().SetName("d").NewPov().SetName("e")
+	got := ""
+	a.Visit(func(p Pov, depth int) bool {
+		got += fmt.Sprintf("%s%d ", p.Name(), depth)
+		return p.Name() != "d" // skip the children of d.
+	})
+	if got != "a0 b1 c2 d1 " {
+		t.Errorf("Unexpected visit order %s", got)
+	}
+}
+
+// TestScenes checks switching and overlaying scenes.
+func TestScenes(t *testing.T) {
+	eng := newEngine(nil)
+	defer eng.Shutdown()
+	menu, game := eng.NewScene("menu"), eng.NewScene("game")
+	if eng.NewScene("menu") != menu || eng.Scene("game") != game || eng.Scene("none") != nil {
+		t.Errorf("Expected scenes by name")
+	}
+	eng.SwitchScene("game")
+	if menu.Enabled() || !game.Enabled() {
+		t.Errorf("Expected only the game scene")
+	}
+	eng.OverlayScene("menu", true)
+	if !menu.Enabled() || !game.Enabled() {
+		t.Errorf("Expected menu over the game")
+	}
+	eng.SwitchScene("none") // ignored.
+	if !menu.Enabled() || !game.Enabled() {
+		t.Errorf("Expected unknown scene to be ignored")
+	}
+	menu.Dispose(PovNode)
+	if eng.Scene("menu") != nil {
+		t.Errorf("Expected disposed scene to be removed")
+	}
+}
+
+// TestDisposeAfter checks timed and budgeted disposal.
+func TestDisposeAfter(t *testing.T) {
+	eng := newEngine(nil)
+	defer eng.Shutdown()
+	shot := eng.Root().NewPov()
+	shot.DisposeAfter(0.05)
+	kept := eng.Root().NewPov()
+	kept.DisposeAfter(0.01)
+	kept.DisposeAfter(-1) // cancel.
+	eng.updateLifetimes(0.02)
+	eng.updateLifetimes(0.02)
+	if !shot.Enabled() || len(eng.root().children) != 2 {
+		t.Errorf("Expected shot to be alive")
+	}
+	eng.updateLifetimes(0.02)
+	if _, ok := eng.povs[shot.(*pov).eid]; ok || len(eng.root().children) != 1 {
+		t.Errorf("Expected shot to be disposed")
+	}
+
+	// many expiring at once are disposed over several updates.
+	for cnt := 0; cnt < disposeBudget+10; cnt++ {
+		eng.Root().NewPov().DisposeAfter(0)
+	}
+	eng.updateLifetimes(0.02)
+	if len(eng.root().children) != 11 || len(eng.lives.expired) != 10 {
+		t.Errorf("Expected budgeted disposal, got %d", len(eng.root().children))
+	}
+	eng.updateLifetimes(0.02)
+	if len(eng.root().children) != 1 || len(eng.lives.expired) != 0 {
+		t.Errorf("Expected all expired to be disposed, got %d", len(eng.root().children))
+	}
+}
+
+// TestEach checks component queries and transform storage reuse.
+func TestEach(t *testing.T) {
+	eng := newEngine(nil)
+	defer eng.Shutdown()
+	lit := eng.Root().NewPov()
+	lit.NewLight()
+	cam := eng.Root().NewPov()
+	cam.NewCam()
+	both := eng.Root().NewPov()
+	both.NewLight()
+	both.NewCam()
+	got := []Pov{}
+	eng.Each(func(p Pov) { got = append(got, p) }, PovLight)
+	if len(got) != 2 || got[0] != lit || got[1] != both {
+		t.Errorf("Expected lights in creation order, got %d", len(got))
+	}
+	got = got[:0]
+	eng.Each(func(p Pov) { got = append(got, p) }, PovLight, PovCam)
+	if len(got) != 1 || got[0] != both {
+		t.Errorf("Expected one light and camera, got %d", len(got))
+	}
+
+	// disposed transforms are reset and reused.
+	x := lit.(*pov).x
+	lit.SetLocation(1, 2, 3).SetScale(2, 2, 2)
+	lit.Dispose(PovNode)
+	p := eng.Root().NewPov()
+	if p.(*pov).x != x {
+		t.Errorf("Expected transform to be reused")
+	}
+	if lx, ly, lz := p.Location(); lx != 0 || ly != 0 || lz != 0 {
+		t.Errorf("Expected reset location, got %f %f %f", lx, ly, lz)
+	}
+	if sx, sy, sz := p.Scale(); sx != 1 || sy != 1 || sz != 1 {
+		t.Errorf("Expected unit scale, got %f %f %f", sx, sy, sz)
+	}
+	lit.SetLocation(4, 5, 6) // stale reference.
+	if lx, _, _ := p.Location(); lx != 0 {
+		t.Errorf("Expected disposed pov to not affect reused transform")
+	}
+}
+
+// TestEachNested checks that queries can be nested.
+func TestEachNested(t *testing.T) {
+	eng := newEngine(nil)
+	defer eng.Shutdown()
+	for cnt := 0; cnt < 3; cnt++ {
+		eng.Root().NewPov().NewLight()
+	}
+	outer, inner := []uint64{}, 0
+	eng.Each(func(p Pov) {
+		outer = append(outer, p.(*pov).eid)
+		eng.Each(func(p Pov) { inner++ }, PovLight)
+	}, PovLight)
+	if fmt.Sprint(outer) != "[2 3 4]" || inner != 9 {
+		t.Errorf("Expected outer [2 3 4] and 9 inner visits, got %v %d", outer, inner)
+	}
+}
+
+// TestTables checks that components are kept in the order they were
+// added while components are removed.
+func TestTables(t *testing.T) {
+	ms := newTable()
+	a, b, c := &model{}, &model{}, &model{}
+	ms.set(5, a)
+	ms.set(2, b)
+	ms.set(9, c)
+	ms.set(5, c) // replace.
+	if fmt.Sprint(ms.eids) != "[5 2 9]" || ms.items[0] != c || ms.items[1] != b {
+		t.Errorf("Expected ids in add order, got %v", ms.eids)
+	}
+	ms.del(2)
+	ms.del(7) // ignored.
+	ms.compact()
+	if fmt.Sprint(ms.eids) != "[5 9]" || ms.size() != 2 || ms.dead != 0 {
+		t.Errorf("Expected ids [5 9] after compacting, got %v", ms.eids)
+	}
+	if m, ok := ms.model(9); !ok || m != c || ms.has(2) {
+		t.Errorf("Expected model 9 to be found and model 2 to be removed")
+	}
+
+	// few removals are left as tombstones.
+	for eid := uint64(10); eid < 20; eid++ {
+		ms.set(eid, a)
+	}
+	ms.del(5)
+	ms.compact()
+	if len(ms.eids) != 12 || ms.eids[0] != 0 || ms.size() != 11 {
+		t.Errorf("Expected a tombstone, got %v", ms.eids)
+	}
+	if _, ok := ms.body(9); ok {
+		t.Errorf("Expected no body for a model")
+	}
+}
+
+// TestEachTables checks that component queries visit in creation order.
+func TestEachTables(t *testing.T) {
+	eng := newEngine(nil)
+	defer eng.Shutdown()
+	a, b, c := eng.Root().NewPov(), eng.Root().NewPov(), eng.Root().NewPov()
+	c.NewModel("c")
+	a.NewModel("a")
+	b.NewModel("b")
+	a.NewBody(NewSphere(1))
+	c.NewBody(NewSphere(1))
+	got := []Pov{}
+	eng.Each(func(p Pov) { got = append(got, p) }, PovModel, PovBody)
+	if len(got) != 2 || got[0] != a || got[1] != c {
+		t.Errorf("Expected models with bodies in creation order, got %d", len(got))
+	}
+	got = got[:0]
+	eng.Each(func(p Pov) {
+		got = append(got, p)
+		p.Dispose(PovNode) // visit can dispose.
+	}, PovModel)
+	if len(got) != 3 || got[0] != a || got[2] != c || eng.models.size() != 0 {
+		t.Errorf("Expected 3 disposed models, got %d", len(got))
+	}
+}
+
+// TestJoint checks that Pov's attached to bones follow the bones.
+func TestJoint(t *testing.T) {
+	eng := newEngine(nil)
+	defer eng.Shutdown()
+	body := eng.Root().NewPov().SetLocation(0, 0, -5)
+	m := body.NewModel("anim").(*model)
+	m.anm = newAnimation("body")
+	m.anm.setJoints([]string{"hip", "hand"}, []*lin.M4{lin.M4I, {Xx: 1, Yy: 1, Zz: 1, Ww: 1, Wx: 1}})
+	m.pose = make([]lin.M4, 2)
+	hat := body.NewPov().SetLocation(0, 0, 1).SetJoint("hand")
+	eng.placeModels(eng.root(), lin.M4I)
+	if x, y, z := hat.World(); !lin.Aeq(x, 1) || !lin.Aeq(y, 0) || !lin.Aeq(z, -4) {
+		t.Errorf("Expected base pose 1 0 -4 got %f %f %f", x, y, z)
+	}
+	m.pose[1] = lin.M4{Xx: 1, Yy: 1, Zz: 1, Ww: 1, Wy: 2} // animated bone.
+	eng.placeModels(eng.root(), lin.M4I)
+	if x, y, z := hat.World(); !lin.Aeq(x, 1) || !lin.Aeq(y, 2) || !lin.Aeq(z, -4) {
+		t.Errorf("Expected animated 1 2 -4 got %f %f %f", x, y, z)
+	}
+	hat.SetJoint("")
+	eng.placeModels(eng.root(), lin.M4I)
+	if x, y, z := hat.World(); !lin.Aeq(x, 0) || !lin.Aeq(y, 0) || !lin.Aeq(z, -4) {
+		t.Errorf("Expected detached 0 0 -4 got %f %f %f", x, y, z)
+	}
+}
@@ -0,0 +1,271 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"sort"
+
+	"github.com/gazed/vu/math/lin"
+	"github.com/gazed/vu/physics"
+)
+
+// Entity storage keeps the per-update Pov transform data in contiguous
+// blocks rather than in separately allocated structures. The transform
+// walk done each update then reads neighbouring memory for Pov's that
+// were created together, ie: the parts of a model or a batch of
+// projectiles. Disposed transforms are reused by new Pov's.
+//
+// The model, body, and noise components that are updated each frame
+// are kept in tables in the order they were added. The updates visit
+// the components by walking the dense table slices instead of ranging
+// over maps and sorting ids each update. The components themselves are
+// still separately allocated since the application holds references
+// to them.
+//
+// The components of an entity are found using the entity id.
+// Eng.Each queries the entities that have a given set of components.
+
+// xformBlock is the number of transforms allocated at once.
+const xformBlock = 256
+
+// xform is the transform data for one Pov.
+type xform struct {
+	at    lin.T       // Local location and rotation referencing loc, rot.
+	loc   lin.V3      // Local location.
+	rot   lin.Q       // Local rotation.
+	scale lin.V3      // Per axis scale.
+	last  [10]float64 // Local transform when last placed.
+	mm    lin.M4      // Model transform.
+	q0    lin.Q       // Scratch rotation.
+}
+
+// reset initializes a transform to the origin with no rotation
+// and unit scale.
+func (x *xform) reset() *xform {
+	*x = xform{}
+	x.at.Loc, x.at.Rot = &x.loc, &x.rot
+	x.rot.W = 1
+	x.scale = lin.V3{X: 1, Y: 1, Z: 1}
+	return x
+}
+
+// xforms allocates transforms from blocks.
+type xforms struct {
+	blocks [][]xform // Allocated transform blocks.
+	used   int       // Transforms used from the last block.
+	free   []*xform  // Disposed transforms.
+}
+
+// get returns an initialized transform, reusing disposed transforms.
+func (xs *xforms) get() *xform {
+	if n := len(xs.free); n > 0 {
+		x := xs.free[n-1]
+		xs.free = xs.free[:n-1]
+		return x.reset()
+	}
+	if len(xs.blocks) == 0 || xs.used == xformBlock {
+		xs.blocks = append(xs.blocks, make([]xform, xformBlock))
+		xs.used = 0
+	}
+	x := &xs.blocks[len(xs.blocks)-1][xs.used]
+	xs.used++
+	return x.reset()
+}
+
+// put returns a disposed transform for reuse.
+func (xs *xforms) put(x *xform) {
+	if x != nil {
+		xs.free = append(xs.free, x)
+	}
+}
+
+// Implement Eng interface. The ids are collected before visiting so
+// that visit can use Each or dispose entities. Each level of nested
+// Each calls uses its own scratch ids.
+func (eng *engine) Each(visit func(p Pov), kinds ...int) {
+	if eng.depth == len(eng.each) {
+		eng.each = append(eng.each, []uint64{})
+	}
+	ids := eng.collect(eng.each[eng.depth][:0], kinds)
+	eng.depth++
+	for _, eid := range ids {
+		if p, ok := eng.povs[eid]; ok { // visit may dispose entities.
+			visit(p)
+		}
+	}
+	eng.depth--
+	eng.each[eng.depth] = ids[:0] // keep any growth.
+}
+
+// collect appends, in creation order, the ids of the entities that
+// have all of the given components. Only the smallest of the given
+// component tables is checked. All entities are checked if the given
+// components are not kept in tables.
+func (eng *engine) collect(ids []uint64, kinds []int) []uint64 {
+	var smallest []*table
+	count := 0
+	for _, kind := range kinds {
+		var tables []*table
+		switch kind {
+		case PovModel:
+			tables = []*table{eng.models}
+		case PovBody:
+			tables = []*table{eng.bodies, eng.solids}
+		case PovNoise:
+			tables = []*table{eng.noises}
+		default:
+			continue
+		}
+		size := 0
+		for _, t := range tables {
+			size += t.size()
+		}
+		if smallest == nil || size < count {
+			smallest, count = tables, size
+		}
+	}
+	if smallest == nil {
+		for eid := range eng.povs {
+			if eng.hasAll(eid, kinds) {
+				ids = append(ids, eid)
+			}
+		}
+	} else {
+		for _, t := range smallest {
+			for _, eid := range t.eids {
+				if eid != 0 && eng.hasAll(eid, kinds) {
+					ids = append(ids, eid)
+				}
+			}
+		}
+	}
+	sort.Sort(eids(ids)) // tables are mostly in creation order.
+	return ids
+}
+
+// hasAll returns true if the entity has all of the given components.
+func (eng *engine) hasAll(eid uint64, kinds []int) bool {
+	for _, kind := range kinds {
+		has := false
+		switch kind {
+		case PovNode:
+			_, has = eng.povs[eid]
+		case PovModel:
+			has = eng.models.has(eid)
+		case PovBody:
+			has = eng.bodies.has(eid) || eng.solids.has(eid)
+		case PovCam:
+			_, has = eng.cams[eid]
+		case PovNoise:
+			has = eng.noises.has(eid)
+		case PovLight:
+			_, has = eng.lights[eid]
+		case PovLayer:
+			_, has = eng.layers[eid]
+		case PovComps:
+			p, ok := eng.povs[eid]
+			has = ok && len(p.comps) > 0
+		}
+		if !has {
+			return false
+		}
+	}
+	return true
+}
+
+// eids sorts entity ids into creation order.
+type eids []uint64
+
+func (e eids) Len() int           { return len(e) }
+func (e eids) Less(i, j int) bool { return e[i] < e[j] }
+func (e eids) Swap(i, j int)      { e[i], e[j] = e[j], e[i] }
+
+// table keeps one kind of component in the order the components were
+// added. The index finds the component of an entity. Removing a
+// component leaves a tombstone, a zero entity id, so that the order of
+// the remaining components is kept and the table can be walked while
+// components are removed. The tombstones are dropped by compact, which
+// is called between updates.
+type table struct {
+	eids  []uint64       // Entity id for each item, 0 if removed.
+	items []interface{}  // Component for each entity id.
+	index map[uint64]int // Item index by entity id.
+	dead  int            // Number of tombstones.
+}
+
+// newTable creates an empty component table.
+func newTable() *table { return &table{index: map[uint64]int{}} }
+
+// get returns the component of the entity, or nil if there is none.
+func (t *table) get(eid uint64) interface{} {
+	if at, ok := t.index[eid]; ok {
+		return t.items[at]
+	}
+	return nil
+}
+
+// has returns true if the entity has a component in the table.
+func (t *table) has(eid uint64) bool {
+	_, ok := t.index[eid]
+	return ok
+}
+
+// size returns the number of components in the table.
+func (t *table) size() int { return len(t.index) }
+
+// set adds the component of the entity to the end of the table,
+// or replaces the existing component of the entity.
+func (t *table) set(eid uint64, item interface{}) {
+	if at, ok := t.index[eid]; ok {
+		t.items[at] = item
+		return
+	}
+	t.index[eid] = len(t.eids)
+	t.eids = append(t.eids, eid)
+	t.items = append(t.items, item)
+}
+
+// del removes the component of the entity, leaving a tombstone.
+func (t *table) del(eid uint64) {
+	if at, ok := t.index[eid]; ok {
+		delete(t.index, eid)
+		t.eids[at], t.items[at] = 0, nil // release the reference.
+		t.dead++
+	}
+}
+
+// compact drops the tombstones, keeping the order of the remaining
+// components. Nothing is done until at least a quarter of the table
+// is tombstones so that tables with few removals aren't copied often.
+func (t *table) compact() {
+	if t.dead == 0 || t.dead*4 < len(t.eids) {
+		return
+	}
+	live := 0
+	for at, eid := range t.eids {
+		if eid != 0 {
+			t.eids[live], t.items[live] = eid, t.items[at]
+			t.index[eid] = live
+			live++
+		}
+	}
+	for at := live; at < len(t.items); at++ {
+		t.items[at] = nil // release the references.
+	}
+	t.eids, t.items, t.dead = t.eids[:live], t.items[:live], 0
+}
+
+// Typed component accessors.
+func (t *table) model(eid uint64) (m *model, ok bool) {
+	m, ok = t.get(eid).(*model)
+	return m, ok
+}
+func (t *table) body(eid uint64) (b physics.Body, ok bool) {
+	b, ok = t.get(eid).(physics.Body)
+	return b, ok
+}
+func (t *table) noise(eid uint64) (n *noise, ok bool) {
+	n, ok = t.get(eid).(*noise)
+	return n, ok
+}
@@ -0,0 +1,120 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+// Events let engine subsystems and application code communicate without
+// holding references to each other. Events are published to a topic and
+// queued. Queued events are delivered to the topic subscribers once per
+// update, before App.Update, in the order they were published, ie:
+//     id := eng.Subscribe(vu.KeyEvent, func(topic string, data interface{}) {
+//         key := data.(int)
+//     })
+//     eng.Publish("game.score", 100) // application topic.
+// Events published while events are being delivered are delivered on
+// the next update.
+
+// Event topics used by the engine. The comments give the type
+// of the published data.
+const (
+	AudioEvent   = "vu.audio"   // string name of a new sound output device.
+	ContactEvent = "vu.contact" // []physics.Contact from the physics update.
+	KeyEvent     = "vu.key"     // int key, or mouse button, pressed this update.
+	LoadedEvent  = "vu.loaded"  // string name of a loaded asset.
+	ShakeEvent   = "vu.shake"   // Jolt published for camera shake.
+	SoundEvent   = "vu.sound"   // Playback that finished or was stopped.
+)
+
+// EventHandler is called with the topic and data of a published event.
+type EventHandler func(topic string, data interface{})
+
+// bus queues events and delivers them to subscribers.
+type bus struct {
+	queue []event                 // Events for the next delivery.
+	sent  []event                 // Events being delivered.
+	subs  map[string][]subscriber // Subscribers by topic.
+	topic map[int]string          // Topics by subscriber id.
+	nid   int                     // Last subscriber id.
+}
+
+// event is a published topic and its data.
+type event struct {
+	topic string
+	data  interface{}
+}
+
+// subscriber is a registered event handler.
+type subscriber struct {
+	id int
+	h  EventHandler
+}
+
+// newBus creates an event bus without any subscribers.
+func newBus() *bus {
+	return &bus{subs: map[string][]subscriber{}, topic: map[int]string{}}
+}
+
+// publish queues an event. Events for topics without any
+// subscribers are dropped.
+func (b *bus) publish(topic string, data interface{}) {
+	if b.subscribed(topic) {
+		b.queue = append(b.queue, event{topic: topic, data: data})
+	}
+}
+
+// subscribed returns true if the topic has subscribers.
+func (b *bus) subscribed(topic string) bool { return len(b.subs[topic]) > 0 }
+
+// subscribe adds the handler to the topic, returning an id
+// for unsubscribe.
+func (b *bus) subscribe(topic string, h EventHandler) int {
+	if h == nil {
+		return 0
+	}
+	b.nid++
+	b.subs[topic] = append(b.subs[topic], subscriber{id: b.nid, h: h})
+	b.topic[b.nid] = topic
+	return b.nid
+}
+
+// unsubscribe removes the handler with the given id.
+func (b *bus) unsubscribe(id int) {
+	topic, ok := b.topic[id]
+	if !ok {
+		return
+	}
+	delete(b.topic, id)
+	subs := b.subs[topic]
+	for index, s := range subs {
+		if s.id == id {
+			// copy so that any in progress delivery is not affected.
+			b.subs[topic] = append(append([]subscriber{}, subs[:index]...), subs[index+1:]...)
+			break
+		}
+	}
+	if len(b.subs[topic]) == 0 {
+		delete(b.subs, topic)
+	}
+}
+
+// dispatch delivers the queued events.
+func (b *bus) dispatch() {
+	b.sent, b.queue = b.queue, b.sent[:0]
+	for _, e := range b.sent {
+		for _, s := range b.subs[e.topic] {
+			if _, ok := b.topic[s.id]; ok { // may have unsubscribed.
+				s.h(e.topic, e.data)
+			}
+		}
+	}
+	for cnt := range b.sent {
+		b.sent[cnt].data = nil // release for garbage collection.
+	}
+}
+
+// Implement Eng interface.
+func (eng *engine) Publish(topic string, data interface{}) { eng.events.publish(topic, data) }
+func (eng *engine) Subscribe(topic string, h EventHandler) int {
+	return eng.events.subscribe(topic, h)
+}
+func (eng *engine) Unsubscribe(id int) { eng.events.unsubscribe(id) }
@@ -0,0 +1,57 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// TestEvents checks event queueing and delivery order.
+func TestEvents(t *testing.T) {
+	eng := newEngine(nil)
+	defer eng.Shutdown()
+	got := []string{}
+	var id1 int
+	id0 := eng.Subscribe("a", func(topic string, data interface{}) {
+		got = append(got, fmt.Sprintf("0%s%v", topic, data))
+		if data == 2 {
+			eng.Publish("a", 3)  // delivered next dispatch.
+			eng.Unsubscribe(id1) // not delivered to id1.
+		}
+	})
+	id1 = eng.Subscribe("a", func(topic string, data interface{}) {
+		got = append(got, fmt.Sprintf("1%s%v", topic, data))
+	})
+	eng.Subscribe("b", func(topic string, data interface{}) {
+		got = append(got, fmt.Sprintf("2%s%v", topic, data))
+	})
+	eng.Publish("a", 1)
+	eng.Publish("b", 1)
+	eng.Publish("a", 2)
+	eng.Publish("c", 1) // no subscribers: dropped.
+	if len(got) != 0 {
+		t.Fatalf("Expected events to be queued")
+	}
+	eng.events.dispatch()
+	if s := strings.Join(got, ","); s != "0a1,1a1,2b1,0a2" {
+		t.Errorf("Unexpected delivery %s", s)
+	}
+	got = got[:0]
+	eng.events.dispatch()
+	if s := strings.Join(got, ","); s != "0a3" {
+		t.Errorf("Unexpected delivery %s", s)
+	}
+	got = got[:0]
+	eng.Unsubscribe(id0)
+	eng.Publish("a", 4) // no subscribers left: dropped.
+	eng.events.dispatch()
+	if s := strings.Join(got, ","); s != "" {
+		t.Errorf("Unexpected delivery %s", s)
+	}
+	if len(eng.events.queue) != 0 || eng.events.subscribed("a") {
+		t.Errorf("Expected empty queue and no subscribers")
+	}
+}
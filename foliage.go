@@ -0,0 +1,236 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/gazed/vu/render"
+)
+
+// Foliage scatters grass, bushes, and trees across a Surface. Each plant
+// is an upright billboard, a camera facing quad that only turns about the
+// surface up axis. The plants are placed using rules for each type of
+// plant and an optional density map. Plants are batched into one mesh for
+// each square chunk of surface quads so that a chunk of plants is drawn
+// at once and whole chunks are culled once they are beyond the fade
+// distance, ie:
+//     f := vu.NewFoliage(pov, "foliage", surface, 32)
+//     f.AddRule(vu.FoliageRule{Density: 0.5, MaxSlope: 0.3, Width: 1, Height: 2})
+//     f.Scatter(seed)
+//     f.Update(cam) // each update: cull distant chunks.
+// The Foliage Pov is expected to have the same transform as the surface
+// model Pov. Chunk meshes have the plant base location at layout location
+// 0, the billboard corner offset and texture uv at 1, and the texture atlas
+// index at 2. See eg/source/foliage.vsh for the shader uniforms.
+type Foliage interface {
+	AddRule(rule FoliageRule) Foliage // Add a type of plant.
+
+	// SetDensity scales the rule densities at each surface point.
+	// Values are expected between 0 and 1. The default, nil, is 1
+	// everywhere.
+	SetDensity(density [][]float64) Foliage
+
+	// SetFade sets the camera distances, in surface units, where plants
+	// start to fade away and are completely gone. Defaults are 40 and 50.
+	// Chunks beyond the end distance are not drawn.
+	SetFade(start, end float64) Foliage
+
+	// SetModel registers a callback that configures each new chunk
+	// model, ie: adding the plant texture atlas.
+	SetModel(setup func(m Model)) Foliage
+
+	// Scatter places plants using the rules and regenerates the chunk
+	// meshes. Plant locations come from the seed so that the same seed,
+	// rules, and surface always give the same plants.
+	Scatter(seed int64)
+	Update(cam Camera) // Show the chunks that are within range of the camera.
+	Plants() int       // Number of plants placed by the last Scatter.
+}
+
+// FoliageRule decides where one type of plant grows and its size.
+type FoliageRule struct {
+	Density   float64 // Average plants per surface quad.
+	MinHeight float64 // Lowest scaled surface height, ie: above the water.
+	MaxHeight float64 // Highest scaled surface height. 0 for no limit.
+	MaxSlope  float64 // Steepest surface, from 0 flat to 1 vertical. 0 for no limit.
+	Width     float32 // Billboard width.
+	Height    float32 // Billboard height.
+	Vary      float32 // 0 to 1. Random size variation.
+	Tindex    int     // Texture atlas index.
+}
+
+// NewFoliage creates plants for the given surface that are drawn as
+// child Pov's of p using the given shader. Plants are grouped into
+// chunks of chunk-by-chunk surface quads.
+func NewFoliage(p Pov, shader string, s Surface, chunk int) Foliage {
+	return newFoliage(p, shader, s, chunk)
+}
+
+// Foliage
+// =============================================================================
+// foliage implements Foliage.
+
+// foliage keeps a model for each chunk that has plants.
+type foliage struct {
+	pov     *pov          // Surface location, orientation, scale.
+	shader  string        // Chunk model shader.
+	s       *surface      // Surface heights and normals.
+	size    int           // Surface quads along each side of a chunk.
+	rules   []FoliageRule // Types of plant.
+	density [][]float64   // Optional density map.
+	fade    [2]float64    // Fade start and end distances.
+	setup   func(m Model) // Optional chunk model configuration.
+	chunks  []*pov        // Chunk models. Nil for chunks without plants.
+	plants  int           // Plants placed by the last Scatter.
+
+	// scratch chunk data. Reused for each chunk.
+	vb  []float32 // Scratch plant base locations.
+	cb  []float32 // Scratch corner offsets and uvs.
+	tb  []float32 // Scratch texture atlas indicies.
+	fb  []uint32  // Scratch face buffer.
+	f16 []uint16  // Scratch face buffer for smaller chunks.
+}
+
+// newFoliage allocates and initializes foliage.
+func newFoliage(p Pov, shader string, s Surface, chunk int) *foliage {
+	f := &foliage{shader: shader, size: chunk, fade: [2]float64{40, 50}}
+	f.pov, _ = p.(*pov)
+	f.s, _ = s.(*surface)
+	if f.size < 1 {
+		f.size = 32
+	}
+	return f
+}
+
+// Implement Foliage.
+func (f *foliage) AddRule(rule FoliageRule) Foliage {
+	f.rules = append(f.rules, rule)
+	return f
+}
+func (f *foliage) SetDensity(density [][]float64) Foliage {
+	f.density = density
+	return f
+}
+func (f *foliage) SetFade(start, end float64) Foliage {
+	if start >= 0 && end > start {
+		f.fade = [2]float64{start, end}
+	}
+	return f
+}
+func (f *foliage) SetModel(setup func(m Model)) Foliage {
+	f.setup = setup
+	return f
+}
+func (f *foliage) Plants() int { return f.plants }
+
+// Scatter implements Foliage. Each rule has a chance of placing
+// a plant at a random location within each surface quad.
+func (f *foliage) Scatter(seed int64) {
+	if f.pov == nil || f.s == nil {
+		return
+	}
+	for _, p := range f.chunks {
+		if p != nil {
+			p.Dispose(PovNode)
+		}
+	}
+	f.chunks, f.plants = f.chunks[:0], 0
+	random := rand.New(rand.NewSource(seed))
+	qx, qy := len(f.s.pts)-1, len(f.s.pts[0])-1 // surface quads.
+	for cx := 0; cx < qx; cx += f.size {
+		for cy := 0; cy < qy; cy += f.size {
+			f.vb, f.cb, f.tb, f.fb = f.vb[:0], f.cb[:0], f.tb[:0], f.fb[:0]
+			for x := cx; x < cx+f.size && x < qx; x++ {
+				for y := cy; y < cy+f.size && y < qy; y++ {
+					for _, rule := range f.rules {
+						f.grow(rule, x, y, random)
+					}
+				}
+			}
+			f.chunks = append(f.chunks, f.chunk())
+		}
+	}
+}
+
+// grow adds the plants for one rule to surface quad x, y.
+func (f *foliage) grow(rule FoliageRule, x, y int, random *rand.Rand) {
+	chance := rule.Density
+	if f.density != nil && x < len(f.density) && y < len(f.density[x]) {
+		chance *= f.density[x][y]
+	}
+	for ; chance > 0; chance-- {
+		if chance < 1 && random.Float64() >= chance {
+			return
+		}
+		px, py := float64(x)+random.Float64(), float64(y)+random.Float64()
+		scale := 1 + rule.Vary*(random.Float32()*2-1)
+		h := f.s.HeightAt(px, py)
+		_, _, nz := f.s.NormalAt(px, py)
+		if h < rule.MinHeight || (rule.MaxHeight != 0 && h > rule.MaxHeight) {
+			continue
+		}
+		if rule.MaxSlope != 0 && 1-nz > rule.MaxSlope {
+			continue
+		}
+		f.plant(float32(px), float32(py), float32(h), rule.Width*scale, rule.Height*scale, rule.Tindex)
+	}
+}
+
+// plant appends the billboard quad for one plant.
+func (f *foliage) plant(x, y, z, width, height float32, tindex int) {
+	vc := uint32(len(f.vb) / 3)
+	half := width * 0.5
+	for _, corner := range [4][4]float32{{-half, 0, 0, 0}, {half, 0, 1, 0}, {-half, height, 0, 1}, {half, height, 1, 1}} {
+		f.vb = append(f.vb, x, y, z)
+		f.cb = append(f.cb, corner[0], corner[1], corner[2], corner[3])
+		f.tb = append(f.tb, float32(tindex))
+	}
+	f.fb = append(f.fb, vc, vc+1, vc+2, vc+1, vc+3, vc+2)
+	f.plants++
+}
+
+// chunk creates the model for the plants in the scratch buffers.
+// Returns nil if the chunk has no plants.
+func (f *foliage) chunk() *pov {
+	if len(f.fb) == 0 {
+		return nil
+	}
+	p := f.pov.NewPov().(*pov)
+	m := p.NewModel(f.shader)
+	if f.setup != nil {
+		f.setup(m)
+	}
+	m.NewMesh("foliage")
+	m.InitMesh(0, 3, render.StaticDraw, false).SetMeshData(0, f.vb)
+	m.InitMesh(1, 4, render.StaticDraw, false).SetMeshData(1, f.cb)
+	m.InitMesh(2, 1, render.StaticDraw, false).SetMeshData(2, f.tb)
+	f.f16 = bindFaces(m, len(f.vb)/3, f.fb, f.f16)
+	m.SetUniform("fade", f.fade[0], f.fade[1])
+	return p
+}
+
+// Update implements Foliage. Chunks are hidden when the closest
+// point of the chunk is beyond the fade distance.
+func (f *foliage) Update(cam Camera) {
+	if f.pov == nil || f.s == nil || cam == nil {
+		return
+	}
+	lx, ly, lz := localCam(f.pov, cam)
+	qy := len(f.s.pts[0]) - 1
+	rows := (qy + f.size - 1) / f.size // chunks along y.
+	for cnt, p := range f.chunks {
+		if p == nil {
+			continue
+		}
+		x0, y0 := float64(cnt/rows*f.size), float64(cnt%rows*f.size)
+		dx := math.Max(0, math.Max(x0-lx, lx-(x0+float64(f.size))))
+		dy := math.Max(0, math.Max(y0-ly, ly-(y0+float64(f.size))))
+		p.SetVisible(math.Hypot(dx, dy) <= f.fade[1])
+		m := p.Model()
+		m.SetUniform("fade", f.fade[0], f.fade[1])
+		m.SetUniform("eye", lx, ly, lz)
+	}
+}
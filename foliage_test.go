@@ -0,0 +1,35 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"testing"
+)
+
+// Check that plants follow the rules and that
+// distant chunks are culled.
+func TestFoliage(t *testing.T) {
+	eng := newEngine(nil)
+	defer eng.Shutdown()
+	s := newSurface(33, 33, 1, 1, 1)
+	for y := 0; y < 33; y++ {
+		s.pts[30][y].Height = 4 // steep cliff from x=29 to x=31.
+	}
+	f := newFoliage(eng.Root().NewPov(), "foliage", s, 16)
+	f.AddRule(FoliageRule{Density: 1, MaxSlope: 0.5, Width: 1, Height: 2})
+	f.Scatter(123)
+	if f.Plants() != 30*32 || len(f.chunks) != 4 {
+		t.Errorf("Expected 960 plants off the cliff in 4 chunks, got %d %d", f.Plants(), len(f.chunks))
+	}
+	f.SetDensity([][]float64{{0.5}}).Scatter(123)
+	if f.Plants() != 30*32-1 && f.Plants() != 30*32 {
+		t.Errorf("Expected density to only affect the first quad, got %d", f.Plants())
+	}
+	cam := eng.Root().NewPov().NewCam()
+	cam.SetLocation(8, 8, 10)
+	f.SetFade(5, 10).Update(cam)
+	if !f.chunks[0].Visible() || !f.chunks[1].Visible() || f.chunks[3].Visible() {
+		t.Errorf("Expected near chunks visible and far chunks culled")
+	}
+}
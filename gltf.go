@@ -0,0 +1,188 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+
+	"github.com/gazed/vu/load"
+	"github.com/gazed/vu/math/lin"
+	"github.com/gazed/vu/render"
+)
+
+// glTF files are exported by most modelling tools. LoadGltf adds the
+// glTF node hierarchy as a Pov hierarchy, ie:
+//     robot, err := eng.LoadGltf("robot", level) // models/robot.gltf
+// Each node keeps its name and transform. Each mesh part is a model
+// drawn with the "pbr" shader, or with the "pbra" shader for skinned
+// meshes with animations. Nodes with more than one mesh part have a
+// child Pov for each part. Skinned models play the skin animations
+// as their Actions, and skin joints are not added as Pov's.
+//
+// Meshes have tangents at lloc=6 and a second set of tex coords at
+// lloc=7. Material textures are models textures, see pbrShader, where
+// missing textures are replaced by single pixel textures. Material
+// values are set as model color, alpha, and the uniforms listed in
+// pbrFragment. Imported meshes and textures are not saved in scenes.
+
+// gltfTexs replace the missing textures of a material.
+var gltfTexs = []struct {
+	name string
+	img  image.Image
+}{
+	{"gltfColor", gltfPixel(255, 255, 255)},
+	{"gltfMetalRough", gltfPixel(255, 255, 255)},
+	{"gltfNormal", gltfPixel(128, 128, 255)}, // unchanged normal.
+	{"gltfOcclusion", gltfPixel(255, 255, 255)},
+	{"gltfEmissive", gltfPixel(255, 255, 255)},
+}
+
+// gltfPixel returns a single pixel image of the given color.
+func gltfPixel(r, g, b uint8) image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+	img.Set(0, 0, color.NRGBA{r, g, b, 255})
+	return img
+}
+
+// Implement Eng interface. Files are read once and reused
+// for later loads of the same name.
+func (eng *engine) LoadGltf(name string, parent Pov) (Pov, error) {
+	gd, ok := eng.gltfs[name]
+	if !ok {
+		var err error
+		if gd, err = eng.loader.ld.Gltf(name); err != nil {
+			return nil, fmt.Errorf("LoadGltf: %s", err)
+		}
+		eng.gltfs[name] = gd
+	}
+	return eng.newGltf(gd, parent)
+}
+
+// newGltf creates a child of parent from the given glTF data.
+func (eng *engine) newGltf(gd *load.GltfData, parent Pov) (Pov, error) {
+	p := eng.newPov(parent)
+	if p == nil {
+		return nil, fmt.Errorf("LoadGltf: invalid parent pov")
+	}
+	anims := map[int]*animation{} // shared by models with the same skin.
+	for _, root := range gd.Roots {
+		eng.gltfNode(gd, p.(*pov), root, anims)
+	}
+	return p, nil
+}
+
+// gltfNode adds a child of parent for the given node and its children.
+func (eng *engine) gltfNode(gd *load.GltfData, parent *pov, index int, anims map[int]*animation) {
+	n := &gd.Nodes[index]
+	if n.Joint {
+		return // animated by skinned models.
+	}
+	p := eng.newPov(parent).(*pov)
+	p.SetLocation(n.Loc[0], n.Loc[1], n.Loc[2])
+	p.at.Rot.SetS(n.Rot[0], n.Rot[1], n.Rot[2], n.Rot[3])
+	p.SetScale(n.Scale[0], n.Scale[1], n.Scale[2])
+	if n.Name != "" {
+		p.SetName(n.Name)
+	}
+	if n.Mesh >= 0 {
+		parts := len(gd.Meshes[n.Mesh].Parts)
+		for cnt := 0; cnt < parts; cnt++ {
+			if parts == 1 {
+				eng.gltfModel(gd, p, n, cnt, anims)
+			} else {
+				eng.gltfModel(gd, eng.newPov(p).(*pov), n, cnt, anims)
+			}
+		}
+	}
+	for _, child := range n.Children {
+		eng.gltfNode(gd, p, child, anims)
+	}
+}
+
+// gltfModel adds a model for one part of the node mesh.
+func (eng *engine) gltfModel(gd *load.GltfData, p *pov, n *load.GltfNode, index int, anims map[int]*animation) {
+	mesh := &gd.Meshes[n.Mesh]
+	part := &mesh.Parts[index]
+	var anm *animation
+	if n.Skin >= 0 && len(part.B) > 0 && len(part.W) > 0 {
+		anm = gltfAnim(gd, n.Skin, anims)
+	}
+	shader := "pbr"
+	if anm != nil {
+		shader = "pbra"
+	}
+	m := p.NewModel(shader).(*model)
+	m.NewMesh(fmt.Sprintf("%s:%s%d", gd.Name, mesh.Name, index))
+	m.msh.initData(0, 3, render.StaticDraw, false).setData(0, part.V)
+	m.msh.initFaces(render.StaticDraw).setFaces(part.F)
+	if len(part.N) > 0 {
+		m.msh.initData(1, 3, render.StaticDraw, false).setData(1, part.N)
+	}
+	if len(part.T) > 0 {
+		m.msh.initData(2, 2, render.StaticDraw, false).setData(2, part.T[0])
+	}
+	if len(part.X) > 0 {
+		m.msh.initData(6, 4, render.StaticDraw, false).setData(6, part.X)
+	}
+	if len(part.T) > 1 {
+		m.msh.initData(7, 2, render.StaticDraw, false).setData(7, part.T[1])
+	}
+	if anm != nil {
+		m.msh.initData(4, 4, render.StaticDraw, false).setData(4, part.B)
+		m.msh.initData(5, 4, render.StaticDraw, true).setData(5, part.W)
+		m.anm = anm
+		m.nFrames = anm.maxFrames(0)
+		m.pose = make([]lin.M4, len(anm.joints))
+	}
+
+	// Material factors are model values and textures are model textures.
+	mat := &gd.Materials[part.Material]
+	m.SetColor(float64(mat.Color[0]), float64(mat.Color[1]), float64(mat.Color[2]))
+	cutoff := float32(0)
+	switch mat.AlphaMode {
+	case "BLEND":
+		m.SetAlpha(float64(mat.Color[3]))
+	case "MASK":
+		m.SetAlpha(float64(mat.Color[3]))
+		cutoff = mat.AlphaCutoff
+	}
+	m.SetUniform("mr", mat.Metallic, mat.Roughness)
+	m.SetUniform("ao", mat.Occlusion, mat.OcclusionTex.UV)
+	m.SetUniform("ke", mat.Emissive[0], mat.Emissive[1], mat.Emissive[2])
+	m.SetUniform("cutoff", cutoff)
+	refs := []load.GltfTexRef{mat.ColorTex, mat.MetalRoughTex, mat.NormalTex, mat.OcclusionTex, mat.EmissiveTex}
+	for cnt, ref := range refs {
+		if ref.Texture >= 0 && gd.Textures[ref.Texture].Img != nil {
+			t := &gd.Textures[ref.Texture]
+			m.NewTex(t.Name).SetImg(cnt, t.Img)
+			if t.Repeat {
+				m.SetTexMode(cnt, TexRepeat)
+			}
+			continue
+		}
+		m.NewTex(gltfTexs[cnt].name).SetImg(cnt, gltfTexs[cnt].img)
+	}
+}
+
+// gltfAnim returns the animation for the given skin, or nil if
+// the skin has no animations.
+func gltfAnim(gd *load.GltfData, skin int, anims map[int]*animation) *animation {
+	if a, ok := anims[skin]; ok {
+		return a
+	}
+	var a *animation
+	if gs := &gd.Skins[skin]; len(gs.Frames) > 0 {
+		a = newAnimation(fmt.Sprintf("%s:%s%d", gd.Name, gs.Name, skin))
+		moves := []movement{}
+		for _, ga := range gs.Anims {
+			moves = append(moves, movement{name: ga.Name, f0: int(ga.F0), fn: int(ga.Fn), rate: float64(ga.Rate)})
+		}
+		a.setData(gs.Frames, gs.Joints, moves)
+		a.setJoints(gs.Names, gs.Bases)
+	}
+	anims[skin] = a
+	return a
+}
@@ -0,0 +1,107 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"image"
+	"math"
+	"testing"
+
+	"github.com/gazed/vu/load"
+	"github.com/gazed/vu/math/lin"
+)
+
+// Check that imported nodes become Pov's with pbr models, where
+// skinned models are animated and joints are skipped.
+func TestGltf(t *testing.T) {
+	eng := newEngine(nil)
+	defer eng.Shutdown()
+	gd := testGltfData()
+	top, err := eng.newGltf(gd, eng.Root())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := eng.newGltf(gd, nil); err == nil {
+		t.Errorf("Expected error for invalid parent")
+	}
+
+	// the joint is not added so the root has the body and the lamp.
+	root := top.(*pov).children[0]
+	if root.Name() != "root" || len(root.children) != 2 {
+		t.Fatalf("Expected root with 2 children, got %s %d", root.Name(), len(root.children))
+	}
+	body, lamp := root.children[0], root.children[1]
+	if x, y, z := lamp.Location(); x != 1 || y != 2 || z != 3 || lamp.Name() != "lamp" {
+		t.Errorf("Expected lamp at 1,2,3 got %f %f %f", x, y, z)
+	}
+	if sx, _, _ := lamp.Scale(); sx != 2 || !lin.Aeq(lamp.at.Rot.Y, math.Sqrt2/2) {
+		t.Errorf("Expected scaled and rotated lamp, got %f %+v", sx, lamp.at.Rot)
+	}
+
+	// skinned models play the skin animations.
+	m := body.Model().(*model)
+	if m.Shader() != "pbra" || m.anm == nil || len(m.anm.moves) != 1 || m.nFrames != 2 || len(m.pose) != 1 {
+		t.Fatalf("Expected animated model, got %s %+v", m.Shader(), m.anm)
+	}
+	if _, ok := m.msh.vdata[6]; !ok || m.msh.vdata[7] == nil || m.msh.vdata[4] == nil {
+		t.Errorf("Expected tangents, second uv set, and joints")
+	}
+	if len(m.texs) != 5 || m.texs[0].name != "skin" || !m.texs[0].repeat || m.texs[2].name != "gltfNormal" {
+		t.Errorf("Expected color texture and default textures, got %d", len(m.texs))
+	}
+	if mr := m.Uniform("mr"); len(mr) != 2 || mr[1] != 0.5 || m.Uniform("cutoff")[0] != 0.25 || m.Alpha() != 0.5 {
+		t.Errorf("Expected material uniforms, got %v %v", mr, m.Uniform("cutoff"))
+	}
+
+	// each mesh part of the lamp is a child model without animation.
+	if lamp.Model() != nil || len(lamp.children) != 2 {
+		t.Fatalf("Expected lamp part models")
+	}
+	lm := lamp.children[1].Model().(*model)
+	if lm.Shader() != "pbr" || lm.anm != nil || lm.msh.name != "lamp:bulb1" || lm.Uniform("cutoff")[0] != 0 {
+		t.Errorf("Expected static pbr model, got %s %s", lm.Shader(), lm.msh.name)
+	}
+}
+
+// testGltfData returns a skinned body and a lamp with two mesh parts.
+func testGltfData() *load.GltfData {
+	part := load.GltfPart{
+		V: []float32{0, 0, 0, 1, 0, 0, 0, 1, 0},
+		N: []float32{0, 0, 1, 0, 0, 1, 0, 0, 1},
+		X: []float32{1, 0, 0, 1, 1, 0, 0, 1, 1, 0, 0, 1},
+		T: [][]float32{{0, 0, 1, 0, 0, 1}, {0, 0, 1, 0, 0, 1}},
+		B: []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+		W: []byte{255, 0, 0, 0, 255, 0, 0, 0, 255, 0, 0, 0},
+		F: []uint32{0, 1, 2},
+	}
+	none := load.GltfTexRef{Texture: -1}
+	mat := load.GltfMaterial{Name: "skin", Color: [4]float32{1, 1, 1, 0.5}, Metallic: 0, Roughness: 0.5,
+		Occlusion: 1, AlphaMode: "MASK", AlphaCutoff: 0.25, ColorTex: load.GltfTexRef{Texture: 0},
+		MetalRoughTex: none, NormalTex: none, OcclusionTex: none, EmissiveTex: none}
+	glass := mat
+	glass.AlphaMode, glass.ColorTex = "OPAQUE", none
+	lamp := part
+	lamp.B, lamp.W = nil, nil
+	lamp.Material = 1
+	return &load.GltfData{
+		Name: "lamp",
+		Nodes: []load.GltfNode{
+			{Name: "root", Rot: [4]float64{0, 0, 0, 1}, Scale: [3]float64{1, 1, 1}, Mesh: -1, Skin: -1, Children: []int{1, 2, 3}},
+			{Name: "body", Rot: [4]float64{0, 0, 0, 1}, Scale: [3]float64{1, 1, 1}, Mesh: 0, Skin: 0},
+			{Name: "hip", Rot: [4]float64{0, 0, 0, 1}, Scale: [3]float64{1, 1, 1}, Mesh: -1, Skin: -1, Joint: true},
+			{Name: "lamp", Loc: [3]float64{1, 2, 3}, Rot: [4]float64{0, math.Sqrt2 / 2, 0, math.Sqrt2 / 2},
+				Scale: [3]float64{2, 2, 2}, Mesh: 1, Skin: -1},
+		},
+		Roots:     []int{0},
+		Meshes:    []load.GltfMesh{{Name: "body", Parts: []load.GltfPart{part}}, {Name: "bulb", Parts: []load.GltfPart{lamp, lamp}}},
+		Materials: []load.GltfMaterial{mat, glass},
+		Textures:  []load.GltfTexture{{Name: "skin", Img: image.NewNRGBA(image.Rect(0, 0, 2, 2)), Repeat: true}},
+		Skins: []load.GltfSkin{{
+			Name: "body", Nodes: []int{2}, Joints: []int32{-1}, Names: []string{"hip"},
+			Bases:  []*lin.M4{lin.NewM4I()},
+			Anims:  []load.IqAnim{{Name: "wave", F0: 0, Fn: 2, Rate: 30}},
+			Frames: []*lin.M4{lin.NewM4I(), lin.NewM4I()},
+		}},
+	}
+}
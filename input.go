@@ -4,26 +4,213 @@
 package vu
 
 import (
+	"math"
+
 	"github.com/gazed/vu/device"
+	"github.com/gazed/vu/math/lin"
 )
 
 // Input is used to communicate user feedback to the application.
 // User feedback is the current cursor location, current pressed keys,
-// mouse buttons, and modifiers. These are sent to the application
-// each App.Update() callback.
+// mouse buttons, modifiers, gamepads, and touches. These are sent to
+// the application each App.Update() callback.
+//
+// Text is the unicode characters typed since the last update. Use Text
+// rather than Down for text fields since it handles keyboard layouts,
+// dead keys, and input methods. Compose is the partial text while an
+// input method (IME) is active. Show Compose after the cursor of the text
+// field until it is replaced by the characters that arrive in Text.
 //
 // The map of keys and mouse buttons that are currently pressed also
 // include how long they have been pressed in update ticks. A negative
 // value indicates a key release, upon which the total down duration can
 // be calculated using the down duration less the RELEASED timestamp.
+//
+// Scan is the same as Down except that keys are identified by their
+// location on a US keyboard rather than their label. Use Scan for
+// movement keys so that, ie: KW, KA, KS, KD are in the same place on
+// AZERTY and other layouts. Eng.KeyName gives the key labels for
+// showing Scan keys to the user.
+//
+// Chord, DoubleTap, Held, and Tapped check key timing so that
+// applications don't need to track it themselves, ie:
+//     if in.Chord(vu.KCtl, vu.KS) { save() }
+//     if in.DoubleTap(vu.KW) { sprint() }
+//     if in.Held(vu.KE) { ... } else if in.Tapped(vu.KE) { ... }
+// Timing is measured in update ticks, see SetTiming.
 type Input struct {
 	Mx, My  int         // Current mouse location.
+	Dx, Dy  int         // Mouse motion since the last update.
+	Capture bool        // True if the mouse is captured, see Eng.CaptureMouse.
 	Down    map[int]int // Keys, buttons with down duration ticks.
+	Scan    map[int]int // Down with keys identified by location.
 	Focus   bool        // True if window is in focus.
 	Resized bool        // True if window was resized or moved.
 	Scroll  int         // Scroll amount: plus, minus or zero.
+	ScrollX float64     // Precise horizontal scroll in lines.
+	ScrollY float64     // Precise vertical scroll in lines.
 	Dt      float64     // Delta time for this update tick.
 	Ut      uint64      // Total number of update ticks.
+	Pads    []Pad       // Gamepads, one for each of MaxPads slots.
+	Touches []Touch     // Current touch points, if any.
+	Gesture Gesture     // Touch gesture for this update.
+	Text    string      // Characters typed since the last update.
+	Compose string      // Text being composed by an input method (IME).
+	Drops   []string    // Paths of files dropped on the window this update.
+
+	sdz  float64  // Stick dead zone.
+	tdz  float64  // Trigger dead zone.
+	gest gestures // Gesture recognizer.
+
+	// Key timing for DoubleTap, Held, and Tapped.
+	dtap  int            // Most ticks between double tap presses.
+	hold  int            // Ticks until a held key is not a tap.
+	taps  map[int]uint64 // Update tick of the last press of each key.
+	twice map[int]uint64 // Update tick of the last double tap.
+}
+
+// Pressed returns true on the update that the key or mouse button went
+// down. Use Pressed for single trigger actions and Down for held actions.
+// Keys that are pressed and released within one update are both Pressed
+// and Released.
+func (in *Input) Pressed(key int) bool { return pressed(in.Down, key) }
+
+// Released returns true on the update that the key or mouse button went up.
+func (in *Input) Released(key int) bool { return released(in.Down, key) }
+
+// Chord returns true on the update that all of the keys are down with
+// at least one of them newly pressed, ie: Ctrl+S is true when S is
+// pressed while Ctrl is held. The keys can be pressed in any order.
+func (in *Input) Chord(keys ...int) bool {
+	triggered := false
+	for _, key := range keys {
+		if duration, ok := in.Down[key]; !ok || duration <= 0 {
+			return false
+		}
+		triggered = triggered || in.Down[key] == 1
+	}
+	return triggered
+}
+
+// DoubleTap returns true on the update that the key is pressed for
+// the second time within the double tap ticks of the first press.
+// A third press starts a new double tap.
+func (in *Input) DoubleTap(key int) bool {
+	tick, ok := in.twice[key]
+	return ok && tick == in.Ut
+}
+
+// Held returns true on the update that the key has been held down for
+// the hold ticks. Tapped returns true on the update that the key is
+// released before the hold ticks. A key press is either Held or Tapped.
+func (in *Input) Held(key int) bool {
+	duration, ok := in.Down[key]
+	return ok && duration == in.hold
+}
+func (in *Input) Tapped(key int) bool {
+	duration, ok := in.Down[key]
+	return ok && duration < 0 && duration-KeyReleased < in.hold
+}
+
+// SetTiming sets the most update ticks between the presses of a double
+// tap and the update ticks that a key is down before it is held.
+// The defaults are DoubleTapTicks and HoldTicks.
+func (in *Input) SetTiming(doubleTap, hold int) {
+	in.dtap, in.hold = doubleTap, hold
+}
+
+// Default key timing in update ticks. There are 50 update ticks a second.
+const (
+	DoubleTapTicks = 15 // Most ticks between double tap presses.
+	HoldTicks      = 25 // Ticks until a key press is held.
+)
+
+// updateTaps remembers when each key was pressed to find double taps.
+// Expected to be called each update after the input has its final
+// keys and update tick.
+func (in *Input) updateTaps() {
+	if in.taps == nil {
+		in.taps, in.twice = map[int]uint64{}, map[int]uint64{}
+	}
+	for key := range in.Down {
+		if !pressed(in.Down, key) {
+			continue
+		}
+		if last, ok := in.taps[key]; ok && in.Ut-last <= uint64(in.dtap) {
+			in.twice[key] = in.Ut
+			delete(in.taps, key) // next press starts again.
+			continue
+		}
+		in.taps[key] = in.Ut
+	}
+}
+
+// pressed returns true if the down duration is the first update tick.
+func pressed(down map[int]int, code int) bool {
+	duration, ok := down[code]
+	return ok && (duration == 1 || duration == KeyReleased)
+}
+
+// released returns true if the down duration marks a release.
+func released(down map[int]int, code int) bool {
+	duration, ok := down[code]
+	return ok && duration < 0
+}
+
+// Pad is the state of one gamepad or joystick. The Down map holds the
+// pressed Pad buttons using the same durations as Input.Down. Plugged
+// reports hot-plug changes for a single update. Stick and trigger values
+// have the Input dead zones applied.
+type Pad struct {
+	Connected bool        // True if a controller is using this slot.
+	Plugged   int         // 1 connected, -1 disconnected, otherwise 0.
+	Down      map[int]int // Pressed buttons with down duration ticks.
+	Lx, Ly    float64     // Left stick -1:1, positive right and up.
+	Rx, Ry    float64     // Right stick -1:1, positive right and up.
+	Lt, Rt    float64     // Left and right triggers 0:1.
+}
+
+// Pressed returns true on the update that the gamepad button went down.
+func (p *Pad) Pressed(button int) bool { return pressed(p.Down, button) }
+
+// Released returns true on the update that the gamepad button went up.
+func (p *Pad) Released(button int) bool { return released(p.Down, button) }
+
+// axis returns the pad stick or trigger value for the given axis index.
+func (p *Pad) axis(index int) float64 {
+	switch index {
+	case PadLx:
+		return p.Lx
+	case PadLy:
+		return p.Ly
+	case PadRx:
+		return p.Rx
+	case PadRy:
+		return p.Ry
+	case PadLt:
+		return p.Lt
+	case PadRt:
+		return p.Rt
+	}
+	return 0
+}
+
+// Default dead zones used for the gamepad sticks and triggers.
+// The values match those recommended for XInput controllers.
+const (
+	StickDeadZone   = 0.24 // Fraction of the full stick range.
+	TriggerDeadZone = 0.12 // Fraction of the full trigger range.
+)
+
+// MaxPads is the number of gamepads reported in Input.Pads.
+const MaxPads = device.MaxPads
+
+// SetDeadZone sets the fraction of the stick and trigger range that
+// is reported as zero. Values outside the dead zone are rescaled so
+// they still cover the full range. Sticks use a circular dead zone.
+func (in *Input) SetDeadZone(stick, trigger float64) {
+	in.sdz = lin.Clamp(stick, 0, 0.99)
+	in.tdz = lin.Clamp(trigger, 0, 0.99)
 }
 
 // convertInput copies the given device.Pressed input into vu.Input.
@@ -31,9 +218,12 @@ type Input struct {
 // in update ticks. It is expected to be called each update.
 func (in *Input) convertInput(pressed *device.Pressed, ut uint64, dt float64) {
 	in.Mx, in.My = pressed.Mx, pressed.My
+	in.Dx, in.Dy = pressed.Dx, pressed.Dy
+	in.Capture = pressed.Capture
 	in.Focus = pressed.Focus
 	in.Resized = pressed.Resized
 	in.Scroll = pressed.Scroll
+	in.ScrollX, in.ScrollY = pressed.ScrollX, pressed.ScrollY
 	in.Dt = dt
 	in.Ut = ut
 
@@ -45,8 +235,94 @@ func (in *Input) convertInput(pressed *device.Pressed, ut uint64, dt float64) {
 	for key, val := range pressed.Down {
 		in.Down[key] = val
 	}
+	if in.Scan == nil {
+		in.Scan = map[int]int{}
+	}
+	for key := range in.Scan {
+		delete(in.Scan, key)
+	}
+	for key, val := range pressed.Scan {
+		in.Scan[key] = val
+	}
+
+	in.Text, in.Compose = pressed.Text, pressed.Compose
+	in.Drops = append(in.Drops[:0], pressed.Drops...)
+	in.Touches = convertTouches(in.Touches, pressed.Touches)
+	in.gest.update(in.Touches, &in.Gesture)
+
+	// Gamepad button maps are refreshed like the key map.
+	if len(in.Pads) != len(pressed.Pads) {
+		in.Pads = make([]Pad, len(pressed.Pads))
+		for cnt := range in.Pads {
+			in.Pads[cnt].Down = map[int]int{}
+		}
+	}
+	for cnt := range pressed.Pads {
+		dp, pad := &pressed.Pads[cnt], &in.Pads[cnt]
+		pad.Connected, pad.Plugged = dp.Connected, dp.Plugged
+		for code := range pad.Down {
+			delete(pad.Down, code)
+		}
+		for code, val := range dp.Down {
+			pad.Down[code] = val
+		}
+		pad.Lx, pad.Ly = deadZone(dp.Axes[device.PadLx], dp.Axes[device.PadLy], in.sdz)
+		pad.Rx, pad.Ry = deadZone(dp.Axes[device.PadRx], dp.Axes[device.PadRy], in.sdz)
+		pad.Lt = triggerZone(dp.Axes[device.PadLt], in.tdz)
+		pad.Rt = triggerZone(dp.Axes[device.PadRt], in.tdz)
+	}
+}
+
+// deadZone zeros stick values inside the circular dead zone and rescales
+// the remaining values so they start at zero at the dead zone edge.
+func deadZone(x, y, dz float64) (float64, float64) {
+	mag := math.Hypot(x, y)
+	if mag <= dz {
+		return 0, 0
+	}
+	scale := math.Min(1, (mag-dz)/(1-dz)) / mag
+	return x * scale, y * scale
 }
 
+// triggerZone zeros trigger values inside the dead zone and rescales
+// the remaining values to cover the range 0 to 1.
+func triggerZone(t, dz float64) float64 {
+	if t <= dz {
+		return 0
+	}
+	return math.Min(1, (t-dz)/(1-dz))
+}
+
+// Indexes for the gamepad sticks and triggers, see Pad.axis.
+const (
+	PadLx = device.PadLx // Left stick x.
+	PadLy = device.PadLy // Left stick y.
+	PadRx = device.PadRx // Right stick x.
+	PadRy = device.PadRy // Right stick y.
+	PadLt = device.PadLt // Left trigger.
+	PadRt = device.PadRt // Right trigger.
+)
+
+// Expose the device package gamepad buttons as a convenience.
+// Gamepad buttons are reported per controller in Pad.Down.
+const (
+	PadUp    = device.PadUp    // Directional pad.
+	PadDown  = device.PadDown  //   "
+	PadLeft  = device.PadLeft  //   "
+	PadRight = device.PadRight //   "
+	PadStart = device.PadStart // Menu buttons.
+	PadBack  = device.PadBack  //   "
+	PadLs    = device.PadLs    // Stick buttons.
+	PadRs    = device.PadRs    //   "
+	PadLb    = device.PadLb    // Shoulder bumpers.
+	PadRb    = device.PadRb    //   "
+	PadGuide = device.PadGuide // Center button, if any.
+	PadA     = device.PadA     // Face buttons.
+	PadB     = device.PadB     //   "
+	PadX     = device.PadX     //   "
+	PadY     = device.PadY     //   "
+)
+
 // Expose the device package keys as a convenience so the
 // device package does not always need including.
 // The symbol associated to each key is shown in the comments.
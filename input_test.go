@@ -0,0 +1,135 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gazed/vu/device"
+	"github.com/gazed/vu/math/lin"
+)
+
+func TestConvertPads(t *testing.T) {
+	pressed := &device.Pressed{Down: map[int]int{}, Pads: make([]device.Pad, MaxPads)}
+	pad := &pressed.Pads[1]
+	pad.Connected, pad.Plugged = true, 1
+	pad.Down = map[int]int{PadA: 3, PadLb: -5 + device.KeyReleased}
+	pad.Axes = [6]float64{0.1, 0.1, 0, 1, 0.05, 1}
+	in := &Input{Down: map[int]int{}}
+	in.SetDeadZone(StickDeadZone, TriggerDeadZone)
+	in.convertInput(pressed, 0, 0)
+	if len(in.Pads) != MaxPads || in.Pads[0].Connected {
+		t.Fatalf("expected %d pads with only pad 1 connected", MaxPads)
+	}
+	got := in.Pads[1]
+	if !got.Connected || got.Plugged != 1 || got.Down[PadA] != 3 || got.Down[PadLb] >= 0 {
+		t.Errorf("bad pad buttons or hot-plug %+v", got)
+	}
+	if got.Lx != 0 || got.Ly != 0 || got.Lt != 0 {
+		t.Errorf("expected dead zone values %f %f %f", got.Lx, got.Ly, got.Lt)
+	}
+	if got.Rx != 0 || got.Ry != 1 || got.Rt != 1 {
+		t.Errorf("expected full range values %f %f %f", got.Rx, got.Ry, got.Rt)
+	}
+
+	// values outside the dead zone are rescaled keeping their direction.
+	pad.Axes = [6]float64{0.62, 0, 0.5, 0.5, 0.56, 0}
+	in.convertInput(pressed, 0, 0)
+	got = in.Pads[1]
+	if !lin.Aeq(got.Lx, 0.5) || !lin.Aeq(got.Lt, 0.5) || !lin.Aeq(got.Rx, got.Ry) {
+		t.Errorf("expected rescaled values %f %f %f %f", got.Lx, got.Lt, got.Rx, got.Ry)
+	}
+	if mag := math.Hypot(got.Rx, got.Ry); math.Abs(mag-(math.Sqrt(0.5)-0.24)/0.76) > 1e-9 {
+		t.Errorf("expected circular dead zone, got magnitude %f", mag)
+	}
+
+	// no dead zone reports the raw values.
+	in.SetDeadZone(0, 0)
+	in.convertInput(pressed, 0, 0)
+	if got = in.Pads[1]; got.Lx != 0.62 || got.Lt != 0.56 {
+		t.Errorf("expected raw values %f %f", got.Lx, got.Lt)
+	}
+}
+
+func TestConvertTextDrops(t *testing.T) {
+	pressed := &device.Pressed{Down: map[int]int{}, Text: "héllo 世界", Compose: "にほ"}
+	in := &Input{Down: map[int]int{}}
+	in.convertInput(pressed, 0, 0)
+	if in.Text != "héllo 世界" || in.Compose != "にほ" {
+		t.Errorf("expected typed and composed text, got %q %q", in.Text, in.Compose)
+	}
+	pressed.Text, pressed.Compose = "", ""
+	if in.convertInput(pressed, 0, 0); in.Text != "" || in.Compose != "" {
+		t.Errorf("expected text to be cleared each update")
+	}
+
+	// dropped files are only reported for one update.
+	pressed.Drops = []string{"/tmp/a.obj", "/tmp/b.png"}
+	if in.convertInput(pressed, 0, 0); len(in.Drops) != 2 || in.Drops[1] != "/tmp/b.png" {
+		t.Errorf("expected dropped files, got %v", in.Drops)
+	}
+	pressed.Drops = pressed.Drops[:0]
+	if in.convertInput(pressed, 0, 0); len(in.Drops) != 0 {
+		t.Errorf("expected drops to be cleared, got %v", in.Drops)
+	}
+}
+
+// key locations are converted separately from the key labels.
+func TestConvertScan(t *testing.T) {
+	pressed := &device.Pressed{Down: map[int]int{KZ: 2}, Scan: map[int]int{KW: 2}}
+	in := &Input{Down: map[int]int{}}
+	in.convertInput(pressed, 0, 0)
+	if in.Down[KZ] != 2 || in.Scan[KW] != 2 || len(in.Scan) != 1 {
+		t.Errorf("expected labelled and located keys, got %v %v", in.Down, in.Scan)
+	}
+	pressed.Scan = map[int]int{}
+	if in.convertInput(pressed, 0, 0); len(in.Scan) != 0 {
+		t.Errorf("expected released key locations to be cleared, got %v", in.Scan)
+	}
+}
+
+func TestPressedReleased(t *testing.T) {
+	in := &Input{Down: map[int]int{KA: 1, KB: 5, KC: 4 + KeyReleased, KD: KeyReleased}}
+	if !in.Pressed(KA) || in.Pressed(KB) || in.Pressed(KC) || !in.Pressed(KD) || in.Pressed(KE) {
+		t.Errorf("expected only A and D pressed")
+	}
+	if in.Released(KA) || in.Released(KB) || !in.Released(KC) || !in.Released(KD) || in.Released(KE) {
+		t.Errorf("expected only C and D released")
+	}
+	pad := &Pad{Down: map[int]int{PadA: 1, PadB: 2 + KeyReleased}}
+	if !pad.Pressed(PadA) || pad.Pressed(PadB) || !pad.Released(PadB) || pad.Released(PadA) {
+		t.Errorf("expected pad A pressed and B released")
+	}
+}
+
+func TestKeyTiming(t *testing.T) {
+	in := newAppData().input
+	in.Down[KCtl], in.Down[KS] = 5, 1
+	if !in.Chord(KCtl, KS) || !in.Chord(KS, KCtl) || in.Chord(KCtl, KS, KA) {
+		t.Errorf("expected chord when S is pressed while Ctrl is held")
+	}
+	if in.Down[KS] = 2; in.Chord(KCtl, KS) {
+		t.Errorf("expected chord only on the update it is pressed")
+	}
+
+	// press W quickly four times. A third press starts a new double tap.
+	in.Down = map[int]int{KW: 1}
+	for ut, tap := range []bool{false, true, false, true} {
+		in.Ut = uint64(ut * 5)
+		if in.updateTaps(); in.DoubleTap(KW) != tap {
+			t.Errorf("expected double tap %t at tick %d", tap, in.Ut)
+		}
+	}
+	in.Ut += DoubleTapTicks + 1
+	if in.updateTaps(); in.DoubleTap(KW) {
+		t.Errorf("expected slow presses not to double tap")
+	}
+
+	// held keys are not taps.
+	in.Down = map[int]int{KE: HoldTicks, KQ: 3 + KeyReleased, KR: HoldTicks + 1 + KeyReleased}
+	if !in.Held(KE) || in.Tapped(KE) || !in.Tapped(KQ) || in.Tapped(KR) {
+		t.Errorf("expected E held, Q tapped, R neither")
+	}
+}
@@ -0,0 +1,295 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+// A journal records Pov changes so that editors can undo and redo them
+// without wrapping each engine call, ie:
+//     j := eng.NewJournal()
+//     j.Step("move crate")
+//     crate.SetLocation(1, 0, 4)       // recorded.
+//     j.Step("delete lamp")
+//     lamp.Dispose(vu.PovNode)        // recorded.
+//     j.Undo()                        // lamp is back.
+//     j.Undo()                        // crate is back where it was.
+// Recorded are Pov creation and disposal, and the Pov transform,
+// visibility, enabled, name, tag, layers, and parent changes made
+// through the Pov methods. Changes made by physics, animation, tweens,
+// and constraints are not recorded. Disposed Pov's are restored from
+// their scene data, see scenefile.go, as new Pov's that replace the
+// disposed Pov in the journal. Restored Pov's are added as the last
+// child of their parent, and anything not kept in a scene file, like
+// components and generated meshes, is not restored.
+//
+// The journal is optional and nothing is recorded unless there is a
+// journal.
+
+// Journal groups recorded Pov changes into undoable steps.
+// Steps without changes are ignored. Changes made after an Undo
+// discard the steps that could be redone.
+type Journal interface {
+	Step(label string) // Group the following changes in a new step.
+
+	// Undo reverts the last step. Redo repeats the last undone step.
+	// The label of the step is returned, and ok is false if there
+	// was nothing to undo or redo.
+	Undo() (label string, ok bool)
+	Redo() (label string, ok bool)
+	Close() // Stop recording and discard the steps.
+}
+
+// journal implements Journal.
+type journal struct {
+	eng    *engine
+	steps  []*step       // Undoable steps, newest last.
+	undone []*step       // Redoable steps, newest last.
+	refs   map[*pov]*ref // Shared references to recorded Pov's.
+	label  string        // Label for the next step.
+	open   bool          // True if changes are added to the last step.
+	replay bool          // True while undoing or redoing.
+}
+
+// step is a group of changes that are undone together.
+type step struct {
+	label   string
+	changes []*change
+}
+
+// ref follows a recorded Pov as it is disposed and restored.
+type ref struct{ p *pov }
+
+// change is one recorded change. State changes have before and after
+// values. Creation and disposal use the undo and redo functions.
+type change struct {
+	r             *ref
+	before, after *povState
+	undo, redo    func(j *journal)
+}
+
+// povState is the recorded Pov values.
+type povState struct {
+	at      [10]float64 // Location, rotation, and scale.
+	visible bool
+	enabled bool
+	name    string
+	tag     string
+	layers  uint32
+	parent  *ref
+}
+
+// Implement Eng interface. Any previous journal is closed.
+func (eng *engine) NewJournal() Journal {
+	if eng.undo != nil {
+		eng.undo.Close()
+	}
+	eng.undo = &journal{eng: eng, refs: map[*pov]*ref{}}
+	return eng.undo
+}
+
+// Implement Journal.
+func (j *journal) Step(label string) {
+	j.label, j.open = label, false
+}
+func (j *journal) Undo() (label string, ok bool) {
+	if len(j.steps) == 0 {
+		return "", false
+	}
+	s := j.steps[len(j.steps)-1]
+	j.steps = j.steps[:len(j.steps)-1]
+	j.replay, j.open = true, false
+	for cnt := len(s.changes) - 1; cnt >= 0; cnt-- {
+		if c := s.changes[cnt]; c.undo != nil {
+			c.undo(j)
+		} else {
+			j.restore(c.r, c.before)
+		}
+	}
+	j.replay = false
+	j.undone = append(j.undone, s)
+	return s.label, true
+}
+func (j *journal) Redo() (label string, ok bool) {
+	if len(j.undone) == 0 {
+		return "", false
+	}
+	s := j.undone[len(j.undone)-1]
+	j.undone = j.undone[:len(j.undone)-1]
+	j.replay, j.open = true, false
+	for _, c := range s.changes {
+		if c.redo != nil {
+			c.redo(j)
+		} else {
+			j.restore(c.r, c.after)
+		}
+	}
+	j.replay = false
+	j.steps = append(j.steps, s)
+	return s.label, true
+}
+func (j *journal) Close() {
+	if j.eng.undo == j {
+		j.eng.undo = nil
+	}
+	j.steps, j.undone, j.refs, j.open = nil, nil, map[*pov]*ref{}, false
+}
+
+// ref returns the shared reference for p.
+func (j *journal) ref(p *pov) *ref {
+	if p == nil {
+		return nil
+	}
+	r, ok := j.refs[p]
+	if !ok {
+		r = &ref{p: p}
+		j.refs[p] = r
+	}
+	return r
+}
+
+// live returns the referenced Pov if it has not been disposed.
+func (j *journal) live(r *ref) *pov {
+	if r == nil || r.p == nil || j.eng.povs[r.p.eid] != r.p {
+		return nil
+	}
+	return r.p
+}
+
+// add records a change in the open step, starting a new step
+// if necessary. Nothing is recorded while replaying.
+func (j *journal) add(c *change) {
+	if j.replay {
+		return
+	}
+	if !j.open {
+		j.steps = append(j.steps, &step{label: j.label})
+		j.label, j.open = "", true
+	}
+	j.undone = nil
+	s := j.steps[len(j.steps)-1]
+	s.changes = append(s.changes, c)
+}
+
+// track returns the function that records the change to p made
+// between calling track and calling the returned function.
+// Expected to be used as:
+//    if j := p.eng.undo; j != nil {
+//        defer j.track(p)()
+//    }
+func (j *journal) track(p *pov) func() {
+	before := j.state(p)
+	return func() {
+		if j.replay || j.eng.undo != j {
+			return
+		}
+		after := j.state(p)
+		if *after == *before {
+			return
+		}
+		if j.open && len(j.steps) > 0 {
+			s := j.steps[len(j.steps)-1]
+			if n := len(s.changes); n > 0 && s.changes[n-1].r == j.refs[p] && s.changes[n-1].after != nil {
+				s.changes[n-1].after = after // merge repeated changes.
+				return
+			}
+		}
+		j.add(&change{r: j.ref(p), before: before, after: after})
+	}
+}
+
+// state returns the current recorded values of p.
+func (j *journal) state(p *pov) *povState {
+	l, r, s := p.at.Loc, p.at.Rot, p.scale
+	return &povState{
+		at:      [10]float64{l.X, l.Y, l.Z, r.X, r.Y, r.Z, r.W, s.X, s.Y, s.Z},
+		visible: p.visible, enabled: p.enabled,
+		name: p.name, tag: p.tag, layers: p.layers,
+		parent: j.ref(p.parent),
+	}
+}
+
+// restore sets the recorded values of the referenced Pov.
+func (j *journal) restore(r *ref, s *povState) {
+	p := j.live(r)
+	if p == nil {
+		return
+	}
+	a := s.at
+	p.at.Loc.SetS(a[0], a[1], a[2])
+	p.at.Rot.SetS(a[3], a[4], a[5], a[6])
+	p.scale.SetS(a[7], a[8], a[9])
+	p.visible, p.enabled = s.visible, s.enabled
+	if p.name != s.name {
+		j.eng.setName(p, s.name)
+	}
+	if p.tag != s.tag {
+		j.eng.setTag(p, s.tag)
+	}
+	p.SetLayers(s.layers)
+	if parent := j.live(s.parent); parent != nil && parent != p.parent {
+		p.SetParent(parent, false)
+	}
+}
+
+// created records the creation of p.
+func (j *journal) created(p *pov) {
+	if j.replay {
+		return
+	}
+	r, parent := j.ref(p), j.ref(p.parent)
+	var node *sceneNode
+	j.add(&change{r: r,
+		undo: func(j *journal) { node = j.take(r) },
+		redo: func(j *journal) { j.recreate(r, parent, node) },
+	})
+}
+
+// disposed records the disposal of p and its children.
+func (j *journal) disposed(p *pov) {
+	if j.replay || j.eng.povs[p.eid] != p {
+		return
+	}
+	r, parent := j.ref(p), j.ref(p.parent)
+	node := j.eng.saveNode(p)
+	j.add(&change{r: r,
+		undo: func(j *journal) { j.recreate(r, parent, node) },
+		redo: func(j *journal) { node = j.take(r) },
+	})
+}
+
+// take disposes the referenced Pov returning its scene data.
+func (j *journal) take(r *ref) *sceneNode {
+	p := j.live(r)
+	if p == nil {
+		return nil
+	}
+	node := j.eng.saveNode(p)
+	j.eng.dispose(p, PovNode)
+	return node
+}
+
+// recreate restores a disposed Pov from its scene data. The new Pov,
+// and its children, replace the disposed Pov's in the journal.
+func (j *journal) recreate(r, parent *ref, node *sceneNode) {
+	pp := j.live(parent)
+	if pp == nil || node == nil || j.live(r) != nil {
+		return
+	}
+	p, err := j.eng.newScene(node, pp)
+	if err != nil {
+		return
+	}
+	j.remap(r.p, p.(*pov))
+}
+
+// remap moves the references from the old Pov hierarchy to the
+// matching Pov's in the new hierarchy.
+func (j *journal) remap(old, p *pov) {
+	if r, ok := j.refs[old]; ok {
+		delete(j.refs, old)
+		r.p = p
+		j.refs[p] = r
+	}
+	for cnt := 0; cnt < len(old.children) && cnt < len(p.children); cnt++ {
+		j.remap(old.children[cnt], p.children[cnt])
+	}
+}
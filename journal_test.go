@@ -0,0 +1,65 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import "testing"
+
+// TestJournal checks that recorded changes are undone and redone.
+func TestJournal(t *testing.T) {
+	eng := newEngine(nil)
+	defer eng.Shutdown()
+	a := eng.Root().NewPov().SetName("a")
+	j := eng.NewJournal()
+	j.Step("move")
+	a.SetLocation(1, 2, 3)
+	a.SetLocation(4, 5, 6) // merged into one change.
+	a.SetVisible(false)
+	j.Step("add")
+	b := a.NewPov().SetName("b").SetLocation(7, 0, 0)
+	b.NewPov().SetName("c")
+	j.Step("empty") // ignored.
+	if len(j.(*journal).steps) != 2 || len(j.(*journal).steps[0].changes) != 1 {
+		t.Fatalf("Expected 2 steps with one move change")
+	}
+
+	// undo and redo creation.
+	if label, ok := j.Undo(); !ok || label != "add" || eng.Find("b") != nil || eng.Find("c") != nil {
+		t.Errorf("Expected b and c removed by %q", label)
+	}
+	if label, ok := j.Redo(); !ok || label != "add" || eng.Find("c") == nil {
+		t.Errorf("Expected b and c restored by %q", label)
+	}
+	if x, _, _ := eng.Find("b").Location(); x != 7 || eng.Find("c").(*pov).parent != eng.Find("b") {
+		t.Errorf("Expected restored b at 7 with child c")
+	}
+
+	// undo and redo disposal.
+	j.Step("delete")
+	eng.Find("b").Dispose(PovNode)
+	j.Undo()
+	if c := eng.Find("c"); c == nil || c.(*pov).parent.name != "b" {
+		t.Errorf("Expected b and c restored")
+	}
+	j.Undo() // add.
+	j.Undo() // move.
+	if x, _, _ := a.Location(); x != 0 || !a.Visible() || eng.Find("b") != nil {
+		t.Errorf("Expected a back at the origin without children")
+	}
+	if _, ok := j.Undo(); ok {
+		t.Errorf("Expected nothing to undo")
+	}
+	j.Redo() // move.
+	a.SetScale(2, 2, 2)
+	if _, ok := j.Redo(); ok {
+		t.Errorf("Expected redo steps discarded by a new change")
+	}
+	if x, _, _ := a.Location(); x != 4 || a.Visible() {
+		t.Errorf("Expected a moved and hidden")
+	}
+	j.Close()
+	a.SetLocation(0, 0, 0)
+	if _, ok := j.Undo(); ok || eng.undo != nil {
+		t.Errorf("Expected nothing recorded after close")
+	}
+}
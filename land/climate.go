@@ -0,0 +1,157 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package land
+
+// Climate maps give each height map point a temperature and a moisture
+// between 0 and 1. Temperature drops with height above the sea and with
+// latitude. Moisture is highest near water. Both are varied with noise
+// so that biome borders are not just height contours. The temperature,
+// moisture, and height then pick a biome for each point following a
+// simplified Whittaker diagram:
+//   https://en.wikipedia.org/wiki/Biome#Whittaker_.281962.2C_1970.2C_1975.29_biome-types
+
+import (
+	"math"
+)
+
+// Biome identifiers returned by Biomes. Use the biome as an index into
+// a list of texture atlas indicies to texture a surface by biome.
+const (
+	Water     = iota // Below the sea level.
+	Sand             // Beaches near the water.
+	Desert           // Warm and dry.
+	Grass            // Temperate.
+	Forest           // Temperate and wet.
+	Jungle           // Warm and wet.
+	Tundra           // Cold and below the snow line.
+	Snow             // Coldest. Above the tree line on mountains.
+	NumBiomes        // Number of biome identifiers.
+)
+
+// Climate holds the settings used to create climate maps and biomes.
+// Start with DefaultClimate and adjust as needed.
+type Climate struct {
+	Sea       float64 // Water level height.
+	Beach     float64 // Height above the sea that is sand.
+	Lapse     float64 // Temperature drop per unit of height above the sea.
+	Latitude  float64 // Temperature drop from the bottom to the top of the map.
+	Wet       float64 // Distance from water, in points, where moisture is added.
+	Variation float64 // 0 to 1. Amount of noise in temperature and moisture.
+	Snow      float64 // Temperature below which there is snow.
+	Tundra    float64 // Temperature below which there is tundra.
+}
+
+// DefaultClimate returns climate settings that work
+// for height maps with heights between -1 and 1.
+func DefaultClimate() Climate {
+	return Climate{
+		Sea:       0,
+		Beach:     0.03,
+		Lapse:     0.9,
+		Latitude:  0,
+		Wet:       16,
+		Variation: 0.2,
+		Snow:      0.2,
+		Tundra:    0.35,
+	}
+}
+
+// Climate creates temperature and moisture maps for the topology
+// section. The noise used to vary the maps comes from the given seed
+// so the same seed and settings always give the same results.
+func (t Topo) Climate(c Climate, seed int64) (temperature, moisture Topo) {
+	sx, sy := t.Size()
+	temperature, moisture = NewTopo(uint(sx), uint(sy)), NewTopo(uint(sx), uint(sy))
+	dist := t.shore(c.Sea)
+	n := newNoise(seed)
+	freq := 4.0 / float64(sx)
+	for x := range t {
+		for y := range t[x] {
+			nx, ny := float64(x)*freq, float64(y)*freq
+			above := math.Max(0, t[x][y]-c.Sea)
+			lat := float64(y) / float64(sy)
+			temp := 1 - c.Lapse*above - c.Latitude*lat + c.Variation*n.generate2D(nx, ny)
+			wet := 0.3
+			if c.Wet > 0 {
+				wet += 0.7 * math.Max(0, 1-dist[x][y]/c.Wet)
+			}
+			wet += c.Variation * n.generate2D(nx+101.3, ny+101.3) // different noise than temperature.
+			temperature[x][y] = math.Max(0, math.Min(1, temp))
+			moisture[x][y] = math.Max(0, math.Min(1, wet))
+		}
+	}
+	return temperature, moisture
+}
+
+// Biomes returns the biome identifier for each point in the topology
+// section using the temperature and moisture maps created by Climate.
+func (t Topo) Biomes(c Climate, temperature, moisture Topo) RegionData {
+	data := make(RegionData, len(t))
+	for x := range t {
+		data[x] = make([]int, len(t[x]))
+		for y := range t[x] {
+			data[x][y] = c.biome(t[x][y], temperature[x][y], moisture[x][y])
+		}
+	}
+	return data
+}
+
+// biome picks the biome for a single point.
+func (c Climate) biome(height, temperature, moisture float64) int {
+	switch {
+	case height < c.Sea:
+		return Water
+	case temperature < c.Snow:
+		return Snow
+	case height < c.Sea+c.Beach:
+		return Sand
+	case temperature < c.Tundra:
+		return Tundra
+	case moisture < 0.25:
+		return Desert
+	case moisture < 0.5:
+		return Grass
+	case moisture < 0.75 || temperature < 0.7:
+		return Forest
+	}
+	return Jungle
+}
+
+// shore returns the distance from each point to the closest point below
+// the sea level. The distances are approximated using two passes of a
+// chamfer distance transform. Maps without water have large distances.
+func (t Topo) shore(sea float64) Topo {
+	sx, sy := t.Size()
+	dist := NewTopo(uint(sx), uint(sy))
+	far := float64(sx + sy)
+	for x := range t {
+		for y := range t[x] {
+			if dist[x][y] = far; t[x][y] < sea {
+				dist[x][y] = 0
+			}
+		}
+	}
+	check := func(x, y, nx, ny int, step float64) {
+		if nx >= 0 && nx < sx && ny >= 0 && ny < sy && dist[nx][ny]+step < dist[x][y] {
+			dist[x][y] = dist[nx][ny] + step
+		}
+	}
+	for x := 0; x < sx; x++ {
+		for y := 0; y < sy; y++ {
+			check(x, y, x-1, y, 1)
+			check(x, y, x, y-1, 1)
+			check(x, y, x-1, y-1, math.Sqrt2)
+			check(x, y, x-1, y+1, math.Sqrt2)
+		}
+	}
+	for x := sx - 1; x >= 0; x-- {
+		for y := sy - 1; y >= 0; y-- {
+			check(x, y, x+1, y, 1)
+			check(x, y, x, y+1, 1)
+			check(x, y, x+1, y+1, math.Sqrt2)
+			check(x, y, x+1, y-1, math.Sqrt2)
+		}
+	}
+	return dist
+}
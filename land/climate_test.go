@@ -0,0 +1,28 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package land
+
+import (
+	"testing"
+)
+
+// Check biomes along a ramp rising out of the water.
+func TestBiomes(t *testing.T) {
+	ramp := NewTopo(64, 8)
+	for x := range ramp {
+		for y := range ramp[x] {
+			ramp[x][y] = float64(x-8) / 48 // water below x=8, 1 at x=56.
+		}
+	}
+	c := DefaultClimate()
+	c.Variation = 0
+	temp, wet := ramp.Climate(c, 123)
+	if temp[10][4] <= temp[60][4] || wet[10][4] <= wet[40][4] {
+		t.Errorf("Expected colder heights and moister shores")
+	}
+	biomes := ramp.Biomes(c, temp, wet)
+	if biomes[2][4] != Water || biomes[8][4] != Sand || biomes[63][4] != Snow {
+		t.Errorf("Expected water, sand, and snow, got %d %d %d", biomes[2][4], biomes[8][4], biomes[63][4])
+	}
+}
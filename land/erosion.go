@@ -0,0 +1,126 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package land
+
+// Hydraulic erosion simulates rain droplets running downhill across the
+// height map. Each droplet picks up sediment while it is fast and moving
+// downhill and drops sediment as it slows, fills pits, or evaporates.
+// Running many droplets carves valleys and leaves sediment fans where
+// slopes flatten out. Based on:
+//   http://ranmantaru.com/blog/2011/10/08/water-erosion-on-heightmap-terrain/
+
+import (
+	"math"
+	"math/rand"
+)
+
+// Erosion holds the hydraulic erosion settings used by Erode.
+// Start with DefaultErosion and adjust as needed.
+type Erosion struct {
+	Droplets  int     // Number of simulated droplets.
+	Lifetime  int     // Maximum steps for each droplet.
+	Inertia   float64 // 0 to 1. Higher keeps droplets moving straighter.
+	Capacity  float64 // Sediment carried per unit of speed, water, and slope.
+	MinSlope  float64 // Lowest slope used for capacity on flat ground.
+	Erode     float64 // 0 to 1. Fraction of free capacity eroded each step.
+	Deposit   float64 // 0 to 1. Fraction of excess sediment dropped each step.
+	Evaporate float64 // 0 to 1. Fraction of water lost each step.
+	Gravity   float64 // Downhill acceleration.
+}
+
+// DefaultErosion returns erosion settings that work
+// for height maps with heights between -1 and 1.
+func DefaultErosion() Erosion {
+	return Erosion{
+		Droplets:  50000,
+		Lifetime:  30,
+		Inertia:   0.05,
+		Capacity:  4,
+		MinSlope:  0.01,
+		Erode:     0.3,
+		Deposit:   0.3,
+		Evaporate: 0.01,
+		Gravity:   4,
+	}
+}
+
+// Erode applies hydraulic erosion to the topology section. Droplet start
+// locations come from the given seed so the same seed and settings always
+// give the same results. Sediment carried off the edge of the map, or
+// still held when a droplet evaporates, is lost.
+func (t Topo) Erode(e Erosion, seed int64) {
+	sx, sy := t.Size()
+	if sx < 2 || sy < 2 {
+		return
+	}
+	random := rand.New(rand.NewSource(seed))
+	for drop := 0; drop < e.Droplets; drop++ {
+		x, y := random.Float64()*float64(sx-1), random.Float64()*float64(sy-1)
+		dx, dy, speed, water, sediment := 0.0, 0.0, 1.0, 1.0, 0.0
+		for life := 0; life < e.Lifetime; life++ {
+			h, gx, gy := t.gradient(x, y)
+
+			// blend the previous direction with the downhill direction.
+			dx = dx*e.Inertia - gx*(1-e.Inertia)
+			dy = dy*e.Inertia - gy*(1-e.Inertia)
+			length := math.Hypot(dx, dy)
+			if length == 0 {
+				break // droplet is stuck on flat ground.
+			}
+			dx, dy = dx/length, dy/length
+			nx, ny := x+dx, y+dy
+			if nx < 0 || nx >= float64(sx-1) || ny < 0 || ny >= float64(sy-1) {
+				break // droplet has left the map.
+			}
+			nh, _, _ := t.gradient(nx, ny)
+			dh := nh - h
+
+			// deposit going uphill or when carrying too much,
+			// otherwise erode up to the free capacity.
+			capacity := math.Max(-dh, e.MinSlope) * speed * water * e.Capacity
+			switch {
+			case dh > 0:
+				amount := math.Min(dh, sediment) // fill the pit.
+				sediment -= amount
+				t.spread(x, y, amount)
+			case sediment > capacity:
+				amount := (sediment - capacity) * e.Deposit
+				sediment -= amount
+				t.spread(x, y, amount)
+			default:
+				amount := math.Min((capacity-sediment)*e.Erode, -dh)
+				sediment += amount
+				t.spread(x, y, -amount)
+			}
+			speed = math.Sqrt(math.Max(0, speed*speed-dh*e.Gravity))
+			water *= 1 - e.Evaporate
+			x, y = nx, ny
+		}
+	}
+}
+
+// gradient returns the bilinearly interpolated height and the height
+// slope along x and y at the given location. The location is expected
+// to be within the map, less than size-1.
+func (t Topo) gradient(x, y float64) (h, gx, gy float64) {
+	ix, iy := int(x), int(y)
+	u, v := x-float64(ix), y-float64(iy)
+	h00, h10 := t[ix][iy], t[ix+1][iy]
+	h01, h11 := t[ix][iy+1], t[ix+1][iy+1]
+	gx = (h10-h00)*(1-v) + (h11-h01)*v
+	gy = (h01-h00)*(1-u) + (h11-h10)*u
+	h = h00*(1-u)*(1-v) + h10*u*(1-v) + h01*(1-u)*v + h11*u*v
+	return h, gx, gy
+}
+
+// spread adds the given height amount to the four points surrounding
+// the location using bilinear weights. Negative amounts remove height.
+func (t Topo) spread(x, y, amount float64) {
+	ix, iy := int(x), int(y)
+	u, v := x-float64(ix), y-float64(iy)
+	t[ix][iy] += amount * (1 - u) * (1 - v)
+	t[ix+1][iy] += amount * u * (1 - v)
+	t[ix][iy+1] += amount * (1 - u) * v
+	t[ix+1][iy+1] += amount * u * v
+}
@@ -0,0 +1,42 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package land
+
+import (
+	"testing"
+)
+
+// Check that erosion is repeatable, lowers the land,
+// and doesn't create material.
+func TestErode(t *testing.T) {
+	a, b := NewTopo(64, 64), NewTopo(64, 64)
+	n := newNoise(123)
+	a.generate(0, 0, 0, n)
+	b.generate(0, 0, 0, n)
+	before := total(a)
+	e := DefaultErosion()
+	e.Droplets = 2000
+	a.Erode(e, 42)
+	b.Erode(e, 42)
+	for x := range a {
+		for y := range a[x] {
+			if a[x][y] != b[x][y] {
+				t.Fatalf("Expected same erosion for same seed at %d,%d", x, y)
+			}
+		}
+	}
+	if after := total(a); after > before+1e-9 || after == before {
+		t.Errorf("Expected erosion to remove material, before %f after %f", before, after)
+	}
+}
+
+// total returns the sum of all heights.
+func total(t Topo) (sum float64) {
+	for x := range t {
+		for y := range t[x] {
+			sum += t[x][y]
+		}
+	}
+	return sum
+}
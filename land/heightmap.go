@@ -0,0 +1,149 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package land
+
+// Heightmaps authored in external terrain tools are commonly saved as
+// 8 or 16 bit grayscale PNG images or as headerless RAW files. These are
+// imported as topology sections with the same conventions as generated
+// topology: heights between -1 and 1, times scale, with 0,0 at the bottom
+// left. The returned topology can then be copied into a vu.Surface.
+// Generated topology can be exported in the same formats so that it can
+// be saved and edited, instead of regenerated, and imported again.
+
+import (
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"io/ioutil"
+	"math"
+)
+
+// ImportImage creates a topology section from a grayscale heightmap
+// image, ie: one returned by load.Png. Black is -scale and white is
+// +scale. 16 bit grayscale images keep their full precision while other
+// image types are converted to grayscale. Wrap adds one extra row and
+// column that repeat the first row and column so that the same
+// heightmap can be tiled without seams.
+func ImportImage(img image.Image, scale float64, wrap bool) Topo {
+	b := img.Bounds()
+	t := newHeights(b.Dx(), b.Dy(), wrap)
+	flip := b.Dy() - 1
+	for x := 0; x < b.Dx(); x++ {
+		for y := 0; y < b.Dy(); y++ {
+			gray := color.Gray16Model.Convert(img.At(b.Min.X+x, b.Min.Y+y)).(color.Gray16)
+			t[x][flip-y] = heightOf(float64(gray.Y)/0xFFFF, scale) // Put 0,0 at bottom left.
+		}
+	}
+	if wrap {
+		t.wrap()
+	}
+	return t
+}
+
+// ImportRaw creates a topology section from a headerless RAW heightmap
+// of width by height values. Values are 8 or 16 bits, where 16 bit
+// values are little endian, and the first row of values is the top of
+// the heightmap. See ImportImage for scale and wrap.
+func ImportRaw(r io.Reader, width, height, bits int, scale float64, wrap bool) (Topo, error) {
+	if width <= 0 || height <= 0 || (bits != 8 && bits != 16) {
+		return nil, fmt.Errorf("ImportRaw: invalid %dx%d %d bit heightmap", width, height, bits)
+	}
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("ImportRaw: %s", err)
+	}
+	if size := width * height * bits / 8; len(data) != size {
+		return nil, fmt.Errorf("ImportRaw: expected %d bytes, got %d", size, len(data))
+	}
+	t := newHeights(width, height, wrap)
+	flip := height - 1
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			at := y*width + x
+			v := float64(data[at]) / 0xFF
+			if bits == 16 {
+				v = float64(binary.LittleEndian.Uint16(data[at*2:])) / 0xFFFF
+			}
+			t[x][flip-y] = heightOf(v, scale) // Put 0,0 at bottom left.
+		}
+	}
+	if wrap {
+		t.wrap()
+	}
+	return t, nil
+}
+
+// ExportImage creates a 16 bit grayscale heightmap image from a topology
+// section. It is the reverse of ImportImage where heights between -scale
+// and scale are mapped to black through white. Heights outside the range
+// are clamped. Use png.Encode to save the image.
+func ExportImage(t Topo, scale float64) *image.Gray16 {
+	img := image.NewGray16(image.Rect(0, 0, len(t), len(t[0])))
+	flip := len(t[0]) - 1
+	for x := range t {
+		for y := range t[x] {
+			v := uint16(valueOf(t[x][flip-y], scale)*0xFFFF + 0.5)
+			img.SetGray16(x, y, color.Gray16{Y: v})
+		}
+	}
+	return img
+}
+
+// ExportRaw writes a topology section as a headerless RAW heightmap
+// of 8 or 16 bit values. It is the reverse of ImportRaw.
+func ExportRaw(w io.Writer, t Topo, bits int, scale float64) error {
+	if bits != 8 && bits != 16 {
+		return fmt.Errorf("ExportRaw: invalid %d bit heightmap", bits)
+	}
+	width, height := t.Size()
+	data := make([]byte, width*height*bits/8)
+	flip := height - 1
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			at, v := y*width+x, valueOf(t[x][flip-y], scale)
+			if bits == 16 {
+				binary.LittleEndian.PutUint16(data[at*2:], uint16(v*0xFFFF+0.5))
+			} else {
+				data[at] = uint8(v*0xFF + 0.5)
+			}
+		}
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("ExportRaw: %s", err)
+	}
+	return nil
+}
+
+// newHeights allocates a topology section with room for the
+// extra wrapping row and column if necessary.
+func newHeights(width, height int, wrap bool) Topo {
+	if wrap {
+		width, height = width+1, height+1
+	}
+	return NewTopo(uint(width), uint(height))
+}
+
+// heightOf maps a normalized 0 to 1 value to a height between
+// -scale and scale.
+func heightOf(v, scale float64) float64 { return (v*2 - 1) * scale }
+
+// valueOf maps a height between -scale and scale to a normalized
+// value between 0 and 1. It is the reverse of heightOf.
+func valueOf(h, scale float64) float64 {
+	if scale == 0 {
+		return 0.5
+	}
+	return math.Max(0, math.Min(1, (h/scale+1)*0.5))
+}
+
+// wrap copies the first row and column into the last row and column.
+func (t Topo) wrap() {
+	last, top := len(t)-1, len(t[0])-1
+	for x := range t {
+		t[x][top] = t[x][0]
+	}
+	copy(t[last], t[0])
+}
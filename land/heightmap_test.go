@@ -0,0 +1,73 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package land
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// Check that image heightmaps are scaled and flipped so 0,0 is bottom left.
+func TestImportImage(t *testing.T) {
+	img := image.NewGray16(image.Rect(0, 0, 3, 2))
+	img.SetGray16(0, 0, color.Gray16{Y: 0xFFFF}) // top left.
+	img.SetGray16(2, 1, color.Gray16{Y: 0x8000}) // bottom right.
+	topo := ImportImage(img, 10, false)
+	if x, y := topo.Size(); x != 3 || y != 2 {
+		t.Fatalf("Expected 3x2 topo, got %dx%d", x, y)
+	}
+	if topo[0][1] != 10 || topo[1][0] != -10 || int(topo[2][0]*1000) != 0 {
+		t.Errorf("Unexpected heights %v", topo)
+	}
+	wrapped := ImportImage(img, 10, true)
+	if x, y := wrapped.Size(); x != 4 || y != 3 || wrapped[3][2] != wrapped[0][0] || wrapped[3][1] != 10 {
+		t.Errorf("Expected wrapped 4x3 topo, got %dx%d %v", x, y, wrapped)
+	}
+}
+
+// Check 8 and 16 bit RAW heightmaps and invalid sizes.
+func TestImportRaw(t *testing.T) {
+	raw8 := []byte{0, 255, 255, 0}
+	topo, err := ImportRaw(bytes.NewReader(raw8), 2, 2, 8, 1, false)
+	if err != nil || topo[0][1] != -1 || topo[1][1] != 1 || topo[0][0] != 1 {
+		t.Errorf("Unexpected 8 bit heights %v %s", topo, err)
+	}
+	raw16 := []byte{0xFF, 0xFF, 0, 0} // little endian 0xFFFF, 0.
+	topo, err = ImportRaw(bytes.NewReader(raw16), 2, 1, 16, 2, true)
+	if err != nil || topo[0][0] != 2 || topo[1][0] != -2 || topo[2][0] != 2 || topo[2][1] != 2 {
+		t.Errorf("Unexpected 16 bit heights %v %s", topo, err)
+	}
+	if _, err = ImportRaw(bytes.NewReader(raw16), 2, 2, 16, 1, false); err == nil {
+		t.Errorf("Expected error for short data")
+	}
+}
+
+// Check that exported heightmaps import as the same topology.
+func TestExportImport(t *testing.T) {
+	topo := NewTopo(4, 3)
+	for x := range topo {
+		for y := range topo[x] {
+			topo[x][y] = math.Sin(float64(x*3+y)) * 2
+		}
+	}
+	img := ImportImage(ExportImage(topo, 2), 2, false)
+	buf := &bytes.Buffer{}
+	if err := ExportRaw(buf, topo, 16, 2); err != nil {
+		t.Fatalf("Export failed %s", err)
+	}
+	raw, err := ImportRaw(buf, 4, 3, 16, 2, false)
+	if err != nil {
+		t.Fatalf("Import failed %s", err)
+	}
+	for x := range topo {
+		for y := range topo[x] {
+			if math.Abs(img[x][y]-topo[x][y]) > 0.0001 || raw[x][y] != img[x][y] {
+				t.Fatalf("Expected height %f at %d,%d, got %f %f", topo[x][y], x, y, img[x][y], raw[x][y])
+			}
+		}
+	}
+}
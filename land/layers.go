@@ -0,0 +1,179 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package land
+
+// Noise generators are combined into layers to create different styles
+// of terrain. Fractal layers add octaves of finer detail, warping bends
+// features by offsetting one noise with another, and masks mix terrain
+// styles, ie: mountains that rise out of rolling hills:
+//     hills := land.Fbm(land.OpenSimplex(seed), 6, 2, 0.5)
+//     peaks := land.Ridged(land.OpenSimplex(seed+1), 6, 2, 0.5)
+//     mask := land.Scale(land.OpenSimplex(seed+2), 0.5, 1)
+//     n := land.Scale(land.Mask(hills, peaks, mask), 1.0/128, 1)
+//     topo.Noise(land.Warp(n, land.OpenSimplex(seed+3), 8), 0, 0)
+// Noise features from the generators are roughly one unit apart so
+// the final noise is scaled down to the height map resolution.
+// Based on:
+//    http://www.iquilezles.org/www/articles/warp/warp.htm
+//    Musgrave. Texturing and Modeling: A Procedural Approach. Chapter 16.
+
+import (
+	"math"
+)
+
+// Noise returns a noise value, roughly between -1 and 1, for any x, y
+// location. Noise is created by OpenSimplex or Simplex and combined
+// using Fbm, Ridged, Billow, Warp, Scale, Sum, and Mask.
+type Noise interface {
+	At(x, y float64) float64 // Noise value at x, y.
+}
+
+// Simplex creates 2D simplex noise using the same generator as Land.
+func Simplex(seed int64) Noise { return newNoise(seed) }
+
+// At implements Noise for the simplex noise generator.
+func (n *noise) At(x, y float64) float64 { return n.generate2D(x, y) }
+
+// Noise fills the topology section with noise values. Point x, y is
+// set to the noise at x+xoff, y+yoff so that adjacent topology sections
+// can be created from the same noise.
+func (t Topo) Noise(n Noise, xoff, yoff int) {
+	for x := range t {
+		for y := range t[x] {
+			t[x][y] = n.At(float64(x+xoff), float64(y+yoff))
+		}
+	}
+}
+
+// Fbm (fractional brownian motion) adds octaves of noise where each
+// octave is lacunarity times the frequency and gain times the amplitude
+// of the previous octave. Typically lacunarity is 2 and gain is 0.5.
+func Fbm(n Noise, octaves int, lacunarity, gain float64) Noise {
+	return &fractal{n, octaves, lacunarity, gain, fbm}
+}
+
+// Ridged creates sharp mountain ridges by folding the noise at zero.
+// Each octave is weighted by the previous octave so that detail
+// collects on the ridges instead of the valleys. See Fbm.
+func Ridged(n Noise, octaves int, lacunarity, gain float64) Noise {
+	return &fractal{n, octaves, lacunarity, gain, ridged}
+}
+
+// Billow creates rounded hills and clouds from the absolute value
+// of the noise. See Fbm.
+func Billow(n Noise, octaves int, lacunarity, gain float64) Noise {
+	return &fractal{n, octaves, lacunarity, gain, billow}
+}
+
+// Fractal layer types.
+const (
+	fbm    = iota // Add octaves.
+	ridged        // Add folded octaves weighted by the previous octave.
+	billow        // Add absolute value octaves.
+)
+
+// fractal implements Noise by combining octaves of another noise.
+type fractal struct {
+	n          Noise   // Source noise.
+	octaves    int     // Number of layers.
+	lacunarity float64 // Frequency multiplier.
+	gain       float64 // Amplitude multiplier.
+	kind       int     // fbm, ridged, or billow.
+}
+
+// At implements Noise. The octaves are normalized by the total
+// amplitude so the results stay between -1 and 1.
+func (f *fractal) At(x, y float64) float64 {
+	sum, norm, freq, amp, weight := 0.0, 0.0, 1.0, 1.0, 1.0
+	for o := 0; o < f.octaves; o++ {
+		v := f.n.At(x*freq, y*freq)
+		switch f.kind {
+		case ridged:
+			v = 1 - math.Abs(v)
+			v *= v * weight
+			weight = math.Max(0, math.Min(1, v*2))
+		case billow:
+			v = 2*math.Abs(v) - 1
+		}
+		sum += v * amp
+		norm += amp
+		freq *= f.lacunarity
+		amp *= f.gain
+	}
+	if norm == 0 {
+		return 0
+	}
+	if f.kind == ridged {
+		return sum/norm*2 - 1 // ridged octaves are between 0 and 1.
+	}
+	return sum / norm
+}
+
+// Warp offsets the noise location by the warp noise times amount.
+// Warping bends and swirls features so they look less like noise.
+func Warp(n, warp Noise, amount float64) Noise { return &warped{n, warp, amount} }
+
+// warped implements Noise.
+type warped struct {
+	n, warp Noise   // Source noise and offset noise.
+	amount  float64 // Offset scale.
+}
+
+// At implements Noise. The y offset samples a different part
+// of the warp noise than the x offset.
+func (w *warped) At(x, y float64) float64 {
+	dx := w.warp.At(x, y)
+	dy := w.warp.At(x+5.2, y+1.3)
+	return w.n.At(x+dx*w.amount, y+dy*w.amount)
+}
+
+// Scale multiplies the noise location by frequency
+// and the noise value by amplitude.
+func Scale(n Noise, frequency, amplitude float64) Noise {
+	return &scaled{n, frequency, amplitude}
+}
+
+// scaled implements Noise.
+type scaled struct {
+	n               Noise   // Source noise.
+	freq, amplitude float64 // Location and value scales.
+}
+
+// At implements Noise.
+func (s *scaled) At(x, y float64) float64 { return s.n.At(x*s.freq, y*s.freq) * s.amplitude }
+
+// Sum adds the values of the given noise layers.
+func Sum(layers ...Noise) Noise { return summed(layers) }
+
+// summed implements Noise.
+type summed []Noise
+
+// At implements Noise.
+func (s summed) At(x, y float64) (sum float64) {
+	for _, n := range s {
+		sum += n.At(x, y)
+	}
+	return sum
+}
+
+// Mask mixes noise a with noise b. Mask values of -1 or less give
+// a, values of 1 or more give b, and values in between blend the two.
+func Mask(a, b, mask Noise) Noise { return &masked{a, b, mask} }
+
+// masked implements Noise.
+type masked struct {
+	a, b, mask Noise // Mask blends from a to b.
+}
+
+// At implements Noise.
+func (m *masked) At(x, y float64) float64 {
+	w := math.Max(0, math.Min(1, (m.mask.At(x, y)+1)*0.5))
+	switch w {
+	case 0:
+		return m.a.At(x, y)
+	case 1:
+		return m.b.At(x, y)
+	}
+	return m.a.At(x, y)*(1-w) + m.b.At(x, y)*w
+}
@@ -18,8 +18,8 @@ import (
 //    http://www.itn.liu.se/~stegu/simplexnoise/SimplexNoise.java
 // Modified with
 //	  https://github.com/Ian-Parberry/Tobler/
-// Be aware of patent on 3D and higher. Possibly move to OpenSimplex
-// or Wavelet noise.
+// Be aware of patent on 3D and higher. See OpenSimplex for an
+// alternative 2D noise.
 //    https://www.google.com/patents/US6867776
 type noise struct {
 	F2, F3    float64     // skewing and unskewing factors...
@@ -4,6 +4,7 @@
 package land
 
 import (
+	"math"
 	"testing"
 )
 
@@ -15,6 +16,41 @@ func TestSameSeed(t *testing.T) {
 	}
 }
 
+// Check that the noise layers are repeatable and stay within range.
+func TestNoiseLayers(t *testing.T) {
+	layers := map[string]Noise{
+		"simplex": Simplex(123),
+		"open":    OpenSimplex(123),
+		"fbm":     Fbm(OpenSimplex(123), 6, 2, 0.5),
+		"ridged":  Ridged(OpenSimplex(123), 6, 2, 0.5),
+		"billow":  Billow(OpenSimplex(123), 6, 2, 0.5),
+		"warp":    Warp(OpenSimplex(123), OpenSimplex(456), 4),
+		"mask":    Mask(OpenSimplex(123), Ridged(Simplex(123), 4, 2, 0.5), OpenSimplex(789)),
+	}
+	for name, n := range layers {
+		low, high := math.MaxFloat64, -math.MaxFloat64
+		for x := 0.0; x < 20; x += 0.13 {
+			for y := 0.0; y < 20; y += 0.17 {
+				v := n.At(x, y)
+				low, high = math.Min(low, v), math.Max(high, v)
+			}
+		}
+		if low < -1.01 || high > 1.01 || high-low < 0.5 {
+			t.Errorf("%s: expected varied noise between -1 and 1, got %f %f", name, low, high)
+		}
+	}
+	if OpenSimplex(1).At(3.3, 4.4) != OpenSimplex(1).At(3.3, 4.4) || OpenSimplex(1).At(3.3, 4.4) == OpenSimplex(2).At(3.3, 4.4) {
+		t.Errorf("Expected noise to depend on the seed")
+	}
+	a, b := NewTopo(8, 8), NewTopo(8, 8)
+	n := Sum(Scale(OpenSimplex(1), 0.1, 0.5), Scale(OpenSimplex(2), 0.3, 0.25))
+	a.Noise(n, 4, 0)
+	b.Noise(n, 0, 0)
+	if a[0][3] != b[4][3] {
+		t.Errorf("Expected offset topology sections to match")
+	}
+}
+
 // ============================================================================
 // benchmarks : go test -bench .
 //
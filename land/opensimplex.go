@@ -0,0 +1,112 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package land
+
+// OpenSimplex noise is similar to simplex noise without the patent
+// concerns, and with fewer directional artifacts than perlin noise.
+// This is the 2D version of the original by Kurt Spencer:
+//    https://gist.github.com/KdotJPG/b1270127455a94ac5d19
+
+import (
+	"math/rand"
+)
+
+// OpenSimplex 2D constants.
+const (
+	osStretch = -0.211324865405187 // (1/sqrt(2+1)-1)/2
+	osSquish  = 0.366025403784439  // (sqrt(2+1)-1)/2
+	osNorm    = 47.0               // scales results to -1, 1.
+)
+
+// osGradients are the 8 gradient directions, as x, y pairs,
+// that point roughly to the lattice vertices.
+var osGradients = [16]float64{5, 2, 2, 5, -5, 2, -2, 5, 5, -2, 2, -5, -5, -2, -2, -5}
+
+// OpenSimplex creates 2D OpenSimplex noise. The seed determines the
+// noise such that noise created from the same seed will be the same.
+// Noise features are roughly one unit apart.
+func OpenSimplex(seed int64) Noise { return newOpenSimplex(seed) }
+
+// openSimplex implements Noise.
+type openSimplex struct {
+	perm [256]byte // pseudo randomly ordered numbers 0-255
+}
+
+// newOpenSimplex shuffles the permutation table using the seed.
+func newOpenSimplex(seed int64) *openSimplex {
+	n := &openSimplex{}
+	random := rand.New(rand.NewSource(seed))
+	for cnt, index := range random.Perm(256) {
+		n.perm[cnt] = byte(index)
+	}
+	return n
+}
+
+// At implements Noise.
+func (n *openSimplex) At(x, y float64) float64 {
+
+	// place the input on the simplectic honeycomb.
+	stretch := (x + y) * osStretch
+	xs, ys := x+stretch, y+stretch
+	xsb, ysb := floor(xs), floor(ys)
+	squish := float64(xsb+ysb) * osSquish
+	dx0, dy0 := x-(float64(xsb)+squish), y-(float64(ysb)+squish)
+	xins, yins := xs-float64(xsb), ys-float64(ysb)
+	inSum := xins + yins
+
+	// contributions from (1,0) and (0,1).
+	value := n.contribute(xsb+1, ysb, dx0-1-osSquish, dy0-osSquish)
+	value += n.contribute(xsb, ysb+1, dx0-osSquish, dy0-1-osSquish)
+
+	// find the extra vertex and the closest of (0,0) or (1,1).
+	var xsv, ysv int
+	var dxv, dyv float64
+	if inSum <= 1 {
+		zins := 1 - inSum
+		switch {
+		case (zins > xins || zins > yins) && xins > yins:
+			xsv, ysv, dxv, dyv = xsb+1, ysb-1, dx0-1, dy0+1
+		case zins > xins || zins > yins:
+			xsv, ysv, dxv, dyv = xsb-1, ysb+1, dx0+1, dy0-1
+		default:
+			xsv, ysv, dxv, dyv = xsb+1, ysb+1, dx0-1-2*osSquish, dy0-1-2*osSquish
+		}
+	} else {
+		zins := 2 - inSum
+		switch {
+		case (zins < xins || zins < yins) && xins > yins:
+			xsv, ysv, dxv, dyv = xsb+2, ysb, dx0-2-2*osSquish, dy0-2*osSquish
+		case zins < xins || zins < yins:
+			xsv, ysv, dxv, dyv = xsb, ysb+2, dx0-2*osSquish, dy0-2-2*osSquish
+		default:
+			xsv, ysv, dxv, dyv = xsb, ysb, dx0, dy0
+		}
+		xsb, ysb = xsb+1, ysb+1
+		dx0, dy0 = dx0-1-2*osSquish, dy0-1-2*osSquish
+	}
+	value += n.contribute(xsb, ysb, dx0, dy0)
+	value += n.contribute(xsv, ysv, dxv, dyv)
+	return value / osNorm
+}
+
+// contribute returns the attenuated gradient contribution
+// of lattice point xsb, ysb at offset dx, dy.
+func (n *openSimplex) contribute(xsb, ysb int, dx, dy float64) float64 {
+	attn := 2 - dx*dx - dy*dy
+	if attn <= 0 {
+		return 0
+	}
+	index := n.perm[(int(n.perm[xsb&0xFF])+ysb)&0xFF] & 0x0E
+	attn *= attn
+	return attn * attn * (osGradients[index]*dx + osGradients[index+1]*dy)
+}
+
+// floor is a faster int(math.Floor(x)).
+func floor(x float64) int {
+	xi := int(x)
+	if x < float64(xi) {
+		return xi - 1
+	}
+	return xi
+}
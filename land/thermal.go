@@ -0,0 +1,100 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package land
+
+// Thermal erosion breaks up cliffs that are steeper than the material
+// can support. Material above the talus (slope) limit slumps down onto
+// lower neighbouring points until the slopes settle. Unlike hydraulic
+// erosion, thermal erosion is often run continually, so it can be
+// spread across many frames by eroding a limited number of points each
+// frame. Based on:
+//   Musgrave, Kolb, and Mace. The Synthesis and Rendering of Eroded
+//   Fractal Terrains. SIGGRAPH 1989.
+
+// Thermal runs thermal erosion passes over a topology section in small
+// steps, ie:
+//     th := land.NewThermal(topo, 0.02, 0.5)
+//     th.Step(1000) // each frame: erode the next 1000 points.
+type Thermal interface {
+	Step(points int) bool // True if a pass finished during the step.
+	Moved() float64       // Material moved by the last finished pass.
+}
+
+// NewThermal prepares thermal erosion for the given topology section.
+// Talus is the largest stable height difference between neighbouring
+// points. Rate, from 0 to 0.5, is the fraction of the excess height
+// that is moved each time a point is eroded.
+func NewThermal(t Topo, talus, rate float64) Thermal {
+	return &thermal{topo: t, talus: talus, rate: rate}
+}
+
+// Slump runs the given number of complete thermal erosion passes.
+// See NewThermal for talus and rate.
+func (t Topo) Slump(talus, rate float64, passes int) {
+	th := &thermal{topo: t, talus: talus, rate: rate}
+	sx, sy := t.Size()
+	for cnt := 0; cnt < passes; cnt++ {
+		th.Step(sx * sy)
+	}
+}
+
+// thermal implements Thermal.
+type thermal struct {
+	topo  Topo    // Height map being eroded.
+	talus float64 // Stable height difference.
+	rate  float64 // Fraction of excess moved.
+	at    int     // Next point in the current pass.
+	moved float64 // Material moved in the current pass.
+	last  float64 // Material moved in the last finished pass.
+}
+
+// Step implements Thermal. Points are eroded in x then y order.
+func (th *thermal) Step(points int) (done bool) {
+	sx, sy := th.topo.Size()
+	for cnt := 0; cnt < points; cnt++ {
+		th.moved += th.erode(th.at/sy, th.at%sy)
+		if th.at++; th.at >= sx*sy {
+			th.at, th.last, th.moved = 0, th.moved, 0
+			done = true
+		}
+	}
+	return done
+}
+
+// Moved implements Thermal.
+func (th *thermal) Moved() float64 { return th.last }
+
+// erode moves material from the point at x, y onto its lower neighbours.
+// The material is shared based on how far each neighbour is over the
+// talus limit. Returns the amount of material moved.
+func (th *thermal) erode(x, y int) float64 {
+	t := th.topo
+	sx, sy := t.Size()
+	h := t[x][y]
+	neighbours := [4][2]int{{x - 1, y}, {x + 1, y}, {x, y - 1}, {x, y + 1}}
+	most, excess := 0.0, 0.0 // largest and total height over the talus.
+	for _, n := range neighbours {
+		if n[0] >= 0 && n[0] < sx && n[1] >= 0 && n[1] < sy {
+			if d := h - t[n[0]][n[1]]; d > th.talus {
+				excess += d - th.talus
+				if d > most {
+					most = d
+				}
+			}
+		}
+	}
+	if excess == 0 {
+		return 0
+	}
+	move := th.rate * (most - th.talus)
+	for _, n := range neighbours {
+		if n[0] >= 0 && n[0] < sx && n[1] >= 0 && n[1] < sy {
+			if d := h - t[n[0]][n[1]]; d > th.talus {
+				t[n[0]][n[1]] += move * (d - th.talus) / excess
+			}
+		}
+	}
+	t[x][y] -= move
+	return move
+}
@@ -0,0 +1,36 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package land
+
+import (
+	"math"
+	"testing"
+)
+
+// Check that a spike slumps without losing material
+// and that incremental steps match complete passes.
+func TestThermal(t *testing.T) {
+	a, b := NewTopo(8, 8), NewTopo(8, 8)
+	a[4][4], b[4][4] = 1, 1
+	a.Slump(0.1, 0.5, 20)
+	th, passes := NewThermal(b, 0.1, 0.5), 0
+	for passes < 20 {
+		if th.Step(16) { // 4 steps each pass.
+			passes++
+		}
+	}
+	if math.Abs(total(a)-1) > 1e-9 || a[4][4] > 0.5 {
+		t.Errorf("Expected spike to slump keeping material, got %f %f", a[4][4], total(a))
+	}
+	for x := range a {
+		for y := range a[x] {
+			if a[x][y] != b[x][y] {
+				t.Fatalf("Expected matching heights at %d %d, got %f %f", x, y, a[x][y], b[x][y])
+			}
+		}
+	}
+	if th.Moved() <= 0 {
+		t.Errorf("Expected material moved by the last pass")
+	}
+}
@@ -11,7 +11,8 @@ import (
 //         Need a clean design *and* need to justify the additionally code
 //         complexity against the render output benefits.
 
-// Layer is used to render to a 1024x1024 sized frame buffer based texture.
+// Layer is used to render to a square, default 1024x1024, sized frame
+// buffer based texture.
 // A layer represents the output of an extra render pass where objects drawn
 // to this off screen texture are used as input for a later render pass.
 type Layer interface{}
@@ -28,14 +29,18 @@ type layer struct {
 	bid  uint32   // Framebuffer id. Default 0 for default framebuffer.
 	db   uint32   // Depth renderbuffer.
 	attr int      // What type of layer. Full IMAGE or SHADOW_MAP.
+	size int32    // Texture width and height in pixels.
 	vp   *lin.M4  // light view-projection layer transform.
 	bm   *lin.M4  // bias matrix.
 	tex  *texture // place holder for rendered texture. Created on GPU.
 }
 
+// layerSize is the default layer texture size.
+const layerSize = 1024
+
 // newLayer creates the framebuffer needed to render to a texture.
 func newLayer(attr int) *layer {
-	l := &layer{attr: attr}
+	l := &layer{attr: attr, size: layerSize}
 	l.vp = &lin.M4{}
 	l.bm = &lin.M4{
 		Xx: 0.5, Xy: 0.0, Xz: 0.0, Xw: 0.0,
@@ -0,0 +1,77 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"container/heap"
+)
+
+// Lifetimes let short lived Pov's, ie: projectiles, dispose themselves
+// using Pov.DisposeAfter. Expired Pov's are disabled immediately and
+// then disposed over the following updates, at most disposeBudget each
+// update, so that many expiring at once don't stall an update.
+
+// disposeBudget is the most expired Pov's disposed in one update.
+const disposeBudget = 64
+
+// lifetimes tracks the Pov's waiting to expire and the expired Pov's
+// waiting to be disposed.
+type lifetimes struct {
+	now     float64  // Game time in seconds.
+	waiting expiries // Pov's ordered by expiry time.
+	expired []*pov   // Disabled Pov's waiting for disposal.
+}
+
+// expiry is a Pov and when it expires.
+type expiry struct {
+	p  *pov
+	at float64 // Game time to expire.
+}
+
+// expiries is a min heap on expiry time. Implements heap.Interface.
+type expiries []expiry
+
+func (e expiries) Len() int            { return len(e) }
+func (e expiries) Less(i, j int) bool  { return e[i].at < e[j].at }
+func (e expiries) Swap(i, j int)       { e[i], e[j] = e[j], e[i] }
+func (e *expiries) Push(x interface{}) { *e = append(*e, x.(expiry)) }
+func (e *expiries) Pop() interface{} {
+	old := *e
+	x := old[len(old)-1]
+	*e = old[:len(old)-1]
+	return x
+}
+
+// disposeAfter schedules p for disposal after the given seconds.
+// Later calls replace earlier ones. Negative seconds cancel.
+func (eng *engine) disposeAfter(p *pov, seconds float64) {
+	if seconds < 0 {
+		p.expires = -1 // no longer matches any waiting expiry.
+		return
+	}
+	p.expires = eng.lives.now + seconds
+	heap.Push(&eng.lives.waiting, expiry{p: p, at: p.expires})
+}
+
+// updateLifetimes advances game time, disabling Pov's that have
+// expired and disposing up to disposeBudget expired Pov's.
+func (eng *engine) updateLifetimes(dts float64) {
+	lt := eng.lives
+	lt.now += dts
+	for len(lt.waiting) > 0 && lt.waiting[0].at <= lt.now {
+		e := heap.Pop(&lt.waiting).(expiry)
+		if e.p.expires == e.at && eng.povs[e.p.eid] == e.p { // still scheduled.
+			e.p.SetEnabled(false)
+			lt.expired = append(lt.expired, e.p)
+		}
+	}
+	cnt := 0
+	for ; cnt < len(lt.expired) && cnt < disposeBudget; cnt++ {
+		if p := lt.expired[cnt]; eng.povs[p.eid] == p { // not already disposed.
+			eng.dispose(p, PovNode)
+		}
+		lt.expired[cnt] = nil
+	}
+	lt.expired = append(lt.expired[:0], lt.expired[cnt:]...)
+}
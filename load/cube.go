@@ -0,0 +1,144 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package load
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"math"
+)
+
+// CubeFaces are the image name suffixes for the six faces of a cube
+// map in the order expected by render.BindCube: +X, -X, +Y, -Y, +Z, -Z.
+// The faces of the cube map "sky" are the images "sky_px.png",
+// "sky_nx.png", "sky_py.png", "sky_ny.png", "sky_pz.png", "sky_nz.png".
+var CubeFaces = []string{"_px", "_nx", "_py", "_ny", "_pz", "_nz"}
+
+// cube loads the six face images of a cube map. A single equirectangular
+// image, ie: "sky.png", is converted to cube faces when there are no face
+// images. Faces are expected to be square and the same size.
+func (l *loader) cube(name string) (faces []image.Image, err error) {
+	for _, suffix := range CubeFaces {
+		face, ferr := l.png(name + suffix)
+		if ferr != nil {
+			if len(faces) > 0 {
+				return nil, fmt.Errorf("cube %s missing face %s", name, suffix)
+			}
+			break // no faces, try an equirectangular image.
+		}
+		faces = append(faces, face)
+	}
+	if len(faces) == 0 {
+		equirect, err := l.png(name)
+		if err != nil {
+			return nil, err
+		}
+		return EquirectToCube(equirect, 0), nil
+	}
+	size := faces[0].Bounds().Size()
+	for cnt, face := range faces {
+		if fs := face.Bounds().Size(); fs.X != fs.Y || fs != size {
+			return nil, fmt.Errorf("cube %s face %s not square or size %d", name, CubeFaces[cnt], size.X)
+		}
+	}
+	return faces, nil
+}
+
+// EquirectToCube converts an equirectangular, or latitude-longitude,
+// panorama into the six cube map faces in CubeFaces order. The panorama
+// is expected to be twice as wide as it is high, with up at the top and
+// the -Z direction at the center. Faces are size by size pixels where
+// size 0 uses a quarter of the panorama width.
+//
+// This does the conversion once on the CPU. Alternatively the sky and
+// reflection shaders ending in "eq" sample a panorama texture directly
+// with the same mapping, doing the conversion on the GPU for every pixel.
+func EquirectToCube(equirect image.Image, size int) []image.Image {
+	bounds := equirect.Bounds()
+	src, ok := equirect.(*image.NRGBA)
+	if !ok || bounds.Min != (image.Point{}) {
+		src = image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+		draw.Draw(src, src.Rect, equirect, bounds.Min, draw.Src)
+	}
+	if size <= 0 {
+		size = src.Rect.Dx() / 4
+		if size < 1 {
+			size = 1
+		}
+	}
+	faces := make([]image.Image, len(CubeFaces))
+	for face := range faces {
+		dst := image.NewNRGBA(image.Rect(0, 0, size, size))
+		for row := 0; row < size; row++ {
+			for col := 0; col < size; col++ {
+				sc := 2*(float64(col)+0.5)/float64(size) - 1
+				tc := 2*(float64(row)+0.5)/float64(size) - 1
+				x, y, z := cubeDir(face, sc, tc)
+				u, v := equirectUV(x, y, z)
+				at := dst.PixOffset(col, row)
+				sampleNRGBA(src, u, v, dst.Pix[at:at+4])
+			}
+		}
+		faces[face] = dst
+	}
+	return faces
+}
+
+// cubeDir returns the direction for face coordinates sc, tc in the
+// range -1 to 1, from the upper left corner of the face image.
+// The mapping is the OpenGL cube map face selection run backwards.
+func cubeDir(face int, sc, tc float64) (x, y, z float64) {
+	switch face {
+	case 0: // +X
+		return 1, -tc, -sc
+	case 1: // -X
+		return -1, -tc, sc
+	case 2: // +Y
+		return sc, 1, tc
+	case 3: // -Y
+		return sc, -1, -tc
+	case 4: // +Z
+		return sc, -tc, 1
+	default: // -Z
+		return -sc, -tc, -1
+	}
+}
+
+// equirectUV returns the panorama texture coordinates, from the upper
+// left, for the given direction. Matches the GPU "eq" shaders.
+func equirectUV(x, y, z float64) (u, v float64) {
+	length := math.Sqrt(x*x + y*y + z*z)
+	u = 0.5 + math.Atan2(x, -z)/(2*math.Pi)
+	v = math.Acos(y/length) / math.Pi
+	return u, v
+}
+
+// sampleNRGBA sets pixel to the bilinear filtered color at u, v.
+// The panorama wraps horizontally and is clamped vertically.
+func sampleNRGBA(img *image.NRGBA, u, v float64, pixel []uint8) {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	fx, fy := u*float64(w)-0.5, v*float64(h)-0.5
+	x0, y0 := int(math.Floor(fx)), int(math.Floor(fy))
+	ax, ay := fx-float64(x0), fy-float64(y0)
+	wrap := func(x int) int { return ((x % w) + w) % w }
+	clamp := func(y int) int {
+		switch {
+		case y < 0:
+			return 0
+		case y >= h:
+			return h - 1
+		}
+		return y
+	}
+	c00 := img.PixOffset(wrap(x0), clamp(y0))
+	c10 := img.PixOffset(wrap(x0+1), clamp(y0))
+	c01 := img.PixOffset(wrap(x0), clamp(y0+1))
+	c11 := img.PixOffset(wrap(x0+1), clamp(y0+1))
+	for cnt := 0; cnt < 4; cnt++ {
+		top := float64(img.Pix[c00+cnt])*(1-ax) + float64(img.Pix[c10+cnt])*ax
+		bot := float64(img.Pix[c01+cnt])*(1-ax) + float64(img.Pix[c11+cnt])*ax
+		pixel[cnt] = uint8(top*(1-ay) + bot*ay + 0.5)
+	}
+}
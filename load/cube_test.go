@@ -0,0 +1,81 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package load
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+	"testing/fstest"
+)
+
+// Check that panoramas are sampled in the direction of each cube face.
+func TestEquirectToCube(t *testing.T) {
+	pano := image.NewNRGBA(image.Rect(0, 0, 64, 32))
+	for y := 0; y < 32; y++ {
+		for x := 0; x < 64; x++ {
+			c := color.NRGBA{0, 0, 255, 255} // below the horizon.
+			switch {
+			case y < 10:
+				c = color.NRGBA{255, 0, 0, 255} // sky.
+			case y < 24 && x >= 24 && x < 40:
+				c = color.NRGBA{0, 255, 0, 255} // straight ahead.
+			case y < 24:
+				c = color.NRGBA{255, 255, 255, 255} // horizon.
+			}
+			pano.SetNRGBA(x, y, c)
+		}
+	}
+	faces := EquirectToCube(pano, 0)
+	if len(faces) != 6 || faces[0].Bounds().Dx() != 16 || faces[0].Bounds().Dy() != 16 {
+		t.Fatalf("Expected 6 16x16 faces")
+	}
+	center := func(face int) color.NRGBA { return faces[face].(*image.NRGBA).NRGBAAt(8, 8) }
+	if c := center(2); c.R != 255 || c.G != 0 {
+		t.Errorf("Expected sky on +Y, got %v", c)
+	}
+	if c := center(3); c.B != 255 || c.R != 0 {
+		t.Errorf("Expected ground on -Y, got %v", c)
+	}
+	if c := center(5); c.G != 255 || c.R != 0 {
+		t.Errorf("Expected ahead on -Z, got %v", c)
+	}
+	for _, face := range []int{0, 1, 4} {
+		if c := center(face); c.R != 255 || c.G != 255 || c.B != 255 {
+			t.Errorf("Expected horizon on face %d, got %v", face, c)
+		}
+	}
+	if c := faces[0].(*image.NRGBA).NRGBAAt(8, 0); c.R != 255 || c.G != 0 {
+		t.Errorf("Expected sky at the top of +X, got %v", c)
+	}
+}
+
+// Check that cube maps load from face images or a panorama.
+func TestLoadCube(t *testing.T) {
+	encode := func(w, h int) *fstest.MapFile {
+		buff := &bytes.Buffer{}
+		png.Encode(buff, image.NewNRGBA(image.Rect(0, 0, w, h)))
+		return &fstest.MapFile{Data: buff.Bytes()}
+	}
+	fsys := fstest.MapFS{"images/pano.png": encode(8, 4), "images/half_px.png": encode(2, 2)}
+	for _, suffix := range CubeFaces {
+		fsys["images/box"+suffix+".png"] = encode(2, 2)
+		fsys["images/odd"+suffix+".png"] = encode(2, 2)
+	}
+	fsys["images/odd_nz.png"] = encode(4, 4)
+	l := newLoader().addSource(FSSource(fsys))
+	if faces, err := l.cube("box"); err != nil || len(faces) != 6 {
+		t.Errorf("Expected six faces %s", err)
+	}
+	if faces, err := l.cube("pano"); err != nil || len(faces) != 6 || faces[0].Bounds().Dx() != 2 {
+		t.Errorf("Expected six converted faces %s", err)
+	}
+	for _, name := range []string{"half", "odd", "none"} {
+		if _, err := l.cube(name); err == nil {
+			t.Errorf("Expected error for %s", name)
+		}
+	}
+}
@@ -0,0 +1,1014 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package load
+
+// glTF: GL Transmission Format, version 2.0.
+// A JSON format, with binary buffers, for 3D scenes that is exported by
+// most modelling tools. Both .gltf files, with embedded or separate
+// buffers, and binary .glb files are supported. See:
+//    https://github.com/KhronosGroup/glTF/tree/master/specification/2.0
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"image"
+	_ "image/jpeg" // glTF images are png or jpeg.
+	"io"
+	"io/ioutil"
+	"math"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/gazed/vu/math/lin"
+)
+
+// GltfData is model data from glTF files. It is intended for populating
+// a hierarchy of static and animated models. The node hierarchy is kept
+// as is while meshes, materials, and textures are shared by index.
+type GltfData struct {
+	Name      string         // Data name from the file name.
+	Nodes     []GltfNode     // All nodes, see Roots for the hierarchy.
+	Roots     []int          // Top level nodes of the default scene.
+	Meshes    []GltfMesh     // Meshes referenced by nodes.
+	Materials []GltfMaterial // Mesh part materials, then any default.
+	Textures  []GltfTexture  // Textures referenced by materials.
+	Skins     []GltfSkin     // Joints and animations for skinned meshes.
+}
+
+// GltfNode is a named transform in the node hierarchy. Nodes can have
+// a mesh, and skinned meshes have a skin. Skin joints are nodes that
+// are animated by the skin.
+type GltfNode struct {
+	Name     string     // Node name, may be empty.
+	Loc      [3]float64 // Translation.
+	Rot      [4]float64 // Quaternion X, Y, Z, W.
+	Scale    [3]float64 // Per axis scale.
+	Mesh     int        // Mesh index, -1 for none.
+	Skin     int        // Skin index, -1 for none.
+	Joint    bool       // True for skin joints.
+	Children []int      // Child node indexes.
+}
+
+// GltfMesh is one or more mesh parts, where each part has its own
+// material.
+type GltfMesh struct {
+	Name  string     // Mesh name, may be empty.
+	Parts []GltfPart // Mesh triangles grouped by material.
+}
+
+// GltfPart is triangle mesh data. Blend indicies indicate which skin
+// joint influences a vertex, up to 4 joints per vertex. Blend weights
+// give the amount of influence of a joint on a vertex.
+type GltfPart struct {
+	V        []float32   // Vertex positions.  Arranged as [][3]float32
+	N        []float32   // Vertex normals.    Arranged as [][3]float32
+	X        []float32   // Vertex tangents.   Arranged as [][4]float32
+	T        [][]float32 // Tex coords by set. Arranged as [][2]float32
+	B        []byte      // Blend indicies.    Arranged as [][4]byte
+	W        []byte      // Blend weights.     Arranged as [][4]byte
+	F        []uint32    // Triangle faces.    Arranged as [][3]uint32
+	Material int         // Material index. See GltfData.Materials.
+}
+
+// GltfMaterial is a metallic-roughness PBR material. Texture values
+// are multiplied by the material factors. Alpha is ignored for OPAQUE
+// materials, compared to AlphaCutoff for MASK materials, and blended
+// for BLEND materials.
+type GltfMaterial struct {
+	Name        string     // Material name, may be empty.
+	Color       [4]float32 // Base color RGBA.
+	Metallic    float32    // 1 for metal, 0 for dielectric.
+	Roughness   float32    // 1 for fully rough, 0 for smooth.
+	Emissive    [3]float32 // Emitted RGB.
+	Occlusion   float32    // Occlusion texture strength.
+	AlphaMode   string     // OPAQUE, MASK, or BLEND.
+	AlphaCutoff float32    // Alpha below this is not drawn in MASK mode.
+	DoubleSided bool       // True if back faces are drawn.
+
+	// Material textures. Metal and roughness are the blue and green
+	// values of MetalRoughTex. Occlusion is the red value of OcclusionTex.
+	ColorTex      GltfTexRef // Base color.
+	MetalRoughTex GltfTexRef // Metallic and roughness.
+	NormalTex     GltfTexRef // Tangent space normals.
+	OcclusionTex  GltfTexRef // Ambient occlusion.
+	EmissiveTex   GltfTexRef // Emitted color.
+}
+
+// GltfTexRef is a material texture and the tex coord set that maps it.
+type GltfTexRef struct {
+	Texture int // Texture index, -1 for none.
+	UV      int // Tex coord set index into GltfPart.T.
+}
+
+// GltfTexture is a decoded texture image. Img is nil for images that
+// could not be found within the file, ie: for unsupported extensions.
+type GltfTexture struct {
+	Name   string      // Image name, file name, or data name and index.
+	Img    image.Image // Decoded png or jpeg image.
+	Repeat bool        // True to repeat, false to clamp.
+}
+
+// GltfSkin is the data for skinned animated meshes. Joints are ordered
+// so that parents come before children and blend indicies are updated
+// to match. Animations are sampled into frames like IqData. Only joint
+// transforms are animated and skins without animations have no frames.
+type GltfSkin struct {
+	Name   string    // Skin name, may be empty.
+	Nodes  []int     // Node index of each joint.
+	Joints []int32   // Joint parent information for each joint.
+	Names  []string  // Joint names for each joint.
+	Bases  []*lin.M4 // Joint base pose model space transforms.
+	Anims  []IqAnim  // One or more animations.
+	Frames []*lin.M4 // Animation transforms: [NumFrames][NumJoints].
+}
+
+// gltfRate is the frames per second of sampled animations.
+const gltfRate = 30
+
+// =============================================================================
+
+// gltf loads glTF model files, looking for a .gltf file and then a .glb file.
+func (l *loader) gltf(name string) (gd *GltfData, err error) {
+	gd = &GltfData{Name: name}
+	var file io.ReadCloser
+	if file, err = l.getResource(l.dir[mod], name+".gltf"); err == nil {
+		defer file.Close()
+		return l.loadGltf(file, gd, false)
+	}
+	if file, err = l.getResource(l.dir[mod], name+".glb"); err == nil {
+		defer file.Close()
+		return l.loadGltf(file, gd, true)
+	}
+	return gd, err
+}
+
+// loadGltf reads a valid glTF or binary glTF file into a GltfData structure.
+func (l *loader) loadGltf(file io.Reader, gd *GltfData, glb bool) (*GltfData, error) {
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		return gd, fmt.Errorf("Invalid .gltf file: %s", err)
+	}
+	var bin []byte // binary chunk of .glb files.
+	if glb {
+		if data, bin, err = l.glbChunks(data); err != nil {
+			return gd, err
+		}
+	}
+	gf := &gltfFile{}
+	if err := json.Unmarshal(data, gf); err != nil {
+		return gd, fmt.Errorf("Invalid .gltf file: %s", err)
+	}
+	if !strings.HasPrefix(gf.Asset.Version, "2.") {
+		return gd, fmt.Errorf("Expecting .gltf version 2.0, got : %s", gf.Asset.Version)
+	}
+
+	// Get the buffer data into memory.
+	for cnt, b := range gf.Buffers {
+		var buff []byte
+		switch {
+		case b.URI == "" && cnt == 0 && bin != nil:
+			buff = bin
+		case b.URI != "":
+			if buff, err = l.gltfURI(b.URI); err != nil {
+				return gd, err
+			}
+		}
+		if len(buff) < b.ByteLength {
+			return gd, fmt.Errorf("Missing .gltf buffer %d data", cnt)
+		}
+		gf.buffs = append(gf.buffs, buff)
+	}
+	if err := l.loadGltfNodes(gf, gd); err != nil {
+		return gd, err
+	}
+	if err := l.loadGltfMeshes(gf, gd); err != nil {
+		return gd, err
+	}
+	if err := l.loadGltfMaterials(gf, gd); err != nil {
+		return gd, err
+	}
+	if err := l.loadGltfSkins(gf, gd); err != nil {
+		return gd, err
+	}
+	return gd, nil
+}
+
+// glbChunks returns the JSON and binary chunks of a binary glTF file.
+func (l *loader) glbChunks(data []byte) (js, bin []byte, err error) {
+	le := binary.LittleEndian
+	if len(data) < 20 || string(data[0:4]) != "glTF" {
+		return nil, nil, fmt.Errorf("Invalid .glb header magic")
+	}
+	if version := le.Uint32(data[4:]); version != 2 {
+		return nil, nil, fmt.Errorf("Expecting .glb version 2, got : %d", version)
+	}
+	for at := 12; at+8 <= len(data); {
+		size, kind := int(le.Uint32(data[at:])), le.Uint32(data[at+4:])
+		if at+8+size > len(data) {
+			return nil, nil, fmt.Errorf("Corrupt .glb file")
+		}
+		chunk := data[at+8 : at+8+size]
+		switch kind {
+		case 0x4E4F534A: // JSON
+			js = chunk
+		case 0x004E4942: // BIN
+			bin = chunk
+		}
+		at += 8 + size
+	}
+	if js == nil {
+		return nil, nil, fmt.Errorf("Invalid .glb file: no JSON chunk")
+	}
+	return js, bin, nil
+}
+
+// gltfURI returns the data from an embedded data URI, or from a file
+// found with the model files.
+func (l *loader) gltfURI(uri string) ([]byte, error) {
+	if strings.HasPrefix(uri, "data:") {
+		comma := strings.Index(uri, ",")
+		if comma < 0 || !strings.HasSuffix(uri[:comma], ";base64") {
+			return nil, fmt.Errorf("Unsupported .gltf data uri")
+		}
+		return base64.StdEncoding.DecodeString(uri[comma+1:])
+	}
+	name, err := url.QueryUnescape(uri)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid .gltf uri %s: %s", uri, err)
+	}
+	file, err := l.getResource(l.dir[mod], name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ioutil.ReadAll(file)
+}
+
+// loadGltfNodes gets the node transforms and the default scene nodes.
+func (l *loader) loadGltfNodes(gf *gltfFile, gd *GltfData) error {
+	hasParent := make([]bool, len(gf.Nodes))
+	for cnt, n := range gf.Nodes {
+		node := GltfNode{Name: n.Name, Mesh: -1, Skin: -1, Children: n.Children}
+		node.Rot, node.Scale = [4]float64{0, 0, 0, 1}, [3]float64{1, 1, 1}
+		if n.Mesh != nil {
+			if node.Mesh = *n.Mesh; node.Mesh < 0 || node.Mesh >= len(gf.Meshes) {
+				return fmt.Errorf("Invalid .gltf node %d mesh", cnt)
+			}
+		}
+		if n.Skin != nil {
+			if node.Skin = *n.Skin; node.Skin < 0 || node.Skin >= len(gf.Skins) {
+				return fmt.Errorf("Invalid .gltf node %d skin", cnt)
+			}
+		}
+		if len(n.Matrix) == 16 {
+			gltfDecompose(n.Matrix, &node)
+		} else {
+			copy(node.Loc[:], n.Translation)
+			if len(n.Rotation) == 4 {
+				copy(node.Rot[:], n.Rotation)
+			}
+			if len(n.Scale) == 3 {
+				copy(node.Scale[:], n.Scale)
+			}
+		}
+		for _, child := range n.Children {
+			if child < 0 || child >= len(gf.Nodes) || hasParent[child] {
+				return fmt.Errorf("Invalid .gltf node %d children", cnt)
+			}
+			hasParent[child] = true
+		}
+		gd.Nodes = append(gd.Nodes, node)
+	}
+
+	// Use the default scene, or all top level nodes if there are no scenes.
+	switch scene := gf.Scene; {
+	case len(gf.Scenes) > 0:
+		if scene < 0 || scene >= len(gf.Scenes) {
+			return fmt.Errorf("Invalid .gltf scene %d", scene)
+		}
+		for _, root := range gf.Scenes[scene].Nodes {
+			if root < 0 || root >= len(gf.Nodes) || hasParent[root] {
+				return fmt.Errorf("Invalid .gltf scene node %d", root)
+			}
+			gd.Roots = append(gd.Roots, root)
+		}
+	default:
+		for cnt := range gf.Nodes {
+			if !hasParent[cnt] {
+				gd.Roots = append(gd.Roots, cnt)
+			}
+		}
+	}
+	return nil
+}
+
+// gltfDecompose sets the node translation, rotation, and scale from
+// a column major transform matrix. Matrices with shear can't be
+// represented exactly.
+func gltfDecompose(m []float64, n *GltfNode) {
+	n.Loc = [3]float64{m[12], m[13], m[14]}
+	r := &lin.M3{
+		Xx: m[0], Xy: m[4], Xz: m[8],
+		Yx: m[1], Yy: m[5], Yz: m[9],
+		Zx: m[2], Zy: m[6], Zz: m[10]}
+	sx := math.Sqrt(r.Xx*r.Xx + r.Yx*r.Yx + r.Zx*r.Zx)
+	sy := math.Sqrt(r.Xy*r.Xy + r.Yy*r.Yy + r.Zy*r.Zy)
+	sz := math.Sqrt(r.Xz*r.Xz + r.Yz*r.Yz + r.Zz*r.Zz)
+	if r.Det() < 0 {
+		sx = -sx // mirrored.
+	}
+	if n.Scale = [3]float64{sx, sy, sz}; sx == 0 || sy == 0 || sz == 0 {
+		return // no rotation for collapsed transforms.
+	}
+	r.Xx, r.Yx, r.Zx = r.Xx/sx, r.Yx/sx, r.Zx/sx
+	r.Xy, r.Yy, r.Zy = r.Xy/sy, r.Yy/sy, r.Zy/sy
+	r.Xz, r.Yz, r.Zz = r.Xz/sz, r.Yz/sz, r.Zz/sz
+
+	// rotation matrix to quaternion, see lin.Q.SetM.
+	q := &lin.Q{}
+	switch trace := r.Xx + r.Yy + r.Zz; {
+	case trace > 0:
+		s := math.Sqrt(trace+1) * 2
+		q.W, q.X, q.Y, q.Z = 0.25*s, (r.Zy-r.Yz)/s, (r.Xz-r.Zx)/s, (r.Yx-r.Xy)/s
+	case r.Xx > r.Yy && r.Xx > r.Zz:
+		s := math.Sqrt(r.Xx-r.Yy-r.Zz+1) * 2
+		q.W, q.X, q.Y, q.Z = (r.Zy-r.Yz)/s, 0.25*s, (r.Xy+r.Yx)/s, (r.Xz+r.Zx)/s
+	case r.Yy > r.Zz:
+		s := math.Sqrt(r.Yy-r.Xx-r.Zz+1) * 2
+		q.W, q.X, q.Y, q.Z = (r.Xz-r.Zx)/s, (r.Xy+r.Yx)/s, 0.25*s, (r.Yz+r.Zy)/s
+	default:
+		s := math.Sqrt(r.Zz-r.Xx-r.Yy+1) * 2
+		q.W, q.X, q.Y, q.Z = (r.Yx-r.Xy)/s, (r.Xz+r.Zx)/s, (r.Yz+r.Zy)/s, 0.25*s
+	}
+	q.Unit()
+	n.Rot = [4]float64{q.X, q.Y, q.Z, q.W}
+}
+
+// loadGltfMeshes gets the vertex data for each mesh part.
+func (l *loader) loadGltfMeshes(gf *gltfFile, gd *GltfData) (err error) {
+	for mcnt, m := range gf.Meshes {
+		mesh := GltfMesh{Name: m.Name}
+		for pcnt, p := range m.Primitives {
+			if p.Mode != nil && *p.Mode != 4 {
+				return fmt.Errorf("Expecting .gltf triangles for mesh %d part %d", mcnt, pcnt)
+			}
+			pos, ok := p.Attributes["POSITION"]
+			if !ok {
+				return fmt.Errorf("Minimally need vertex data for .gltf mesh %d part %d", mcnt, pcnt)
+			}
+			part := GltfPart{Material: -1}
+			if part.V, err = gf.floats(pos, 3); err != nil {
+				return err
+			}
+			numVerts := len(part.V) / 3
+			if index, ok := p.Attributes["NORMAL"]; ok {
+				if part.N, err = gf.floats(index, 3); err != nil {
+					return err
+				}
+			}
+			if index, ok := p.Attributes["TANGENT"]; ok {
+				if part.X, err = gf.floats(index, 4); err != nil {
+					return err
+				}
+			}
+			for set := 0; ; set++ {
+				index, ok := p.Attributes[fmt.Sprintf("TEXCOORD_%d", set)]
+				if !ok {
+					break
+				}
+				uvs, err := gf.floats(index, 2)
+				if err != nil {
+					return err
+				}
+				part.T = append(part.T, uvs)
+			}
+			if index, ok := p.Attributes["JOINTS_0"]; ok {
+				joints, err := gf.ints(index, 4)
+				if err != nil {
+					return err
+				}
+				part.B = make([]byte, len(joints))
+				for cnt, joint := range joints {
+					if joint > 255 {
+						return fmt.Errorf("Too many .gltf joints for mesh %d part %d", mcnt, pcnt)
+					}
+					part.B[cnt] = byte(joint)
+				}
+			}
+			if index, ok := p.Attributes["WEIGHTS_0"]; ok {
+				weights, err := gf.values(index, 4)
+				if err != nil {
+					return err
+				}
+				part.W = make([]byte, len(weights))
+				for cnt, weight := range weights {
+					part.W[cnt] = byte(math.Max(0, math.Min(255, math.Floor(weight*255+0.5))))
+				}
+			}
+			if p.Indices != nil {
+				if part.F, err = gf.ints(*p.Indices, 1); err != nil {
+					return err
+				}
+			} else {
+				part.F = make([]uint32, numVerts)
+				for cnt := range part.F {
+					part.F[cnt] = uint32(cnt)
+				}
+			}
+			for _, face := range part.F {
+				if int(face) >= numVerts {
+					return fmt.Errorf("Invalid .gltf face index for mesh %d part %d", mcnt, pcnt)
+				}
+			}
+			if p.Material != nil {
+				if part.Material = *p.Material; part.Material < 0 || part.Material >= len(gf.Materials) {
+					return fmt.Errorf("Invalid .gltf material for mesh %d part %d", mcnt, pcnt)
+				}
+			}
+			mesh.Parts = append(mesh.Parts, part)
+		}
+		gd.Meshes = append(gd.Meshes, mesh)
+	}
+	return nil
+}
+
+// loadGltfMaterials gets the materials and decodes their texture images.
+func (l *loader) loadGltfMaterials(gf *gltfFile, gd *GltfData) error {
+	imgs := make([]image.Image, len(gf.Images))
+	for cnt, gi := range gf.Images {
+		var data []byte
+		var err error
+		switch {
+		case gi.BufferView != nil:
+			data, _, err = gf.view(*gi.BufferView)
+		case gi.URI != "":
+			data, err = l.gltfURI(gi.URI)
+		}
+		if err != nil {
+			return err
+		}
+		if imgs[cnt], _, err = image.Decode(bytes.NewReader(data)); err != nil {
+			return fmt.Errorf("Invalid .gltf image %d: %s", cnt, err)
+		}
+	}
+	for cnt, gt := range gf.Textures {
+		tex := GltfTexture{Repeat: true}
+		if gt.Source != nil && *gt.Source >= 0 && *gt.Source < len(imgs) {
+			gi := gf.Images[*gt.Source]
+			switch tex.Img = imgs[*gt.Source]; {
+			case gi.Name != "":
+				tex.Name = gi.Name
+			case gi.URI != "" && !strings.HasPrefix(gi.URI, "data:"):
+				tex.Name = strings.TrimSuffix(path.Base(gi.URI), path.Ext(gi.URI))
+			}
+		}
+		if tex.Name == "" {
+			tex.Name = fmt.Sprintf("%s%d", gd.Name, cnt)
+		}
+		if gt.Sampler != nil && *gt.Sampler >= 0 && *gt.Sampler < len(gf.Samplers) {
+			tex.Repeat = gf.Samplers[*gt.Sampler].WrapS != 33071 // CLAMP_TO_EDGE
+		}
+		gd.Textures = append(gd.Textures, tex)
+	}
+	for _, gm := range gf.Materials {
+		pbr := &gm.PbrMetallicRoughness
+		m := newGltfMaterial(gm.Name)
+		m.DoubleSided = gm.DoubleSided
+		copy(m.Color[:], pbr.BaseColorFactor)
+		copy(m.Emissive[:], gm.EmissiveFactor)
+		if pbr.MetallicFactor != nil {
+			m.Metallic = *pbr.MetallicFactor
+		}
+		if pbr.RoughnessFactor != nil {
+			m.Roughness = *pbr.RoughnessFactor
+		}
+		if gm.AlphaMode != "" {
+			m.AlphaMode = gm.AlphaMode
+		}
+		if gm.AlphaCutoff != nil {
+			m.AlphaCutoff = *gm.AlphaCutoff
+		}
+		if gm.OcclusionTexture != nil && gm.OcclusionTexture.Strength != nil {
+			m.Occlusion = *gm.OcclusionTexture.Strength
+		}
+		m.ColorTex = gd.texRef(pbr.BaseColorTexture)
+		m.MetalRoughTex = gd.texRef(pbr.MetallicRoughnessTexture)
+		m.NormalTex = gd.texRef(gm.NormalTexture)
+		m.OcclusionTex = gd.texRef(gm.OcclusionTexture)
+		m.EmissiveTex = gd.texRef(gm.EmissiveTexture)
+		gd.Materials = append(gd.Materials, m)
+	}
+
+	// Parts without a material share a default material.
+	dflt := -1
+	for mcnt := range gd.Meshes {
+		for pcnt := range gd.Meshes[mcnt].Parts {
+			if part := &gd.Meshes[mcnt].Parts[pcnt]; part.Material < 0 {
+				if dflt < 0 {
+					dflt = len(gd.Materials)
+					gd.Materials = append(gd.Materials, newGltfMaterial(""))
+				}
+				part.Material = dflt
+			}
+		}
+	}
+	return nil
+}
+
+// newGltfMaterial returns a material with the glTF default values.
+func newGltfMaterial(name string) GltfMaterial {
+	m := GltfMaterial{Name: name, Color: [4]float32{1, 1, 1, 1}, Metallic: 1, Roughness: 1}
+	m.Occlusion, m.AlphaMode, m.AlphaCutoff = 1, "OPAQUE", 0.5
+	none := GltfTexRef{Texture: -1}
+	m.ColorTex, m.MetalRoughTex, m.NormalTex, m.OcclusionTex, m.EmissiveTex = none, none, none, none, none
+	return m
+}
+
+// texRef returns the material texture reference, or no texture
+// for missing or invalid references.
+func (gd *GltfData) texRef(ref *gltfTexRef) GltfTexRef {
+	if ref == nil || ref.Index < 0 || ref.Index >= len(gd.Textures) {
+		return GltfTexRef{Texture: -1}
+	}
+	return GltfTexRef{Texture: ref.Index, UV: ref.TexCoord}
+}
+
+// loadGltfSkins gets the skin joints and samples the joint animations.
+func (l *loader) loadGltfSkins(gf *gltfFile, gd *GltfData) error {
+	parents := make([]int, len(gd.Nodes))
+	for cnt := range parents {
+		parents[cnt] = -1
+	}
+	for cnt, n := range gd.Nodes {
+		for _, child := range n.Children {
+			parents[child] = cnt
+		}
+	}
+	remapped := map[int]bool{} // meshes with updated blend indicies.
+	for scnt, gs := range gf.Skins {
+		skin := GltfSkin{Name: gs.Name}
+		joints := map[int]int{} // node to file joint index.
+		for cnt, node := range gs.Joints {
+			if node < 0 || node >= len(gd.Nodes) {
+				return fmt.Errorf("Invalid .gltf skin %d joint %d", scnt, cnt)
+			}
+			joints[node] = cnt
+			gd.Nodes[node].Joint = true
+		}
+
+		// Order the joints by depth so parents come before children.
+		numJoints := len(gs.Joints)
+		depth := make([]int, numJoints)
+		for cnt, node := range gs.Joints {
+			for parent := parents[node]; parent >= 0; parent = parents[parent] {
+				if _, ok := joints[parent]; ok {
+					depth[cnt]++
+				}
+			}
+		}
+		order := make([]int, numJoints) // file joint index to skin joint index.
+		for level := 0; len(skin.Nodes) < numJoints; level++ {
+			for cnt, node := range gs.Joints {
+				if depth[cnt] == level {
+					order[cnt] = len(skin.Nodes)
+					skin.Nodes = append(skin.Nodes, node)
+				}
+			}
+		}
+		for _, node := range skin.Nodes {
+			parent := int32(-1)
+			for p := parents[node]; p >= 0 && parent < 0; p = parents[p] {
+				if joint, ok := joints[p]; ok {
+					parent = int32(order[joint])
+				}
+			}
+			skin.Joints = append(skin.Joints, parent)
+			skin.Names = append(skin.Names, gd.Nodes[node].Name)
+		}
+
+		// Inverse bind matrices are column major, which read in order gives
+		// the row major transforms used by animations.
+		ibms := make([]*lin.M4, numJoints)
+		skin.Bases = make([]*lin.M4, numJoints)
+		var ibm []float64
+		if gs.InverseBindMatrices != nil {
+			var err error
+			if ibm, err = gf.values(*gs.InverseBindMatrices, 16); err != nil {
+				return err
+			}
+			if len(ibm) < numJoints*16 {
+				return fmt.Errorf("Missing .gltf skin %d inverse bind matrices", scnt)
+			}
+		}
+		for cnt := range gs.Joints {
+			m := lin.NewM4I()
+			if ibm != nil {
+				v := ibm[cnt*16:]
+				m.Xx, m.Xy, m.Xz, m.Xw = v[0], v[1], v[2], v[3]
+				m.Yx, m.Yy, m.Yz, m.Yw = v[4], v[5], v[6], v[7]
+				m.Zx, m.Zy, m.Zz, m.Zw = v[8], v[9], v[10], v[11]
+				m.Wx, m.Wy, m.Wz, m.Ww = v[12], v[13], v[14], v[15]
+			}
+			ibms[order[cnt]] = m
+			skin.Bases[order[cnt]] = lin.NewM4().Inv(m)
+		}
+		if err := l.loadGltfAnims(gf, gd, &skin, joints, order, ibms); err != nil {
+			return err
+		}
+
+		// Update the blend indicies of the skinned meshes.
+		for _, n := range gd.Nodes {
+			if n.Skin != scnt || n.Mesh < 0 || remapped[n.Mesh] {
+				continue
+			}
+			remapped[n.Mesh] = true
+			for _, part := range gd.Meshes[n.Mesh].Parts {
+				for cnt, joint := range part.B {
+					if int(joint) >= numJoints {
+						return fmt.Errorf("Invalid .gltf joint for skin %d", scnt)
+					}
+					part.B[cnt] = byte(order[joint])
+				}
+			}
+		}
+		gd.Skins = append(gd.Skins, skin)
+	}
+	return nil
+}
+
+// loadGltfAnims samples the animations of the skin joints into frames.
+// Each frame transform is prepared as with IQM frames, see genFrame:
+//    childInverseBasePose * childPose * parentBasePose
+func (l *loader) loadGltfAnims(gf *gltfFile, gd *GltfData, skin *GltfSkin, joints map[int]int, order []int, ibms []*lin.M4) error {
+	numJoints := len(skin.Nodes)
+	pose := make([][10]float64, numJoints) // translate, rotate, scale.
+	q := &lin.Q{}                          // scratch
+	for acnt, ga := range gf.Animations {
+		chans := []*gltfChannel{}
+		duration := 0.0
+		for _, gc := range ga.Channels {
+			joint, ok := joints[gc.Target.Node]
+			offset, span := 0, 3
+			switch gc.Target.Path {
+			case "translation":
+			case "rotation":
+				offset, span = 3, 4
+			case "scale":
+				offset = 7
+			default:
+				ok = false // morph target weights are not animated.
+			}
+			if !ok {
+				continue
+			}
+			if gc.Sampler < 0 || gc.Sampler >= len(ga.Samplers) {
+				return fmt.Errorf("Invalid .gltf animation %d sampler", acnt)
+			}
+			gs := ga.Samplers[gc.Sampler]
+			ch := &gltfChannel{joint: order[joint], offset: offset, span: span}
+			ch.cubic, ch.step = gs.Interpolation == "CUBICSPLINE", gs.Interpolation == "STEP"
+			var err error
+			if ch.times, err = gf.values(gs.Input, 1); err != nil {
+				return err
+			}
+			if ch.values, err = gf.values(gs.Output, span); err != nil {
+				return err
+			}
+			keys := len(ch.times)
+			if keys == 0 || (ch.cubic && len(ch.values) < keys*span*3) || len(ch.values) < keys*span {
+				return fmt.Errorf("Invalid .gltf animation %d keys", acnt)
+			}
+			duration = math.Max(duration, ch.times[keys-1])
+			chans = append(chans, ch)
+		}
+		if len(chans) == 0 {
+			continue // animation does not affect this skin.
+		}
+		name := ga.Name
+		if name == "" {
+			name = fmt.Sprintf("anim%d", acnt)
+		}
+		numFrames := int(math.Max(1, math.Floor(duration*gltfRate+0.5)))
+		anim := IqAnim{Name: name, F0: uint32(len(skin.Frames) / numJoints), Fn: uint32(numFrames), Rate: gltfRate}
+		skin.Anims = append(skin.Anims, anim)
+		for frame := 0; frame < numFrames; frame++ {
+			for cnt, node := range skin.Nodes {
+				n := &gd.Nodes[node]
+				pose[cnt] = [10]float64{n.Loc[0], n.Loc[1], n.Loc[2],
+					n.Rot[0], n.Rot[1], n.Rot[2], n.Rot[3], n.Scale[0], n.Scale[1], n.Scale[2]}
+			}
+			for _, ch := range chans {
+				ch.sample(float64(frame)/gltfRate, pose[ch.joint][ch.offset:ch.offset+ch.span])
+			}
+			for cnt := range skin.Nodes {
+				pt := &pose[cnt]
+				m := lin.NewM4().SetQ(q.SetS(pt[3], pt[4], pt[5], pt[6]).Unit())
+				m.Transpose(m).ScaleSM(pt[7], pt[8], pt[9])     // apply scale before rotation.
+				m.Wx, m.Wy, m.Wz, m.Ww = pt[0], pt[1], pt[2], 1 // translation added in, not multiplied.
+				m.Mult(ibms[cnt], m)
+				if parent := skin.Joints[cnt]; parent >= 0 {
+					m.Mult(m, skin.Bases[parent])
+				}
+				skin.Frames = append(skin.Frames, m)
+			}
+		}
+	}
+	return nil
+}
+
+// =============================================================================
+// The glTF JSON structures. Only the used fields are decoded.
+
+// gltfFile is the top level glTF JSON object.
+type gltfFile struct {
+	Asset struct {
+		Version string
+	}
+	Scene  int // Default scene.
+	Scenes []struct {
+		Nodes []int
+	}
+	Nodes []struct {
+		Name        string
+		Children    []int
+		Mesh        *int
+		Skin        *int
+		Matrix      []float64 // Column major.
+		Translation []float64
+		Rotation    []float64
+		Scale       []float64
+	}
+	Meshes []struct {
+		Name       string
+		Primitives []struct {
+			Attributes map[string]int
+			Indices    *int
+			Material   *int
+			Mode       *int // 4 for triangles.
+		}
+	}
+	Materials []struct {
+		Name                 string
+		PbrMetallicRoughness struct {
+			BaseColorFactor          []float32
+			BaseColorTexture         *gltfTexRef
+			MetallicFactor           *float32
+			RoughnessFactor          *float32
+			MetallicRoughnessTexture *gltfTexRef
+		}
+		NormalTexture    *gltfTexRef
+		OcclusionTexture *gltfTexRef
+		EmissiveTexture  *gltfTexRef
+		EmissiveFactor   []float32
+		AlphaMode        string
+		AlphaCutoff      *float32
+		DoubleSided      bool
+	}
+	Textures []struct {
+		Sampler *int
+		Source  *int
+	}
+	Samplers []struct {
+		WrapS int
+	}
+	Images []struct {
+		Name       string
+		URI        string
+		BufferView *int
+	}
+	Skins []struct {
+		Name                string
+		InverseBindMatrices *int
+		Joints              []int
+	}
+	Animations []struct {
+		Name     string
+		Channels []struct {
+			Sampler int
+			Target  struct {
+				Node int
+				Path string
+			}
+		}
+		Samplers []struct {
+			Input         int
+			Output        int
+			Interpolation string
+		}
+	}
+	Accessors []struct {
+		BufferView    *int
+		ByteOffset    int
+		ComponentType int
+		Normalized    bool
+		Count         int
+		Type          string
+		Sparse        *json.RawMessage
+	}
+	BufferViews []struct {
+		Buffer     int
+		ByteOffset int
+		ByteLength int
+		ByteStride int
+	}
+	Buffers []struct {
+		URI        string
+		ByteLength int
+	}
+	buffs [][]byte // Buffer data.
+}
+
+// gltfTexRef is a material texture reference.
+type gltfTexRef struct {
+	Index    int
+	TexCoord int
+	Strength *float32 // Occlusion textures only.
+}
+
+// Accessor component types and the bytes for each component.
+var gltfComponents = map[int]int{
+	5120: 1, // BYTE
+	5121: 1, // UNSIGNED_BYTE
+	5122: 2, // SHORT
+	5123: 2, // UNSIGNED_SHORT
+	5125: 4, // UNSIGNED_INT
+	5126: 4, // FLOAT
+}
+
+// Accessor types and the components for each element.
+var gltfTypes = map[string]int{"SCALAR": 1, "VEC2": 2, "VEC3": 3, "VEC4": 4, "MAT4": 16}
+
+// view returns the buffer view data and the byte stride
+// for interleaved data.
+func (gf *gltfFile) view(index int) (data []byte, stride int, err error) {
+	if index < 0 || index >= len(gf.BufferViews) {
+		return nil, 0, fmt.Errorf("Invalid .gltf buffer view %d", index)
+	}
+	bv := gf.BufferViews[index]
+	if bv.Buffer < 0 || bv.Buffer >= len(gf.buffs) {
+		return nil, 0, fmt.Errorf("Invalid .gltf buffer view %d buffer", index)
+	}
+	buff := gf.buffs[bv.Buffer]
+	if bv.ByteOffset < 0 || bv.ByteLength < 0 || bv.ByteOffset+bv.ByteLength > len(buff) {
+		return nil, 0, fmt.Errorf("Invalid .gltf buffer view %d range", index)
+	}
+	return buff[bv.ByteOffset : bv.ByteOffset+bv.ByteLength], bv.ByteStride, nil
+}
+
+// values returns the accessor data as float64 values. Normalized integer
+// values are converted to the 0->1, or -1->1, range. The accessor is
+// expected to have span components for each element.
+func (gf *gltfFile) values(index, span int) ([]float64, error) {
+	if index < 0 || index >= len(gf.Accessors) {
+		return nil, fmt.Errorf("Invalid .gltf accessor %d", index)
+	}
+	a := gf.Accessors[index]
+	size, ok := gltfComponents[a.ComponentType]
+	if !ok || gltfTypes[a.Type] != span || a.Count < 0 {
+		return nil, fmt.Errorf("Unexpected .gltf accessor %d type %s", index, a.Type)
+	}
+	if a.Sparse != nil {
+		return nil, fmt.Errorf("Sparse .gltf accessor %d not supported", index)
+	}
+	vals := make([]float64, a.Count*span)
+	if a.BufferView == nil || a.Count == 0 {
+		return vals, nil // zeros.
+	}
+	data, stride, err := gf.view(*a.BufferView)
+	if err != nil {
+		return nil, err
+	}
+	if stride == 0 {
+		stride = size * span // tightly packed.
+	}
+	if a.ByteOffset < 0 || a.ByteOffset+(a.Count-1)*stride+size*span > len(data) {
+		return nil, fmt.Errorf("Invalid .gltf accessor %d range", index)
+	}
+	le := binary.LittleEndian
+	for cnt := range vals {
+		b := data[a.ByteOffset+(cnt/span)*stride+(cnt%span)*size:]
+		switch a.ComponentType {
+		case 5120:
+			if vals[cnt] = float64(int8(b[0])); a.Normalized {
+				vals[cnt] = math.Max(vals[cnt]/127, -1)
+			}
+		case 5121:
+			if vals[cnt] = float64(b[0]); a.Normalized {
+				vals[cnt] /= 255
+			}
+		case 5122:
+			if vals[cnt] = float64(int16(le.Uint16(b))); a.Normalized {
+				vals[cnt] = math.Max(vals[cnt]/32767, -1)
+			}
+		case 5123:
+			if vals[cnt] = float64(le.Uint16(b)); a.Normalized {
+				vals[cnt] /= 65535
+			}
+		case 5125:
+			vals[cnt] = float64(le.Uint32(b))
+		case 5126:
+			vals[cnt] = float64(math.Float32frombits(le.Uint32(b)))
+		}
+	}
+	return vals, nil
+}
+
+// floats returns the accessor data as float32 values.
+func (gf *gltfFile) floats(index, span int) ([]float32, error) {
+	vals, err := gf.values(index, span)
+	if err != nil {
+		return nil, err
+	}
+	floats := make([]float32, len(vals))
+	for cnt, val := range vals {
+		floats[cnt] = float32(val)
+	}
+	return floats, nil
+}
+
+// ints returns the accessor data as unsigned integer values.
+func (gf *gltfFile) ints(index, span int) ([]uint32, error) {
+	vals, err := gf.values(index, span)
+	if err != nil {
+		return nil, err
+	}
+	ints := make([]uint32, len(vals))
+	for cnt, val := range vals {
+		if val < 0 {
+			return nil, fmt.Errorf("Invalid .gltf accessor %d index", index)
+		}
+		ints[cnt] = uint32(val)
+	}
+	return ints, nil
+}
+
+// gltfChannel is a decoded animation channel for one skin joint.
+type gltfChannel struct {
+	joint  int       // Skin joint index.
+	offset int       // Pose offset: 0 translate, 3 rotate, 7 scale.
+	span   int       // Values per key: 4 for rotations, otherwise 3.
+	times  []float64 // Key times in seconds.
+	values []float64 // Key values. In, value, out tangents for cubic.
+	cubic  bool      // True for cubic spline interpolation.
+	step   bool      // True for step interpolation.
+}
+
+// key returns the value of the given key. Cubic spline keys are
+// in-tangent, value, out-tangent for parts 0, 1, 2.
+func (ch *gltfChannel) key(index, part int) []float64 {
+	at := index * ch.span
+	if ch.cubic {
+		at = (index*3 + part) * ch.span
+	}
+	return ch.values[at : at+ch.span]
+}
+
+// sample sets v to the channel value at time t.
+func (ch *gltfChannel) sample(t float64, v []float64) {
+	last := len(ch.times) - 1
+	k := 0
+	for k < last && ch.times[k+1] <= t {
+		k++
+	}
+	if k == last || t <= ch.times[0] {
+		copy(v, ch.key(k, 1))
+		return
+	}
+	dt := ch.times[k+1] - ch.times[k]
+	s := (t - ch.times[k]) / dt
+	v0, v1 := ch.key(k, 1), ch.key(k+1, 1)
+	switch {
+	case ch.step:
+		copy(v, v0)
+	case ch.cubic:
+		m0, m1 := ch.key(k, 2), ch.key(k+1, 0)
+		s2, s3 := s*s, s*s*s
+		for cnt := range v {
+			v[cnt] = (2*s3-3*s2+1)*v0[cnt] + (s3-2*s2+s)*dt*m0[cnt] + (-2*s3+3*s2)*v1[cnt] + (s3-s2)*dt*m1[cnt]
+		}
+	case ch.span == 4:
+		// spherical interpolation along the shortest path.
+		dot := v0[0]*v1[0] + v0[1]*v1[1] + v0[2]*v1[2] + v0[3]*v1[3]
+		sign := 1.0
+		if dot < 0 {
+			dot, sign = -dot, -1
+		}
+		w0, w1 := 1-s, s
+		if dot < 0.9995 {
+			theta := math.Acos(dot)
+			w0, w1 = math.Sin((1-s)*theta)/math.Sin(theta), math.Sin(s*theta)/math.Sin(theta)
+		}
+		for cnt := range v {
+			v[cnt] = w0*v0[cnt] + sign*w1*v1[cnt]
+		}
+	default:
+		for cnt := range v {
+			v[cnt] = v0[cnt] + (v1[cnt]-v0[cnt])*s
+		}
+	}
+}
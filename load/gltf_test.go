@@ -0,0 +1,194 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package load
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"image"
+	"image/png"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gazed/vu/math/lin"
+)
+
+// Check a skinned and textured triangle with a node hierarchy.
+func TestLoadGltf(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "gltf")
+	defer os.RemoveAll(dir)
+	doc, bin := testGltf(t)
+	doc["buffers"] = []interface{}{map[string]interface{}{
+		"byteLength": len(bin),
+		"uri":        "data:application/octet-stream;base64," + base64.StdEncoding.EncodeToString(bin),
+	}}
+	js, _ := json.Marshal(doc)
+	ioutil.WriteFile(filepath.Join(dir, "tri.gltf"), js, 0644)
+	gd, err := newLoader().setDir(mod, dir).gltf("tri")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// nodes keep the hierarchy. Matrices are split into transforms.
+	if len(gd.Roots) != 1 || gd.Roots[0] != 0 || len(gd.Nodes) != 5 || len(gd.Nodes[0].Children) != 3 {
+		t.Fatalf("Expected node hierarchy, got %v %+v", gd.Roots, gd.Nodes)
+	}
+	if lamp := gd.Nodes[4]; lamp.Loc != [3]float64{1, 2, 3} || !lin.Aeq(lamp.Scale[0], 2) ||
+		!lin.Aeq(lamp.Rot[1], math.Sqrt2/2) || !lin.Aeq(lamp.Rot[3], math.Sqrt2/2) {
+		t.Errorf("Expected lamp transform, got %+v", lamp)
+	}
+	if body := gd.Nodes[1]; body.Mesh != 0 || body.Skin != 0 || body.Joint || !gd.Nodes[3].Joint {
+		t.Errorf("Expected skinned mesh, got %+v", body)
+	}
+
+	// mesh data includes tangents and both uv sets.
+	part := gd.Meshes[0].Parts[0]
+	if len(part.V) != 9 || len(part.N) != 9 || len(part.X) != 12 || len(part.T) != 2 || len(part.T[1]) != 6 || len(part.F) != 3 {
+		t.Errorf("Expected mesh data, got %+v", part)
+	}
+	if !bytes.Equal(part.B, []byte{0, 1, 1, 1, 0, 1, 1, 1, 1, 1, 1, 1}) || part.W[0] != 255 || part.W[1] != 0 {
+		t.Errorf("Expected blend data for reordered joints, got %v %v", part.B, part.W)
+	}
+
+	// materials reference decoded textures.
+	mat := gd.Materials[part.Material]
+	if mat.Color != [4]float32{1, 0.5, 0.5, 1} || mat.Metallic != 0 || mat.Roughness != 0.5 || mat.AlphaMode != "OPAQUE" {
+		t.Errorf("Expected material factors, got %+v", mat)
+	}
+	if mat.ColorTex.Texture != 0 || mat.OcclusionTex.UV != 1 || mat.Occlusion != 0.5 || mat.NormalTex.Texture != -1 {
+		t.Errorf("Expected material textures, got %+v", mat)
+	}
+	if tex := gd.Textures[0]; tex.Name != "tri0" || tex.Img == nil || tex.Img.Bounds().Dx() != 2 || tex.Repeat {
+		t.Errorf("Expected clamped image, got %+v", tex)
+	}
+
+	// skin joints are ordered parents first and animations are sampled.
+	skin := gd.Skins[0]
+	if len(skin.Names) != 2 || skin.Names[0] != "hip" || skin.Joints[0] != -1 || skin.Joints[1] != 0 {
+		t.Fatalf("Expected hip then knee, got %v %v", skin.Names, skin.Joints)
+	}
+	if !lin.Aeq(skin.Bases[1].Wy, 2) {
+		t.Errorf("Expected knee base pose, got %+v", skin.Bases[1])
+	}
+	if len(skin.Anims) != 1 || skin.Anims[0] != (IqAnim{"walk", 0, 30, 30}) || len(skin.Frames) != 60 {
+		t.Fatalf("Expected sampled walk, got %+v %d", skin.Anims, len(skin.Frames))
+	}
+	if !skin.Frames[0].Aeq(lin.M4I) || !skin.Frames[1].Aeq(lin.M4I) {
+		t.Errorf("Expected bind pose at frame 0")
+	}
+	v := lin.NewV4().MultvM(lin.NewV4S(0, 3, 0, 1), skin.Frames[15*2+1]) // knee at 45 degrees.
+	if !lin.Aeq(v.X, -math.Sqrt2/2) || !lin.Aeq(v.Y, 2+math.Sqrt2/2) {
+		t.Errorf("Expected bent knee, got %+v", v)
+	}
+}
+
+// Binary files keep the buffer in a chunk after the JSON.
+func TestLoadGlb(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "gltf")
+	defer os.RemoveAll(dir)
+	doc, bin := testGltf(t)
+	doc["buffers"] = []interface{}{map[string]interface{}{"byteLength": len(bin)}}
+	js, _ := json.Marshal(doc)
+	for len(js)%4 != 0 {
+		js = append(js, ' ')
+	}
+	glb := &bytes.Buffer{}
+	binary.Write(glb, binary.LittleEndian, []uint32{0x46546C67, 2, uint32(12 + 8 + len(js) + 8 + len(bin))})
+	binary.Write(glb, binary.LittleEndian, []uint32{uint32(len(js)), 0x4E4F534A})
+	glb.Write(js)
+	binary.Write(glb, binary.LittleEndian, []uint32{uint32(len(bin)), 0x004E4942})
+	glb.Write(bin)
+	ioutil.WriteFile(filepath.Join(dir, "tri.glb"), glb.Bytes(), 0644)
+	gd, err := newLoader().setDir(mod, dir).gltf("tri")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if part := gd.Meshes[0].Parts[0]; part.V[7] != 3 || part.F[2] != 2 || len(gd.Skins[0].Frames) != 60 {
+		t.Errorf("Expected binary mesh and skin data")
+	}
+	if _, err := newLoader().setDir(mod, dir).gltf("none"); err == nil {
+		t.Errorf("Expected error for missing file")
+	}
+}
+
+// testGltf returns a glTF document and buffer for a triangle that is
+// skinned to a hip and a knee joint, where the knee bends over a second.
+func testGltf(t *testing.T) (doc map[string]interface{}, bin []byte) {
+	buff := &bytes.Buffer{}
+	views, accessors := []interface{}{}, []interface{}{}
+	add := func(data interface{}, ctype int, kind string, count int, normalized bool) int {
+		at := buff.Len()
+		binary.Write(buff, binary.LittleEndian, data)
+		views = append(views, map[string]interface{}{"buffer": 0, "byteOffset": at, "byteLength": buff.Len() - at})
+		for buff.Len()%4 != 0 {
+			buff.WriteByte(0)
+		}
+		accessors = append(accessors, map[string]interface{}{
+			"bufferView": len(views) - 1, "componentType": ctype, "type": kind, "count": count, "normalized": normalized})
+		return len(accessors) - 1
+	}
+	hipIbm := []float32{1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1, 0, 0, -1, 0, 1}
+	kneeIbm := []float32{1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1, 0, 0, -2, 0, 1}
+	attrs := map[string]int{
+		"POSITION":   add([]float32{0, 0, 0, 1, 0, 0, 0, 3, 0}, 5126, "VEC3", 3, false),
+		"NORMAL":     add([]float32{0, 0, 1, 0, 0, 1, 0, 0, 1}, 5126, "VEC3", 3, false),
+		"TANGENT":    add([]float32{1, 0, 0, 1, 1, 0, 0, 1, 1, 0, 0, 1}, 5126, "VEC4", 3, false),
+		"TEXCOORD_0": add([]float32{0, 0, 1, 0, 0, 1}, 5126, "VEC2", 3, false),
+		"TEXCOORD_1": add([]float32{0, 0, 0.5, 0, 0, 0.5}, 5126, "VEC2", 3, false),
+		"JOINTS_0":   add([]uint8{1, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0}, 5121, "VEC4", 3, false),
+		"WEIGHTS_0":  add([]uint8{255, 0, 0, 0, 255, 0, 0, 0, 255, 0, 0, 0}, 5121, "VEC4", 3, true),
+	}
+	indices := add([]uint16{0, 1, 2}, 5123, "SCALAR", 3, false)
+	ibms := add(append(kneeIbm, hipIbm...), 5126, "MAT4", 2, false)
+	times := add([]float32{0, 1}, 5126, "SCALAR", 2, false)
+	s := float32(math.Sqrt2 / 2)
+	rots := add([]float32{0, 0, 0, 1, 0, 0, s, s}, 5126, "VEC4", 2, false)
+
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	pic := &bytes.Buffer{}
+	if err := png.Encode(pic, img); err != nil {
+		t.Fatal(err)
+	}
+	doc = map[string]interface{}{
+		"asset":  map[string]interface{}{"version": "2.0"},
+		"scene":  0,
+		"scenes": []interface{}{map[string]interface{}{"nodes": []int{0}}},
+		"nodes": []interface{}{
+			map[string]interface{}{"name": "root", "children": []int{1, 2, 4}},
+			map[string]interface{}{"name": "body", "mesh": 0, "skin": 0},
+			map[string]interface{}{"name": "hip", "translation": []float64{0, 1, 0}, "children": []int{3}},
+			map[string]interface{}{"name": "knee", "matrix": []float64{1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1, 0, 0, 1, 0, 1}},
+			map[string]interface{}{"name": "lamp", "matrix": []float64{0, 0, -2, 0, 0, 2, 0, 0, 2, 0, 0, 0, 1, 2, 3, 1}},
+		},
+		"meshes": []interface{}{map[string]interface{}{"name": "tri", "primitives": []interface{}{
+			map[string]interface{}{"attributes": attrs, "indices": indices, "material": 0},
+		}}},
+		"materials": []interface{}{map[string]interface{}{
+			"pbrMetallicRoughness": map[string]interface{}{
+				"baseColorFactor":  []float32{1, 0.5, 0.5, 1},
+				"baseColorTexture": map[string]interface{}{"index": 0},
+				"metallicFactor":   0,
+				"roughnessFactor":  0.5,
+			},
+			"occlusionTexture": map[string]interface{}{"index": 0, "texCoord": 1, "strength": 0.5},
+		}},
+		"textures": []interface{}{map[string]interface{}{"sampler": 0, "source": 0}},
+		"samplers": []interface{}{map[string]interface{}{"wrapS": 33071, "wrapT": 33071}},
+		"images":   []interface{}{map[string]interface{}{"uri": "data:image/png;base64," + base64.StdEncoding.EncodeToString(pic.Bytes())}},
+		"skins":    []interface{}{map[string]interface{}{"joints": []int{3, 2}, "inverseBindMatrices": ibms}},
+		"animations": []interface{}{map[string]interface{}{
+			"name":     "walk",
+			"channels": []interface{}{map[string]interface{}{"sampler": 0, "target": map[string]interface{}{"node": 3, "path": "rotation"}}},
+			"samplers": []interface{}{map[string]interface{}{"input": times, "output": rots}},
+		}},
+		"bufferViews": views,
+		"accessors":   accessors,
+	}
+	return doc, buff.Bytes()
+}
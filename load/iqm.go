@@ -38,6 +38,8 @@ type IqData struct {
 	B      []byte    // Vertex blend indicies. Arranged as [][4]byte
 	W      []byte    // Vertex blend weights.  Arranged as [][4]byte
 	Joints []int32   // Joint parent information for each joint.
+	Names  []string  // Joint names for each joint.
+	Bases  []*lin.M4 // Joint base pose model space transforms.
 	Frames []*lin.M4 // Animation transforms: [NumFrames][NumJoints].
 }
 
@@ -50,7 +52,8 @@ type IqTexture struct {
 }
 
 // IqAnim allows a model to have multiple animations. The named animation
-// affects frames from F0 to F0+FN. Expected to be used as part of IqData.
+// affects frames from F0 to F0+FN. Expected to be used as part of IqData
+// and GltfSkin.
 type IqAnim struct {
 	Name   string  // Name of the animation
 	F0, Fn uint32  // First frame, number of frames.
@@ -243,15 +246,13 @@ func (l *loader) loadIqmAnims(hdr *iqmheader, data []byte, iqd *IqData, scr *scr
 		return fmt.Errorf("Invalid .iqm file: %s", err)
 	}
 	iqd.Joints = make([]int32, hdr.NumJoints)
+	iqd.Names = make([]string, hdr.NumJoints)
 
 	// process the joint base transforms using an intermediate form.
 	basePoses := []*transform{}
 	for cnt, j := range jnts {
-		iqd.Joints[cnt] = j.Parent // save the joint parent data
-
-		// FUTURE: use joint names as attachment points where the convention
-		//         is to have "attachment" in the joint name, and not have
-		//         the joint affect any verticies.
+		iqd.Joints[cnt] = j.Parent          // save the joint parent data
+		iqd.Names[cnt] = scr.labels[j.Name] // ... and joint attachment name.
 
 		// put the pose data into a transform ready structure.
 		t := &lin.V3{X: float64(j.Translate[0]), Y: float64(j.Translate[1]), Z: float64(j.Translate[2])}
@@ -260,6 +261,7 @@ func (l *loader) loadIqmAnims(hdr *iqmheader, data []byte, iqd *IqData, scr *scr
 		basePoses = append(basePoses, &transform{t, r, s})
 	}
 	l.createBaseFrames(iqd, basePoses, scr)
+	iqd.Bases = scr.baseframe
 
 	// Get the per frame pose data.
 	buff.Seek(int64(hdr.OfsPoses-iqmheaderSize), 0)
@@ -15,3 +15,20 @@ func TestLoadIqm(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+// Joint names and base poses are used as attachment points.
+func TestIqmJoints(t *testing.T) {
+	load := newLoader().setDir(mod, "../eg/models")
+	iqm, err := load.iqm("runner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(iqm.Joints) == 0 || len(iqm.Names) != len(iqm.Joints) || len(iqm.Bases) != len(iqm.Joints) {
+		t.Errorf("Expected names and bases for %d joints", len(iqm.Joints))
+	}
+	for cnt, name := range iqm.Names {
+		if name == "" {
+			t.Errorf("Expected name for joint %d", cnt)
+		}
+	}
+}
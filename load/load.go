@@ -2,8 +2,8 @@
 // Use is governed by a BSD-style license found in the LICENSE file.
 
 // Package load fetches disk based data that will be used for 3D assets.
-// Data is loaded directly from disk for development builds and from a zip
-// file for production builds.
+// Data is loaded directly from disk for development builds and from a pack,
+// or zip, file for production builds.
 //
 // Data that can be loaded from disk is listed in the Loader interface.
 // Data is returned in an intermediate format that is close to how the
@@ -17,8 +17,11 @@
 //   vertex shader program  : txtfile.vsh -┐
 //   fragment shader program: txtfile.fsh --> rendered model shader
 //   animated models        : binfile.iqm --> rendered model animation
+//   model hierarchies      : txtfile.gltf -> rendered models and animations
 //   images                 : binfile.png --> rendered model texture
+//   cube map images        : binfile.png --> rendered sky or reflection
 //   audio                  : binfile.wav --> sound played in 3D world
+//   compressed audio       : binfile.ogg --> sound played in 3D world
 //
 // Package load is currently intended for smaller 3D applications where data
 // is loaded directly from files to memory, i.e. no database involved.
@@ -58,16 +61,24 @@ type Loader interface {
 
 	// Supported file formats.
 	Png(name string) (img image.Image, err error)         // .png
+	Cube(name string) (faces []image.Image, err error)    // 6 .png, or 1 .png
 	Mtl(name string) (mtl *MtlData, err error)            // .mtl
+	Mtls(name string) (mtls []*MtlData, err error)        // .mtl libraries
 	Obj(name string) (obj []*ObjData, err error)          // .obj
 	Fnt(name string) (fnt *FntData, err error)            // .fnt
 	Vsh(name string) (src []string, err error)            // .vsh
 	Fsh(name string) (src []string, err error)            // .fsh
 	Wav(name string) (wh *WavHdr, data []byte, err error) // .wav
+	Ogg(name string) (wh *WavHdr, data []byte, err error) // .ogg
 	Iqm(name string) (iqd *IqData, err error)             // .iqm
+	Gltf(name string) (gd *GltfData, err error)           // .gltf, .glb
 
 	// GetResource allows applications to include and find custom resources.
 	GetResource(directory, name string) (file io.ReadCloser, err error)
+
+	// AddSource adds an asset location that is checked after earlier
+	// sources. See source.go.
+	AddSource(s Source) Loader
 }
 
 // Asset type identifiers for SetDir.
@@ -90,20 +101,35 @@ func NewLoader() Loader { return newLoader() }
 // layer. Loader supports importing. Asset files are expected to be created
 // by 3rdParty tools like Blender or Gimp.
 type loader struct {
+	sources []Source // Checked first. See source.go.
+
 	// Used as the resource file if set.
+	pack   *pack           // Checked before the zip file. See pack.go.
 	reader *zip.ReadCloser // Otherwise use the file system.
 	dir    map[int]string  // Data directory locations.
 }
 
 // newLoader creates the appropriate asset loader. Production assets are
-// in a zip file that is either included within the production binary or
-// in a directory relative to the executable. Development builds have a nil
-// loader.reader and will look locally on disk.
+// in a pack or zip file that is either included within the production binary
+// or in a directory relative to the executable. Development builds have a nil
+// loader.pack and loader.reader and will look locally on disk.
 //
-// The zip creator must call loader.dispose()
+// The pack or zip creator must call loader.dispose()
 func newLoader() *loader {
 	var resources *zip.ReadCloser // packaged resources.
 	programName := os.Args[0]     // qualified path to executable
+	var assets *pack              // packed resources.
+	for _, packFile := range []string{
+		path.Join(path.Dir(programName), "../Resources/resources.vpk"), // OSX
+		path.Join(path.Dir(programName), "Resources/resources.vpk"),    // Windows
+		path.Join(path.Dir(programName), "resources.vpk"),
+		programName, // Pack appended to executable.
+	} {
+		if p, err := openPack(packFile); err == nil {
+			assets = p
+			break
+		}
+	}
 	resourceZip := path.Join(path.Dir(programName), "../Resources/resources.zip")
 	if reader, err := zip.OpenReader(resourceZip); err == nil {
 		resources = reader // OSX
@@ -115,7 +141,7 @@ func newLoader() *loader {
 			resources = reader // Zip appened to executable.
 		}
 	}
-	l := &loader{reader: resources}
+	l := &loader{sources: defaultSources(), pack: assets, reader: resources}
 	l.dir = map[int]string{
 		mod: "models",
 		snd: "audio",
@@ -127,15 +153,20 @@ func newLoader() *loader {
 
 // Comply with the Loader interface.
 func (l *loader) Wav(name string) (wh *WavHdr, data []byte, err error) { return l.wav(name) }
+func (l *loader) Ogg(name string) (wh *WavHdr, data []byte, err error) { return l.ogg(name) }
 func (l *loader) Png(name string) (img image.Image, err error)         { return l.png(name) }
+func (l *loader) Cube(name string) (faces []image.Image, err error)    { return l.cube(name) }
 func (l *loader) Fnt(name string) (fnt *FntData, err error)            { return l.fnt(name) }
 func (l *loader) Vsh(name string) (src []string, err error)            { return l.txt(name + ".vsh") }
 func (l *loader) Fsh(name string) (src []string, err error)            { return l.txt(name + ".fsh") }
 func (l *loader) Mtl(name string) (mtl *MtlData, err error)            { return l.mtl(name) }
+func (l *loader) Mtls(name string) (mtls []*MtlData, err error)        { return l.mtls(name) }
 func (l *loader) Obj(name string) (obj []*ObjData, err error)          { return l.obj(name) }
 func (l *loader) Iqm(name string) (iqd *IqData, err error)             { return l.iqm(name) }
+func (l *loader) Gltf(name string) (gd *GltfData, err error)           { return l.gltf(name) }
 func (l *loader) SetDir(dataType int, dir string) Loader               { return l.setDir(dataType, dir) }
 func (l *loader) Dispose()                                             { l.dispose() }
+func (l *loader) AddSource(s Source) Loader                            { return l.addSource(s) }
 
 // GetResource exposes the resource location ability
 // in the Loader interface.
@@ -146,6 +177,9 @@ func (l *loader) GetResource(directory, name string) (file io.ReadCloser, err er
 // dispose properly terminates the loader.
 // This is only needed when the loader has been reading resources from a file.
 func (l *loader) dispose() {
+	if l.pack != nil {
+		l.pack.Close()
+	}
 	if l.reader != nil {
 		l.reader.Close()
 	}
@@ -163,6 +197,13 @@ func (l *loader) setDir(dataType int, dir string) *loader {
 	return l
 }
 
+// addSource adds an asset location. Sources are owned by the caller
+// and are not closed by dispose.
+func (l *loader) addSource(s Source) *loader {
+	l.sources = append(l.sources, s)
+	return l
+}
+
 // getResource locates the named resource.  This is expected to be used either
 // in production where the resources have been included with the application,
 // or development where the resources are on disk in the local directory.
@@ -170,6 +211,16 @@ func (l *loader) setDir(dataType int, dir string) *loader {
 // The caller is responsible for closing the returned file.
 func (l *loader) getResource(directory, name string) (file io.ReadCloser, err error) {
 	filePath := strings.TrimSpace(path.Join(directory, name))
+	for _, s := range l.sources {
+		if file, err := s.Open(filePath); err == nil {
+			return file, nil
+		}
+	}
+	if l.pack != nil {
+		if file, ok := l.pack.open(filePath); ok {
+			return file, nil
+		}
+	}
 	if l.reader != nil {
 		for _, resource := range l.reader.File {
 			if filePath == resource.Name {
@@ -7,6 +7,7 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"path"
 	"strconv"
 	"strings"
 )
@@ -14,10 +15,13 @@ import (
 // MtlData holds color and alpha information.
 // It is intended for populating rendered models.
 type MtlData struct {
+	Name          string  // Material name from the .mtl file.
 	KaR, KaG, KaB float32 // Ambient color.
 	KdR, KdG, KdB float32 // Diffuse color.
 	KsR, KsG, KsB float32 // Specular color.
 	Tr            float32 // Transparency
+	Ns            float32 // Specular exponent.
+	MapKd         string  // Diffuse texture image name. Empty if none.
 }
 
 // Load a Wavefront .mtl file which is a text representation of one
@@ -25,42 +29,72 @@ type MtlData struct {
 //    https://en.wikipedia.org/wiki/Wavefront_.obj_file#File_format
 //    http://web.archive.org/web/20080813073052/
 //    http://paulbourke.net/dataformats/mtl/
+// The first material is returned for files with more than one material.
 func (l *loader) mtl(name string) (data *MtlData, err error) {
-	mtl := &MtlData{}
+	mtls, err := l.mtls(name)
+	if err != nil || len(mtls) == 0 {
+		return &MtlData{Tr: 1}, err
+	}
+	return mtls[0], nil
+}
+
+// mtls loads all the materials from a Wavefront .mtl material library.
+// Texture map names have their directory and file extension removed,
+// ie: "map_Kd textures/wood.png" is the diffuse texture "wood".
+func (l *loader) mtls(name string) (mtls []*MtlData, err error) {
+	mtls = []*MtlData{}
 	var file io.ReadCloser
 	if file, err = l.getResource(l.dir[mod], name+".mtl"); err != nil {
-		return mtl, fmt.Errorf("could not open %s %s", name+".mtl", err)
+		return mtls, fmt.Errorf("could not open %s %s", name+".mtl", err)
 	}
 	defer file.Close()
+	var mtl *MtlData
 	var f1, f2, f3 float32
 	reader := bufio.NewReader(file)
 	line, e1 := reader.ReadString('\n')
 	for ; e1 == nil; line, e1 = reader.ReadString('\n') {
-		tokens := strings.Split(line, " ")
+		line = strings.TrimSpace(line)
+		tokens := strings.Fields(line)
+		if len(tokens) < 2 || strings.HasPrefix(tokens[0], "#") {
+			continue // ignore blank lines and comments.
+		}
+		if tokens[0] == "newmtl" || mtl == nil {
+			mtl = &MtlData{Tr: 1} // opaque unless set.
+			mtls = append(mtls, mtl)
+		}
 		switch tokens[0] {
 		case "Ka": // ambient
 			if _, e := fmt.Sscanf(line, "Ka %f %f %f", &f1, &f2, &f3); e != nil {
-				return mtl, fmt.Errorf("could not parse ambient values %s", e)
+				return mtls, fmt.Errorf("could not parse ambient values %s", e)
 			}
 			mtl.KaR, mtl.KaG, mtl.KaB = f1, f2, f3
 		case "Kd": // diffuse
 			if _, e := fmt.Sscanf(line, "Kd %f %f %f", &f1, &f2, &f3); e != nil {
-				return mtl, fmt.Errorf("could not parse diffuse values %s", e)
+				return mtls, fmt.Errorf("could not parse diffuse values %s", e)
 			}
 			mtl.KdR, mtl.KdG, mtl.KdB = f1, f2, f3
 		case "Ks": // specular
 			if _, e := fmt.Sscanf(line, "Ks %f %f %f", &f1, &f2, &f3); e != nil {
-				return mtl, fmt.Errorf("could not parse specular values %s", e)
+				return mtls, fmt.Errorf("could not parse specular values %s", e)
 			}
 			mtl.KsR, mtl.KsG, mtl.KsB = f1, f2, f3
 		case "d": // transparency
-			a, _ := strconv.ParseFloat(strings.TrimSpace(tokens[1]), 32)
+			a, _ := strconv.ParseFloat(tokens[1], 32)
 			mtl.Tr = float32(a)
+		case "Tr": // inverted transparency
+			a, _ := strconv.ParseFloat(tokens[1], 32)
+			mtl.Tr = 1 - float32(a)
+		case "Ns": // specular exponent
+			ns, _ := strconv.ParseFloat(tokens[1], 32)
+			mtl.Ns = float32(ns)
+		case "map_Kd": // diffuse texture. Options preceed the file name.
+			file := path.Base(strings.Replace(tokens[len(tokens)-1], "\\", "/", -1))
+			mtl.MapKd = strings.TrimSuffix(file, path.Ext(file))
 		case "newmtl": // material name
-		case "Ns": // specular exponent - scaler. Ignored for now.
+			mtl.Name = strings.Join(tokens[1:], " ")
 		case "Ni": // optical density - scaler. Ignored for now.
 		case "illum": // illumination model - int. Ignored for now.
 		}
 	}
-	return mtl, nil
+	return mtls, nil
 }
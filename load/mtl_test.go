@@ -20,3 +20,18 @@ func TestLoadMtl(t *testing.T) {
 		t.Errorf(format, got, want)
 	}
 }
+
+// Material libraries have many materials with texture maps.
+func TestLoadMtls(t *testing.T) {
+	load := newLoader().setDir(mod, "../eg/models")
+	mtls, err := load.mtls("level1")
+	if len(mtls) != 3 || err != nil {
+		t.Fatalf("Should be able to load a material library %s", err)
+	}
+	if m := mtls[1]; m.Name != "Floor1" || m.MapKd != "Floor1" || m.Tr != 0.7 || m.KsR != 0.5 {
+		t.Errorf("Expected Floor1 material, got %+v", m)
+	}
+	if m, _ := load.mtl("level1"); m.Name != "Block11" {
+		t.Errorf("Expected first material, got %s", m.Name)
+	}
+}
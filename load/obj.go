@@ -18,11 +18,20 @@ import (
 // The V,F buffers are expected to have data.
 // The N,T buffers are optional.
 type ObjData struct {
-	Name string    // Data name from .obj file.
-	V    []float32 // Vertex positions.    Arranged as [][3]float32
-	N    []float32 // Vertex normals.      Arranged as [][3]float32
-	T    []float32 // Texture coordinates. Arranged as [][2]float32
-	F    []uint16  // Triangle faces.      Arranged as [][3]uint16
+	Name   string     // Data name from .obj file.
+	V      []float32  // Vertex positions.    Arranged as [][3]float32
+	N      []float32  // Vertex normals.      Arranged as [][3]float32
+	T      []float32  // Texture coordinates. Arranged as [][2]float32
+	F      []uint16   // Triangle faces.      Arranged as [][3]uint16
+	Mtllib string     // Material library name without .mtl. Empty if none.
+	Groups []ObjGroup // Faces by material. Covers all of F.
+}
+
+// ObjGroup is a range of faces that share a material. Faces are
+// reordered so that each material in a mesh has one group.
+type ObjGroup struct {
+	Material string // Material name from the .obj file. Empty if none.
+	F0, Fn   int    // First face index in F, number of face indexes.
 }
 
 // obj loads a Wavefront .obj file containing one or more mesh descriptions.
@@ -34,20 +43,22 @@ type ObjData struct {
 //
 // Note that the .obj files refer to vertices and normals through a absolute
 // count from the beginning of the file. Both .obj and .mtl files can be
-// created from Blender.
+// created from Blender. The materials named by usemtl are in the mtllib
+// material library, see mtls.
 func (l *loader) obj(name string) (objs []*ObjData, err error) {
 	objs = []*ObjData{}
 	var file io.ReadCloser
 	fname := name + ".obj"
 	if file, err = l.getResource(l.dir[mod], fname); err == nil {
 		defer file.Close()
-		objects := l.obj2Strings(file)
+		objects, mtllib := l.obj2Strings(file)
 
 		// parse each wavefront object into a mesh.
 		odata := &objData{}
 		for _, obj := range objects {
 			if faces, derr := l.obj2Data(obj.lines, odata); derr == nil {
 				if objData, merr := l.obj2ObjData(obj.name, odata, faces); merr == nil {
+					objData.Mtllib = mtllib
 					objs = append(objs, objData)
 				} else {
 					return objs, fmt.Errorf("obj2ObjData %s: %s", fname, merr)
@@ -70,9 +81,10 @@ type objStrings struct {
 // Each .obj file keeps a global count of the data below.  This is referenced
 // from the face data.
 type objData struct {
-	v []dataPoint // vertices
-	n []dataPoint // normals
-	t []uvPoint   // texture coordinates
+	v   []dataPoint // vertices
+	n   []dataPoint // normals
+	t   []uvPoint   // texture coordinates
+	mtl string      // current material, kept across objects.
 }
 
 // dataPoint is an internal structure for passing vertices or normals.
@@ -87,13 +99,14 @@ type uvPoint struct {
 
 // face is an internal structure for passing face indexes.
 type face struct {
-	s []string // each point is a "x/y/z" value.
+	s   []string // each point is a "x/y/z" value.
+	mtl string   // material name.
 }
 
 // obj2Strings reads in all the file data grouped by object name. This is needed
 // because a single wavefront file can hold many objects. Separating the objects
-// makes parsing easier.
-func (l *loader) obj2Strings(file io.ReadCloser) (objs []*objStrings) {
+// makes parsing easier. The material library is also returned.
+func (l *loader) obj2Strings(file io.ReadCloser) (objs []*objStrings, mtllib string) {
 	objs = []*objStrings{}
 	name := ""
 	var curr *objStrings
@@ -106,6 +119,8 @@ func (l *loader) obj2Strings(file io.ReadCloser) (objs []*objStrings) {
 			name = strings.TrimSpace(tokens[1])
 			curr = &objStrings{name, []string{}}
 			objs = append(objs, curr)
+		} else if len(tokens) >= 2 && tokens[0] == "mtllib" {
+			mtllib = strings.TrimSuffix(strings.Join(tokens[1:], " "), ".mtl")
 		} else if len(name) > 0 {
 			curr.lines = append(curr.lines, strings.TrimSpace(line))
 		}
@@ -143,11 +158,12 @@ func (l *loader) obj2Data(lines []string, odata *objData) (faces []face, err err
 				log.Printf("Bad face: %s\n", line)
 				return faces, fmt.Errorf("could not parse face %s", e)
 			}
-			faces = append(faces, face{[]string{s1, s2, s3}})
+			faces = append(faces, face{[]string{s1, s2, s3}, odata.mtl})
 		case "o": // mesh name is processed before this method is called.
 		case "s": // FUTURE: smoothing group - ignored for now.
-		case "mtllib": // materials loaded separately and explicitly.
-		case "usemtl": // material name - ignored, see above.
+		case "mtllib": // material library is processed before this method is called.
+		case "usemtl": // material for the following faces.
+			odata.mtl = strings.TrimSpace(strings.TrimPrefix(line, "usemtl"))
 		}
 	}
 	return
@@ -165,12 +181,26 @@ func (l *loader) obj2Data(lines []string, odata *objData) (faces []face, err err
 // faces are the indexes for this mesh.
 //
 // Additionally the normals at each vertex are generated as the sum of the
-// normals for each face that shares that vertex.
+// normals for each face that shares that vertex. Faces are grouped
+// by material in the order that the materials are first used.
 func (l *loader) obj2ObjData(name string, odata *objData, faces []face) (data *ObjData, err error) {
 	data = &ObjData{}
 	data.Name = name
 	vmap := make(map[string]int) // the unique vertex data points for this face.
 	vcnt := -1
+	groups := map[string][]face{}
+	for _, face := range faces {
+		if _, ok := groups[face.mtl]; !ok {
+			data.Groups = append(data.Groups, ObjGroup{Material: face.mtl})
+		}
+		groups[face.mtl] = append(groups[face.mtl], face)
+	}
+	faces = make([]face, 0, len(faces))
+	for cnt, group := range data.Groups {
+		data.Groups[cnt].F0 = len(faces) * 3
+		data.Groups[cnt].Fn = len(groups[group.Material]) * 3
+		faces = append(faces, groups[group.Material]...)
+	}
 
 	// process each vertex of each face.  Each one represents a combination vertex,
 	// texture coordinate, and normal.
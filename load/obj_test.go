@@ -4,6 +4,9 @@
 package load
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -71,4 +74,27 @@ func TestLoadLevel(t *testing.T) {
 	if ms[0].Name != "Glow1" || ms[1].Name != "Block1" || ms[2].Name != "Floor1" {
 		t.Error("Invalid name level1.obj")
 	}
+	if g := ms[1].Groups; ms[1].Mtllib != "level1" || len(g) != 1 || g[0] != (ObjGroup{"Block1", 0, len(ms[1].F)}) {
+		t.Errorf("Expected one Block1 material group, got %s %+v", ms[1].Mtllib, g)
+	}
+}
+
+// Faces are grouped by material even when materials are used more than once.
+func TestLoadObjGroups(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "obj")
+	defer os.RemoveAll(dir)
+	obj := "mtllib quads.mtl\no quads\nv 0 0 0\nv 1 0 0\nv 1 1 0\nv 0 1 0\nvn 0 0 1\n" +
+		"usemtl red\nf 1//1 2//1 3//1\nusemtl blue\nf 1//1 3//1 4//1\nusemtl red\nf 3//1 2//1 1//1\n"
+	ioutil.WriteFile(filepath.Join(dir, "quads.obj"), []byte(obj), 0644)
+	ms, err := newLoader().setDir(mod, dir).obj("quads")
+	if err != nil || len(ms) != 1 {
+		t.Fatalf("Could not load quads.obj %s", err)
+	}
+	g := ms[0].Groups
+	if len(g) != 2 || g[0] != (ObjGroup{"red", 0, 6}) || g[1] != (ObjGroup{"blue", 6, 3}) {
+		t.Fatalf("Expected red and blue groups, got %+v", g)
+	}
+	if f := ms[0].F; f[3] != 2 || f[5] != 0 || f[8] != 3 || len(ms[0].V) != 12 {
+		t.Errorf("Expected faces ordered by material, got %v", f)
+	}
 }
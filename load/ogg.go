@@ -0,0 +1,216 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package load
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+)
+
+// ogg attempts to load an Ogg Vorbis audio file. The compressed audio
+// is decoded to 16 bit PCM data described by a header that matches a
+// .wav file holding the same audio. This allows compressed music and
+// longer sound effects to be used the same way as .wav files.
+// The Ogg container format is:
+//    https://xiph.org/ogg/doc/framing.html
+func (l *loader) ogg(filename string) (wh *WavHdr, data []byte, err error) {
+	var file io.ReadCloser
+	if file, err = l.getResource(l.dir[snd], filename+".ogg"); err == nil {
+		defer file.Close()
+		return l.loadOgg(file)
+	}
+	return nil, []byte{}, err
+}
+
+// loadOgg decodes the first Vorbis stream in an Ogg file.
+// Invalid files return a nil header and an empty data slice.
+func (l *loader) loadOgg(file io.Reader) (wh *WavHdr, data []byte, err error) {
+	raw, err := ioutil.ReadAll(file)
+	if err != nil {
+		return nil, []byte{}, fmt.Errorf("Invalid .ogg audio file: %s", err)
+	}
+	channels, rate, pcm, err := decodeOgg(raw)
+	if err != nil {
+		return nil, []byte{}, fmt.Errorf("Invalid .ogg audio file: %s", err)
+	}
+	data = make([]byte, len(pcm)*2)
+	for cnt, sample := range pcm {
+		binary.LittleEndian.PutUint16(data[cnt*2:], uint16(sample))
+	}
+	wh = &WavHdr{
+		RiffID:      [4]byte{'R', 'I', 'F', 'F'},
+		FileSize:    uint32(36 + len(data)),
+		WaveID:      [4]byte{'W', 'A', 'V', 'E'},
+		Fmt:         [4]byte{'f', 'm', 't', ' '},
+		FmtSize:     16,
+		AudioFormat: 1,
+		Channels:    uint16(channels),
+		Frequency:   uint32(rate),
+		ByteRate:    uint32(rate * channels * 2),
+		BlockAlign:  uint16(channels * 2),
+		SampleBits:  16,
+		DataID:      [4]byte{'d', 'a', 't', 'a'},
+		DataSize:    uint32(len(data)),
+	}
+	return wh, data, nil
+}
+
+// decodeOgg returns the interleaved 16 bit samples of the first
+// Vorbis stream in the Ogg file data.
+func decodeOgg(raw []byte) (channels, rate int, pcm []int16, err error) {
+	r := &oggReader{data: raw, granule: -1}
+	v := &vorbis{}
+	for kind := 1; kind <= 5; kind += 2 { // identification, comment, setup.
+		packet, perr := r.next()
+		if perr == io.EOF {
+			return 0, 0, nil, fmt.Errorf("missing vorbis headers")
+		}
+		if perr != nil {
+			return 0, 0, nil, perr
+		}
+		if err = v.header(packet, kind); err != nil {
+			return 0, 0, nil, err
+		}
+	}
+	for {
+		packet, perr := r.next()
+		if perr == io.EOF {
+			break
+		}
+		if perr != nil {
+			return 0, 0, nil, perr
+		}
+		pcm = v.decode(packet, pcm)
+	}
+
+	// the last granule position is the number of samples in the stream.
+	// It trims the padding from the final block.
+	if size := r.granule * int64(v.channels); r.granule >= 0 && size < int64(len(pcm)) {
+		pcm = pcm[:size]
+	}
+	return v.channels, v.rate, pcm, nil
+}
+
+// pcm16 converts a decoded sample to 16 bits.
+func pcm16(sample float64) int16 {
+	return int16(math.Max(-32768, math.Min(32767, math.Floor(sample*32767+0.5))))
+}
+
+// Ogg
+// =============================================================================
+// oggReader returns the packets of one logical stream.
+
+// oggReader splits Ogg pages into the packets of the first logical
+// stream in the file. Pages of any other streams are skipped.
+type oggReader struct {
+	data    []byte // Ogg file data.
+	at      int    // Offset of the next page.
+	serial  uint32 // Logical stream being read.
+	started bool   // True once the first page has been read.
+	last    bool   // True once the end of stream page has been read.
+	segs    []byte // Unread lacing values of the current page.
+	body    []byte // Unread data of the current page.
+	packet  []byte // Packet being assembled across pages.
+	granule int64  // Granule position of the last page, -1 if none.
+}
+
+// next returns the next complete packet. Returns io.EOF when there
+// are no more packets.
+func (r *oggReader) next() (packet []byte, err error) {
+	for {
+		for len(r.segs) > 0 {
+			size := int(r.segs[0])
+			r.packet = append(r.packet, r.body[:size]...)
+			r.segs, r.body = r.segs[1:], r.body[size:]
+			if size < 255 {
+				packet, r.packet = r.packet, nil
+				return packet, nil
+			}
+		}
+		if r.last {
+			return nil, io.EOF
+		}
+		if err = r.page(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// page reads the next page of the logical stream. A file that
+// ends without an end of stream page is treated as complete.
+func (r *oggReader) page() error {
+	for {
+		page := r.data[r.at:]
+		if len(page) < 27 {
+			return io.EOF
+		}
+		if string(page[:4]) != "OggS" || page[4] != 0 {
+			return fmt.Errorf("invalid ogg page")
+		}
+		hdr := 27 + int(page[26])
+		if len(page) < hdr {
+			return io.EOF
+		}
+		size := hdr
+		for _, seg := range page[27:hdr] {
+			size += int(seg)
+		}
+		if len(page) < size {
+			return io.EOF
+		}
+		page = page[:size]
+		r.at += size
+		if oggCrc(page) != binary.LittleEndian.Uint32(page[22:]) {
+			return fmt.Errorf("corrupt ogg page")
+		}
+		serial := binary.LittleEndian.Uint32(page[14:])
+		if !r.started {
+			r.started, r.serial = true, serial
+		}
+		if serial != r.serial {
+			continue
+		}
+		if page[5]&1 == 0 {
+			r.packet = nil // not a continued page: drop any partial packet.
+		}
+		if granule := int64(binary.LittleEndian.Uint64(page[6:])); granule != -1 {
+			r.granule = granule
+		}
+		r.last = page[5]&4 != 0
+		r.segs, r.body = page[27:hdr], page[hdr:]
+		return nil
+	}
+}
+
+// oggCrc returns the page checksum. The checksum field
+// itself is treated as zero.
+func oggCrc(page []byte) (crc uint32) {
+	for cnt, b := range page {
+		if cnt >= 22 && cnt < 26 {
+			b = 0
+		}
+		crc = crc<<8 ^ oggCrcTable[byte(crc>>24)^b]
+	}
+	return crc
+}
+
+// oggCrcTable holds the checksum of each byte value
+// using the polynomial 0x04c11db7.
+var oggCrcTable = func() (table [256]uint32) {
+	for cnt := range table {
+		crc := uint32(cnt) << 24
+		for bit := 0; bit < 8; bit++ {
+			if crc&0x80000000 != 0 {
+				crc = crc<<1 ^ 0x04c11db7
+			} else {
+				crc <<= 1
+			}
+		}
+		table[cnt] = crc
+	}
+	return table
+}()
@@ -0,0 +1,245 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package load
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+// Decode a small hand built mono stream and check the samples against
+// a direct calculation of the windowed and overlapped inverse MDCT.
+func TestLoadOgg(t *testing.T) {
+	floor, blocks := 220, []map[int]int{{5: 3}, {20: -2}, {}}
+	stream := testOggStream(floor, blocks, 250)
+	wh, data, err := newLoader().loadOgg(bytes.NewReader(stream))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wh.Channels != 1 || wh.Frequency != 8000 || wh.SampleBits != 16 || int(wh.DataSize) != len(data) {
+		t.Fatalf("Unexpected header %+v", wh)
+	}
+	if len(data) != 250*2 {
+		t.Fatalf("Expected 250 samples, got %d", len(data)/2)
+	}
+
+	// expected samples overlap the second half of each block
+	// with the first half of the next block.
+	n, amp := 256, math.Pow(10, float64(floor-255)*7/256)
+	out := make([][]float64, len(blocks))
+	for cnt, res := range blocks {
+		out[cnt] = make([]float64, n)
+		for i := range out[cnt] {
+			for k, val := range res {
+				out[cnt][i] += float64(val) * amp * math.Cos(math.Pi/float64(2*n)*float64(2*i+1+n/2)*float64(2*k+1))
+			}
+			w := math.Sin((float64(i) + 0.5) / float64(n/2) * math.Pi / 2)
+			if i >= n/2 {
+				w = math.Sin((float64(n-1-i) + 0.5) / float64(n/2) * math.Pi / 2)
+			}
+			out[cnt][i] *= math.Sin(math.Pi / 2 * w * w)
+		}
+	}
+	for cnt := 0; cnt < 250; cnt++ {
+		block, at := cnt/(n/2)+1, cnt%(n/2)
+		want := math.Floor((out[block-1][n/2+at]+out[block][at])*32767 + 0.5)
+		if got := float64(int16(binary.LittleEndian.Uint16(data[cnt*2:]))); math.Abs(got-want) > 1 {
+			t.Fatalf("Sample %d expected %f got %f", cnt, want, got)
+		}
+	}
+
+	// corrupt page data is detected.
+	stream[len(stream)-1] ^= 0xff
+	if _, _, err = newLoader().loadOgg(bytes.NewReader(stream)); err == nil {
+		t.Error("Expected corrupt page error")
+	}
+}
+
+func TestInvalidLoadOgg(t *testing.T) {
+	load := newLoader().setDir(snd, "../eg/audio")
+	if _, data, err := load.ogg("xxx"); err == nil || len(data) != 0 {
+		t.Error("Should not be able to load a missing file")
+	}
+	if _, data, err := load.loadOgg(bytes.NewReader([]byte("OggS"))); err == nil || len(data) != 0 {
+		t.Error("Should not be able to load an invalid file")
+	}
+}
+
+// testOggStream creates an 8000Hz mono stream with 256 sample blocks.
+// The floor is flat and the residue values are given for each block.
+// The setup uses the following codebooks:
+//    0: 256 floor values with 8 bit codes.
+//    1: 2 residue classes with 1 bit codes: unused or coded.
+//    2: 16 residue values -8 to 7 with 4 bit codes.
+func testOggStream(floor int, blocks []map[int]int, samples int64) []byte {
+	id := testVorbisHeader(1)
+	id.write(0, 32)    // version
+	id.write(1, 8)     // channels
+	id.write(8000, 32) // rate
+	id.write(0, 32)    // bit rates
+	id.write(0, 32)    //   "
+	id.write(0, 32)    //   "
+	id.write(8, 4)     // short block 256
+	id.write(8, 4)     // long block 256
+	id.write(1, 1)     // framing
+	comment := testVorbisHeader(3)
+	comment.write(0, 32) // vendor
+	comment.write(0, 32) // comments
+	comment.write(1, 1)  // framing
+
+	setup := testVorbisHeader(5)
+	setup.write(2, 8) // 3 codebooks
+	testBook(setup, 256, 8)
+	setup.write(0, 4) // no lookup
+	testBook(setup, 2, 1)
+	setup.write(0, 4) // no lookup
+	testBook(setup, 16, 4)
+	setup.write(1, 4)                     // lookup type 1
+	setup.write(0x80000000|788<<21|8, 32) // minimum -8
+	setup.write(788<<21|1, 32)            // delta 1
+	setup.write(3, 4)                     // 4 bit values
+	setup.write(0, 1)                     // not sequential
+	for cnt := 0; cnt < 16; cnt++ {
+		setup.write(uint32(cnt), 4)
+	}
+	setup.write(0, 6)  // 1 time domain
+	setup.write(0, 16) //   "
+	setup.write(0, 6)  // 1 floor
+	setup.write(1, 16) // floor type 1
+	setup.write(1, 5)  // 1 partition
+	setup.write(0, 4)  // partition class 0
+	setup.write(0, 3)  // class 0 has 1 value
+	setup.write(0, 2)  // no subclasses
+	setup.write(1, 8)  // value book 0
+	setup.write(0, 2)  // multiplier 1
+	setup.write(7, 4)  // 7 bit locations
+	setup.write(64, 7) // value location
+	setup.write(0, 6)  // 1 residue
+	setup.write(1, 16) // residue type 1
+	setup.write(0, 24) // begin
+	setup.write(128, 24)
+	setup.write(31, 24) // partition size 32
+	setup.write(1, 6)   // 2 classes
+	setup.write(1, 8)   // class book 1
+	setup.write(0, 3)   // class 0 has no passes
+	setup.write(0, 1)   //   "
+	setup.write(1, 3)   // class 1 has pass 0
+	setup.write(0, 1)   //   "
+	setup.write(2, 8)   // pass 0 book 2
+	setup.write(0, 6)   // 1 mapping
+	setup.write(0, 16)  // type 0
+	setup.write(0, 1)   // 1 submap
+	setup.write(0, 1)   // no coupling
+	setup.write(0, 2)   // reserved
+	setup.write(0, 8)   // submap time
+	setup.write(0, 8)   // submap floor
+	setup.write(0, 8)   // submap residue
+	setup.write(0, 6)   // 1 mode
+	setup.write(0, 1)   // short blocks
+	setup.write(0, 16)  // window
+	setup.write(0, 16)  // transform
+	setup.write(0, 8)   // mapping
+	setup.write(1, 1)   // framing
+
+	stream := testOggPage(id.data, 0, 2, 0)
+	stream = append(stream, testOggPage(comment.data, 1, 0, 0)...)
+	stream = append(stream, testOggPage(setup.data, 2, 0, 0)...)
+	for cnt, res := range blocks {
+		audio := &testBits{}
+		audio.write(0, 1)             // audio packet, only one mode.
+		audio.write(1, 1)             // floor used
+		audio.write(uint32(floor), 8) // floor values
+		audio.write(uint32(floor), 8) //   "
+		audio.code(0, 8)              // value 0 stays on the line.
+		for part := 0; part < 4; part++ {
+			coded := false
+			for k := range res {
+				coded = coded || k/32 == part
+			}
+			if !coded {
+				audio.code(0, 1)
+				continue
+			}
+			audio.code(1, 1)
+			for k := part * 32; k < part*32+32; k++ {
+				audio.code(uint32(res[k]+8), 4)
+			}
+		}
+		flags, granule := byte(0), int64(-1)
+		if cnt == len(blocks)-1 {
+			flags, granule = 4, samples
+		}
+		stream = append(stream, testOggPage(audio.data, uint32(3+cnt), flags, granule)...)
+	}
+	return stream
+}
+
+// testBits writes values least significant bit first.
+type testBits struct {
+	data []byte
+	at   int
+}
+
+func (b *testBits) write(val uint32, n int) {
+	for cnt := 0; cnt < n; cnt++ {
+		if b.at/8 >= len(b.data) {
+			b.data = append(b.data, 0)
+		}
+		b.data[b.at/8] |= byte(val>>uint(cnt)&1) << uint(b.at%8)
+		b.at++
+	}
+}
+
+// code writes a Huffman code most significant bit first.
+func (b *testBits) code(code uint32, n int) {
+	for cnt := n - 1; cnt >= 0; cnt-- {
+		b.write(code>>uint(cnt)&1, 1)
+	}
+}
+
+// testVorbisHeader starts a header packet.
+func testVorbisHeader(kind uint32) *testBits {
+	b := &testBits{}
+	b.write(kind, 8)
+	for _, c := range []byte("vorbis") {
+		b.write(uint32(c), 8)
+	}
+	return b
+}
+
+// testBook writes a one dimensional codebook with equal length codes.
+func testBook(b *testBits, entries, length int) {
+	b.write(0x564342, 24)
+	b.write(1, 16)
+	b.write(uint32(entries), 24)
+	b.write(0, 1) // not ordered
+	b.write(0, 1) // not sparse
+	for cnt := 0; cnt < entries; cnt++ {
+		b.write(uint32(length-1), 5)
+	}
+}
+
+// testOggPage wraps one packet in an Ogg page.
+func testOggPage(packet []byte, seq uint32, flags byte, granule int64) []byte {
+	segs := []byte{}
+	for size := len(packet); ; size -= 255 {
+		if size < 255 {
+			segs = append(segs, byte(size))
+			break
+		}
+		segs = append(segs, 255)
+	}
+	page := make([]byte, 27, 27+len(segs)+len(packet))
+	copy(page, "OggS")
+	page[5] = flags
+	binary.LittleEndian.PutUint64(page[6:], uint64(granule))
+	binary.LittleEndian.PutUint32(page[14:], 7)
+	binary.LittleEndian.PutUint32(page[18:], seq)
+	page[26] = byte(len(segs))
+	page = append(append(page, segs...), packet...)
+	binary.LittleEndian.PutUint32(page[22:], oggCrc(page))
+	return page
+}
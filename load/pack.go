@@ -0,0 +1,274 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package load
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Pack files bundle the asset directories of a shipped game into one
+// file so that assets are not read from a loose directory tree. A pack
+// file is found as "resources.vpk" in the same places as the resource
+// zip file, and may also be appended to the executable. Packs are
+// created by PackDirs, or by adding assets to a Packer.
+//
+// Each pack has a header, the asset data, an index, and a trailer:
+//     header : "vupk" version:uint32
+//     data   : asset bytes. Deflate compressed for PackDeflate.
+//     index  : count:uint32 then for each asset:
+//              name_len:uint16 name flags:uint32 offset:uint64 size:uint64 hash:[32]byte
+//     trailer: index_offset:uint64 pack_size:uint64 "vupk"
+// Numbers are little endian and offsets are from the start of the pack.
+// Asset names are slash separated paths in the asset directories, ie:
+// "models/box.obj". Hashes are the SHA-256 of the uncompressed asset and
+// are checked, for PackHashed assets, when the asset has been read.
+type Packer interface {
+	Add(name string, data []byte, flags int) error // Flags are Pack*.
+	Close() error                                  // Writes the index.
+}
+
+// Pack asset flags.
+const (
+	PackDeflate = 1 << iota // Compress the asset.
+	PackHashed              // Check the asset hash when it is read.
+)
+
+// Pack file layout constants.
+const (
+	packMagic   = "vupk"
+	packVersion = 1
+	packTrailer = 8 + 8 + 4 // index offset, pack size, magic.
+)
+
+// NewPacker creates a Packer that writes a pack file to w.
+func NewPacker(w io.Writer) Packer { return &packer{w: w} }
+
+// PackDirs writes a pack file to w with all the files in the given
+// asset directories, ie:
+//     err := load.PackDirs(file, load.PackDeflate|load.PackHashed, "models", "images")
+// Asset names are the file paths relative to the current directory.
+func PackDirs(w io.Writer, flags int, dirs ...string) error {
+	p := NewPacker(w)
+	for _, dir := range dirs {
+		err := filepath.Walk(dir, func(file string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return err
+			}
+			data, err := ioutil.ReadFile(file)
+			if err != nil {
+				return err
+			}
+			return p.Add(filepath.ToSlash(file), data, flags)
+		})
+		if err != nil {
+			return fmt.Errorf("PackDirs: %s", err)
+		}
+	}
+	return p.Close()
+}
+
+// Packer
+// =============================================================================
+// packer implements Packer.
+
+// packer writes assets as they are added and the index on Close.
+type packer struct {
+	w       io.Writer       // Pack file.
+	at      uint64          // Bytes written.
+	entries []*packEntry    // Added assets.
+	names   map[string]bool // Added asset names. Nil until the header is written.
+	err     error           // First write error.
+}
+
+// packEntry is one asset in the pack index.
+type packEntry struct {
+	name   string   // Slash separated path.
+	flags  uint32   // Pack* flags.
+	offset uint64   // Start of data from the start of the pack.
+	size   uint64   // Stored data size.
+	hash   [32]byte // SHA-256 of the uncompressed data.
+}
+
+// Implement Packer.
+func (p *packer) Add(name string, data []byte, flags int) error {
+	if p.names == nil {
+		p.names = map[string]bool{}
+		p.write([]byte(packMagic), uint32(packVersion))
+	}
+	if p.names[name] || len(name) == 0 || len(name) > 0xFFFF {
+		return fmt.Errorf("pack: invalid or duplicate asset name %s", name)
+	}
+	p.names[name] = true
+	e := &packEntry{name: name, flags: uint32(flags), offset: p.at, hash: sha256.Sum256(data)}
+	if flags&PackDeflate != 0 {
+		buff := &bytes.Buffer{}
+		fw, _ := flate.NewWriter(buff, flate.BestCompression)
+		fw.Write(data)
+		fw.Close()
+		data = buff.Bytes()
+	}
+	e.size = uint64(len(data))
+	p.write(data)
+	p.entries = append(p.entries, e)
+	return p.err
+}
+func (p *packer) Close() error {
+	if p.names == nil {
+		p.write([]byte(packMagic), uint32(packVersion)) // empty pack.
+	}
+	index := p.at
+	p.write(uint32(len(p.entries)))
+	for _, e := range p.entries {
+		p.write(uint16(len(e.name)), []byte(e.name), e.flags, e.offset, e.size, e.hash)
+	}
+	p.write(index, p.at+packTrailer, []byte(packMagic))
+	return p.err
+}
+
+// write appends the little endian values to the pack file.
+func (p *packer) write(values ...interface{}) {
+	for _, v := range values {
+		if p.err == nil {
+			p.err = binary.Write(p.w, binary.LittleEndian, v)
+			p.at += uint64(binary.Size(v))
+		}
+	}
+}
+
+// packer
+// =============================================================================
+// pack reads pack files.
+
+// pack is an opened pack file.
+type pack struct {
+	r     io.ReaderAt           // Pack file.
+	c     io.Closer             // Closes the pack file. Nil if none.
+	base  int64                 // Start of the pack within r.
+	index map[string]*packEntry // Assets by name.
+}
+
+// openPack opens the pack at the end of the named file.
+func openPack(name string) (p *pack, err error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err == nil {
+		if p, err = readPack(file, info.Size()); err == nil {
+			p.c = file
+			return p, nil
+		}
+	}
+	file.Close()
+	return nil, err
+}
+
+// readPack reads the index of the pack that ends at size.
+func readPack(r io.ReaderAt, size int64) (*pack, error) {
+	trailer := make([]byte, packTrailer)
+	if size < packTrailer {
+		return nil, fmt.Errorf("pack: no pack")
+	}
+	if _, err := r.ReadAt(trailer, size-packTrailer); err != nil {
+		return nil, fmt.Errorf("pack: %s", err)
+	}
+	if string(trailer[16:]) != packMagic {
+		return nil, fmt.Errorf("pack: no pack")
+	}
+	index := binary.LittleEndian.Uint64(trailer[0:])
+	packSize := binary.LittleEndian.Uint64(trailer[8:])
+	if packSize > uint64(size) || index > packSize-packTrailer {
+		return nil, fmt.Errorf("pack: invalid trailer")
+	}
+	p := &pack{r: r, base: size - int64(packSize), index: map[string]*packEntry{}}
+	header := make([]byte, 8)
+	if _, err := r.ReadAt(header, p.base); err != nil || string(header[:4]) != packMagic {
+		return nil, fmt.Errorf("pack: invalid header")
+	}
+	if version := binary.LittleEndian.Uint32(header[4:]); version != packVersion {
+		return nil, fmt.Errorf("pack: unsupported version %d", version)
+	}
+	ir := io.NewSectionReader(r, p.base+int64(index), int64(packSize-packTrailer-index))
+	var count uint32
+	if err := binary.Read(ir, binary.LittleEndian, &count); err != nil {
+		return nil, fmt.Errorf("pack: invalid index %s", err)
+	}
+	for cnt := uint32(0); cnt < count; cnt++ {
+		var nameLen uint16
+		if err := binary.Read(ir, binary.LittleEndian, &nameLen); err != nil {
+			return nil, fmt.Errorf("pack: invalid index %s", err)
+		}
+		name := make([]byte, nameLen)
+		e := &packEntry{}
+		for _, v := range []interface{}{name, &e.flags, &e.offset, &e.size, &e.hash} {
+			if err := binary.Read(ir, binary.LittleEndian, v); err != nil {
+				return nil, fmt.Errorf("pack: invalid index %s", err)
+			}
+		}
+		if e.offset+e.size > index {
+			return nil, fmt.Errorf("pack: invalid asset %s", name)
+		}
+		e.name = string(name)
+		p.index[e.name] = e
+	}
+	return p, nil
+}
+
+// open returns a reader for the named asset,
+// or false if the asset is not in the pack.
+func (p *pack) open(name string) (file io.ReadCloser, ok bool) {
+	e, ok := p.index[name]
+	if !ok {
+		return nil, false
+	}
+	pr := &packReader{name: name, r: io.NewSectionReader(p.r, p.base+int64(e.offset), int64(e.size))}
+	if e.flags&PackDeflate != 0 {
+		fr := flate.NewReader(pr.r)
+		pr.r, pr.c = fr, fr
+	}
+	if e.flags&PackHashed != 0 {
+		pr.hash, pr.want = sha256.New(), e.hash
+	}
+	return pr, true
+}
+
+// packReader reads one asset, checking the hash at the end of the data.
+type packReader struct {
+	name string    // Asset name.
+	r    io.Reader // Asset data.
+	c    io.Closer // Decompressor. Nil if uncompressed.
+	hash hash.Hash // Nil if unchecked.
+	want [32]byte  // Expected hash.
+}
+
+// Read implements io.Reader. Returns an error instead of io.EOF
+// if the data does not match the hash.
+func (pr *packReader) Read(b []byte) (n int, err error) {
+	n, err = pr.r.Read(b)
+	if pr.hash != nil {
+		pr.hash.Write(b[:n])
+		if err == io.EOF && !bytes.Equal(pr.hash.Sum(nil), pr.want[:]) {
+			err = fmt.Errorf("pack: corrupt asset %s", pr.name)
+		}
+	}
+	return n, err
+}
+
+// Close implements io.Closer.
+func (pr *packReader) Close() error {
+	if pr.c != nil {
+		return pr.c.Close()
+	}
+	return nil
+}
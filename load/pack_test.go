@@ -0,0 +1,85 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package load
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Check that packed assets are read back, even when the pack
+// is appended to another file, and that hashes are checked.
+func TestPack(t *testing.T) {
+	buff := &bytes.Buffer{}
+	buff.WriteString("executable")
+	p := NewPacker(buff)
+	shader := bytes.Repeat([]byte("void main() {}\n"), 100)
+	p.Add("source/tint.vsh", shader, PackDeflate|PackHashed)
+	p.Add("models/box.obj", []byte("o box\n"), 0)
+	p.Add("images/wood.png", []byte("png"), PackHashed)
+	if err := p.Add("images/wood.png", nil, 0); err == nil {
+		t.Errorf("Expected duplicate name error")
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	data := buff.Bytes()
+	pk, err := readPack(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pk.index) != 3 || pk.index["source/tint.vsh"].size >= uint64(len(shader)) {
+		t.Fatalf("Expected 3 assets with a compressed shader, got %+v", pk.index)
+	}
+	l := newLoader()
+	l.pack = pk
+	if src, err := l.txt("tint.vsh"); err != nil || len(src) != 100 {
+		t.Errorf("Expected packed shader, got %d %s", len(src), err)
+	}
+	if file, err := l.getResource("models", "box.obj"); err != nil {
+		t.Errorf("Expected packed model %s", err)
+	} else if b, _ := ioutil.ReadAll(file); string(b) != "o box\n" {
+		t.Errorf("Expected box model, got %s", b)
+	}
+
+	// corrupt hashed assets fail when read.
+	at := pk.index["images/wood.png"].offset + uint64(len("executable"))
+	data[at] = 'j'
+	file, _ := pk.open("images/wood.png")
+	if _, err := ioutil.ReadAll(file); err == nil {
+		t.Errorf("Expected corrupt asset error")
+	}
+	if _, err := readPack(bytes.NewReader(data[:len(data)-1]), int64(len(data)-1)); err == nil {
+		t.Errorf("Expected error for a truncated pack")
+	}
+}
+
+// PackDirs adds each file using its slash separated path.
+func TestPackDirs(t *testing.T) {
+	wd, _ := os.Getwd()
+	dir, _ := ioutil.TempDir("", "pack")
+	defer os.RemoveAll(dir)
+	defer os.Chdir(wd)
+	os.Chdir(dir)
+	os.MkdirAll(filepath.Join("models", "level"), 0755)
+	ioutil.WriteFile(filepath.Join("models", "level", "one.scn"), []byte("{}"), 0644)
+	file, _ := os.Create("resources.vpk")
+	if err := PackDirs(file, PackDeflate, "models"); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+	pk, err := openPack("resources.vpk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pk.Close()
+	if f, ok := pk.open("models/level/one.scn"); !ok {
+		t.Errorf("Expected packed scene")
+	} else if b, _ := ioutil.ReadAll(f); string(b) != "{}" {
+		t.Errorf("Expected scene data, got %s", b)
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package load
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Source locates asset files so that assets can come from somewhere
+// other than the local disk, ie: from an embedded file system for single
+// binary distribution, or downloaded from a server:
+//     //go:embed models images source audio
+//     var assets embed.FS
+//     ...
+//     load.AddSource(load.FSSource(assets))
+//     load.AddSource(load.URLSource("https://example.com/game/"))
+// Assets are named by their slash separated path in the asset
+// directories, ie: "models/box.obj". Sources are checked in the order
+// they are added, before the default pack, zip, and disk sources.
+type Source interface {
+	Open(name string) (file io.ReadCloser, err error) // Caller closes file.
+}
+
+// AddSource adds a source for all loaders created after the call.
+// Expected to be called on startup before the engine is created.
+func AddSource(s Source) {
+	sources.Lock()
+	defer sources.Unlock()
+	sources.list = append(sources.list, s)
+}
+
+// DirSource returns a source for the asset files in a disk directory.
+func DirSource(dir string) Source { return &dirSource{dir: dir} }
+
+// FSSource returns a source for the asset files
+// in a file system, ie: a go:embed embed.FS.
+func FSSource(fsys fs.FS) Source { return &fsSource{fsys: fsys} }
+
+// URLSource returns a source that downloads asset files
+// from the given base URL, ie: "https://example.com/game/"
+// for "https://example.com/game/models/box.obj".
+func URLSource(base string) Source {
+	if !strings.HasSuffix(base, "/") {
+		base += "/"
+	}
+	return &urlSource{base: base, client: http.DefaultClient}
+}
+
+// PackSource returns a source for the assets in the named pack file.
+// The source is also an io.Closer that releases the file. See pack.go.
+func PackSource(file string) (Source, error) { return openPack(file) }
+
+// sources are the sources added by AddSource.
+var sources struct {
+	sync.Mutex
+	list []Source
+}
+
+// defaultSources returns a copy of the sources added by AddSource.
+func defaultSources() []Source {
+	sources.Lock()
+	defer sources.Unlock()
+	return append([]Source{}, sources.list...)
+}
+
+// Source
+// =============================================================================
+// Source implementations.
+
+// dirSource opens disk files.
+type dirSource struct {
+	dir string // Asset directory root.
+}
+
+// Open implements Source.
+func (s *dirSource) Open(name string) (file io.ReadCloser, err error) {
+	return os.Open(filepath.Join(s.dir, filepath.FromSlash(name)))
+}
+
+// fsSource opens files from a file system.
+type fsSource struct {
+	fsys fs.FS // Asset directory root.
+}
+
+// Open implements Source.
+func (s *fsSource) Open(name string) (file io.ReadCloser, err error) {
+	return s.fsys.Open(name)
+}
+
+// urlSource downloads files.
+type urlSource struct {
+	base   string       // URL ending with a slash.
+	client *http.Client // Shared HTTP connections.
+}
+
+// Open implements Source.
+func (s *urlSource) Open(name string) (file io.ReadCloser, err error) {
+	resp, err := s.client.Get(s.base + name)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("could not download %s: %s", name, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// Open implements Source.
+func (p *pack) Open(name string) (file io.ReadCloser, err error) {
+	if file, ok := p.open(name); ok {
+		return file, nil
+	}
+	return nil, fmt.Errorf("pack: no asset %s", name)
+}
+
+// Close implements io.Closer.
+func (p *pack) Close() error {
+	if p.c != nil {
+		return p.c.Close()
+	}
+	return nil
+}
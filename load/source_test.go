@@ -0,0 +1,51 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package load
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+// Check that assets are found in the added sources, in order,
+// before the default disk files.
+func TestSources(t *testing.T) {
+	fsys := fstest.MapFS{
+		"source/tint.fsh": {Data: []byte("embedded\n")},
+		"models/box.obj":  {Data: []byte("o box\n")},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/game/source/tint.fsh" && r.URL.Path != "/game/source/tint.vsh" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("downloaded\n"))
+	}))
+	defer server.Close()
+	l := newLoader().addSource(FSSource(fsys)).addSource(URLSource(server.URL + "/game"))
+	if src, err := l.txt("tint.fsh"); err != nil || src[0] != "embedded\n" {
+		t.Errorf("Expected embedded shader, got %v %s", src, err)
+	}
+	if src, err := l.txt("tint.vsh"); err != nil || src[0] != "downloaded\n" {
+		t.Errorf("Expected downloaded shader, got %v %s", src, err)
+	}
+	if _, err := l.txt("none.vsh"); err == nil {
+		t.Errorf("Expected error for a missing asset")
+	}
+
+	// added sources are used by new loaders.
+	AddSource(DirSource("../eg"))
+	defer func() { sources.list = nil }()
+	if meshes, err := newLoader().obj("cube"); err != nil || len(meshes) != 1 {
+		t.Errorf("Expected cube from the directory source %s", err)
+	}
+	if file, err := FSSource(fsys).Open("models/box.obj"); err != nil {
+		t.Errorf("Expected box %s", err)
+	} else if b, _ := ioutil.ReadAll(file); string(b) != "o box\n" {
+		t.Errorf("Expected box model, got %s", b)
+	}
+}
@@ -0,0 +1,1063 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package load
+
+import (
+	"fmt"
+	"math"
+)
+
+// vorbis decodes the packets of a Vorbis I audio stream. The format is:
+//    https://xiph.org/vorbis/doc/Vorbis_I_spec.html
+// Only floor type 1 is supported. Floor type 0 was replaced by floor 1
+// before the Vorbis 1.0 release and is not used by current encoders.
+type vorbis struct {
+	channels int        // Number of audio channels.
+	rate     int        // Samples per second.
+	bsize    [2]int     // Short and long block sizes.
+	books    []codebook // Setup header configuration...
+	floors   []floor1   //   "
+	residues []residue  //   "
+	maps     []mapping  //   "
+	modes    []mode     //   "
+
+	// Transforms and windows for the short and long blocks.
+	imdct  [2]*imdct
+	slopes [2][]float64
+
+	// Decoding state and scratch buffers reused for each packet.
+	prev  [][]float64 // Windowed samples of the previous block per channel.
+	prevN int         // Size of the previous block, 0 before the first.
+	out   [][]float64 // Windowed samples of the current block per channel.
+	spec  [][]float64 // Spectrum of the current block per channel.
+	ys    [][]int     // Floor values per channel.
+	used  []bool      // True for channels with a floor.
+	skip  []bool      // True for channels without residue.
+	curve []float64   // Floor curve.
+	steps []bool      // Floor values used for the curve.
+	vecs  [][]float64 // Residue vectors of a submap.
+	skips []bool      // Residue flags of a submap.
+	work  []float64   // Interleaved residue vector.
+	bits  bitReader   // Packet reader.
+}
+
+// header reads one of the three header packets that start the stream.
+// The kind is 1 for identification, 3 for comments and 5 for setup.
+func (v *vorbis) header(packet []byte, kind int) error {
+	if len(packet) < 7 || int(packet[0]) != kind || string(packet[1:7]) != "vorbis" {
+		return fmt.Errorf("expected vorbis header %d", kind)
+	}
+	b := &bitReader{data: packet, at: 7 * 8}
+	switch kind {
+	case 1:
+		return v.identification(b)
+	case 3:
+		return nil // comments are not used.
+	}
+	return v.setup(b)
+}
+
+// identification reads the audio format.
+func (v *vorbis) identification(b *bitReader) error {
+	if b.read(32) != 0 {
+		return fmt.Errorf("unsupported vorbis version")
+	}
+	v.channels, v.rate = int(b.read(8)), int(b.read(32))
+	b.read(32) // bit rates are not used.
+	b.read(32) //   "
+	b.read(32) //   "
+	v.bsize[0], v.bsize[1] = 1<<b.read(4), 1<<b.read(4)
+	if v.channels == 0 || v.rate == 0 || v.bsize[0] < 64 || v.bsize[0] > v.bsize[1] ||
+		v.bsize[1] > 8192 || !b.flag() || b.eop {
+		return fmt.Errorf("invalid vorbis identification header")
+	}
+	return nil
+}
+
+// setup reads the decoder configuration and prepares the decoding buffers.
+func (v *vorbis) setup(b *bitReader) (err error) {
+	v.books = make([]codebook, b.read(8)+1)
+	for cnt := range v.books {
+		if err = v.books[cnt].read(b); err != nil {
+			return err
+		}
+	}
+	for cnt := b.read(6) + 1; cnt > 0; cnt-- {
+		if b.read(16) != 0 {
+			return fmt.Errorf("invalid vorbis time domain")
+		}
+	}
+	v.floors = make([]floor1, b.read(6)+1)
+	for cnt := range v.floors {
+		if err = v.floors[cnt].read(b, len(v.books)); err != nil {
+			return err
+		}
+	}
+	v.residues = make([]residue, b.read(6)+1)
+	for cnt := range v.residues {
+		if err = v.residues[cnt].read(b, v.books); err != nil {
+			return err
+		}
+	}
+	v.maps = make([]mapping, b.read(6)+1)
+	for cnt := range v.maps {
+		if err = v.maps[cnt].read(b, v); err != nil {
+			return err
+		}
+	}
+	v.modes = make([]mode, b.read(6)+1)
+	for cnt := range v.modes {
+		md := &v.modes[cnt]
+		md.long = b.flag()
+		window, transform := b.read(16), b.read(16)
+		if md.mapping = int(b.read(8)); window != 0 || transform != 0 || md.mapping >= len(v.maps) {
+			return fmt.Errorf("invalid vorbis mode")
+		}
+	}
+	if !b.flag() || b.eop {
+		return fmt.Errorf("invalid vorbis setup header")
+	}
+
+	// allocate the decoding buffers.
+	for cnt, n := range v.bsize {
+		v.imdct[cnt] = newImdct(n)
+		v.slopes[cnt] = windowSlope(n / 2)
+	}
+	long := v.bsize[1]
+	v.prev, v.out, v.spec = make([][]float64, v.channels), make([][]float64, v.channels), make([][]float64, v.channels)
+	v.ys = make([][]int, v.channels)
+	for ch := 0; ch < v.channels; ch++ {
+		v.prev[ch], v.out[ch], v.spec[ch] = make([]float64, long), make([]float64, long), make([]float64, long/2)
+		v.ys[ch] = make([]int, floorValues)
+	}
+	v.used, v.skip = make([]bool, v.channels), make([]bool, v.channels)
+	v.curve, v.steps = make([]float64, long/2), make([]bool, floorValues)
+	return nil
+}
+
+// decode appends the samples of an audio packet to pcm. Samples are
+// interleaved by channel. Each packet completes the samples that overlap
+// the previous packet so the first packet does not produce any samples.
+// Invalid packets are ignored.
+func (v *vorbis) decode(packet []byte, pcm []int16) []int16 {
+	v.bits = bitReader{data: packet}
+	b := &v.bits
+	if b.flag() || len(v.modes) == 0 {
+		return pcm // not an audio packet.
+	}
+	m := int(b.read(ilog(len(v.modes) - 1)))
+	if m >= len(v.modes) || b.eop {
+		return pcm
+	}
+	md := &v.modes[m]
+	n, prevLong, nextLong := v.bsize[0], false, false
+	if md.long {
+		n, prevLong, nextLong = v.bsize[1], b.flag(), b.flag()
+	}
+	n2, mp := n/2, &v.maps[md.mapping]
+
+	// read the floors and decide which channels have residue.
+	for ch := 0; ch < v.channels; ch++ {
+		f := &v.floors[mp.floors[mp.mux[ch]]]
+		v.used[ch] = f.decode(b, v.books, v.ys[ch])
+		v.skip[ch] = !v.used[ch]
+		v.spec[ch] = v.spec[ch][:n2]
+		for cnt := range v.spec[ch] {
+			v.spec[ch][cnt] = 0
+		}
+	}
+	for cnt := range mp.mag {
+		if !v.skip[mp.mag[cnt]] || !v.skip[mp.ang[cnt]] {
+			v.skip[mp.mag[cnt]], v.skip[mp.ang[cnt]] = false, false
+		}
+	}
+
+	// read the residue for the channels of each submap.
+	for sub, res := range mp.residues {
+		v.vecs, v.skips = v.vecs[:0], v.skips[:0]
+		for ch := 0; ch < v.channels; ch++ {
+			if mp.mux[ch] == sub {
+				v.vecs, v.skips = append(v.vecs, v.spec[ch]), append(v.skips, v.skip[ch])
+			}
+		}
+		if len(v.vecs) > 0 {
+			v.residues[res].decode(v, b, v.vecs, v.skips)
+		}
+	}
+
+	// undo the channel coupling in the reverse order it was applied.
+	for cnt := len(mp.mag) - 1; cnt >= 0; cnt-- {
+		mags, angs := v.spec[mp.mag[cnt]], v.spec[mp.ang[cnt]]
+		for j, mag := range mags {
+			ang := angs[j]
+			switch {
+			case mag > 0 && ang > 0:
+				mags[j], angs[j] = mag, mag-ang
+			case mag > 0:
+				mags[j], angs[j] = mag+ang, mag
+			case ang > 0:
+				mags[j], angs[j] = mag, mag+ang
+			default:
+				mags[j], angs[j] = mag-ang, mag
+			}
+		}
+	}
+
+	// apply the floor curve, transform back to samples, and window.
+	tr := v.imdct[0]
+	if md.long {
+		tr = v.imdct[1]
+	}
+	for ch := 0; ch < v.channels; ch++ {
+		spec, out := v.spec[ch], v.out[ch][:n]
+		if v.used[ch] {
+			f := &v.floors[mp.floors[mp.mux[ch]]]
+			f.render(v.ys[ch], v.steps, v.curve[:n2])
+			for cnt := range spec {
+				spec[cnt] *= v.curve[cnt]
+			}
+		} else {
+			for cnt := range spec {
+				spec[cnt] = 0
+			}
+		}
+		tr.inverse(spec, out)
+		v.window(out, md.long, prevLong, nextLong)
+	}
+
+	// overlap the previous block from its center to the current center.
+	// The 3/4 point of the previous block lines up with the 1/4 point
+	// of the current block.
+	if v.prevN > 0 {
+		pq, cq := v.prevN/4, n/4
+		for t := 0; t < pq+cq; t++ {
+			for ch := 0; ch < v.channels; ch++ {
+				sample := 0.0
+				if at := v.prevN/2 + t; at < v.prevN {
+					sample += v.prev[ch][at]
+				}
+				if at := t + cq - pq; at >= 0 && at < n {
+					sample += v.out[ch][at]
+				}
+				pcm = append(pcm, pcm16(sample))
+			}
+		}
+	}
+	v.prev, v.out, v.prevN = v.out, v.prev, n
+	return pcm
+}
+
+// window applies the block window. Long blocks next to short blocks
+// use the short block slope for that side.
+func (v *vorbis) window(out []float64, long, prevLong, nextLong bool) {
+	n := len(out)
+	left, right := v.slope(n/2), v.slope(n/2)
+	if long && !prevLong {
+		left = v.slopes[0]
+	}
+	if long && !nextLong {
+		right = v.slopes[0]
+	}
+	ls, rs := n/4-len(left)/2, n*3/4-len(right)/2
+	for cnt := 0; cnt < ls; cnt++ {
+		out[cnt] = 0
+	}
+	for cnt, w := range left {
+		out[ls+cnt] *= w
+	}
+	for cnt := range right {
+		out[rs+cnt] *= right[len(right)-1-cnt]
+	}
+	for cnt := rs + len(right); cnt < n; cnt++ {
+		out[cnt] = 0
+	}
+}
+
+// slope returns the rising window slope of the given size.
+func (v *vorbis) slope(size int) []float64 {
+	if size == len(v.slopes[0]) {
+		return v.slopes[0]
+	}
+	return v.slopes[1]
+}
+
+// windowSlope returns the rising half of the Vorbis window.
+func windowSlope(size int) []float64 {
+	slope := make([]float64, size)
+	for cnt := range slope {
+		s := math.Sin((float64(cnt) + 0.5) / float64(size) * math.Pi / 2)
+		slope[cnt] = math.Sin(math.Pi / 2 * s * s)
+	}
+	return slope
+}
+
+// mode selects the block size and mapping for an audio packet.
+type mode struct {
+	long    bool // True for long blocks.
+	mapping int  // Channel mapping.
+}
+
+// Mapping
+// =============================================================================
+
+// mapping assigns channels to floors and residues, and lists
+// the channel pairs that were coupled by the encoder.
+type mapping struct {
+	mag, ang []int // Magnitude and angle channel of each coupling step.
+	mux      []int // Submap of each channel.
+	floors   []int // Floor of each submap.
+	residues []int // Residue of each submap.
+}
+
+// read the mapping configuration.
+func (mp *mapping) read(b *bitReader, v *vorbis) error {
+	if b.read(16) != 0 {
+		return fmt.Errorf("invalid vorbis mapping")
+	}
+	subs := 1
+	if b.flag() {
+		subs = int(b.read(4)) + 1
+	}
+	if b.flag() {
+		bits := ilog(v.channels - 1)
+		for cnt := b.read(8) + 1; cnt > 0; cnt-- {
+			mag, ang := int(b.read(bits)), int(b.read(bits))
+			if mag == ang || mag >= v.channels || ang >= v.channels {
+				return fmt.Errorf("invalid vorbis channel coupling")
+			}
+			mp.mag, mp.ang = append(mp.mag, mag), append(mp.ang, ang)
+		}
+	}
+	if b.read(2) != 0 {
+		return fmt.Errorf("invalid vorbis mapping")
+	}
+	mp.mux = make([]int, v.channels)
+	if subs > 1 {
+		for ch := range mp.mux {
+			if mp.mux[ch] = int(b.read(4)); mp.mux[ch] >= subs {
+				return fmt.Errorf("invalid vorbis submap")
+			}
+		}
+	}
+	for cnt := 0; cnt < subs; cnt++ {
+		b.read(8) // time configuration is not used.
+		f, r := int(b.read(8)), int(b.read(8))
+		if f >= len(v.floors) || r >= len(v.residues) {
+			return fmt.Errorf("invalid vorbis submap")
+		}
+		mp.floors, mp.residues = append(mp.floors, f), append(mp.residues, r)
+	}
+	return nil
+}
+
+// Floor
+// =============================================================================
+
+// floorValues is the most values a floor can have.
+const floorValues = 65
+
+// floor1 describes the spectral envelope of a block using line segments.
+type floor1 struct {
+	parts  []int   // Class of each partition.
+	dims   []int   // Values for each class.
+	subs   []int   // Subclass bits for each class.
+	master []int   // Subclass book for each class.
+	books  [][]int // Value books for each class subclass, -1 for none.
+	mult   int     // Value multiplier 1-4.
+	xs     []int   // Location of each value.
+	order  []int   // Value indexes sorted by location.
+	low    []int   // Closest lower earlier value for each value.
+	high   []int   // Closest higher earlier value for each value.
+}
+
+// floorRanges is the range of the floor values for each multiplier.
+var floorRanges = [4]int{256, 128, 86, 64}
+
+// read the floor configuration.
+func (f *floor1) read(b *bitReader, books int) error {
+	if kind := b.read(16); kind != 1 {
+		return fmt.Errorf("unsupported vorbis floor type %d", kind)
+	}
+	f.parts = make([]int, b.read(5))
+	classes := 0
+	for cnt := range f.parts {
+		if f.parts[cnt] = int(b.read(4)); f.parts[cnt] >= classes {
+			classes = f.parts[cnt] + 1
+		}
+	}
+	f.dims, f.subs, f.master = make([]int, classes), make([]int, classes), make([]int, classes)
+	f.books = make([][]int, classes)
+	for cnt := 0; cnt < classes; cnt++ {
+		f.dims[cnt], f.subs[cnt] = int(b.read(3))+1, int(b.read(2))
+		if f.subs[cnt] != 0 {
+			if f.master[cnt] = int(b.read(8)); f.master[cnt] >= books {
+				return fmt.Errorf("invalid vorbis floor book")
+			}
+		}
+		f.books[cnt] = make([]int, 1<<uint(f.subs[cnt]))
+		for j := range f.books[cnt] {
+			if f.books[cnt][j] = int(b.read(8)) - 1; f.books[cnt][j] >= books {
+				return fmt.Errorf("invalid vorbis floor book")
+			}
+		}
+	}
+	f.mult = int(b.read(2)) + 1
+	bits := int(b.read(4))
+	f.xs = []int{0, 1 << uint(bits)}
+	for _, class := range f.parts {
+		for cnt := 0; cnt < f.dims[class]; cnt++ {
+			f.xs = append(f.xs, int(b.read(bits)))
+		}
+	}
+	if len(f.xs) > floorValues || b.eop {
+		return fmt.Errorf("invalid vorbis floor")
+	}
+
+	// sort the locations and find the neighbors of each value.
+	f.order = make([]int, len(f.xs))
+	for cnt := range f.order {
+		j := cnt
+		for ; j > 0 && f.xs[f.order[j-1]] > f.xs[cnt]; j-- {
+			f.order[j] = f.order[j-1]
+		}
+		f.order[j] = cnt
+	}
+	for cnt := 1; cnt < len(f.order); cnt++ {
+		if f.xs[f.order[cnt]] == f.xs[f.order[cnt-1]] {
+			return fmt.Errorf("invalid vorbis floor")
+		}
+	}
+	f.low, f.high = make([]int, len(f.xs)), make([]int, len(f.xs))
+	for cnt := 2; cnt < len(f.xs); cnt++ {
+		lo, hi, x := 0, 1, f.xs[cnt]
+		for j := 2; j < cnt; j++ {
+			if f.xs[j] < x && f.xs[j] > f.xs[lo] {
+				lo = j
+			}
+			if f.xs[j] > x && f.xs[j] < f.xs[hi] {
+				hi = j
+			}
+		}
+		f.low[cnt], f.high[cnt] = lo, hi
+	}
+	return nil
+}
+
+// decode reads the floor values of one channel into ys.
+// Returns false if the floor, and the channel, is unused.
+func (f *floor1) decode(b *bitReader, books []codebook, ys []int) bool {
+	if !b.flag() {
+		return false
+	}
+	bits := ilog(floorRanges[f.mult-1] - 1)
+	ys[0], ys[1] = int(b.read(bits)), int(b.read(bits))
+	at := 2
+	for _, class := range f.parts {
+		sub := uint(f.subs[class])
+		cval := 0
+		if sub > 0 {
+			cval = books[f.master[class]].decode(b)
+		}
+		for cnt := 0; cnt < f.dims[class]; cnt++ {
+			ys[at] = 0
+			if book := f.books[class][cval&(1<<sub-1)]; book >= 0 {
+				ys[at] = books[book].decode(b)
+			}
+			cval >>= sub
+			at++
+		}
+	}
+	return !b.eop
+}
+
+// render converts the floor values into the floor curve. The values
+// are offsets from the line between neighboring values. Values
+// that stay on the line are not used to draw the curve.
+func (f *floor1) render(ys []int, steps []bool, curve []float64) {
+	rng := floorRanges[f.mult-1]
+	steps[0], steps[1] = true, true
+	for cnt := 2; cnt < len(f.xs); cnt++ {
+		lo, hi := f.low[cnt], f.high[cnt]
+		pred := renderPoint(f.xs[lo], ys[lo], f.xs[hi], ys[hi], f.xs[cnt])
+		val, highroom, lowroom := ys[cnt], rng-pred, pred
+		room := lowroom * 2
+		if highroom < lowroom {
+			room = highroom * 2
+		}
+		if val == 0 {
+			steps[cnt], ys[cnt] = false, pred
+			continue
+		}
+		steps[lo], steps[hi], steps[cnt] = true, true, true
+		switch {
+		case val >= room && highroom > lowroom:
+			ys[cnt] = val - lowroom + pred
+		case val >= room:
+			ys[cnt] = pred - val + highroom - 1
+		case val&1 == 1:
+			ys[cnt] = pred - (val+1)/2
+		default:
+			ys[cnt] = pred + val/2
+		}
+	}
+	lx, ly, hx, hy := 0, ys[0]*f.mult, 0, 0
+	for _, cnt := range f.order[1:] {
+		if steps[cnt] {
+			hx, hy = f.xs[cnt], ys[cnt]*f.mult
+			renderLine(lx, ly, hx, hy, curve)
+			lx, ly = hx, hy
+		}
+	}
+	if hx < len(curve) {
+		renderLine(hx, hy, len(curve), hy, curve)
+	}
+}
+
+// renderPoint returns the y value at x on the line between two points.
+func renderPoint(x0, y0, x1, y1, x int) int {
+	dy := y1 - y0
+	off := abs(dy) * (x - x0) / (x1 - x0)
+	if dy < 0 {
+		return y0 - off
+	}
+	return y0 + off
+}
+
+// renderLine draws the integer line from x0 up to, but not including,
+// x1 into the curve. Line values are converted from decibels.
+func renderLine(x0, y0, x1, y1 int, curve []float64) {
+	dy, adx := y1-y0, x1-x0
+	if adx <= 0 {
+		return
+	}
+	base := dy / adx
+	ady, sy := abs(dy)-abs(base)*adx, base+1
+	if dy < 0 {
+		sy = base - 1
+	}
+	y, err := y0, 0
+	for x := x0; x < x1 && x < len(curve); x++ {
+		if x > x0 {
+			if err += ady; err >= adx {
+				err -= adx
+				y += sy
+			} else {
+				y += base
+			}
+		}
+		switch {
+		case y < 0:
+			curve[x] = floorDB[0]
+		case y > 255:
+			curve[x] = floorDB[255]
+		default:
+			curve[x] = floorDB[y]
+		}
+	}
+}
+
+// floorDB converts floor values to amplitudes. The values
+// cover 140dB in steps of 140/256 dB.
+var floorDB = func() (table [256]float64) {
+	for cnt := range table {
+		table[cnt] = math.Pow(10, float64(cnt-255)*7/256)
+	}
+	return table
+}()
+
+// Residue
+// =============================================================================
+
+// residue describes how the fine spectral detail of a block is coded.
+// Each channel vector is split into partitions and each partition is
+// coded by up to 8 passes of vector quantization books.
+type residue struct {
+	kind    int      // Residue type 0, 1, or 2.
+	begin   int      // First coded spectrum value.
+	end     int      // Last coded spectrum value, exclusive.
+	size    int      // Partition size.
+	classes int      // Number of partition classifications.
+	book    int      // Classification book.
+	books   [][8]int // Book of each class for each pass, -1 for none.
+	class   [][]int  // Scratch partition classes per channel.
+}
+
+// read the residue configuration.
+func (r *residue) read(b *bitReader, books []codebook) error {
+	if r.kind = int(b.read(16)); r.kind > 2 {
+		return fmt.Errorf("unsupported vorbis residue type %d", r.kind)
+	}
+	r.begin, r.end, r.size = int(b.read(24)), int(b.read(24)), int(b.read(24))+1
+	r.classes, r.book = int(b.read(6))+1, int(b.read(8))
+	if r.book >= len(books) || books[r.book].dims == 0 {
+		return fmt.Errorf("invalid vorbis residue book")
+	}
+	cascade := make([]int, r.classes)
+	for cnt := range cascade {
+		cascade[cnt] = int(b.read(3))
+		if b.flag() {
+			cascade[cnt] |= int(b.read(5)) << 3
+		}
+	}
+	r.books = make([][8]int, r.classes)
+	for cnt, passes := range cascade {
+		for pass := range r.books[cnt] {
+			r.books[cnt][pass] = -1
+			if passes&(1<<uint(pass)) != 0 {
+				book := int(b.read(8))
+				if book >= len(books) || books[book].values == nil {
+					return fmt.Errorf("invalid vorbis residue book")
+				}
+				r.books[cnt][pass] = book
+			}
+		}
+	}
+	return nil
+}
+
+// decode adds the residue to the channel vectors that are not skipped.
+// Type 2 residue codes all the channels as one interleaved vector.
+func (r *residue) decode(v *vorbis, b *bitReader, vecs [][]float64, skip []bool) {
+	if r.kind != 2 {
+		r.partitions(v, b, vecs, skip, r.kind)
+		return
+	}
+	decode := false
+	for _, s := range skip {
+		decode = decode || !s
+	}
+	if !decode {
+		return
+	}
+	size, chans := len(vecs[0]), len(vecs)
+	if cap(v.work) < size*chans {
+		v.work = make([]float64, size*chans)
+	}
+	work := v.work[:size*chans]
+	for cnt := range work {
+		work[cnt] = 0
+	}
+	r.partitions(v, b, [][]float64{work}, []bool{false}, 1)
+	for cnt := 0; cnt < size; cnt++ {
+		for ch, vec := range vecs {
+			vec[cnt] = work[cnt*chans+ch]
+		}
+	}
+}
+
+// partitions reads the classification and vectors of each partition.
+// Reading stops at the end of the packet.
+func (r *residue) partitions(v *vorbis, b *bitReader, vecs [][]float64, skip []bool, kind int) {
+	size := len(vecs[0])
+	begin, end := r.begin, r.end
+	if end > size {
+		end = size
+	}
+	parts := (end - begin) / r.size
+	if parts <= 0 {
+		return
+	}
+	book := &v.books[r.book]
+	words := book.dims
+	for len(r.class) < len(vecs) {
+		r.class = append(r.class, nil)
+	}
+	for ch := range vecs {
+		if len(r.class[ch]) < parts+words {
+			r.class[ch] = make([]int, parts+words)
+		}
+	}
+	for pass := 0; pass < 8; pass++ {
+		for p := 0; p < parts; {
+			if pass == 0 {
+				for ch := range vecs {
+					if skip[ch] {
+						continue
+					}
+					word := book.decode(b)
+					if word < 0 {
+						return
+					}
+					for cnt := words - 1; cnt >= 0; cnt-- {
+						r.class[ch][p+cnt] = word % r.classes
+						word /= r.classes
+					}
+				}
+			}
+			for cnt := 0; cnt < words && p < parts; cnt, p = cnt+1, p+1 {
+				for ch, vec := range vecs {
+					if skip[ch] {
+						continue
+					}
+					if vq := r.books[r.class[ch][p]][pass]; vq >= 0 {
+						at := begin + p*r.size
+						if !v.books[vq].add(b, kind, vec[at:at+r.size]) {
+							return
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+// Codebook
+// =============================================================================
+
+// codebook holds the Huffman codes of its entries. Books used for
+// vector quantization also hold a vector of values for each entry.
+type codebook struct {
+	dims    int       // Values in each entry vector.
+	entries int       // Number of entries.
+	tree    []int32   // Huffman tree, see decode.
+	values  []float64 // Entry vectors, nil for books without vectors.
+}
+
+// read the codebook configuration.
+func (c *codebook) read(b *bitReader) error {
+	if b.read(24) != 0x564342 {
+		return fmt.Errorf("invalid vorbis codebook")
+	}
+	c.dims, c.entries = int(b.read(16)), int(b.read(24))
+	lengths := make([]uint8, c.entries)
+	if ordered := b.flag(); !ordered {
+		sparse := b.flag()
+		for cnt := range lengths {
+			if !sparse || b.flag() {
+				lengths[cnt] = uint8(b.read(5) + 1)
+			}
+		}
+	} else {
+		length := int(b.read(5)) + 1
+		for cnt := 0; cnt < c.entries; length++ {
+			num := int(b.read(ilog(c.entries - cnt)))
+			if cnt+num > c.entries || length > 32 {
+				return fmt.Errorf("invalid vorbis codebook")
+			}
+			for ; num > 0; num-- {
+				lengths[cnt] = uint8(length)
+				cnt++
+			}
+			if b.eop {
+				return fmt.Errorf("invalid vorbis codebook")
+			}
+		}
+	}
+	if err := c.codes(lengths); err != nil {
+		return err
+	}
+
+	// read the entry vectors.
+	lookup := b.read(4)
+	switch lookup {
+	case 0:
+		return nil
+	case 1, 2:
+	default:
+		return fmt.Errorf("unsupported vorbis codebook lookup %d", lookup)
+	}
+	if c.dims == 0 || c.entries*c.dims > 1<<24 {
+		return fmt.Errorf("invalid vorbis codebook")
+	}
+	minimum, delta := float32Unpack(b.read(32)), float32Unpack(b.read(32))
+	bits, sequence := int(b.read(4))+1, b.flag()
+	size := c.entries * c.dims
+	if lookup == 1 {
+		size = lookup1Values(c.entries, c.dims)
+	}
+	mults := make([]float64, size)
+	for cnt := range mults {
+		mults[cnt] = float64(b.read(bits))
+	}
+	if b.eop {
+		return fmt.Errorf("invalid vorbis codebook")
+	}
+	c.values = make([]float64, c.entries*c.dims)
+	for entry := 0; entry < c.entries; entry++ {
+		last, div := 0.0, 1
+		for dim := 0; dim < c.dims; dim++ {
+			at := entry*c.dims + dim
+			if lookup == 1 {
+				at = entry / div % size
+				div *= size
+			}
+			val := mults[at]*delta + minimum + last
+			if sequence {
+				last = val
+			}
+			c.values[entry*c.dims+dim] = val
+		}
+	}
+	return nil
+}
+
+// codes builds the Huffman tree from the codeword lengths. Each entry gets
+// the lowest valued code of its length that is still available.
+// Entries with length 0 are unused.
+func (c *codebook) codes(lengths []uint8) error {
+	var avail [33]uint32 // Available code at each length, 0 for none.
+	c.tree = []int32{0, 0}
+	used := 0
+	for entry, size := range lengths {
+		if size == 0 {
+			continue
+		}
+		code, n := uint32(0), int(size)
+		if used == 0 {
+			for cnt := 1; cnt <= n; cnt++ {
+				avail[cnt] = 1 << uint(32-cnt)
+			}
+		} else {
+			z := n
+			for z > 0 && avail[z] == 0 {
+				z--
+			}
+			if z == 0 {
+				return fmt.Errorf("invalid vorbis codebook lengths")
+			}
+			code, avail[z] = avail[z], 0
+			for cnt := n; cnt > z; cnt-- {
+				avail[cnt] = code + 1<<uint(32-cnt)
+			}
+		}
+		c.insert(code, n, entry)
+		used++
+	}
+	if used == 1 {
+		c.tree[1] = c.tree[0] // the only entry is read for either bit.
+	}
+	return nil
+}
+
+// insert adds the entry code, most significant bit first, to the tree.
+func (c *codebook) insert(code uint32, n, entry int) {
+	node := 0
+	for cnt := 0; cnt < n; cnt++ {
+		at := node*2 + int(code>>uint(31-cnt)&1)
+		if cnt == n-1 {
+			c.tree[at] = int32(-entry - 1)
+			return
+		}
+		if c.tree[at] == 0 {
+			c.tree[at] = int32(len(c.tree) / 2)
+			c.tree = append(c.tree, 0, 0)
+		}
+		node = int(c.tree[at])
+	}
+}
+
+// decode reads one entry. The tree holds the two children of each
+// node. Positive children are nodes and negative children are entries.
+// Returns -1 at the end of the packet or for an invalid code.
+func (c *codebook) decode(b *bitReader) int {
+	node := 0
+	for {
+		bit := int(b.read(1))
+		if b.eop {
+			return -1
+		}
+		next := c.tree[node*2+bit]
+		switch {
+		case next < 0:
+			return int(-next - 1)
+		case next == 0:
+			return -1
+		}
+		node = int(next)
+	}
+}
+
+// add reads entry vectors into vec. Type 0 residue interleaves
+// the entry vector values while the other types are sequential.
+// Returns false at the end of the packet.
+func (c *codebook) add(b *bitReader, kind int, vec []float64) bool {
+	dims := c.dims
+	if kind == 0 {
+		step := len(vec) / dims
+		for cnt := 0; cnt < step; cnt++ {
+			entry := c.decode(b)
+			if entry < 0 {
+				return false
+			}
+			for dim := 0; dim < dims; dim++ {
+				vec[cnt+dim*step] += c.values[entry*dims+dim]
+			}
+		}
+		return true
+	}
+	for cnt := 0; cnt < len(vec); {
+		entry := c.decode(b)
+		if entry < 0 {
+			return false
+		}
+		for dim := 0; dim < dims && cnt < len(vec); dim++ {
+			vec[cnt] += c.values[entry*dims+dim]
+			cnt++
+		}
+	}
+	return true
+}
+
+// float32Unpack converts the Vorbis packed float format.
+func float32Unpack(x uint32) float64 {
+	mantissa := float64(x & 0x1fffff)
+	if x&0x80000000 != 0 {
+		mantissa = -mantissa
+	}
+	return math.Ldexp(mantissa, int(x>>21&0x3ff)-788)
+}
+
+// lookup1Values returns the largest value whose dims power
+// is not more than the number of entries.
+func lookup1Values(entries, dims int) int {
+	val := int(math.Pow(float64(entries), 1/float64(dims)))
+	for power(val+1, dims) <= entries {
+		val++
+	}
+	for val > 0 && power(val, dims) > entries {
+		val--
+	}
+	return val
+}
+
+// power returns base to the exp, stopping once it is too big to matter.
+func power(base, exp int) int {
+	val := 1
+	for cnt := 0; cnt < exp && val <= 1<<24; cnt++ {
+		val *= base
+	}
+	return val
+}
+
+// Transform
+// =============================================================================
+
+// imdct is the inverse modified discrete cosine transform for one block
+// size n. The n/2 spectrum values are turned into n samples using:
+//    y[i] = sum over k of x[k]*cos(pi/(2n)*(2i+1+n/2)*(2k+1))
+// This is done with a type IV discrete cosine transform of size n/2 that
+// uses a complex FFT of size n/4.
+type imdct struct {
+	n    int          // Block size.
+	pre  []complex128 // Rotation before the FFT.
+	post []complex128 // Rotation after the FFT.
+	tw   []complex128 // FFT twiddle factors.
+	rev  []int        // FFT bit reversal order.
+	z    []complex128 // Scratch FFT values.
+	u    []float64    // Scratch cosine transform values.
+}
+
+// newImdct prepares the transform for block size n.
+func newImdct(n int) *imdct {
+	m, l := n/2, n/4
+	t := &imdct{n: n, z: make([]complex128, l), u: make([]float64, m)}
+	t.pre, t.post = make([]complex128, l), make([]complex128, l)
+	for cnt := 0; cnt < l; cnt++ {
+		t.pre[cnt] = rotation(-math.Pi * float64(cnt) / float64(m))
+		t.post[cnt] = rotation(-math.Pi * (float64(cnt) + 0.25) / float64(m))
+	}
+	t.tw = make([]complex128, l/2)
+	for cnt := range t.tw {
+		t.tw[cnt] = rotation(-2 * math.Pi * float64(cnt) / float64(l))
+	}
+	bits := ilog(l - 1)
+	t.rev = make([]int, l)
+	for cnt := range t.rev {
+		for bit := 0; bit < bits; bit++ {
+			t.rev[cnt] |= (cnt >> uint(bit) & 1) << uint(bits-1-bit)
+		}
+	}
+	return t
+}
+
+// rotation returns the unit complex number at the given angle.
+func rotation(angle float64) complex128 {
+	return complex(math.Cos(angle), math.Sin(angle))
+}
+
+// inverse transforms the n/2 spectrum values in x into n samples in y.
+func (t *imdct) inverse(x, y []float64) {
+	m, l := t.n/2, t.n/4
+
+	// type IV cosine transform of x into u.
+	for cnt := 0; cnt < l; cnt++ {
+		t.z[t.rev[cnt]] = complex(x[2*cnt], x[m-1-2*cnt]) * t.pre[cnt]
+	}
+	for size := 2; size <= l; size <<= 1 {
+		half, step := size/2, l/size
+		for start := 0; start < l; start += size {
+			for k := 0; k < half; k++ {
+				a, b := t.z[start+k], t.z[start+k+half]*t.tw[k*step]
+				t.z[start+k], t.z[start+k+half] = a+b, a-b
+			}
+		}
+	}
+	for cnt := 0; cnt < l; cnt++ {
+		z := t.z[cnt] * t.post[cnt]
+		t.u[2*cnt], t.u[m-1-2*cnt] = real(z), -imag(z)
+	}
+
+	// the samples are the cosine transform shifted by n/4
+	// and extended using its symmetries.
+	for cnt := range y[:t.n] {
+		k := cnt + m/2
+		switch {
+		case k < m:
+			y[cnt] = t.u[k]
+		case k < 2*m:
+			y[cnt] = -t.u[2*m-1-k]
+		default:
+			y[cnt] = -t.u[k-2*m]
+		}
+	}
+}
+
+// Bits
+// =============================================================================
+
+// bitReader reads values from a packet. Vorbis packs values starting
+// with the least significant bit of each byte.
+type bitReader struct {
+	data []byte // Packet.
+	at   int    // Bits read.
+	eop  bool   // True after reading past the end of the packet.
+}
+
+// read returns the next n bits, up to 32. Reading past the end
+// of the packet sets eop and returns 0.
+func (b *bitReader) read(n int) uint32 {
+	if b.at+n > len(b.data)*8 {
+		b.eop, b.at = true, len(b.data)*8
+		return 0
+	}
+	val := uint32(0)
+	for cnt := 0; cnt < n; {
+		take, off := 8-b.at&7, uint(b.at&7)
+		if take > n-cnt {
+			take = n - cnt
+		}
+		val |= uint32(b.data[b.at>>3]>>off) & (1<<uint(take) - 1) << uint(cnt)
+		cnt, b.at = cnt+take, b.at+take
+	}
+	return val
+}
+
+// flag reads a single bit.
+func (b *bitReader) flag() bool { return b.read(1) == 1 }
+
+// ilog returns the number of bits needed to hold x.
+func ilog(x int) (bits int) {
+	for ; x > 0; x >>= 1 {
+		bits++
+	}
+	return bits
+}
+
+// abs returns the absolute value of x.
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
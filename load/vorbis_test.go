@@ -0,0 +1,53 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package load
+
+import (
+	"math"
+	"testing"
+)
+
+// The fast inverse transform matches the direct calculation.
+func TestImdct(t *testing.T) {
+	for _, n := range []int{64, 256, 2048} {
+		x, y := make([]float64, n/2), make([]float64, n)
+		for k := range x {
+			x[k] = math.Sin(float64(k)*1.3) + 0.5
+		}
+		newImdct(n).inverse(x, y)
+		for i := range y {
+			want := 0.0
+			for k, val := range x {
+				want += val * math.Cos(math.Pi/float64(2*n)*float64(2*i+1+n/2)*float64(2*k+1))
+			}
+			if math.Abs(y[i]-want) > 1e-9 {
+				t.Fatalf("Block %d sample %d expected %f got %f", n, i, want, y[i])
+			}
+		}
+	}
+}
+
+// Floor values are drawn as lines between the used values.
+func TestFloorRender(t *testing.T) {
+	f := &floor1{mult: 1, xs: []int{0, 128, 64}, order: []int{0, 2, 1}, low: []int{0, 0, 0}, high: []int{0, 0, 1}}
+	curve, steps := make([]float64, 128), make([]bool, 3)
+	f.render([]int{10, 20, 0}, steps, curve)
+	for x, val := range curve {
+		if want := floorDB[10+10*x/128]; val != want {
+			t.Fatalf("Expected straight line at %d", x)
+		}
+	}
+
+	// value 4 is 2 above the predicted 15.
+	f.render([]int{10, 20, 4}, steps, curve)
+	for x, val := range curve {
+		want := floorDB[10+7*x/64]
+		if x >= 64 {
+			want = floorDB[17+3*(x-64)/64]
+		}
+		if val != want {
+			t.Fatalf("Expected line through 64,17 at %d", x)
+		}
+	}
+}
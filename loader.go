@@ -5,9 +5,11 @@ package vu
 
 import (
 	"fmt"
+	"image"
 	"log"
 	"math"
 	"strconv"
+	"sync"
 
 	"github.com/gazed/vu/load"
 	"github.com/gazed/vu/render"
@@ -25,10 +27,15 @@ type loader struct {
 	// loader goroutine communication.
 	ld     load.Loader     // asset loader.
 	cache  cache           // asset cache.
+	mem    *memAssets      // application created assets.
 	stop   chan bool       // shutdown requests.
 	load   chan []*loadReq // asset load requests.
 	loaded chan []*loadReq // loaded asset replies.
 	binder chan msg        // machine loop request channel.
+
+	// Quality texture size shared with the loader goroutines.
+	texLock sync.Mutex // Guards texSize.
+	texSize int        // Largest texture width and height, 0 for any.
 }
 
 // newLoader is expected to be called once on startup by the engine.
@@ -36,6 +43,7 @@ func newLoader(loaded chan []*loadReq, binder chan msg) *loader {
 	l := &loader{loaded: loaded, binder: binder}
 	l.ld = load.NewLoader()
 	l.cache = newCache()
+	l.mem = newMemAssets()
 	l.stop = make(chan bool)
 	l.load = make(chan []*loadReq)
 	return l
@@ -70,6 +78,7 @@ func (l *loader) runLoader() {
 					} else {
 						req.a = nil   // return explicit nil for asset interface.
 						req.msh = nil // release mesh on fail.
+						req.err = fmt.Errorf("loader.loadAnim: could not load %s", a.name)
 					}
 				case *material:
 					req.a, req.err = l.loadMaterial(a)
@@ -127,32 +136,41 @@ func (l *loader) shutdown() { l.stop <- true }
 
 // loadSound returns a loaded sound immediately if it is cached.
 // Otherwise the sound is returned after it is loaded and bound.
+// Streams have no data to load and are not shared.
 func (l *loader) loadSound(s *sound) (*sound, error) {
-	data := asset(s)
-	if err := l.cache.fetch(&data); err == nil {
-		return data.(*sound), nil // only initialized stuff is in the cache.
-	}
+	if !s.stream {
+		data := asset(s)
+		if err := l.cache.fetch(&data); err == nil {
+			return data.(*sound), nil // only initialized stuff is in the cache.
+		}
 
-	// Otherwise the sound needs to be loaded and bound.
-	if err := l.importSound(s); err != nil {
-		return nil, err
+		// Otherwise the sound needs to be loaded and bound.
+		if err := l.importSound(s); err != nil {
+			return nil, err
+		}
 	}
 	bindReply := make(chan error)
 	l.binder <- &bindData{data: s, reply: bindReply} // request bind.
 	if err := <-bindReply; err != nil {              // wait for bind.
 		return nil, err
 	}
-	l.cache.store(s)
+	if !s.stream {
+		l.cache.store(s)
+	}
 	return s, nil
 }
 
 // importSound transfers audio data loaded from disk to the sound object.
+// Compressed .ogg files are used when there is no .wav file.
 func (l *loader) importSound(s *sound) error {
-	if wh, data, err := l.ld.Wav(s.name); err == nil {
-		s.data.Set(wh.Channels, wh.SampleBits, wh.Frequency, wh.DataSize, data)
-	} else {
-		return fmt.Errorf("loader.loadSound: could not load %s %s", s.label(), err)
+	wh, data, err := l.ld.Wav(s.name)
+	if err != nil {
+		var oerr error
+		if wh, data, oerr = l.ld.Ogg(s.name); oerr != nil {
+			return fmt.Errorf("loader.loadSound: could not load %s %s %s", s.label(), err, oerr)
+		}
 	}
+	s.data.Set(wh.Channels, wh.SampleBits, wh.Frequency, wh.DataSize, data)
 	return nil
 }
 
@@ -229,7 +247,11 @@ func (l *loader) bindMesh(m *mesh) error {
 }
 
 // importMesh transfers data loaded from disk to the render object.
+// Memory meshes are used before mesh files.
 func (l *loader) importMesh(m *mesh) error {
+	if l.mem.mesh(m) {
+		return nil
+	}
 	if data, err := l.ld.Obj(m.name); err == nil && len(data) > 0 {
 		if len(data[0].V) <= 0 || len(data[0].F) <= 0 {
 			return fmt.Errorf("Minimally need vertex and face data for %s", m.name)
@@ -270,13 +292,62 @@ func (l *loader) loadTexture(t *texture) (*texture, error) {
 	return t, nil
 }
 
+// setTextureSize sets the largest width and height of imported textures.
+func (l *loader) setTextureSize(size int) {
+	l.texLock.Lock()
+	l.texSize = size
+	l.texLock.Unlock()
+}
+
+// textureSize returns the largest width and height of imported textures.
+func (l *loader) textureSize() int {
+	l.texLock.Lock()
+	defer l.texLock.Unlock()
+	return l.texSize
+}
+
 // importTexture transfers data loaded from disk to the render object.
+// Memory textures are used before image files. Textures are shrunk to
+// fit the quality texture size.
 func (l *loader) importTexture(t *texture) error {
+	if t.cube {
+		return l.importCube(t)
+	}
+	if img := l.mem.img(t.name); img != nil {
+		t.set(shrink(img, l.textureSize()))
+		return nil
+	}
 	img, err := l.ld.Png(t.name)
 	if err != nil {
 		return fmt.Errorf("loader.loadTexture: could not load %s %s", t.name, err)
 	}
-	t.set(img)
+	t.set(shrink(img, l.textureSize()))
+	return nil
+}
+
+// importCube transfers the cube map faces loaded from disk to the
+// render object. Memory face images, or a memory panorama, are used
+// before image files. See load.Cube.
+func (l *loader) importCube(t *texture) error {
+	faces := []image.Image{}
+	for _, suffix := range load.CubeFaces {
+		if img := l.mem.img(t.name + suffix); img != nil {
+			faces = append(faces, img)
+		}
+	}
+	if len(faces) != len(load.CubeFaces) {
+		var err error
+		if equirect := l.mem.img(t.name); equirect != nil {
+			faces = load.EquirectToCube(equirect, 0)
+		} else if faces, err = l.ld.Cube(t.name); err != nil {
+			return fmt.Errorf("loader.loadTexture: could not load cube %s %s", t.name, err)
+		}
+	}
+	size := l.textureSize()
+	for cnt, face := range faces {
+		faces[cnt] = shrink(face, size)
+	}
+	t.setFaces(faces)
 	return nil
 }
 
@@ -420,6 +491,7 @@ func (l *loader) importAnim(a *animation, m *mesh) (texs []*texture, err error)
 			moves = append(moves, movement)
 		}
 		a.setData(iqd.Frames, iqd.Joints, moves)
+		a.setJoints(iqd.Names, iqd.Bases)
 	}
 
 	// Get model textures. There may be more than one.
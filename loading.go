@@ -0,0 +1,177 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+// Loading reports the progress of the assets being loaded for the
+// models and noises of a Pov hierarchy, see Eng.Loads. Assets are
+// read by the loader goroutine and uploaded to the GPU, or audio card,
+// on the render thread while the update loop keeps running, ie: to
+// show spawned content once it has loaded:
+//     ship := eng.Spawn("ship", level)
+//     ship.SetVisible(false)
+//     eng.Loads(ship).OnDone(func(errs []error) {
+//         ship.SetVisible(true)
+//     })
+// Callbacks are run on the update goroutine, before App.Update, in the
+// update after the progress changed. Each shader, mesh, texture, font,
+// material, animation, and sound counts as one asset.
+type Loading interface {
+	Progress() (done, total int) // Assets that loaded, or failed.
+	Done() bool                  // True when all assets are done.
+	Errs() []error               // Assets that failed to load.
+
+	// OnProgress is called each time more assets are done. OnDone is
+	// called once when all assets are done. Both are called for
+	// hierarchies that have already loaded.
+	OnProgress(progress func(done, total int)) Loading
+	OnDone(finish func(errs []error)) Loading
+}
+
+// Loading
+// =============================================================================
+// loading implements Loading.
+
+// loading polls the load state of the model and noise components
+// for a group of entities.
+type loading struct {
+	eng      *engine               // Polls loadings each update.
+	eids     []uint64              // Tracked entities.
+	done     int                   // Assets loaded or failed.
+	total    int                   // Assets requested.
+	errs     []error               // Failed loads.
+	progress func(done, total int) // Optional progress callback.
+	finish   func(errs []error)    // Optional done callback.
+	reported int                   // Done count last reported. -1 for none.
+	finished bool                  // True once finish has been called.
+	polled   bool                  // True while the engine is polling.
+}
+
+// Implement Loading.
+func (l *loading) Progress() (done, total int) { return l.done, l.total }
+func (l *loading) Done() bool                  { return l.done >= l.total }
+func (l *loading) Errs() []error               { return l.errs }
+func (l *loading) OnProgress(progress func(done, total int)) Loading {
+	l.progress, l.reported = progress, -1
+	l.poll()
+	return l
+}
+func (l *loading) OnDone(finish func(errs []error)) Loading {
+	l.finish, l.finished = finish, false
+	l.poll()
+	return l
+}
+
+// poll ensures the engine reports progress for this loading.
+func (l *loading) poll() {
+	if !l.polled {
+		l.polled = true
+		l.eng.loads = append(l.eng.loads, l)
+	}
+}
+
+// count updates the done and total assets from the current
+// state of the tracked entities.
+func (l *loading) count(eng *engine) {
+	l.done, l.total = len(l.errs), 0
+	for _, eid := range l.eids {
+		if m, ok := eng.models.model(eid); ok {
+			l.add(m.shd != nil, m.shd != nil && m.shd.loaded)
+			l.add(m.msh != nil, m.msh != nil && m.msh.loaded)
+			l.add(m.fnt != nil, m.fnt != nil && m.fnt.loaded)
+			l.add(m.mat != nil, m.mat != nil && m.mat.loaded)
+			l.add(m.anm != nil, m.anm != nil && m.anm.loaded)
+			for _, t := range m.texs {
+				l.add(true, t.loaded)
+			}
+		}
+		if n, ok := eng.noises.noise(eid); ok {
+			for _, s := range n.snds {
+				l.add(true, s.sid != 0)
+			}
+		}
+	}
+	if l.done > l.total {
+		l.done = l.total // failed assets are never loaded.
+	}
+}
+
+// add counts one asset if it is used.
+func (l *loading) add(used, loaded bool) {
+	if used {
+		l.total++
+		if loaded {
+			l.done++
+		}
+	}
+}
+
+// failed records a load error for a tracked entity.
+func (l *loading) failed(eng *engine, req *loadReq) {
+	for _, eid := range l.eids {
+		m, isModel := eng.models.model(eid)
+		n, isNoise := eng.noises.noise(eid)
+		if (isModel && req.data == m) || (isNoise && req.data == n) {
+			l.errs = append(l.errs, req.err)
+			return
+		}
+	}
+}
+
+// loading
+// =============================================================================
+// engine loading handling.
+
+// Implement Eng interface. Existing entities in the hierarchy are tracked.
+func (eng *engine) Loads(p Pov) Loading {
+	l := &loading{eng: eng, reported: -1}
+	if pv, ok := p.(*pov); ok && pv != nil {
+		l.eids = eng.loadEids(pv, l.eids)
+	}
+	l.count(eng)
+	l.poll() // for load errors.
+	return l
+}
+
+// loadEids returns the entities in the hierarchy p that have assets.
+func (eng *engine) loadEids(p *pov, eids []uint64) []uint64 {
+	_, isModel := eng.models.model(p.eid)
+	_, isNoise := eng.noises.noise(p.eid)
+	if isModel || isNoise {
+		eids = append(eids, p.eid)
+	}
+	for _, child := range p.children {
+		eids = eng.loadEids(child, eids)
+	}
+	return eids
+}
+
+// updateLoadings reports load progress. Loadings are dropped
+// once they are done. Expected to be called once per update.
+func (eng *engine) updateLoadings() {
+	loadings := eng.loads
+	eng.loads = nil // callbacks may add loadings.
+	for _, l := range loadings {
+		l.count(eng)
+		if l.Done() {
+			l.polled = false // nothing left to report.
+		} else {
+			eng.loads = append(eng.loads, l)
+		}
+		if l.progress != nil && l.done != l.reported {
+			l.reported = l.done
+			l.progress(l.done, l.total)
+		}
+		if l.Done() && l.finish != nil && !l.finished {
+			l.finished = true
+			l.finish(l.errs)
+		}
+	}
+}
+
+// loadFailed records a load error with the loadings for the request.
+func (eng *engine) loadFailed(req *loadReq) {
+	for _, l := range eng.loads {
+		l.failed(eng, req)
+	}
+}
@@ -0,0 +1,44 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"fmt"
+	"testing"
+)
+
+// Check that load progress is reported as assets load or fail.
+func TestLoading(t *testing.T) {
+	eng := newEngine(nil)
+	defer eng.Shutdown()
+	p := eng.Root().NewPov()
+	m := p.NewModel("solid").LoadMesh("box").AddTex("wood").(*model)
+	n := p.NewPov().NewNoise().(*noise)
+	n.Add("bang")
+	reports, finished := []string{}, 0
+	l := eng.Loads(p).OnProgress(func(done, total int) {
+		reports = append(reports, fmt.Sprintf("%d/%d", done, total))
+	}).OnDone(func(errs []error) { finished++ })
+	if done, total := l.Progress(); done != 0 || total != 4 || l.Done() {
+		t.Fatalf("Expected shader, mesh, texture, and sound, got %d/%d", done, total)
+	}
+	m.shd.loaded = true
+	eng.updateLoadings()
+	eng.loadFailed(&loadReq{data: m, err: fmt.Errorf("no wood")})
+	m.msh.loaded, n.snds[0].sid = true, 7
+	eng.updateLoadings()
+	eng.updateLoadings()
+	if len(reports) != 2 || reports[0] != "1/4" || reports[1] != "4/4" || finished != 1 {
+		t.Fatalf("Expected progress and one finish, got %v %d", reports, finished)
+	}
+	if !l.Done() || len(l.Errs()) != 1 || len(eng.loads) != 0 {
+		t.Errorf("Expected done with one error, got %v %d", l.Errs(), len(eng.loads))
+	}
+
+	// late callbacks are still called.
+	l.OnDone(func(errs []error) { finished++ })
+	if eng.updateLoadings(); finished != 2 {
+		t.Errorf("Expected late finish")
+	}
+}
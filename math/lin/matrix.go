@@ -573,6 +573,39 @@ func (m *M3) Inv(a *M3) *M3 {
 	return m
 }
 
+// Inv updates m to be the inverse of matrix a. The inverse is found from
+// the 2x2 determinants of the top two and the bottom two rows.
+// The updated matrix m is returned. Matrix m is not updated if the
+// matrix has no inverse. Same behaviour as M3.Inv()
+func (m *M4) Inv(a *M4) *M4 {
+	s0 := a.Xx*a.Yy - a.Yx*a.Xy
+	s1 := a.Xx*a.Yz - a.Yx*a.Xz
+	s2 := a.Xx*a.Yw - a.Yx*a.Xw
+	s3 := a.Xy*a.Yz - a.Yy*a.Xz
+	s4 := a.Xy*a.Yw - a.Yy*a.Xw
+	s5 := a.Xz*a.Yw - a.Yz*a.Xw
+	c0 := a.Zx*a.Wy - a.Wx*a.Zy
+	c1 := a.Zx*a.Wz - a.Wx*a.Zz
+	c2 := a.Zx*a.Ww - a.Wx*a.Zw
+	c3 := a.Zy*a.Wz - a.Wy*a.Zz
+	c4 := a.Zy*a.Ww - a.Wy*a.Zw
+	c5 := a.Zz*a.Ww - a.Wz*a.Zw
+	det := s0*c5 - s1*c4 + s2*c3 + s3*c2 - s4*c1 + s5*c0
+	if det == 0 {
+		return m
+	}
+	d := 1 / det
+	xx, xy, xz, xw := (a.Yy*c5-a.Yz*c4+a.Yw*c3)*d, (-a.Xy*c5+a.Xz*c4-a.Xw*c3)*d, (a.Wy*s5-a.Wz*s4+a.Ww*s3)*d, (-a.Zy*s5+a.Zz*s4-a.Zw*s3)*d
+	yx, yy, yz, yw := (-a.Yx*c5+a.Yz*c2-a.Yw*c1)*d, (a.Xx*c5-a.Xz*c2+a.Xw*c1)*d, (-a.Wx*s5+a.Wz*s2-a.Ww*s1)*d, (a.Zx*s5-a.Zz*s2+a.Zw*s1)*d
+	zx, zy, zz, zw := (a.Yx*c4-a.Yy*c2+a.Yw*c0)*d, (-a.Xx*c4+a.Xy*c2-a.Xw*c0)*d, (a.Wx*s4-a.Wy*s2+a.Ww*s0)*d, (-a.Zx*s4+a.Zy*s2-a.Zw*s0)*d
+	wx, wy, wz, ww := (-a.Yx*c3+a.Yy*c1-a.Yz*c0)*d, (a.Xx*c3-a.Xy*c1+a.Xz*c0)*d, (-a.Wx*s3+a.Wy*s1-a.Wz*s0)*d, (a.Zx*s3-a.Zy*s1+a.Zz*s0)*d
+	m.Xx, m.Xy, m.Xz, m.Xw = xx, xy, xz, xw
+	m.Yx, m.Yy, m.Yz, m.Yw = yx, yy, yz, yw
+	m.Zx, m.Zy, m.Zz, m.Zw = zx, zy, zz, zw
+	m.Wx, m.Wy, m.Wz, m.Ww = wx, wy, wz, ww
+	return m
+}
+
 // SetAa set axis-angle, updates m to be a rotation matrix from the
 // given axis (ax, ay, az) and angle (in radians). See:
 //    http://en.wikipedia.org/wiki/Rotation_matrix#Rotation_matrix_from_axis_and_angle
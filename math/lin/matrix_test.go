@@ -306,6 +306,22 @@ func TestInvM3(t *testing.T) {
 	}
 }
 
+func TestInvM4(t *testing.T) {
+	m, a := &M4{},
+		&M4{1, 2, 3, 4,
+			0, 1, 4, 2,
+			5, 6, 0, 1,
+			2, 0, 1, 3}
+	m.Inv(a)
+	if !NewM4().Mult(m, a).Aeq(M4I) {
+		t.Errorf(format, m.Dump(), a.Dump())
+	}
+	p := NewM4().Persp(60, 1.5, 0.1, 100)
+	if !m.Inv(p).Aeq(NewM4().PerspInv(60, 1.5, 0.1, 100)) {
+		t.Errorf("Expected the perspective inverse")
+	}
+}
+
 func TestSetAxisAngle(t *testing.T) {
 	m, want := &M3{},
 		&M3{1, 0, 0, // rotation 90 degrees around X.
@@ -24,6 +24,8 @@ type mesh struct {
 	vao    uint32 // GPU reference for the mesh and all buffers.
 	bound  bool   // False if the data needs rebinding.
 	loaded bool   // True if data has been set.
+	gen    bool   // True if the application generates the data.
+	bounds bounds // Vertex location bounds.
 
 	// Per-vertex and vertex index data.
 	faces render.Data            // Triangle face indicies.
@@ -48,6 +50,9 @@ func (m *mesh) initData(lloc, span, usage uint32, normalize bool) *mesh {
 	if _, ok := m.vdata[lloc]; !ok {
 		vd := render.NewVertexData(lloc, span, usage, normalize)
 		m.vdata[lloc] = vd
+		if lloc == 0 {
+			m.bounds.span = span // vertex locations.
+		}
 	}
 	return m
 }
@@ -57,6 +62,19 @@ func (m *mesh) setData(lloc uint32, data interface{}) {
 	if _, ok := m.vdata[lloc]; ok {
 		m.vdata[lloc].Set(data)
 		m.loaded = true
+		if lloc == 0 {
+			m.bounds.addVerts(data, true)
+		}
+	}
+}
+
+// setRange replaces part of the data in the specified vertex buffer.
+func (m *mesh) setRange(lloc uint32, from int, data interface{}) {
+	if vd, ok := m.vdata[lloc]; ok {
+		vd.SetRange(from, data)
+		if lloc == 0 {
+			m.bounds.addVerts(data, false) // only grows.
+		}
 	}
 }
 
@@ -69,7 +87,8 @@ func (m *mesh) initFaces(usage uint32) *mesh {
 }
 
 // setFaces stores data for a triangle face index buffer.
-func (m *mesh) setFaces(data []uint16) {
+// Data is expected as []uint16 or []uint32.
+func (m *mesh) setFaces(data interface{}) {
 	if m.faces != nil {
 		m.faces.Set(data)
 	}
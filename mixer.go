@@ -0,0 +1,385 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"log"
+	"math"
+	"sort"
+
+	"github.com/gazed/vu/audio"
+)
+
+// Mixer groups let a settings menu adjust categories of sounds without
+// touching every playing sound, ie:
+//     eng.Mixer(vu.MixMusic).SetVolume(0.5)
+//     eng.Mixer(vu.MixSfx).Mute(true)
+//     eng.Mixer(vu.MixMaster).Pause(true) // game paused.
+// Noise sounds play through one of the music, sfx, or voice groups,
+// see Noise.SetMixer, and all groups play through the master group.
+// A sound is heard at its group volume times the master volume.
+// Sounds played in a paused group start when the group is resumed.
+//
+// Groups and noises can have a reverb or echo effect, see audio.Effect,
+// and a low-pass filter that muffles sounds, ie: for underwater scenes.
+// A noise effect replaces its group effect, which in turn replaces the
+// master effect. Low-pass filters combine, so a muffled noise in a
+// muffled group is muffled twice. Effects are ignored on platforms
+// without audio effects support.
+//     eng.Mixer(vu.MixSfx).SetEffect(audio.CaveReverb)
+//     eng.Mixer(vu.MixMaster).SetLowPass(0.1) // underwater.
+//
+// Groups can limit how many sounds play at once, where the master
+// limit applies to all sounds. A sound played at the limit stops the
+// oldest lowest priority sound, see Noise.SetPriority, or isn't played
+// if the playing sounds all have a higher priority, ie:
+//     eng.Mixer(vu.MixSfx).SetVoices(16)    // many collisions.
+//     eng.Mixer(vu.MixMaster).SetVoices(32) // all sounds.
+
+// Mixer is the volume control for a group of sounds.
+type Mixer interface {
+	Volume() float64             // Group volume from 0 to 1.
+	SetVolume(zeroToOne float64) // Clamped to the range 0 to 1.
+	Muted() bool                 // True if the group is silent.
+	Mute(mute bool)              // Silence, volume is kept.
+	Paused() bool                // True if the group is paused.
+	Pause(pause bool)            // Pause or resume playing sounds.
+	Effect() audio.Effect        // Group effect, nil if none.
+	SetEffect(fx audio.Effect)   // Reverb or echo, nil for none.
+	LowPass() float64            // High frequency volume from 0 to 1.
+	SetLowPass(gainHF float64)   // Muffle sounds. 1 for no filter.
+	Voices() int                 // Most sounds playing at once.
+	SetVoices(max int)           // 0 for no limit, the default.
+}
+
+// Mixer groups used by Eng.Mixer and Noise.SetMixer.
+const (
+	MixMaster = iota // All sounds. Same as Eng.SetVolume and Eng.Mute.
+	MixMusic         // Background music.
+	MixSfx           // Sound effects. Default for noises.
+	MixVoice         // Dialog.
+	mixGroups        // Number of mixer groups.
+)
+
+// Mixer
+// =============================================================================
+// mixGroup implements Mixer.
+
+// mixGroup is the application facing mixer group. Changes are sent
+// to the machine which applies them to the played sounds.
+type mixGroup struct {
+	eng    *engine // Sends changes to the machine.
+	mix    mixSet  // Current group settings.
+	voices int     // Most playing sounds. 0 for no limit.
+}
+
+// mixSet is the state of one mixer group.
+type mixSet struct {
+	group  int     // MixMaster, MixMusic, MixSfx, MixVoice.
+	volume float64 // Group volume from 0 to 1.
+	muted  bool    // True if silenced.
+	paused bool    // True if paused.
+	fx     soundFx // Group effects.
+}
+
+// soundFx is the effect and low-pass filter applied to sounds.
+type soundFx struct {
+	effect audio.Effect // Reverb or echo. Nil for none.
+	muffle float64      // Low-pass high frequency cut. 0 for none.
+	duck   float64      // Sound volume cut. 0 for none.
+}
+
+// newMixGroup creates a full volume mixer group.
+func newMixGroup(eng *engine, group int) *mixGroup {
+	return &mixGroup{eng: eng, mix: mixSet{group: group, volume: 1}}
+}
+
+// Implement Mixer.
+func (g *mixGroup) Volume() float64 { return g.mix.volume }
+func (g *mixGroup) Muted() bool     { return g.mix.muted }
+func (g *mixGroup) Paused() bool    { return g.mix.paused }
+func (g *mixGroup) Effect() audio.Effect {
+	return g.mix.fx.effect
+}
+func (g *mixGroup) LowPass() float64 { return 1 - g.mix.fx.muffle }
+func (g *mixGroup) SetVolume(zeroToOne float64) {
+	g.mix.volume = math.Max(0, math.Min(1, zeroToOne))
+	g.send()
+}
+func (g *mixGroup) Mute(mute bool) {
+	g.mix.muted = mute
+	g.send()
+}
+func (g *mixGroup) Pause(pause bool) {
+	g.mix.paused = pause
+	g.send()
+}
+func (g *mixGroup) SetEffect(fx audio.Effect) {
+	g.mix.fx.effect = fx
+	g.send()
+}
+func (g *mixGroup) SetLowPass(gainHF float64) {
+	g.mix.fx.muffle = 1 - math.Max(0, math.Min(1, gainHF))
+	g.send()
+}
+func (g *mixGroup) Voices() int { return g.voices }
+func (g *mixGroup) SetVoices(max int) {
+	if max >= 0 {
+		g.voices = max // checked by the engine as sounds are played.
+	}
+}
+
+// send the group settings to the machine.
+func (g *mixGroup) send() {
+	go func(mix mixSet) { g.eng.machine <- &mix }(g.mix)
+}
+
+// Implement Eng. Returns nil for unknown groups.
+func (eng *engine) Mixer(group int) Mixer {
+	if group >= 0 && group < mixGroups {
+		return eng.mixers[group]
+	}
+	return nil
+}
+
+// mixGroup
+// =============================================================================
+// mixer applies the mixer groups to the played sounds.
+
+// mixer is used by the machine to track the played sounds.
+type mixer struct {
+	groups [mixGroups]mixSet       // Latest group settings.
+	sounds map[uint64]*mixSound    // Played sounds.
+	slots  map[audio.Effect]uint64 // Bound effects. 0 if binding failed.
+	sids   map[uint64]bool         // Streams that have been sent data.
+}
+
+// mixSound is the mixer state of a played sound.
+type mixSound struct {
+	pid     uint64     // Playback id.
+	group   int        // Mixer group.
+	fx      soundFx    // Sound effects.
+	ctl     playCtl    // Playback controls.
+	held    *playSound // Set if played while paused.
+	playing bool       // True until reported as ended.
+}
+
+// newMixer creates a mixer with full volume groups.
+func newMixer() *mixer {
+	mx := &mixer{sounds: map[uint64]*mixSound{}, slots: map[audio.Effect]uint64{}, sids: map[uint64]bool{}}
+	for group := range mx.groups {
+		mx.groups[group] = mixSet{group: group, volume: 1}
+	}
+	return mx
+}
+
+// volume returns the volume of a mixer group.
+func (mx *mixer) volume(group int) float64 {
+	if mx.groups[group].muted {
+		return 0
+	}
+	return mx.groups[group].volume
+}
+
+// gain returns the volume of a sound, not including master.
+func (mx *mixer) gain(s *mixSound) float64 {
+	return mx.volume(s.group) * s.ctl.volume * (1 - s.fx.duck)
+}
+
+// paused returns true if the group of a sound is paused.
+func (mx *mixer) paused(group int) bool {
+	return mx.groups[group].paused || mx.groups[MixMaster].paused
+}
+
+// play starts a sound at its group volume. Sounds that are paused,
+// or in a paused group, are held until they are resumed.
+func (mx *mixer) play(ac audio.Audio, ps *playSound) {
+	at := ps.at
+	s := &mixSound{pid: ps.pid, group: ps.mix, fx: ps.fx, ctl: ps.ctl, playing: true}
+	mx.sounds[ps.sid] = s
+	mx.control(ac, ps.sid, s)
+	mx.apply(ac, ps.sid)
+	ac.SetDistance(ps.sid, ps.min, ps.max, ps.rolloff)
+	ac.SetRelative(ps.sid, ps.relative)
+	ac.PlaceSound(ps.sid, at.x, at.y, at.z, at.vx, at.vy, at.vz)
+	if s.ctl.paused || mx.paused(s.group) {
+		s.held = ps
+		return
+	}
+	ac.PlaySound(ps.sid, at.x, at.y, at.z)
+}
+
+// control applies the playback controls of a sound.
+func (mx *mixer) control(ac audio.Audio, sid uint64, s *mixSound) {
+	ac.SetSoundGain(sid, mx.gain(s))
+	ac.SetLooping(sid, s.ctl.loop)
+	ac.SetPitch(sid, s.ctl.pitch)
+}
+
+// playback changes the playback controls of a played sound.
+func (mx *mixer) playback(ac audio.Audio, pc *playControl) {
+	s, ok := mx.sounds[pc.sid]
+	if !ok {
+		return
+	}
+	if pc.stop {
+		s.held = nil // ends without being played.
+		ac.StopSound(pc.sid)
+		return
+	}
+	was := s.ctl.paused || mx.paused(s.group)
+	s.ctl = pc.ctl
+	mx.control(ac, pc.sid, s)
+	mx.pause(ac, pc.sid, s, was)
+}
+
+// pause pauses or resumes a sound whose pause state has changed.
+func (mx *mixer) pause(ac audio.Audio, sid uint64, s *mixSound, was bool) {
+	switch paused := s.ctl.paused || mx.paused(s.group); {
+	case paused && !was:
+		ac.PauseSound(sid)
+	case !paused && was:
+		if ps := s.held; ps != nil {
+			ps.ctl, ps.fx = s.ctl, s.fx // latest changes to the held sound.
+			mx.play(ac, ps)
+		} else {
+			ac.ResumeSound(sid)
+		}
+	}
+}
+
+// set updates a mixer group and applies the change to the sounds
+// in the group. Master group changes affect all sounds.
+func (mx *mixer) set(ac audio.Audio, mix *mixSet) {
+	if mix.group < 0 || mix.group >= mixGroups {
+		return
+	}
+	var was [mixGroups]bool
+	for group := range was {
+		was[group] = mx.paused(group)
+	}
+	changed := mx.groups[mix.group].fx != mix.fx
+	mx.groups[mix.group] = *mix
+	if mix.group == MixMaster {
+		ac.SetGain(mx.volume(MixMaster))
+	}
+	for sid, s := range mx.sounds {
+		if mix.group != MixMaster && mix.group != s.group {
+			continue
+		}
+		ac.SetSoundGain(sid, mx.gain(s))
+		if changed {
+			mx.apply(ac, sid)
+		}
+		mx.pause(ac, sid, s, s.ctl.paused || was[s.group])
+	}
+	mx.prune(ac)
+}
+
+// effect changes the effects of played sounds.
+func (mx *mixer) effect(ac audio.Audio, se *soundEffect) {
+	for _, sid := range se.sids {
+		if s, ok := mx.sounds[sid]; ok {
+			ducked := s.fx.duck != se.fx.duck
+			s.fx = se.fx
+			if ducked {
+				ac.SetSoundGain(sid, mx.gain(s))
+			}
+			mx.apply(ac, sid)
+		}
+	}
+	mx.prune(ac)
+}
+
+// apply sets the effect and low-pass filter of a sound. The first
+// effect of the sound, its group, and the master group is used.
+// The low-pass filters of all three are combined.
+func (mx *mixer) apply(ac audio.Audio, sid uint64) {
+	s := mx.sounds[sid]
+	fx, group, master := s.fx, mx.groups[s.group].fx, mx.groups[MixMaster].fx
+	effect := fx.effect
+	if effect == nil {
+		effect = group.effect
+	}
+	if effect == nil {
+		effect = master.effect
+	}
+	ac.SetSoundEffect(sid, mx.bind(ac, effect))
+	ac.SetLowPass(sid, (1-fx.muffle)*(1-group.muffle)*(1-master.muffle))
+}
+
+// bind returns the bound effect, binding new effects. Equal effects
+// share the same binding. Returns 0 for no effect.
+func (mx *mixer) bind(ac audio.Audio, effect audio.Effect) uint64 {
+	if effect == nil {
+		return 0
+	}
+	eid, ok := mx.slots[effect]
+	if !ok {
+		if err := ac.BindEffect(&eid, effect); err != nil {
+			log.Printf("mixer: %s", err)
+		}
+		mx.slots[effect] = eid // remember failures to only log once.
+	}
+	return eid
+}
+
+// prune releases the bound effects that are no longer used.
+func (mx *mixer) prune(ac audio.Audio) {
+	for effect, eid := range mx.slots {
+		used := false
+		for _, mix := range mx.groups {
+			used = used || mix.fx.effect == effect
+		}
+		for _, s := range mx.sounds {
+			used = used || s.fx.effect == effect
+		}
+		if !used {
+			if eid != 0 {
+				ac.ReleaseEffect(eid)
+			}
+			delete(mx.slots, effect)
+		}
+	}
+}
+
+// ended appends the playbacks that finished since the last call,
+// in playback order. Held sounds have not started and are not
+// finished. Expected to be called once per update.
+func (mx *mixer) ended(ac audio.Audio, pids []uint64) []uint64 {
+	start := len(pids)
+	for sid, s := range mx.sounds {
+		if s.playing && s.held == nil && !ac.IsPlaying(sid) {
+			s.playing = false
+			pids = append(pids, s.pid)
+		}
+	}
+	sort.Sort(eids(pids[start:]))
+	return pids
+}
+
+// queue sends generated samples to a stream.
+func (mx *mixer) queue(ac audio.Audio, qs *queueSound) {
+	mx.sids[qs.sid] = true
+	ac.QueueSound(qs.sid, qs.data)
+}
+
+// queued updates the number of buffers queued on each stream.
+func (mx *mixer) queued(ac audio.Audio, queue map[uint64]int) {
+	for sid := range queue {
+		if !mx.sids[sid] {
+			delete(queue, sid) // released.
+		}
+	}
+	for sid := range mx.sids {
+		queue[sid] = ac.Queued(sid)
+	}
+}
+
+// release forgets a sound that is no longer bound.
+func (mx *mixer) release(ac audio.Audio, sid uint64) {
+	delete(mx.sounds, sid)
+	delete(mx.sids, sid)
+	mx.prune(ac)
+}
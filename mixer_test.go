@@ -0,0 +1,198 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gazed/vu/audio"
+)
+
+// Mixer group changes are sent to the machine.
+func TestMixerGroups(t *testing.T) {
+	machine := make(chan msg, 10)
+	eng := newEngine(machine)
+	if eng.Mixer(mixGroups) != nil || eng.Mixer(-1) != nil {
+		t.Errorf("Expected no mixer for unknown groups")
+	}
+	music := eng.Mixer(MixMusic)
+	music.SetVolume(2)
+	if mix, ok := nextMsg(machine).(*mixSet); !ok || mix.group != MixMusic || mix.volume != 1 || music.Volume() != 1 {
+		t.Errorf("Expected clamped music volume, got %+v", mix)
+	}
+	eng.SetVolume(0.5)
+	if mix, ok := nextMsg(machine).(*mixSet); !ok || mix.group != MixMaster || mix.volume != 0.5 {
+		t.Errorf("Expected master volume, got %+v", mix)
+	}
+
+	// noises play through their mixer group.
+	n := eng.Root().NewPov().NewNoise().(*noise)
+	n.snds, n.loaded = []*sound{{sid: 7}}, true
+	n.SetMixer(MixMaster)
+	n.SetMixer(MixVoice)
+	n.Play(0)
+	eng.updatePlays(0)
+	if ps, ok := nextMsg(machine).(*playSound); !ok || ps.mix != MixVoice {
+		t.Errorf("Expected voice sound, got %+v", ps)
+	}
+	n.SetLowPass(0.25)
+	if se, ok := nextMsg(machine).(*soundEffect); !ok || len(se.sids) != 1 || se.fx.muffle != 0.75 {
+		t.Errorf("Expected played sound effects, got %+v", se)
+	}
+}
+
+// The machine applies mixer groups to played sounds.
+func TestMixerSounds(t *testing.T) {
+	ac, mx := &testAudio{}, newMixer()
+	mx.play(ac, &playSound{sid: 1, mix: MixMusic, ctl: testCtl})
+	mx.play(ac, &playSound{sid: 2, mix: MixSfx, ctl: testCtl})
+	ac.check(t, "gain 1 1", "effect 1 0", "filter 1 1", "play 1", "gain 2 1", "effect 2 0", "filter 2 1", "play 2")
+
+	mx.set(ac, &mixSet{group: MixMusic, volume: 0.5})
+	mx.set(ac, &mixSet{group: MixSfx, volume: 1, muted: true})
+	ac.check(t, "gain 1 0.5", "gain 2 0")
+
+	// paused groups hold new sounds until resumed.
+	mx.set(ac, &mixSet{group: MixMaster, volume: 0.8, paused: true})
+	ac.check(t, "master 0.8", "gain 1 0.5", "pause 1", "gain 2 0", "pause 2")
+	mx.play(ac, &playSound{sid: 3, mix: MixVoice, ctl: testCtl})
+	ac.check(t, "gain 3 1", "effect 3 0", "filter 3 1")
+	mx.set(ac, &mixSet{group: MixMusic, volume: 0.5, paused: true})
+	mx.set(ac, &mixSet{group: MixMaster, volume: 0.8})
+	ac.check(t, "gain 1 0.5", "master 0.8", "gain 1 0.5", "gain 2 0", "resume 2", "gain 3 1", "gain 3 1", "effect 3 0", "filter 3 1", "play 3")
+
+	// released sounds are no longer mixed.
+	mx.release(ac, 1)
+	mx.release(ac, 2)
+	mx.release(ac, 3)
+	mx.set(ac, &mixSet{group: MixMusic, volume: 1})
+	ac.check(t)
+}
+
+// Sound effects replace group effects and low-pass filters combine.
+func TestMixerEffects(t *testing.T) {
+	ac, mx := &testAudio{}, newMixer()
+	mx.play(ac, &playSound{sid: 1, mix: MixSfx, ctl: testCtl, fx: soundFx{muffle: 0.5}})
+	mx.play(ac, &playSound{sid: 2, mix: MixMusic, ctl: testCtl, fx: soundFx{effect: audio.DefaultEcho}})
+	ac.check(t, "gain 1 1", "effect 1 0", "filter 1 0.5", "play 1", "bind 1", "gain 2 1", "effect 2 1", "filter 2 1", "play 2")
+
+	// group effects are shared by equal effects.
+	cave := mixSet{group: MixSfx, volume: 1, fx: soundFx{effect: audio.CaveReverb, muffle: 0.5}}
+	mx.set(ac, &cave)
+	cave.group = MixMaster
+	mx.set(ac, &cave)
+	ac.check(t, "bind 2", "gain 1 1", "effect 1 2", "filter 1 0.25",
+		"master 1", "gain 1 1", "effect 1 2", "filter 1 0.125", "gain 2 1", "effect 2 1", "filter 2 0.5")
+
+	// unused effects are released.
+	mx.effect(ac, &soundEffect{sids: []uint64{2}})
+	ac.check(t, "effect 2 2", "filter 2 0.5", "release 1")
+
+	// ducked sounds are quieter.
+	mx.effect(ac, &soundEffect{sids: []uint64{2}, fx: soundFx{duck: 0.5}})
+	ac.check(t, "gain 2 0.5", "effect 2 2", "filter 2 0.5")
+}
+
+// The machine controls played sounds and reports ended playbacks.
+func TestMixerPlayback(t *testing.T) {
+	ac, mx := &testAudio{playing: map[uint64]bool{}}, newMixer()
+	mx.play(ac, &playSound{sid: 1, pid: 5, mix: MixSfx, ctl: playCtl{loop: true, pitch: 2, volume: 0.5}})
+	ac.check(t, "gain 1 0.5", "loop 1", "pitch 1 2", "effect 1 0", "filter 1 1", "play 1")
+	mx.playback(ac, &playControl{sid: 1, ctl: playCtl{pitch: 1, volume: 1, paused: true}})
+	ac.check(t, "gain 1 1", "pause 1")
+
+	// paused playbacks are held until resumed.
+	mx.play(ac, &playSound{sid: 2, pid: 6, mix: MixSfx, ctl: playCtl{pitch: 1, volume: 1, paused: true}})
+	ac.check(t, "gain 2 1", "effect 2 0", "filter 2 1")
+	ac.playing[1] = true // paused.
+	if pids := mx.ended(ac, nil); len(pids) != 0 {
+		t.Errorf("Expected paused and held sounds to be playing %v", pids)
+	}
+	mx.playback(ac, &playControl{sid: 2, ctl: testCtl})
+	ac.check(t, "gain 2 1", "gain 2 1", "effect 2 0", "filter 2 1", "play 2")
+
+	// stopped and finished sounds are reported once.
+	mx.playback(ac, &playControl{sid: 1, stop: true})
+	ac.check(t, "stop 1")
+	ac.playing[1] = false
+	if pids := mx.ended(ac, nil); len(pids) != 2 || pids[0] != 5 || pids[1] != 6 {
+		t.Errorf("Expected ended playbacks, got %v", pids)
+	}
+	if pids := mx.ended(ac, nil); len(pids) != 0 {
+		t.Errorf("Expected ended playbacks to be reported once, got %v", pids)
+	}
+}
+
+// testCtl are the default playback controls.
+var testCtl = playCtl{pitch: 1, volume: 1}
+
+// testAudio records the mixer calls.
+type testAudio struct {
+	audio.Audio                 // Panics for calls that aren't expected.
+	calls       []string        // Calls since the last check.
+	eids        uint64          // Last bound effect.
+	playing     map[uint64]bool // Sounds that are playing.
+}
+
+func (a *testAudio) SetGain(gain float64) { a.log("master %g", gain) }
+func (a *testAudio) SetSoundGain(sid uint64, gain float64) {
+	a.log("gain %d %g", sid, gain)
+}
+func (a *testAudio) PlaySound(sid uint64, x, y, z float64)              { a.log("play %d", sid) }
+func (a *testAudio) PauseSound(sid uint64)                              { a.log("pause %d", sid) }
+func (a *testAudio) ResumeSound(sid uint64)                             { a.log("resume %d", sid) }
+func (a *testAudio) SetDistance(sid uint64, min, max, rolloff float64)  {}
+func (a *testAudio) PlaceSound(sid uint64, x, y, z, vx, vy, vz float64) {}
+func (a *testAudio) StopSound(sid uint64)                               { a.log("stop %d", sid) }
+func (a *testAudio) IsPlaying(sid uint64) bool                          { return a.playing[sid] }
+func (a *testAudio) QueueSound(sid uint64, data []byte)                 { a.log("queue %d %d", sid, len(data)) }
+func (a *testAudio) Queued(sid uint64) int                              { return 2 }
+func (a *testAudio) SetRelative(sid uint64, relative bool) {
+	if relative {
+		a.log("relative %d", sid)
+	}
+}
+func (a *testAudio) SetLooping(sid uint64, loop bool) {
+	if loop {
+		a.log("loop %d", sid) // only log changes from the defaults.
+	}
+}
+func (a *testAudio) SetPitch(sid uint64, pitch float64) {
+	if pitch != 1 {
+		a.log("pitch %d %g", sid, pitch)
+	}
+}
+func (a *testAudio) SetSoundEffect(sid, eid uint64)        { a.log("effect %d %d", sid, eid) }
+func (a *testAudio) SetLowPass(sid uint64, gainHF float64) { a.log("filter %d %g", sid, gainHF) }
+func (a *testAudio) ReleaseEffect(eid uint64)              { a.log("release %d", eid) }
+func (a *testAudio) BindEffect(eid *uint64, fx audio.Effect) error {
+	a.eids++
+	*eid = a.eids
+	a.log("bind %d", *eid)
+	return nil
+}
+func (a *testAudio) log(format string, args ...interface{}) {
+	a.calls = append(a.calls, fmt.Sprintf(format, args...))
+}
+
+// check compares the calls, ignoring the order of calls for
+// different sounds, and clears them.
+func (a *testAudio) check(t *testing.T, want ...string) {
+	got := map[string]int{}
+	for _, call := range a.calls {
+		got[call]++
+	}
+	for _, call := range want {
+		got[call]--
+	}
+	for _, cnt := range got {
+		if cnt != 0 {
+			t.Errorf("Expected %s, got %s", strings.Join(want, ", "), strings.Join(a.calls, ", "))
+			break
+		}
+	}
+	a.calls = nil
+}
@@ -49,15 +49,25 @@ type Model interface {
 	//    Vertex normals   lloc=1 span=3_floats_per_vertex.
 	//    UV tex coords    lloc=2 span=2_floats_per_vertex.
 	//    Color            lloc=3 span=4_floats_per_vertex.
+	//    Joint indexes    lloc=4 span=4_bytes_per_vertex.
+	//    Joint weights    lloc=5 span=4_bytes_per_vertex.
+	//    Tangents         lloc=6 span=4_floats_per_vertex.
+	//    UV set 1 coords  lloc=7 span=2_floats_per_vertex.
 	InitMesh(lloc, span, usage uint32, normalize bool) Model
 	SetMeshData(lloc uint32, data interface{}) // Only works after InitMesh
 	InitFaces(usage uint32) Model              // Defaults to STATIC_DRAW
-	SetFaces(data []uint16)                    // Indicies to vertex positions.
+	SetFaces(data interface{})                 // []uint16, or []uint32 for large meshes.
 	SetDrawMode(mode int) Model                // TRIANGLES, LINES, POINTS.
 
+	// SetMeshRange replaces part of the existing data for lloc starting
+	// at the given vertex. Only works after SetMeshData and only the
+	// replaced data is copied to the GPU.
+	SetMeshRange(lloc uint32, from int, data interface{})
+
 	// Models can have one or more textures applied to a single mesh.
 	// Textures are initialized from assets and can be updated with images.
 	AddTex(name string) Model             // Loads and adds a texture.
+	AddCube(name string) Model            // Loads and adds a cube map.
 	NewTex(name string) Model             // Adds new texture. Needs SetImg.
 	SetTex(index int, name string)        // Replace/reload texture.
 	SetImg(index int, img image.Image)    // Replace image, nil values ignored.
@@ -73,6 +83,7 @@ type Model interface {
 	Action() (action, frame, maxFrame int) // Current movement info.
 	Actions() []string                     // Animation sequence names.
 	Pose(bone int) *lin.M4                 // Bone transform: attach point.
+	Joint(name string) (bone int)          // Bone index, -1 if unknown.
 
 	// Fonts are used to display small text phrases using a mesh plane.
 	// Fonts imply a texture shader and a texture for this model.
@@ -120,6 +131,7 @@ type model struct {
 	move    int        // Aurrent animation defaults to 0.
 	nFrames int        // Number of frames in the current movement.
 	pose    []lin.M4   // Pose refreshed each update.
+	rag     *ragdoll   // Optional: physics replaces the animation.
 
 	// Optional font information.
 	fnt         *font  // Optional: font layout data.
@@ -214,6 +226,7 @@ func (m *model) LoadMesh(meshName string) Model {
 func (m *model) NewMesh(meshName string) Model {
 	if m.msh == nil && m.anm == nil {
 		m.msh = newMesh(meshName)
+		m.msh.gen = true
 	}
 	return m
 }
@@ -229,13 +242,19 @@ func (m *model) SetMeshData(lloc uint32, data interface{}) {
 		m.msh.bound = false
 	}
 }
+func (m *model) SetMeshRange(lloc uint32, from int, data interface{}) {
+	if m.msh != nil {
+		m.msh.setRange(lloc, from, data)
+		m.msh.bound = false
+	}
+}
 func (m *model) InitFaces(usage uint32) Model {
 	if m.msh != nil {
 		m.msh.initFaces(usage)
 	}
 	return m
 }
-func (m *model) SetFaces(data []uint16) {
+func (m *model) SetFaces(data interface{}) {
 	if m.msh != nil {
 		m.msh.setFaces(data)
 		m.msh.bound = false
@@ -254,6 +273,12 @@ func (m *model) AddTex(name string) Model {
 	m.loads = append(m.loads, &loadReq{data: m, index: index, a: newTexture(name)})
 	return m
 }
+func (m *model) AddCube(name string) Model {
+	index := len(m.texs)
+	m.texs = append(m.texs, newCube(name))
+	m.loads = append(m.loads, &loadReq{data: m, index: index, a: newCube(name)})
+	return m
+}
 func (m *model) SetTex(index int, name string) {
 	if index >= 0 && index < len(m.texs) {
 		// Add the set request to a list of textures that need to be loaded.
@@ -263,7 +288,9 @@ func (m *model) SetTex(index int, name string) {
 	}
 }
 func (m *model) NewTex(name string) Model {
-	m.texs = append(m.texs, newTexture(name))
+	t := newTexture(name)
+	t.gen = true
+	m.texs = append(m.texs, t)
 	return m
 }
 func (m *model) SetImg(index int, img image.Image) {
@@ -321,6 +348,7 @@ func (m *model) SetPhrase(phrase string) Model {
 	if m.msh == nil {
 		m.msh = newMesh("phrase") // dynamic mesh for phrase backing.
 		m.msh.loaded = true       // trigger a rebind in updateModels.
+		m.msh.gen = true
 	}
 	if len(phrase) > 0 && m.phrase != phrase {
 		m.phrase = phrase   // used by loader to set mesh data.
@@ -396,6 +424,28 @@ func (m *model) Pose(index int) *lin.M4 {
 	return lin.M4I
 }
 
+// Joint returns the index of the named bone. Returns -1 if the
+// bone is unknown or the animation is not yet loaded.
+func (m *model) Joint(name string) int {
+	if m.anm != nil {
+		return m.anm.joint(name)
+	}
+	return -1
+}
+
+// jointTransform sets jt to the current model space transform of
+// the given bone. Returns false if the model has no such bone.
+func (m *model) jointTransform(bone int, jt *lin.M4) bool {
+	if m.anm == nil || bone < 0 || bone >= len(m.anm.bases) {
+		return false
+	}
+	jt.Set(&m.anm.bases[bone])
+	if bone < len(m.pose) && m.pose[bone].Ww != 0 { // animated.
+		jt.Mult(jt, &m.pose[bone]) // basePose * (inverseBasePose * animatedPose)
+	}
+	return true
+}
+
 // SetEffect ties the particle effect classes to the model.
 func (m *model) SetEffect(mover ParticleEffect, maxParticles int) Model {
 	if mover != nil {
@@ -0,0 +1,204 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+// Music plays a game soundtrack through the MixMusic mixer group, ie:
+//     music := eng.Music()
+//     music.Intro("theme_intro", "theme_loop", 0) // menu music.
+//     ...
+//     music.Play("battle", 2)                       // crossfade over 2s.
+//     music.Queue("level1", "level2", "level3")     // then a playlist.
+// Tracks are sound names that are loaded when first used. Tracks start
+// once loaded, so queue tracks ahead of time to avoid a delay. Queued
+// tracks play back to back after the current track ends. Music plays
+// relative to the listener so it is heard as recorded.
+
+// Music controls the soundtrack. See Eng.Music.
+type Music interface {
+	Play(track string, fade float64)        // Crossfade to the track.
+	Intro(intro, loop string, fade float64) // Play intro then repeat loop.
+	Queue(tracks ...string)                 // Play after the current track.
+	Next(fade float64)                      // Crossfade to the next queued.
+	Stop(fade float64)                      // Fade out. Clears the queue.
+	SetRepeat(repeat bool)                  // Replay tracks once done.
+	Track() string                          // Current track, "" if none.
+}
+
+// musicPriority keeps music playing when sounds are limited.
+const musicPriority = 100
+
+// Music
+// =============================================================================
+// music implements Music.
+
+// music crossfades between the tracks of a noise.
+type music struct {
+	eng    *engine        // Creates the music Pov.
+	pov    *pov           // Music entity. Never moves.
+	noise  *noise         // Loads and plays the tracks.
+	tracks map[string]int // Noise sound index of each track.
+	queue  []string       // Tracks to play next.
+	played []string       // Queued tracks that were played.
+	repeat bool           // True to replay played tracks.
+	fading []Playback     // Tracks fading out.
+
+	// The current track and the track that is waiting to start.
+	track   string     // Current track.
+	loop    string     // Track that follows the current track.
+	pb      Playback   // Current track playback.
+	waiting *musicPlay // Track waiting to be loaded.
+}
+
+// musicPlay is a track request.
+type musicPlay struct {
+	track, loop string  // Track and the track that follows it.
+	fade        float64 // Crossfade seconds.
+}
+
+// Implement Eng.
+func (eng *engine) Music() Music {
+	eng.music.ensure()
+	return eng.music
+}
+
+// ensure there is a Pov for playing music. Music is reset when
+// the Pov is disposed, ie: by Eng.Reset.
+func (m *music) ensure() {
+	if m.pov != nil {
+		if _, ok := m.eng.povs[m.pov.eid]; ok {
+			return
+		}
+	}
+	*m = music{eng: m.eng, repeat: m.repeat, tracks: map[string]int{}}
+	m.pov = m.eng.root().NewPov().(*pov)
+	m.noise = m.pov.NewNoise().(*noise)
+	m.noise.SetMixer(MixMusic)
+	m.noise.SetPriority(musicPriority)
+	m.noise.rel = true
+}
+
+// Implement Music.
+func (m *music) Track() string         { return m.track }
+func (m *music) SetRepeat(repeat bool) { m.repeat = repeat }
+func (m *music) Play(track string, fade float64) {
+	m.request(&musicPlay{track: track, fade: fade})
+}
+func (m *music) Intro(intro, loop string, fade float64) {
+	if intro == "" {
+		intro = loop
+	}
+	m.request(&musicPlay{track: intro, loop: loop, fade: fade})
+}
+func (m *music) Queue(tracks ...string) {
+	for _, track := range tracks {
+		m.add(track)
+	}
+	m.queue = append(m.queue, tracks...)
+}
+func (m *music) Next(fade float64) {
+	if len(m.queue) > 0 {
+		m.request(&musicPlay{track: m.next(), fade: fade})
+	}
+}
+func (m *music) Stop(fade float64) {
+	m.queue, m.played, m.waiting = nil, nil, nil
+	m.fadeOut(fade)
+}
+
+// request replaces any waiting track. The track
+// starts once it is loaded.
+func (m *music) request(mp *musicPlay) {
+	m.add(mp.track)
+	m.add(mp.loop)
+	m.waiting = mp
+}
+
+// add loads new tracks.
+func (m *music) add(track string) {
+	if _, ok := m.tracks[track]; !ok && track != "" {
+		m.tracks[track] = len(m.noise.snds)
+		m.noise.Add(track)
+	}
+}
+
+// next takes the next track from the queue.
+func (m *music) next() (track string) {
+	track, m.queue = m.queue[0], m.queue[1:]
+	m.played = append(m.played, track)
+	return track
+}
+
+// loaded returns true if the track can be played.
+func (m *music) loaded(track string) bool {
+	index, ok := m.tracks[track]
+	return ok && m.noise.loaded && m.noise.snds[index].sid != 0
+}
+
+// fadeOut fades and then stops the current track.
+func (m *music) fadeOut(fade float64) {
+	if m.pb != nil {
+		if fade > 0 {
+			m.pb.Fade(0, fade)
+			m.fading = append(m.fading, m.pb)
+		} else {
+			m.pb.Stop()
+		}
+	}
+	m.track, m.loop, m.pb = "", "", nil
+}
+
+// start crossfades from the current track to the given track.
+func (m *music) start(mp *musicPlay) {
+	m.fadeOut(mp.fade)
+	m.track, m.loop = mp.track, mp.loop
+	m.pb = m.noise.Play(m.tracks[mp.track])
+	m.pb.SetLoop(mp.track == mp.loop)
+	if mp.fade > 0 {
+		m.pb.SetVolume(0)
+		m.pb.Fade(1, mp.fade)
+	}
+}
+
+// update starts tracks once they are loaded and the previous track
+// has ended. Expected to be called once per update.
+func (m *music) update() {
+	if m.pov == nil {
+		return // music not used.
+	}
+	if _, ok := m.eng.povs[m.pov.eid]; !ok {
+		m.pov = nil // music disposed.
+		return
+	}
+
+	// stop faded out tracks.
+	fading := m.fading[:0]
+	for _, pb := range m.fading {
+		if pb.IsPlaying() && pb.Volume() > 0 {
+			fading = append(fading, pb)
+		} else {
+			pb.Stop()
+		}
+	}
+	m.fading = fading
+
+	// start the next track once the current track is done.
+	if m.waiting == nil && m.pb != nil && !m.pb.IsPlaying() {
+		switch {
+		case m.loop != "":
+			m.waiting = &musicPlay{track: m.loop, loop: m.loop}
+		case len(m.queue) == 0 && m.repeat && len(m.played) > 0:
+			m.queue, m.played = m.played, nil
+			fallthrough
+		default:
+			m.track, m.pb = "", nil
+		}
+	}
+	if m.waiting == nil && m.pb == nil && len(m.queue) > 0 {
+		m.waiting = &musicPlay{track: m.next()}
+	}
+	if m.waiting != nil && m.loaded(m.waiting.track) && (m.waiting.loop == "" || m.loaded(m.waiting.loop)) {
+		m.start(m.waiting)
+		m.waiting = nil
+	}
+}
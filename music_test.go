@@ -0,0 +1,79 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"testing"
+)
+
+// Music waits for tracks to load, plays intros before loops,
+// and crossfades between tracks.
+func TestMusic(t *testing.T) {
+	machine := make(chan msg, 10)
+	eng := newEngine(machine)
+	m := eng.Music().(*music)
+	m.Intro("intro", "loop", 0)
+	if m.update(); m.Track() != "" {
+		t.Fatalf("Expected music to wait for tracks to load")
+	}
+	testTracks(m, "intro", "loop")
+	m.update()
+	eng.updatePlays(0)
+	if ps, ok := nextMsg(machine).(*playSound); !ok || !ps.relative || ps.mix != MixMusic || ps.ctl.loop || m.Track() != "intro" {
+		t.Fatalf("Expected intro music, got %+v", ps)
+	}
+	eng.endPlays([]uint64{m.pb.(*playback).pid})
+	m.update()
+	eng.updatePlays(0)
+	if ps, ok := nextMsg(machine).(*playSound); !ok || !ps.ctl.loop || m.Track() != "loop" {
+		t.Fatalf("Expected looping music, got %+v", ps)
+	}
+
+	// crossfades fade out the old track while fading in the new track.
+	old := m.pb
+	m.Play("battle", 2)
+	testTracks(m, "battle")
+	m.update()
+	eng.updatePlays(1)
+	if m.Track() != "battle" || m.pb.Volume() != 0.5 || old.Volume() != 0.5 {
+		t.Fatalf("Expected crossfade %s %f %f", m.Track(), m.pb.Volume(), old.Volume())
+	}
+	eng.updatePlays(1)
+	if m.update(); old.IsPlaying() || len(m.fading) != 0 || m.pb.Volume() != 1 {
+		t.Errorf("Expected faded out track to stop")
+	}
+
+	// queued tracks play after the current track and repeat.
+	m.Queue("a", "b")
+	m.SetRepeat(true)
+	testTracks(m, "a", "b")
+	for _, track := range []string{"a", "b", "a"} {
+		eng.endPlays([]uint64{m.pb.(*playback).pid})
+		if m.update(); m.Track() != track {
+			t.Errorf("Expected track %s, got %s", track, m.Track())
+		}
+	}
+	m.Next(0)
+	if m.update(); m.Track() != "b" {
+		t.Errorf("Expected next track, got %s", m.Track())
+	}
+	m.Stop(0)
+	if m.update(); m.Track() != "" || len(m.queue) != 0 {
+		t.Errorf("Expected stopped music")
+	}
+
+	// music is reset with the engine.
+	eng.Reset()
+	if m.update(); eng.Music().Track() != "" || len(m.tracks) != 0 {
+		t.Errorf("Expected reset music")
+	}
+}
+
+// testTracks fakes loading music tracks.
+func testTracks(m *music, tracks ...string) {
+	for _, track := range tracks {
+		m.noise.snds[m.tracks[track]] = &sound{sid: uint64(m.tracks[track] + 1)}
+	}
+	m.noise.loaded = true
+}
@@ -4,33 +4,162 @@
 package vu
 
 import (
+	"math"
+
 	"github.com/gazed/vu/audio"
 )
 
 // Noise manages sounds associated with a singe Pov. Each noise must be
 // loaded with sound data that has been bound to the audio card in order
 // for the noise to be played.
+//
+// Played sounds follow the Pov world location and are heard in 3D by
+// the listener, see Eng.SetListener. Sounds fade with distance from the
+// listener and are panned using the listener orientation. Sounds are
+// pitch shifted using the velocities of the Pov physics bodies once
+// doppler is turned on with Eng.SetDoppler. Only mono sounds are heard
+// in 3D. Stereo sounds, like music, play as recorded.
 type Noise interface {
-	Add(sound string) // Loads and adds a sound.
-	Play(index int)   // Play. Loaded and bound sounds only.
+	Add(sound string)                         // Loads and adds a sound.
+	AddStream(channels, frequency int) Stream // Adds a generated sound.
+	Play(index int) Playback                  // Play loaded and bound sounds only.
+
+	// SetDistance sets the distances used to fade the noise sounds,
+	// see Eng.SetAttenuation. Sounds closer than min are full volume
+	// and fade no more past max. Rolloff scales how quickly sounds fade,
+	// where 1 is normal. Defaults are 1, no max, and 1.
+	SetDistance(min, max, rolloff float64)
+
+	// SetMixer routes the noise sounds through the MixMusic, MixSfx,
+	// or MixVoice mixer group for the next Play. Default is MixSfx.
+	SetMixer(group int)
+
+	// SetPriority sets the priority of the next Play for sharing the
+	// mixer voices, see Mixer.SetVoices. Higher priority sounds stop
+	// lower priority sounds when there are no free voices. Default is 0.
+	// Music plays at priority 100.
+	SetPriority(priority int)
+
+	// SetEffect and SetLowPass change the effects of played sounds
+	// and sounds that are played later, see Mixer.
+	SetEffect(fx audio.Effect) // Reverb or echo, nil for none.
+	SetLowPass(gainHF float64) // Muffle sounds. 1 for no filter.
+
+	// SetOcclusion ducks and muffles the noise sounds while a solid
+	// body in the mask layers is between the listener and the noise.
+	// Duck cuts the volume and muffle cuts the high frequencies, both
+	// from 0 for no change to 1 for silent. Occlusion is checked once
+	// per update. The default 0 mask turns occlusion off.
+	SetOcclusion(mask uint32, duck, muffle float64)
 }
 
+// Sound attenuation models for Eng.SetAttenuation.
+// See audio.Attenuate for the attenuation formulas.
+const (
+	AttenuateNone        = audio.NoAttenuation // Sounds don't fade.
+	AttenuateLinear      = audio.Linear        // Silent at max distance.
+	AttenuateInverse     = audio.Inverse       // Default.
+	AttenuateExponential = audio.Exponential   // Fades by a power of distance.
+)
+
 // Noise
 // =============================================================================
 // noise implements noise.
 
 // noise deals with sounds that are mapped to a location.
 type noise struct {
-	eng    *engine    // Entity manager.
-	eid    uint64     // Entity identifier related to this sound.
-	loaded bool       // True if data has been set.
-	snds   []*sound   // one or more sounds.
-	loads  []*loadReq // Assets waiting to be loaded.
+	eng     *engine    // Entity manager.
+	eid     uint64     // Entity identifier related to this sound.
+	loaded  bool       // True if data has been set.
+	snds    []*sound   // one or more sounds.
+	loads   []*loadReq // Assets waiting to be loaded.
+	played  []uint64   // Played sounds that follow the Pov.
+	min     float64    // Full volume distance.
+	max     float64    // Distance where sounds stop fading.
+	rolloff float64    // How quickly sounds fade.
+	at      soundAt    // Last location and velocity sent to played sounds.
+	mix     int        // Mixer group for played sounds.
+	pri     int        // Voice priority for played sounds.
+	fx      soundFx    // Effects for played sounds.
+	rel     bool       // Play relative to the listener.
+
+	// Occlusion changes the effects while the sounds are blocked.
+	occMask  uint32  // Layers that block sounds. 0 for none.
+	occFx    soundFx // Duck and muffle when blocked.
+	occluded bool    // True if the sounds are blocked.
+}
+
+// soundAt is the world location and velocity of a sound or listener.
+type soundAt struct {
+	x, y, z    float64 // World location.
+	vx, vy, vz float64 // Physics body velocity.
 }
 
 // newNoise allocates data structures for a noise.
 func newNoise(eng *engine, eid uint64) *noise {
-	return &noise{eng: eng, eid: eid}
+	return &noise{eng: eng, eid: eid, min: 1, max: math.MaxFloat32, rolloff: 1, mix: MixSfx}
+}
+
+// SetPriority updates the priority for the next Play.
+func (n *noise) SetPriority(priority int) { n.pri = priority }
+
+// SetDistance updates the distances for the next Play.
+func (n *noise) SetDistance(min, max, rolloff float64) {
+	n.min, n.max, n.rolloff = min, max, rolloff
+}
+
+// SetMixer ignores the master group and unknown groups.
+func (n *noise) SetMixer(group int) {
+	if group > MixMaster && group < mixGroups {
+		n.mix = group
+	}
+}
+
+// Implement Noise.
+func (n *noise) SetEffect(fx audio.Effect) {
+	n.fx.effect = fx
+	n.sendFx()
+}
+func (n *noise) SetLowPass(gainHF float64) {
+	n.fx.muffle = 1 - math.Max(0, math.Min(1, gainHF))
+	n.sendFx()
+}
+
+func (n *noise) SetOcclusion(mask uint32, duck, muffle float64) {
+	n.occMask = mask
+	n.occFx.duck = math.Max(0, math.Min(1, duck))
+	n.occFx.muffle = math.Max(0, math.Min(1, muffle))
+	if n.occluded {
+		n.occluded = mask != 0
+		n.sendFx()
+	}
+}
+
+// occlude changes the played sounds effects when
+// the sounds become blocked or unblocked.
+func (n *noise) occlude(occluded bool) {
+	if occluded != n.occluded {
+		n.occluded = occluded
+		n.sendFx()
+	}
+}
+
+// heard returns the played sound effects including any occlusion.
+func (n *noise) heard() soundFx {
+	fx := n.fx
+	if n.occluded {
+		fx.duck = n.occFx.duck
+		fx.muffle = 1 - (1-fx.muffle)*(1-n.occFx.muffle)
+	}
+	return fx
+}
+
+// sendFx updates the effects of the played sounds.
+func (n *noise) sendFx() {
+	if len(n.played) > 0 {
+		se := &soundEffect{sids: append([]uint64{}, n.played...), fx: n.heard()}
+		go func(se *soundEffect) { n.eng.machine <- se }(se)
+	}
 }
 
 // Add a sound to the noise and mark the noise as needing loading.
@@ -40,19 +169,54 @@ func (n *noise) Add(soundName string) {
 	n.snds = append(n.snds, newSound(soundName))
 }
 
+// AddStream adds a sound that plays generated samples. The
+// stream sound is bound like a loaded sound.
+func (n *noise) AddStream(channels, frequency int) Stream {
+	s := newStream(n, len(n.snds), channels, frequency)
+	n.loaded = false
+	n.loads = append(n.loads, &loadReq{data: n, index: s.index, a: s.snd})
+	n.snds = append(n.snds, s.snd)
+	n.eng.streams = append(n.eng.streams, s)
+	return s
+}
+
 // Play gets the sounds location and generates a play sound request.
-// The play request is sent to a goroutine allowing the goroutine to block
-// until the machine can service the request.
-func (n *noise) Play(index int) {
+// The play request is sent with any playback changes at the end of
+// the update.
+func (n *noise) Play(index int) Playback {
 	if n.loaded && index >= 0 && index < len(n.snds) {
 		snd := n.snds[index]
-		if p, ok := n.eng.povs[n.eid]; ok {
-			x, y, z := p.Location()
-			go func(sid uint64, x, y, z float64) {
-				n.eng.machine <- &playSound{sid: sid, x: x, y: y, z: z}
-			}(snd.sid, x, y, z)
+		if p, ok := n.eng.povs[n.eid]; ok && p.active() {
+			n.at = n.eng.soundAt(p)
+			if n.occMask != 0 {
+				n.occluded = n.eng.occluded(p, n.occMask)
+			}
+			n.follow(snd.sid)
+			ps := &playSound{sid: snd.sid, at: n.at, min: n.min, max: n.max, rolloff: n.rolloff, relative: n.rel, mix: n.mix, fx: n.heard()}
+			return n.eng.play(ps, n.pri)
+		}
+	}
+	return &playback{done: true} // nothing to play.
+}
+
+// follow remembers the played sound so that it moves with the Pov.
+func (n *noise) follow(sid uint64) {
+	for _, played := range n.played {
+		if played == sid {
+			return
 		}
 	}
+	n.played = append(n.played, sid)
+}
+
+// place moves the played sounds when the Pov location
+// or velocity has changed.
+func (n *noise) place(p *pov) {
+	if at := n.eng.soundAt(p); at != n.at && len(n.played) > 0 {
+		n.at = at
+		ps := &placeSound{sids: append([]uint64{}, n.played...), at: at}
+		go func(ps *placeSound) { n.eng.machine <- ps }(ps)
+	}
 }
 
 // noise
@@ -66,6 +230,7 @@ type sound struct {
 	sid        uint64      // Audio card identifier related to sound location.
 	did        uint64      // Audio data reference identifier.
 	data       *audio.Data // noise data.
+	stream     bool        // True for generated sounds, see Stream.
 	lx, ly, lz float64     // noise location.
 }
 
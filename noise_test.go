@@ -0,0 +1,152 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gazed/vu/math/lin"
+	"github.com/gazed/vu/physics"
+)
+
+// Check that played sounds follow their Pov and body velocity, and
+// that the listener is oriented by its Pov.
+func TestNoiseSpatial(t *testing.T) {
+	machine := make(chan msg, 10)
+	eng := newEngine(machine)
+	p := eng.Root().NewPov().SetLocation(1, 2, 3)
+	p.NewBody(physics.NewBody(physics.NewSphere(1))).Push(4, 0, 0)
+	n := p.NewNoise().(*noise)
+	n.snds, n.loaded = []*sound{{sid: 7}}, true
+	n.SetDistance(2, 50, 0.5)
+	n.Play(0)
+	eng.updatePlays(0)
+	if ps, ok := nextMsg(machine).(*playSound); !ok || ps.sid != 7 || ps.at != (soundAt{1, 2, 3, 4, 0, 0}) || ps.min != 2 || ps.max != 50 || ps.rolloff != 0.5 {
+		t.Fatalf("Expected sound played at the pov, got %+v", ps)
+	}
+	p.SetLocation(5, 2, 3)
+	eng.updateNoises()
+	if ps, ok := nextMsg(machine).(*placeSound); !ok || len(ps.sids) != 1 || ps.sids[0] != 7 || ps.at.x != 5 {
+		t.Fatalf("Expected played sound to move, got %+v", ps)
+	}
+	if eng.updateNoises(); len(machine) != 0 {
+		t.Errorf("Expected no update for an unmoved sound")
+	}
+
+	// the listener faces down -Z of its pov.
+	l := eng.Root().NewPov()
+	l.SetRotation(lin.NewQ().SetAa(0, 1, 0, lin.Rad(90)))
+	l.SetListener()
+	eng.updateSoundListener()
+	pl, ok := nextMsg(machine).(*placeListener)
+	if !ok || !lin.Aeq(pl.fx, -1) || !lin.Aeq(pl.fz, 0) || !lin.Aeq(pl.uy, 1) {
+		t.Errorf("Expected listener facing -X, got %+v", pl)
+	}
+}
+
+// The listener hears from its camera and gets a velocity from moving.
+func TestListener(t *testing.T) {
+	machine := make(chan msg, 10)
+	eng := newEngine(machine)
+	l := eng.Root().NewPov()
+	cam := l.NewCam()
+	cam.SetLocation(2, 0, 0)
+	eng.SetListener(l)
+	if eng.Listener() != l {
+		t.Fatalf("Expected new listener")
+	}
+	eng.updateSoundListener()
+	pl, ok := nextMsg(machine).(*placeListener)
+	if !ok || pl.at != (soundAt{x: 2}) || !lin.Aeq(pl.fz, -1) || !lin.Aeq(pl.uy, 1) {
+		t.Fatalf("Expected listener at the camera, got %+v", pl)
+	}
+	cam.SetLocation(3, 0, 0)
+	eng.updateSoundListener()
+	if pl, ok = nextMsg(machine).(*placeListener); !ok || pl.at.x != 3 || !lin.Aeq(pl.at.vx, 1/dt.Seconds()) {
+		t.Fatalf("Expected moving listener, got %+v", pl)
+	}
+
+	// switching listeners doesn't measure motion between them.
+	far := eng.Root().NewPov().SetLocation(100, 0, 0)
+	eng.SetListener(far)
+	eng.updateSoundListener()
+	if pl, ok = nextMsg(machine).(*placeListener); !ok || pl.at.x != 100 || pl.at.vx != 0 {
+		t.Fatalf("Expected still listener, got %+v", pl)
+	}
+
+	// disposed listeners go back to the root.
+	far.Dispose(PovNode)
+	if eng.Listener() != eng.Root() {
+		t.Errorf("Expected root listener")
+	}
+}
+
+// Changes to the sound output device are published.
+func TestAudioDevice(t *testing.T) {
+	machine := make(chan msg, 10)
+	eng := newEngine(machine)
+	devices := []string{}
+	eng.Subscribe(AudioEvent, func(topic string, data interface{}) {
+		devices = append(devices, data.(string))
+	})
+	for _, device := range []string{"speakers", "speakers", "headphones"} {
+		eng.updateDevice(&State{AudioDevice: device})
+	}
+	eng.events.dispatch()
+	if len(devices) != 1 || devices[0] != "headphones" {
+		t.Errorf("Expected new device to be published, got %v", devices)
+	}
+	eng.SetAudioDevice("speakers")
+	if sd, ok := nextMsg(machine).(*setAudioDevice); !ok || sd.name != "speakers" {
+		t.Errorf("Expected device request, got %+v", sd)
+	}
+}
+
+// Sounds behind solid bodies are ducked and muffled.
+func TestOcclusion(t *testing.T) {
+	machine := make(chan msg, 10)
+	eng := newEngine(machine)
+	wall := eng.Root().NewPov().SetLocation(0, 0, -5)
+	wall.NewBody(NewBox(1, 1, 0.1))
+	wall.SetSolid(0, 0)
+	p := eng.Root().NewPov().SetLocation(0, 0, -10).(*pov)
+	n := p.NewNoise().(*noise)
+	n.snds, n.loaded = []*sound{{sid: 7}}, true
+	n.SetLowPass(0.5)
+	n.SetOcclusion(1, 0.5, 0.5)
+	n.Play(0)
+	eng.updatePlays(0)
+	if ps, ok := nextMsg(machine).(*playSound); !ok || ps.fx.duck != 0.5 || ps.fx.muffle != 0.75 {
+		t.Fatalf("Expected occluded sound, got %+v", ps)
+	}
+
+	// bodies in other layers don't block sounds.
+	n.SetOcclusion(2, 0.5, 0.25)
+	if se, ok := nextMsg(machine).(*soundEffect); !ok || se.fx.duck != 0.5 || se.fx.muffle != 0.625 {
+		t.Fatalf("Expected new occlusion, got %+v", se)
+	}
+	eng.updateNoises()
+	if se, ok := nextMsg(machine).(*soundEffect); !ok || se.fx.duck != 0 || se.fx.muffle != 0.5 {
+		t.Fatalf("Expected unblocked sound, got %+v", se)
+	}
+	if eng.updateNoises(); len(machine) != 0 {
+		t.Errorf("Expected no update for unchanged occlusion")
+	}
+
+	// only bodies between the listener and the sound block sounds.
+	if !eng.occluded(p, 1) || eng.occluded(p.SetLocation(0, 0, -2).(*pov), 1) {
+		t.Errorf("Expected sounds behind the wall to be blocked")
+	}
+}
+
+// nextMsg returns the next machine message, or nil if none arrive.
+func nextMsg(machine chan msg) msg {
+	select {
+	case m := <-machine:
+		return m
+	case <-time.After(time.Second):
+		return nil
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/gazed/vu/load"
+	"github.com/gazed/vu/render"
+)
+
+// Wavefront .obj files can describe many meshes where each mesh uses
+// the materials from a .mtl material library. LoadObj adds a Pov for
+// each mesh, ie:
+//     level, err := eng.LoadObj("level1", eng.Root()) // models/level1.obj
+// Each mesh Pov is named for its object and has a model for each material
+// that the object uses. Objects with more than one material have a child
+// Pov for each material. Materials with a diffuse texture map are drawn
+// with the "uv" shader, others with the "phong" shader. Models use the
+// material colors and transparency and load the diffuse textures from
+// the image assets. Objects without materials are drawn white. Imported
+// meshes are not saved in scenes.
+
+// objFile is the mesh and material data for one .obj file.
+type objFile struct {
+	objs []*load.ObjData // Meshes in file order.
+	mtls []*load.MtlData // Material library. Empty if none.
+}
+
+// Implement Eng interface. Files are read once and reused
+// for later loads of the same name.
+func (eng *engine) LoadObj(name string, parent Pov) (Pov, error) {
+	of, ok := eng.objs[name]
+	if !ok {
+		objs, err := eng.loader.ld.Obj(name)
+		if err != nil {
+			return nil, fmt.Errorf("LoadObj: %s", err)
+		}
+		of = &objFile{objs: objs}
+		if len(objs) > 0 && objs[0].Mtllib != "" {
+			if of.mtls, err = eng.loader.ld.Mtls(objs[0].Mtllib); err != nil {
+				log.Printf("LoadObj: using default materials %s", err)
+			}
+		}
+		eng.objs[name] = of
+	}
+	return eng.newObj(name, of, parent)
+}
+
+// newObj creates a child of parent from the given obj file data.
+func (eng *engine) newObj(name string, of *objFile, parent Pov) (Pov, error) {
+	top := eng.newPov(parent)
+	if top == nil {
+		return nil, fmt.Errorf("LoadObj: invalid parent pov")
+	}
+	mats := map[string]*material{} // shared by all the meshes.
+	for _, mtl := range of.mtls {
+		mat := newMaterial(mtl.Name)
+		kd := &rgb{mtl.KdR, mtl.KdG, mtl.KdB}
+		ka := &rgb{mtl.KaR, mtl.KaG, mtl.KaB}
+		ks := &rgb{mtl.KsR, mtl.KsG, mtl.KsB}
+		mat.setMaterial(kd, ka, ks, mtl.Tr)
+		mats[mtl.Name] = mat
+	}
+	for _, od := range of.objs {
+		p := eng.newPov(top).(*pov)
+		p.SetName(od.Name)
+		for cnt, group := range od.Groups {
+			tex := ""
+			for _, mtl := range of.mtls {
+				if mtl.Name == group.Material {
+					tex = mtl.MapKd
+				}
+			}
+			mp := p
+			if len(od.Groups) > 1 {
+				mp = eng.newPov(p).(*pov)
+			}
+			objModel(mp, fmt.Sprintf("%s:%s%d", name, od.Name, cnt), od, group, mats[group.Material], tex)
+		}
+	}
+	return top, nil
+}
+
+// objModel adds a model for the faces in the given material group.
+// Textures are ignored for meshes without texture coordinates.
+func objModel(p *pov, mesh string, od *load.ObjData, g load.ObjGroup, mat *material, tex string) {
+	v, n, t, f := objGroup(od, g)
+	shader := "phong"
+	if tex != "" && len(t) > 0 {
+		shader = "uv"
+	}
+	m := p.NewModel(shader).(*model)
+	m.NewMesh(mesh)
+	m.msh.initData(0, 3, render.StaticDraw, false).setData(0, v)
+	if len(n) > 0 {
+		m.msh.initData(1, 3, render.StaticDraw, false).setData(1, n)
+	}
+	if len(t) > 0 {
+		m.msh.initData(2, 2, render.StaticDraw, false).setData(2, t)
+	}
+	m.msh.initFaces(render.StaticDraw).setFaces(f)
+	if mat != nil {
+		m.mat = mat
+		m.kd, m.ka, m.ks, m.alpha = mat.kd, mat.ka, mat.ks, mat.tr
+	} else {
+		m.SetColor(1, 1, 1)
+	}
+	if shader == "uv" {
+		m.AddTex(tex)
+	}
+}
+
+// objGroup returns the vertex data used by the faces of one material
+// group. Vertexes are renumbered from 0 for meshes with many groups.
+func objGroup(od *load.ObjData, g load.ObjGroup) (v, n, t []float32, f []uint16) {
+	if g.F0 == 0 && g.Fn == len(od.F) {
+		return od.V, od.N, od.T, od.F // one group uses all the data.
+	}
+	remap := map[uint16]uint16{}
+	for _, index := range od.F[g.F0 : g.F0+g.Fn] {
+		if _, ok := remap[index]; !ok {
+			remap[index] = uint16(len(remap))
+			v = append(v, od.V[index*3:index*3+3]...)
+			if len(od.N) > 0 {
+				n = append(n, od.N[index*3:index*3+3]...)
+			}
+			if len(od.T) > 0 {
+				t = append(t, od.T[index*2:index*2+2]...)
+			}
+		}
+		f = append(f, remap[index])
+	}
+	return v, n, t, f
+}
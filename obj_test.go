@@ -0,0 +1,56 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"testing"
+
+	"github.com/gazed/vu/load"
+)
+
+// Check that each obj material group is a model with its material,
+// where textured materials load their diffuse texture.
+func TestObj(t *testing.T) {
+	eng := newEngine(nil)
+	defer eng.Shutdown()
+	of := &objFile{
+		objs: []*load.ObjData{{
+			Name: "quads",
+			V:    []float32{0, 0, 0, 1, 0, 0, 1, 1, 0, 0, 1, 0},
+			N:    []float32{0, 0, 1, 0, 0, 1, 0, 0, 1, 0, 0, 1},
+			T:    []float32{0, 0, 1, 0, 1, 1, 0, 1},
+			F:    []uint16{0, 1, 2, 0, 2, 3},
+			Groups: []load.ObjGroup{
+				{Material: "wood", F0: 0, Fn: 3},
+				{Material: "none", F0: 3, Fn: 3},
+			},
+		}},
+		mtls: []*load.MtlData{{Name: "wood", KdR: 0.5, Tr: 0.25, MapKd: "wood"}},
+	}
+	top, err := eng.newObj("quads", of, eng.Root())
+	if err != nil {
+		t.Fatal(err)
+	}
+	quads := top.(*pov).children[0]
+	if quads.Name() != "quads" || len(quads.children) != 2 {
+		t.Fatalf("Expected a child for each material, got %s %d", quads.Name(), len(quads.children))
+	}
+	wood := quads.children[0].Model().(*model)
+	if wood.Shader() != "uv" || wood.mat.name != "wood" || wood.kd.R != 0.5 || wood.Alpha() != 0.25 {
+		t.Errorf("Expected textured wood material, got %s %+v", wood.Shader(), wood.mat)
+	}
+	if len(wood.texs) != 1 || wood.texs[0].name != "wood" || wood.msh.name != "quads:quads0" {
+		t.Errorf("Expected wood texture, got %d", len(wood.texs))
+	}
+
+	// groups only keep the vertexes that they use.
+	plain := quads.children[1].Model().(*model)
+	if plain.Shader() != "phong" || plain.mat != nil || plain.kd.R != 1 || len(plain.texs) != 0 {
+		t.Errorf("Expected default white material, got %s %+v", plain.Shader(), plain.kd)
+	}
+	v, _, _, f := objGroup(of.objs[0], of.objs[0].Groups[1])
+	if len(v) != 9 || f[0] != 0 || f[1] != 1 || f[2] != 2 || v[3] != 1 || v[4] != 1 {
+		t.Errorf("Expected renumbered vertexes, got %v %v", v, f)
+	}
+}
@@ -78,11 +78,13 @@ func newParticleEffect(m *model, pe ParticleEffect, maxParticles int) *particleE
 }
 
 // update is called to transform the active particle set into vertex
-// point data that can be sent to the GPU for rendering.
-func (e *particleEffect) update(m *model, dt float64) {
+// point data that can be sent to the GPU for rendering. The effect is
+// given the fraction, from quality settings, of the maximum particles.
+func (e *particleEffect) update(m *model, dt, fraction float64) {
 	// Have the application defined effect return the current
 	// set of particles to be rendered.
-	activeParticles := e.effect(e.particles, dt)
+	limit := int(float64(len(e.particles))*fraction + 0.5)
+	activeParticles := e.effect(e.particles[:limit], dt)
 
 	// Turn the particle positions and data into vertex buffer data.
 	e.pv, e.pd = e.pv[:0], e.pd[:0] // keep previous memory.
@@ -45,6 +45,12 @@ type Body interface {
 	//                 of the two colliding bodies. If one of the bodies has 0
 	//                 bounciness then there is no bounce effect.
 	SetMaterial(mass, bounciness float64) Body
+	Material() (mass, bounciness float64) // Current physical properties.
+
+	// SetLayers limits collisions to bodies where each body has a layer
+	// bit in the others mask. Defaults are layer 1 and all mask bits.
+	SetLayers(layers, mask uint32) Body
+	Layers() (layers, mask uint32) // Current layer and mask bits.
 }
 
 // Body interface
@@ -57,6 +63,7 @@ type body struct {
 	shape Shape   // Body shape for collisions.
 	world *lin.T  // World transform for the given shape.
 	v0    *lin.V3 // Scratch vector.
+	step  uint64  // Last physics step that included this body.
 
 	guess   *lin.T // Predicted world transform for the given shape.
 	movable bool   // Body has mass. It is able to move.
@@ -76,6 +83,8 @@ type body struct {
 	// needed by the solver. It is initialized and consumed by the solver as needed.
 	friction    float64     // Ideally non-zero.
 	restitution float64     // Bounciness. Zero to one expected.
+	layers      uint32      // Collision layer bits.
+	mask        uint32      // Collides with bodies in these layers.
 	sbod        *solverBody // Body related solver data.
 
 	// Scratch variables are optimizations that avoid creating/destroying
@@ -98,8 +107,9 @@ func NewBody(shape Shape) Body { return newBody(shape) }
 func newBody(shape Shape) *body {
 	b := &body{}
 	b.shape = shape
-	b.imass = 0                 // no mass, static body by default
-	b.friction = 0.5            // good to have some friction
+	b.imass = 0      // no mass, static body by default
+	b.friction = 0.5 // good to have some friction
+	b.layers, b.mask = 1, ^uint32(0)
 	b.world = lin.NewT().SetI() // world transform
 	b.guess = lin.NewT().SetI() // predicted world transform
 
@@ -161,6 +171,22 @@ func (b *body) Turn(x, y, z float64) {
 func (b *body) SetMaterial(mass, bounciness float64) Body {
 	return b.setMaterial(mass, bounciness)
 }
+func (b *body) Material() (mass, bounciness float64) {
+	if b.imass != 0 {
+		mass = 1.0 / b.imass
+	}
+	return mass, b.restitution
+}
+func (b *body) Layers() (layers, mask uint32) { return b.layers, b.mask }
+func (b *body) SetLayers(layers, mask uint32) Body {
+	b.layers, b.mask = layers, mask
+	return b
+}
+
+// collides returns true if the layers allow a and b to collide.
+func (b *body) collides(a *body) bool {
+	return b.layers&a.mask != 0 && a.layers&b.mask != 0
+}
 func (b *body) setMaterial(mass, bounciness float64) *body {
 	b.imass = 0 // static unless there is mass.
 	if !lin.AeqZ(mass) {
@@ -288,7 +314,9 @@ func (b *body) worldAabb(ab *Abox) *Abox { return b.shape.Aabb(b.world, ab, 0) }
 
 // predictedAabb updates Abox ab to be the bodies axis-aligned bounding box
 // in the predicted world coordinates.
-func (b *body) predictedAabb(ab *Abox, margin float64) *Abox { return b.shape.Aabb(b.guess, ab, margin) }
+func (b *body) predictedAabb(ab *Abox, margin float64) *Abox {
+	return b.shape.Aabb(b.guess, ab, margin)
+}
 
 // updatePredictedTransform provides a guess where the body would appear using
 // the current linear and angular velocities within the supplied timestep.
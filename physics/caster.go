@@ -24,11 +24,11 @@ type cast func(r, f Body) (hit bool, x, y, z float64)
 
 // rayCastAlgorithms holds the algorithms for the supported shapes that
 // a ray can be checked against.
-//
-// FUTURE: Look into adding support for boxes.
 var rayCastAlgorithms = map[int]cast{
-	PlaneShape:  castRayPlane,
-	SphereShape: castRaySphere,
+	PlaneShape:   castRayPlane,
+	SphereShape:  castRaySphere,
+	BoxShape:     castRayBox,
+	CapsuleShape: castRayCapsule,
 }
 
 // ============================================================================
@@ -90,7 +90,85 @@ func castRaySphere(a, b Body) (hit bool, x, y, z float64) {
 }
 
 // ============================================================================
-//
-// FUTURE:
-// https://truesculpt.googlecode.com/hg-history/Release%25200.8/Doc/ray_box_intersect.pdf
-// http://www.scratchapixel.com/lessons/3d-basic-lessons/lesson-7-intersecting-simple-shapes/ray-box-intersection/
+// ray-box cast: http://www.scratchapixel.com/lessons/3d-basic-lessons/lesson-7-intersecting-simple-shapes/ray-box-intersection/
+
+// castRayBox calculates the point of collision between ray:a and box:b.
+// The ray is moved into box space so the box is axis aligned. The contact
+// point is where the ray enters the box, or the ray origin if the ray
+// starts inside the box.
+func castRayBox(a, b Body) (hit bool, x, y, z float64) {
+	sa, sb := a.Shape().(*ray), b.Shape().(*box)
+	la, tb := a.World().Loc, b.World()
+	rdir := a.(*body).v0.SetS(sa.dx, sa.dy, sa.dz).Unit() // ray direction.
+	ox, oy, oz := tb.InvS(la.X, la.Y, la.Z)               // ray origin in box space.
+	ex, ey, ez := tb.InvS(la.X+rdir.X, la.Y+rdir.Y, la.Z+rdir.Z)
+	near, far := 0.0, math.MaxFloat64
+	slabs := [3][3]float64{{ox, ex - ox, sb.Hx}, {oy, ey - oy, sb.Hy}, {oz, ez - oz, sb.Hz}}
+	for _, s := range slabs {
+		o, d, h := s[0], s[1], s[2]
+		if d == 0 {
+			if o < -h || o > h {
+				return false, 0, 0, 0 // parallel to and outside the slab.
+			}
+			continue
+		}
+		t0, t1 := (-h-o)/d, (h-o)/d
+		if t0 > t1 {
+			t0, t1 = t1, t0
+		}
+		near, far = math.Max(near, t0), math.Min(far, t1)
+		if near > far {
+			return false, 0, 0, 0 // no solutions
+		}
+	}
+	x, y, z = rdir.X*near+la.X, rdir.Y*near+la.Y, rdir.Z*near+la.Z
+	return true, x, y, z
+}
+
+// ============================================================================
+// ray-capsule cast: Real-Time Collision Detection by Christer Ericson. 5.3.7
+
+// castRayCapsule calculates the point of collision between ray:a and
+// capsule:b. The ray is moved into capsule space where the capsule center
+// line is along the Y axis. The contact point is the nearest of where the
+// ray enters the cylinder or the end spheres, or the ray origin if the ray
+// starts inside the capsule.
+func castRayCapsule(a, b Body) (hit bool, x, y, z float64) {
+	sa, sb := a.Shape().(*ray), b.Shape().(*capsule)
+	la, tb := a.World().Loc, b.World()
+	rdir := a.(*body).v0.SetS(sa.dx, sa.dy, sa.dz).Unit() // ray direction.
+	ox, oy, oz := tb.InvS(la.X, la.Y, la.Z)               // ray origin in capsule space.
+	ex, ey, ez := tb.InvS(la.X+rdir.X, la.Y+rdir.Y, la.Z+rdir.Z)
+	dx, dy, dz := ex-ox, ey-oy, ez-oz
+	r2 := sb.R * sb.R
+	if cy := lin.Clamp(oy, -sb.H, sb.H); ox*ox+(oy-cy)*(oy-cy)+oz*oz <= r2 {
+		return true, la.X, la.Y, la.Z // ray starts inside.
+	}
+
+	// Check the cylinder around the center line.
+	near := math.MaxFloat64
+	if a2 := dx*dx + dz*dz; a2 > lin.Epsilon {
+		b2, c2 := ox*dx+oz*dz, ox*ox+oz*oz-r2
+		if disc := b2*b2 - a2*c2; disc >= 0 {
+			if t := (-b2 - math.Sqrt(disc)) / a2; t >= 0 && math.Abs(oy+dy*t) <= sb.H {
+				near = t
+			}
+		}
+	}
+
+	// Check the spheres at either end of the center line.
+	for _, cy := range [2]float64{-sb.H, sb.H} {
+		py := oy - cy
+		b2, c2 := ox*dx+py*dy+oz*dz, ox*ox+py*py+oz*oz-r2
+		if disc := b2*b2 - c2; disc >= 0 {
+			if t := -b2 - math.Sqrt(disc); t >= 0 && t < near {
+				near = t
+			}
+		}
+	}
+	if near == math.MaxFloat64 {
+		return false, 0, 0, 0 // no solutions
+	}
+	x, y, z = rdir.X*near+la.X, rdir.Y*near+la.Y, rdir.Z*near+la.Z
+	return true, x, y, z
+}
@@ -4,6 +4,7 @@
 package physics
 
 import (
+	"math"
 	"testing"
 
 	"github.com/gazed/vu/math/lin"
@@ -42,6 +43,21 @@ func TestCastRaySphere(t *testing.T) {
 	}
 }
 
+func TestCastRayBox(t *testing.T) {
+	r := newBody(NewRay(0, 0, -1)) // ray at origin pointing down -Z
+	b := newBody(NewBox(1, 1, 1))  // box rotated 45 degrees about Y.
+	b.World().SetAa(0, 1, 0, lin.Rad(45))
+	b.World().Loc.SetS(0, 0, -10)
+	hit, x, y, z := castRayBox(r, b)
+	cx, cy, cz := 0.0, 0.0, -8.5857864 // expected contact location.
+	if !hit || !lin.Aeq(x, cx) || !lin.Aeq(y, cy) || !lin.Aeq(z, cz) {
+		t.Errorf("%t Expected ray-box hit at %2.7f %2.7f %2.7f, got %2.7f %2.7f %2.7f", hit, cx, cy, cz, x, y, z)
+	}
+	if hit, _, _, _ = castRayBox(newBody(NewRay(0, 0, 1)), b); hit {
+		t.Errorf("Expected ray pointing away from the box to miss")
+	}
+}
+
 func TestCastRotatedRaySphere(t *testing.T) {
 	r := newBody(NewRay(0, 0.70710678, -0.70710678)) // ray at origin pointing down +Y -Z
 	r.World().Loc.SetS(0, 0, 20)                     // move ray origin +20 on Z axis.
@@ -53,3 +69,24 @@ func TestCastRotatedRaySphere(t *testing.T) {
 		t.Errorf("%t Expected ray-plane hit at %2.7f %2.7f %2.7f, got %2.7f %2.7f %2.7f", hit, cx, cy, cz, x, y, z)
 	}
 }
+
+func TestCastRayCapsule(t *testing.T) {
+	r := newBody(NewRay(0, 0, -1))   // ray pointing down -Z.
+	c := newBody(NewCapsule(0.5, 1)) // capsule along Y.
+	c.World().Loc.SetS(0, 0, -10)
+	if hit, x, y, z := castRayCapsule(r, c); !hit || !lin.Aeq(x, 0) || !lin.Aeq(y, 0) || !lin.Aeq(z, -9.5) {
+		t.Errorf("%t Expected ray-capsule side hit at 0 0 -9.5, got %f %f %f", hit, x, y, z)
+	}
+	r.World().Loc.SetS(0, 1.25, 0) // hit the upper end sphere.
+	if hit, _, _, z := castRayCapsule(r, c); !hit || !lin.Aeq(z, -10+math.Sqrt(0.25-0.0625)) {
+		t.Errorf("%t Expected ray-capsule end hit, got %f", hit, z)
+	}
+	r.World().Loc.SetS(0, 1.6, 0) // miss above.
+	if hit, _, _, _ := castRayCapsule(r, c); hit {
+		t.Error("Expected ray to miss capsule")
+	}
+	r.World().Loc.SetS(0, 0, -10) // start inside.
+	if hit, _, _, z := castRayCapsule(r, c); !hit || z != -10 {
+		t.Errorf("%t Expected ray origin inside capsule, got %f", hit, z)
+	}
+}
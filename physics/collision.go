@@ -38,6 +38,11 @@ func newCollider() *collider {
 	c.algorithms[SphereShape][BoxShape] = collideSphereBox
 	c.algorithms[BoxShape][SphereShape] = collideBoxSphere
 	c.algorithms[BoxShape][BoxShape] = collideBoxBox
+	c.algorithms[CapsuleShape][CapsuleShape] = collideCapsuleCapsule
+	c.algorithms[CapsuleShape][SphereShape] = collideCapsuleCapsule
+	c.algorithms[SphereShape][CapsuleShape] = collideCapsuleCapsule
+	c.algorithms[CapsuleShape][BoxShape] = collideCapsuleBox
+	c.algorithms[BoxShape][CapsuleShape] = collideBoxCapsule
 	return c
 }
 
@@ -93,16 +98,32 @@ func collideSphereBox(a, b Body, c []*pointOfContact) (i, j Body, k []*pointOfCo
 	aa, bb := a.(*body), b.(*body)
 	sphere, box := aa.shape.(*sphere), bb.shape.(*box)
 	scenter := aa.World().Loc
-	sradius := sphere.R
+
+	// Convert sphere's world to the box's local.
+	sx, sy, sz := bb.World().InvS(scenter.X, scenter.Y, scenter.Z)
+	px, py, pz, nx, ny, nz, depth, hit := sphereBoxContact(box, sx, sy, sz, sphere.R)
+	if !hit {
+		return a, b, c[0:0]
+	}
+
+	// Apply the box world transform to get back to world space.
+	c0 := c[0]
+	c0.point.SetS(bb.World().AppS(px+nx*margin, py+ny*margin, pz+nz*margin))
+	c0.normal.SetS(bb.World().AppR(nx, ny, nz)) // only need rotation.
+	c0.depth = depth
+	return a, b, c[0:1]
+}
+
+// sphereBoxContact finds the closest box point and box space normal for
+// a sphere with center sx, sy, sz in box space. Returns false if the
+// sphere is not within the contact breaking threshold of the box.
+func sphereBoxContact(box *box, sx, sy, sz, sradius float64) (px, py, pz, nx, ny, nz, depth float64, hit bool) {
 	maxContactDistance := 0.1 // contact breaking threshold
 	boxMargin := margin
-
-	// Get the box local half extents. Convert sphere's world to the box's local.
 	hx, hy, hz := box.Hx, box.Hy, box.Hz
-	sx, sy, sz := bb.World().InvS(scenter.X, scenter.Y, scenter.Z)
 
 	// Determine the closest box vertex to the sphere center.
-	px, py, pz := sx, sy, sz
+	px, py, pz = sx, sy, sz
 	px = math.Min(hx, px)
 	px = math.Max(-hx, px)
 	py = math.Min(hy, py)
@@ -114,12 +135,12 @@ func collideSphereBox(a, b Body, c []*pointOfContact) (i, j Body, k []*pointOfCo
 	// (when the box center is outside the sphere)
 	intersectionDist := sradius + boxMargin
 	contactDist := intersectionDist + maxContactDistance
-	nx, ny, nz := sx-px, sy-py, sz-pz
+	nx, ny, nz = sx-px, sy-py, sz-pz
 
 	// No penetration means no collision.
 	dsqrd := nx*nx + ny*ny + nz*nz
 	if dsqrd > contactDist*contactDist {
-		return a, b, c[0:0]
+		return px, py, pz, nx, ny, nz, 0, false
 	}
 
 	// Collision occurred, figure out the collision details.
@@ -132,13 +153,7 @@ func collideSphereBox(a, b Body, c []*pointOfContact) (i, j Body, k []*pointOfCo
 		distance = math.Sqrt(dsqrd)
 		nx, ny, nz = nx/distance, ny/distance, nz/distance
 	}
-
-	// Apply the box world transform to get back to world space.
-	c0 := c[0]
-	c0.point.SetS(bb.World().AppS(px+nx*boxMargin, py+ny*boxMargin, pz+nz*boxMargin))
-	c0.normal.SetS(bb.World().AppR(nx, ny, nz)) // only need rotation.
-	c0.depth = distance - intersectionDist
-	return a, b, c[0:1]
+	return px, py, pz, nx, ny, nz, distance - intersectionDist, true
 }
 
 // sphereBoxPenetration calculates the closest point and normal when the sphere center
@@ -192,6 +207,144 @@ func collideBoxSphere(a, b Body, c []*pointOfContact) (i, j Body, k []*pointOfCo
 
 // sphere-box collision
 // ============================================================================
+// capsule collision
+
+// collideCapsuleCapsule handles capsules and spheres, treating a sphere as
+// a capsule with a zero length center line. The closest points between the
+// center lines give 0 or 1 contact points.
+func collideCapsuleCapsule(a, b Body, c []*pointOfContact) (i, j Body, k []*pointOfContact) {
+	aa, bb := a.(*body), b.(*body)
+	p1, q1, ra := segment(aa)
+	p2, q2, rb := segment(bb)
+	s, t := closestSegments(&p1, &q1, &p2, &q2)
+	var pa, pb lin.V3
+	pa.Lerp(&p1, &q1, s) // closest point on A's center line.
+	pb.Lerp(&p2, &q2, t) // closest point on B's center line.
+	dx, dy, dz := pa.X-pb.X, pa.Y-pb.Y, pa.Z-pb.Z
+	separation := math.Sqrt(dx*dx + dy*dy + dz*dz)
+	if separation > ra+rb {
+		return a, b, c[0:0] // no contact.
+	}
+	c0 := c[0]
+	c0.depth = separation - (ra + rb) // how much overlap
+	c0.normal.SetS(1, 0, 0)           // center lines touch.
+	if separation > lin.Epsilon {
+		c0.normal.SetS(dx/separation, dy/separation, dz/separation)
+	}
+	c0.point.Scale(c0.normal, rb) // point of contact on capsule B.
+	c0.point.Add(&pb, c0.point)
+	return a, b, c[0:1]
+}
+
+// collideCapsuleBox treats the ends of the capsule, and the point on the
+// capsule center line closest to the box, as spheres colliding with the
+// box. It returns 0 to 3 points so that capsules can rest on boxes.
+func collideCapsuleBox(a, b Body, c []*pointOfContact) (i, j Body, k []*pointOfContact) {
+	aa, bb := a.(*body), b.(*body)
+	box, bw := bb.shape.(*box), bb.World()
+	p, q, radius := segment(aa)
+	px, py, pz := bw.InvS(p.X, p.Y, p.Z) // center line in box space.
+	qx, qy, qz := bw.InvS(q.X, q.Y, q.Z)
+	dx, dy, dz := qx-px, qy-py, qz-pz
+
+	// The distance from the center line to the box is convex so a ternary
+	// search finds the center line point closest to the box.
+	dist := func(t float64) float64 {
+		x, y, z := px+dx*t, py+dy*t, pz+dz*t
+		cx := x - math.Max(-box.Hx, math.Min(box.Hx, x))
+		cy := y - math.Max(-box.Hy, math.Min(box.Hy, y))
+		cz := z - math.Max(-box.Hz, math.Min(box.Hz, z))
+		return cx*cx + cy*cy + cz*cz
+	}
+	lo, hi := 0.0, 1.0
+	for cnt := 0; cnt < 32; cnt++ {
+		t0, t1 := lo+(hi-lo)/3, hi-(hi-lo)/3
+		if dist(t0) < dist(t1) {
+			hi = t1
+		} else {
+			lo = t0
+		}
+	}
+	closest := (lo + hi) * 0.5
+	checks := [3]float64{0, 1, closest}
+	numChecks := 2 // the end points.
+	if dc := dist(closest); dc < dist(0)-lin.Epsilon && dc < dist(1)-lin.Epsilon {
+		numChecks = 3 // the middle is closer than both end points.
+	}
+	contacts := 0
+	for _, t := range checks[:numChecks] {
+		sx, sy, sz := px+dx*t, py+dy*t, pz+dz*t
+		bx, by, bz, nx, ny, nz, depth, hit := sphereBoxContact(box, sx, sy, sz, radius)
+		if hit {
+			poc := c[contacts]
+			poc.point.SetS(bw.AppS(bx+nx*margin, by+ny*margin, bz+nz*margin))
+			poc.normal.SetS(bw.AppR(nx, ny, nz)) // only need rotation.
+			poc.depth = depth
+			contacts++
+		}
+	}
+	return a, b, c[0:contacts]
+}
+
+// collideBoxCapsule reverses the collision to be CapsuleBox.
+func collideBoxCapsule(a, b Body, c []*pointOfContact) (i, j Body, k []*pointOfContact) {
+	return collideCapsuleBox(b, a, c)
+}
+
+// segment returns the world space end points of the center line of a
+// capsule body, or the center of a sphere body as a zero length line.
+func segment(b *body) (p, q lin.V3, radius float64) {
+	loc := b.world.Loc
+	switch sh := b.shape.(type) {
+	case *capsule:
+		ux, uy, uz := lin.MultSQ(0, sh.H, 0, b.world.Rot)
+		p.SetS(loc.X-ux, loc.Y-uy, loc.Z-uz)
+		q.SetS(loc.X+ux, loc.Y+uy, loc.Z+uz)
+		return p, q, sh.R
+	case *sphere:
+		p.Set(loc)
+		q.Set(loc)
+		return p, q, sh.R
+	}
+	return p, q, 0
+}
+
+// closestSegments returns the fractions s and t along the line segments
+// p1-q1 and p2-q2 of the closest points between the two segments.
+//
+// Based on Real-Time Collision Detection by Christer Ericson. Section 5.1.9
+func closestSegments(p1, q1, p2, q2 *lin.V3) (s, t float64) {
+	var d1, d2, r lin.V3
+	d1.Sub(q1, p1)
+	d2.Sub(q2, p2)
+	r.Sub(p1, p2)
+	a, e, f := d1.Dot(&d1), d2.Dot(&d2), d2.Dot(&r)
+	switch {
+	case a <= lin.Epsilon && e <= lin.Epsilon:
+		return 0, 0 // both segments are points.
+	case a <= lin.Epsilon:
+		return 0, lin.Clamp(f/e, 0, 1) // first segment is a point.
+	}
+	c := d1.Dot(&r)
+	if e <= lin.Epsilon {
+		return lin.Clamp(-c/a, 0, 1), 0 // second segment is a point.
+	}
+	b := d1.Dot(&d2)
+	if denom := a*e - b*b; denom > lin.Epsilon {
+		s = lin.Clamp((b*f-c*e)/denom, 0, 1) // not parallel.
+	}
+	t = (b*s + f) / e
+	switch {
+	case t < 0:
+		return lin.Clamp(-c/a, 0, 1), 0
+	case t > 1:
+		return lin.Clamp((b-c)/a, 0, 1), 1
+	}
+	return s, t
+}
+
+// capsule collision
+// ============================================================================
 // box-box collision
 
 // collideBoxBox uses the Separating Axis Test to check for overlap. If there
@@ -84,6 +84,65 @@ func TestCollideBoxSphere(t *testing.T) {
 	}
 }
 
+func TestCollideCapsuleCapsule(t *testing.T) {
+	a, b, cons := NewBody(NewCapsule(0.5, 1)), NewBody(NewCapsule(0.5, 1)), newManifold()
+	a.World().Loc.SetS(0.9, 0.5, 0)
+	if _, _, cs := collideCapsuleCapsule(a, b, cons); len(cs) != 1 || !lin.Aeq(cs[0].depth, -0.1) ||
+		dumpV3(cs[0].point) != "{0.5 -0.5 0.0}" || dumpV3(cs[0].normal) != "{1.0 0.0 0.0}" {
+		t.Errorf("Side by side capsules should touch %f %s %s", cs[0].depth, dumpV3(cs[0].point), dumpV3(cs[0].normal))
+	}
+
+	// crossed capsules, and capsule ends.
+	a.World().SetAa(0, 0, 1, lin.Rad(90)).Loc.SetS(0, 0, 0.9)
+	if _, _, cs := collideCapsuleCapsule(a, b, cons); len(cs) != 1 || !lin.Aeq(cs[0].depth, -0.1) ||
+		dumpV3(cs[0].normal) != "{0.0 0.0 1.0}" {
+		t.Errorf("Crossed capsules should touch %f %s", cs[0].depth, dumpV3(cs[0].normal))
+	}
+	a.World().SetI().Loc.SetS(0, 3.1, 0)
+	if _, _, cs := collideCapsuleCapsule(a, b, cons); len(cs) != 0 {
+		t.Error("Capsules end to end should not touch")
+	}
+}
+
+func TestCollideCapsuleSphere(t *testing.T) {
+	c, cons := newCollider(), newManifold()
+	capsule, sphere := newBody(NewCapsule(0.5, 1)), newBody(NewSphere(1))
+	sphere.World().Loc.SetS(0, 2.25, 0)
+	algorithm := c.algorithms[sphere.shape.Type()][capsule.shape.Type()]
+	if _, _, cs := algorithm(sphere, capsule, cons); len(cs) != 1 || !lin.Aeq(cs[0].depth, -0.25) ||
+		dumpV3(cs[0].point) != "{0.0 1.5 0.0}" || dumpV3(cs[0].normal) != "{0.0 1.0 0.0}" {
+		t.Errorf("Sphere should touch capsule end %f %s %s", cs[0].depth, dumpV3(cs[0].point), dumpV3(cs[0].normal))
+	}
+	sphere.World().Loc.SetS(1.6, 0, 0)
+	if _, _, cs := algorithm(sphere, capsule, cons); len(cs) != 0 {
+		t.Error("Sphere should not touch capsule side")
+	}
+}
+
+func TestCollideCapsuleBox(t *testing.T) {
+	c, cons := newCollider(), newManifold()
+	capsule, box := newBody(NewCapsule(0.5, 1)), newBody(NewBox(2, 1, 2))
+	capsule.World().SetAa(0, 0, 1, lin.Rad(90)).Loc.SetS(0, 1.5, 0) // lying on the box.
+	algorithm := c.algorithms[box.shape.Type()][capsule.shape.Type()]
+	i, j, cs := algorithm(box, capsule, cons)
+	if i.Shape().Type() != CapsuleShape || j.Shape().Type() != BoxShape {
+		t.Error("Should have flipped the objects into Capsule, Box")
+	}
+	if len(cs) != 2 || !lin.Aeq(cs[0].depth, -margin) || dumpV3(cs[0].normal) != "{0.0 1.0 0.0}" {
+		t.Errorf("Capsule should rest on box at both ends %d %f %s", len(cs), cs[0].depth, dumpV3(cs[0].normal))
+	}
+
+	// standing across the edge of the box touches in the middle.
+	capsule.World().SetAa(0, 0, 1, lin.Rad(45)).Loc.SetS(2.3, 1.3, 0)
+	if _, _, cs := collideCapsuleBox(capsule, box, cons); len(cs) != 1 || cs[0].depth >= 0 {
+		t.Errorf("Capsule should touch box edge %d", len(cs))
+	}
+	capsule.World().SetI().Loc.SetS(0, 2.7, 0)
+	if _, _, cs := collideCapsuleBox(capsule, box, cons); len(cs) != 0 {
+		t.Error("Capsule should be above the box")
+	}
+}
+
 func TestCollideBoxBox(t *testing.T) {
 	a, b, cons := NewBody(NewBox(0.5, 0.5, 0.5)), NewBody(NewBox(1, 1, 1)), newManifold()
 	if _, _, cs := collideBoxBox(a, b, cons); len(cs) == 0 || cs[0].depth != -1.58 ||
@@ -9,6 +9,23 @@ import (
 	"github.com/gazed/vu/math/lin"
 )
 
+// Contact describes the touching points between two bodies after the
+// last Physics.Step. It is intended for game effects, like footstep sounds
+// or damage, that need to know where and how hard bodies are colliding.
+type Contact struct {
+	A, B   Body           // Contacting bodies.
+	Points []ContactPoint // Up to 4 points of contact.
+}
+
+// ContactPoint is one point of contact between two bodies.
+// Contact points and normals are in world coordinates.
+type ContactPoint struct {
+	X, Y, Z    float64 // Point of contact on body B.
+	Nx, Ny, Nz float64 // Unit contact normal on body B.
+	Depth      float64 // Penetration depth. Negative is overlapping.
+	Impulse    float64 // Impulse applied by the solver to separate the bodies.
+}
+
 // contactPair contains information about two bodies that are close or
 // contacting. The bodies may be overlapping (pre-solver) or in resting contact
 // (post-solver). Contacts are created, if necessary, during broad phase,
@@ -19,6 +36,7 @@ type contactPair struct {
 	pid   uint64            // Unique pair identifier.
 	pocs  []*pointOfContact // The current points of contact.
 	valid bool              // Broadphase check for deleted bodies.
+	touch bool              // Narrowphase found contacts last step.
 
 	// The following fields are used only by the solver.
 	processingLimit float64 // Bodies outside this range are ignored.
@@ -47,6 +65,18 @@ func newContactPair(bodyA, bodyB *body) *contactPair {
 	return con
 }
 
+// contact copies the current points of contact into c,
+// reusing the memory in c where possible.
+func (con *contactPair) contact(c *Contact) {
+	c.A, c.B = con.bodyA, con.bodyB
+	c.Points = c.Points[:0]
+	for _, poc := range con.pocs {
+		p, n := poc.point, poc.normal
+		c.Points = append(c.Points, ContactPoint{X: p.X, Y: p.Y, Z: p.Z,
+			Nx: n.X, Ny: n.Y, Nz: n.Z, Depth: poc.depth, Impulse: poc.sp.warmImpulse})
+	}
+}
+
 // refreshContacts updates the solver information for existing points.
 // Any changes to the world transforms are applied to the existing points
 // and invalid points are discarded.
@@ -0,0 +1,117 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package physics
+
+import (
+	"math"
+
+	"github.com/gazed/vu/math/lin"
+)
+
+// Joint holds two bodies together at a shared anchor point, ie: the
+// shoulder between an upper arm and a torso. A joint starts as a ball
+// and socket that allows any rotation. Limits are added by making the
+// joint a cone or a hinge. Joints are solved along with the contacts
+// after they are added with Physics.Join.
+type Joint interface {
+	Bodies() (a, b Body) // The jointed bodies.
+
+	// SetCone limits the joint axis of body b to within swing radians
+	// of the joint axis of body a, and limits the twist about the joint
+	// axis to within twist radians. Negative values remove a limit.
+	SetCone(swing, twist float64) Joint
+
+	// SetHinge limits rotation to the world axis ax, ay, az, based
+	// on the current body locations. The rotation about the axis is
+	// limited to between lo and hi radians, ie: elbows and knees.
+	// The hinge axis replaces the joint axis.
+	SetHinge(ax, ay, az, lo, hi float64) Joint
+}
+
+// Joint kinds.
+const (
+	ballJoint  = iota // Any rotation.
+	coneJoint         // Limited swing and twist.
+	hingeJoint        // Single axis rotation.
+)
+
+// joint is the default implementation of the Joint interface.
+type joint struct {
+	a, b   *body               // Jointed bodies.
+	kind   int                 // ballJoint, coneJoint, hingeJoint.
+	pa, pb *lin.V3             // Anchor point in each body's local space.
+	ta, tb *lin.V3             // Joint axis in each body's local space.
+	ua, ub *lin.V3             // Perpendicular to the joint axis for twists.
+	swing  float64             // Cone swing limit, negative for none.
+	twist  float64             // Cone twist limit, negative for none.
+	lo, hi float64             // Hinge rotation limits.
+	rows   []*solverConstraint // Reused solver constraints.
+
+	// Scratch world space axes recalculated for each step.
+	wta, wtb, wua, wub *lin.V3
+}
+
+// NewJoint creates a joint between bodies a and b at the world anchor
+// point x, y, z. The world joint axis ax, ay, az is the center of cone
+// limits. The anchor and axis are fixed to each body using the current
+// body locations, so position the bodies before creating the joint.
+func NewJoint(a, b Body, x, y, z, ax, ay, az float64) Joint {
+	j := &joint{a: a.(*body), b: b.(*body), swing: -1, twist: -1}
+	j.pa, j.pb = lin.NewV3(), lin.NewV3()
+	j.ta, j.tb = lin.NewV3(), lin.NewV3()
+	j.ua, j.ub = lin.NewV3(), lin.NewV3()
+	j.wta, j.wtb = lin.NewV3(), lin.NewV3()
+	j.wua, j.wub = lin.NewV3(), lin.NewV3()
+	for cnt := 0; cnt < 6; cnt++ {
+		j.rows = append(j.rows, newSolverConstraint())
+	}
+	j.pa.SetS(j.a.World().InvS(x, y, z))
+	j.pb.SetS(j.b.World().InvS(x, y, z))
+	j.setAxis(ax, ay, az)
+	return j
+}
+
+// Joint interface implementation.
+func (j *joint) Bodies() (a, b Body) { return j.a, j.b }
+func (j *joint) SetCone(swing, twist float64) Joint {
+	j.kind, j.swing, j.twist = coneJoint, swing, twist
+	return j
+}
+func (j *joint) SetHinge(ax, ay, az, lo, hi float64) Joint {
+	j.kind, j.lo, j.hi = hingeJoint, lo, hi
+	j.setAxis(ax, ay, az)
+	return j
+}
+
+// setAxis fixes the world joint axis, and a perpendicular reference
+// direction for measuring twists, to each body.
+func (j *joint) setAxis(ax, ay, az float64) {
+	axis := j.wta.SetS(ax, ay, az)
+	if axis.AeqZ() {
+		axis.SetS(0, 1, 0)
+	}
+	axis.Unit()
+	perp := j.wua.SetS(1, 0, 0)
+	if math.Abs(axis.X) > 0.9 {
+		perp.SetS(0, 1, 0)
+	}
+	perp.Cross(axis, perp).Unit()
+	inv := &lin.Q{}
+	inv.Inv(j.a.World().Rot)
+	j.ta.MultQ(axis, inv)
+	j.ua.MultQ(perp, inv)
+	inv.Inv(j.b.World().Rot)
+	j.tb.MultQ(axis, inv)
+	j.ub.MultQ(perp, inv)
+}
+
+// twistAngle returns the signed angle about the world joint axis from
+// the body a reference direction to the body b reference direction.
+func (j *joint) twistAngle() float64 {
+	t, ua, ub := j.wta, j.wua, j.wub
+	d := t.Dot(ub)
+	px, py, pz := ub.X-t.X*d, ub.Y-t.Y*d, ub.Z-t.Z*d // ub on the twist plane.
+	cx, cy, cz := ua.Y*pz-ua.Z*py, ua.Z*px-ua.X*pz, ua.X*py-ua.Y*px
+	return math.Atan2(t.X*cx+t.Y*cy+t.Z*cz, ua.X*px+ua.Y*py+ua.Z*pz)
+}
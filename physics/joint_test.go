@@ -0,0 +1,96 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package physics
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gazed/vu/math/lin"
+)
+
+// Check that a swinging limb stays attached to a fixed anchor.
+func TestJointPendulum(t *testing.T) {
+	px := newPhysics()
+	anchor := newBody(NewSphere(0.5)).SetMaterial(0, 0)
+	anchor.World().Loc.SetS(0, 10, 0)
+	limb := newBody(NewCapsule(0.25, 1)).SetMaterial(1, 0)
+	limb.World().Loc.SetS(1.5, 10, 0)
+	limb.World().Rot.SetAa(0, 0, 1, 90) // lying along x.
+	px.Join(NewJoint(anchor, limb, 0, 10, 0, 1, 0, 0))
+	bodies := []Body{anchor, limb}
+	lowest := 10.0
+	for cnt := 0; cnt < 150; cnt++ {
+		px.Step(bodies, 0.02)
+		at := limb.World().Loc
+		lowest = math.Min(lowest, at.Y)
+		if d := at.Dist(anchor.World().Loc); math.Abs(d-1.5) > 0.1 {
+			t.Fatalf("Step %d limb moved off the anchor %f", cnt, d)
+		}
+	}
+	if lowest > 8.6 {
+		t.Errorf("Expected limb to swing down, lowest %f", lowest)
+	}
+}
+
+// Check that a cone joint limits the swing away from the joint axis.
+func TestJointCone(t *testing.T) {
+	px := newPhysics()
+	px.SetGravity(0)
+	anchor := newBody(NewBox(0.5, 0.5, 0.5)).SetMaterial(0, 0)
+	limb := newBody(NewCapsule(0.25, 1)).SetMaterial(1, 0)
+	limb.World().Loc.SetS(0, -1.5, 0)
+	px.Join(NewJoint(anchor, limb, 0, -0.5, 0, 0, -1, 0).SetCone(0.3, -1))
+	bodies := []Body{anchor, limb}
+	axis := &lin.V3{}
+	for cnt := 0; cnt < 100; cnt++ {
+		limb.Push(2, 0, 0)
+		px.Step(bodies, 0.02)
+	}
+	axis.MultQ(&lin.V3{X: 0, Y: -1, Z: 0}, limb.World().Rot)
+	if swing := math.Acos(-axis.Y); swing > 0.4 {
+		t.Errorf("Expected swing near 0.3, got %f", swing)
+	}
+}
+
+// Check that a hinge joint only turns about the hinge axis, within limits.
+func TestJointHinge(t *testing.T) {
+	px := newPhysics()
+	px.SetGravity(0)
+	anchor := newBody(NewBox(0.5, 0.5, 0.5)).SetMaterial(0, 0)
+	limb := newBody(NewCapsule(0.25, 1)).SetMaterial(1, 0)
+	limb.World().Loc.SetS(0, -1.5, 0)
+	px.Join(NewJoint(anchor, limb, 0, -0.5, 0, 0, -1, 0).SetHinge(0, 0, 1, -0.5, 0.5))
+	bodies := []Body{anchor, limb}
+	axis := &lin.V3{}
+	for cnt := 0; cnt < 100; cnt++ {
+		limb.Push(2, 0, 2)
+		px.Step(bodies, 0.02)
+	}
+	axis.MultQ(&lin.V3{X: 0, Y: -1, Z: 0}, limb.World().Rot)
+	if math.Abs(axis.Z) > 0.05 {
+		t.Errorf("Expected no rotation off the hinge axis, got %s", dumpV3(axis))
+	}
+	if bend := math.Atan2(axis.X, -axis.Y); bend < 0.4 || bend > 0.6 {
+		t.Errorf("Expected bend near 0.5, got %f", bend)
+	}
+}
+
+// Check that jointed bodies are not checked for collisions.
+func TestJointNoCollide(t *testing.T) {
+	px := newPhysics()
+	a := newBody(NewSphere(1)).SetMaterial(1, 0)
+	b := newBody(NewSphere(1)).SetMaterial(1, 0)
+	b.World().Loc.SetS(1, 0, 0)
+	j := NewJoint(a, b, 0.5, 0, 0, 1, 0, 0)
+	px.Join(j)
+	px.Join(j) // ignored.
+	if px.broadphase([]Body{a, b}, px.overlapped); len(px.overlapped) != 0 {
+		t.Errorf("Expected no overlaps for jointed bodies")
+	}
+	px.Unjoin(j)
+	if px.broadphase([]Body{a, b}, px.overlapped); len(px.overlapped) != 1 || len(px.joints) != 0 {
+		t.Errorf("Expected overlap once the joint is removed")
+	}
+}
@@ -29,6 +29,9 @@
 // Bodies are created using NewBody(shape). For example:
 //    box    := NewBody(NewBox(hx, hy, hz))
 //    sphere := NewBody(NewSphere(radius))
+//    limb   := NewBody(NewCapsule(radius, halfHeight))
+//
+// Bodies can be held together with joints, see NewJoint and Physics.Join.
 //
 // Creating and storing bodies is the responsibility of the calling application.
 // Bodies are moved with frequent and regular calls to Physics.Step().
@@ -39,6 +42,10 @@
 // Package physics is provided as part of the vu (virtual universe) 3D engine.
 package physics
 
+import (
+	"sort"
+)
+
 // See the open source physics engines:
 //     www.bulletphysics.com
 //     www.ode.org
@@ -67,6 +74,26 @@ type Physics interface {
 	// the current physics simulation. Bodies positions and velocities
 	// are not updated. Provided for occasional or one-off checks.
 	Collide(a, b Body) bool
+
+	// Contacts returns the contacts found during the last Step. The memory
+	// in the given contacts slice is reused where possible. Only contacts
+	// involving body b are returned, or all contacts if b is nil.
+	Contacts(b Body, contacts []Contact) []Contact
+
+	// Join adds a joint that holds two bodies together. Joints are solved
+	// in the order they were added, each Step that includes one of the
+	// jointed bodies. A jointed body that is not part of the Step is held
+	// in place. Jointed bodies do not collide with each other.
+	Join(j Joint)
+	Unjoin(j Joint) // Remove a joint.
+
+	// Save returns the dynamic simulation state of the given bodies
+	// and their contacts. Restore expects the same bodies, in the same
+	// order, and returns an error if the data does not match the bodies.
+	// There is no sleep state in this physics package and joints keep
+	// no state between steps, so joints are not saved.
+	Save(bodies []Body) []byte
+	Restore(bodies []Body, data []byte) error
 }
 
 // Physics interface
@@ -81,11 +108,15 @@ type physics struct {
 	col        *collider               // Checks for collisions, updates collision contacts.
 	sol        *solver                 // Resolves collisions, updates bodies locations.
 	overlapped map[uint64]*contactPair // Overlapping pairs. Updated during broadphase.
+	joints     []*joint                // Joints in the order they were added.
+	jointed    map[uint64]int          // Joint counts by body pair identifier.
+	step       uint64                  // Step count marks the stepped bodies.
 
 	// scratch variables keep memory so that temp variables
 	// don't have to be continually allocated and garbage collected
 	abA, abB *Abox             // Scratch broadphase axis aligned bounding boxes.
 	mf0      []*pointOfContact // Scratch narrowphase manifold.
+	active   []*joint          // Scratch joints with stepped bodies.
 }
 
 // NewPhysics creates and returns a mover instance. Generally expected
@@ -97,6 +128,7 @@ func newPhysics() *physics {
 	px.col = newCollider()
 	px.sol = newSolver()
 	px.overlapped = map[uint64]*contactPair{}
+	px.jointed = map[uint64]int{}
 	px.mf0 = newManifold()
 	px.abA = &Abox{}
 	px.abB = &Abox{}
@@ -116,30 +148,89 @@ var maxFriction = 10.0
 func (px *physics) Step(bodies []Body, timestep float64) {
 
 	// apply forces (e.g. gravity) to bodies and predict body locations
+	px.step++
 	px.predictBodyLocations(bodies, timestep)
 
 	// update overlapped pairs
+	var colliding map[uint32]*body
 	px.broadphase(bodies, px.overlapped)
 	if len(px.overlapped) > 0 {
 
 		// collide overlapped pairs
-		if colliding := px.narrowphase(px.overlapped); len(colliding) > 0 {
-			px.sol.info.timestep = timestep
+		colliding = px.narrowphase(px.overlapped)
+	}
 
-			// resolve all colliding pairs
-			px.sol.solve(colliding, px.overlapped)
+	// resolve all colliding pairs and joints.
+	px.active = px.active[:0]
+	for _, j := range px.joints {
+		if j.a.step == px.step || j.b.step == px.step {
+			px.active = append(px.active, j)
 		}
 	}
+	if len(colliding) > 0 || len(px.active) > 0 {
+		if colliding == nil {
+			colliding = map[uint32]*body{}
+		}
+		px.sol.info.timestep = timestep
+		px.sol.solve(colliding, px.overlapped, px.active, px.step)
+	}
 
 	// adjust body locations based on velocities
 	px.updateBodyLocations(bodies, timestep)
 	px.clearForces(bodies)
 }
 
+// Contacts implements Physics. The contacts are returned in pair
+// identifier order so that the results are consistent between calls.
+func (px *physics) Contacts(b Body, contacts []Contact) []Contact {
+	pids := pairIDs{}
+	for pid, pair := range px.overlapped {
+		if pair.touch && len(pair.pocs) > 0 && (b == nil || b == pair.bodyA || b == pair.bodyB) {
+			pids = append(pids, pid)
+		}
+	}
+	sort.Sort(pids)
+	if cap(contacts) < len(pids) {
+		contacts = make([]Contact, len(pids))
+	}
+	contacts = contacts[:len(pids)]
+	for cnt, pid := range pids {
+		px.overlapped[pid].contact(&contacts[cnt])
+	}
+	return contacts
+}
+
 // Physics interface implementation.
 func (px *physics) SetGravity(gravity float64)        { px.gravity = gravity }
 func (px *physics) SetMargin(collisionMargin float64) { margin = collisionMargin }
 
+// Join implements Physics. Joining the same joint twice is ignored.
+func (px *physics) Join(j Joint) {
+	jj := j.(*joint)
+	for _, existing := range px.joints {
+		if existing == jj {
+			return
+		}
+	}
+	px.joints = append(px.joints, jj)
+	px.jointed[jj.a.pairID(jj.b)]++
+}
+
+// Unjoin implements Physics.
+func (px *physics) Unjoin(j Joint) {
+	jj := j.(*joint)
+	for index, existing := range px.joints {
+		if existing == jj {
+			px.joints = append(px.joints[:index], px.joints[index+1:]...)
+			pid := jj.a.pairID(jj.b)
+			if px.jointed[pid]--; px.jointed[pid] <= 0 {
+				delete(px.jointed, pid)
+			}
+			return
+		}
+	}
+}
+
 // predictBodyLocations applies motion to moving/awake bodies as if there
 // was nothing else around.
 //
@@ -148,6 +239,7 @@ func (px *physics) predictBodyLocations(bodies []Body, dt float64) {
 	var b *body
 	for _, bb := range bodies {
 		b = bb.(*body)
+		b.step = px.step
 		b.guess.Set(b.world)
 		if b.movable {
 
@@ -179,12 +271,10 @@ func (px *physics) broadphase(bodies []Body, pairs map[uint64]*contactPair) {
 		for _, B2 := range uniques {
 			bodyB = B2.(*body)
 
-			// FUTURE: Add masking feature that allows bodies to only collide
-			//         with other bodies that have matching mask types.
-
-			// check as long as one of the bodies can move.
-			if bodyA.movable || bodyB.movable {
-				pairID = bodyA.pairID(bodyB)
+			// check as long as one of the bodies can move, the bodies
+			// layers allow them to collide, and they are not jointed.
+			pairID = bodyA.pairID(bodyB)
+			if (bodyA.movable || bodyB.movable) && bodyA.collides(bodyB) && px.jointed[pairID] == 0 {
 				pair, existing := pairs[pairID]
 				if existing {
 					pair.valid = true
@@ -235,7 +325,8 @@ func (px *physics) narrowphase(pairs map[uint64]*contactPair) (colliding map[uin
 
 		// bodies are colliding if there are contact points in the manifold.
 		// Update any contact points and prepare for the solver.
-		if len(manifold) > 0 {
+		cpair.touch = len(manifold) > 0
+		if cpair.touch {
 			colliding[bodyA.bid] = bodyA
 			colliding[bodyB.bid] = bodyB
 			cpair.refreshContacts(bodyA.world, bodyB.world)
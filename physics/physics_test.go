@@ -5,6 +5,7 @@ package physics
 
 import (
 	"fmt"
+	"math"
 	"testing"
 
 	"github.com/gazed/vu/math/lin"
@@ -24,6 +25,19 @@ func TestBroadphaseUniqueCompare(t *testing.T) {
 	}
 }
 
+// Check that bodies without matching layers are not compared.
+func TestBroadphaseLayers(t *testing.T) {
+	px, sp := newPhysics(), NewSphere(1)
+	a, b, c := newBody(sp), newBody(sp), newBody(sp)
+	a.SetMaterial(1, 0).SetLayers(1, 1)
+	b.SetMaterial(1, 0).SetLayers(2, ^uint32(0)) // not in a's mask.
+	c.SetMaterial(1, 0).SetLayers(1, 2)          // collides with b.
+	px.broadphase([]Body{a, b, c}, px.overlapped)
+	if len(px.overlapped) != 1 || px.overlapped[b.pairID(c)] == nil {
+		t.Errorf("Expected only the b, c pair. Got %d", len(px.overlapped))
+	}
+}
+
 // Basic test to check that a sphere will end up above a slab.
 // The test uses no restitution (bounciness).
 func TestSphereAt(t *testing.T) {
@@ -58,6 +72,66 @@ func TestCollide(t *testing.T) {
 	}
 }
 
+// Check that contacts are reported for a ball resting on a slab.
+func TestContacts(t *testing.T) {
+	px := newPhysics()
+	slab := newBody(NewBox(100, 25, 100)).SetMaterial(0, 0)
+	slab.World().Loc.SetS(0, -25, 0)
+	ball := newBody(NewSphere(1)).SetMaterial(1, 0)
+	ball.World().Loc.SetS(0, 1.5, 0)
+	other := newBody(NewSphere(1)).SetMaterial(1, 0)
+	other.World().Loc.SetS(10, 20, 0) // away from everything.
+	bodies := []Body{slab, ball, other}
+	for cnt := 0; cnt < 50; cnt++ {
+		px.Step(bodies, 0.02)
+	}
+	contacts := px.Contacts(nil, nil)
+	if len(contacts) != 1 || len(contacts[0].Points) == 0 {
+		t.Fatalf("Expected 1 contact with points, got %d", len(contacts))
+	}
+	if c := contacts[0]; !(c.A == ball && c.B == slab) && !(c.A == slab && c.B == ball) {
+		t.Errorf("Expected ball and slab contact")
+	}
+	for _, p := range contacts[0].Points {
+		if p.Impulse <= 0 || math.Abs(p.Y) > 0.1 || math.Abs(p.Ny) != 1 {
+			t.Errorf("Unexpected contact point %+v", p)
+		}
+	}
+	if contacts = px.Contacts(other, contacts); len(contacts) != 0 {
+		t.Errorf("Expected no contacts for other, got %d", len(contacts))
+	}
+}
+
+// Check that the same bodies and steps give identical results.
+func TestStepRepeatable(t *testing.T) {
+	pile := func() []float64 {
+		px := newPhysics()
+		slab := newBody(NewBox(100, 25, 100)).SetMaterial(0, 0)
+		slab.World().Loc.SetS(0, -25, 0)
+		bodies := []Body{slab}
+		for cnt := 0; cnt < 8; cnt++ {
+			ball := newBody(NewSphere(1)).SetMaterial(1, 0.5)
+			ball.World().Loc.SetS(float64(cnt%3)*1.5, 2+float64(cnt)*1.8, float64(cnt%2)*0.5)
+			bodies = append(bodies, ball)
+		}
+		for cnt := 0; cnt < 120; cnt++ {
+			px.Step(bodies, 0.02)
+		}
+		at := []float64{}
+		for _, b := range bodies {
+			l := b.World().Loc
+			at = append(at, l.X, l.Y, l.Z)
+		}
+		return at
+	}
+	want := pile()
+	for run := 0; run < 5; run++ {
+		if got := pile(); fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Fatalf("Run %d differs:\n%v\n%v", run, want, got)
+		}
+	}
+}
+
 // Testing
 // ============================================================================
 // Utility functions for all package testcases.
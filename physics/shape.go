@@ -38,15 +38,34 @@ type Shape interface {
 const (
 	SphereShape  = iota // Considered convex (curving outwards).
 	BoxShape            // Polyhedral (flat faces, straight edges). Convex.
+	CapsuleShape        // Sphere swept along a line. Convex.
 	VolumeShapes        // Separates shapes with volume from those without.
 	PlaneShape          // Area, no volume or mass.
 	RayShape            // Points on a line, no area, volume or mass.
 	NumShapes           // Keep this last.
 )
 
+// Dimensions returns the values used to create the given shape:
+// the box half extents, the sphere radius as x, the capsule radius
+// and half height as x, y, the plane normal, or the ray direction.
+func Dimensions(s Shape) (x, y, z float64) {
+	switch sh := s.(type) {
+	case *box:
+		return sh.Hx, sh.Hy, sh.Hz
+	case *sphere:
+		return sh.R, 0, 0
+	case *capsule:
+		return sh.R, sh.H, 0
+	case *plane:
+		return sh.nx, sh.ny, sh.nz
+	case *ray:
+		return sh.dx, sh.dy, sh.dz
+	}
+	return 0, 0, 0
+}
+
 // Currently the shapes are so simple they are all kept in this one file.
 // Future shapes get crazy complex. For example:
-//    FUTURE: Capsule
 //    FUTURE: Cylinder
 //    FUTURE: Cone
 //    FUTURE: Multi sphere
@@ -145,6 +164,52 @@ func (s *sphere) Inertia(mass float64, inertia *lin.V3) *lin.V3 {
 
 // sphere
 // ============================================================================
+// capsule shape
+
+// capsule is a collision shape primitive that is a sphere swept along a
+// line segment. The segment is centered at the origin along the Y axis.
+// Capsules are often used for characters and the limbs of ragdolls.
+type capsule struct {
+	R float64 // Radius.
+	H float64 // Half height of the center line, not including the end caps.
+}
+
+// NewCapsule creates a Capsule shape along the Y axis. Negative values are
+// turned positive. The capsule height is 2*(halfHeight+radius).
+func NewCapsule(radius, halfHeight float64) Shape {
+	return &capsule{math.Abs(radius), math.Abs(halfHeight)}
+}
+
+// Implements Shape.Type
+func (c *capsule) Type() int { return CapsuleShape }
+
+// Implements Shape.Aabb
+// The box surrounds the spheres at either end of the rotated center line.
+func (c *capsule) Aabb(t *lin.T, ab *Abox, margin float64) *Abox {
+	ux, uy, uz := lin.MultSQ(0, c.H, 0, t.Rot)
+	sides := c.R + margin
+	ex, ey, ez := math.Abs(ux)+sides, math.Abs(uy)+sides, math.Abs(uz)+sides
+	ab.Sx, ab.Sy, ab.Sz = t.Loc.X-ex, t.Loc.Y-ey, t.Loc.Z-ez
+	ab.Lx, ab.Ly, ab.Lz = t.Loc.X+ex, t.Loc.Y+ey, t.Loc.Z+ez
+	return ab
+}
+
+// Implements Shape.Volume
+func (c *capsule) Volume() float64 {
+	return math.Pi*c.R*c.R*2*c.H + 4.0/3.0*math.Pi*c.R*c.R*c.R
+}
+
+// Implements Shape.Inertia
+// Based on bullet physics btCapsuleShape::calculateLocalInertia which
+// uses the inertia of the surrounding box.
+func (c *capsule) Inertia(mass float64, inertia *lin.V3) *lin.V3 {
+	lx2, ly2 := 4.0*c.R*c.R, 4.0*(c.R+c.H)*(c.R+c.H)
+	inertia.SetS(mass/12.0*(ly2+lx2), mass/12.0*(lx2+lx2), mass/12.0*(lx2+ly2))
+	return inertia
+}
+
+// capsule
+// ============================================================================
 // Abox
 
 // Abox is an axis aligned bounding box used with the Shape interface.
@@ -4,6 +4,7 @@
 package physics
 
 import (
+	"math"
 	"testing"
 
 	"github.com/gazed/vu/math/lin"
@@ -67,6 +68,42 @@ func TestSphereInertia(t *testing.T) {
 	}
 }
 
+func TestCapsule(t *testing.T) {
+	cp := Shape(NewCapsule(0.5, 1)) // compiler checks Shape interface.
+	if cp.Type() != CapsuleShape {
+		t.Error("Invalid capsule shape")
+	}
+	if c := cp.(*capsule); c.R != 0.5 || c.H != 1 {
+		t.Errorf("Expected capsule dimensions 0.5 1, got %f %f", c.R, c.H)
+	}
+}
+
+func TestCapsuleAabb(t *testing.T) {
+	cp := Shape(NewCapsule(0.5, 1))
+	ab := cp.Aabb(lin.NewT().SetI(), &Abox{}, 0)
+	if ab.Sx != -0.5 || ab.Sy != -1.5 || ab.Sz != -0.5 || ab.Lx != 0.5 || ab.Ly != 1.5 || ab.Lz != 0.5 {
+		t.Error("Invalid bounding box for Capsule")
+	}
+	ab = cp.Aabb(lin.NewT().SetAa(0, 0, 1, lin.Rad(90)), &Abox{}, 0)
+	if !lin.Aeq(ab.Sx, -1.5) || !lin.Aeq(ab.Sy, -0.5) || !lin.Aeq(ab.Lx, 1.5) || !lin.Aeq(ab.Ly, 0.5) {
+		t.Errorf("Invalid bounding box for rotated Capsule %+v", ab)
+	}
+}
+
+func TestCapsuleVolume(t *testing.T) {
+	cp := Shape(NewCapsule(1, 1))
+	if want := 2*math.Pi + 4.0/3.0*math.Pi; !lin.Aeq(cp.Volume(), want) {
+		t.Errorf("Expected capsule volume %f, got %f", want, cp.Volume())
+	}
+}
+
+func TestCapsuleInertia(t *testing.T) {
+	cp, inertia, want := Shape(NewCapsule(0.5, 1)), lin.NewV3(), "{0.8 0.2 0.8}"
+	if cp.Inertia(1, inertia); dumpV3(inertia) != want {
+		t.Errorf("Expected capsule inertia %s, got %s", want, dumpV3(inertia))
+	}
+}
+
 func TestAboxOverlap(t *testing.T) {
 	var a, b, c, d *Abox
 	a, b = &Abox{0, 0, 0, 1, 1, 1}, &Abox{-1, -1, -1, 0, 0, 0}
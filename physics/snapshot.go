@@ -0,0 +1,178 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package physics
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+
+	"github.com/gazed/vu/math/lin"
+)
+
+// Snapshots capture the dynamic simulation state so that it can be restored
+// later for quick-saves, rewinds, or rollback networking. A snapshot holds:
+//    o each bodies world transform, velocities, and pending forces.
+//    o the persistent contact pairs and their warm start impulses.
+// Bodies are identified by their position in the bodies slice. This means
+// Restore must be given the same bodies, in the same order, that were given
+// to Save. Shapes and materials are not saved since they are not changed
+// by the simulation.
+
+// snapshotMagic identifies, and versions, snapshot data.
+const snapshotMagic uint32 = 0x76757031 // "vup1"
+
+// Save implements Physics.
+func (px *physics) Save(bodies []Body) []byte {
+	buf := &bytes.Buffer{}
+	index := map[uint32]uint32{} // body id to bodies index.
+	binary.Write(buf, binary.LittleEndian, snapshotMagic)
+	binary.Write(buf, binary.LittleEndian, uint32(len(bodies)))
+	for cnt, bb := range bodies {
+		b := bb.(*body)
+		index[b.bid] = uint32(cnt)
+		writeT(buf, b.world)
+		writeV3(buf, b.lvel, b.avel, b.lfor, b.afor)
+	}
+
+	// Save the contact pairs in a consistent order.
+	pids := pairIDs{}
+	for pid, pair := range px.overlapped {
+		_, okA := index[pair.bodyA.bid]
+		_, okB := index[pair.bodyB.bid]
+		if okA && okB {
+			pids = append(pids, pid)
+		}
+	}
+	sort.Sort(pids)
+	binary.Write(buf, binary.LittleEndian, uint32(len(pids)))
+	for _, pid := range pids {
+		pair := px.overlapped[pid]
+		binary.Write(buf, binary.LittleEndian, index[pair.bodyA.bid])
+		binary.Write(buf, binary.LittleEndian, index[pair.bodyB.bid])
+		binary.Write(buf, binary.LittleEndian, uint32(len(pair.pocs)))
+		for _, poc := range pair.pocs {
+			sp := poc.sp
+			writeV3(buf, poc.point, poc.normal)
+			writeV3(buf, sp.localA, sp.localB, sp.worldA, sp.worldB)
+			writeV3(buf, sp.normalWorldB, sp.lateralFrictionDir)
+			binary.Write(buf, binary.LittleEndian, []float64{poc.depth,
+				sp.distance, sp.combinedFriction, sp.combinedRestitution, sp.warmImpulse})
+		}
+	}
+	return buf.Bytes()
+}
+
+// Restore implements Physics. The bodies are only updated
+// if the whole snapshot can be read. Only the contact pairs between
+// the given bodies are replaced. Other contact pairs are kept.
+func (px *physics) Restore(bodies []Body, data []byte) (err error) {
+	buf := bytes.NewReader(data)
+	var magic, nbodies uint32
+	if err = binary.Read(buf, binary.LittleEndian, &magic); err != nil || magic != snapshotMagic {
+		return fmt.Errorf("physics.Restore: invalid snapshot")
+	}
+	if err = binary.Read(buf, binary.LittleEndian, &nbodies); err != nil {
+		return fmt.Errorf("physics.Restore: %s", err)
+	}
+	if int(nbodies) != len(bodies) {
+		return fmt.Errorf("physics.Restore: expected %d bodies, got %d", nbodies, len(bodies))
+	}
+
+	// read everything into temporary storage before updating any body.
+	bstate := make([]float64, nbodies*19) // 7 transform, 4*3 vectors.
+	if err = binary.Read(buf, binary.LittleEndian, bstate); err != nil {
+		return fmt.Errorf("physics.Restore: %s", err)
+	}
+	var npairs uint32
+	if err = binary.Read(buf, binary.LittleEndian, &npairs); err != nil {
+		return fmt.Errorf("physics.Restore: %s", err)
+	}
+	pairs := map[uint64]*contactPair{}
+	for cnt := uint32(0); cnt < npairs; cnt++ {
+		var ia, ib, npocs uint32
+		binary.Read(buf, binary.LittleEndian, &ia)
+		binary.Read(buf, binary.LittleEndian, &ib)
+		if err = binary.Read(buf, binary.LittleEndian, &npocs); err != nil {
+			return fmt.Errorf("physics.Restore: %s", err)
+		}
+		if ia >= nbodies || ib >= nbodies || npocs > 4 {
+			return fmt.Errorf("physics.Restore: invalid contact pair")
+		}
+		pair := newContactPair(bodies[ia].(*body), bodies[ib].(*body))
+		pair.valid = true
+		pair.pocs = pair.pocs[:npocs]
+		for _, poc := range pair.pocs {
+			vals := make([]float64, 8*3+5)
+			if err = binary.Read(buf, binary.LittleEndian, vals); err != nil {
+				return fmt.Errorf("physics.Restore: %s", err)
+			}
+			sp := poc.sp
+			vals = readV3(vals, poc.point, poc.normal)
+			vals = readV3(vals, sp.localA, sp.localB, sp.worldA, sp.worldB)
+			vals = readV3(vals, sp.normalWorldB, sp.lateralFrictionDir)
+			poc.depth, sp.distance = vals[0], vals[1]
+			sp.combinedFriction, sp.combinedRestitution = vals[2], vals[3]
+			sp.warmImpulse = vals[4]
+		}
+		pairs[pair.pid] = pair
+	}
+
+	// the snapshot was valid, update the bodies and contacts.
+	restored := map[uint32]bool{}
+	for _, bb := range bodies {
+		restored[bb.(*body).bid] = true
+	}
+	for pid, pair := range px.overlapped {
+		if restored[pair.bodyA.bid] && restored[pair.bodyB.bid] {
+			delete(px.overlapped, pid)
+		}
+	}
+	for pid, pair := range pairs {
+		px.overlapped[pid] = pair
+	}
+	for cnt, bb := range bodies {
+		b := bb.(*body)
+		vals := bstate[cnt*19 : (cnt+1)*19]
+		b.world.Loc.SetS(vals[0], vals[1], vals[2])
+		b.world.Rot.SetS(vals[3], vals[4], vals[5], vals[6])
+		readV3(vals[7:], b.lvel, b.avel, b.lfor, b.afor)
+		b.guess.Set(b.world)
+		if b.movable {
+			b.updateInertiaTensor()
+		}
+	}
+	return nil
+}
+
+// writeT appends the location and rotation of transform t.
+func writeT(buf *bytes.Buffer, t *lin.T) {
+	l, r := t.Loc, t.Rot
+	binary.Write(buf, binary.LittleEndian, []float64{l.X, l.Y, l.Z, r.X, r.Y, r.Z, r.W})
+}
+
+// writeV3 appends the values of each of the given vectors.
+func writeV3(buf *bytes.Buffer, vs ...*lin.V3) {
+	for _, v := range vs {
+		binary.Write(buf, binary.LittleEndian, []float64{v.X, v.Y, v.Z})
+	}
+}
+
+// readV3 sets each of the given vectors from vals, returning
+// the values that were not consumed.
+func readV3(vals []float64, vs ...*lin.V3) []float64 {
+	for _, v := range vs {
+		v.SetS(vals[0], vals[1], vals[2])
+		vals = vals[3:]
+	}
+	return vals
+}
+
+// pairIDs sorts contact pair identifiers.
+type pairIDs []uint64
+
+func (p pairIDs) Len() int           { return len(p) }
+func (p pairIDs) Less(i, j int) bool { return p[i] < p[j] }
+func (p pairIDs) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
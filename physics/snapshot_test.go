@@ -0,0 +1,91 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package physics
+
+import (
+	"testing"
+)
+
+// Check that stepping from a restored snapshot gives the
+// same results as stepping from the saved state.
+func TestSaveRestore(t *testing.T) {
+	px := newPhysics()
+	slab := newBody(NewBox(100, 25, 100)).SetMaterial(0, 0)
+	slab.World().Loc.SetS(0, -25, 0)
+	ball := newBody(NewSphere(1)).SetMaterial(1, 0.5)
+	ball.World().Loc.SetS(-5, 3, -3)
+	ball.Push(2, 0, 1)
+	bodies := []Body{slab, ball}
+	for cnt := 0; cnt < 50; cnt++ {
+		px.Step(bodies, 0.02) // get the ball into contact.
+	}
+	data := px.Save(bodies)
+	for cnt := 0; cnt < 20; cnt++ {
+		px.Step(bodies, 0.02)
+	}
+	want := dumpT(ball.World())
+
+	// restore into a new simulation and new bodies.
+	px = newPhysics()
+	slab = newBody(NewBox(100, 25, 100)).SetMaterial(0, 0)
+	ball = newBody(NewSphere(1)).SetMaterial(1, 0.5)
+	bodies = []Body{slab, ball}
+	if err := px.Restore(bodies, data); err != nil {
+		t.Fatalf("Unexpected restore error %s", err)
+	}
+	if len(px.overlapped) != 1 {
+		t.Errorf("Expected 1 restored contact, got %d", len(px.overlapped))
+	}
+	for cnt := 0; cnt < 20; cnt++ {
+		px.Step(bodies, 0.02)
+	}
+	if got := dumpT(ball.World()); got != want {
+		t.Errorf("Expected ball at %s, got %s", want, got)
+	}
+}
+
+// Check that mismatched snapshots are rejected
+// without changing the bodies.
+func TestRestoreErrors(t *testing.T) {
+	px := newPhysics()
+	ball := newBody(NewSphere(1)).SetMaterial(1, 0)
+	ball.World().Loc.SetS(1, 2, 3)
+	data := px.Save([]Body{ball})
+	if err := px.Restore([]Body{ball, ball}, data); err == nil {
+		t.Errorf("Expected body count error")
+	}
+	ball.World().Loc.SetS(0, 0, 0)
+	if err := px.Restore([]Body{ball}, data[:len(data)-8]); err == nil {
+		t.Errorf("Expected short data error")
+	}
+	if x, y, z := ball.World().Loc.GetS(); x != 0 || y != 0 || z != 0 {
+		t.Errorf("Expected unchanged body")
+	}
+	if err := px.Restore([]Body{ball}, []byte("garbage")); err == nil {
+		t.Errorf("Expected invalid data error")
+	}
+	if err := px.Restore([]Body{ball}, []byte{0x31}); err == nil {
+		t.Errorf("Expected short header error")
+	}
+}
+
+// Check that restoring some bodies keeps the contacts of other bodies.
+func TestRestoreSome(t *testing.T) {
+	px := newPhysics()
+	a := newBody(NewSphere(1)).SetMaterial(1, 0)
+	b := newBody(NewSphere(1)).SetMaterial(1, 0)
+	data := px.Save([]Body{a, b}) // no contacts.
+	c := newBody(NewSphere(1)).SetMaterial(1, 0)
+	bodies := []Body{a, b, c}
+	px.broadphase(bodies, px.overlapped)
+	if len(px.overlapped) != 3 {
+		t.Fatalf("Expected 3 overlapping pairs, got %d", len(px.overlapped))
+	}
+	if err := px.Restore([]Body{a, b}, data); err != nil {
+		t.Fatalf("Unexpected restore error %s", err)
+	}
+	if len(px.overlapped) != 2 || px.overlapped[a.(*body).pairID(b.(*body))] != nil {
+		t.Errorf("Expected only the pairs with c, got %d", len(px.overlapped))
+	}
+}
@@ -24,6 +24,7 @@ package physics
 import (
 	"log"
 	"math"
+	"sort"
 
 	"github.com/gazed/vu/math/lin"
 )
@@ -46,11 +47,13 @@ type solver struct {
 	info   *solverInfo         // Constants for the solver.
 	constC []*solverConstraint // Contact related equations.
 	constF []*solverConstraint // Friction related equations.
+	constJ []*solverConstraint // Joint related equations.
 
 	// scratch variables are optimizations that avoid creating/destroying
 	// temporary objects that are needed each timestep.
 	v0, v1, v2 *lin.V3 // scratch vectors.
 	ra, rb     *lin.V3 // scratch relative positions for converting contacts.
+	pids       pairIDs // scratch contact pair ids in solving order.
 }
 
 // newSolver creates the necessary space for the solver to work.
@@ -60,6 +63,7 @@ func newSolver() *solver {
 	sol.info = newSolverInfo()
 	sol.constC = []*solverConstraint{}
 	sol.constF = []*solverConstraint{}
+	sol.constJ = []*solverConstraint{}
 	sol.v0 = lin.NewV3()
 	sol.v1 = lin.NewV3()
 	sol.v2 = lin.NewV3()
@@ -69,10 +73,16 @@ func newSolver() *solver {
 }
 
 // solve is expected to be called each physics update. It creates constraints
-// based on contact points and then solves the constraints by adjusting bodies
-// velocities to satisfy the constraints.
-func (sol *solver) solve(bodies map[uint32]*body, contactPairs map[uint64]*contactPair) {
+// based on contact points and joints and then solves the constraints by
+// adjusting bodies velocities to satisfy the constraints. Jointed bodies
+// are added to the colliding bodies. Jointed bodies that were not part
+// of the given step are treated as fixed.
+func (sol *solver) solve(bodies map[uint32]*body, contactPairs map[uint64]*contactPair, joints []*joint, step uint64) {
 	sol.setupConstraints(bodies, contactPairs)
+	sol.constJ = sol.constJ[0:0]
+	for _, j := range joints {
+		sol.convertJoint(j, bodies, step, sol.info)
+	}
 	sol.solveIterations(sol.info)
 	sol.finish(bodies, sol.info)
 }
@@ -102,9 +112,16 @@ func (sol *solver) setupConstraints(bodies map[uint32]*body, contactPairs map[ui
 	sol.constC = sol.constC[0:0]
 	sol.constF = sol.constF[0:0]
 
-	// Generate the solver constraints for each contact pair.
-	for _, contactPair := range contactPairs {
-		sol.convertContacts(contactPair, sol.info)
+	// Generate the solver constraints for each contact pair in pair
+	// identifier order. The constraints are solved sequentially so the
+	// order must not depend on map iteration for repeatable results.
+	sol.pids = sol.pids[:0]
+	for pid := range contactPairs {
+		sol.pids = append(sol.pids, pid)
+	}
+	sort.Sort(sol.pids)
+	for _, pid := range sol.pids {
+		sol.convertContacts(contactPairs[pid], sol.info)
 	}
 }
 
@@ -155,6 +172,154 @@ func (sol *solver) convertContacts(pair *contactPair, info *solverInfo) {
 	}
 }
 
+// convertJoint generates the solver constraints that keep the anchor points
+// of the jointed bodies together and the bodies within the joint limits.
+func (sol *solver) convertJoint(j *joint, bodies map[uint32]*body, step uint64, info *solverInfo) {
+	sbodA, sbodB := jointSolverBody(j.a, bodies, step), jointSolverBody(j.b, bodies, step)
+	if sbodA.oBody == nil && sbodB.oBody == nil {
+		return // nothing can move.
+	}
+	worldA, worldB := j.a.world, j.b.world
+	rows := j.rows[:0]
+
+	// Three linear rows hold the anchor points together.
+	{ // scratch v0, v1, ra, rb
+		relPosA := sol.ra.MultQ(j.pa, worldA.Rot)
+		relPosB := sol.rb.MultQ(j.pb, worldB.Rot)
+		diff := sol.v1.Add(worldA.Loc, relPosA).Sub(sol.v1, worldB.Loc).Sub(sol.v1, relPosB)
+		for axis, dist := range [3]float64{diff.X, diff.Y, diff.Z} {
+			n := sol.v0.SetS(0, 0, 0)
+			switch axis {
+			case 0:
+				n.X = 1
+			case 1:
+				n.Y = 1
+			default:
+				n.Z = 1
+			}
+			sc := j.rows[len(rows)]
+			sol.setupJointConstraint(sc, sbodA, sbodB, n, relPosA, relPosB, dist, -1e10, info)
+			rows = append(rows, sc)
+		}
+	} // scratch v0, v1, ra, rb free
+
+	// Angular rows keep the rotations within the joint limits.
+	ta, tb := j.wta.MultQ(j.ta, worldA.Rot), j.wtb.MultQ(j.tb, worldB.Rot)
+	j.wua.MultQ(j.ua, worldA.Rot)
+	j.wub.MultQ(j.ub, worldB.Rot)
+	switch j.kind {
+	case coneJoint:
+		{ // scratch v0
+			cross := sol.v0.Cross(ta, tb)
+			sin := cross.Len()
+			if swing := math.Atan2(sin, ta.Dot(tb)); j.swing >= 0 && swing > j.swing && sin > lin.Epsilon {
+				sc := j.rows[len(rows)]
+				sol.setupJointConstraint(sc, sbodA, sbodB, cross.Scale(cross, 1/sin), nil, nil, j.swing-swing, 0, info)
+				rows = append(rows, sc)
+			}
+		} // scratch v0 free
+		if j.twist >= 0 {
+			rows = sol.limitJoint(j, sbodA, sbodB, -j.twist, j.twist, rows, info)
+		}
+	case hingeJoint:
+		{ // scratch v0, v1, v2
+			p, q := sol.v0, sol.v1
+			ta.Plane(p, q)
+			misaligned := sol.v2.Cross(ta, tb)
+			sc := j.rows[len(rows)]
+			sol.setupJointConstraint(sc, sbodA, sbodB, p, nil, nil, -misaligned.Dot(p), -1e10, info)
+			rows = append(rows, sc)
+			sc = j.rows[len(rows)]
+			sol.setupJointConstraint(sc, sbodA, sbodB, q, nil, nil, -misaligned.Dot(q), -1e10, info)
+			rows = append(rows, sc)
+		} // scratch v0, v1, v2 free
+		rows = sol.limitJoint(j, sbodA, sbodB, j.lo, j.hi, rows, info)
+	}
+	sol.constJ = append(sol.constJ, rows...)
+}
+
+// limitJoint adds a row when the twist about the joint axis is beyond
+// the lo or hi limit. The updated rows are returned.
+func (sol *solver) limitJoint(j *joint, sbodA, sbodB *solverBody, lo, hi float64,
+	rows []*solverConstraint, info *solverInfo) []*solverConstraint {
+	twist := j.twistAngle()
+	switch {
+	case twist > hi:
+		sc := j.rows[len(rows)]
+		sol.setupJointConstraint(sc, sbodA, sbodB, j.wta, nil, nil, hi-twist, 0, info)
+		return append(rows, sc)
+	case twist < lo:
+		sc := j.rows[len(rows)]
+		{ // scratch v0
+			sol.setupJointConstraint(sc, sbodA, sbodB, sol.v0.Neg(j.wta), nil, nil, twist-lo, 0, info)
+		} // scratch v0 free
+		return append(rows, sc)
+	}
+	return rows
+}
+
+// jointSolverBody returns the solver body for a jointed body, initializing
+// and adding it to the solved bodies if necessary. Bodies that were not
+// part of the step use the fixed solver body.
+func jointSolverBody(b *body, bodies map[uint32]*body, step uint64) *solverBody {
+	if b.step != step || !b.movable {
+		return fixedSolverBody()
+	}
+	if _, ok := bodies[b.bid]; !ok {
+		b.initSolverBody()
+		bodies[b.bid] = b
+	}
+	return b.sbod
+}
+
+// setupJointConstraint initializes a joint constraint along the linear
+// axis n for the anchor points at relPosA, relPosB from the body centers.
+// A nil relPosA gives an angular constraint about axis n. The constraint
+// removes the error, dist, over time. Use a lower limit of 0 for a
+// constraint that only acts when dist is negative.
+func (sol *solver) setupJointConstraint(sc *solverConstraint, sbodA, sbodB *solverBody,
+	n, relPosA, relPosB *lin.V3, dist, lowerLimit float64, info *solverInfo) {
+	bodyA, bodyB := sbodA.oBody, sbodB.oBody // either may be nil if body is fixed.
+	sc.sbodA, sc.sbodB = sbodA, sbodB
+	sc.oPoint, sc.frictionIndex = nil, nil
+	if relPosA != nil {
+		sc.normal.Set(n)
+		sc.relpos1CrossNormal.Cross(relPosA, n)
+		sc.relpos2CrossNormal.Cross(relPosB, n)
+		sc.relpos2CrossNormal.Neg(sc.relpos2CrossNormal)
+	} else {
+		sc.normal.SetS(0, 0, 0)
+		sc.relpos1CrossNormal.Set(n)
+		sc.relpos2CrossNormal.Neg(n)
+	}
+	denom, vel := 0.0, 0.0
+	sc.angularComponentA.SetS(0, 0, 0)
+	if bodyA != nil {
+		sc.angularComponentA.MultMv(bodyA.iitw, sc.relpos1CrossNormal)
+		denom += bodyA.imass*sc.normal.LenSqr() + sc.relpos1CrossNormal.Dot(sc.angularComponentA)
+		vel += sc.normal.Dot(sbodA.linearVelocity) + sc.relpos1CrossNormal.Dot(sbodA.angularVelocity)
+	}
+	sc.angularComponentB.SetS(0, 0, 0)
+	if bodyB != nil {
+		sc.angularComponentB.MultMv(bodyB.iitw, sc.relpos2CrossNormal)
+		denom += bodyB.imass*sc.normal.LenSqr() + sc.relpos2CrossNormal.Dot(sc.angularComponentB)
+		vel += sc.relpos2CrossNormal.Dot(sbodB.angularVelocity) - sc.normal.Dot(sbodB.linearVelocity)
+	}
+	sc.jacDiagABInv = 0
+	if denom > lin.Epsilon {
+		sc.jacDiagABInv = 1 / denom
+	}
+
+	// Joints have no warm start so that there is no state between steps.
+	velocityError := -dist*info.erp/info.timestep - vel
+	sc.rhs = velocityError * sc.jacDiagABInv
+	sc.rhsPenetration = 0
+	sc.appliedImpulse, sc.appliedPushImpulse = 0, 0
+	sc.cfm = 0
+	sc.lowerLimit = lowerLimit
+	sc.upperLimit = 1e10
+}
+
 // setupContactConstraint initializes contact based constraints.
 // Expected to be called on solver setup for each contact point.
 func (sol *solver) setupContactConstraint(sc *solverConstraint, sbodA, sbodB *solverBody,
@@ -342,6 +507,9 @@ func (sol *solver) solveIterations(info *solverInfo) {
 // solverBody deltaVelocity values that better match all the constraints.
 func (sol *solver) solveSingleIteration(iteration int, info *solverInfo) {
 	if iteration < info.numIterations {
+		for _, sc := range sol.constJ {
+			sol.resolveSingleConstraint(sc.sbodA, sc.sbodB, sc, true)
+		}
 		for _, sc := range sol.constC {
 			sol.resolveSingleConstraint(sc.sbodA, sc.sbodB, sc, true)
 		}
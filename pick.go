@@ -0,0 +1,172 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/gazed/vu/math/lin"
+	"github.com/gazed/vu/render"
+)
+
+// Picking finds the Pov whose Model is drawn at a window pixel without
+// needing physics bodies, ie: for selecting things in an editor.
+//     if p := eng.Pick(in.Mx, in.My); p != nil {
+//         selected = p
+//     }
+// Pick renders the Models drawn by the last update, each in a unique
+// color, to a one pixel offscreen buffer using a projection that
+// stretches the picked pixel over the buffer. The color of the pixel
+// identifies the Pov. Only the cameras with the highest viewport order
+// containing the pixel are picked from. Models are picked using their
+// mesh shape so shader effects, like animation and billboards, are not
+// included. Pick waits for the render and is expected to be called as
+// needed, ie: on a mouse click, rather than every update.
+
+// picker renders pick colors to find the Pov under the mouse.
+type picker struct {
+	shd   *shader       // Pick color shader.
+	fbo   *layer        // One pixel render target.
+	frame []render.Draw // Pick draw calls.
+	povs  []*pov        // Pov for each pick color less one.
+	cpm   *lin.M4       // Camera projection with the pick projection.
+	pm    *lin.M4       // Scratch pick projection.
+	mv    *lin.M4       // Scratch model-view matrix.
+	mvp   *lin.M4       // Scratch model-view-projection matrix.
+}
+
+// newPicker creates the pick shader and render target.
+func newPicker(eng *engine) (*picker, error) {
+	pk := &picker{cpm: &lin.M4{}, pm: &lin.M4{}, mv: &lin.M4{}, mvp: &lin.M4{}}
+	shd, err := eng.loader.loadShader(newShader("pick"))
+	if err != nil {
+		return nil, fmt.Errorf("pick shader: %s", err)
+	}
+	pk.shd = shd
+	pk.fbo = newLayer(render.ImageBuffer)
+	pk.fbo.size = 1
+	if err := eng.loader.bindLayer(pk.fbo); err != nil {
+		return nil, fmt.Errorf("pick layer: %s", err)
+	}
+	return pk, nil
+}
+
+// Implement Eng interface. The pick shader and render target are
+// created the first time Pick is called.
+func (eng *engine) Pick(mx, my int) Pov {
+	if eng.machine == nil {
+		return nil
+	}
+	if eng.picks == nil {
+		pk, err := newPicker(eng)
+		if err != nil {
+			log.Printf("eng.Pick: %s", err)
+			return nil
+		}
+		eng.picks = pk
+	}
+	pk := eng.picks
+	if pk.build(eng, mx, my); len(pk.frame) == 0 {
+		return nil
+	}
+	reply := make(chan uint32)
+	eng.machine <- &pickFrame{frame: pk.frame, fbo: pk.fbo.bid, reply: reply}
+	if id := int(<-reply); id > 0 && id <= len(pk.povs) {
+		if p := pk.povs[id-1]; eng.povs[p.eid] == p {
+			return p
+		}
+	}
+	return nil
+}
+
+// build creates the pick draw calls for the Pov's rendered
+// by the last update.
+func (pk *picker) build(eng *engine, mx, my int) {
+	for cnt := range pk.povs {
+		pk.povs[cnt] = nil // release for garbage collection.
+	}
+	pk.frame, pk.povs = pk.frame[:0], pk.povs[:0]
+
+	// only pick from the top cameras containing the pixel.
+	top, found := 0, false
+	for _, p := range eng.scene.scene {
+		if cam, ok := eng.cams[p.eid]; ok && pk.project(cam, mx, my) {
+			if !found || cam.order > top {
+				top, found = cam.order, true
+			}
+		}
+	}
+	var cam *camera
+	picked := false
+	for _, p := range eng.scene.scene {
+		if c, ok := eng.cams[p.eid]; ok {
+			cam = c
+			picked = cam.order == top && pk.project(cam, mx, my)
+		}
+		m, ok := eng.models.model(p.eid)
+		if !ok || !picked || !m.loaded() || m.msh == nil || len(m.msh.vdata) == 0 {
+			continue
+		}
+		var draw *render.Draw
+		if pk.frame, draw = eng.scene.getDraw(pk.frame); draw != nil {
+			pk.povs = append(pk.povs, p)
+			pk.toDraw(*draw, p, cam, m, len(pk.povs))
+		}
+	}
+	render.SortDraws(pk.frame)
+}
+
+// project sets the camera pick projection that stretches the pixel
+// at mx,my over the pick render target. Returns false if the pixel
+// is outside the camera viewport.
+func (pk *picker) project(cam *camera, mx, my int) bool {
+	vx, vy, vw, vh := cam.viewSize()
+	px, py := float64(mx)-vx, float64(my)-vy // same as Camera.Ray.
+	if vw <= 0 || vh <= 0 || px < 0 || px > vw || py < 0 || py > vh {
+		return false
+	}
+
+	// scale and move clip space so that the pixel fills -1:1.
+	cx, cy := 2*px/vw-1, 2*py/vh-1
+	pk.pm.Set(lin.M4I)
+	pk.pm.Xx, pk.pm.Wx = vw, -vw*cx
+	pk.pm.Yy, pk.pm.Wy = vh, -vh*cy
+	pk.cpm.Mult(cam.pm, pk.pm)
+	return true
+}
+
+// toDraw sets a pick draw call that renders the model in the color
+// given by id.
+func (pk *picker) toDraw(d render.Draw, p *pov, cam *camera, m *model, id int) {
+	d.SetMv(pk.mv.Mult(p.mm, cam.vm))
+	d.SetMvp(pk.mvp.Mult(pk.mv, pk.cpm))
+	d.SetScale(p.Scale())
+	d.SetTag(p.eid)
+	bucket := render.Opaque
+	if cam.overlay > 0 {
+		bucket = cam.overlay
+	}
+	depth := cam.depth && m.depth
+	tocam := 0.0
+	if depth {
+		tocam = p.toc
+	}
+	d.SetHints(bucket, tocam, depth, pk.fbo.bid)
+	d.SetViewport(cam.order, 0, 0, 1, 1)
+	d.SetRefs(pk.shd.program, m.msh.vao, m.drawMode)
+	d.SetTex(0, 0, 0, 0, 0)
+	d.SetPose(nil)
+	d.SetUniforms(pk.shd.uniforms)
+	r, g, b := id>>16&0xff, id>>8&0xff, id&0xff
+	d.SetFloats("pid", float32(r)/255, float32(g)/255, float32(b)/255)
+}
+
+// pickFrame requests a pick render. The reply is the pick color
+// of the rendered pixel.
+type pickFrame struct {
+	frame []render.Draw
+	fbo   uint32
+	reply chan uint32
+}
@@ -0,0 +1,37 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gazed/vu/math/lin"
+)
+
+// Check that the pick projection only keeps the picked pixel.
+func TestPickProject(t *testing.T) {
+	cam, _, _ := initScene()
+	cam.SetLocation(0, 0, 14)
+	pk := &picker{cpm: &lin.M4{}, pm: &lin.M4{}}
+	sx, sy, _ := cam.Screen(1, 1, 0)
+	if !pk.project(cam, sx, sy) {
+		t.Fatalf("Expected pixel inside the viewport")
+	}
+	clip := func(x, y, z float64) (cx, cy float64) {
+		v := (&lin.V4{X: x, Y: y, Z: z, W: 1}).MultvM(&lin.V4{X: x, Y: y, Z: z, W: 1}, cam.vm)
+		v.MultvM(v, pk.cpm)
+		return v.X / v.W, v.Y / v.W
+	}
+	if cx, cy := clip(1, 1, 0); math.Abs(cx) > 1 || math.Abs(cy) > 1 {
+		t.Errorf("Expected point in the pick pixel, got %f %f", cx, cy)
+	}
+	if cx, _ := clip(1.1, 1, 0); math.Abs(cx) <= 1 {
+		t.Errorf("Expected nearby point outside the pick pixel, got %f", cx)
+	}
+	cam.SetViewport(0.5, 0, 0.5, 1)
+	if pk.project(cam, 10, 10) {
+		t.Errorf("Expected pixel outside the viewport")
+	}
+}
@@ -0,0 +1,318 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"math"
+)
+
+// Planet renders a small world as a cube-sphere, six Surface faces, one
+// for each side of a cube, whose verticies are projected onto a sphere.
+// Each face is a regular Surface so the land package height maps, biomes,
+// carving, and brushes all work on planet faces. The application sets the
+// face points using a PlanetFiller, which is given the direction from the
+// planet center to each point so that heights can be generated without
+// seams between the faces:
+//     p := vu.NewPlanet(pov, "land", 64, 100)
+//     p.SetModel(func(m vu.Model) { m.AddTex("land") })
+//     p.Fill(filler)
+//     p.Update(cam) // each update: pick the level of detail for each face.
+// Face heights are added to the planet radius. Projected verticies and
+// normals are in the planet Pov coordinates with the planet center at the
+// origin. Faces are stitched to coarser neighbouring faces as with
+// Terrain chunks, which needs a face size that is a power of 2.
+// The face surface HeightAt and NormalAt methods return flat face values
+// and UpdateRegion regenerates the whole face.
+type Planet interface {
+
+	// SetLod sets the coarsest level of detail, see Surface.SetLod, used
+	// for distant faces. Faces closer to the camera than the given
+	// distance are full resolution and each doubling of the distance
+	// uses one level coarser. Defaults are 0, always full resolution,
+	// and the planet radius.
+	SetLod(level int, distance float64) Planet
+
+	// SetSurface changes the NewSurface values used for the faces.
+	// The defaults are spread 1, textureRatio 1, and scale 1.
+	SetSurface(spread int, textureRatio, scale float32) Planet
+
+	// SetModel registers a callback that configures each face
+	// model, ie: adding textures or materials.
+	SetModel(setup func(m Model)) Planet
+
+	// Fill calls the filler for every point on every face and
+	// regenerates all the faces on the next Update.
+	Fill(fill PlanetFiller)
+
+	// Changed regenerates the given face, 0 to 5, on the next Update.
+	// Use after changing the face surface points directly.
+	Changed(face int)
+
+	// Update regenerates the faces whose level of detail, or whose
+	// neighbours level of detail, changed with the camera location.
+	Update(cam Camera)
+	Face(face int) Surface // Face 0 to 5: +x, -x, +y, -y, +z, -z.
+
+	// Direction returns the unit direction from the planet center to
+	// face surface location x, y.
+	Direction(face int, x, y float64) (dx, dy, dz float64)
+}
+
+// PlanetFiller is the application callback that sets the height and
+// texture for one point of a planet face. The point is in the direction
+// dx, dy, dz from the planet center.
+type PlanetFiller func(face int, dx, dy, dz float64, pt *SurfacePoint)
+
+// NewPlanet creates a planet with faces of size-by-size quads that
+// are rendered as child Pov's of p using the given shader.
+func NewPlanet(p Pov, shader string, size int, radius float64) Planet {
+	return newPlanet(p, shader, size, radius)
+}
+
+// Planet
+// =============================================================================
+// planet implements Planet.
+
+// planet tracks the six cube faces.
+type planet struct {
+	pov      *pov           // Planet location, orientation, scale.
+	shader   string         // Face model shader.
+	size     int            // Quads along each side of a face.
+	radius   float64        // Height 0 distance from the planet center.
+	maxLod   int            // Coarsest level of detail.
+	distance float64        // Full resolution distance.
+	setup    func(m Model)  // Optional face model configuration.
+	faces    [6]*planetFace // Cube faces.
+}
+
+// planetFace is one side of the cube.
+type planetFace struct {
+	id      int      // Face index.
+	pl      *planet  // Owning planet.
+	pov     *pov     // Face model.
+	s       *surface // Face height data.
+	lod     [5]int   // Last level of detail and neighbour levels.
+	changed bool     // True to regenerate on the next Update.
+}
+
+// planetAxes holds the outward normal and the surface x and y
+// directions for each face. The x direction crossed with the
+// y direction is the normal so that faces wind outwards.
+var planetAxes = [6][3][3]float64{
+	{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}},  // +x
+	{{-1, 0, 0}, {0, 0, 1}, {0, 1, 0}}, // -x
+	{{0, 1, 0}, {0, 0, 1}, {1, 0, 0}},  // +y
+	{{0, -1, 0}, {1, 0, 0}, {0, 0, 1}}, // -y
+	{{0, 0, 1}, {1, 0, 0}, {0, 1, 0}},  // +z
+	{{0, 0, -1}, {0, 1, 0}, {1, 0, 0}}, // -z
+}
+
+// newPlanet allocates and initializes a planet.
+func newPlanet(p Pov, shader string, size int, radius float64) *planet {
+	pl := &planet{shader: shader, size: size, radius: radius, distance: radius}
+	pl.pov, _ = p.(*pov)
+	if pl.size < 1 {
+		pl.size = 64
+	}
+	for f := range pl.faces {
+		s := newSurface(pl.size+1, pl.size+1, 1, 1, 1)
+		pl.faces[f] = &planetFace{id: f, pl: pl, s: s, lod: [5]int{-1}}
+		s.project = pl.faces[f].project
+	}
+	return pl
+}
+
+// Implement Planet.
+func (pl *planet) SetLod(level int, distance float64) Planet {
+	if level >= 0 && distance > 0 {
+		pl.maxLod, pl.distance = level, distance
+	}
+	return pl
+}
+func (pl *planet) SetSurface(spread int, textureRatio, scale float32) Planet {
+	for _, face := range pl.faces {
+		face.s.spread, face.s.tratio, face.s.scale = spread, textureRatio, scale
+		face.changed = true
+	}
+	return pl
+}
+func (pl *planet) SetModel(setup func(m Model)) Planet {
+	pl.setup = setup
+	return pl
+}
+func (pl *planet) Changed(face int) {
+	if face >= 0 && face < len(pl.faces) {
+		pl.faces[face].changed = true
+	}
+}
+func (pl *planet) Face(face int) Surface {
+	if face >= 0 && face < len(pl.faces) {
+		return pl.faces[face].s
+	}
+	return nil
+}
+
+// Fill implements Planet.
+func (pl *planet) Fill(fill PlanetFiller) {
+	for f, face := range pl.faces {
+		for x := range face.s.pts {
+			for y := range face.s.pts[x] {
+				dx, dy, dz := pl.Direction(f, float64(x), float64(y))
+				fill(f, dx, dy, dz, &face.s.pts[x][y])
+			}
+		}
+		face.changed = true
+	}
+}
+
+// Direction implements Planet. Cube points are mapped onto the sphere
+// so that the quads are closer to the same size than they are with a
+// plain normalized cube. Locations beyond the face continue onto the
+// neighbouring faces, which is used to find the normals at face edges.
+// See: http://mathproofs.blogspot.ca/2005/07/mapping-cube-to-sphere.html
+func (pl *planet) Direction(face int, x, y float64) (dx, dy, dz float64) {
+	axes := planetAxes[face]
+	a, b := 2*x/float64(pl.size)-1, 2*y/float64(pl.size)-1
+	cx := axes[0][0] + a*axes[1][0] + b*axes[2][0]
+	cy := axes[0][1] + a*axes[1][1] + b*axes[2][1]
+	cz := axes[0][2] + a*axes[1][2] + b*axes[2][2]
+	x2, y2, z2 := cx*cx, cy*cy, cz*cz
+	dx = cx * math.Sqrt(math.Max(0, 1-y2/2-z2/2+y2*z2/3))
+	dy = cy * math.Sqrt(math.Max(0, 1-z2/2-x2/2+z2*x2/3))
+	dz = cz * math.Sqrt(math.Max(0, 1-x2/2-y2/2+x2*y2/3))
+	length := math.Sqrt(dx*dx + dy*dy + dz*dz)
+	return dx / length, dy / length, dz / length
+}
+
+// Update implements Planet.
+func (pl *planet) Update(cam Camera) {
+	if pl.pov == nil || cam == nil {
+		return
+	}
+	lx, ly, lz := localCam(pl.pov, cam)
+	levels := [6]int{}
+	for f := range levels {
+		levels[f] = pl.level(f, lx, ly, lz)
+	}
+	for f, face := range pl.faces {
+		if face.pov == nil {
+			face.attach()
+		}
+		lod := [5]int{levels[f]}
+		for edge, n := range pl.neighbours(f) {
+			lod[edge+1] = levels[n]
+		}
+		if lod != face.lod || face.changed {
+			face.lod, face.changed = lod, false
+			face.s.SetLod(lod[0], lod[1], lod[2], lod[3], lod[4])
+			face.s.Update(face.pov.Model(), 0, 0)
+		}
+	}
+}
+
+// level returns the level of detail for a face based on the distance
+// from the camera to the closest point on the face. The closest point
+// is approximated by clamping the camera direction to the face.
+func (pl *planet) level(face int, lx, ly, lz float64) int {
+	axes := planetAxes[face]
+	n := lx*axes[0][0] + ly*axes[0][1] + lz*axes[0][2]
+	u := lx*axes[1][0] + ly*axes[1][1] + lz*axes[1][2]
+	v := lx*axes[2][0] + ly*axes[2][1] + lz*axes[2][2]
+	m := math.Max(math.Abs(n), math.Max(math.Abs(u), math.Abs(v)))
+	if m == 0 {
+		return 0 // camera at the planet center.
+	}
+	half := float64(pl.size) / 2
+	x := (math.Max(-1, math.Min(1, u/m)) + 1) * half
+	y := (math.Max(-1, math.Min(1, v/m)) + 1) * half
+	dx, dy, dz := pl.Direction(face, x, y)
+	dist := math.Sqrt(sq(lx-dx*pl.radius) + sq(ly-dy*pl.radius) + sq(lz-dz*pl.radius))
+	if dist < pl.distance {
+		return 0
+	}
+	level := int(math.Log2(dist/pl.distance)) + 1
+	if level > pl.maxLod {
+		return pl.maxLod
+	}
+	return level
+}
+
+// neighbours returns the faces beyond the left (x=0), right, bottom
+// (y=0), and top edges of the given face, in Surface.SetLod order.
+func (pl *planet) neighbours(face int) (edges [4]int) {
+	axes := planetAxes[face]
+	for cnt, dir := range [4][3]float64{
+		{-axes[1][0], -axes[1][1], -axes[1][2]}, axes[1],
+		{-axes[2][0], -axes[2][1], -axes[2][2]}, axes[2],
+	} {
+		for f := range planetAxes {
+			if planetAxes[f][0] == dir {
+				edges[cnt] = f
+			}
+		}
+	}
+	return edges
+}
+
+// attach creates the model for a face.
+func (face *planetFace) attach() {
+	face.pov = face.pl.pov.NewPov().(*pov)
+	m := face.pov.NewModel(face.pl.shader)
+	if face.pl.setup != nil {
+		face.pl.setup(m)
+	}
+	m.NewMesh("planet")
+}
+
+// project moves the flat verticies generated by the face surface onto
+// the sphere. It is called by the surface before the vertex data is
+// copied to the model. Every vertex, including skirt verticies, sits on
+// a surface grid point and holds its scaled height in z. The normals and
+// tangents are recalculated from the projected neighbouring points.
+func (face *planetFace) project() {
+	s, pl := face.s, face.pl
+	sx, sy := len(s.pts), len(s.pts[0])
+	for v := 0; v < len(s.ab); v++ {
+		gx, gy, h := float64(s.vb[v*3]), float64(s.vb[v*3+1]), float64(s.vb[v*3+2])
+		dx, dy, dz := pl.Direction(face.id, gx, gy)
+		r := pl.radius + h
+		s.vb[v*3], s.vb[v*3+1], s.vb[v*3+2] = float32(dx*r), float32(dy*r), float32(dz*r)
+
+		// normal from the slopes to the neighbouring points.
+		x, y := iclamp(int(gx+0.5), 0, sx-1), iclamp(int(gy+0.5), 0, sy-1)
+		ux, uy, uz := face.slope(x, y, 1, 0)
+		vx, vy, vz := face.slope(x, y, 0, 1)
+		nx, ny, nz := uy*vz-uz*vy, uz*vx-ux*vz, ux*vy-uy*vx
+		length := math.Sqrt(nx*nx + ny*ny + nz*nz)
+		nx, ny, nz = nx/length, ny/length, nz/length
+		s.nb[v*3], s.nb[v*3+1], s.nb[v*3+2] = float32(nx), float32(ny), float32(nz)
+		if s.tangent {
+			dot := ux*nx + uy*ny + uz*nz
+			tx, ty, tz := ux-nx*dot, uy-ny*dot, uz-nz*dot
+			length = math.Sqrt(tx*tx + ty*ty + tz*tz)
+			s.gb[v*4], s.gb[v*4+1], s.gb[v*4+2] = float32(tx/length), float32(ty/length), float32(tz/length)
+		}
+	}
+}
+
+// slope returns the difference between the projected points on either
+// side of grid point x, y along direction dx, dy. Points beyond the
+// face edge use the edge height.
+func (face *planetFace) slope(x, y, dx, dy int) (sx, sy, sz float64) {
+	ax, ay, az := face.point(x-dx, y-dy)
+	bx, by, bz := face.point(x+dx, y+dy)
+	return bx - ax, by - ay, bz - az
+}
+
+// point returns the projected location of face grid point x, y.
+func (face *planetFace) point(x, y int) (px, py, pz float64) {
+	s := face.s
+	h := s.pts[iclamp(x, 0, len(s.pts)-1)][iclamp(y, 0, len(s.pts[0])-1)].Height
+	r := face.pl.radius + float64(h*s.scale)
+	dx, dy, dz := face.pl.Direction(face.id, float64(x), float64(y))
+	return dx * r, dy * r, dz * r
+}
+
+// sq returns v squared.
+func sq(v float64) float64 { return v * v }
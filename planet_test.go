@@ -0,0 +1,47 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"math"
+	"testing"
+)
+
+// Check that faces are projected onto the sphere, meet at their
+// edges, and lose detail away from the camera.
+func TestPlanet(t *testing.T) {
+	eng := newEngine(nil)
+	defer eng.Shutdown()
+	cam := eng.Root().NewPov().NewCam()
+	pl := newPlanet(eng.Root().NewPov(), "land", 8, 10)
+	pl.SetLod(2, 6).Fill(func(face int, dx, dy, dz float64, pt *SurfacePoint) {
+		pt.Height = float32(dz) // higher at the +z pole.
+	})
+	cam.SetLocation(0, 0, 15) // above the +z face.
+	pl.Update(cam)
+	top, side, bottom := pl.faces[4], pl.faces[0], pl.faces[5]
+	if top.lod != [5]int{0, 1, 1, 1, 1} || side.lod[0] != 1 || bottom.lod[0] != 2 {
+		t.Errorf("Expected detailed top face, got %v %v %v", top.lod, side.lod, bottom.lod)
+	}
+	vb, nb := top.s.vb, top.s.nb
+	for v := 0; v < len(vb)/3; v++ {
+		x, y, z := float64(vb[v*3]), float64(vb[v*3+1]), float64(vb[v*3+2])
+		r := math.Sqrt(x*x + y*y + z*z)
+		if r < 10 || r > 11.0001 || float64(nb[v*3])*x+float64(nb[v*3+1])*y+float64(nb[v*3+2])*z <= 0 {
+			t.Fatalf("Expected outward vertex above the radius, got %f %f %f", x, y, z)
+		}
+	}
+
+	// the top face x=size edge meets the +x face top edge.
+	for y := 0; y <= 8; y++ {
+		tx, ty, tz := top.point(8, y)
+		sx, sy, sz := side.point(y, 8)
+		if math.Abs(tx-sx)+math.Abs(ty-sy)+math.Abs(tz-sz) > 1e-9 {
+			t.Errorf("Expected shared edge point, got %f %f %f and %f %f %f", tx, ty, tz, sx, sy, sz)
+		}
+	}
+	if pl.neighbours(4) != [4]int{1, 0, 3, 2} {
+		t.Errorf("Expected +z neighbours -x +x -y +y, got %v", pl.neighbours(4))
+	}
+}
@@ -0,0 +1,249 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"math"
+	"sort"
+)
+
+// Playback controls a played sound, ie:
+//     music := noise.Play(0)
+//     music.SetLoop(true)
+//     ...
+//     music.Fade(0, 2) // fade out over 2 seconds.
+//     music.OnDone(func() { ... })
+// Playback changes are sent to the audio layer once per update, so
+// changes made right after Play apply from the start of the sound.
+// Each sound plays once at a time. Playing a sound again restarts the
+// sound and ends the earlier playback. Playbacks that finish, or are
+// stopped, call OnDone and publish a SoundEvent at the start of the
+// next update. Playbacks are also stopped to free voices for higher
+// priority sounds, see Mixer.SetVoices.
+
+// Playback is returned by Noise.Play. Playbacks of sounds that
+// could not be played are already done.
+type Playback interface {
+	SetLoop(loop bool)               // Repeat until stopped.
+	SetPitch(pitch float64)          // Speed: 1 normal, 2 double, 0.5 half.
+	Volume() float64                 // Volume within the mixer group.
+	SetVolume(zeroToOne float64)     // Clamped to the range 0 to 1.
+	Fade(zeroToOne, seconds float64) // Change the volume over time.
+	Paused() bool                    // True if paused.
+	Pause(pause bool)                // Pause or resume playing.
+	Stop()                           // End the playback.
+	IsPlaying() bool                 // False once finished or stopped.
+	OnDone(done func())              // Called once finished or stopped.
+}
+
+// Playback
+// =============================================================================
+// playback implements Playback.
+
+// playback tracks a played sound until it finishes.
+type playback struct {
+	eng      *engine    // Sends changes to the machine.
+	pid      uint64     // Unique playback id.
+	sid      uint64     // Played sound.
+	mix      int        // Mixer group.
+	priority int        // Voice priority.
+	ctl      playCtl    // Current playback controls.
+	fadeTo   float64    // Fade target volume.
+	fadeRate float64    // Volume change per second. 0 if not fading.
+	ps       *playSound // Play request. Nil once sent.
+	changed  bool       // True if ctl changed since it was sent.
+	stopped  bool       // True if the playback was stopped.
+	done     bool       // True once finished or stopped.
+	onDone   func()     // Called once done.
+}
+
+// playCtl are the playback controls of a played sound.
+type playCtl struct {
+	loop   bool    // Repeat until stopped.
+	pitch  float64 // Playback speed. 1 is normal.
+	volume float64 // Volume within the mixer group.
+	paused bool    // True if paused.
+}
+
+// Implement Playback.
+func (p *playback) Volume() float64    { return p.ctl.volume }
+func (p *playback) Paused() bool       { return p.ctl.paused }
+func (p *playback) IsPlaying() bool    { return !p.done }
+func (p *playback) OnDone(done func()) { p.onDone = done }
+func (p *playback) SetLoop(loop bool) {
+	p.ctl.loop = loop
+	p.change()
+}
+func (p *playback) SetPitch(pitch float64) {
+	if pitch > 0 {
+		p.ctl.pitch = pitch
+		p.change()
+	}
+}
+func (p *playback) SetVolume(zeroToOne float64) {
+	p.ctl.volume, p.fadeRate = math.Max(0, math.Min(1, zeroToOne)), 0
+	p.change()
+}
+func (p *playback) Pause(pause bool) {
+	p.ctl.paused = pause
+	p.change()
+}
+func (p *playback) Stop() {
+	if !p.done {
+		p.stopped = true
+		p.finish()
+	}
+}
+
+// Fade changes the volume immediately for fades that take no time.
+func (p *playback) Fade(zeroToOne, seconds float64) {
+	p.fadeTo = math.Max(0, math.Min(1, zeroToOne))
+	if seconds <= 0 {
+		p.SetVolume(p.fadeTo)
+		return
+	}
+	p.fadeRate = (p.fadeTo - p.ctl.volume) / seconds
+}
+
+// change marks the controls as needing to be sent.
+// Changes to done playbacks are not sent.
+func (p *playback) change() { p.changed = !p.done }
+
+// fade moves the volume towards the fade target.
+func (p *playback) fade(dts float64) {
+	if p.fadeRate == 0 || p.done {
+		return
+	}
+	volume := p.ctl.volume + p.fadeRate*dts
+	if (p.fadeRate > 0 && volume >= p.fadeTo) || (p.fadeRate < 0 && volume <= p.fadeTo) {
+		volume, p.fadeRate = p.fadeTo, 0
+	}
+	p.ctl.volume = volume
+	p.change()
+}
+
+// finish marks the playback as done. The completion callback and
+// event happen at the start of the next update.
+func (p *playback) finish() {
+	p.done, p.fadeRate = true, 0
+	p.eng.finished = append(p.eng.finished, p)
+}
+
+// playback
+// =============================================================================
+// engine playback handling.
+
+// play queues a play request, ending any earlier playback of the sound.
+func (eng *engine) play(ps *playSound, priority int) *playback {
+	if old, ok := eng.plays[ps.sid]; ok && !old.done {
+		old.finish()
+	}
+	eng.pid++
+	p := &playback{eng: eng, pid: eng.pid, sid: ps.sid, mix: ps.mix, priority: priority, ps: ps}
+	p.ctl = playCtl{pitch: 1, volume: 1}
+	eng.plays[ps.sid] = p
+	return p
+}
+
+// updatePlays advances volume fades and sends play requests and
+// playback changes to the machine. Playbacks are sent in sound order
+// once the new playbacks have been given voices.
+// Expected to be called once per update.
+func (eng *engine) updatePlays(dts float64) {
+	eng.sids = eng.sids[:0]
+	for sid := range eng.plays {
+		eng.sids = append(eng.sids, sid)
+	}
+	sort.Sort(eids(eng.sids))
+	for _, sid := range eng.sids {
+		if p := eng.plays[sid]; p.ps != nil && !p.done && !eng.voice(p) {
+			p.finish() // no voice for the new playback.
+		}
+	}
+	for _, sid := range eng.sids {
+		p := eng.plays[sid]
+		p.fade(dts)
+		switch {
+		case p.ps != nil:
+			if !p.done { // not stopped before it was sent.
+				ps := p.ps
+				ps.pid, ps.ctl = p.pid, p.ctl
+				go func(ps *playSound) { eng.machine <- ps }(ps)
+			}
+			p.ps = nil
+		case p.stopped:
+			pc := &playControl{sid: sid, stop: true}
+			go func(pc *playControl) { eng.machine <- pc }(pc)
+		case p.changed:
+			pc := &playControl{sid: sid, ctl: p.ctl}
+			go func(pc *playControl) { eng.machine <- pc }(pc)
+		}
+		p.changed = false
+		if p.done {
+			delete(eng.plays, sid)
+		}
+	}
+}
+
+// voice makes room for a new playback within the voice limits of
+// its mixer group and the master group. Returns false if there are
+// only higher priority playbacks at the limit.
+func (eng *engine) voice(p *playback) bool {
+	for _, group := range []int{p.mix, MixMaster} {
+		max := eng.mixers[group].voices
+		for max > 0 && eng.voices(group, p) >= max {
+			steal := eng.steal(group, p)
+			if steal == nil {
+				return false
+			}
+			steal.Stop()
+		}
+	}
+	return true
+}
+
+// voices counts the playing sounds in a mixer group, other than p.
+func (eng *engine) voices(group int, p *playback) (cnt int) {
+	for _, o := range eng.plays {
+		if o != p && !o.done && (group == MixMaster || o.mix == group) {
+			cnt++
+		}
+	}
+	return cnt
+}
+
+// steal returns the oldest of the lowest priority playbacks in a
+// mixer group. Returns nil if all have a higher priority than p.
+func (eng *engine) steal(group int, p *playback) (steal *playback) {
+	for _, o := range eng.plays {
+		if o == p || o.done || o.priority > p.priority || (group != MixMaster && o.mix != group) {
+			continue
+		}
+		if steal == nil || o.priority < steal.priority || (o.priority == steal.priority && o.pid < steal.pid) {
+			steal = o
+		}
+	}
+	return steal
+}
+
+// endPlays finishes the playbacks that the machine reported as ended.
+// Then the done playbacks call their callbacks and are published.
+// Expected to be called once per update before the events are sent.
+func (eng *engine) endPlays(pids []uint64) {
+	for _, pid := range pids {
+		for _, p := range eng.plays {
+			if p.pid == pid && !p.done {
+				p.finish()
+			}
+		}
+	}
+	finished := eng.finished
+	eng.finished = nil // callbacks can finish other playbacks.
+	for _, p := range finished {
+		if p.onDone != nil {
+			p.onDone()
+		}
+		eng.events.publish(SoundEvent, p)
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"testing"
+)
+
+// Playback changes are sent with the play request and each update.
+func TestPlayback(t *testing.T) {
+	machine := make(chan msg, 10)
+	eng := newEngine(machine)
+	if pb := eng.Root().NewPov().NewNoise().Play(0); pb.IsPlaying() {
+		t.Errorf("Expected unplayable sound to be done")
+	}
+	n := eng.Root().NewPov().NewNoise().(*noise)
+	n.snds, n.loaded = []*sound{{sid: 7}}, true
+	pb := n.Play(0)
+	pb.SetLoop(true)
+	pb.SetPitch(2)
+	eng.updatePlays(0.02)
+	if ps, ok := nextMsg(machine).(*playSound); !ok || ps.pid != 1 || ps.ctl != (playCtl{loop: true, pitch: 2, volume: 1}) {
+		t.Fatalf("Expected looping sound, got %+v", ps)
+	}
+
+	// fades change the volume each update.
+	pb.Fade(0, 0.04)
+	for _, volume := range []float64{0.5, 0} {
+		eng.updatePlays(0.02)
+		if pc, ok := nextMsg(machine).(*playControl); !ok || pc.sid != 7 || pc.ctl.volume != volume {
+			t.Fatalf("Expected volume %f, got %+v", volume, pc)
+		}
+	}
+	if eng.updatePlays(0.02); len(machine) != 0 || pb.Volume() != 0 {
+		t.Errorf("Expected fade to finish")
+	}
+
+	// ended playbacks are done and call back.
+	done, events := 0, 0
+	pb.OnDone(func() { done++ })
+	eng.Subscribe(SoundEvent, func(topic string, data interface{}) {
+		if data.(Playback) == pb {
+			events++
+		}
+	})
+	eng.endPlays([]uint64{1})
+	eng.events.dispatch()
+	if pb.IsPlaying() || done != 1 || events != 1 {
+		t.Errorf("Expected done playback %t %d %d", pb.IsPlaying(), done, events)
+	}
+
+	// playing again ends the earlier playback.
+	first, second := n.Play(0), n.Play(0)
+	first.OnDone(func() { done++ })
+	eng.endPlays(nil)
+	if first.IsPlaying() || !second.IsPlaying() || done != 2 {
+		t.Errorf("Expected first playback to be done")
+	}
+	eng.updatePlays(0.02)
+	if ps, ok := nextMsg(machine).(*playSound); !ok || ps.pid != 3 {
+		t.Fatalf("Expected second playback, got %+v", ps)
+	}
+	second.Stop()
+	eng.updatePlays(0.02)
+	if pc, ok := nextMsg(machine).(*playControl); !ok || !pc.stop || second.IsPlaying() {
+		t.Errorf("Expected stopped playback, got %+v", pc)
+	}
+	n.Play(0).Stop()
+	if eng.updatePlays(0.02); len(machine) != 0 || len(eng.plays) != 0 {
+		t.Errorf("Expected playback stopped before playing to not play")
+	}
+}
+
+// New playbacks steal voices from the oldest lower priority playbacks.
+func TestVoices(t *testing.T) {
+	machine := make(chan msg, 20)
+	eng := newEngine(machine)
+	eng.Mixer(MixSfx).SetVoices(2)
+	eng.Mixer(MixMaster).SetVoices(3)
+	play := func(sid uint64, group, priority int) Playback {
+		n := eng.Root().NewPov().NewNoise().(*noise)
+		n.snds, n.loaded = []*sound{{sid: sid}}, true
+		n.SetMixer(group)
+		n.SetPriority(priority)
+		pb := n.Play(0)
+		eng.updatePlays(0)
+		return pb
+	}
+	first, second, third := play(1, MixSfx, 0), play(2, MixSfx, 0), play(3, MixSfx, 0)
+	if first.IsPlaying() || !second.IsPlaying() || !third.IsPlaying() {
+		t.Errorf("Expected group limit to stop the oldest sound")
+	}
+	if low := play(4, MixSfx, -1); low.IsPlaying() {
+		t.Errorf("Expected lower priority sound to not play")
+	}
+
+	// the master limit applies to all groups.
+	dialog, music := play(5, MixVoice, 10), play(6, MixMusic, musicPriority)
+	if !dialog.IsPlaying() || !music.IsPlaying() || second.IsPlaying() || !third.IsPlaying() {
+		t.Errorf("Expected master limit to stop lower priority sounds")
+	}
+	if eng.Mixer(MixMaster).Voices() != 3 || len(eng.plays) != 3 {
+		t.Errorf("Expected three voices, got %d", len(eng.plays))
+	}
+}
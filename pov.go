@@ -27,19 +27,102 @@ type Pov interface {
 	Spin(x, y, z float64)            // Rotate degrees about the given axis.
 	Move(x, y, z float64, q *lin.Q)  // Move along indicated direction.
 
+	// World space transforms combine the local transforms of this Pov
+	// and its parents. They are calculated when called, unlike World and
+	// WorldMatrix which are from the last update. The set methods adjust
+	// the local transform to give the requested world transform. World
+	// scales are only exact when parents are uniformly scaled.
+	WorldLocation() (x, y, z float64)     // Get, or
+	SetWorldLocation(x, y, z float64) Pov // ...Set the world location.
+	WorldRotation() (q *lin.Q)            // Get new, or
+	SetWorldRotation(q *lin.Q) Pov        // ...Set the world rotation.
+	WorldScale() (x, y, z float64)        // Combined per axis scale.
+	WorldMatrix() *lin.M4                 // Model matrix. Don't alter.
+
+	// Bounds returns the world space box containing the models and
+	// bodies of this Pov and its enabled children as of the last update.
+	// Returns false if there is nothing with bounds. See bounds.go.
+	Bounds() (box physics.Abox, ok bool)
+
+	// Replicated Pov's are included in Eng.Snapshot. Default false.
+	Replicated() bool                  // Get, or
+	SetReplicated(replicated bool) Pov // ...Set snapshot replication.
+
 	// Visible affects this Pov and its child Pov's.
 	Visible() bool           // Invisible Pov's are removed from
 	SetVisible(visible bool) // ...rendering without disposing them.
 
+	// Enabled affects this Pov and its child Pov's. Disabled Pov's keep
+	// their components but models are not drawn or animated, bodies are
+	// not simulated, noises are not played, and transforms of child Pov's
+	// are not updated. Useful for stashing UI screens or level sections.
+	Enabled() bool           // Get, or
+	SetEnabled(enabled bool) // ...Set, default true.
+
+	// Moved is true if the world transform changed in the last update.
+	// SetMoved registers a callback that is called each update where
+	// the world transform changes. Set to nil to remove the callback.
+	Moved() bool
+	SetMoved(moved func(p Pov))
+
 	// Per axis scale. Normal is 1, greater than 1 to enlarge,
 	// positive fraction to shrink.
 	Scale() (x, y, z float64)     // Get, or
 	SetScale(x, y, z float64) Pov // ...Set the current scale.
 
+	// Names and tags let game systems find Pov's using Eng.Find and
+	// Eng.FindAllTagged. Names are expected to be unique. Many Pov's
+	// can share a tag. Empty strings remove the name or tag.
+	Name() string            // Get, or
+	SetName(name string) Pov // ...Set the lookup name.
+	Tag() string             // Get, or
+	SetTag(tag string) Pov   // ...Set the group tag.
+
+	// Layers are bits that group Pov's for camera culling, body
+	// collisions, and ray casts. See Camera.SetMask, Body.SetLayers,
+	// and Eng.Raycast. The default is layer bit 1.
+	Layers() uint32              // Get, or
+	SetLayers(layers uint32) Pov // ...Set the layer bits.
+
 	// Create a child POV from this pov.
 	NewPov() Pov      // Creates attaches a new child transform Pov.
 	Dispose(kind int) // Discard POV, MODEL, BODY, VIEW, NOISE, or LAYER.
 
+	// DisposeAfter disposes this Pov, and its children, once the given
+	// game time in seconds has passed. The Pov is disabled when it expires
+	// and disposed within the next few updates. Later calls replace
+	// earlier ones. Negative seconds cancel a pending disposal.
+	DisposeAfter(seconds float64)
+
+	// SetParent moves this Pov, and its children, to a new parent.
+	// keepWorld adjusts the location, rotation, and scale so that the
+	// Pov stays where it is in world space. Scales are only exactly
+	// kept for uniformly scaled parents. Ignored for the root or if the
+	// new parent is this Pov or one of its children.
+	SetParent(parent Pov, keepWorld bool) Pov
+
+	// SetJoint attaches this Pov to the named bone of the parent Pov's
+	// animated model. The Pov then follows the animated bone with its
+	// location, rotation, and scale relative to the bone. Use "" to
+	// detach. World, and WorldMatrix include the bone transform.
+	// The other world accessors do not.
+	Joint() string            // Get, or
+	SetJoint(name string) Pov // ...Set the parent model bone.
+
+	// Constraints set the world rotation each update to track a target
+	// Pov. LookAt faces the target keeping +Y up. Aim faces the target
+	// with the given world up direction. CopyRotation matches the target
+	// world rotation. A nil target removes the constraint. See constraint.go.
+	LookAt(target Pov) Pov
+	Aim(target Pov, ux, uy, uz float64) Pov
+	CopyRotation(target Pov) Pov
+
+	// Visit calls visitor with this Pov, at depth 0, and then with each
+	// child Pov, depth first, in the order they were added. Returning false
+	// skips the children of the visited Pov. The hierarchy is not expected
+	// to be changed while visiting.
+	Visit(visitor func(p Pov, depth int) bool)
+
 	// Adding a camera to a Pov means that all rendered models in the Pov's
 	// hierarchy will be viewed with this camera settings.
 	Cam() Camera    // Nil if no camera for this Pov.
@@ -48,6 +131,7 @@ type Pov interface {
 	// Model is an optional rendered component associated with a Pov.
 	Model() Model                 // Nil if no model.
 	NewModel(shader string) Model // Nil if a model already exists.
+	NewSky(shader string) Model   // Skybox model. See sky.go.
 
 	// Body is an optional physics component associated with a Pov. Bodies
 	// are set on top level Pov transforms to get valid world coordindates.
@@ -55,6 +139,13 @@ type Pov interface {
 	NewBody(b physics.Body) physics.Body // Create non-colliding body.
 	SetSolid(mass, bounce float64)       // Make existing body collide.
 
+	// Ragdoll replaces the animation of this Pov's model with physics
+	// bodies built from the named bones, ie: when a character dies.
+	// NewRagdoll returns nil if the model is not a loaded animated model
+	// or already has a ragdoll. See ragdoll.go.
+	Ragdoll() Ragdoll                                         // Nil if no ragdoll.
+	NewRagdoll(radius, mass float64, bones ...string) Ragdoll // Start physics.
+
 	// Noise is an optional audio component. Played noises occur at the
 	// associated Pov's location. Noises that are played will be louder
 	// as the distance between the played noise and listener decreases.
@@ -67,6 +158,13 @@ type Pov interface {
 	Light() Light    // Nil if no light for this Pov.
 	NewLight() Light // Create a light at this Pov.
 
+	// Components are optional application behaviours. Components are
+	// updated, in the order they were added, after each App.Update.
+	// Components of disabled Pov's are not updated. See Component.
+	AddComponent(c Component)    // Attach and call c.OnAttach.
+	RemoveComponent(c Component) // Detach and call c.OnDispose.
+	Components() []Component     // Attached components. Don't alter.
+
 	// Layer is an optional render to texture pass. This Pov and all
 	// child Pov's will be rendered to this texture layer.
 	Layer() Layer    // Nil if no layer for this Pov.
@@ -82,11 +180,20 @@ type Pov interface {
 // All user object creation requests pass through the pov instances which
 // forward them to the engine entity manager.
 type pov struct {
-	eng     *engine // Entity manager.
-	eid     uint64  // Unique entity identifier.
-	at      *lin.T  // point of view: local location/orientation.
-	scale   *lin.V3 // Per axis scale: >1 to enlarge, 0<1 to shrink.
-	visible bool    // True means visible for rendering.
+	eng     *engine     // Entity manager.
+	eid     uint64      // Unique entity identifier.
+	x       *xform      // Transform storage for at, scale, rot, mm.
+	at      *lin.T      // point of view: local location/orientation.
+	scale   *lin.V3     // Per axis scale: >1 to enlarge, 0<1 to shrink.
+	visible bool        // True means visible for rendering.
+	enabled bool        // False suspends this Pov and its children.
+	name    string      // Optional unique lookup name.
+	tag     string      // Optional group tag.
+	layers  uint32      // Layer bits. Default 1.
+	joint   string      // Optional parent model bone name.
+	con     *constraint // Optional target tracking.
+	netted  bool        // True to include in snapshots.
+	comps   []Component // Optional application behaviours.
 
 	// Each pov node can have children which base their position and
 	// orientation relative to the parents.
@@ -97,20 +204,50 @@ type pov struct {
 	toc float64 // distance to camera.
 	rot *lin.Q  // rotation/orientation.
 	mm  *lin.M4 // model transform.
+
+	// Track changes to avoid recalculating unchanged transforms.
+	placed  bool      // True once the model transform is calculated.
+	moved   bool      // True if the model transform changed last update.
+	pending bool      // True if disabled children need updating.
+	wasOn   bool      // Enabled when last placed.
+	onMove  func(Pov) // Optional model transform change callback.
+	expires float64   // Game time to dispose if scheduled.
+
+	// World bounds of this pov and its children.
+	bb      physics.Abox // World space bounds.
+	bounded bool         // True if bb is valid.
+	mbound  *mesh        // Mesh included in bb.
+	mstamp  int          // Mesh bounds stamp included in bb.
+
+	// Spatial index cells covered by bb.
+	slo, shi cell // Lowest and highest cells.
+	sindexed bool // True if in the spatial index.
+	sbig     bool // True if in the spatial index big list.
+	sstamp   int  // Last query that checked this pov.
 }
 
 // newPov allocates and initialzes a point of view transform.
 func newPov(eng *engine, eid uint64) *pov {
-	p := &pov{eng: eng, eid: eid, visible: true}
-	p.at = lin.NewT()
-	p.scale = &lin.V3{X: 1, Y: 1, Z: 1}
-
-	// allocate scratch variables.
-	p.rot = lin.NewQ()
-	p.mm = &lin.M4{}
+	p := &pov{eng: eng, eid: eid, visible: true, enabled: true, layers: 1}
+	p.setXform(eng.xforms.get())
 	return p
 }
 
+// setXform points the pov transform data at the given storage.
+// Disposed pov's are given their own storage so that stale references
+// don't affect the new pov's reusing the original storage.
+func (p *pov) setXform(x *xform) {
+	if p.x != nil && x != p.x {
+		*x = *p.x // keep the current values.
+		x.at.Loc, x.at.Rot = &x.loc, &x.rot
+	}
+	p.x = x
+	p.at = &x.at
+	p.scale = &x.scale
+	p.rot = &x.q0 // scratch.
+	p.mm = &x.mm
+}
+
 // Implement Pov.
 func (p *pov) Location() (x, y, z float64) {
 	return p.at.Loc.X, p.at.Loc.Y, p.at.Loc.Z
@@ -118,6 +255,9 @@ func (p *pov) Location() (x, y, z float64) {
 
 // Implement Pov.
 func (p *pov) SetLocation(x, y, z float64) Pov {
+	if j := p.eng.undo; j != nil {
+		defer j.track(p)()
+	}
 	p.at.Loc.X, p.at.Loc.Y, p.at.Loc.Z = x, y, z
 	return p
 }
@@ -137,12 +277,18 @@ func (p *pov) World() (x, y, z float64) {
 
 // Implement Pov.
 func (p *pov) SetRotation(q *lin.Q) {
+	if j := p.eng.undo; j != nil {
+		defer j.track(p)()
+	}
 	r := p.at.Rot
 	r.X, r.Y, r.Z, r.W = q.X, q.Y, q.Z, q.W
 }
 
 // Implement Pov.
 func (p *pov) Spin(x, y, z float64) {
+	if j := p.eng.undo; j != nil {
+		defer j.track(p)()
+	}
 	if x != 0 {
 		p.rot.SetAa(1, 0, 0, lin.Rad(x))
 		p.at.Rot.Mult(p.rot, p.at.Rot)
@@ -161,6 +307,9 @@ func (p *pov) Spin(x, y, z float64) {
 // along the given direction. Physics bodies should use Body.Push which
 // affects velocity.
 func (p *pov) Move(x, y, z float64, dir *lin.Q) {
+	if j := p.eng.undo; j != nil {
+		defer j.track(p)()
+	}
 	dx, dy, dz := lin.MultSQ(x, y, z, dir)
 	p.at.Loc.X += dx
 	p.at.Loc.Y += dy
@@ -170,21 +319,206 @@ func (p *pov) Move(x, y, z float64, dir *lin.Q) {
 // Implement Pov.
 func (p *pov) Visible() bool { return p.visible }
 func (p *pov) SetVisible(visible bool) {
+	if j := p.eng.undo; j != nil {
+		defer j.track(p)()
+	}
 	p.visible = visible
 }
 
 // Implement Pov.
 func (p *pov) Scale() (x, y, z float64) { return p.scale.X, p.scale.Y, p.scale.Z }
 func (p *pov) SetScale(x, y, z float64) Pov {
+	if j := p.eng.undo; j != nil {
+		defer j.track(p)()
+	}
 	p.scale.X, p.scale.Y, p.scale.Z = x, y, z
 	return p
 }
 
+// Implement Pov.
+func (p *pov) Moved() bool { return p.moved }
+func (p *pov) SetMoved(moved func(p Pov)) {
+	p.onMove = moved
+}
+
+// changed returns true if the local transform differs from the
+// last call, remembering the current local transform.
+func (p *pov) changed() bool {
+	l, r, s := p.at.Loc, p.at.Rot, p.scale
+	now := [10]float64{l.X, l.Y, l.Z, r.X, r.Y, r.Z, r.W, s.X, s.Y, s.Z}
+	if p.placed && now == p.x.last {
+		return false
+	}
+	p.placed, p.x.last = true, now
+	return true
+}
+
+// Implement Pov.
+func (p *pov) Enabled() bool { return p.enabled }
+func (p *pov) SetEnabled(enabled bool) {
+	if j := p.eng.undo; j != nil {
+		defer j.track(p)()
+	}
+	p.enabled = enabled
+}
+
+// active returns true if this pov and all its parents are enabled.
+func (p *pov) active() bool {
+	for ; p != nil; p = p.parent {
+		if !p.enabled {
+			return false
+		}
+	}
+	return true
+}
+
+// Implement Pov. The engine keeps the name and tag lookups.
+func (p *pov) Name() string { return p.name }
+func (p *pov) SetName(name string) Pov {
+	if j := p.eng.undo; j != nil {
+		defer j.track(p)()
+	}
+	p.eng.setName(p, name)
+	return p
+}
+func (p *pov) Tag() string { return p.tag }
+func (p *pov) SetTag(tag string) Pov {
+	if j := p.eng.undo; j != nil {
+		defer j.track(p)()
+	}
+	p.eng.setTag(p, tag)
+	return p
+}
+
+// Implement Pov.
+func (p *pov) Replicated() bool { return p.netted }
+func (p *pov) SetReplicated(replicated bool) Pov {
+	p.netted = replicated
+	return p
+}
+
+// Implement Pov.
+func (p *pov) Joint() string { return p.joint }
+func (p *pov) SetJoint(name string) Pov {
+	p.joint, p.placed = name, false
+	return p
+}
+
+// Implement Pov. Any body is moved to the same collision layers.
+func (p *pov) Layers() uint32 { return p.layers }
+func (p *pov) SetLayers(layers uint32) Pov {
+	if j := p.eng.undo; j != nil {
+		defer j.track(p)()
+	}
+	p.layers = layers
+	if b := p.Body(); b != nil {
+		_, mask := b.Layers()
+		b.SetLayers(layers, mask)
+	}
+	return p
+}
+
+// Implement Pov.
+func (p *pov) SetParent(parent Pov, keepWorld bool) Pov {
+	np, ok := parent.(*pov)
+	if !ok || np == nil || p.parent == nil || np == p.parent {
+		return p
+	}
+	for ancestor := np; ancestor != nil; ancestor = ancestor.parent {
+		if ancestor == p {
+			return p // can't become a child of a child.
+		}
+	}
+	if j := p.eng.undo; j != nil {
+		defer j.track(p)()
+	}
+	wl, wr, ws := p.worldTransform()
+	p.parent.remChild(p)
+	p.placed = false // recalculate with the new parent.
+	p.parent = np
+	np.children = append(np.children, p)
+	if keepWorld {
+		p.SetWorldLocation(wl.X, wl.Y, wl.Z)
+		p.SetWorldRotation(wr)
+		_, _, ns := np.worldTransform()
+		p.scale.SetS(ws.X/ns.X, ws.Y/ns.Y, ws.Z/ns.Z)
+	}
+	return p
+}
+
+// Implement Pov.
+func (p *pov) WorldLocation() (x, y, z float64) {
+	l, _, _ := p.worldTransform()
+	return l.X, l.Y, l.Z
+}
+func (p *pov) WorldRotation() (q *lin.Q) {
+	_, r, _ := p.worldTransform()
+	return r
+}
+func (p *pov) WorldScale() (x, y, z float64) {
+	_, _, s := p.worldTransform()
+	return s.X, s.Y, s.Z
+}
+func (p *pov) WorldMatrix() *lin.M4 { return p.mm }
+
+// Implement Pov. The root has no parent so its local transform
+// is the world transform.
+func (p *pov) SetWorldLocation(x, y, z float64) Pov {
+	if p.parent == nil {
+		return p.SetLocation(x, y, z)
+	}
+	pl, pr, ps := p.parent.worldTransform()
+	x, y, z = lin.MultSQ(x-pl.X, y-pl.Y, z-pl.Z, pr.Inv(pr))
+	return p.SetLocation(x/ps.X, y/ps.Y, z/ps.Z)
+}
+func (p *pov) SetWorldRotation(q *lin.Q) Pov {
+	if p.parent == nil {
+		p.SetRotation(q)
+		return p
+	}
+	if j := p.eng.undo; j != nil {
+		defer j.track(p)()
+	}
+	_, pr, _ := p.parent.worldTransform()
+	p.at.Rot.Mult(q, pr.Inv(pr))
+	return p
+}
+
+// Implement Pov.
+func (p *pov) Visit(visitor func(p Pov, depth int) bool) {
+	p.visit(visitor, 0)
+}
+
+// visit recursively walks the hierarchy for Visit.
+func (p *pov) visit(visitor func(p Pov, depth int) bool, depth int) {
+	if visitor(p, depth) {
+		for _, child := range p.children {
+			child.visit(visitor, depth+1)
+		}
+	}
+}
+
+// worldTransform combines the local transforms from the root
+// to this pov. Unlike World it does not rely on placeModels.
+func (p *pov) worldTransform() (loc *lin.V3, rot *lin.Q, scale *lin.V3) {
+	loc, rot, scale = lin.NewV3(), lin.NewQI(), &lin.V3{X: 1, Y: 1, Z: 1}
+	for c := p; c != nil; c = c.parent {
+		// apply the parent transform c to the accumulated child transform.
+		s, l := c.scale, c.at.Loc
+		x, y, z := lin.MultSQ(loc.X*s.X, loc.Y*s.Y, loc.Z*s.Z, c.at.Rot)
+		loc.SetS(x+l.X, y+l.Y, z+l.Z)
+		rot.Mult(rot, c.at.Rot)
+		scale.SetS(scale.X*s.X, scale.Y*s.Y, scale.Z*s.Z)
+	}
+	return loc, rot, scale
+}
+
 // remChild is used by a pov removing itself from the hierarchy.
 func (p *pov) remChild(c *pov) {
-	for index, c := range p.children {
-		if c.eid == p.eid {
+	for index, child := range p.children {
+		if child.eid == c.eid {
 			p.children = append(p.children[:index], p.children[index+1:]...)
+			p.placed = false // recalculate bounds without the child.
 			return
 		}
 	}
@@ -195,10 +529,12 @@ func (p *pov) remChild(c *pov) {
 // referenced anywhere else.
 func (p *pov) NewPov() Pov                         { return p.eng.newPov(p) }
 func (p *pov) Dispose(kind int)                    { p.eng.dispose(p, kind) }
+func (p *pov) DisposeAfter(seconds float64)        { p.eng.disposeAfter(p, seconds) }
 func (p *pov) Cam() Camera                         { return p.eng.cam(p) }
 func (p *pov) NewCam() Camera                      { return p.eng.newCam(p) }
 func (p *pov) Model() Model                        { return p.eng.model(p) }
 func (p *pov) NewModel(shader string) Model        { return p.eng.newModel(p, shader) }
+func (p *pov) NewSky(shader string) Model          { return p.eng.newSky(p, shader) }
 func (p *pov) Light() Light                        { return p.eng.light(p) }
 func (p *pov) NewLight() Light                     { return p.eng.newLight(p) }
 func (p *pov) Layer() Layer                        { return p.eng.layer(p) }
@@ -206,6 +542,13 @@ func (p *pov) NewLayer() Layer                     { return p.eng.newLayer(p, re
 func (p *pov) Body() physics.Body                  { return p.eng.body(p) }
 func (p *pov) NewBody(b physics.Body) physics.Body { return p.eng.newBody(p, b) }
 func (p *pov) SetSolid(mass, bounce float64)       { p.eng.setSolid(p, mass, bounce) }
-func (p *pov) Noise() Noise                        { return p.eng.noise(p) }
-func (p *pov) NewNoise() Noise                     { return p.eng.newNoise(p) }
-func (p *pov) SetListener()                        { p.eng.setListener(p) }
+func (p *pov) Ragdoll() Ragdoll                    { return p.eng.ragdoll(p) }
+func (p *pov) NewRagdoll(radius, mass float64, bones ...string) Ragdoll {
+	return p.eng.newRagdoll(p, radius, mass, bones)
+}
+func (p *pov) Noise() Noise                { return p.eng.noise(p) }
+func (p *pov) NewNoise() Noise             { return p.eng.newNoise(p) }
+func (p *pov) SetListener()                { p.eng.setListener(p) }
+func (p *pov) AddComponent(c Component)    { p.eng.addComponent(p, c) }
+func (p *pov) RemoveComponent(c Component) { p.eng.removeComponent(p, c) }
+func (p *pov) Components() []Component     { return p.comps }
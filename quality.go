@@ -0,0 +1,91 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"log"
+)
+
+// Quality bundles the engine settings that trade rendering quality for
+// speed. Start from one of the presets and override individual settings
+// as needed, ie:
+//     q := vu.QualityPreset(vu.QualityHigh)
+//     q.Particles = 0.5                     // ...but fewer particles.
+//     eng.SetQuality(q)
+type Quality struct {
+	ShadowSize int     // Shadow map width and height in pixels.
+	Particles  float64 // Fraction, 0 to 1, of each effects max particles.
+
+	// TextureSize is the largest texture width and height in pixels.
+	// Larger textures are halved as they are loaded until they fit.
+	// Zero does not limit texture sizes. Changes apply to textures
+	// loaded after the change.
+	TextureSize int
+
+	// PostEffects turns on the camera lens depth of field. Shaders are
+	// given a zero dof, the same as a camera without a Lens, when off.
+	PostEffects bool
+
+	// LodBias is added to the distance based levels of detail. Positive
+	// values use coarser levels closer to the camera, negative values
+	// keep finer levels further away. Terrain chunk levels and Cdlod
+	// ranges use the bias. Use Lod to bias levels given to Surface.SetLod.
+	LodBias int
+}
+
+// Quality presets for QualityPreset.
+const (
+	QualityLow    = iota // Fastest.
+	QualityMedium        // Default.
+	QualityHigh          // Best looking.
+)
+
+// QualityPreset returns the settings for one of QualityLow,
+// QualityMedium, or QualityHigh. Unknown presets are QualityMedium.
+func QualityPreset(preset int) Quality {
+	switch preset {
+	case QualityLow:
+		return Quality{ShadowSize: 512, Particles: 0.5, TextureSize: 512, LodBias: 1}
+	case QualityHigh:
+		return Quality{ShadowSize: 2048, Particles: 1, PostEffects: true, LodBias: -1}
+	}
+	return Quality{ShadowSize: layerSize, Particles: 1, TextureSize: 2048, PostEffects: true}
+}
+
+// Lod returns the given level of detail with the LodBias applied.
+// Levels are never less than 0, full resolution.
+func (q Quality) Lod(level int) int {
+	if level += q.LodBias; level < 0 {
+		return 0
+	}
+	return level
+}
+
+// Quality implements Eng.
+func (eng *engine) Quality() Quality { return eng.quality }
+
+// SetQuality implements Eng. Invalid settings are clamped or ignored.
+// The shadow map is only rebound when its size changes.
+func (eng *engine) SetQuality(q Quality) {
+	if q.ShadowSize < 1 {
+		q.ShadowSize = eng.quality.ShadowSize
+	}
+	if q.TextureSize < 0 {
+		q.TextureSize = 0
+	}
+	eng.loader.setTextureSize(q.TextureSize)
+	switch {
+	case q.Particles < 0:
+		q.Particles = 0
+	case q.Particles > 1:
+		q.Particles = 1
+	}
+	if sm := eng.scene.shadowMap; sm != nil && int(sm.size) != q.ShadowSize {
+		sm.size = int32(q.ShadowSize)
+		if err := eng.loader.bindLayer(sm); err != nil {
+			log.Printf("Could not resize shadow map %s", err)
+		}
+	}
+	eng.quality = q
+}
@@ -0,0 +1,114 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/gazed/vu/render"
+)
+
+// Check that presets can be overridden and invalid values are fixed.
+func TestSetQuality(t *testing.T) {
+	eng := newEngine(nil)
+	defer eng.Shutdown()
+	if q := eng.Quality(); q != QualityPreset(QualityMedium) {
+		t.Errorf("Expected medium quality by default, got %+v", q)
+	}
+	q := QualityPreset(QualityLow)
+	q.ShadowSize, q.Particles = 0, 2
+	eng.SetQuality(q)
+	if q = eng.Quality(); q.ShadowSize != layerSize || q.Particles != 1 {
+		t.Errorf("Expected invalid values to be fixed, got %+v", q)
+	}
+}
+
+// Check that the LOD bias changes terrain and cdlod detail.
+func TestLodBias(t *testing.T) {
+	eng := newEngine(nil)
+	defer eng.Shutdown()
+	if q := (Quality{LodBias: -2}); q.Lod(1) != 0 || q.Lod(3) != 1 {
+		t.Errorf("Expected biased levels clamped to 0")
+	}
+	cam := eng.Root().NewPov().NewCam()
+	cam.SetLocation(4, 4, 10)
+	tr := newTerrain(eng.Root().NewPov(), "land", 8, nil)
+	tr.SetRange(1).SetLod(3)
+	c := newCdlod(eng.Root().NewPov(), "cdlod", newSurface(129, 129, 1, 1, 1), 8)
+	near := c.rangeOf(1)
+	q := QualityPreset(QualityLow)
+	eng.SetQuality(q)
+	tr.Update(cam)
+	if lod := tr.chunks[chunkID{0, 0}].lod[0]; lod != 1 {
+		t.Errorf("Expected coarser center chunk, got %d", lod)
+	}
+	if biased := c.rangeOf(1); biased != near*0.5 {
+		t.Errorf("Expected half the range, got %f %f", biased, near)
+	}
+}
+
+// Check that effects are limited by the particle quality.
+func TestParticleQuality(t *testing.T) {
+	given := 0
+	effect := func(all []*Particle, dt float64) []*Particle {
+		given = len(all)
+		return all
+	}
+	m := newModel("effect")
+	e := newParticleEffect(m, effect, 10)
+	if e.update(m, 0.02, 0.25); given != 3 {
+		t.Errorf("Expected 3 particles, got %d", given)
+	}
+}
+
+// Check that large textures are shrunk to the quality texture size.
+func TestTextureQuality(t *testing.T) {
+	eng := newEngine(nil)
+	defer eng.Shutdown()
+	img := image.NewNRGBA(image.Rect(0, 0, 8, 4))
+	for x := 0; x < 8; x++ {
+		for y := 0; y < 4; y++ {
+			img.Set(x, y, color.NRGBA{R: uint8(200 * (1 - y/2)), A: 255}) // red over black.
+		}
+	}
+	eng.AddTexture("big", img)
+	q := eng.Quality()
+	q.TextureSize = 2
+	eng.SetQuality(q)
+	tex := newTexture("big")
+	if err := eng.loader.importTexture(tex); err != nil {
+		t.Fatal(err)
+	}
+	if b := tex.img.Bounds(); b.Dx() != 2 || b.Dy() != 1 {
+		t.Errorf("Expected 2x1 texture, got %dx%d", b.Dx(), b.Dy())
+	}
+	if r, _, _, a := tex.img.At(0, 0).RGBA(); r>>8 != 100 || a>>8 != 255 {
+		t.Errorf("Expected averaged pixel, got %d %d", r>>8, a>>8)
+	}
+	if shrink(img, 0) != img || shrink(img, 8) != img {
+		t.Errorf("Expected fitting images to be unchanged")
+	}
+	if b := shrink(image.NewNRGBA(image.Rect(0, 0, 5, 3)), 2).Bounds(); b.Dx() != 2 || b.Dy() != 1 {
+		t.Errorf("Expected odd sizes to round up, got %dx%d", b.Dx(), b.Dy())
+	}
+}
+
+// Check that post effects turn the lens depth of field on and off.
+func TestPostEffects(t *testing.T) {
+	c := newCamera()
+	c.SetLens(Lens{Focal: 50, SensorW: 36, SensorH: 24, FStop: 2.8, Focus: 4})
+	d := render.NewDraw()
+	uniforms := map[string]int32{"dof": 0}
+	if c.lensUniforms(d, uniforms, true); d.Floats("dof")[0] != 4 {
+		t.Errorf("Expected depth of field with post effects")
+	}
+	if c.lensUniforms(d, uniforms, false); d.Floats("dof")[0] != 0 {
+		t.Errorf("Expected no depth of field without post effects")
+	}
+	if QualityPreset(QualityLow).PostEffects || !QualityPreset(QualityHigh).PostEffects {
+		t.Errorf("Expected post effects off for low quality only")
+	}
+}
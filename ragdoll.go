@@ -0,0 +1,286 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"log"
+	"math"
+
+	"github.com/gazed/vu/math/lin"
+	"github.com/gazed/vu/physics"
+)
+
+// Ragdoll replaces the animation of a model with jointed physics bodies,
+// one capsule shaped limb for each given bone. Each limb runs from its
+// bone to the bone's first child and is held to the limb of its nearest
+// limbed parent bone with a cone joint. Bones without limbs follow the
+// limb of their nearest limbed parent. For example:
+//     rag := character.NewRagdoll(0.1, 2, "hips", "chest", "head",
+//         "arm.L", "forearm.L", "arm.R", "forearm.R",
+//         "thigh.L", "shin.L", "thigh.R", "shin.R")
+//     rag.Hinge("shin.L", 1, 0, 0, 0, 2.5).Hinge("shin.R", 1, 0, 0, 0, 2.5)
+// The limbs are top level Pov's with solid bodies that start with the
+// current animated pose and the speed of the character's body, if any.
+// A character body is left to the application, ie: dispose it so that
+// it doesn't collide with the limbs.
+type Ragdoll interface {
+	Limb(bone string) Pov // Limb for the bone, nil if the bone has no limb.
+
+	// Cone sets the swing and twist limits, in radians, of the joint
+	// between the bone's limb and its parent limb.
+	Cone(bone string, swing, twist float64) Ragdoll
+
+	// Hinge limits the joint between the bone's limb and its parent limb
+	// to rotate about the model space axis ax, ay, az, between lo and hi
+	// radians, ie: elbows and knees.
+	Hinge(bone string, ax, ay, az, lo, hi float64) Ragdoll
+
+	// Dispose removes the limbs. The model goes back to being animated.
+	Dispose()
+}
+
+// Default joint limits for ragdoll limbs.
+const (
+	ragdollSwing = math.Pi * 0.25 // Cone swing limit.
+	ragdollTwist = math.Pi * 0.1  // Cone twist limit.
+)
+
+// Ragdoll
+// =============================================================================
+// ragdoll implements Ragdoll.
+
+// ragdoll maps the limb bodies back onto the bones of an animated model.
+type ragdoll struct {
+	eng    *engine
+	owner  *pov            // Pov with the animated model.
+	m      *model          // Model posed by the limbs.
+	limbs  []*pov          // Limb for each limbed bone.
+	bones  []int           // Bone index for each limb.
+	joints []physics.Joint // Joint to the parent limb, nil if no parent.
+	drive  []int           // Index of the limb that moves each bone.
+	offs   []lin.M4        // Bone world transform relative to its limb.
+	ibases []lin.M4        // Inverse base pose for each bone.
+
+	// scratch values for updating poses.
+	lm, im, bm *lin.M4
+}
+
+// ragdoll entities.
+func (eng *engine) ragdoll(p Pov) Ragdoll {
+	if pv, ok := p.(*pov); ok && pv != nil {
+		if m, ok := eng.models.model(pv.eid); ok && m.rag != nil {
+			return m.rag
+		}
+	}
+	return nil
+}
+func (eng *engine) newRagdoll(p Pov, radius, mass float64, bones []string) Ragdoll {
+	pv, ok := p.(*pov)
+	if !ok || pv == nil {
+		return nil
+	}
+	m, ok := eng.models.model(pv.eid)
+	if !ok || m.rag != nil || m.anm == nil || !m.anm.loaded || len(bones) == 0 {
+		return nil
+	}
+	for _, bone := range bones {
+		if m.Joint(bone) < 0 {
+			log.Printf("NewRagdoll: unknown bone %s", bone)
+			return nil
+		}
+	}
+	m.rag = newRagdoll(eng, pv, m, radius, mass, bones)
+	return m.rag
+}
+
+// newRagdoll creates the limbs from the model's current pose.
+func newRagdoll(eng *engine, owner *pov, m *model, radius, mass float64, bones []string) *ragdoll {
+	r := &ragdoll{eng: eng, owner: owner, m: m}
+	r.lm, r.im, r.bm = &lin.M4{}, &lin.M4{}, &lin.M4{}
+	anm := m.anm
+	count := len(anm.bases)
+	if len(m.pose) < count {
+		m.pose = make([]lin.M4, count)
+	}
+
+	// Get the current world transform of each bone.
+	worlds := make([]lin.M4, count)
+	for bone := range worlds {
+		m.jointTransform(bone, &worlds[bone])
+		worlds[bone].Mult(&worlds[bone], owner.mm)
+	}
+	limbOf := make([]int, count) // limb index for each bone, -1 if none.
+	for bone := range limbOf {
+		limbOf[bone] = -1
+	}
+	for _, name := range bones {
+		if bone := m.Joint(name); limbOf[bone] < 0 {
+			limbOf[bone] = len(r.bones)
+			r.bones = append(r.bones, bone)
+		}
+	}
+
+	// Create a capsule limb from each limbed bone to its first child,
+	// preferring children with limbs.
+	var vx, vy, vz float64
+	for _, bone := range r.bones {
+		w := &worlds[bone]
+		end := -1
+		for child, parent := range anm.joints {
+			if int(parent) == bone && (end < 0 || limbOf[end] < 0 && limbOf[child] >= 0) {
+				end = child
+			}
+		}
+		vx, vy, vz = 0, 2*radius, 0 // leaf bone without a parent.
+		switch parent := anm.joints[bone]; {
+		case end >= 0:
+			e := &worlds[end]
+			vx, vy, vz = e.Wx-w.Wx, e.Wy-w.Wy, e.Wz-w.Wz
+		case parent >= 0:
+			pw := &worlds[parent]
+			px, py, pz := w.Wx-pw.Wx, w.Wy-pw.Wy, w.Wz-pw.Wz
+			if length := math.Sqrt(px*px + py*py + pz*pz); length > lin.Epsilon {
+				vx, vy, vz = px/length*2*radius, py/length*2*radius, pz/length*2*radius
+			}
+		}
+		length := math.Sqrt(vx*vx + vy*vy + vz*vz)
+		if length < lin.Epsilon {
+			vx, vy, vz, length = 0, 2*radius, 0, 2*radius
+		}
+		limb := eng.root().NewPov().SetLayers(owner.layers).(*pov)
+		limb.SetLocation(w.Wx+vx*0.5, w.Wy+vy*0.5, w.Wz+vz*0.5)
+		limb.SetRotation(alignY(vx/length, vy/length, vz/length))
+		limb.NewBody(NewCapsule(radius, math.Max(length*0.5-radius, 0)))
+		limb.SetSolid(mass, 0)
+		if b := eng.body(owner); b != nil {
+			limb.Body().Push(b.Speed())
+		}
+
+		// Hold the limb to its parent limb at the start of the bone.
+		var joint physics.Joint
+		if parent := r.parentLimb(bone, limbOf); parent >= 0 {
+			pbody, lbody := r.limbs[parent].Body(), limb.Body()
+			joint = physics.NewJoint(pbody, lbody, w.Wx, w.Wy, w.Wz, vx, vy, vz)
+			joint.SetCone(ragdollSwing, ragdollTwist)
+			eng.physics.Join(joint)
+		}
+		r.limbs = append(r.limbs, limb)
+		r.joints = append(r.joints, joint)
+	}
+
+	// Fix each bone to the limb that drives it.
+	r.drive = make([]int, count)
+	r.offs = make([]lin.M4, count)
+	r.ibases = make([]lin.M4, count)
+	for bone := range r.drive {
+		if r.drive[bone] = limbOf[bone]; r.drive[bone] < 0 {
+			if r.drive[bone] = r.parentLimb(bone, limbOf); r.drive[bone] < 0 {
+				r.drive[bone] = 0 // bones above the ragdoll follow the first limb.
+			}
+		}
+		limbMatrix(r.limbs[r.drive[bone]], r.lm)
+		r.offs[bone].Mult(&worlds[bone], r.im.Inv(r.lm))
+		r.ibases[bone].Inv(&anm.bases[bone])
+	}
+	return r
+}
+
+// parentLimb returns the limb of the nearest limbed parent of the given
+// bone, or -1 if there is none.
+func (r *ragdoll) parentLimb(bone int, limbOf []int) int {
+	for parent := r.m.anm.joints[bone]; parent >= 0; parent = r.m.anm.joints[parent] {
+		if limbOf[parent] >= 0 {
+			return limbOf[parent]
+		}
+	}
+	return -1
+}
+
+// limb returns the limb index for the named bone, or -1 if none.
+func (r *ragdoll) limb(name string) int {
+	bone := r.m.Joint(name)
+	for index, limbBone := range r.bones {
+		if limbBone == bone {
+			return index
+		}
+	}
+	return -1
+}
+
+// Implement Ragdoll.
+func (r *ragdoll) Limb(bone string) Pov {
+	if index := r.limb(bone); index >= 0 {
+		return r.limbs[index]
+	}
+	return nil
+}
+
+// Implement Ragdoll.
+func (r *ragdoll) Cone(bone string, swing, twist float64) Ragdoll {
+	if index := r.limb(bone); index >= 0 && r.joints[index] != nil {
+		r.joints[index].SetCone(swing, twist)
+	}
+	return r
+}
+
+// Implement Ragdoll. The model space axis is rotated into world space.
+func (r *ragdoll) Hinge(bone string, ax, ay, az, lo, hi float64) Ragdoll {
+	if index := r.limb(bone); index >= 0 && r.joints[index] != nil {
+		mm := r.owner.mm
+		wx := ax*mm.Xx + ay*mm.Yx + az*mm.Zx
+		wy := ax*mm.Xy + ay*mm.Yy + az*mm.Zy
+		wz := ax*mm.Xz + ay*mm.Yz + az*mm.Zz
+		r.joints[index].SetHinge(wx, wy, wz, lo, hi)
+	}
+	return r
+}
+
+// Implement Ragdoll. Safe to call more than once.
+func (r *ragdoll) Dispose() {
+	for index, limb := range r.limbs {
+		if joint := r.joints[index]; joint != nil {
+			r.eng.physics.Unjoin(joint)
+		}
+		if r.eng.povs[limb.eid] == limb {
+			r.eng.dispose(limb, PovNode)
+		}
+	}
+	r.limbs, r.joints = r.limbs[:0], r.joints[:0]
+	if r.m.rag == r {
+		r.m.rag = nil
+	}
+}
+
+// update maps the limb locations back onto the model pose. Each bone
+// world transform follows its limb and is then put back in model space:
+//    pose = inverseBasePose * boneToLimb * limb * inverseModelTransform
+func (r *ragdoll) update() {
+	if len(r.limbs) == 0 {
+		return
+	}
+	r.im.Inv(r.owner.mm)
+	for bone := range r.drive {
+		limbMatrix(r.limbs[r.drive[bone]], r.lm)
+		r.bm.Mult(&r.offs[bone], r.lm).Mult(r.bm, r.im)
+		r.m.pose[bone].Mult(&r.ibases[bone], r.bm)
+	}
+}
+
+// limbMatrix sets m to the world transform of the limb body.
+// Limbs are top level Pov's without scaling.
+func limbMatrix(limb *pov, m *lin.M4) {
+	q := limb.rot.Inv(limb.at.Rot)
+	m.SetQ(q)
+	l := limb.at.Loc
+	m.TranslateMT(l.X, l.Y, l.Z)
+}
+
+// alignY returns the rotation that turns the Y axis to the given
+// unit direction.
+func alignY(dx, dy, dz float64) *lin.Q {
+	if dy < -1+lin.Epsilon {
+		return &lin.Q{X: 1, Y: 0, Z: 0, W: 0} // half turn about X.
+	}
+	return (&lin.Q{X: dz, Y: 0, Z: -dx, W: 1 + dy}).Unit()
+}
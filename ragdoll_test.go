@@ -0,0 +1,83 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"testing"
+
+	"github.com/gazed/vu/math/lin"
+)
+
+// Check that limbs are built along the bones and that moving
+// the limbs moves the bones.
+func TestRagdoll(t *testing.T) {
+	eng := newEngine(nil)
+	defer eng.Shutdown()
+	body := eng.Root().NewPov().SetLocation(0, 0, -5)
+	if body.NewModel("anim"); body.NewRagdoll(0.1, 1, "hip") != nil {
+		t.Errorf("Expected no ragdoll without an animation")
+	}
+	m := body.Model().(*model)
+	m.anm = newAnimation("leg")
+	m.anm.setJoints([]string{"hip", "knee", "foot"}, []*lin.M4{
+		{Xx: 1, Yy: 1, Zz: 1, Ww: 1, Wy: 1},
+		{Xx: 1, Yy: 1, Zz: 1, Ww: 1, Wy: 0.5},
+		{Xx: 1, Yy: 1, Zz: 1, Ww: 1, Wy: 0},
+	})
+	m.anm.joints, m.anm.loaded = []int32{-1, 0, 1}, true
+	eng.placeModels(eng.root(), lin.M4I)
+	if body.NewRagdoll(0.1, 1, "hip", "ankle") != nil {
+		t.Errorf("Expected no ragdoll for unknown bones")
+	}
+	rag := body.NewRagdoll(0.1, 1, "hip", "knee")
+	if rag == nil || body.Ragdoll() != rag || body.NewRagdoll(0.1, 1, "hip") != nil {
+		t.Fatalf("Expected one ragdoll")
+	}
+	hip, knee := rag.Limb("hip"), rag.Limb("knee")
+	if hip == nil || knee == nil || rag.Limb("foot") != nil {
+		t.Fatalf("Expected hip and knee limbs")
+	}
+	if x, y, z := hip.Location(); !lin.Aeq(x, 0) || !lin.Aeq(y, 0.75) || !lin.Aeq(z, -5) {
+		t.Errorf("Expected hip limb at 0 0.75 -5 got %f %f %f", x, y, z)
+	}
+	if x, y, z := knee.Location(); !lin.Aeq(x, 0) || !lin.Aeq(y, 0.25) || !lin.Aeq(z, -5) {
+		t.Errorf("Expected knee limb at 0 0.25 -5 got %f %f %f", x, y, z)
+	}
+
+	// The starting pose matches the base pose.
+	m.rag.update()
+	for bone := range m.pose {
+		if !m.pose[bone].Aeq(lin.M4I) {
+			t.Errorf("Expected identity pose for bone %d got %+v", bone, m.pose[bone])
+		}
+	}
+
+	// Bones follow their limbs. The foot follows the knee limb.
+	knee.SetLocation(1, 0.25, -5)
+	m.rag.update()
+	if p := m.pose[0]; !p.Aeq(lin.M4I) {
+		t.Errorf("Expected hip to stay put %+v", p)
+	}
+	for _, bone := range []int{1, 2} {
+		if p := m.pose[bone]; !lin.Aeq(p.Wx, 1) || !lin.Aeq(p.Wy, 0) {
+			t.Errorf("Expected bone %d moved by 1 0 0 got %f %f", bone, p.Wx, p.Wy)
+		}
+	}
+
+	// Disposing the model removes the limbs.
+	body.Dispose(PovModel)
+	if _, ok := eng.povs[hip.(*pov).eid]; ok || body.Ragdoll() != nil {
+		t.Errorf("Expected limbs to be disposed")
+	}
+}
+
+// Check that the limb rotation turns Y to the bone direction.
+func TestAlignY(t *testing.T) {
+	for _, dir := range []lin.V3{{X: 1}, {Y: 1}, {Y: -1}, {X: 0.6, Z: -0.8}} {
+		v := &lin.V3{X: 0, Y: 1, Z: 0}
+		if v.MultQ(v, alignY(dir.X, dir.Y, dir.Z)); !v.Aeq(&dir) {
+			t.Errorf("Expected %+v got %+v", dir, *v)
+		}
+	}
+}
@@ -14,6 +14,11 @@ type Data interface {
 	Set(data interface{}) // Copy data in. Invalid types are logged.
 	Len() int             // Number of elements.
 	Size() uint32         // Number of bytes
+
+	// SetRange replaces existing data starting at vertex, or face index,
+	// from. Data that doesn't fit in the existing data is ignored and
+	// logged. Only the changed range is rebound to the GPU when possible.
+	SetRange(from int, data interface{})
 }
 
 // NewVertexData creates and specifies usage for a set of vertex data.
@@ -37,11 +42,13 @@ func NewVertexData(lloc, span, usage uint32, normalize bool) Data {
 
 // NewFaceData creates and specifies usagefor a set of triangle faces.
 // Triangle faces contain vertex indicies ordered to draw triangles.
-// Data can now be loaded and updated using Data.Set().
+// Data can now be loaded and updated using Data.Set() with either
+// []uint16 or, for meshes with more than 65536 verticies, []uint32.
 //     usage     : STATIC or DYNAMIC
 func NewFaceData(usage uint32) Data {
 	fd := &faceData{}
 	fd.data = []uint16{}
+	fd.wide = []uint32{}
 	fd.usage = usage
 	return fd
 }
@@ -65,6 +72,8 @@ type vertexData struct {
 	usage     uint32    // STATIC_DRAW, DYNAMIC_DRAW.
 	vcnt      int       // Number of verticies covered by this data.
 	rebind    bool      // Data was updated and needs GPU rebind.
+	lo, hi    int       // Changed float range. All data if hi is 0.
+	gpu       int       // Number of floats allocated on the GPU.
 	floats    []float32 // Vertex buffer arranged as [][span]float32
 	bytes     []byte    // Vertex buffer arranged as [][span]byte
 }
@@ -73,6 +82,7 @@ type vertexData struct {
 // the data as needed to be resent to the GPU.
 func (vd *vertexData) Set(data interface{}) {
 	vd.vcnt = 0
+	vd.lo, vd.hi = 0, 0 // rebind all data.
 	switch d := data.(type) {
 	case []float32:
 		vd.floats = vd.floats[:0]           // keep allocated memory.
@@ -89,6 +99,34 @@ func (vd *vertexData) Set(data interface{}) {
 	}
 }
 
+// SetRange copies float data over the existing data starting at the
+// given vertex. The changed range grows to cover any earlier changes
+// that have not yet been rebound.
+func (vd *vertexData) SetRange(from int, data interface{}) {
+	d, ok := data.([]float32)
+	lo := from * int(vd.span)
+	if !ok || lo < 0 || lo+len(d) > len(vd.floats) {
+		log.Printf("vertexData.SetRange: invalid data %T at %d", data, from)
+		return
+	}
+	copy(vd.floats[lo:], d)
+	hi := lo + len(d)
+	switch {
+	case vd.rebind && vd.hi == 0:
+		// all data already needs rebinding.
+	case vd.rebind:
+		if lo < vd.lo {
+			vd.lo = lo
+		}
+		if hi > vd.hi {
+			vd.hi = hi
+		}
+	default:
+		vd.lo, vd.hi = lo, hi
+	}
+	vd.rebind = true
+}
+
 // Size returns the current buffer data size in bytes.
 func (vd *vertexData) Size() uint32 {
 	if len(vd.floats) > 0 {
@@ -108,29 +146,59 @@ func (vd *vertexData) Len() int { return vd.vcnt }
 // faceData
 
 // faceData contains the vertex draw order. The values specify the
-// order the GPU should render/processes the vertex data.
+// order the GPU should render/processes the vertex data. A face buffer
+// holds one of uint16 or uint32 indicies, but not both.
 type faceData struct {
 	data   []uint16 // Vertex buffer arranged as [][span]uint16.
+	wide   []uint32 // Vertex buffer arranged as [][span]uint32.
 	ref    uint32   // Vertex GPU buffer reference.
 	usage  uint32   // STATIC_DRAW, DYNAMIC_DRAW.
 	rebind bool     // True when data has changed and needs rebinding.
 }
 
 // Set makes a copy of the given data, replacing any existing data, and marks
-// the data as needed to be rebinding. Data is expected as []uint16.
+// the data as needed to be rebinding. Data is expected as []uint16
+// or []uint32.
 func (fd *faceData) Set(data interface{}) {
 	switch d := data.(type) {
 	case []uint16:
 		fd.data = fd.data[:0]           // keep allocated memory.
 		fd.data = append(fd.data, d...) // copy in new data.
+		fd.wide = fd.wide[:0]           // only one index size.
+		fd.rebind = true                // Set to false when rebound.
+	case []uint32:
+		fd.wide = fd.wide[:0]           // keep allocated memory.
+		fd.wide = append(fd.wide, d...) // copy in new data.
+		fd.data = fd.data[:0]           // only one index size.
 		fd.rebind = true                // Set to false when rebound.
 	default:
 		log.Printf("faceData.Set: invalid data type %t", d)
 	}
 }
 
+// SetRange copies face indicies over the existing data starting
+// at the given face index. The indicies must be the same size as
+// the existing indicies. Face data is always completely rebound.
+func (fd *faceData) SetRange(from int, data interface{}) {
+	switch d := data.(type) {
+	case []uint16:
+		if from >= 0 && from+len(d) <= len(fd.data) {
+			copy(fd.data[from:], d)
+			fd.rebind = true
+			return
+		}
+	case []uint32:
+		if from >= 0 && from+len(d) <= len(fd.wide) {
+			copy(fd.wide[from:], d)
+			fd.rebind = true
+			return
+		}
+	}
+	log.Printf("faceData.SetRange: invalid data %T at %d", data, from)
+}
+
 // Size returns the size of the face data in bytes.
-func (fd *faceData) Size() uint32 { return uint32(len(fd.data)) * 2 }
+func (fd *faceData) Size() uint32 { return uint32(len(fd.data))*2 + uint32(len(fd.wide))*4 }
 
 // Len returns the number of face indicies.
-func (fd *faceData) Len() int { return len(fd.data) }
+func (fd *faceData) Len() int { return len(fd.data) + len(fd.wide) }
@@ -23,6 +23,7 @@ type Draw interface {
 	SetMvp(mvp *lin.M4)          // Model-View-Projection transform.
 	SetPm(pm *lin.M4)            // Projection matrix only.
 	SetDbm(dbm *lin.M4)          // Depth bias matrix for shadow maps.
+	SetIvm(ivm *lin.M4)          // Inverse view for world directions.
 	SetScale(sx, sy, sz float64) // Scaling, per axis.
 	SetPose(pose []lin.M4)       // Animation joint/bone transforms.
 
@@ -33,6 +34,12 @@ type Draw interface {
 	//   asTex : True to render to texture.
 	SetHints(bucket int, tocam float64, depth bool, fbo uint32)
 
+	// SetViewport sets the window area for drawing to the screen. The
+	// area is given as fractions of the window from the lower left.
+	// Draws are sorted by order first, so that each camera is drawn
+	// after the cameras with lower orders. Default 0, 0, 0, 1, 1.
+	SetViewport(order int, x, y, w, h float64)
+
 	// SetCounts for bound references
 	//   faces  : Number of triangles to be rendered.
 	//   verts  : Number of verticies to be rendered.
@@ -77,7 +84,9 @@ func NewDraw() Draw {
 	d.pm = &m4{}
 	d.nm = &m3{}
 	d.dbm = &m4{}
+	d.ivm = &m4{}
 	d.scale = &v3{1, 1, 1}
+	d.view = fullView
 	d.floats = map[string][]float32{} // Float uniform values.
 	return d
 }
@@ -102,6 +111,8 @@ type draw struct {
 	tocam  float64 // Distance to Camera.
 	depth  bool    // True to render with depth.
 	fbo    uint32  // Framebuffer id. 0 for default.
+	order  int     // Viewport draw order.
+	view   view    // Viewport window fractions.
 
 	// Shader uniform data.
 	uniforms map[string]int32     // Expected uniforms and shader references.
@@ -115,6 +126,7 @@ type draw struct {
 	pm    *m4    // Projection only.
 	nm    *m3    // Normal matrix
 	dbm   *m4    // Depth bias matrix for shadow maps.
+	ivm   *m4    // Inverse view, camera to world.
 	scale *v3    // Scale X, Y, Z
 	pose  []m34  // Per render frame of animation bone data.
 	tag   uint64 // Tag for application debugging.
@@ -125,6 +137,7 @@ func (d *draw) SetMv(mv *lin.M4)   { d.mv.tom4(mv) }
 func (d *draw) SetMvp(mvp *lin.M4) { d.mvp.tom4(mvp) }
 func (d *draw) SetPm(pm *lin.M4)   { d.pm.tom4(pm) }
 func (d *draw) SetDbm(dbm *lin.M4) { d.dbm.tom4(dbm) }
+func (d *draw) SetIvm(ivm *lin.M4) { d.ivm.tom4(ivm) }
 func (d *draw) SetScale(sx, sy, sz float64) {
 	d.scale.x, d.scale.y, d.scale.z = float32(sx), float32(sy), float32(sz)
 }
@@ -153,6 +166,17 @@ func (d *draw) SetHints(bucket int, toCam float64, depth bool, fbo uint32) {
 	d.bucket, d.tocam, d.depth, d.fbo = bucket, toCam, depth, fbo
 }
 
+// view is a viewport as x, y, width, height fractions of the window.
+type view [4]float32
+
+// fullView is the whole window.
+var fullView = view{0, 0, 1, 1}
+
+// SetViewport sets the camera draw order and window area.
+func (d *draw) SetViewport(order int, x, y, w, h float64) {
+	d.order, d.view = order, view{float32(x), float32(y), float32(w), float32(h)}
+}
+
 // SetRefs
 //   shader: Compiled, linked shader program reference.
 //   meshes: Vao buffer reference.
@@ -216,6 +240,9 @@ func (d draws) Len() int      { return len(d) }
 func (d draws) Swap(i, j int) { d[i], d[j] = d[j], d[i] }
 func (d draws) Less(i, j int) bool {
 	di, dj := d[i].(*draw), d[j].(*draw)
+	if di.order != dj.order {
+		return di.order < dj.order // Draw cameras in order.
+	}
 	if di.bucket != dj.bucket {
 		return di.bucket < dj.bucket // First sort into buckets.
 	}
@@ -227,7 +254,7 @@ func (d draws) Less(i, j int) bool {
 	return di.tag < dj.tag // Sort by eid.
 }
 
-// SortDraws sorts draw requests by buckets then by
+// SortDraws sorts draw requests by viewport order, buckets, then by
 // distance to camera, and finally by object creation order
 // with earlier objects rendered before later objects.
 func SortDraws(frame []Draw) { sort.Sort(draws(frame)) }
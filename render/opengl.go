@@ -15,15 +15,30 @@ import (
 // opengl is the OpenGL implemntation of Renderer. See the Renderer interface
 // for comments. See the OpenGL documentation for OpenGL methods and constants.
 type opengl struct {
-	depthTest bool   // Track current depth setting to reduce state switching.
-	shader    uint32 // Track the current shader to reduce shader switching.
-	fbo       uint32 // Track current framebuffer object to reduce switching.
-	vw, vh    int32  // Remember the viewport size for framebuffer switching.
+	depthTest bool       // Track current depth setting to reduce state switching.
+	shader    uint32     // Track the current shader to reduce shader switching.
+	fbo       uint32     // Track current framebuffer object to reduce switching.
+	vw, vh    int32      // Remember the viewport size for framebuffer switching.
+	view      view       // Current window viewport.
+	cleared   []view     // Partial viewports cleared this frame.
+	color     [4]float32 // Window clear color.
+
+	// framebuffer texture sizes are needed to set the viewport.
+	fbs map[uint32]int32 // Framebuffer size indexed by fbo.
+
+	// meshes with more than 65536 verticies use 32 bit face indicies.
+	wide map[uint32]bool // True for 32 bit face indicies, indexed by vao.
+
+	// cube map textures are bound to a different texture target.
+	cubes map[uint32]bool // True for cube map textures, indexed by tid.
 }
 
 // newRenderer returns an OpenGL implementation of Renderer.
 func newRenderer() Renderer {
 	gc := &opengl{}
+	gc.fbs = map[uint32]int32{}
+	gc.wide = map[uint32]bool{}
+	gc.cubes = map[uint32]bool{}
 	return gc
 }
 
@@ -47,11 +62,74 @@ func (gc *opengl) Init() error {
 }
 
 // Renderer implementation.
-func (gc *opengl) Color(r, g, b, a float32) { gl.ClearColor(r, g, b, a) }
-func (gc *opengl) Clear()                   { gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT) }
+func (gc *opengl) Color(r, g, b, a float32) {
+	gc.color = [4]float32{r, g, b, a}
+	gl.ClearColor(r, g, b, a)
+}
+func (gc *opengl) Clear() {
+	gc.bindFrame(0) // the window may not be the current framebuffer.
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+	gc.cleared = gc.cleared[:0]
+}
+
+// bindFrame switches to the given framebuffer, if necessary, and sets
+// the viewport to the whole framebuffer.
+func (gc *opengl) bindFrame(fbo uint32) {
+	if gc.fbo != fbo {
+		gl.BindFramebuffer(gl.FRAMEBUFFER, fbo)
+		gc.fbo = fbo
+		if fbo == 0 {
+			gc.viewport(fullView)
+		} else {
+			size := gc.fbs[fbo]
+			gl.Viewport(0, 0, size, size) // framebuffer textures are square.
+		}
+	}
+}
+
+// Renderer implementation.
+func (gc *opengl) ClearFrame(fbo uint32) {
+	gc.bindFrame(fbo)
+	gl.ClearColor(0, 0, 0, 0)
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+	gl.ClearColor(gc.color[0], gc.color[1], gc.color[2], gc.color[3])
+}
+
+// Renderer implementation.
+func (gc *opengl) ReadPixel(fbo uint32, x, y int) (r, g, b, a uint8) {
+	gc.bindFrame(fbo)
+	pixel := [4]uint8{}
+	gl.ReadPixels(int32(x), int32(y), 1, 1, gl.RGBA, gl.UNSIGNED_BYTE, gl.Pointer(&pixel[0]))
+	return pixel[0], pixel[1], pixel[2], pixel[3]
+}
 func (gc *opengl) Viewport(width int, height int) {
 	gc.vw, gc.vh = int32(width), int32(height)
 	gl.Viewport(0, 0, int32(width), int32(height))
+	gc.view = fullView
+}
+
+// viewport sets the window area for the following draws. Partial
+// window areas are cleared the first time they are used each frame
+// so that overlapping viewports, ie: picture-in-picture, are drawn
+// over the lower ordered viewports.
+func (gc *opengl) viewport(v view) {
+	gc.view = v
+	x0, y0 := int32(v[0]*float32(gc.vw)), int32(v[1]*float32(gc.vh))
+	x1, y1 := int32((v[0]+v[2])*float32(gc.vw)), int32((v[1]+v[3])*float32(gc.vh))
+	gl.Viewport(x0, y0, x1-x0, y1-y0)
+	if v == fullView {
+		return // cleared by Clear.
+	}
+	for _, cv := range gc.cleared {
+		if cv == v {
+			return
+		}
+	}
+	gc.cleared = append(gc.cleared, v)
+	gl.Enable(gl.SCISSOR_TEST)
+	gl.Scissor(x0, y0, x1-x0, y1-y0)
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+	gl.Disable(gl.SCISSOR_TEST)
 }
 
 // Renderer implementation.
@@ -104,12 +182,15 @@ func (gc *opengl) Render(dr Draw) {
 	if gc.fbo != d.fbo {
 		gl.BindFramebuffer(gl.FRAMEBUFFER, d.fbo)
 		if d.fbo == 0 {
-			gl.Viewport(0, 0, gc.vw, gc.vh)
+			gc.viewport(d.view)
 		} else {
 			gl.Clear(gl.DEPTH_BUFFER_BIT)
-			gl.Viewport(0, 0, 1024, 1024) // size convention for framebuffer texture.
+			size := gc.fbs[d.fbo]
+			gl.Viewport(0, 0, size, size) // framebuffer textures are square.
 		}
 		gc.fbo = d.fbo
+	} else if d.fbo == 0 && gc.view != d.view {
+		gc.viewport(d.view) // switch window areas only if necessary.
 	}
 
 	// switch shaders only if necessary.
@@ -124,10 +205,14 @@ func (gc *opengl) Render(dr Draw) {
 
 	// bind the data buffers and render.
 	gl.BindVertexArray(d.vao)
+	itype, isize := uint32(gl.UNSIGNED_SHORT), int64(2) // face index type and bytes.
+	if gc.wide[d.vao] {
+		itype, isize = gl.UNSIGNED_INT, 4
+	}
 	switch d.mode {
 	case Lines:
 		gl.PolygonMode(gl.FRONT_AND_BACK, gl.LINE)
-		gl.DrawElements(gl.LINES, d.numFaces, gl.UNSIGNED_SHORT, 0)
+		gl.DrawElements(gl.LINES, d.numFaces, itype, 0)
 		gl.PolygonMode(gl.FRONT_AND_BACK, gl.FILL)
 	case Points:
 		gl.Enable(gl.PROGRAM_POINT_SIZE)
@@ -142,12 +227,12 @@ func (gc *opengl) Render(dr Draw) {
 				gl.BindTexture(gl.TEXTURE_2D, tex.tid)
 				// fn is the number of triangles, 3 indicies per triangle.
 				// f0 is the offset in triangles where each triangle has 3 indicies
-				//    of 2 bytes (uShort) each.
-				gl.DrawElements(gl.TRIANGLES, tex.fn*3, gl.UNSIGNED_SHORT, int64(3*2*tex.f0))
+				//    of isize bytes each.
+				gl.DrawElements(gl.TRIANGLES, tex.fn*3, itype, 3*isize*int64(tex.f0))
 			}
 		} else {
 			// Single textures are handled with a standard bindUniforms
-			gl.DrawElements(gl.TRIANGLES, d.numFaces, gl.UNSIGNED_SHORT, 0)
+			gl.DrawElements(gl.TRIANGLES, d.numFaces, itype, 0)
 		}
 	}
 }
@@ -163,6 +248,8 @@ func (gc *opengl) bindUniforms(d *draw) {
 			gc.bindUniform(ref, x4, 1, d.mv.Pointer())
 		case "dbm":
 			gc.bindUniform(ref, x4, 1, d.dbm.Pointer())
+		case "ivm":
+			gc.bindUniform(ref, x4, 1, d.ivm.Pointer())
 		case "pm":
 			gc.bindUniform(ref, x4, 1, d.pm.Pointer())
 		case "nm":
@@ -285,6 +372,7 @@ func (gc *opengl) BindMesh(vao *uint32, vdata map[uint32]Data, fdata Data) error
 	if fd, ok := fdata.(*faceData); ok {
 		if fd.rebind {
 			gc.bindFaceBuffer(fd)
+			gc.wide[*vao] = len(fd.wide) > 0
 			fd.rebind = false
 		}
 	}
@@ -319,6 +407,12 @@ func (gc *opengl) bindVertexBuffer(vdata Data) {
 	case DynamicDraw:
 		var null gl.Pointer // zero.
 		switch {
+		case len(vd.floats) > 0 && vd.hi > 0 && vd.gpu >= len(vd.floats):
+			// Only copy the changed range into the existing buffer.
+			gl.BindBuffer(gl.ARRAY_BUFFER, vd.ref)
+			size := int64((vd.hi - vd.lo) * bytes)
+			gl.BufferSubData(gl.ARRAY_BUFFER, int64(vd.lo*bytes), size, gl.Pointer(&(vd.floats[vd.lo])))
+			gl.VertexAttribPointer(vd.lloc, vd.span, gl.FLOAT, false, 0, 0)
 		case len(vd.floats) > 0:
 			gl.BindBuffer(gl.ARRAY_BUFFER, vd.ref)
 
@@ -327,21 +421,28 @@ func (gc *opengl) bindVertexBuffer(vdata Data) {
 			gl.BufferData(gl.ARRAY_BUFFER, int64(cap(vd.floats)*bytes), null, vd.usage)
 			gl.BufferSubData(gl.ARRAY_BUFFER, 0, int64(len(vd.floats)*bytes), gl.Pointer(&(vd.floats[0])))
 			gl.VertexAttribPointer(vd.lloc, vd.span, gl.FLOAT, false, 0, 0)
+			vd.gpu = cap(vd.floats)
 		}
 	}
+	vd.lo, vd.hi = 0, 0 // next rebind is all data unless changed by SetRange.
 	gl.EnableVertexAttribArray(vd.lloc)
 }
 
 // bindFaceBuffer copies triangle face data from the CPU to the GPU.
 func (gc *opengl) bindFaceBuffer(fdata Data) {
 	fd := fdata.(*faceData)
-	if len(fd.data) > 0 {
+	if fd.Len() > 0 {
 		if fd.ref == 0 {
 			gl.GenBuffers(1, &fd.ref)
 		}
 		gl.BindBuffer(gl.ELEMENT_ARRAY_BUFFER, fd.ref)
-		bytes := 2 // 2 bytes for uint16 (gl.UNSIGNED_SHORT)
-		gl.BufferData(gl.ELEMENT_ARRAY_BUFFER, int64(len(fd.data)*bytes), gl.Pointer(&(fd.data[0])), fd.usage)
+		if len(fd.wide) > 0 {
+			// 4 bytes for uint32 (gl.UNSIGNED_INT)
+			gl.BufferData(gl.ELEMENT_ARRAY_BUFFER, int64(fd.Size()), gl.Pointer(&(fd.wide[0])), fd.usage)
+			return
+		}
+		// 2 bytes for uint16 (gl.UNSIGNED_SHORT)
+		gl.BufferData(gl.ELEMENT_ARRAY_BUFFER, int64(fd.Size()), gl.Pointer(&(fd.data[0])), fd.usage)
 	}
 }
 
@@ -379,12 +480,27 @@ func (gc *opengl) BindTexture(tid *uint32, img image.Image, repeat bool) (err er
 	}
 	gl.BindTexture(gl.TEXTURE_2D, *tid)
 
-	// FUTURE: check if RGBA, or NRGBA are alpha pre-multiplied. The docs say yes
-	// for RGBA but the data is from PNG files which are not pre-multiplied
-	// and the go png Decode looks like its reading values directly.
-	var ptr gl.Pointer
+	ptr, width, height, err := pixels(img)
+	if err != nil {
+		return err
+	}
+	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA, width, height, 0, gl.RGBA, gl.UNSIGNED_BYTE, ptr)
+	gl.GenerateMipmap(gl.TEXTURE_2D)
+	gc.setTextureMode(*tid, repeat)
+	if glerr := gl.GetError(); glerr != gl.NO_ERROR {
+		err = fmt.Errorf("Failed binding texture %d\n", glerr)
+	}
+	return err
+}
+
+// pixels returns the image data and size for binding.
+//
+// FUTURE: check if RGBA, or NRGBA are alpha pre-multiplied. The docs say yes
+// for RGBA but the data is from PNG files which are not pre-multiplied
+// and the go png Decode looks like its reading values directly.
+func pixels(img image.Image) (ptr gl.Pointer, width, height int32, err error) {
 	bounds := img.Bounds()
-	width, height := int32(bounds.Dx()), int32(bounds.Dy())
+	width, height = int32(bounds.Dx()), int32(bounds.Dy())
 	switch imgType := img.(type) {
 	case *image.RGBA:
 		i := img.(*image.RGBA)
@@ -393,13 +509,46 @@ func (gc *opengl) BindTexture(tid *uint32, img image.Image, repeat bool) (err er
 		i := img.(*image.NRGBA)
 		ptr = gl.Pointer(&(i.Pix[0]))
 	default:
-		return fmt.Errorf("Unsupported image format %T", imgType)
+		return nil, 0, 0, fmt.Errorf("Unsupported image format %T", imgType)
 	}
-	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA, width, height, 0, gl.RGBA, gl.UNSIGNED_BYTE, ptr)
-	gl.GenerateMipmap(gl.TEXTURE_2D)
-	gc.setTextureMode(*tid, repeat)
+	return ptr, width, height, nil
+}
+
+// BindCube uploads the six cube faces to one cube map texture.
+// Cube maps are sampled by direction so the edges are clamped
+// and filtering is seamless across the faces.
+func (gc *opengl) BindCube(tid *uint32, faces []image.Image) (err error) {
 	if glerr := gl.GetError(); glerr != gl.NO_ERROR {
-		err = fmt.Errorf("Failed binding texture %d\n", glerr)
+		log.Printf("opengl:bindCube need to find and fix prior error %X", glerr)
+	}
+	if len(faces) != 6 {
+		return fmt.Errorf("Cube map needs 6 faces, got %d", len(faces))
+	}
+	if *tid == 0 {
+		gl.GenTextures(1, tid)
+	}
+	gl.BindTexture(gl.TEXTURE_CUBE_MAP, *tid)
+	for cnt, face := range faces {
+		ptr, width, height, err := pixels(face)
+		if err != nil {
+			return err
+		}
+		if width != height || (cnt > 0 && face.Bounds().Size() != faces[0].Bounds().Size()) {
+			return fmt.Errorf("Cube map faces must be square and the same size")
+		}
+		target := gl.TEXTURE_CUBE_MAP_POSITIVE_X + uint32(cnt)
+		gl.TexImage2D(target, 0, gl.RGBA, width, height, 0, gl.RGBA, gl.UNSIGNED_BYTE, ptr)
+	}
+	gl.GenerateMipmap(gl.TEXTURE_CUBE_MAP)
+	gl.TexParameteri(gl.TEXTURE_CUBE_MAP, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_CUBE_MAP, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_CUBE_MAP, gl.TEXTURE_WRAP_R, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_CUBE_MAP, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
+	gl.TexParameteri(gl.TEXTURE_CUBE_MAP, gl.TEXTURE_MIN_FILTER, gl.LINEAR_MIPMAP_LINEAR)
+	gl.Enable(gl.TEXTURE_CUBE_MAP_SEAMLESS)
+	gc.cubes[*tid] = true
+	if glerr := gl.GetError(); glerr != gl.NO_ERROR {
+		err = fmt.Errorf("Failed binding cube map %d\n", glerr)
 	}
 	return err
 }
@@ -423,9 +572,9 @@ func (gc *opengl) setTextureMode(tid uint32, repeat bool) {
 // BindFrame creates a framebuffer object with an associated texture.
 //    http://www.opengl-tutorial.org/intermediate-tutorials/tutorial-14-render-to-texture/
 //    http://www.opengl-tutorial.org/intermediate-tutorials/tutorial-16-shadow-mapping/
-func (gc *opengl) BindFrame(buf int, fbo, tid, db *uint32) (err error) {
-	size := int32(1024)
+func (gc *opengl) BindFrame(buf int, size int32, fbo, tid, db *uint32) (err error) {
 	gl.GenFramebuffers(1, fbo)
+	gc.fbs[*fbo] = size
 	gl.BindFramebuffer(gl.FRAMEBUFFER, *fbo)
 
 	// Create a texture specifically for the framebuffer.
@@ -530,14 +679,25 @@ const (
 func (gc *opengl) useTexture(sampler, texUnit int32, tid uint32) {
 	gc.bindUniform(sampler, i1, 1, texUnit)
 	gl.ActiveTexture(gl.TEXTURE0 + uint32(texUnit))
+	if gc.cubes[tid] {
+		gl.BindTexture(gl.TEXTURE_CUBE_MAP, tid)
+		return
+	}
 	gl.BindTexture(gl.TEXTURE_2D, tid)
 }
 
 // Remove graphic resources.
-func (gc *opengl) ReleaseMesh(vao uint32)    { gl.DeleteVertexArrays(1, &vao) }
-func (gc *opengl) ReleaseShader(sid uint32)  { gl.DeleteProgram(sid) }
-func (gc *opengl) ReleaseTexture(tid uint32) { gl.DeleteTextures(1, &tid) }
+func (gc *opengl) ReleaseShader(sid uint32) { gl.DeleteProgram(sid) }
+func (gc *opengl) ReleaseTexture(tid uint32) {
+	delete(gc.cubes, tid)
+	gl.DeleteTextures(1, &tid)
+}
+func (gc *opengl) ReleaseMesh(vao uint32) {
+	delete(gc.wide, vao)
+	gl.DeleteVertexArrays(1, &vao)
+}
 func (gc *opengl) ReleaseFrame(fbo, tid, db uint32) {
+	delete(gc.fbs, fbo)
 	gl.DeleteFramebuffers(1, &fbo)
 	gl.DeleteTextures(1, &tid)
 	gl.DeleteRenderbuffers(1, &db)
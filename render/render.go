@@ -37,12 +37,23 @@ type Renderer interface {
 	BindTexture(tid *uint32, img image.Image, repeat bool) (err error)
 	Render(d Draw) // Render bound data and textures with bound shaders.
 
+	// BindCube creates a cube map texture from six square images of
+	// the same size in the order +X, -X, +Y, -Y, +Z, -Z. Shaders use
+	// cube map textures with a samplerCube uniform.
+	BindCube(tid *uint32, faces []image.Image) (err error)
+
 	// BindFrame creates a framebuffer object with an associated texture.
 	//   buf : DEPTH_BUFF, for depth, or IMAGE_BUFF, for color and depth.
+	//   size: texture width and height in pixels.
 	//   fbo : returned frame buffer object identifier.
 	//   tid : returned texture identifier.
 	//   db  : returned depth buffer render buffer.
-	BindFrame(buf int, fbo, tid, db *uint32) (err error)
+	BindFrame(buf int, size int32, fbo, tid, db *uint32) (err error)
+	ClearFrame(fbo uint32) // Clear a framebuffer color and depth to zero.
+
+	// ReadPixel returns the color of the pixel at x, y, from the lower
+	// left, in the given framebuffer. Framebuffer 0 is the window.
+	ReadPixel(fbo uint32, x, y int) (r, g, b, a uint8)
 
 	// Releasing frees up previous bound graphics card data.
 	ReleaseMesh(vao uint32)           // Free bound vao reference.
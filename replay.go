@@ -0,0 +1,119 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// Input recordings capture the user input of each update so that a run
+// can be replayed exactly, ie: for demo regression tests or for bug
+// reports that can be reproduced, ie:
+//     rec := eng.RecordInput(file) // in App.Create.
+//     ...
+//     rec.Close()                  // when done recording.
+// and later:
+//     play := eng.PlayInput(file)
+//     ...
+//     if play.Done() { ... }       // in App.Update.
+// Each update is written as one line of JSON holding the Input,
+// including the update tick and delta time. Since updates are
+// repeatable given the same inputs, see engine.update, playing the
+// input back repeats the recorded run. Playback replaces the device
+// input, except for window resizes, until the recording runs out.
+// The window should be the same size as the recorded window since
+// mouse and touch locations are in window pixels.
+//
+// Only one recording or playback is active at a time.
+
+// Recording is an input recording or playback started
+// by Eng.RecordInput or Eng.PlayInput.
+type Recording interface {
+	Ticks() int   // Updates recorded or played so far.
+	Done() bool   // True when closed or playback ran out of input.
+	Err() error   // First read or write error, nil if none.
+	Close() error // Stop recording or playing. Returns Err.
+}
+
+// replay implements Recording.
+type replay struct {
+	enc   *json.Encoder // Set when recording.
+	dec   *json.Decoder // Set when playing.
+	ticks int           // Updates recorded or played.
+	done  bool          // True once stopped.
+	err   error         // First read or write error.
+}
+
+// Implement Eng interface. Any previous recording or playback is closed.
+func (eng *engine) RecordInput(w io.Writer) Recording {
+	return eng.setReplay(&replay{enc: json.NewEncoder(w)})
+}
+func (eng *engine) PlayInput(r io.Reader) Recording {
+	return eng.setReplay(&replay{dec: json.NewDecoder(r)})
+}
+
+// setReplay makes r the active recording or playback.
+func (eng *engine) setReplay(r *replay) *replay {
+	if eng.replay != nil {
+		eng.replay.Close()
+	}
+	eng.replay = r
+	return r
+}
+
+// Implement Recording.
+func (r *replay) Ticks() int   { return r.ticks }
+func (r *replay) Done() bool   { return r.done }
+func (r *replay) Err() error   { return r.err }
+func (r *replay) Close() error { r.done = true; return r.err }
+
+// update records the input, or replaces it with the next recorded
+// input. Expected to be called each update before the input is used.
+// The recording stops on the first error.
+func (r *replay) update(in *Input) {
+	if r.done {
+		return
+	}
+	if r.enc != nil {
+		if r.err = r.enc.Encode(in); r.err != nil {
+			r.done = true
+			return
+		}
+		r.ticks++
+		return
+	}
+	frame := Input{}
+	if err := r.dec.Decode(&frame); err != nil {
+		if err != io.EOF {
+			r.err = err
+		}
+		r.done = true
+		return
+	}
+	in.replay(&frame)
+	r.ticks++
+}
+
+// replay copies the recorded input keeping the input settings and any
+// window resize. The recorded maps and slices are not shared with
+// anything else so they are used directly.
+func (in *Input) replay(frame *Input) {
+	resized := in.Resized
+	frame.sdz, frame.tdz, frame.gest = in.sdz, in.tdz, in.gest
+	frame.dtap, frame.hold, frame.taps, frame.twice = in.dtap, in.hold, in.taps, in.twice
+	*in = *frame
+	in.Resized = in.Resized || resized
+	if in.Down == nil {
+		in.Down = map[int]int{}
+	}
+	if in.Scan == nil {
+		in.Scan = map[int]int{}
+	}
+	for cnt := range in.Pads {
+		if in.Pads[cnt].Down == nil {
+			in.Pads[cnt].Down = map[int]int{}
+		}
+	}
+}
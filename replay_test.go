@@ -0,0 +1,54 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"bytes"
+	"testing"
+)
+
+// Check that recorded input plays back the same values and that
+// playback stops when the recording runs out.
+func TestRecordPlayInput(t *testing.T) {
+	eng := newEngine(nil)
+	defer eng.Shutdown()
+	buf := &bytes.Buffer{}
+	rec := eng.RecordInput(buf).(*replay)
+	in := newAppData().input
+	in.Mx, in.My, in.Dt, in.Ut = 10, 20, 0.02, 1
+	in.Down[KA] = 3
+	in.Pads = []Pad{{Connected: true, Down: map[int]int{PadA: 1}, Lx: 0.1}}
+	in.Touches = []Touch{{ID: 7, X: 1.5, Y: 2.5}}
+	rec.update(in)
+	in.Mx, in.Ut, in.Text = 11, 2, "é"
+	delete(in.Down, KA)
+	rec.update(in)
+	if rec.Close(); rec.Ticks() != 2 || rec.Err() != nil {
+		t.Fatalf("Expected 2 recorded updates, got %d %v", rec.Ticks(), rec.Err())
+	}
+
+	// play back into device input that differs from the recording.
+	play := eng.PlayInput(buf).(*replay)
+	got := newAppData().input
+	got.Mx, got.Resized = 99, true
+	got.Down[KB] = 1
+	play.update(got)
+	if got.Mx != 10 || got.My != 20 || got.Dt != 0.02 || got.Ut != 1 || got.Down[KA] != 3 || got.Down[KB] != 0 {
+		t.Errorf("Expected first recorded input, got %d %d %f %d %v", got.Mx, got.My, got.Dt, got.Ut, got.Down)
+	}
+	if len(got.Pads) != 1 || got.Pads[0].Down[PadA] != 1 || got.Pads[0].Lx != 0.1 || got.Touches[0].ID != 7 {
+		t.Errorf("Expected recorded pads and touches, got %v %v", got.Pads, got.Touches)
+	}
+	if !got.Resized || got.sdz != StickDeadZone {
+		t.Errorf("Expected device resize and dead zones to be kept")
+	}
+	play.update(got)
+	if got.Mx != 11 || got.Text != "é" || len(got.Down) != 0 {
+		t.Errorf("Expected second recorded input, got %d %q %v", got.Mx, got.Text, got.Down)
+	}
+	got.Mx = 42
+	if play.update(got); !play.Done() || play.Ticks() != 2 || got.Mx != 42 {
+		t.Errorf("Expected playback to end leaving device input")
+	}
+}
@@ -99,14 +99,15 @@ func (sm *scene) snapshot(eng *engine, frame []render.Draw) []render.Draw {
 // Note that the render layer target is updated on the camera here so that
 // it affects the relevant children, and not others.
 func (sm *scene) updateScene(eng *engine, rt uint32, cam *camera, p *pov, scene []*pov) []*pov {
-	if p.visible {
+	if p.visible && p.enabled {
 		culled := false // process children that aren't culled.
 
-		// only calculate distance for visible models.
-		if _, ok := eng.models[p.eid]; ok && cam != nil {
+		// only calculate distance for visible models. Models outside
+		// the camera layers are skipped, but not their children.
+		if _, ok := eng.models.model(p.eid); ok && cam != nil {
 			px, py, pz := sm.sceneLocation(p, cam.depth)
 			p.toc = cam.Distance(px, py, pz) // may not make sense for 2D screen objects.
-			if culled = cam.isCulled(px, py, pz); !culled {
+			if culled = cam.isCulled(px, py, pz); !culled && p.layers&cam.mask != 0 {
 				scene = append(scene, p)
 			}
 		} else {
@@ -168,7 +169,7 @@ func (sm *scene) updateFrame(eng *engine, viewed []*pov, frame []render.Draw) []
 		}
 
 		// render all models with loaded assets.
-		if model, ok := eng.models[p.eid]; ok && model.loaded() {
+		if model, ok := eng.models.model(p.eid); ok && model.loaded() {
 			if model.msh != nil && len(model.msh.vdata) > 0 {
 				var draw *render.Draw
 
@@ -200,6 +201,7 @@ func (sm *scene) updateFrame(eng *engine, viewed []*pov, frame []render.Draw) []
 					sm.toDraw(*draw, p, cam, model, cam.target)
 					model.toDraw(*draw, p.mm)
 					light.toDraw(*draw, lwx, lwy, lwz)
+					cam.lensUniforms(*draw, model.shd.uniforms, eng.quality.PostEffects)
 
 					// capture statistics.
 					sm.renDraws++                           // models rendered.
@@ -221,6 +223,7 @@ func (sm *scene) toDraw(d render.Draw, p *pov, cam *camera, m *model, rt uint32)
 	d.SetMv(sm.mv.Mult(p.mm, cam.vm))    // model-view
 	d.SetMvp(sm.mvp.Mult(sm.mv, cam.pm)) // model-view-projection
 	d.SetPm(cam.pm)                      // projection only.
+	d.SetIvm(cam.ivm)                    // inverse view for reflections.
 	d.SetScale(p.Scale())
 	d.SetTag(p.eid)
 
@@ -241,6 +244,7 @@ func (sm *scene) toDraw(d render.Draw, p *pov, cam *camera, m *model, rt uint32)
 		tocam = p.toc
 	}
 	d.SetHints(bucket, tocam, depth, rt)
+	d.SetViewport(cam.order, cam.view[0], cam.view[1], cam.view[2], cam.view[3])
 
 	// use the shadow map texture for models that show shadows.
 	if m.hasShadows {
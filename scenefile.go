@@ -0,0 +1,392 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+
+	"github.com/gazed/vu/math/lin"
+	"github.com/gazed/vu/physics"
+)
+
+// Scene files save a Pov hierarchy as JSON so that levels can be authored
+// once and loaded by name rather than rebuilt in App.Create code, ie:
+//     err := eng.SaveScene(level, file)         // write a hierarchy.
+//     level, err := eng.LoadScene("level1", eng.Root()) // models/level1.scn
+// Each node keeps its transform, visibility, model asset names, light,
+// camera, and physics body. Models reference assets by name so the assets
+// themselves are loaded as usual. Generated meshes and textures, ie: from
+// Surface or Foliage, are not saved and are expected to be recreated by the
+// application. Noises and layers are not saved.
+//
+// Prefabs use the same nodes to copy a hierarchy, ie:
+//     eng.Prefab("ball", ball)      // record a copy.
+//     b := eng.Spawn("ball", level) // add a new ball to level.
+
+// sceneVersion is incremented when the scene file format changes.
+const sceneVersion = 1
+
+// sceneFile is the top level of a scene file.
+type sceneFile struct {
+	Version int
+	Root    *sceneNode
+}
+
+// sceneNode is one Pov and its components.
+type sceneNode struct {
+	Loc      [3]float64   // Location.
+	Rot      [4]float64   // Quaternion X, Y, Z, W.
+	Scale    [3]float64   // Per axis scale.
+	Hidden   bool         `json:",omitempty"`
+	Disabled bool         `json:",omitempty"`
+	Name     string       `json:",omitempty"`
+	Tag      string       `json:",omitempty"`
+	Joint    string       `json:",omitempty"` // Parent model bone.
+	Layers   uint32       // Pov layer bits.
+	Model    *sceneModel  `json:",omitempty"`
+	Light    *[3]float64  `json:",omitempty"` // Light color.
+	Cam      *sceneCam    `json:",omitempty"`
+	Body     *sceneBody   `json:",omitempty"`
+	Children []*sceneNode `json:",omitempty"`
+}
+
+// sceneModel holds the model asset names and settings.
+type sceneModel struct {
+	Shader     string
+	Mesh       string               `json:",omitempty"`
+	Anim       string               `json:",omitempty"`
+	Material   string               `json:",omitempty"`
+	Textures   []sceneTex           `json:",omitempty"`
+	Font       string               `json:",omitempty"`
+	Phrase     string               `json:",omitempty"`
+	Alpha      float64              // Transparency.
+	Color      [3]float64           // Diffuse color.
+	DrawMode   int                  `json:",omitempty"`
+	NoDepth    bool                 `json:",omitempty"`
+	CastShadow bool                 `json:",omitempty"`
+	HasShadows bool                 `json:",omitempty"`
+	Uniforms   map[string][]float32 `json:",omitempty"`
+}
+
+// sceneTex is a texture asset name and mode.
+type sceneTex struct {
+	Name   string
+	Repeat bool `json:",omitempty"`
+}
+
+// sceneCam holds the camera location, orientation, and projection.
+type sceneCam struct {
+	Loc     [3]float64
+	Pitch   float64
+	Yaw     float64
+	Proj    []float64 `json:",omitempty"` // 4 perspective, 6 orthographic, or 16 custom.
+	UI      bool      `json:",omitempty"`
+	NoDepth bool      `json:",omitempty"`
+	Overlay int       `json:",omitempty"` // Render bucket from SetLast.
+	Mask    uint32    // Drawn Pov layers.
+	View    []float64 `json:",omitempty"` // Partial window viewport x, y, w, h.
+	Order   int       `json:",omitempty"` // Viewport draw order.
+	Ortho   []float64 `json:",omitempty"` // SetOrthoSize height, near, far.
+	Zoom    float64   `json:",omitempty"` // Ortho size zoom.
+	Lens    *Lens     `json:",omitempty"` // Physical camera settings.
+}
+
+// sceneBody holds the physics shape and material.
+type sceneBody struct {
+	Shape  string     // box, sphere, capsule, plane, or ray.
+	Size   [3]float64 // See physics.Dimensions.
+	Solid  bool       `json:",omitempty"`
+	Mass   float64    `json:",omitempty"`
+	Bounce float64    `json:",omitempty"`
+	Mask   uint32     // Collides with these layers.
+}
+
+// sceneShapes maps physics shape types to scene file names.
+var sceneShapes = map[int]string{
+	physics.BoxShape:     "box",
+	physics.SphereShape:  "sphere",
+	physics.CapsuleShape: "capsule",
+	physics.PlaneShape:   "plane",
+	physics.RayShape:     "ray",
+}
+
+// Implement Eng interface.
+func (eng *engine) SaveScene(p Pov, w io.Writer) error {
+	pv, ok := p.(*pov)
+	if !ok || pv == nil {
+		return fmt.Errorf("SaveScene: invalid pov")
+	}
+	data, err := json.MarshalIndent(&sceneFile{Version: sceneVersion, Root: eng.saveNode(pv)}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("SaveScene: %s", err)
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// Implement Eng interface.
+func (eng *engine) ReadScene(r io.Reader, parent Pov) (Pov, error) {
+	root, err := readScene(r)
+	if err != nil {
+		return nil, err
+	}
+	return eng.newScene(root, parent)
+}
+
+// readScene decodes a scene file returning the root node.
+func readScene(r io.Reader) (*sceneNode, error) {
+	sf := &sceneFile{}
+	if err := json.NewDecoder(r).Decode(sf); err != nil {
+		return nil, fmt.Errorf("ReadScene: %s", err)
+	}
+	if sf.Version != sceneVersion || sf.Root == nil {
+		return nil, fmt.Errorf("ReadScene: unsupported scene version %d", sf.Version)
+	}
+	return sf.Root, nil
+}
+
+// newScene creates a child of parent from the given scene nodes.
+// Nothing is added to parent if the nodes are invalid.
+func (eng *engine) newScene(root *sceneNode, parent Pov) (Pov, error) {
+	p := eng.newPov(parent)
+	if p == nil {
+		return nil, fmt.Errorf("ReadScene: invalid parent pov")
+	}
+	if err := eng.loadNode(p.(*pov), root); err != nil {
+		eng.dispose(p, PovNode)
+		return nil, err
+	}
+	return p, nil
+}
+
+// Implement Eng interface. Scene files are found with the model assets.
+func (eng *engine) LoadScene(name string, parent Pov) (Pov, error) {
+	file, err := eng.loader.ld.GetResource("models", name+".scn")
+	if err != nil {
+		return nil, fmt.Errorf("LoadScene: %s", err)
+	}
+	defer file.Close()
+	return eng.ReadScene(file, parent)
+}
+
+// saveNode converts a pov and its children to scene nodes.
+func (eng *engine) saveNode(p *pov) *sceneNode {
+	l, q, s := p.at.Loc, p.at.Rot, p.scale
+	n := &sceneNode{Hidden: !p.visible, Disabled: !p.enabled, Name: p.name, Tag: p.tag, Joint: p.joint, Layers: p.layers}
+	n.Loc = [3]float64{l.X, l.Y, l.Z}
+	n.Rot = [4]float64{q.X, q.Y, q.Z, q.W}
+	n.Scale = [3]float64{s.X, s.Y, s.Z}
+	if m, ok := eng.models.model(p.eid); ok {
+		n.Model = saveModel(m)
+	}
+	if l, ok := eng.lights[p.eid]; ok {
+		n.Light = &[3]float64{l.r, l.g, l.b}
+	}
+	if c, ok := eng.cams[p.eid]; ok {
+		n.Cam = &sceneCam{Pitch: c.xdeg, Yaw: c.ydeg, UI: c.ui, NoDepth: !c.depth, Overlay: c.overlay, Mask: c.mask, Order: c.order}
+		if c.view != [4]float64{0, 0, 1, 1} {
+			n.Cam.View = append([]float64{}, c.view[:]...)
+		}
+		n.Cam.Loc = [3]float64{c.at.Loc.X, c.at.Loc.Y, c.at.Loc.Z}
+		n.Cam.Proj = append([]float64{}, c.proj...)
+		if len(c.osize) == 3 {
+			n.Cam.Proj, n.Cam.Ortho, n.Cam.Zoom = nil, append([]float64{}, c.osize...), c.zoom
+		}
+		if l, ok := c.Lens(); ok {
+			n.Cam.Proj, n.Cam.Lens = nil, &l
+		}
+	}
+	if b := eng.body(p); b != nil {
+		_, solid := eng.solids.body(p.eid)
+		if shape, ok := sceneShapes[b.Shape().Type()]; ok {
+			sb := &sceneBody{Shape: shape, Solid: solid}
+			_, sb.Mask = b.Layers()
+			sb.Size[0], sb.Size[1], sb.Size[2] = physics.Dimensions(b.Shape())
+			if solid {
+				sb.Mass, sb.Bounce = b.Material()
+			}
+			n.Body = sb
+		}
+	}
+	for _, child := range p.children {
+		n.Children = append(n.Children, eng.saveNode(child))
+	}
+	return n
+}
+
+// saveModel returns the model asset references. Returns nil for
+// models with generated meshes since those can't be reloaded.
+func saveModel(m *model) *sceneModel {
+	if m.shd == nil || (m.msh != nil && m.msh.gen && m.fnt == nil) {
+		return nil
+	}
+	sm := &sceneModel{Shader: m.shd.name, Alpha: float64(m.alpha), DrawMode: m.drawMode}
+	sm.Color = [3]float64{float64(m.kd.R), float64(m.kd.G), float64(m.kd.B)}
+	sm.NoDepth, sm.CastShadow, sm.HasShadows = !m.depth, m.castShadow, m.hasShadows
+	switch {
+	case m.anm != nil:
+		sm.Anim = m.anm.name
+	case m.fnt != nil:
+		sm.Font, sm.Phrase = m.fnt.name, m.phrase
+	case m.msh != nil:
+		sm.Mesh = m.msh.name
+	}
+	if m.mat != nil {
+		sm.Material = m.mat.name
+	}
+	for _, t := range m.texs {
+		if t.gen || (m.layer != nil && t == m.layer.tex) || (m.anm != nil && t.name == m.anm.name+"0") {
+			continue // not an asset.
+		}
+		sm.Textures = append(sm.Textures, sceneTex{Name: t.name, Repeat: t.repeat})
+	}
+	if len(m.uniforms) > 0 {
+		sm.Uniforms = map[string][]float32{}
+		for id, values := range m.uniforms {
+			sm.Uniforms[id] = append([]float32{}, values...)
+		}
+	}
+	return sm
+}
+
+// loadNode sets the pov and creates its components and children
+// from the given scene node.
+func (eng *engine) loadNode(p *pov, n *sceneNode) error {
+	p.SetLocation(n.Loc[0], n.Loc[1], n.Loc[2])
+	p.at.Rot.SetS(n.Rot[0], n.Rot[1], n.Rot[2], n.Rot[3])
+	p.SetScale(n.Scale[0], n.Scale[1], n.Scale[2])
+	p.SetVisible(!n.Hidden)
+	p.SetEnabled(!n.Disabled)
+	p.SetName(n.Name).SetTag(n.Tag).SetJoint(n.Joint).SetLayers(n.Layers)
+	if n.Model != nil {
+		loadModel(p.NewModel(n.Model.Shader).(*model), n.Model)
+	}
+	if n.Light != nil {
+		p.NewLight().SetColor(n.Light[0], n.Light[1], n.Light[2])
+	}
+	if sc := n.Cam; sc != nil {
+		c := p.NewCam().(*camera)
+		if sc.UI {
+			c.SetUI()
+		}
+		c.depth, c.overlay, c.mask, c.order = !sc.NoDepth, sc.Overlay, sc.Mask, sc.Order
+		if v := sc.View; len(v) == 4 {
+			c.SetViewport(v[0], v[1], v[2], v[3])
+		}
+		c.SetLocation(sc.Loc[0], sc.Loc[1], sc.Loc[2])
+		c.SetPitch(sc.Pitch)
+		c.SetYaw(sc.Yaw)
+		switch pj := sc.Proj; len(pj) {
+		case 4:
+			c.SetPerspective(pj[0], pj[1], pj[2], pj[3])
+		case 6:
+			c.SetOrthographic(pj[0], pj[1], pj[2], pj[3], pj[4], pj[5])
+		case 16:
+			c.SetProjection(&lin.M4{
+				Xx: pj[0], Xy: pj[1], Xz: pj[2], Xw: pj[3], Yx: pj[4], Yy: pj[5], Yz: pj[6], Yw: pj[7],
+				Zx: pj[8], Zy: pj[9], Zz: pj[10], Zw: pj[11], Wx: pj[12], Wy: pj[13], Wz: pj[14], Ww: pj[15]})
+		}
+		if o := sc.Ortho; len(o) == 3 {
+			c.SetZoom(sc.Zoom)
+			c.SetOrthoSize(o[0], o[1], o[2])
+		}
+		if sc.Lens != nil {
+			c.SetLens(*sc.Lens)
+		}
+	}
+	if sb := n.Body; sb != nil {
+		var b physics.Body
+		x, y, z := sb.Size[0], sb.Size[1], sb.Size[2]
+		switch sb.Shape {
+		case "box":
+			b = NewBox(x, y, z)
+		case "sphere":
+			b = NewSphere(x)
+		case "capsule":
+			b = NewCapsule(x, y)
+		case "plane":
+			b = NewPlane(x, y, z)
+		case "ray":
+			b = NewRay(x, y, z)
+		default:
+			return fmt.Errorf("ReadScene: unknown body shape %s", sb.Shape)
+		}
+		p.NewBody(b).SetLayers(n.Layers, sb.Mask)
+		if sb.Solid {
+			p.SetSolid(sb.Mass, sb.Bounce)
+		}
+	}
+	for _, child := range n.Children {
+		if err := eng.loadNode(eng.newPov(p).(*pov), child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadModel requests the model assets from the given scene model.
+func loadModel(m *model, sm *sceneModel) {
+	switch {
+	case sm.Anim != "":
+		m.LoadAnim(sm.Anim)
+	case sm.Font != "":
+		m.LoadFont(sm.Font)
+	case sm.Mesh != "":
+		m.LoadMesh(sm.Mesh)
+	}
+	for _, t := range sm.Textures {
+		m.AddTex(t.Name)
+		if t.Repeat {
+			m.SetTexMode(len(m.texs)-1, TexRepeat)
+		}
+	}
+	if sm.Material != "" {
+		m.LoadMat(sm.Material)
+	}
+	if sm.Phrase != "" {
+		m.SetPhrase(sm.Phrase)
+	}
+	m.SetAlpha(sm.Alpha)
+	m.SetColor(sm.Color[0], sm.Color[1], sm.Color[2])
+	m.SetDrawMode(sm.DrawMode)
+	m.depth, m.castShadow, m.hasShadows = !sm.NoDepth, sm.CastShadow, sm.HasShadows
+	for id, values := range sm.Uniforms {
+		m.uniforms[id] = append([]float32{}, values...)
+	}
+}
+
+// Implement Eng interface. The prefab is a copy so p can be
+// changed or disposed afterwards.
+func (eng *engine) Prefab(name string, p Pov) {
+	if pv, ok := p.(*pov); ok && pv != nil {
+		eng.prefab[name] = eng.saveNode(pv)
+	}
+}
+
+// Implement Eng interface.
+func (eng *engine) Spawn(name string, parent Pov) Pov {
+	root, ok := eng.prefab[name]
+	if !ok {
+		file, err := eng.loader.ld.GetResource("models", name+".scn")
+		if err != nil {
+			log.Printf("Spawn: no prefab %s: %s", name, err)
+			return nil
+		}
+		defer file.Close()
+		if root, err = readScene(file); err != nil {
+			log.Printf("Spawn: prefab %s: %s", name, err)
+			return nil
+		}
+		eng.prefab[name] = root
+	}
+	p, err := eng.newScene(root, parent)
+	if err != nil {
+		log.Printf("Spawn: prefab %s: %s", name, err)
+		return nil
+	}
+	return p
+}
@@ -0,0 +1,117 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/gazed/vu/math/lin"
+	"github.com/gazed/vu/physics"
+)
+
+// TestScene saves a hierarchy, reads it back, and checks that saving
+// the loaded hierarchy gives the same scene file.
+func TestScene(t *testing.T) {
+	eng := newEngine(nil)
+	defer eng.Shutdown()
+	level := eng.Root().NewPov().SetLocation(1, 2, 3)
+	level.NewLight().SetColor(0.5, 0.6, 0.7)
+	c := level.NewCam()
+	c.SetPerspective(60, 1.5, 0.1, 50)
+	c.SetLocation(0, 5, 10)
+	c.SetPitch(20)
+	box := level.NewPov().SetScale(2, 2, 2)
+	box.Spin(0, 45, 0)
+	box.NewModel("diffuse").LoadMesh("box").LoadMat("red").AddTex("wood").SetTexMode(0, TexRepeat)
+	box.NewBody(NewBox(1, 2, 3))
+	box.SetSolid(5, 0.3)
+	ground := level.NewPov()
+	ground.SetVisible(false)
+	ground.NewModel("land").NewMesh("land") // generated: not saved.
+	ground.NewBody(NewPlane(0, 1, 0))
+	level.NewPov().NewBody(NewCapsule(0.5, 1))
+
+	save := &bytes.Buffer{}
+	if err := eng.SaveScene(level, save); err != nil {
+		t.Fatalf("Save failed: %s", err)
+	}
+	loaded, err := eng.ReadScene(bytes.NewReader(save.Bytes()), eng.Root())
+	if err != nil {
+		t.Fatalf("Read failed: %s", err)
+	}
+	resave := &bytes.Buffer{}
+	if eng.SaveScene(loaded, resave); resave.String() != save.String() {
+		t.Errorf("Expected same scene, got\n%s\nwant\n%s", resave, save)
+	}
+
+	// spot check the loaded hierarchy.
+	lp := loaded.(*pov)
+	if x, y, z := lp.Location(); x != 1 || y != 2 || z != 3 || len(lp.children) != 3 {
+		t.Errorf("Expected location 1,2,3 with 3 children, got %f %f %f %d", x, y, z, len(lp.children))
+	}
+	if pitch := lp.Cam().Pitch(); pitch != 20 {
+		t.Errorf("Expected camera pitch 20, got %f", pitch)
+	}
+	lbox, lground := lp.children[0], lp.children[1]
+	m := lbox.Model().(*model)
+	if m.Shader() != "diffuse" || m.msh.name != "box" || m.mat.name != "red" || !m.texs[0].repeat {
+		t.Errorf("Expected diffuse, box, red, repeating wood got %s %s %s", m.Shader(), m.msh.name, m.mat.name)
+	}
+	if mass, bounce := lbox.Body().Material(); !lin.Aeq(mass, 5) || bounce != 0.3 {
+		t.Errorf("Expected mass 5 and bounce 0.3, got %f %f", mass, bounce)
+	}
+	if lground.Visible() || lground.Model() != nil || lground.Body() == nil {
+		t.Errorf("Expected hidden ground body without a model")
+	}
+	if r, h, _ := physics.Dimensions(lp.children[2].Body().Shape()); r != 0.5 || h != 1 {
+		t.Errorf("Expected capsule 0.5 1, got %f %f", r, h)
+	}
+}
+
+// TestSceneErrors checks that bad scene files are rejected.
+func TestSceneErrors(t *testing.T) {
+	eng := newEngine(nil)
+	defer eng.Shutdown()
+	for _, scene := range []string{"", "{}", `{"Version":1,"Root":{"Body":{"Shape":"cone"}}}`} {
+		if _, err := eng.ReadScene(bytes.NewReader([]byte(scene)), eng.Root()); err == nil {
+			t.Errorf("Expected error for %q", scene)
+		}
+	}
+	if len(eng.root().children) != 0 {
+		t.Errorf("Expected failed scenes to be removed")
+	}
+}
+
+// TestPrefab checks that spawned copies match the prefab and that
+// the prefab is independent of the original Pov.
+func TestPrefab(t *testing.T) {
+	eng := newEngine(nil)
+	defer eng.Shutdown()
+	ball := eng.Root().NewPov().SetScale(2, 2, 2)
+	ball.NewBody(NewSphere(1))
+	ball.SetSolid(1, 0.5)
+	ball.NewModel("gouraud").LoadMesh("sphere").LoadMat("red")
+	ball.NewPov().SetLocation(0, 1, 0).NewModel("gouraud").LoadMesh("hat")
+	eng.Prefab("ball", ball)
+	ball.Dispose(PovNode)
+
+	b0, b1 := eng.Spawn("ball", eng.Root()), eng.Spawn("ball", eng.Root())
+	if b0 == nil || b1 == nil || b0 == b1 || len(eng.root().children) != 2 {
+		t.Fatalf("Expected two spawned balls")
+	}
+	b0.SetLocation(5, 0, 0)
+	if x, _, _ := b1.Location(); x != 0 {
+		t.Errorf("Expected independent copies")
+	}
+	if sx, _, _ := b1.Scale(); sx != 2 || b1.Body() == nil || b1.Body() == b0.Body() {
+		t.Errorf("Expected scaled copy with its own body")
+	}
+	if hat := b1.(*pov).children; len(hat) != 1 || hat[0].Model().(*model).msh.name != "hat" {
+		t.Errorf("Expected child parts to be copied")
+	}
+	if eng.Spawn("missing", eng.Root()) != nil {
+		t.Errorf("Expected nil for unknown prefab")
+	}
+}
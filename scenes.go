@@ -0,0 +1,51 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+// Scenes are independent top level hierarchies, ie: menu, loading, and
+// game world, that are switched or overlaid rather than culling parts
+// of one large hierarchy, ie:
+//     menu := eng.NewScene("menu")   // build, or LoadScene into, menu.
+//     game := eng.NewScene("game")   // ...
+//     eng.SwitchScene("game")        // only the game is active.
+//     eng.OverlayScene("menu", true) // menu over the game.
+// Each scene is a child of Eng.Root. Inactive scenes are disabled, see
+// Pov.SetEnabled, so they are not rendered and their bodies, components,
+// and noises are suspended. Active scenes are rendered in the order
+// they were created. Scene cameras can use Camera.SetLast to order
+// overlays.
+
+// Implement Eng interface. New scenes are active.
+func (eng *engine) NewScene(name string) Pov {
+	if p, ok := eng.scenes[name]; ok {
+		return p
+	}
+	p := eng.newPov(eng.root()).(*pov)
+	eng.scenes[name] = p
+	return p
+}
+
+// Implement Eng interface.
+func (eng *engine) Scene(name string) Pov {
+	if p, ok := eng.scenes[name]; ok {
+		return p
+	}
+	return nil
+}
+
+// Implement Eng interface.
+func (eng *engine) SwitchScene(name string) {
+	if _, ok := eng.scenes[name]; ok {
+		for sname, p := range eng.scenes {
+			p.SetEnabled(sname == name)
+		}
+	}
+}
+
+// Implement Eng interface.
+func (eng *engine) OverlayScene(name string, show bool) {
+	if p, ok := eng.scenes[name]; ok {
+		p.SetEnabled(show)
+	}
+}
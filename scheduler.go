@@ -0,0 +1,228 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"container/heap"
+	"time"
+)
+
+// The scheduler runs application functions later, or repeatedly, on the
+// update goroutine. This replaces countdown fields in application code,
+// ie:
+//     eng.After(2*time.Second, func() { door.SetVisible(false) })
+//     id := eng.Every(time.Second, spawnEnemy)
+//     eng.Run(new(vu.Sequence).Do(open).Wait(time.Second).Do(close).Next)
+//     eng.Cancel(id)
+// Scheduled functions are called once per update after App.Update and
+// the components update. Time is game time. It advances with each update
+// so that long updates don't skip scheduled functions. Functions due in
+// the same update are called in the order they were scheduled.
+
+// Step is one resumable step of a sequence. It returns how long to wait
+// before calling the step again, or done true to stop.
+type Step func() (wait time.Duration, done bool)
+
+// timer is a scheduled function.
+type timer struct {
+	id    int     // Unique timer id used to Cancel.
+	at    float64 // Game time in seconds to call the function.
+	every float64 // Seconds between calls for repeating timers.
+	fn    func()  // Timer function, or ...
+	step  Step    // ... sequence step.
+	dead  bool    // True once finished or cancelled.
+}
+
+// timers is a min heap of timers ordered by time,
+// then by creation. Implements heap.Interface.
+type timers []*timer
+
+func (t timers) Len() int { return len(t) }
+func (t timers) Less(i, j int) bool {
+	return t[i].at < t[j].at || (t[i].at == t[j].at && t[i].id < t[j].id)
+}
+func (t timers) Swap(i, j int)       { t[i], t[j] = t[j], t[i] }
+func (t *timers) Push(x interface{}) { *t = append(*t, x.(*timer)) }
+func (t *timers) Pop() interface{} {
+	old := *t
+	x := old[len(old)-1]
+	old[len(old)-1] = nil
+	*t = old[:len(old)-1]
+	return x
+}
+
+// scheduler tracks the timers waiting to be called.
+type scheduler struct {
+	now     float64        // Game time in seconds.
+	waiting timers         // Timers ordered by call time.
+	ids     map[int]*timer // Scheduled timers by id.
+	due     []*timer       // Scratch timers due this update.
+	nid     int            // Last timer id.
+}
+
+// newScheduler creates a scheduler without any timers.
+func newScheduler() *scheduler { return &scheduler{ids: map[int]*timer{}} }
+
+// schedule adds a timer to the scheduler, returning the timer id.
+func (s *scheduler) schedule(t *timer) int {
+	s.nid++
+	t.id = s.nid
+	s.ids[t.id] = t
+	heap.Push(&s.waiting, t)
+	return t.id
+}
+
+// cancel stops the timer with the given id.
+func (s *scheduler) cancel(id int) {
+	if t, ok := s.ids[id]; ok {
+		t.dead = true
+		delete(s.ids, id)
+	}
+}
+
+// update advances game time and calls the timers that are due. Each
+// timer is called at most once per update. Timers scheduled during the
+// update are called no earlier than the next update.
+func (s *scheduler) update(dts float64) {
+	s.now += dts
+	s.due = s.due[:0]
+	for len(s.waiting) > 0 && s.waiting[0].at <= s.now {
+		s.due = append(s.due, heap.Pop(&s.waiting).(*timer))
+	}
+	for _, t := range s.due {
+		if t.dead {
+			continue // cancelled.
+		}
+		switch {
+		case t.step != nil:
+			wait, done := t.step()
+			if done || t.dead {
+				s.finish(t)
+				continue
+			}
+			t.at = s.now + wait.Seconds()
+			heap.Push(&s.waiting, t)
+		case t.every > 0:
+			t.fn()
+			if t.dead {
+				continue
+			}
+			t.at += t.every
+			if t.at <= s.now {
+				t.at = s.now + t.every // fell behind: skip missed calls.
+			}
+			heap.Push(&s.waiting, t)
+		default:
+			s.finish(t)
+			t.fn()
+		}
+	}
+	for cnt := range s.due {
+		s.due[cnt] = nil // release for garbage collection.
+	}
+}
+
+// finish removes a timer that won't be called again.
+func (s *scheduler) finish(t *timer) {
+	t.dead = true
+	delete(s.ids, t.id)
+}
+
+// Implement Eng interface.
+func (eng *engine) After(wait time.Duration, fn func()) int {
+	if fn == nil {
+		return 0
+	}
+	s := eng.timers
+	return s.schedule(&timer{at: s.now + wait.Seconds(), fn: fn})
+}
+
+// Implement Eng interface. Intervals less than a millisecond are
+// treated as a millisecond.
+func (eng *engine) Every(interval time.Duration, fn func()) int {
+	if fn == nil {
+		return 0
+	}
+	if interval < time.Millisecond {
+		interval = time.Millisecond
+	}
+	s := eng.timers
+	every := interval.Seconds()
+	return s.schedule(&timer{at: s.now + every, every: every, fn: fn})
+}
+
+// Implement Eng interface.
+func (eng *engine) Run(step Step) int {
+	if step == nil {
+		return 0
+	}
+	s := eng.timers
+	return s.schedule(&timer{at: s.now, step: step})
+}
+
+// Implement Eng interface.
+func (eng *engine) Cancel(id int) { eng.timers.cancel(id) }
+
+// Sequence
+// ===========================================================================
+
+// Sequence is a list of steps that are run one after the other by
+// Eng.Run using Sequence.Next. Sequences are built using Do, Wait,
+// and Until, ie:
+//     seq := new(vu.Sequence).Do(fadeOut).Wait(time.Second).Do(fadeIn)
+//     eng.Run(seq.Next)
+type Sequence struct {
+	steps []seqStep // Steps in run order.
+	at    int       // Index of the next step.
+}
+
+// seqStep is one step in a sequence.
+type seqStep struct {
+	fn    func()        // Call once, or ...
+	wait  time.Duration // ... wait, or ...
+	until func() bool   // ... wait until true.
+}
+
+// Do adds a function call to the sequence.
+func (s *Sequence) Do(fn func()) *Sequence {
+	s.steps = append(s.steps, seqStep{fn: fn})
+	return s
+}
+
+// Wait adds a pause to the sequence.
+func (s *Sequence) Wait(wait time.Duration) *Sequence {
+	s.steps = append(s.steps, seqStep{wait: wait})
+	return s
+}
+
+// Until adds a pause to the sequence that continues once the
+// given function returns true. The function is checked each update.
+func (s *Sequence) Until(cond func() bool) *Sequence {
+	s.steps = append(s.steps, seqStep{until: cond})
+	return s
+}
+
+// Next runs the sequence up to the next pause. Next is expected to
+// be the Step given to Eng.Run.
+func (s *Sequence) Next() (wait time.Duration, done bool) {
+	for s.at < len(s.steps) {
+		step := s.steps[s.at]
+		switch {
+		case step.until != nil:
+			if !step.until() {
+				return 0, false // check again next update.
+			}
+			s.at++
+		case step.fn != nil:
+			s.at++
+			step.fn()
+		default:
+			s.at++
+			if step.wait > 0 {
+				return step.wait, false
+			}
+		}
+	}
+	return 0, true
+}
@@ -0,0 +1,58 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestScheduler checks delayed, repeating, and cancelled timers.
+func TestScheduler(t *testing.T) {
+	eng := newEngine(nil)
+	defer eng.Shutdown()
+	got := []string{}
+	eng.After(30*time.Millisecond, func() { got = append(got, "a") })
+	every := eng.Every(20*time.Millisecond, func() { got = append(got, "e") })
+	gone := eng.After(10*time.Millisecond, func() { got = append(got, "x") })
+	eng.Cancel(gone)
+	for cnt := 0; cnt < 4; cnt++ {
+		eng.timers.update(0.02)
+	}
+	eng.Cancel(every)
+	eng.timers.update(0.02)
+	if s := strings.Join(got, ""); s != "eaeee" {
+		t.Errorf("Expected eaeee got %s", s)
+	}
+	if len(eng.timers.ids) != 0 {
+		t.Errorf("Expected no scheduled timers, got %d", len(eng.timers.ids))
+	}
+}
+
+// TestSequence checks that sequences resume after each pause.
+func TestSequence(t *testing.T) {
+	eng := newEngine(nil)
+	defer eng.Shutdown()
+	got, ready := []string{}, false
+	seq := new(Sequence).Do(func() { got = append(got, "1") })
+	seq.Wait(50 * time.Millisecond).Do(func() { got = append(got, "2") })
+	seq.Until(func() bool { return ready }).Do(func() { got = append(got, "3") })
+	eng.Run(seq.Next)
+	eng.timers.update(0.02)
+	eng.timers.update(0.02)
+	if s := strings.Join(got, ""); s != "1" {
+		t.Errorf("Expected 1 got %s", s)
+	}
+	eng.timers.update(0.02)
+	eng.timers.update(0.02)
+	if s := strings.Join(got, ""); s != "12" {
+		t.Errorf("Expected 12 got %s", s)
+	}
+	ready = true
+	eng.timers.update(0.02)
+	if s := strings.Join(got, ""); s != "123" || len(eng.timers.ids) != 0 {
+		t.Errorf("Expected finished 123 got %s", s)
+	}
+}
@@ -0,0 +1,207 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package script
+
+import (
+	"github.com/gazed/vu"
+)
+
+// bind.go exposes entities to scripts. Scripts refer to entities using
+// handles. A handle of 0, or nil, is no entity. Functions given an
+// invalid handle do nothing and return nil. The functions are:
+//     self() handle                    -- the scripted entity.
+//     find(name) handle                -- see vu.Eng.Find.
+//     pov_location(h) x, y, z          -- see vu.Pov.Location.
+//     pov_set_location(h, x, y, z)     -- see vu.Pov.SetLocation.
+//     pov_world(h) x, y, z             -- see vu.Pov.World.
+//     pov_scale(h) x, y, z             -- see vu.Pov.Scale.
+//     pov_set_scale(h, x, y, z)        -- see vu.Pov.SetScale.
+//     pov_spin(h, x, y, z)             -- see vu.Pov.Spin.
+//     pov_move(h, x, y, z)             -- move along the entity rotation.
+//     pov_visible(h) bool              -- see vu.Pov.Visible.
+//     pov_set_visible(h, bool)         -- see vu.Pov.SetVisible.
+//     model_alpha(h) a                 -- see vu.Model.Alpha.
+//     model_set_alpha(h, a)            -- see vu.Model.SetAlpha.
+//     model_color(h) r, g, b           -- see vu.Model.Color.
+//     model_set_color(h, r, g, b)      -- see vu.Model.SetColor.
+//     cam_location(h) x, y, z          -- see vu.Camera.Location.
+//     cam_set_location(h, x, y, z)     -- see vu.Camera.SetLocation.
+//     cam_pitch(h) deg                 -- see vu.Camera.Pitch.
+//     cam_set_pitch(h, deg)            -- see vu.Camera.SetPitch.
+//     cam_yaw(h) deg                   -- see vu.Camera.Yaw.
+//     cam_set_yaw(h, deg)              -- see vu.Camera.SetYaw.
+//     key_down(key) ticks              -- vu.Input.Down for a vu.K* key.
+//     mouse() x, y                     -- vu.Input mouse location.
+
+// bind registers the entity functions with the script interpreter.
+func (s *scripts) bind(sc *script) {
+	vm := sc.vm
+	vm.Register("self", func(args []interface{}) []interface{} {
+		return vals(s.handle(sc.p))
+	})
+	vm.Register("find", func(args []interface{}) []interface{} {
+		if name, ok := arg(args, 0).(string); ok {
+			if p := s.eng.Find(name); p != nil {
+				return vals(s.handle(p))
+			}
+		}
+		return nil
+	})
+
+	// Pov functions.
+	s.pov(vm, "pov_location", func(p vu.Pov, args []interface{}) []interface{} {
+		return vals(p.Location())
+	})
+	s.pov(vm, "pov_set_location", func(p vu.Pov, args []interface{}) []interface{} {
+		p.SetLocation(num(args, 1), num(args, 2), num(args, 3))
+		return nil
+	})
+	s.pov(vm, "pov_world", func(p vu.Pov, args []interface{}) []interface{} {
+		return vals(p.World())
+	})
+	s.pov(vm, "pov_scale", func(p vu.Pov, args []interface{}) []interface{} {
+		return vals(p.Scale())
+	})
+	s.pov(vm, "pov_set_scale", func(p vu.Pov, args []interface{}) []interface{} {
+		p.SetScale(num(args, 1), num(args, 2), num(args, 3))
+		return nil
+	})
+	s.pov(vm, "pov_spin", func(p vu.Pov, args []interface{}) []interface{} {
+		p.Spin(num(args, 1), num(args, 2), num(args, 3))
+		return nil
+	})
+	s.pov(vm, "pov_move", func(p vu.Pov, args []interface{}) []interface{} {
+		p.Move(num(args, 1), num(args, 2), num(args, 3), p.Rotation())
+		return nil
+	})
+	s.pov(vm, "pov_visible", func(p vu.Pov, args []interface{}) []interface{} {
+		return vals(p.Visible())
+	})
+	s.pov(vm, "pov_set_visible", func(p vu.Pov, args []interface{}) []interface{} {
+		visible, _ := arg(args, 1).(bool)
+		p.SetVisible(visible)
+		return nil
+	})
+
+	// Model functions.
+	s.model(vm, "model_alpha", func(m vu.Model, args []interface{}) []interface{} {
+		return vals(m.Alpha())
+	})
+	s.model(vm, "model_set_alpha", func(m vu.Model, args []interface{}) []interface{} {
+		m.SetAlpha(num(args, 1))
+		return nil
+	})
+	s.model(vm, "model_color", func(m vu.Model, args []interface{}) []interface{} {
+		return vals(m.Color())
+	})
+	s.model(vm, "model_set_color", func(m vu.Model, args []interface{}) []interface{} {
+		m.SetColor(num(args, 1), num(args, 2), num(args, 3))
+		return nil
+	})
+
+	// Camera functions.
+	s.cam(vm, "cam_location", func(c vu.Camera, args []interface{}) []interface{} {
+		return vals(c.Location())
+	})
+	s.cam(vm, "cam_set_location", func(c vu.Camera, args []interface{}) []interface{} {
+		c.SetLocation(num(args, 1), num(args, 2), num(args, 3))
+		return nil
+	})
+	s.cam(vm, "cam_pitch", func(c vu.Camera, args []interface{}) []interface{} {
+		return vals(c.Pitch())
+	})
+	s.cam(vm, "cam_set_pitch", func(c vu.Camera, args []interface{}) []interface{} {
+		c.SetPitch(num(args, 1))
+		return nil
+	})
+	s.cam(vm, "cam_yaw", func(c vu.Camera, args []interface{}) []interface{} {
+		return vals(c.Yaw())
+	})
+	s.cam(vm, "cam_set_yaw", func(c vu.Camera, args []interface{}) []interface{} {
+		c.SetYaw(num(args, 1))
+		return nil
+	})
+
+	// Input functions.
+	vm.Register("key_down", func(args []interface{}) []interface{} {
+		if s.in == nil {
+			return vals(0)
+		}
+		return vals(s.in.Down[int(num(args, 0))])
+	})
+	vm.Register("mouse", func(args []interface{}) []interface{} {
+		if s.in == nil {
+			return vals(0, 0)
+		}
+		return vals(s.in.Mx, s.in.My)
+	})
+}
+
+// pov registers a function whose first argument is an entity handle.
+func (s *scripts) pov(vm Interpreter, name string, fn func(p vu.Pov, args []interface{}) []interface{}) {
+	vm.Register(name, func(args []interface{}) []interface{} {
+		if p, ok := s.povs[int(num(args, 0))]; ok {
+			return fn(p, args)
+		}
+		return nil
+	})
+}
+
+// model registers a function for the model of an entity handle.
+func (s *scripts) model(vm Interpreter, name string, fn func(m vu.Model, args []interface{}) []interface{}) {
+	s.pov(vm, name, func(p vu.Pov, args []interface{}) []interface{} {
+		if m := p.Model(); m != nil {
+			return fn(m, args)
+		}
+		return nil
+	})
+}
+
+// cam registers a function for the camera of an entity handle.
+func (s *scripts) cam(vm Interpreter, name string, fn func(c vu.Camera, args []interface{}) []interface{}) {
+	s.pov(vm, name, func(p vu.Pov, args []interface{}) []interface{} {
+		if c := p.Cam(); c != nil {
+			return fn(c, args)
+		}
+		return nil
+	})
+}
+
+// arg returns the indexed argument or nil if there is no such argument.
+func arg(args []interface{}, index int) interface{} {
+	if index < len(args) {
+		return args[index]
+	}
+	return nil
+}
+
+// num returns the indexed argument as a number, or 0
+// if the argument is not a number.
+func num(args []interface{}, index int) float64 {
+	switch n := arg(args, index).(type) {
+	case float64:
+		return n
+	case float32:
+		return float64(n)
+	case int:
+		return float64(n)
+	case int64:
+		return float64(n)
+	}
+	return 0
+}
+
+// vals converts script results to script values, numbers
+// become float64.
+func vals(results ...interface{}) []interface{} {
+	for index, r := range results {
+		switch n := r.(type) {
+		case int:
+			results[index] = float64(n)
+		case float32:
+			results[index] = float64(n)
+		}
+	}
+	return results
+}
@@ -0,0 +1,92 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+// +build lua
+// Lua Interpreter using github.com/yuin/gopher-lua. Build with -tags lua.
+
+package script
+
+import (
+	"strings"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// NewLua returns a Lua Interpreter. It is only available when built
+// with the lua tag, ie:
+//     scripts := script.New(eng, script.NewLua)
+func NewLua() Interpreter { return &luaVM{state: lua.NewState()} }
+
+// luaVM implements Interpreter by wrapping a Lua state.
+type luaVM struct {
+	state *lua.LState
+}
+
+// Register implements Interpreter.
+func (vm *luaVM) Register(name string, fn Func) {
+	vm.state.SetGlobal(name, vm.state.NewFunction(func(L *lua.LState) int {
+		args := make([]interface{}, L.GetTop())
+		for index := range args {
+			args[index] = fromLua(L.Get(index + 1))
+		}
+		results := fn(args)
+		for _, result := range results {
+			L.Push(toLua(result))
+		}
+		return len(results)
+	}))
+}
+
+// Load implements Interpreter.
+func (vm *luaVM) Load(name, source string) error {
+	fn, err := vm.state.Load(strings.NewReader(source), name)
+	if err != nil {
+		return err
+	}
+	vm.state.Push(fn)
+	return vm.state.PCall(0, lua.MultRet, nil)
+}
+
+// Call implements Interpreter.
+func (vm *luaVM) Call(fn string, args ...interface{}) error {
+	f := vm.state.GetGlobal(fn)
+	if f.Type() != lua.LTFunction {
+		return nil // scripts only define the functions they need.
+	}
+	largs := make([]lua.LValue, len(args))
+	for index, arg := range args {
+		largs[index] = toLua(arg)
+	}
+	return vm.state.CallByParam(lua.P{Fn: f, NRet: 0, Protect: true}, largs...)
+}
+
+// Close implements Interpreter.
+func (vm *luaVM) Close() { vm.state.Close() }
+
+// fromLua converts a Lua value to a script value.
+func fromLua(v lua.LValue) interface{} {
+	switch lv := v.(type) {
+	case lua.LNumber:
+		return float64(lv)
+	case lua.LString:
+		return string(lv)
+	case lua.LBool:
+		return bool(lv)
+	}
+	return nil
+}
+
+// toLua converts a script value to a Lua value.
+func toLua(v interface{}) lua.LValue {
+	switch sv := v.(type) {
+	case float64:
+		return lua.LNumber(sv)
+	case int:
+		return lua.LNumber(sv)
+	case string:
+		return lua.LString(sv)
+	case bool:
+		return lua.LBool(sv)
+	}
+	return lua.LNil
+}
@@ -0,0 +1,49 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+// +build lua
+// Run with: go test -tags lua
+
+package script
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestLua checks that a Lua update drives a Pov through the bindings.
+func TestLua(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "script")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "mover.lua")
+	ioutil.WriteFile(path, []byte(`
+function attach()
+    pov_set_location(self(), 0, 2, 3)
+end
+function update(dt)
+    local x, y, z = pov_location(self())
+    pov_set_location(self(), x+dt, y, z)
+end
+`), 0644)
+
+	s := New(nil, NewLua).SetReload(0)
+	p := &testPov{}
+	c := s.Attach(p, path)
+	if c == nil {
+		t.Fatalf("Expected attached script")
+	}
+	c.Update(0.25)
+	c.Update(0.5)
+	if x, y, z := p.Location(); x != 0.75 || y != 2 || z != 3 {
+		t.Errorf("Expected 0.75 2 3 got %f %f %f", x, y, z)
+	}
+	c.OnDispose()
+
+	// bad scripts are not attached.
+	ioutil.WriteFile(path, []byte("function update(dt"), 0644)
+	if s.Attach(&testPov{}, path) != nil {
+		t.Errorf("Expected no script for a syntax error")
+	}
+}
@@ -0,0 +1,200 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+// Package script attaches scripts to vu entities so that behaviour
+// can be changed without recompiling the application. Scripts are
+// run by an Interpreter. A Lua Interpreter, NewLua, wraps gopher-lua
+// and is included by building with the lua tag, ie:
+//     go get github.com/yuin/gopher-lua
+//     go build -tags lua
+// Otherwise the application supplies its own Interpreter. Each scripted
+// entity has its own Interpreter. Script files are reloaded when they
+// change.
+//
+// A script defines any of the following functions which are called
+// by the script component:
+//     attach()     -- once when the script is attached.
+//     update(dt)   -- each update with the delta time in seconds.
+//     reload()     -- after the script file is reloaded.
+//     dispose()    -- once when the script is removed.
+// Scripts control entities using the functions in bind.go, ie:
+//     function update(dt)
+//         pov_spin(self(), 0, 90*dt, 0)
+//     end
+//
+// Package script is provided as part of the vu (virtual universe) 3D engine.
+package script
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"time"
+
+	"github.com/gazed/vu"
+)
+
+// Interpreter runs scripts. See NewLua, or implement it using the
+// script language of choice.
+// Numbers are passed to and from scripts as float64, text as string,
+// booleans as bool, and no value as nil.
+type Interpreter interface {
+	Register(name string, fn Func)             // Expose fn to scripts.
+	Load(name, source string) error            // Run script source.
+	Call(fn string, args ...interface{}) error // Ignores undefined fn.
+	Close()                                    // Release resources.
+}
+
+// Func is a Go function called by scripts.
+type Func func(args []interface{}) (results []interface{})
+
+// Scripts attaches script components to entities and provides the
+// engine state used by the script bindings.
+type Scripts interface {
+
+	// Attach loads the script file and adds it to p as a component.
+	// Returns nil if the script could not be loaded.
+	Attach(p vu.Pov, path string) vu.Component
+
+	// SetInput is expected to be called each App.Update so that
+	// scripts can check the user input.
+	SetInput(in *vu.Input)
+
+	// SetReload sets how often script files are checked for changes.
+	// Zero turns off reloading. Default is one second.
+	SetReload(interval time.Duration) Scripts
+}
+
+// New creates a script manager for the engine. The vm function
+// is called to create an Interpreter for each attached script.
+func New(eng vu.Eng, vm func() Interpreter) Scripts {
+	return &scripts{eng: eng, vm: vm, reload: 1, ids: map[vu.Pov]int{}, povs: map[int]vu.Pov{}}
+}
+
+// scripts implements Scripts.
+type scripts struct {
+	eng    vu.Eng             // Engine for finding entities.
+	vm     func() Interpreter // Creates script interpreters.
+	in     *vu.Input          // Latest user input.
+	reload float64            // Seconds between script file checks.
+	ids    map[vu.Pov]int     // Script handles by entity.
+	povs   map[int]vu.Pov     // Entities by script handle.
+	nid    int                // Last script handle.
+}
+
+// Implement Scripts.
+func (s *scripts) SetInput(in *vu.Input) { s.in = in }
+func (s *scripts) SetReload(interval time.Duration) Scripts {
+	s.reload = interval.Seconds()
+	return s
+}
+
+// Implement Scripts.
+func (s *scripts) Attach(p vu.Pov, path string) vu.Component {
+	src, mod, err := read(path)
+	if err != nil {
+		log.Printf("script.Attach %s: %s", path, err)
+		return nil
+	}
+	sc := &script{s: s, p: p, path: path, mod: mod, vm: s.vm()}
+	s.bind(sc)
+	if err = sc.vm.Load(path, src); err != nil {
+		log.Printf("script.Attach %s: %s", path, err)
+		sc.vm.Close()
+		return nil
+	}
+	p.AddComponent(sc)
+	return sc
+}
+
+// handle returns the script handle for p, creating one if necessary.
+func (s *scripts) handle(p vu.Pov) int {
+	if id, ok := s.ids[p]; ok {
+		return id
+	}
+	s.nid++
+	s.ids[p], s.povs[s.nid] = s.nid, p
+	return s.nid
+}
+
+// release removes the script handle for p.
+func (s *scripts) release(p vu.Pov) {
+	if id, ok := s.ids[p]; ok {
+		delete(s.ids, p)
+		delete(s.povs, id)
+	}
+}
+
+// read returns the script source and when it was last modified.
+func read(path string) (src string, mod time.Time, err error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", mod, err
+	}
+	bytes, err := ioutil.ReadFile(path)
+	return string(bytes), info.ModTime(), err
+}
+
+// script
+// ===========================================================================
+
+// script is a per-entity script component. Implements vu.Component.
+type script struct {
+	s     *scripts    // Script manager.
+	vm    Interpreter // Runs the script.
+	p     vu.Pov      // Scripted entity.
+	path  string      // Script file.
+	mod   time.Time   // Script file modification time.
+	check float64     // Seconds since the file was checked.
+}
+
+// Implement vu.Component.
+func (sc *script) OnAttach(p vu.Pov) {
+	sc.p = p
+	sc.call("attach")
+}
+
+// Implement vu.Component.
+func (sc *script) Update(dt float64) {
+	if sc.s.reload > 0 {
+		if sc.check += dt; sc.check >= sc.s.reload {
+			sc.check = 0
+			sc.reload()
+		}
+	}
+	sc.call("update", dt)
+}
+
+// Implement vu.Component.
+func (sc *script) OnDispose() {
+	sc.call("dispose")
+	sc.vm.Close()
+	sc.s.release(sc.p)
+}
+
+// reload loads the script again if the file has changed. Errors are
+// logged and the previously loaded script continues to run.
+func (sc *script) reload() {
+	info, err := os.Stat(sc.path)
+	if err != nil || !info.ModTime().After(sc.mod) {
+		return
+	}
+	src, mod, err := read(sc.path)
+	if err != nil {
+		log.Printf("script reload %s: %s", sc.path, err)
+		return
+	}
+	sc.mod = mod
+	if err = sc.vm.Load(sc.path, src); err != nil {
+		log.Printf("script reload %s: %s", sc.path, err)
+		return
+	}
+	sc.call("reload")
+}
+
+// call runs a script function, logging any script errors.
+func (sc *script) call(fn string, args ...interface{}) {
+	if err := sc.vm.Call(fn, args...); err != nil {
+		log.Printf("script %s %s: %s", sc.path, fn, err)
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package script
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gazed/vu"
+)
+
+// TestScript checks the script calls, bindings, and reloading.
+func TestScript(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "script")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "door.lua")
+	ioutil.WriteFile(path, []byte("v1"), 0644)
+
+	vm := &testVM{fns: map[string]Func{}}
+	s := New(nil, func() Interpreter { return vm }).SetReload(time.Second)
+	p := &testPov{}
+	c := s.Attach(p, path)
+	if c == nil || len(p.comps) != 1 || vm.src != "v1" {
+		t.Fatalf("Expected attached script")
+	}
+
+	// bindings work with the handle of the scripted entity.
+	self := vm.fns["self"](nil)[0]
+	vm.fns["pov_set_location"]([]interface{}{self, 1.0, 2, 3})
+	if x, y, z := p.Location(); x != 1 || y != 2 || z != 3 {
+		t.Errorf("Expected 1 2 3 got %f %f %f", x, y, z)
+	}
+	if r := vm.fns["pov_location"]([]interface{}{99.0}); r != nil {
+		t.Errorf("Expected nothing for invalid handle")
+	}
+
+	// changed scripts are reloaded.
+	ioutil.WriteFile(path, []byte("v2"), 0644)
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(path, later, later)
+	c.Update(0.5)
+	if vm.src != "v1" {
+		t.Errorf("Expected no reload before check interval")
+	}
+	c.Update(0.5)
+	if vm.src != "v2" {
+		t.Errorf("Expected reload got %s", vm.src)
+	}
+	c.OnDispose()
+	if calls := strings.Join(vm.calls, " "); calls != "attach update reload update dispose" || !vm.closed {
+		t.Errorf("Unexpected calls %s", calls)
+	}
+}
+
+// testVM records script calls. Implements Interpreter.
+type testVM struct {
+	fns    map[string]Func
+	src    string
+	calls  []string
+	closed bool
+}
+
+func (vm *testVM) Register(name string, fn Func)  { vm.fns[name] = fn }
+func (vm *testVM) Load(name, source string) error { vm.src = source; return nil }
+func (vm *testVM) Close()                         { vm.closed = true }
+func (vm *testVM) Call(fn string, args ...interface{}) error {
+	vm.calls = append(vm.calls, fn)
+	return nil
+}
+
+// testPov tracks location and components. Implements vu.Pov.
+type testPov struct {
+	vu.Pov  // unused methods panic.
+	x, y, z float64
+	comps   []vu.Component
+}
+
+func (p *testPov) Location() (x, y, z float64) { return p.x, p.y, p.z }
+func (p *testPov) SetLocation(x, y, z float64) vu.Pov {
+	p.x, p.y, p.z = x, y, z
+	return p
+}
+func (p *testPov) AddComponent(c vu.Component) {
+	p.comps = append(p.comps, c)
+	c.OnAttach(p)
+}
@@ -86,17 +86,24 @@ func (s *shader) ensureNewLines() {
 // by a unique name. These provide some basic shaders to get simple examples
 // running quickly and can be used as starting templates for new shaders.
 var shaderLibrary = map[string]func() (vsh, fsh []string){
-	"solid":   solidShader,
-	"alpha":   alphaShader,
-	"diffuse": diffuseShader,
-	"gouraud": gouraudShader,
-	"phong":   phongShader,
-	"uv":      uvShader,
-	"bb":      bbShader,
-	"bbr":     bbrShader,
-	"anim":    animShader,
-	"depth":   depthShader,
-	"shadow":  shadowShader,
+	"solid":     solidShader,
+	"alpha":     alphaShader,
+	"diffuse":   diffuseShader,
+	"gouraud":   gouraudShader,
+	"phong":     phongShader,
+	"uv":        uvShader,
+	"bb":        bbShader,
+	"bbr":       bbrShader,
+	"anim":      animShader,
+	"pbr":       pbrShader,
+	"pbra":      pbraShader,
+	"sky":       skyShader,
+	"skyeq":     skyeqShader,
+	"reflect":   reflectShader,
+	"reflecteq": reflecteqShader,
+	"depth":     depthShader,
+	"shadow":    shadowShader,
+	"pick":      pickShader,
 }
 
 // FUTURE: Add edge-detect and emboss shaders, see:
@@ -445,6 +452,278 @@ func animShader() (vsh, fsh []string) {
 
 // =============================================================================
 
+// pbrShader lights metallic-roughness materials, ie: from glTF files.
+// The model textures are uv0:base color, uv1:metallic-roughness,
+// uv2:normal, uv3:occlusion, and uv4:emissive. Normal textures are
+// only used for meshes with tangents.
+func pbrShader() (vsh, fsh []string) {
+	vsh = []string{
+		"#version 330",
+		"layout(location=0) in vec3 in_v;",  // verticies
+		"layout(location=1) in vec3 in_n;",  // vertex normals
+		"layout(location=2) in vec2 in_t;",  // texture coordinates
+		"layout(location=6) in vec4 in_x;",  // vertex tangents
+		"layout(location=7) in vec2 in_t1;", // second texture coordinates
+		"",
+		"uniform mat4  mvpm;", // model view projection matrix
+		"uniform mat4  mvm;",  // model view matrix
+		"uniform mat3  nm;",   // normal matrix
+		"uniform vec4  l;",    // untransformed light position
+		"out   vec3  v_n;",    // vertex normal
+		"out   vec4  v_x;",    // vertex tangent and handedness
+		"out   vec3  v_s;",    // vector from vertex to light.
+		"out   vec3  v_e;",    // vertex eye position.
+		"out   vec2  t_uv;",   // pass uv coordinates through
+		"out   vec2  t_uv1;",  // ditto
+		"void main() {",
+		"   vec4 vpos = vec4(in_v, 1.0);",
+		"   vec4 eyeCoords = mvm * vpos;",
+		"   v_n = normalize(nm * in_n);",
+		"   v_x = vec4(nm * in_x.xyz, in_x.w);",
+		"   v_s = normalize(vec3(l - eyeCoords));",
+		"   v_e = normalize(-eyeCoords.xyz);",
+		"   t_uv = in_t;",
+		"   t_uv1 = in_t1;",
+		"   gl_Position = mvpm * vpos;",
+		"}",
+	}
+	return vsh, pbrFragment()
+}
+
+// pbraShader is the skeletal animation version of pbrShader.
+func pbraShader() (vsh, fsh []string) {
+	vsh = []string{
+		"#version 330",
+		"layout(location=0) in vec3 in_v;",   // verticies
+		"layout(location=1) in vec3 in_n;",   // vertex normals
+		"layout(location=2) in vec2 in_t;",   // texture coordinates
+		"layout(location=4) in vec4 joint;",  // joint indicies
+		"layout(location=5) in vec4 weight;", // joint weights
+		"layout(location=6) in vec4 in_x;",   // vertex tangents
+		"layout(location=7) in vec2 in_t1;",  // second texture coordinates
+		"",
+		"uniform mat3x4 bpos[100];", // bone positioning transforms. Row-Major!
+		"uniform mat4   mvpm;",      // model view projection matrix
+		"uniform mat4   mvm;",       // model view matrix
+		"uniform mat3   nm;",        // normal matrix
+		"uniform vec4   l;",         // untransformed light position
+		"out   vec3  v_n;",          // vertex normal
+		"out   vec4  v_x;",          // vertex tangent and handedness
+		"out   vec3  v_s;",          // vector from vertex to light.
+		"out   vec3  v_e;",          // vertex eye position.
+		"out   vec2  t_uv;",         // pass uv coordinates through
+		"out   vec2  t_uv1;",        // ditto
+		"void main() {",
+		"   mat3x4 m = bpos[int(joint.x)] * weight.x;", // up to four joints affect vertex.
+		"   m += bpos[int(joint.y)] * weight.y;",
+		"   m += bpos[int(joint.z)] * weight.z;",
+		"   m += bpos[int(joint.w)] * weight.w;",
+		"   vec4 vpos = vec4(vec4(in_v, 1.0) * m, 1.0);", // Row-Major pre-multiply.
+		"   vec4 eyeCoords = mvm * vpos;",
+		"   v_n = normalize(nm * (vec4(in_n, 0.0) * m));",
+		"   v_x = vec4(nm * (vec4(in_x.xyz, 0.0) * m), in_x.w);",
+		"   v_s = normalize(vec3(l - eyeCoords));",
+		"   v_e = normalize(-eyeCoords.xyz);",
+		"   t_uv = in_t;",
+		"   t_uv1 = in_t1;",
+		"   gl_Position = mvpm * vpos;",
+		"}",
+	}
+	return vsh, pbrFragment()
+}
+
+// pbrFragment is the fragment shader shared by pbr and pbra.
+// It uses a single light and the material uniforms:
+//    kd, alpha: base color and alpha.
+//    mr       : metallic, roughness.
+//    ao       : occlusion strength, occlusion uv set 0 or 1.
+//    ke       : emissive color.
+//    cutoff   : alpha mask, 0 for none.
+func pbrFragment() []string {
+	return []string{
+		"#version 330",
+		"in      vec3      v_n;",            // interpolated normal
+		"in      vec4      v_x;",            // interpolated tangent
+		"in      vec3      v_s;",            // interpolated vector from vertex to light.
+		"in      vec3      v_e;",            // interpolated vector from eye to vertex.
+		"in      vec2      t_uv;",           // interpolated uv coordinates
+		"in      vec2      t_uv1;",          // ditto
+		"uniform sampler2D uv0;",            // base color
+		"uniform sampler2D uv1;",            // metallic-roughness
+		"uniform sampler2D uv2;",            // normals
+		"uniform sampler2D uv3;",            // occlusion
+		"uniform sampler2D uv4;",            // emissive
+		"uniform vec3      ld;",             // light source intensity
+		"uniform vec3      kd;",             // base color
+		"uniform float     alpha;",          // transparency
+		"uniform vec2      mr;",             // metallic, roughness
+		"uniform vec2      ao;",             // occlusion strength, uv set
+		"uniform vec3      ke;",             // emissive color
+		"uniform float     cutoff;",         // alpha mask
+		"const   vec3      la = vec3(0.3);", // FUTURE make la a uniform.
+		"const   float     pi = 3.14159265;",
+		"out     vec4      ffc;", // final fragment color
+		"void main() {",
+		"   vec4 base = texture(uv0, t_uv) * vec4(kd, alpha);",
+		"   if (cutoff > 0.0) {",
+		"      if (base.a < cutoff) discard;",
+		"      base.a = 1.0;",
+		"   }",
+		"   vec4 mrt = texture(uv1, t_uv);",
+		"   float metal = mr.x * mrt.b;",
+		"   float rough = clamp(mr.y * mrt.g, 0.04, 1.0);",
+		"   vec3 n = normalize(v_n);",
+		"   if (dot(v_x.xyz, v_x.xyz) > 0.0) {", // tangent space normals.
+		"      vec3 t = normalize(v_x.xyz - n * dot(n, v_x.xyz));",
+		"      vec3 b = cross(n, t) * v_x.w;",
+		"      n = normalize(mat3(t, b, n) * (texture(uv2, t_uv).xyz * 2.0 - 1.0));",
+		"   }",
+		"   vec3 s = normalize(v_s);",
+		"   vec3 e = normalize(v_e);",
+		"   vec3 h = normalize(s + e);",
+		"   float nDotL = max(dot(n, s), 0.0);",
+		"   float nDotV = max(dot(n, e), 0.001);",
+		"   float nDotH = max(dot(n, h), 0.0);",
+		"   float a2 = rough * rough * rough * rough;",
+		"   float d = nDotH * nDotH * (a2 - 1.0) + 1.0;", // GGX distribution.
+		"   float k = (rough + 1.0) * (rough + 1.0) / 8.0;",
+		"   float g = nDotV / (nDotV * (1.0 - k) + k) * nDotL / (nDotL * (1.0 - k) + k);",
+		"   vec3 f0 = mix(vec3(0.04), base.rgb, metal);",
+		"   vec3 f = f0 + (1.0 - f0) * pow(1.0 - max(dot(h, e), 0.0), 5.0);", // Schlick fresnel.
+		"   vec3 spec = f * a2 / (pi * d * d) * g / (4.0 * nDotV * max(nDotL, 0.001));",
+		"   vec3 diffuse = (1.0 - f) * (1.0 - metal) * base.rgb / pi;",
+		"   float occ = mix(1.0, texture(uv3, mix(t_uv, t_uv1, ao.y)).r, ao.x);",
+		"   vec3 color = (diffuse + spec) * ld * nDotL * pi + la * base.rgb * occ;",
+		"   color += ke * texture(uv4, t_uv).rgb;",
+		"   ffc = vec4(color, base.a);",
+		"}",
+	}
+}
+
+// =============================================================================
+
+// skyShader draws a skybox from a cube map texture. The box is centered
+// on the camera and drawn behind everything else. See Pov.NewSky.
+//    https://learnopengl.com/Advanced-OpenGL/Cubemaps
+func skyShader() (vsh, fsh []string) {
+	fsh = []string{
+		"#version 330",
+		"in      vec3        v_d;", // cube map direction
+		"uniform samplerCube uv;",  // cube map texture
+		"out     vec4        ffc;", // final fragment color
+		"void main() {",
+		"   ffc = texture(uv, v_d);",
+		"}",
+	}
+	return skyVertex(), fsh
+}
+
+// skyeqShader is a skyShader that converts an equirectangular
+// panorama texture to cube map directions on the GPU. The mapping
+// matches load.EquirectToCube.
+func skyeqShader() (vsh, fsh []string) {
+	fsh = []string{
+		"#version 330",
+		"in      vec3      v_d;", // panorama direction
+		"uniform sampler2D uv;",  // equirectangular texture
+		"out     vec4      ffc;", // final fragment color
+		"void main() {",
+		"   vec3 d = normalize(v_d);",
+		"   float u = 0.5 + atan(d.x, -d.z)/6.28318531;", // -Z at the center.
+		"   vec2 eq = vec2(u, acos(clamp(d.y, -1.0, 1.0))/3.14159265);", // up at the top.
+		"   ffc = textureLod(uv, eq, 0.0);",                             // lod 0 hides the seam.
+		"}",
+	}
+	return skyVertex(), fsh
+}
+
+// skyVertex is the vertex shader shared by sky and skyeq. The camera
+// translation is ignored and the depth is set just inside the far plane.
+func skyVertex() []string {
+	return []string{
+		"#version 330",
+		"layout(location=0) in vec3 in_v;", // verticies
+		"",
+		"uniform mat4  mvm;", // model view matrix
+		"uniform mat4  pm;",  // projection matrix
+		"out     vec3  v_d;", // sky direction
+		"void main() {",
+		"   vec4 pos = pm * vec4(mat3(mvm) * in_v, 1.0);",
+		"   gl_Position = vec4(pos.xy, pos.w*0.99999, pos.w);",
+		"   v_d = in_v;",
+		"}",
+	}
+}
+
+// ===========================================================================
+
+// reflectShader reflects the surroundings, from a cube map texture,
+// off a model. Reflection directions are transformed to world space
+// so that models and skyboxes can share the cube map.
+func reflectShader() (vsh, fsh []string) {
+	fsh = []string{
+		"#version 330",
+		"in      vec3        v_e;",   // vertex eye position.
+		"in      vec3        v_n;",   // vertex normal
+		"uniform samplerCube uv;",    // cube map texture
+		"uniform mat4        ivm;",   // inverse view matrix
+		"uniform float       alpha;", // transparency
+		"out     vec4        ffc;",   // final fragment color
+		"void main() {",
+		"   vec3 r = reflect(normalize(v_e), normalize(v_n));",
+		"   ffc = texture(uv, (ivm * vec4(r, 0.0)).xyz);",
+		"   ffc.a = ffc.a*alpha;",
+		"}",
+	}
+	return reflectVertex(), fsh
+}
+
+// reflecteqShader is a reflectShader that converts an equirectangular
+// panorama texture to cube map directions on the GPU.
+func reflecteqShader() (vsh, fsh []string) {
+	fsh = []string{
+		"#version 330",
+		"in      vec3      v_e;",   // vertex eye position.
+		"in      vec3      v_n;",   // vertex normal
+		"uniform sampler2D uv;",    // equirectangular texture
+		"uniform mat4      ivm;",   // inverse view matrix
+		"uniform float     alpha;", // transparency
+		"out     vec4      ffc;",   // final fragment color
+		"void main() {",
+		"   vec3 r = reflect(normalize(v_e), normalize(v_n));",
+		"   vec3 d = normalize((ivm * vec4(r, 0.0)).xyz);",
+		"   float u = 0.5 + atan(d.x, -d.z)/6.28318531;",
+		"   vec2 eq = vec2(u, acos(clamp(d.y, -1.0, 1.0))/3.14159265);",
+		"   ffc = textureLod(uv, eq, 0.0);",
+		"   ffc.a = ffc.a*alpha;",
+		"}",
+	}
+	return reflectVertex(), fsh
+}
+
+// reflectVertex is the vertex shader shared by reflect and reflecteq.
+func reflectVertex() []string {
+	return []string{
+		"#version 330",
+		"layout(location=0) in vec3 in_v;", // verticies
+		"layout(location=1) in vec3 in_n;", // vertex normals
+		"",
+		"uniform mat4  mvpm;", // model view projection matrix
+		"uniform mat4  mvm;",  // model view matrix
+		"uniform mat3  nm;",   // normal matrix
+		"out     vec3  v_e;",  // vertex eye position.
+		"out     vec3  v_n;",  // vertex normal
+		"void main() {",
+		"   vec4 vpos = vec4(in_v, 1.0);",
+		"   v_e = (mvm * vpos).xyz;",
+		"   v_n = nm * in_n;",
+		"   gl_Position = mvpm * vpos;",
+		"}",
+	}
+}
+
+// =============================================================================
+
 // depthShader is used to create shadow maps by writing objects depths.
 // Expected to be used during the shadow map render pass to render to
 // a texture. See:
@@ -470,6 +749,30 @@ func depthShader() (vsh, fsh []string) {
 
 // =============================================================================
 
+// pickShader draws models in a single pick color that identifies
+// the model. Used by the engine when picking.
+func pickShader() (vsh, fsh []string) {
+	vsh = []string{
+		"#version 330",
+		"layout (location = 0) in vec3 in_v;",
+		"uniform mat4          mvpm;",
+		"void main() {",
+		"    gl_Position = mvpm * vec4(in_v, 1.0);",
+		"}",
+	}
+	fsh = []string{
+		"#version 330",
+		"uniform vec3 pid;", // pick color.
+		"out     vec4 ffc;", // final fragment color.
+		"void main() {",
+		"    ffc = vec4(pid, 1.0);",
+		"}",
+	}
+	return vsh, fsh
+}
+
+// =============================================================================
+
 // shadowShader incorporates a shadow depth map into lighting calculations. See:
 // http://www.opengl-tutorial.org/intermediate-tutorials/tutorial-16-shadow-mapping
 func shadowShader() (vsh, fsh []string) {
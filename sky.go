@@ -0,0 +1,52 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"github.com/gazed/vu/render"
+)
+
+// Skyboxes and reflections use cube map textures of the surroundings.
+// A cube map is either six square images, ie: "stars_px", "stars_nx",
+// "stars_py", "stars_ny", "stars_pz", "stars_nz", or a single
+// equirectangular panorama, ie: "stars", that is converted on the CPU
+// when the cube map is loaded:
+//     scene.NewPov().NewSky("sky").AddCube("stars")
+//     ship.NewModel("reflect").LoadMesh("ship").AddCube("stars")
+// Panoramas can also be converted on the GPU, for every drawn pixel,
+// by using a regular texture with the "eq" shaders:
+//     scene.NewPov().NewSky("skyeq").AddTex("stars")
+//     ship.NewModel("reflecteq").LoadMesh("ship").AddTex("stars")
+// The sky follows the camera and is drawn behind all other models.
+// Rotating the sky Pov rotates the sky. Memory images, see Eng.AddTexture,
+// can be used for the faces or the panorama.
+
+// newSky creates a model with a generated skybox mesh.
+func (eng *engine) newSky(p Pov, shader string) Model {
+	m := eng.newModel(p, shader)
+	if m != nil {
+		m.NewMesh("sky").InitMesh(0, 3, render.StaticDraw, false).InitFaces(render.StaticDraw)
+		m.SetMeshData(0, skyVerts)
+		m.SetFaces(skyFaces)
+	}
+	return m
+}
+
+// skyVerts are the corners of a unit cube where corner i
+// is at x=i&1, y=i>>1&1, z=i>>2&1 with 0 being -1.
+var skyVerts = []float32{
+	-1, -1, -1, 1, -1, -1, -1, 1, -1, 1, 1, -1,
+	-1, -1, 1, 1, -1, 1, -1, 1, 1, 1, 1, 1,
+}
+
+// skyFaces wind the triangles to face the inside of the cube so
+// they are not culled when viewed from the center.
+var skyFaces = []uint16{
+	0, 1, 3, 0, 3, 2, // -Z
+	4, 7, 5, 4, 6, 7, // +Z
+	0, 6, 4, 0, 2, 6, // -X
+	1, 5, 7, 1, 7, 3, // +X
+	0, 4, 5, 0, 5, 1, // -Y
+	2, 7, 6, 2, 3, 7, // +Y
+}
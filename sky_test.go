@@ -0,0 +1,53 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"image"
+	"testing"
+
+	"github.com/gazed/vu/load"
+	"github.com/gazed/vu/math/lin"
+)
+
+// Check that the skybox faces the inside of the cube.
+func TestSkyFaces(t *testing.T) {
+	corner := func(i uint16) *lin.V3 {
+		return &lin.V3{X: float64(skyVerts[i*3]), Y: float64(skyVerts[i*3+1]), Z: float64(skyVerts[i*3+2])}
+	}
+	for cnt := 0; cnt < len(skyFaces); cnt += 3 {
+		a, b, c := corner(skyFaces[cnt]), corner(skyFaces[cnt+1]), corner(skyFaces[cnt+2])
+		n := (&lin.V3{}).Cross((&lin.V3{}).Sub(b, a), (&lin.V3{}).Sub(c, a))
+		if n.Dot(a) >= 0 {
+			t.Errorf("Expected triangle %d to face inside", cnt/3)
+		}
+	}
+}
+
+// Check that cube maps are created from memory faces and panoramas.
+func TestCubeMaps(t *testing.T) {
+	eng := newEngine(nil)
+	defer eng.Shutdown()
+	m := eng.Root().NewPov().NewSky("sky").AddCube("stars").(*model)
+	if m.msh == nil || !m.msh.gen || len(m.texs) != 1 || !m.texs[0].cube || len(m.loads) != 2 {
+		t.Fatalf("Expected sky mesh, shader, and cube map requests")
+	}
+	if m.texs[0].aid() == newTexture("stars").aid() {
+		t.Errorf("Expected cube maps and textures to be cached separately")
+	}
+	for _, suffix := range load.CubeFaces {
+		eng.AddTexture("box"+suffix, image.NewNRGBA(image.Rect(0, 0, 2, 2)))
+	}
+	eng.AddTexture("pano", image.NewNRGBA(image.Rect(0, 0, 16, 8)))
+	box, pano := newCube("box"), newCube("pano")
+	if eng.loader.importTexture(box) != nil || len(box.faces) != 6 || !box.loaded {
+		t.Errorf("Expected memory cube faces")
+	}
+	if eng.loader.importTexture(pano) != nil || len(pano.faces) != 6 || pano.faces[0].Bounds().Dx() != 4 {
+		t.Errorf("Expected converted memory panorama")
+	}
+	if eng.loader.importTexture(newCube("none")) == nil {
+		t.Errorf("Expected error for missing cube map")
+	}
+}
@@ -0,0 +1,396 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// Snapshots capture the state of replicated Pov's so that it can be
+// sent to, and recreated on, another machine. A server sends the
+// differences between the current snapshot and the last snapshot
+// acknowledged by a client, ie:
+//     p.SetReplicated(true)                // on entity creation.
+//     snap := eng.Snapshot(tick)           // each network update.
+//     data, _ := snap.Diff(acked).MarshalBinary()
+// and the client recreates the snapshot from its copy of the baseline:
+//     delta := &vu.Delta{}
+//     err := delta.UnmarshalBinary(data)
+//     snap := acked.Apply(delta)
+// Entities are identified by their engine entity id. Applying the
+// snapshot state to client side entities is up to the application.
+
+// Snapshot is the replicated state of entities at one update.
+type Snapshot struct {
+	Tick     uint64        // Application update count.
+	Entities []EntityState // Replicated entities sorted by Id.
+}
+
+// EntityState is the replicated state of an entity.
+type EntityState struct {
+	Id     uint64     // Entity id.
+	Parent uint64     // Parent entity id. 0 for no parent.
+	Loc    [3]float64 // Local location.
+	Rot    [4]float64 // Local rotation quaternion X, Y, Z, W.
+	Scale  [3]float64 // Per axis scale.
+	Speed  [3]float64 // Physics body linear velocity.
+	Whirl  [3]float64 // Physics body angular velocity.
+	Shader string     // Model shader, mesh, and animation names.
+	Mesh   string     // ...
+	Anim   string     // ...
+}
+
+// Delta is the difference between two snapshots.
+type Delta struct {
+	Tick    uint64        // Tick of the new snapshot.
+	Base    uint64        // Tick of the baseline snapshot.
+	Changed []EntityDelta // New or changed entities.
+	Removed []uint64      // Ids of removed entities.
+}
+
+// EntityDelta is the changed state of an entity. Only the
+// state fields flagged in Changed are valid.
+type EntityDelta struct {
+	Changed uint8 // Changed Delta fields.
+	State   EntityState
+}
+
+// Delta fields.
+const (
+	DeltaParent uint8 = 1 << iota // Parent.
+	DeltaLoc                      // Loc.
+	DeltaRot                      // Rot.
+	DeltaScale                    // Scale.
+	DeltaSpeed                    // Speed.
+	DeltaWhirl                    // Whirl.
+	DeltaModel                    // Shader, Mesh, and Anim.
+)
+
+// Implement Eng interface.
+func (eng *engine) Snapshot(tick uint64) *Snapshot {
+	snap := &Snapshot{Tick: tick}
+	eng.eids = eng.eids[:0]
+	for eid, p := range eng.povs {
+		if p.netted {
+			eng.eids = append(eng.eids, eid)
+		}
+	}
+	sort.Sort(eids(eng.eids))
+	snap.Entities = make([]EntityState, len(eng.eids))
+	for cnt, eid := range eng.eids {
+		eng.capture(eng.povs[eid], &snap.Entities[cnt])
+	}
+	return snap
+}
+
+// capture records the replicated state of p.
+func (eng *engine) capture(p *pov, es *EntityState) {
+	es.Id = p.eid
+	if p.parent != nil {
+		es.Parent = p.parent.eid
+	}
+	l, r, s := p.at.Loc, p.at.Rot, p.scale
+	es.Loc = [3]float64{l.X, l.Y, l.Z}
+	es.Rot = [4]float64{r.X, r.Y, r.Z, r.W}
+	es.Scale = [3]float64{s.X, s.Y, s.Z}
+	if b := p.Body(); b != nil {
+		es.Speed[0], es.Speed[1], es.Speed[2] = b.Speed()
+		es.Whirl[0], es.Whirl[1], es.Whirl[2] = b.Whirl()
+	}
+	if m, ok := eng.models.model(p.eid); ok {
+		if m.shd != nil {
+			es.Shader = m.shd.name
+		}
+		if m.msh != nil && m.anm == nil {
+			es.Mesh = m.msh.name
+		}
+		if m.anm != nil {
+			es.Anim = m.anm.name
+		}
+	}
+}
+
+// Diff returns the changes from the baseline snapshot to this snapshot.
+// A nil baseline treats all entities as new.
+func (s *Snapshot) Diff(base *Snapshot) *Delta {
+	d := &Delta{Tick: s.Tick}
+	var old []EntityState
+	if base != nil {
+		d.Base, old = base.Tick, base.Entities
+	}
+	bi := 0
+	for _, es := range s.Entities {
+		for bi < len(old) && old[bi].Id < es.Id {
+			d.Removed = append(d.Removed, old[bi].Id)
+			bi++
+		}
+		changed := uint8(0xFF) // new entity.
+		if bi < len(old) && old[bi].Id == es.Id {
+			changed = es.changes(&old[bi])
+			bi++
+		}
+		if changed != 0 {
+			d.Changed = append(d.Changed, EntityDelta{Changed: changed & deltaAll, State: es})
+		}
+	}
+	for ; bi < len(old); bi++ {
+		d.Removed = append(d.Removed, old[bi].Id)
+	}
+	return d
+}
+
+// deltaAll flags all the Delta fields.
+const deltaAll = DeltaParent | DeltaLoc | DeltaRot | DeltaScale | DeltaSpeed | DeltaWhirl | DeltaModel
+
+// changes returns the fields of es that differ from the baseline b.
+func (es *EntityState) changes(b *EntityState) (changed uint8) {
+	if es.Parent != b.Parent {
+		changed |= DeltaParent
+	}
+	if es.Loc != b.Loc {
+		changed |= DeltaLoc
+	}
+	if es.Rot != b.Rot {
+		changed |= DeltaRot
+	}
+	if es.Scale != b.Scale {
+		changed |= DeltaScale
+	}
+	if es.Speed != b.Speed {
+		changed |= DeltaSpeed
+	}
+	if es.Whirl != b.Whirl {
+		changed |= DeltaWhirl
+	}
+	if es.Shader != b.Shader || es.Mesh != b.Mesh || es.Anim != b.Anim {
+		changed |= DeltaModel
+	}
+	return changed
+}
+
+// Apply returns the snapshot created by applying the delta to this
+// baseline snapshot. The baseline is not changed.
+func (s *Snapshot) Apply(d *Delta) *Snapshot {
+	snap := &Snapshot{Tick: d.Tick}
+	removed := map[uint64]bool{}
+	for _, id := range d.Removed {
+		removed[id] = true
+	}
+	ci, changes := 0, d.Changed
+	add := func(ed *EntityDelta, base *EntityState) {
+		es := EntityState{Id: ed.State.Id}
+		if base != nil {
+			es = *base
+		}
+		es.apply(ed)
+		snap.Entities = append(snap.Entities, es)
+	}
+	for cnt := range s.Entities {
+		base := &s.Entities[cnt]
+		for ci < len(changes) && changes[ci].State.Id < base.Id {
+			add(&changes[ci], nil) // new entity.
+			ci++
+		}
+		switch {
+		case ci < len(changes) && changes[ci].State.Id == base.Id:
+			add(&changes[ci], base)
+			ci++
+		case !removed[base.Id]:
+			snap.Entities = append(snap.Entities, *base)
+		}
+	}
+	for ; ci < len(changes); ci++ {
+		add(&changes[ci], nil)
+	}
+	return snap
+}
+
+// apply copies the changed fields into es.
+func (es *EntityState) apply(ed *EntityDelta) {
+	c, n := ed.Changed, &ed.State
+	if c&DeltaParent != 0 {
+		es.Parent = n.Parent
+	}
+	if c&DeltaLoc != 0 {
+		es.Loc = n.Loc
+	}
+	if c&DeltaRot != 0 {
+		es.Rot = n.Rot
+	}
+	if c&DeltaScale != 0 {
+		es.Scale = n.Scale
+	}
+	if c&DeltaSpeed != 0 {
+		es.Speed = n.Speed
+	}
+	if c&DeltaWhirl != 0 {
+		es.Whirl = n.Whirl
+	}
+	if c&DeltaModel != 0 {
+		es.Shader, es.Mesh, es.Anim = n.Shader, n.Mesh, n.Anim
+	}
+}
+
+// Delta encoding
+// ===========================================================================
+
+// MarshalBinary encodes the delta with only the changed fields.
+func (d *Delta) MarshalBinary() ([]byte, error) {
+	w := &deltaWriter{}
+	w.uvarint(d.Tick)
+	w.uvarint(d.Base)
+	w.uvarint(uint64(len(d.Changed)))
+	for _, ed := range d.Changed {
+		es := &ed.State
+		w.uvarint(es.Id)
+		w.buf.WriteByte(ed.Changed)
+		if ed.Changed&DeltaParent != 0 {
+			w.uvarint(es.Parent)
+		}
+		if ed.Changed&DeltaLoc != 0 {
+			w.floats(es.Loc[:])
+		}
+		if ed.Changed&DeltaRot != 0 {
+			w.floats(es.Rot[:])
+		}
+		if ed.Changed&DeltaScale != 0 {
+			w.floats(es.Scale[:])
+		}
+		if ed.Changed&DeltaSpeed != 0 {
+			w.floats(es.Speed[:])
+		}
+		if ed.Changed&DeltaWhirl != 0 {
+			w.floats(es.Whirl[:])
+		}
+		if ed.Changed&DeltaModel != 0 {
+			w.str(es.Shader)
+			w.str(es.Mesh)
+			w.str(es.Anim)
+		}
+	}
+	w.uvarint(uint64(len(d.Removed)))
+	for _, id := range d.Removed {
+		w.uvarint(id)
+	}
+	return w.buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes a delta encoded by MarshalBinary.
+func (d *Delta) UnmarshalBinary(data []byte) (err error) {
+	r := &deltaReader{buf: bytes.NewReader(data)}
+	*d = Delta{Tick: r.uvarint(), Base: r.uvarint()}
+	cnt := r.count()
+	for ; cnt > 0 && r.err == nil; cnt-- {
+		ed := EntityDelta{}
+		es := &ed.State
+		es.Id = r.uvarint()
+		ed.Changed = r.byte()
+		if ed.Changed&DeltaParent != 0 {
+			es.Parent = r.uvarint()
+		}
+		if ed.Changed&DeltaLoc != 0 {
+			r.floats(es.Loc[:])
+		}
+		if ed.Changed&DeltaRot != 0 {
+			r.floats(es.Rot[:])
+		}
+		if ed.Changed&DeltaScale != 0 {
+			r.floats(es.Scale[:])
+		}
+		if ed.Changed&DeltaSpeed != 0 {
+			r.floats(es.Speed[:])
+		}
+		if ed.Changed&DeltaWhirl != 0 {
+			r.floats(es.Whirl[:])
+		}
+		if ed.Changed&DeltaModel != 0 {
+			es.Shader, es.Mesh, es.Anim = r.str(), r.str(), r.str()
+		}
+		d.Changed = append(d.Changed, ed)
+	}
+	for cnt = r.count(); cnt > 0 && r.err == nil; cnt-- {
+		d.Removed = append(d.Removed, r.uvarint())
+	}
+	if r.err != nil {
+		return fmt.Errorf("Delta.UnmarshalBinary: %s", r.err)
+	}
+	return nil
+}
+
+// deltaWriter encodes delta values.
+type deltaWriter struct {
+	buf bytes.Buffer
+	tmp [binary.MaxVarintLen64]byte
+}
+
+func (w *deltaWriter) uvarint(v uint64) {
+	n := binary.PutUvarint(w.tmp[:], v)
+	w.buf.Write(w.tmp[:n])
+}
+func (w *deltaWriter) floats(vals []float64) {
+	for _, v := range vals {
+		binary.LittleEndian.PutUint64(w.tmp[:8], math.Float64bits(v))
+		w.buf.Write(w.tmp[:8])
+	}
+}
+func (w *deltaWriter) str(s string) {
+	w.uvarint(uint64(len(s)))
+	w.buf.WriteString(s)
+}
+
+// deltaReader decodes delta values, remembering the first error.
+type deltaReader struct {
+	buf *bytes.Reader
+	tmp [8]byte
+	err error
+}
+
+func (r *deltaReader) uvarint() (v uint64) {
+	if r.err == nil {
+		v, r.err = binary.ReadUvarint(r.buf)
+	}
+	return v
+}
+func (r *deltaReader) byte() (b byte) {
+	if r.err == nil {
+		b, r.err = r.buf.ReadByte()
+	}
+	return b
+}
+func (r *deltaReader) floats(vals []float64) {
+	for cnt := range vals {
+		if r.err == nil {
+			if _, r.err = io.ReadFull(r.buf, r.tmp[:]); r.err == nil {
+				vals[cnt] = math.Float64frombits(binary.LittleEndian.Uint64(r.tmp[:]))
+			}
+		}
+	}
+}
+func (r *deltaReader) str() string {
+	n := r.uvarint()
+	if r.err != nil || n > uint64(r.buf.Len()) {
+		if r.err == nil {
+			r.err = io.ErrUnexpectedEOF
+		}
+		return ""
+	}
+	b := make([]byte, n)
+	_, r.err = io.ReadFull(r.buf, b)
+	return string(b)
+}
+
+// count reads a list length, checking that it is possible
+// given the remaining data.
+func (r *deltaReader) count() uint64 {
+	n := r.uvarint()
+	if r.err == nil && n > uint64(r.buf.Len()) {
+		r.err = fmt.Errorf("invalid count %d", n)
+	}
+	return n
+}
@@ -0,0 +1,52 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestSnapshot checks that deltas recreate snapshots.
+func TestSnapshot(t *testing.T) {
+	eng := newEngine(nil)
+	defer eng.Shutdown()
+	a := eng.Root().NewPov().SetReplicated(true)
+	b := eng.Root().NewPov().SetReplicated(true).SetLocation(1, 2, 3)
+	eng.Root().NewPov() // not replicated.
+	base := eng.Snapshot(1)
+	if len(base.Entities) != 2 {
+		t.Fatalf("Expected 2 entities got %d", len(base.Entities))
+	}
+
+	// change, add, and remove entities.
+	b.SetLocation(4, 5, 6)
+	c := b.NewPov().SetReplicated(true)
+	a.Dispose(PovNode)
+	snap := eng.Snapshot(2)
+	delta := snap.Diff(base)
+	if len(delta.Changed) != 2 || delta.Changed[0].Changed != DeltaLoc || len(delta.Removed) != 1 {
+		t.Errorf("Expected changed b, new c, removed a got %+v", delta)
+	}
+	data, err := delta.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := &Delta{}
+	if err = got.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if rebuilt := base.Apply(got); !reflect.DeepEqual(rebuilt, snap) {
+		t.Errorf("Expected %+v got %+v", snap, rebuilt)
+	}
+	if es := snap.Entities[1]; es.Id != c.(*pov).eid || es.Parent != b.(*pov).eid {
+		t.Errorf("Expected child c of b")
+	}
+	if err = got.UnmarshalBinary(data[:len(data)-3]); err == nil {
+		t.Errorf("Expected error for truncated data")
+	}
+	if d := snap.Diff(snap); len(d.Changed) != 0 || len(d.Removed) != 0 {
+		t.Errorf("Expected no differences")
+	}
+}
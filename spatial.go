@@ -0,0 +1,252 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"math"
+	"sort"
+
+	"github.com/gazed/vu/physics"
+)
+
+// The spatial index is a loose grid of cells over the world bounds of the
+// Pov's that have models or bodies. It is updated as bounds change, see
+// bounds.go, so that proximity queries only check nearby entities, ie:
+//     near = eng.WithinSphere(x, y, z, 10, near[:0])
+//     hits = eng.AlongRay(ray, hits[:0]) // nearest first.
+// Pov's whose bounds cover too many cells are kept in a list that is
+// checked by every query. Disabled Pov's are not returned.
+
+// Spatial index settings.
+const (
+	cellSize = 8.0 // Cell width in world units.
+	maxCells = 512 // Most cells for one entity.
+)
+
+// cell identifies one spatial index cell.
+type cell [3]int
+
+// spatial is a loose grid spatial index.
+type spatial struct {
+	cells map[cell][]*pov // Entities touching each cell.
+	big   []*pov          // Entities touching too many cells.
+	stamp int             // Incremented for each query.
+	near  []*pov          // Scratch query results.
+	hits  rayHits         // Scratch ray query results.
+}
+
+// newSpatial creates an empty spatial index.
+func newSpatial() *spatial { return &spatial{cells: map[cell][]*pov{}} }
+
+// cellRange returns the cells covered by the given box.
+func cellRange(box *physics.Abox) (lo, hi cell) {
+	lo = cell{int(math.Floor(box.Sx / cellSize)), int(math.Floor(box.Sy / cellSize)), int(math.Floor(box.Sz / cellSize))}
+	hi = cell{int(math.Floor(box.Lx / cellSize)), int(math.Floor(box.Ly / cellSize)), int(math.Floor(box.Lz / cellSize))}
+	return lo, hi
+}
+
+// place adds, moves, or removes p in the index according to its bounds.
+// Pov's are indexed if they have their own model or body bounds.
+func (s *spatial) place(p *pov, indexed bool) {
+	if !indexed || !p.bounded {
+		s.remove(p)
+		return
+	}
+	lo, hi := cellRange(&p.bb)
+	if p.sindexed && lo == p.slo && hi == p.shi {
+		return // same cells.
+	}
+	s.remove(p)
+	p.sindexed, p.slo, p.shi = true, lo, hi
+	if cnt := (hi[0] - lo[0] + 1) * (hi[1] - lo[1] + 1) * (hi[2] - lo[2] + 1); cnt > maxCells {
+		p.sbig = true
+		s.big = append(s.big, p)
+		return
+	}
+	for x := lo[0]; x <= hi[0]; x++ {
+		for y := lo[1]; y <= hi[1]; y++ {
+			for z := lo[2]; z <= hi[2]; z++ {
+				c := cell{x, y, z}
+				s.cells[c] = append(s.cells[c], p)
+			}
+		}
+	}
+}
+
+// remove takes p out of the index.
+func (s *spatial) remove(p *pov) {
+	if !p.sindexed {
+		return
+	}
+	p.sindexed = false
+	if p.sbig {
+		p.sbig = false
+		s.big = removePov(s.big, p)
+		return
+	}
+	lo, hi := p.slo, p.shi
+	for x := lo[0]; x <= hi[0]; x++ {
+		for y := lo[1]; y <= hi[1]; y++ {
+			for z := lo[2]; z <= hi[2]; z++ {
+				c := cell{x, y, z}
+				if povs := removePov(s.cells[c], p); len(povs) > 0 {
+					s.cells[c] = povs
+				} else {
+					delete(s.cells, c)
+				}
+			}
+		}
+	}
+}
+
+// removePov returns the list without p.
+func removePov(povs []*pov, p *pov) []*pov {
+	for index, pv := range povs {
+		if pv == p {
+			povs[index] = povs[len(povs)-1]
+			povs[len(povs)-1] = nil
+			return povs[:len(povs)-1]
+		}
+	}
+	return povs
+}
+
+// visit calls fn once for each enabled Pov in the given cells
+// and in the big list.
+func (s *spatial) visit(lo, hi cell, fn func(p *pov)) {
+	s.stamp++
+	check := func(p *pov) {
+		if p.sstamp != s.stamp {
+			p.sstamp = s.stamp
+			if p.active() {
+				fn(p)
+			}
+		}
+	}
+	for _, p := range s.big {
+		check(p)
+	}
+	for x := lo[0]; x <= hi[0]; x++ {
+		for y := lo[1]; y <= hi[1]; y++ {
+			for z := lo[2]; z <= hi[2]; z++ {
+				for _, p := range s.cells[cell{x, y, z}] {
+					check(p)
+				}
+			}
+		}
+	}
+}
+
+// Implement Eng interface. Results are in creation order.
+func (eng *engine) WithinSphere(x, y, z, radius float64, found []Pov) []Pov {
+	s := eng.index
+	s.near = s.near[:0]
+	lo, hi := cellRange(&physics.Abox{Sx: x - radius, Sy: y - radius, Sz: z - radius,
+		Lx: x + radius, Ly: y + radius, Lz: z + radius})
+	s.visit(lo, hi, func(p *pov) {
+		b := &p.bb // distance from the sphere center to the closest box point.
+		dx := x - math.Max(b.Sx, math.Min(x, b.Lx))
+		dy := y - math.Max(b.Sy, math.Min(y, b.Ly))
+		dz := z - math.Max(b.Sz, math.Min(z, b.Lz))
+		if dx*dx+dy*dy+dz*dz <= radius*radius {
+			s.near = append(s.near, p)
+		}
+	})
+	sort.Sort(byEid(s.near))
+	for cnt, p := range s.near {
+		found = append(found, p)
+		s.near[cnt] = nil
+	}
+	return found
+}
+
+// Implement Eng interface. Occupied cells are checked against the ray
+// rather than stepping along the ray since rays are not limited in length.
+func (eng *engine) AlongRay(ray physics.Body, found []Pov) []Pov {
+	if ray == nil || ray.Shape().Type() != physics.RayShape {
+		return found
+	}
+	s := eng.index
+	o := ray.World().Loc
+	dx, dy, dz := physics.Dimensions(ray.Shape())
+	if l := math.Sqrt(dx*dx + dy*dy + dz*dz); l > 0 {
+		dx, dy, dz = dx/l, dy/l, dz/l
+	}
+	s.stamp++
+	s.hits = s.hits[:0]
+	test := func(p *pov) {
+		if p.sstamp != s.stamp {
+			p.sstamp = s.stamp
+			if dist, hit := rayBox(o.X, o.Y, o.Z, dx, dy, dz, &p.bb); hit && p.active() {
+				s.hits = append(s.hits, rayHit{p: p, dist: dist})
+			}
+		}
+	}
+	for _, p := range s.big {
+		test(p)
+	}
+	cb := &physics.Abox{}
+	for c, povs := range s.cells {
+		cb.Sx, cb.Sy, cb.Sz = float64(c[0])*cellSize, float64(c[1])*cellSize, float64(c[2])*cellSize
+		cb.Lx, cb.Ly, cb.Lz = cb.Sx+cellSize, cb.Sy+cellSize, cb.Sz+cellSize
+		if _, hit := rayBox(o.X, o.Y, o.Z, dx, dy, dz, cb); hit {
+			for _, p := range povs {
+				test(p)
+			}
+		}
+	}
+	sort.Sort(s.hits)
+	for cnt, h := range s.hits {
+		found = append(found, h.p)
+		s.hits[cnt].p = nil
+	}
+	return found
+}
+
+// rayBox returns the distance along the unit ray direction to where
+// the ray enters the box. The distance is 0 if the ray starts inside.
+func rayBox(ox, oy, oz, dx, dy, dz float64, b *physics.Abox) (dist float64, hit bool) {
+	near, far := 0.0, math.MaxFloat64
+	axes := [3][4]float64{{ox, dx, b.Sx, b.Lx}, {oy, dy, b.Sy, b.Ly}, {oz, dz, b.Sz, b.Lz}}
+	for _, a := range axes {
+		o, d, lo, hi := a[0], a[1], a[2], a[3]
+		if d == 0 {
+			if o < lo || o > hi {
+				return 0, false // parallel to and outside the slab.
+			}
+			continue
+		}
+		t0, t1 := (lo-o)/d, (hi-o)/d
+		if t0 > t1 {
+			t0, t1 = t1, t0
+		}
+		near, far = math.Max(near, t0), math.Min(far, t1)
+		if near > far {
+			return 0, false
+		}
+	}
+	return near, true
+}
+
+// rayHit is a ray query result.
+type rayHit struct {
+	p    *pov
+	dist float64 // Distance along ray.
+}
+
+// rayHits sorts ray query results nearest first, then by creation.
+type rayHits []rayHit
+
+func (r rayHits) Len() int      { return len(r) }
+func (r rayHits) Swap(i, j int) { r[i], r[j] = r[j], r[i] }
+func (r rayHits) Less(i, j int) bool {
+	return r[i].dist < r[j].dist || (r[i].dist == r[j].dist && r[i].p.eid < r[j].p.eid)
+}
+
+// byEid sorts Pov's into creation order.
+type byEid []*pov
+
+func (b byEid) Len() int           { return len(b) }
+func (b byEid) Less(i, j int) bool { return b[i].eid < b[j].eid }
+func (b byEid) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
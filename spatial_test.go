@@ -0,0 +1,50 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"testing"
+
+	"github.com/gazed/vu/math/lin"
+)
+
+// TestSpatial checks sphere and ray queries.
+func TestSpatial(t *testing.T) {
+	eng := newEngine(nil)
+	defer eng.Shutdown()
+	top := eng.Root().NewPov()
+	a := top.NewPov().SetLocation(0, 0, 0)
+	a.NewBody(NewSphere(1))
+	b := top.NewPov().SetLocation(5, 0, 0)
+	b.NewBody(NewSphere(1))
+	c := top.NewPov().SetLocation(50, 0, 0)
+	c.NewBody(NewSphere(1))
+	wall := top.NewPov().SetLocation(30, 0, 0).SetScale(1, 1000, 1000) // big.
+	wall.NewBody(NewBox(1, 1, 1))
+	eng.placeModels(eng.root(), lin.M4I)
+
+	if near := eng.WithinSphere(1, 0, 0, 3, nil); len(near) != 2 || near[0] != a || near[1] != b {
+		t.Errorf("Expected a and b got %d", len(near))
+	}
+	if len(eng.index.big) != 1 {
+		t.Errorf("Expected wall in big list")
+	}
+	ray := NewRay(1, 0, 0)
+	ray.World().Loc.SetS(-10, 0, 0)
+	if hits := eng.AlongRay(ray, nil); len(hits) != 4 || hits[0] != a || hits[2] != wall || hits[3] != c {
+		t.Errorf("Expected a, b, wall, c got %d", len(hits))
+	}
+
+	// moved, disabled, and disposed entities.
+	c.SetLocation(1, 0, 0)
+	b.SetEnabled(false)
+	eng.placeModels(eng.root(), lin.M4I)
+	if near := eng.WithinSphere(1, 0, 0, 3, nil); len(near) != 2 || near[0] != a || near[1] != c {
+		t.Errorf("Expected a and c got %d", len(near))
+	}
+	top.Dispose(PovNode)
+	if len(eng.index.cells) != 0 || len(eng.index.big) != 0 {
+		t.Errorf("Expected empty index")
+	}
+}
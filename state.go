@@ -13,6 +13,10 @@ type State struct {
 	Blend      bool    // True for texture blending.
 	FullScreen bool    // True when window is full screen.
 	Mute       bool    // True when audio is muted.
+
+	// Sound output devices, see Eng.SetAudioDevice.
+	AudioDevice  string   // Current device.
+	AudioDevices []string // All devices.
 }
 
 // Screen is a convenience method returning the current window dimensions.
@@ -0,0 +1,123 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+// Stream plays sound samples that are generated while the game runs,
+// ie: synthesizers, voice chat, or engine sounds built from sample
+// grains. Streams are noise sounds, see Noise.AddStream, that are
+// placed, mixed, and controlled like other played sounds, ie:
+//     synth := noise.AddStream(1, 22050)
+//     synth.SetFill(func(samples []int16) int {
+//         ... // generate up to len(samples) samples.
+//         return len(samples)
+//     })
+//     synth.Play()
+// Samples are signed 16 bit values that are interleaved for stereo
+// streams. Samples are pushed using Submit, or pulled by the fill
+// function which is called each update while less than about 0.15
+// seconds of samples are waiting to be played. Streams that run out
+// of samples are silent until more samples are sent.
+type Stream interface {
+	Submit(samples []int16)                 // Play samples after earlier samples.
+	SetFill(fill func(samples []int16) int) // Fill returns the samples set.
+	Play() Playback                         // Play the noise stream sound.
+}
+
+// Stream buffering. Pulled samples are sent in chunks.
+const (
+	streamChunk   = 0.05 // Seconds of samples in each fill.
+	streamBuffers = 3    // Chunks waiting to be played.
+)
+
+// Stream
+// =============================================================================
+// stream implements Stream.
+
+// stream sends generated samples to a bound stream sound.
+type stream struct {
+	n       *noise                    // Plays the stream.
+	index   int                       // Noise sound index.
+	snd     *sound                    // Bound stream sound.
+	fill    func(samples []int16) int // Pulls samples. Nil if unset.
+	samples []int16                   // Fill buffer.
+	data    []byte                    // Submitted samples to send.
+	queued  int                       // Fills not yet played.
+}
+
+// newStream creates a stream sound with the given format.
+func newStream(n *noise, index, channels, frequency int) *stream {
+	s := &stream{n: n, index: index, snd: newSound("stream")}
+	s.snd.stream = true
+	s.snd.data.Set(uint16(channels), 16, uint32(frequency), 0, nil)
+	s.samples = make([]int16, int(float64(frequency)*streamChunk)*channels)
+	return s
+}
+
+// Implement Stream.
+func (s *stream) SetFill(fill func(samples []int16) int) { s.fill = fill }
+func (s *stream) Play() Playback                         { return s.n.Play(s.index) }
+func (s *stream) Submit(samples []int16) {
+	s.data = appendSamples(s.data, samples)
+}
+
+// update sends the submitted samples and fills the stream until
+// enough samples are waiting to be played. Streams are not sent
+// until they are bound.
+func (s *stream) update(eng *engine, queued int) {
+	if s.snd.sid == 0 {
+		return
+	}
+	if s.queued = queued; len(s.data) > 0 {
+		s.send(eng, s.data)
+		s.data = nil
+	}
+	for s.fill != nil && s.queued < streamBuffers {
+		cnt := s.fill(s.samples)
+		if cnt <= 0 {
+			return // nothing to play yet.
+		}
+		s.send(eng, appendSamples(nil, s.samples[:cnt]))
+	}
+}
+
+// send queues sample data on the stream sound.
+func (s *stream) send(eng *engine, data []byte) {
+	qs := &queueSound{sid: s.snd.sid, data: data}
+	go func(qs *queueSound) { eng.machine <- qs }(qs)
+	s.queued++
+}
+
+// appendSamples appends the little endian sample bytes to data.
+func appendSamples(data []byte, samples []int16) []byte {
+	for _, sample := range samples {
+		data = append(data, byte(sample), byte(sample>>8))
+	}
+	return data
+}
+
+// stream
+// =============================================================================
+// engine stream handling.
+
+// updateStreams sends stream samples to the machine using the machine
+// count of the buffers queued on each stream. Streams are updated in
+// creation order. Expected to be called once per update.
+func (eng *engine) updateStreams(queued map[uint64]int) {
+	for _, s := range eng.streams {
+		s.update(eng, queued[s.snd.sid])
+	}
+}
+
+// disposeStreams releases the stream sounds of a disposed noise.
+func (eng *engine) disposeStreams(n *noise) {
+	streams := eng.streams[:0]
+	for _, s := range eng.streams {
+		if s.n != n {
+			streams = append(streams, s)
+		} else if s.snd.sid != 0 {
+			go eng.release(&releaseData{data: s.snd})
+		}
+	}
+	eng.streams = streams
+}
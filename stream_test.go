@@ -0,0 +1,68 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"bytes"
+	"testing"
+)
+
+// Submitted and pulled samples are sent until enough are queued.
+func TestStream(t *testing.T) {
+	machine := make(chan msg, 10)
+	eng := newEngine(machine)
+	p := eng.Root().NewPov()
+	n := p.NewNoise().(*noise)
+	s := n.AddStream(1, 100).(*stream)
+	if len(n.loads) != 1 || n.snds[0] != s.snd || !s.snd.stream || len(s.samples) != 5 {
+		t.Fatalf("Expected stream sound to be loaded")
+	}
+	fills := 0
+	s.Submit([]int16{1, -2})
+	s.SetFill(func(samples []int16) int {
+		fills++
+		samples[0] = 3
+		return 1
+	})
+	if eng.updateStreams(map[uint64]int{}); len(machine) != 0 || fills != 0 {
+		t.Fatalf("Expected unbound streams to wait")
+	}
+
+	// the submitted samples and one fill top up the queued buffers.
+	s.snd.sid = 7
+	eng.updateStreams(map[uint64]int{7: 1})
+	sent := map[string]bool{}
+	for cnt := 0; cnt < 2; cnt++ {
+		if qs, ok := nextMsg(machine).(*queueSound); ok && qs.sid == 7 {
+			sent[string(qs.data)] = true
+		}
+	}
+	if !sent[string([]byte{1, 0, 0xfe, 0xff})] || !sent[string([]byte{3, 0})] || fills != 1 {
+		t.Fatalf("Expected submitted and pulled samples, got %v", sent)
+	}
+	if eng.updateStreams(map[uint64]int{7: 3}); len(machine) != 0 || fills != 1 {
+		t.Errorf("Expected no samples while enough are queued")
+	}
+
+	// disposed noises release their streams.
+	p.Dispose(PovNoise)
+	if rd, ok := nextMsg(machine).(*releaseData); !ok || rd.data != s.snd || len(eng.streams) != 0 {
+		t.Errorf("Expected released stream, got %+v", rd)
+	}
+}
+
+// The machine queues samples and reports the queued buffers.
+func TestMixerStreams(t *testing.T) {
+	ac, mx := &testAudio{}, newMixer()
+	mx.queue(ac, &queueSound{sid: 7, data: bytes.Repeat([]byte{0}, 4)})
+	ac.check(t, "queue 7 4")
+	queue := map[uint64]int{9: 1}
+	if mx.queued(ac, queue); len(queue) != 1 || queue[7] != 2 {
+		t.Errorf("Expected queued buffers, got %v", queue)
+	}
+	mx.release(ac, 7)
+	if mx.queued(ac, queue); len(queue) != 0 {
+		t.Errorf("Expected released streams to not be reported, got %v", queue)
+	}
+}
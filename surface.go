@@ -4,7 +4,11 @@
 package vu
 
 import (
+	"image"
+	"io"
 	"math"
+	"runtime"
+	"sync"
 
 	"github.com/gazed/vu/render"
 )
@@ -16,15 +20,113 @@ type Surface interface {
 	Pts() [][]SurfacePoint      // Per vertex information.
 	Update(m Model, xo, yo int) // Generates rendering data into Model.
 	Resize(w, h int)            // Resize the surface point holders.
+
+	// SetHeights copies height values, ie: an imported land.Topo,
+	// into the surface points. Heights outside the surface are ignored.
+	SetHeights(heights [][]float64)
+
+	// SetBiomes sets each surface point texture atlas index using the
+	// biome identifier, ie: from land.Biomes, as an index into atlas.
+	// Points with biomes outside of atlas are left unchanged.
+	SetBiomes(biomes [][]int, atlas []int)
+
+	// Carve cuts a river, or flattens a road, along a path of x, y
+	// surface location pairs. Points near the path are shaped and
+	// textured as described by the carving.
+	Carve(path []float64, c Carving)
+
+	// Brush raises, lowers, smooths, flattens, or paints the points
+	// around surface location x, y. Returns the changed points so that
+	// they can be passed to UpdateRegion.
+	Brush(x, y float64, b Brush) (x0, y0, x1, y1 int)
+
+	// HeightAt returns the scaled surface height at surface location
+	// x, y. NormalAt returns the unit normal at x, y. Both follow the
+	// triangles generated by Update, including the level of detail
+	// and edge stitching, so that objects can follow the rendered
+	// surface exactly. Locations outside the surface are clamped.
+	HeightAt(x, y float64) float64
+	NormalAt(x, y float64) (nx, ny, nz float64)
+
+	// CollisionMesh returns the x, y, z vertex locations and triangle
+	// faces of the surface rendered by Update, without the per quad
+	// vertex duplication, texture data, or skirts, ie: for a static
+	// mesh collider. The mesh uses the current level of detail, edge
+	// stitching, and holes so that physics matches the rendered surface.
+	// The returned data is newly allocated for each call.
+	CollisionMesh() (verts []float32, faces []uint32)
+
+	// Save writes the surface point heights, texture indicies, blends,
+	// and holes in a compact binary format. Load replaces the surface
+	// points, and size, with previously saved points. Baked ambient
+	// occlusion is not saved and is reset by Load.
+	Save(w io.Writer) error
+	Load(r io.Reader) error
+
+	// SetLod sets the level of detail used by the next Update. Level 0
+	// is full resolution and each higher level doubles the quad size.
+	// The neighbouring surface levels for the left (x=0), right, bottom
+	// (y=0), and top edges are used to match edge heights with coarser
+	// neighbours so that there are no cracks between surfaces.
+	// The levels are used as given, see Quality.Lod for the engine bias.
+	SetLod(level, left, right, bottom, top int)
+
+	// BakeAO calculates the amount of ambient light reaching each
+	// surface point, darkening valleys and leaving ridges lit. More
+	// samples give smoother results. The baked values are included,
+	// as vertex data at layout location 3, by the next call to Update.
+	// BakeAO needs to be called again after the heights change.
+	BakeAO(samples int)
+
+	// SetIndexed switches Update to generate one vertex per surface
+	// point, shared by the neighbouring quads, instead of 4 vertices per
+	// quad. Indexed meshes are about a quarter of the size but have only
+	// one texture atlas index per point and need a shader that wraps the
+	// texture coordinates. Default false.
+	SetIndexed(indexed bool)
+
+	// UpdateRegion regenerates only the rendering data affected by
+	// changes to the points from x0, y0 to x1, y1 inclusive, ie: after
+	// terraforming, and only copies the changed data to the GPU. The
+	// model is expected to be the one used by the last Update. A full
+	// Update is done when the level of detail, indexing, holes, size,
+	// or texture offsets have changed since the last Update.
+	UpdateRegion(m Model, xoff, yoff, x0, y0, x1, y1 int)
+
+	// SetSkirt adds a skirt, a strip of triangles hanging down the given
+	// depth from each surface edge, to the next Update. Skirts hide the
+	// cracks between neighbouring surfaces that have different levels of
+	// detail or that have not been updated yet. The depth is in the same
+	// units as the scaled heights. Default 0 is no skirt.
+	SetSkirt(depth float32)
+
+	// Displace is an alternative to Update for surfaces whose points
+	// change often, ie: water and animated land. It creates a static
+	// grid mesh once and then copies only the surface points, as a
+	// height map texture added after the existing model textures, each
+	// time it is called. The model shader is expected to displace the
+	// grid, ie: eg/source/landd.vsh. Level of detail, skirts, tangents,
+	// and ambient occlusion are not used by displaced surfaces.
+	Displace(m Model, xoff, yoff int)
+
+	// SetTangents adds per vertex tangents, as vertex data at layout
+	// location 4, to the next Update for normal mapping shaders. Each
+	// tangent is a vec4 in the same coordinates as the normals, pointing
+	// along the texture u direction, where w is the handedness of the
+	// bitangent: cross(normal, tangent.xyz) * tangent.w. Default false.
+	SetTangents(tangents bool)
 }
 
 // SurfacePoint stores a height value and a texture atlas index
 // for one point in a Surface. Blend indicates the amount of blending of
 // the texture at the given index with the next texture in the atlas.
+// Hole removes the quad between this point and the next x and y points
+// so that caves, tunnels, and foundations can cut through the surface.
 type SurfacePoint struct {
 	Height float32 // Surface height value.
 	Tindex int     // Surface texture atlas index.
 	Blend  float32 // Texture blend value between 0 and 1.
+	Hole   bool    // True to skip the quad starting at this point.
 }
 
 // NewSurface creates a surface that holds a sx-by-sy set of SurfacePoints.
@@ -45,17 +147,38 @@ func NewSurface(sx, sy, spread int, textureRatio, scale float32) Surface {
 //    images/ : for a texture atlas resource.
 //    source/ : for a surface specific shader.
 type surface struct {
-	tratio float32          // Texture atlas ratio (textureSize/atlasSize).
-	scale  float32          // Height scaling factor.
-	spread int              // Smear texture across tiles. 1, 2, 4, 8, ...
-	pts    [][]SurfacePoint // Per vertex information.
+	tratio  float32          // Texture atlas ratio (textureSize/atlasSize).
+	scale   float32          // Height scaling factor.
+	spread  int              // Smear texture across tiles. 1, 2, 4, 8, ...
+	pts     [][]SurfacePoint // Per vertex information.
+	ao      [][]float32      // Per vertex ambient occlusion. 1 is fully lit.
+	lod     int              // Level of detail. 0 is full resolution.
+	edges   [4]int           // Neighbour levels: left, right, bottom, top.
+	indexed bool             // True to share vertices between quads.
+	skirt   float32          // Skirt depth. 0 for no skirt.
+	skirt0  int              // First skirt vertex.
+	tangent bool             // True to generate tangents.
+	built   layout           // Settings used by the last full Update.
+	project func()           // Optional vertex projection, see Planet.
 
 	// scratch rendering data. Reused each time Update is called.
 	vb  []float32 // Scratch vertex buffer
 	nb  []float32 // Scratch normal buffer
 	tb  []float32 // Scratch texture uv buffer
-	fb  []uint16  // Scratch face buffer
+	ab  []float32 // Scratch ambient occlusion buffer
+	gb  []float32 // Scratch tangent buffer
+	fb  []uint32  // Scratch face buffer
+	f16 []uint16  // Scratch face buffer for smaller surfaces.
+	qv  []int     // First vertex for each quad. -1 for holes.
 	nms [][]xyz   // Scratch for normal calculations.
+	tgs [][]xyz   // Scratch for tangent calculations.
+	avg []float32 // Scratch for smoothing brushes.
+
+	// displaced surface data, see Displace.
+	dmod  *model       // Model holding the grid mesh.
+	dsize [2]int       // Surface size of the grid mesh.
+	dtex  int          // Height map texture index.
+	hmap  *image.NRGBA // Height map image.
 }
 
 // newSurface allocates and initializes surface.
@@ -64,21 +187,47 @@ func newSurface(sx, sy, spread int, textureRatio, scale float32) *surface {
 	s.tratio = textureRatio
 	s.spread = spread
 	s.scale = scale
+	s.allocate(sx, sy)
+	s.vb = []float32{}
+	s.nb = []float32{}
+	s.tb = []float32{}
+	s.ab = []float32{}
+	s.gb = []float32{}
+	s.fb = []uint32{}
+	s.f16 = []uint16{}
+	return s
+}
+
+// clear resets the point data of a reused surface to flat, fully
+// lit land without holes or textures.
+func (s *surface) clear() {
+	for x := range s.pts {
+		for y := range s.pts[x] {
+			s.pts[x][y] = SurfacePoint{}
+			s.ao[x][y] = 1
+		}
+	}
+}
+
+// allocate creates the per point data for a sx-by-sy surface.
+func (s *surface) allocate(sx, sy int) {
 	s.pts = make([][]SurfacePoint, sx)
+	s.ao = make([][]float32, sx)
 	for x := range s.pts {
 		s.pts[x] = make([]SurfacePoint, sy)
+		s.ao[x] = make([]float32, sy)
+		for y := range s.ao[x] {
+			s.ao[x][y] = 1 // fully lit until baked.
+		}
 	}
-	s.vb = []float32{}
-	s.nb = []float32{}
-	s.tb = []float32{}
-	s.fb = []uint16{}
 
-	// scratch for normal generation.
+	// scratch for normal and tangent generation.
 	s.nms = make([][]xyz, len(s.pts))
+	s.tgs = make([][]xyz, len(s.pts))
 	for x := range s.nms {
-		s.nms[x] = make([]xyz, len(s.pts[0]))
+		s.nms[x] = make([]xyz, sy)
+		s.tgs[x] = make([]xyz, sy)
 	}
-	return s
 }
 
 // Implement Surface.
@@ -90,124 +239,725 @@ func (s *surface) Resize(w, h int) {
 	}
 }
 
+// SetHeights implements Surface.
+func (s *surface) SetHeights(heights [][]float64) {
+	for x := 0; x < len(s.pts) && x < len(heights); x++ {
+		for y := 0; y < len(s.pts[x]) && y < len(heights[x]); y++ {
+			s.pts[x][y].Height = float32(heights[x][y])
+		}
+	}
+}
+
+// SetBiomes implements Surface.
+func (s *surface) SetBiomes(biomes [][]int, atlas []int) {
+	for x := 0; x < len(s.pts) && x < len(biomes); x++ {
+		for y := 0; y < len(s.pts[x]) && y < len(biomes[x]); y++ {
+			if b := biomes[x][y]; b >= 0 && b < len(atlas) {
+				s.pts[x][y].Tindex = atlas[b]
+			}
+		}
+	}
+}
+
+// SetLod implements Surface.
+func (s *surface) SetLod(level, left, right, bottom, top int) {
+	if level >= 0 {
+		s.lod = level
+		s.edges = [4]int{left, right, bottom, top}
+	}
+}
+
+// SetIndexed implements Surface.
+func (s *surface) SetIndexed(indexed bool) { s.indexed = indexed }
+
+// SetSkirt implements Surface.
+func (s *surface) SetSkirt(depth float32) { s.skirt = depth }
+
+// SetTangents implements Surface.
+func (s *surface) SetTangents(tangents bool) { s.tangent = tangents }
+
+// height returns the surface height at x, y. Edge heights are
+// interpolated when the neighbour on that edge is coarser so that
+// the edge matches the neighbours edge.
+func (s *surface) height(x, y int) float32 {
+	sx, sy := len(s.pts), len(s.pts[0])
+	switch {
+	case x == 0 && s.edges[0] > s.lod:
+		return s.edgeHeight(x, y, 0, 1, sy, s.edges[0])
+	case x == sx-1 && s.edges[1] > s.lod:
+		return s.edgeHeight(x, y, 0, 1, sy, s.edges[1])
+	case y == 0 && s.edges[2] > s.lod:
+		return s.edgeHeight(x, y, 1, 0, sx, s.edges[2])
+	case y == sy-1 && s.edges[3] > s.lod:
+		return s.edgeHeight(x, y, 1, 0, sx, s.edges[3])
+	}
+	return s.pts[x][y].Height
+}
+
+// edgeHeight interpolates the height at x, y between the points
+// that exist at the given level along the edge direction dx, dy.
+func (s *surface) edgeHeight(x, y, dx, dy, size, level int) float32 {
+	at, step := x*dx+y*dy, 1<<uint(level)
+	a := at / step * step
+	b := a + step
+	if b > size-1 {
+		b = size - 1
+	}
+	if at == a || a == b {
+		return s.pts[x][y].Height
+	}
+	ha := s.pts[x+(a-at)*dx][y+(a-at)*dy].Height
+	hb := s.pts[x+(b-at)*dx][y+(b-at)*dy].Height
+	t := float32(at-a) / float32(b-a)
+	return ha + (hb-ha)*t
+}
+
+// HeightAt implements Surface.
+func (s *surface) HeightAt(x, y float64) float64 {
+	h, _, _, _ := s.triangle(x, y)
+	return h
+}
+
+// NormalAt implements Surface. The normal is the face normal of the
+// rendered triangle in the same coordinates as the Update verticies.
+func (s *surface) NormalAt(x, y float64) (nx, ny, nz float64) {
+	_, nx, ny, nz = s.triangle(x, y)
+	length := math.Sqrt(nx*nx + ny*ny + nz*nz)
+	return nx / length, ny / length, nz / length
+}
+
+// triangle finds the rendered triangle at surface location x, y.
+// Returns the interpolated height and the unnormalized triangle normal.
+// Quads are split from the x1,y0 corner to the x0,y1 corner.
+func (s *surface) triangle(x, y float64) (h, nx, ny, nz float64) {
+	sx, sy := len(s.pts), len(s.pts[0])
+	if sx < 2 || sy < 2 {
+		return float64(s.pts[0][0].Height * s.scale), 0, 0, 1
+	}
+	step := 1 << uint(s.lod)
+	x = math.Max(0, math.Min(x, float64(sx-1)))
+	y = math.Max(0, math.Min(y, float64(sy-1)))
+	x0, y0 := imin(int(x), sx-2)/step*step, imin(int(y), sy-2)/step*step
+	x1, y1 := next(x0, step, sx), next(y0, step, sy)
+	dx, dy := float64(x1-x0), float64(y1-y0)
+	u, v := (x-float64(x0))/dx, (y-float64(y0))/dy
+	scale := float64(s.scale)
+	h00, h10 := float64(s.height(x0, y0))*scale, float64(s.height(x1, y0))*scale
+	h01, h11 := float64(s.height(x0, y1))*scale, float64(s.height(x1, y1))*scale
+	if u+v <= 1 {
+		h = h00 + (h10-h00)*u + (h01-h00)*v
+		return h, -(h10 - h00) * dy, -(h01 - h00) * dx, dx * dy
+	}
+	h = h11 + (h01-h11)*(1-u) + (h10-h11)*(1-v)
+	return h, (h01 - h11) * dy, (h10 - h11) * dx, dx * dy
+}
+
+// CollisionMesh implements Surface. There is one vertex for each
+// level of detail grid point, like an indexed surface, and the quads
+// are split the same way as the rendered quads.
+func (s *surface) CollisionMesh() (verts []float32, faces []uint32) {
+	sx, sy := len(s.pts), len(s.pts[0])
+	step, ys := 1<<uint(s.lod), s.rows()
+	for x := 0; x < sx; x = next(x, step, sx) {
+		for y := 0; y < sy; y = next(y, step, sy) {
+			verts = append(verts, float32(x), float32(y), s.height(x, y)*s.scale)
+		}
+	}
+	for i, x0 := 0, 0; x0 < sx-1; i, x0 = i+1, next(x0, step, sx) {
+		for j, y0 := 0, 0; y0 < sy-1; j, y0 = j+1, next(y0, step, sy) {
+			if s.hole(x0, y0, next(x0, step, sx), next(y0, step, sy)) {
+				continue
+			}
+			v0 := uint32(i*ys + j)
+			v1, v2, v3 := v0+uint32(ys), v0+1, v0+uint32(ys)+1
+			faces = append(faces, v0, v1, v2, v1, v3, v2)
+		}
+	}
+	return verts, faces
+}
+
 // Update recalculates the vertex data needed to render the given land patch.
 // It also uses the texture index to assign a textures from a texture atlas
 func (s *surface) Update(m Model, xoff, yoff int) {
-	vb := s.vb[:0] // keep any allocated memory.
-	nb := s.nb[:0] //   "
-	tb := s.tb[:0] //   "
-	fb := s.fb[:0] //   "
-
-	// generate the per-vertex normals based on the slopes to connecting verticies.
-	// http://www.flipcode.com/archives/Calculating_Vertex_Normals_for_Height_Maps.shtml
-	// http://www.gamedev.net/topic/163625-fast-way-to-calculate-heightmap-normals/
+	s.vb = s.vb[:0] // keep any allocated memory.
+	s.nb = s.nb[:0] //   "
+	s.tb = s.tb[:0] //   "
+	s.ab = s.ab[:0] //   "
+	s.gb = s.gb[:0] //   "
+	s.fb = s.fb[:0] //   "
+	s.qv = s.qv[:0] //   "
 	sx, sy := len(s.pts), len(s.pts[0])
-	norms := s.nms
-	yScale, xzScale := s.scale, float32(1)
-	for x := 0; x < sx; x++ {
-		for y := 0; y < sy; y++ {
+	s.normals(0, 0, sx-1, sy-1)
+	s.built = layout{s.lod, s.edges, s.indexed, s.skirt, s.tangent, sx, sy, xoff, yoff}
+	if s.indexed {
+		s.updateIndexed(xoff, yoff)
+		s.skirts(true)
+		s.bind(m)
+		return
+	}
 
-			// average xslope
-			xmax, xmin := x, x
-			if xmax < sx-1 {
-				xmax++
+	// Find the first vertex of each quad and generate the triangle faces.
+	step := 1 << uint(s.lod)
+	vc := uint32(0) // vertex counter.
+	for x0 := 0; x0 < sx-1; x0 += step {
+		x1 := x0 + step
+		if x1 > sx-1 {
+			x1 = sx - 1
+		}
+		for y0 := 0; y0 < sy-1; y0 += step {
+			y1 := y0 + step
+			if y1 > sy-1 {
+				y1 = sy - 1
 			}
-			if xmin > 0 {
-				xmin--
+			if s.hole(x0, y0, x1, y1) {
+				s.qv = append(s.qv, -1)
+				continue
 			}
-			xslope := float32(s.pts[xmax][y].Height - s.pts[xmin][y].Height)
-			if x == 0 || x == sx-1 {
-				xslope *= 2
+			s.qv = append(s.qv, int(vc))
+			s.fb = append(s.fb, vc, vc+1, vc+2, vc+1, vc+3, vc+2)
+			vc += 4
+		}
+	}
+
+	// Generate the verticies and matching normals. Each quad writes
+	// its own verticies so the quads are generated in parallel.
+	s.resize(int(vc))
+	qy := (sy-2)/step + 1 // quads along y.
+	parallel(len(s.qv)/qy, func(lo, hi int) {
+		for i := lo; i < hi; i++ {
+			x0 := i * step
+			x1 := next(x0, step, sx)
+			for j := 0; j < qy; j++ {
+				if v := s.qv[i*qy+j]; v >= 0 {
+					y0 := j * step
+					s.quad(v, x0, y0, x1, next(y0, step, sy), xoff, yoff)
+				}
 			}
+		}
+	})
+	s.skirts(true)
+	s.bind(m)
+}
 
-			// average yslope
-			ymax, ymin := y, y
-			if ymax < sy-1 {
-				ymax++
+// UpdateRegion implements Surface. Height changes affect the normals
+// of neighbouring points and any edge heights that are stitched to a
+// coarser neighbour, so the region is grown to cover these points.
+// The changed verticies are regenerated in place.
+func (s *surface) UpdateRegion(m Model, xoff, yoff, x0, y0, x1, y1 int) {
+	sx, sy := len(s.pts), len(s.pts[0])
+	if len(s.qv) == 0 || s.project != nil || s.built != (layout{s.lod, s.edges, s.indexed, s.skirt, s.tangent, sx, sy, xoff, yoff}) {
+		s.Update(m, xoff, yoff)
+		return
+	}
+	reach := s.lod
+	for _, level := range s.edges {
+		if level > reach {
+			reach = level
+		}
+	}
+	reach = 1 << uint(reach)
+	x0, y0 = iclamp(x0-reach, 0, sx-1), iclamp(y0-reach, 0, sy-1)
+	x1, y1 = iclamp(x1+reach, 0, sx-1), iclamp(y1+reach, 0, sy-1)
+	s.normals(x0, y0, x1, y1)
+
+	// regenerate the quads that touch the region.
+	step, verts := 1<<uint(s.lod), len(s.ab)
+	ys := s.rows()
+	lo, hi, q := verts, 0, 0 // changed vertex range and quad index.
+	for i, qx0 := 0, 0; qx0 < sx-1; i, qx0 = i+1, qx0+step {
+		qx1 := next(qx0, step, sx)
+		for j, qy0 := 0, 0; qy0 < sy-1; j, qy0, q = j+1, qy0+step, q+1 {
+			qy1 := next(qy0, step, sy)
+			if qx1 < x0 || qx0 > x1 || qy1 < y0 || qy0 > y1 {
+				continue
 			}
-			if ymin > 0 {
-				ymin--
+			at := s.qv[q]
+			if s.hole(qx0, qy0, qx1, qy1) != (at < 0) {
+				s.Update(m, xoff, yoff) // holes changed.
+				return
 			}
-			yslope := float32(s.pts[x][ymax].Height - s.pts[x][ymin].Height)
-			if y == 0 || y == sy-1 {
-				yslope *= 2
+			switch {
+			case s.indexed:
+				v0 := i*ys + j
+				for _, v := range [4][3]int{{v0, qx0, qy0}, {v0 + ys, qx1, qy0}, {v0 + 1, qx0, qy1}, {v0 + ys + 1, qx1, qy1}} {
+					s.point(v[0], v[1], v[2], xoff, yoff)
+				}
+				lo, hi = imin(lo, v0), imax(hi, v0+ys+2)
+			case at >= 0:
+				s.quad(at, qx0, qy0, qx1, qy1, xoff, yoff)
+				lo, hi = imin(lo, at), imax(hi, at+4)
 			}
+		}
+	}
+	if s.skirt != 0 && (x0 == 0 || y0 == 0 || x1 == sx-1 || y1 == sy-1) {
+		s.resize(s.skirt0)
+		s.skirts(false) // region reaches the edge skirts.
+		lo, hi = imin(lo, s.skirt0), verts
+	}
+	if lo < hi {
+		m.SetMeshRange(0, lo, s.vb[lo*3:hi*3])
+		m.SetMeshRange(1, lo, s.nb[lo*3:hi*3])
+		m.SetMeshRange(2, lo, s.tb[lo*4:hi*4])
+		m.SetMeshRange(3, lo, s.ab[lo:hi])
+		if s.tangent {
+			m.SetMeshRange(4, lo, s.gb[lo*4:hi*4])
+		}
+	}
+}
+
+// resize sets the vertex buffers to hold the given number of
+// verticies. Existing verticies are kept and the underlying memory
+// is only reallocated when the buffers grow beyond their capacity.
+func (s *surface) resize(verts int) {
+	s.vb, s.nb, s.tb, s.ab = grow(s.vb, verts*3), grow(s.nb, verts*3), grow(s.tb, verts*4), grow(s.ab, verts)
+	if s.tangent {
+		s.gb = grow(s.gb, verts*4)
+	}
+}
+
+// grow returns buffer b with length n, reusing the memory when possible.
+func grow(b []float32, n int) []float32 {
+	if n > cap(b) {
+		nb := make([]float32, n, n+n/4)
+		copy(nb, b)
+		return nb
+	}
+	return b[:n]
+}
+
+// layout records the settings used by the last full Update.
+// A partial update reuses the existing buffers only when the
+// layout has not changed.
+type layout struct {
+	lod        int     // Level of detail.
+	edges      [4]int  // Neighbour levels of detail.
+	indexed    bool    // Shared or unique verticies.
+	skirt      float32 // Skirt depth.
+	tangent    bool    // Tangents included.
+	sx, sy     int     // Surface size.
+	xoff, yoff int     // Texture offsets.
+}
 
-			// store the unit length normal.
-			nx, ny, nz := -xslope*yScale, 2*xzScale, yslope*yScale
-			length := float32(math.Sqrt(float64(nx*nx + ny*ny + nz*nz)))
-			norms[x][y].x, norms[x][y].y, norms[x][y].z = nx/length, ny/length, nz/length
+// normals generates the per-vertex normals, for the points from x0, y0
+// to x1, y1 inclusive, based on the slopes to connecting verticies.
+// The tangents follow the x slope which is the texture u direction.
+// http://www.flipcode.com/archives/Calculating_Vertex_Normals_for_Height_Maps.shtml
+// http://www.gamedev.net/topic/163625-fast-way-to-calculate-heightmap-normals/
+func (s *surface) normals(x0, y0, x1, y1 int) {
+	sx, sy := len(s.pts), len(s.pts[0])
+	yScale, xzScale := s.scale, float32(1)
+	parallel(x1-x0+1, func(lo, hi int) {
+		for x := x0 + lo; x < x0+hi; x++ {
+			s.normalRow(x, y0, y1, sx, sy, yScale, xzScale)
+		}
+	})
+}
+
+// normalRow generates the normals for points x, y0 to x, y1.
+func (s *surface) normalRow(x, y0, y1, sx, sy int, yScale, xzScale float32) {
+	norms := s.nms
+	for y := y0; y <= y1; y++ {
+
+		// average xslope
+		xmax, xmin := x, x
+		if xmax < sx-1 {
+			xmax++
+		}
+		if xmin > 0 {
+			xmin--
+		}
+		xslope := float32(s.pts[xmax][y].Height - s.pts[xmin][y].Height)
+		if x == 0 || x == sx-1 {
+			xslope *= 2
+		}
+
+		// average yslope
+		ymax, ymin := y, y
+		if ymax < sy-1 {
+			ymax++
+		}
+		if ymin > 0 {
+			ymin--
+		}
+		yslope := float32(s.pts[x][ymax].Height - s.pts[x][ymin].Height)
+		if y == 0 || y == sy-1 {
+			yslope *= 2
+		}
+
+		// store the unit length normal.
+		nx, ny, nz := -xslope*yScale, 2*xzScale, yslope*yScale
+		length := float32(math.Sqrt(float64(nx*nx + ny*ny + nz*nz)))
+		norms[x][y].x, norms[x][y].y, norms[x][y].z = nx/length, ny/length, nz/length
+		if s.tangent {
+			tx, ty := 2*xzScale, xslope*yScale
+			length = float32(math.Sqrt(float64(tx*tx + ty*ty)))
+			s.tgs[x][y].x, s.tgs[x][y].y, s.tgs[x][y].z = tx/length, ty/length, 0
 		}
 	}
+}
+
+// quad sets the 4 unique verticies, starting at vertex v, for the quad
+// from x0, y0 to x1, y1. The vertex buffers are expected to be sized.
+// Appending to the capacity limited buffer slices writes the vertex
+// data in place.
+func (s *surface) quad(v, x0, y0, x1, y1, xoff, yoff int) {
+	vb, nb := s.vb[v*3:v*3:v*3+12], s.nb[v*3:v*3:v*3+12]
+	tb, ab, norms := s.tb[v*4:v*4:v*4+16], s.ab[v:v:v+4], s.nms
 
 	// UV texture coordinate values.
 	textureRatio := s.tratio                  // single texture to texture atlas value.
 	width := textureRatio / float32(s.spread) // tile width.
 	border := float32(0.001)
 
-	// Generate the verticies, triangle faces, and matching normals.
+	// Coarser levels of detail use larger quads. Quads larger than
+	// the texture spread stretch one texture across the quad.
+	step := 1 << uint(s.lod)
+	tiles, qw := step, width*float32(step) // quad tiles and uv width.
+	if step >= s.spread {
+		tiles, qw = s.spread, textureRatio
+	}
+
+	// Generate the verticies for one quad.
 	hscale := s.scale // scaling range of 1 to -1
-	vc := uint16(0)   // vertex counter.
-	for x := 0; x < sx-1; x++ {
-		for y := 0; y < sy-1; y++ {
-
-			// Generate the verticies for one quad.
-			vx0, vy0, vz0 := float32(x), float32(y), s.pts[x][y].Height*hscale
-			vx1, vy1, vz1 := float32(x+1), float32(y), s.pts[x+1][y].Height*hscale
-			vx2, vy2, vz2 := float32(x), float32(y+1), s.pts[x][y+1].Height*hscale
-			vx3, vy3, vz3 := float32(x+1), float32(y+1), s.pts[x+1][y+1].Height*hscale
-			vb = append(vb, vx0, vy0, vz0)
-			vb = append(vb, vx1, vy1, vz1)
-			vb = append(vb, vx2, vy2, vz2)
-			vb = append(vb, vx3, vy3, vz3)
-
-			// Pack the uv indicies with the texture index and blend factor.
-			basex := float32((x+xoff)%s.spread) / float32(s.spread)
-			basey := 1.0 - float32((y+yoff)%s.spread)/float32(s.spread) - 1/float32(s.spread)
-			uv0, uv1 := basex*textureRatio, basey*textureRatio+width       // uv0 top-left     0,1
-			uv2, uv3 := basex*textureRatio+width, basey*textureRatio+width // uv1 top-right    1,1
-			uv4, uv5 := basex*textureRatio, basey*textureRatio             // uv3 bottom-left  0,0
-			uv6, uv7 := basex*textureRatio+width, basey*textureRatio       // uv4 bottom-right 1,0
-
-			// Add a small border to the outside of the overall texture
-			// to avoid a white line between textures.
-			if uv0 == 0 {
-				uv0 += border
-				uv4 += border
+	vx0, vy0, vz0 := float32(x0), float32(y0), s.height(x0, y0)*hscale
+	vx1, vy1, vz1 := float32(x1), float32(y0), s.height(x1, y0)*hscale
+	vx2, vy2, vz2 := float32(x0), float32(y1), s.height(x0, y1)*hscale
+	vx3, vy3, vz3 := float32(x1), float32(y1), s.height(x1, y1)*hscale
+	vb = append(vb, vx0, vy0, vz0)
+	vb = append(vb, vx1, vy1, vz1)
+	vb = append(vb, vx2, vy2, vz2)
+	vb = append(vb, vx3, vy3, vz3)
+
+	// Pack the uv indicies with the texture index and blend factor.
+	basex := float32((x0+xoff)%s.spread) / float32(s.spread)
+	basey := 1.0 - float32((y0+yoff)%s.spread)/float32(s.spread) - float32(tiles)/float32(s.spread)
+	if tiles == s.spread {
+		basex, basey = 0, 0
+	}
+	uv0, uv1 := basex*textureRatio, basey*textureRatio+qw    // uv0 top-left     0,1
+	uv2, uv3 := basex*textureRatio+qw, basey*textureRatio+qw // uv1 top-right    1,1
+	uv4, uv5 := basex*textureRatio, basey*textureRatio       // uv3 bottom-left  0,0
+	uv6, uv7 := basex*textureRatio+qw, basey*textureRatio    // uv4 bottom-right 1,0
+
+	// Add a small border to the outside of the overall texture
+	// to avoid a white line between textures.
+	if uv0 == 0 {
+		uv0 += border
+		uv4 += border
+	}
+	if uv2 == textureRatio {
+		uv2 -= border
+		uv6 -= border
+	}
+	if uv5 == 0 {
+		uv5 += border
+		uv7 += border
+	}
+	if uv1 == textureRatio {
+		uv1 -= border
+		uv3 -= border
+	}
+	tindex, blend := float32(s.pts[x0][y0].Tindex), s.pts[x0][y0].Blend
+	tb = append(tb, uv0, uv1, tindex, blend)
+	tb = append(tb, uv2, uv3, tindex, blend)
+	tb = append(tb, uv4, uv5, tindex, blend)
+	tb = append(tb, uv6, uv7, tindex, blend)
+
+	// Ambient occlusion for each vertex in the map quad.
+	ab = append(ab, s.ao[x0][y0], s.ao[x1][y0], s.ao[x0][y1], s.ao[x1][y1])
+
+	// Add normal information for each vertex in the map quad.
+	nb = append(nb, norms[x0][y0].x, norms[x0][y0].y, norms[x0][y0].z)
+	nb = append(nb, norms[x1][y0].x, norms[x1][y0].y, norms[x1][y0].z)
+	nb = append(nb, norms[x0][y1].x, norms[x0][y1].y, norms[x0][y1].z)
+	nb = append(nb, norms[x1][y1].x, norms[x1][y1].y, norms[x1][y1].z)
+	if s.tangent {
+		s.tangents(v, x0, y0)
+		s.tangents(v+1, x1, y0)
+		s.tangents(v+2, x0, y1)
+		s.tangents(v+3, x1, y1)
+	}
+}
+
+// tangents sets the vertex v tangent for surface point x, y. The texture
+// v direction is opposite to y so the bitangent handedness is negative.
+func (s *surface) tangents(v, x, y int) {
+	t := s.tgs[x][y]
+	s.gb[v*4], s.gb[v*4+1], s.gb[v*4+2], s.gb[v*4+3] = t.x, t.y, t.z, -1
+}
+
+// point sets shared vertex v for surface point x, y.
+func (s *surface) point(v, x, y, xoff, yoff int) {
+	n, spread := s.nms[x][y], float32(s.spread)
+	s.vb[v*3], s.vb[v*3+1], s.vb[v*3+2] = float32(x), float32(y), s.height(x, y)*s.scale
+	s.nb[v*3], s.nb[v*3+1], s.nb[v*3+2] = n.x, n.y, n.z
+	tu, tv := float32(x+xoff)/spread, -float32(y+yoff)/spread
+	s.tb[v*4], s.tb[v*4+1], s.tb[v*4+2], s.tb[v*4+3] = tu, tv, float32(s.pts[x][y].Tindex), s.pts[x][y].Blend
+	s.ab[v] = s.ao[x][y]
+	if s.tangent {
+		s.tangents(v, x, y)
+	}
+}
+
+// rows returns the number of level of detail grid points along y.
+func (s *surface) rows() (ys int) {
+	sy, step := len(s.pts[0]), 1<<uint(s.lod)
+	for y := 0; y < sy; y = next(y, step, sy) {
+		ys++
+	}
+	return ys
+}
+
+// skirts appends the skirt verticies below the edges of the surface
+// quads generated by the last Update. The skirt faces are also added
+// when faces is true. Skirts are after all the surface verticies so
+// that the surface vertex locations are the same with or without a
+// skirt. Skirt verticies copy the edge verticies, which are lowered
+// by the skirt depth, so that skirts are lit and textured like the
+// edge of the surface.
+func (s *surface) skirts(faces bool) {
+	s.skirt0 = len(s.ab)
+	if s.skirt == 0 {
+		return
+	}
+	sx, sy := len(s.pts), len(s.pts[0])
+	step, ys, q := 1<<uint(s.lod), s.rows(), 0
+	for x0 := 0; x0 < sx-1; x0 += step {
+		x1 := next(x0, step, sx)
+		for y0 := 0; y0 < sy-1; y0, q = y0+step, q+1 {
+			y1 := next(y0, step, sy)
+			v0 := s.qv[q]
+			if v0 < 0 {
+				continue // no skirt below holes.
 			}
-			if uv2 == textureRatio {
-				uv2 -= border
-				uv6 -= border
+			c := [4]int{v0, v0 + 1, v0 + 2, v0 + 3}
+			if s.indexed {
+				c = [4]int{v0, v0 + ys, v0 + 1, v0 + ys + 1}
 			}
-			if uv5 == 0 {
-				uv5 += border
-				uv7 += border
+
+			// edge verticies in the order that faces the skirt outwards.
+			for _, edge := range [4][3]int{{y0, 0, 1}, {sx - 1 - x1, 1, 3}, {sy - 1 - y1, 3, 2}, {x0, 2, 0}} {
+				if edge[0] == 0 {
+					s.skirtQuad(c[edge[1]], c[edge[2]], faces)
+				}
 			}
-			if uv1 == textureRatio {
-				uv1 -= border
-				uv3 -= border
+		}
+	}
+}
+
+// skirtQuad appends the skirt below the edge from vertex a to vertex b.
+func (s *surface) skirtQuad(a, b int, faces bool) {
+	vc := uint32(len(s.ab))
+	for cnt, v := range [4]int{a, b, a, b} {
+		z := s.vb[v*3+2]
+		if cnt >= 2 {
+			z -= s.skirt
+		}
+		s.vb = append(s.vb, s.vb[v*3], s.vb[v*3+1], z)
+		s.nb = append(s.nb, s.nb[v*3], s.nb[v*3+1], s.nb[v*3+2])
+		s.tb = append(s.tb, s.tb[v*4], s.tb[v*4+1], s.tb[v*4+2], s.tb[v*4+3])
+		s.ab = append(s.ab, s.ab[v])
+		if s.tangent {
+			s.gb = append(s.gb, s.gb[v*4], s.gb[v*4+1], s.gb[v*4+2], s.gb[v*4+3])
+		}
+	}
+	if faces {
+		s.fb = append(s.fb, vc, vc+2, vc+1, vc+1, vc+2, vc+3)
+	}
+}
+
+// maxShortIndex is the most verticies that can be
+// referenced using 16 bit face indicies.
+const maxShortIndex = 1 << 16
+
+// bind copies the generated scratch buffers into the model mesh.
+func (s *surface) bind(m Model) {
+	if s.project != nil {
+		s.project()
+	}
+	m.InitMesh(0, 3, render.DynamicDraw, false).SetMeshData(0, s.vb)
+	m.InitMesh(1, 3, render.DynamicDraw, false).SetMeshData(1, s.nb)
+	m.InitMesh(2, 4, render.DynamicDraw, false).SetMeshData(2, s.tb)
+	m.InitMesh(3, 1, render.DynamicDraw, false).SetMeshData(3, s.ab)
+	if s.tangent {
+		m.InitMesh(4, 4, render.DynamicDraw, false).SetMeshData(4, s.gb)
+	}
+	s.f16 = bindFaces(m, len(s.vb)/3, s.fb, s.f16)
+}
+
+// bindFaces initializes and sets the model faces. The faces are copied
+// into the 16 bit scratch faces, which are returned for reuse, when there
+// are few enough verticies.
+func bindFaces(m Model, verts int, fb []uint32, f16 []uint16) []uint16 {
+	m.InitFaces(render.DynamicDraw)
+	if verts > maxShortIndex {
+		m.SetFaces(fb)
+		return f16
+	}
+	f16 = f16[:0]
+	for _, f := range fb {
+		f16 = append(f16, uint16(f))
+	}
+	m.SetFaces(f16)
+	return f16
+}
+
+// updateIndexed generates one vertex for each surface point used by
+// the current level of detail. Neighbouring quads share vertices.
+// The texture uv values are not wrapped into the atlas since shared
+// vertices are used by more than one tile. Instead a uv of 1 is the
+// width of one texture and the shader is expected to wrap the uv
+// values, ie: fract(uv)*ratio, before indexing the atlas.
+func (s *surface) updateIndexed(xoff, yoff int) {
+	sx, sy := len(s.pts), len(s.pts[0])
+	step := 1 << uint(s.lod)
+
+	// vertices for each point on the level of detail grid.
+	// The last row and column are always included. Each point
+	// writes its own vertex so the points are generated in parallel.
+	xs, ys := 0, s.rows() // grid points along x and y.
+	for x := 0; x < sx; x = next(x, step, sx) {
+		xs++
+	}
+	s.resize(xs * ys)
+	parallel(xs, func(lo, hi int) {
+		for i := lo; i < hi; i++ {
+			x := imin(i*step, sx-1)
+			for j := 0; j < ys; j++ {
+				s.point(i*ys+j, x, imin(j*step, sy-1), xoff, yoff)
 			}
-			tindex, blend := float32(s.pts[x][y].Tindex), s.pts[x][y].Blend
-			tb = append(tb, uv0, uv1, tindex, blend)
-			tb = append(tb, uv2, uv3, tindex, blend)
-			tb = append(tb, uv4, uv5, tindex, blend)
-			tb = append(tb, uv6, uv7, tindex, blend)
-
-			// Generate the triangle faces for the above quad.
-			fb = append(fb, vc, vc+1, vc+2, vc+1, vc+3, vc+2)
-			vc += 4
+		}
+	})
+
+	// two triangles for each grid quad using the same
+	// vertex order as the unique vertex quads.
+	for i, x0 := 0, 0; i < xs-1; i, x0 = i+1, next(x0, step, sx) {
+		for j, y0 := 0, 0; j < ys-1; j, y0 = j+1, next(y0, step, sy) {
+			v0 := uint32(i*ys + j)
+			if s.hole(x0, y0, next(x0, step, sx), next(y0, step, sy)) {
+				s.qv = append(s.qv, -1)
+				continue
+			}
+			s.qv = append(s.qv, int(v0))
+			v1, v2, v3 := v0+uint32(ys), v0+1, v0+uint32(ys)+1
+			s.fb = append(s.fb, v0, v1, v2, v1, v3, v2)
+		}
+	}
+}
+
+// hole returns true if any of the full resolution quads covered
+// by the quad from x0, y0 to x1, y1 has been marked as a hole.
+func (s *surface) hole(x0, y0, x1, y1 int) bool {
+	for x := x0; x < x1; x++ {
+		for y := y0; y < y1; y++ {
+			if s.pts[x][y].Hole {
+				return true
+			}
+		}
+	}
+	return false
+}
 
-			// Add normal information for each vertex in the map quad.
-			nb = append(nb, norms[x][y].x, norms[x][y].y, norms[x][y].z)
-			nb = append(nb, norms[x+1][y].x, norms[x+1][y].y, norms[x+1][y].z)
-			nb = append(nb, norms[x][y+1].x, norms[x][y+1].y, norms[x][y+1].z)
-			nb = append(nb, norms[x+1][y+1].x, norms[x+1][y+1].y, norms[x+1][y+1].z)
+// minParallel is the fewest rows of surface points given
+// to each goroutine when work is split across goroutines.
+const minParallel = 64
+
+// parallel splits the rows from 0 to n into ranges that are worked on
+// by separate goroutines, one for each available processor, and waits
+// for all of them to finish. Small amounts of work are done directly.
+func parallel(n int, work func(lo, hi int)) {
+	workers := runtime.GOMAXPROCS(0)
+	if workers > n/minParallel {
+		workers = n / minParallel
+	}
+	if workers <= 1 {
+		work(0, n)
+		return
+	}
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(lo, hi int) {
+			defer wg.Done()
+			work(lo, hi)
+		}(n*w/workers, n*(w+1)/workers)
+	}
+	wg.Wait()
+}
+
+// iclamp returns v limited to the range lo to hi.
+func iclamp(v, lo, hi int) int {
+	switch {
+	case v < lo:
+		return lo
+	case v > hi:
+		return hi
+	}
+	return v
+}
+
+// imin returns the smaller of a and b.
+func imin(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// imax returns the larger of a and b.
+func imax(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// next returns the next level of detail grid location after at.
+// The last location, size-1, is always returned before size.
+func next(at, step, size int) int {
+	if at == size-1 {
+		return size
+	}
+	if at += step; at > size-1 {
+		return size - 1
+	}
+	return at
+}
+
+// aoRadius is the number of surface points checked in each
+// direction when baking ambient occlusion.
+const aoRadius = 8
+
+// BakeAO estimates how much of the sky is visible from each surface
+// point. For each sample direction the steepest rise to a nearby point
+// is found. The sine of the rise angle is the occluded amount.
+func (s *surface) BakeAO(samples int) {
+	if samples < 1 {
+		samples = 1
+	}
+	sx, sy := len(s.pts), len(s.pts[0])
+	scale := float64(s.scale)
+	for x := 0; x < sx; x++ {
+		for y := 0; y < sy; y++ {
+			h := float64(s.pts[x][y].Height) * scale
+			occlusion := 0.0
+			for cnt := 0; cnt < samples; cnt++ {
+				dx, dy := math.Cos(2*math.Pi*float64(cnt)/float64(samples)), math.Sin(2*math.Pi*float64(cnt)/float64(samples))
+				slope := 0.0 // steepest rise over distance.
+				for step := 1.0; step <= aoRadius; step++ {
+					px, py := x+int(math.Floor(dx*step+0.5)), y+int(math.Floor(dy*step+0.5))
+					if px < 0 || px >= sx || py < 0 || py >= sy {
+						break
+					}
+					if dist := math.Hypot(float64(px-x), float64(py-y)); dist > 0 {
+						slope = math.Max(slope, (float64(s.pts[px][py].Height)*scale-h)/dist)
+					}
+				}
+				occlusion += slope / math.Sqrt(1+slope*slope) // sin(atan(slope))
+			}
+			s.ao[x][y] = float32(1 - occlusion/float64(samples))
 		}
 	}
-	m.InitMesh(0, 3, render.DynamicDraw, false).SetMeshData(0, vb)
-	m.InitMesh(1, 3, render.DynamicDraw, false).SetMeshData(1, nb)
-	m.InitMesh(2, 4, render.DynamicDraw, false).SetMeshData(2, tb)
-	m.InitFaces(render.DynamicDraw).SetFaces(fb)
 }
 
 type xyz struct{ x, y, z float32 } // temporary structure for generating normals.
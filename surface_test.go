@@ -0,0 +1,618 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// The surface baseline tests guard the Surface.Update mesh generation.
+// A fixed surface is generated and its vertex, normal, texture, and face
+// buffers are compared against testdata/surface.golden. The buffers are
+// also rasterized on the CPU, top down with simple lighting, and compared
+// against testdata/surface.png. The CPU image is used instead of a GPU
+// screenshot so the check is exact across machines and runs without a
+// display. After an intended change to the surface mesh, regenerate the
+// baselines, and review the image, using:
+//     go test -run Surface -surface.update
+var updateSurface = flag.Bool("surface.update", false, "rewrite surface baselines")
+
+// surfaceBaseline is the baseline file location.
+const surfaceBaseline = "testdata/surface"
+
+// Check the generated surface buffers against the baseline.
+func TestSurfaceBuffers(t *testing.T) {
+	s := fixedSurface()
+	got := dumpSurface(s)
+	if *updateSurface {
+		if err := ioutil.WriteFile(surfaceBaseline+".golden", got, 0644); err != nil {
+			t.Fatalf("Could not update baseline %s", err)
+		}
+		return
+	}
+	want, err := ioutil.ReadFile(surfaceBaseline + ".golden")
+	if err != nil {
+		t.Fatalf("Missing baseline, run with -surface.update %s", err)
+	}
+	if err := diffSurface(want, got); err != nil {
+		t.Error(err)
+	}
+}
+
+// Check the rasterized surface against the baseline image.
+func TestSurfaceImage(t *testing.T) {
+	got := rasterSurface(fixedSurface(), 8)
+	if *updateSurface {
+		if err := writePng(surfaceBaseline+".png", got); err != nil {
+			t.Fatalf("Could not update baseline %s", err)
+		}
+		return
+	}
+	file, err := os.Open(surfaceBaseline + ".png")
+	if err != nil {
+		t.Fatalf("Missing baseline, run with -surface.update %s", err)
+	}
+	defer file.Close()
+	want, err := png.Decode(file)
+	if err != nil {
+		t.Fatalf("Invalid baseline image %s", err)
+	}
+	if diffs := diffImage(want, got, 2); diffs > 0 {
+		out := filepath.Join(os.TempDir(), "surface.png")
+		writePng(out, got)
+		t.Errorf("Surface image has %d different pixels, see %s", diffs, out)
+	}
+}
+
+// Check that valleys are darker than ridges and
+// that flat land is not occluded.
+func TestBakeAO(t *testing.T) {
+	s := newSurface(17, 17, 1, 1, 1)
+	s.BakeAO(8)
+	if s.ao[8][8] != 1 {
+		t.Errorf("Expected flat land to be fully lit, got %f", s.ao[8][8])
+	}
+	pts := s.Pts()
+	for x := range pts {
+		for y := range pts[x] {
+			pts[x][y].Height = float32(math.Abs(float64(x)-8)) * 0.5 // v shaped valley.
+		}
+	}
+	s.BakeAO(8)
+	valley, slope, ridge := s.ao[8][8], s.ao[4][8], s.ao[0][8]
+	if !(valley < slope && slope < ridge) || ridge != 1 {
+		t.Errorf("Expected valley %f < slope %f < ridge %f", valley, slope, ridge)
+	}
+}
+
+// Check that coarser levels of detail use fewer quads and
+// that edges are matched to coarser neighbours.
+func TestSurfaceLod(t *testing.T) {
+	s := fixedSurface()
+	full := len(s.fb)
+	s.SetLod(2, 2, 2, 2, 2)
+	s.Update(newModel("surface").NewMesh("surface"), 0, 0)
+	if len(s.fb) != full/16 || len(s.vb) != 4*4*3 {
+		t.Errorf("Expected 4 quads, got %d faces %d verts", len(s.fb)/6, len(s.vb)/3)
+	}
+
+	// full resolution with a coarser left neighbour.
+	s.SetLod(0, 1, 0, 0, 0)
+	pts := s.Pts()
+	want := (pts[0][2].Height + pts[0][4].Height) * 0.5
+	if got := s.height(0, 3); got != want || s.height(1, 3) != pts[1][3].Height {
+		t.Errorf("Expected stitched height %f, got %f", want, got)
+	}
+}
+
+// Check that indexed surfaces share vertices and
+// generate the same triangles as unique vertex quads.
+func TestSurfaceIndexed(t *testing.T) {
+	for _, lod := range []int{0, 1, 3} {
+		s := fixedSurface()
+		s.SetLod(lod, lod, lod, lod, lod)
+		s.Update(newModel("surface").NewMesh("surface"), 1, 2)
+		want := triangles(s)
+		s.SetIndexed(true)
+		s.Update(newModel("surface").NewMesh("surface"), 1, 2)
+		got, side := triangles(s), 8/(1<<uint(lod))+1
+		if len(s.vb) != side*side*3 || len(s.nb) != len(s.vb) || len(s.ab) != side*side {
+			t.Errorf("Lod %d expected %d shared verticies, got %d", lod, side*side, len(s.vb)/3)
+		}
+		if len(got) != len(want) {
+			t.Fatalf("Lod %d expected %d triangles, got %d", lod, len(want), len(got))
+		}
+		for cnt := range want {
+			if got[cnt] != want[cnt] {
+				t.Errorf("Lod %d triangle %d expected %v, got %v", lod, cnt, want[cnt], got[cnt])
+				break
+			}
+		}
+	}
+}
+
+// Check that holes remove quads at all levels of detail
+// for both unique and shared vertex surfaces.
+func TestSurfaceHoles(t *testing.T) {
+	s := fixedSurface()
+	full := len(s.fb)
+	s.Pts()[3][4].Hole = true
+	for _, indexed := range []bool{false, true} {
+		s.SetIndexed(indexed)
+		s.SetLod(0, 0, 0, 0, 0)
+		s.Update(newModel("surface").NewMesh("surface"), 0, 0)
+		if len(s.fb) != full-6 {
+			t.Errorf("Indexed %t expected one less quad, got %d faces", indexed, len(s.fb)/3)
+		}
+		s.SetLod(1, 1, 1, 1, 1) // the 2x2 quad covering 3,4 is removed.
+		s.Update(newModel("surface").NewMesh("surface"), 0, 0)
+		if len(s.fb) != full/4-6 {
+			t.Errorf("Indexed %t expected one less coarse quad, got %d faces", indexed, len(s.fb)/3)
+		}
+	}
+}
+
+// Check that region updates match a full update after editing heights.
+func TestSurfaceRegion(t *testing.T) {
+	for _, indexed := range []bool{false, true} {
+		for _, lod := range []int{0, 1} {
+			s, m := fixedSurface(), newModel("surface").NewMesh("surface")
+			s.SetIndexed(indexed)
+			s.SetLod(lod, lod, lod+1, lod, lod)
+			s.Update(m, 1, 2)
+			s.Pts()[7][3].Height += 0.5 // next to the stitched right edge.
+			s.Pts()[2][2].Height -= 0.5
+			s.UpdateRegion(m, 1, 2, 2, 2, 7, 3)
+			got := dumpSurface(s)
+			s.Update(m, 1, 2)
+			if err := diffSurface(dumpSurface(s), got); err != nil {
+				t.Errorf("Indexed %t lod %d: %s", indexed, lod, err)
+			}
+		}
+	}
+}
+
+// Check that skirts hang outwards below the surface edges
+// and follow edge height changes.
+func TestSurfaceSkirt(t *testing.T) {
+	for _, indexed := range []bool{false, true} {
+		s, m := fixedSurface(), newModel("surface").NewMesh("surface")
+		s.SetIndexed(indexed)
+		s.SetSkirt(2)
+		s.Update(m, 0, 0)
+		if verts := len(s.ab) - s.skirt0; verts != 4*4*8 {
+			t.Errorf("Indexed %t: expected 128 skirt verticies, got %d", indexed, verts)
+		}
+		for i := len(s.fb) - 32*6; i < len(s.fb); i += 6 {
+			a, b, c := s.fb[i]*3, s.fb[i+1]*3, s.fb[i+2]*3
+			if s.vb[b+2] != s.vb[a+2]-2 {
+				t.Fatalf("Indexed %t: expected skirt depth 2", indexed)
+			}
+			ex, ey := s.vb[c]-s.vb[a], s.vb[c+1]-s.vb[a+1] // edge direction.
+			nx, ny := 2*ey, -2*ex                          // down x edge.
+			mx, my := s.vb[a]-4, s.vb[a+1]-4               // from the middle.
+			if nx*mx+ny*my <= 0 {
+				t.Fatalf("Indexed %t: expected outward skirt at %f %f", indexed, s.vb[a], s.vb[a+1])
+			}
+		}
+		s.Pts()[0][3].Height += 0.5
+		s.UpdateRegion(m, 0, 0, 0, 3, 0, 3)
+		got := dumpSurface(s)
+		s.Update(m, 0, 0)
+		if err := diffSurface(dumpSurface(s), got); err != nil {
+			t.Errorf("Indexed %t: %s", indexed, err)
+		}
+	}
+}
+
+// Check that tangents are unit length, perpendicular to the
+// normals, and follow region updates.
+func TestSurfaceTangents(t *testing.T) {
+	for _, indexed := range []bool{false, true} {
+		s, m := fixedSurface(), newModel("surface").NewMesh("surface")
+		s.SetIndexed(indexed)
+		s.SetTangents(true)
+		s.SetSkirt(1)
+		s.Update(m, 0, 0)
+		if len(s.gb) != len(s.ab)*4 {
+			t.Fatalf("Indexed %t: expected a tangent for each vertex", indexed)
+		}
+		for v := 0; v < len(s.ab); v++ {
+			tx, ty, tz := float64(s.gb[v*4]), float64(s.gb[v*4+1]), float64(s.gb[v*4+2])
+			nx, ny, nz := float64(s.nb[v*3]), float64(s.nb[v*3+1]), float64(s.nb[v*3+2])
+			if math.Abs(tx*nx+ty*ny+tz*nz) > 1e-6 || math.Abs(tx*tx+ty*ty+tz*tz-1) > 1e-6 || s.gb[v*4+3] != -1 {
+				t.Fatalf("Indexed %t: expected unit tangent perpendicular to normal at %d", indexed, v)
+			}
+		}
+		s.Pts()[4][0].Height += 0.5
+		s.UpdateRegion(m, 0, 0, 4, 0, 4, 0)
+		got := append([]float32{}, s.gb...)
+		s.Update(m, 0, 0)
+		for i := range got {
+			if got[i] != s.gb[i] {
+				t.Fatalf("Indexed %t: expected matching region tangent at %d", indexed, i/4)
+			}
+		}
+	}
+}
+
+// Check that saved surfaces load with the same points.
+func TestSurfaceSaveLoad(t *testing.T) {
+	s := fixedSurface()
+	s.Pts()[2][3].Hole = true
+	buf := &bytes.Buffer{}
+	if err := s.Save(buf); err != nil {
+		t.Fatalf("Save failed %s", err)
+	}
+	saved := buf.Bytes()
+	loaded := newSurface(3, 3, 1, 1, 1)
+	if err := loaded.Load(bytes.NewReader(saved[:len(saved)-1])); err == nil {
+		t.Errorf("Expected error loading partial data")
+	}
+	for _, size := range []uint32{maxSurfaceSize + 1, 1 << 31, 4000} {
+		huge := append([]byte{}, saved[:12]...)
+		binary.LittleEndian.PutUint32(huge[4:], size)
+		binary.LittleEndian.PutUint32(huge[8:], size)
+		if err := loaded.Load(bytes.NewReader(huge)); err == nil || len(loaded.pts) != 3 {
+			t.Errorf("Expected error loading size %d without changing the surface", size)
+		}
+	}
+	if err := loaded.Load(bytes.NewReader(saved)); err != nil {
+		t.Fatalf("Load failed %s", err)
+	}
+	if len(loaded.pts) != 9 || len(loaded.pts[0]) != 9 {
+		t.Fatalf("Expected 9x9 surface, got %dx%d", len(loaded.pts), len(loaded.pts[0]))
+	}
+	for x := range s.pts {
+		for y := range s.pts[x] {
+			if loaded.pts[x][y] != s.pts[x][y] {
+				t.Fatalf("Expected %v at %d,%d, got %v", s.pts[x][y], x, y, loaded.pts[x][y])
+			}
+		}
+	}
+}
+
+// Check that biomes are mapped to texture atlas indicies.
+func TestSurfaceBiomes(t *testing.T) {
+	s := newSurface(2, 2, 1, 1, 1)
+	s.pts[1][1].Tindex = 9
+	s.SetBiomes([][]int{{0, 1}, {2, 7}}, []int{4, 5, 6})
+	if s.pts[0][0].Tindex != 4 || s.pts[0][1].Tindex != 5 || s.pts[1][0].Tindex != 6 || s.pts[1][1].Tindex != 9 {
+		t.Errorf("Expected atlas indicies, got %v", s.pts)
+	}
+}
+
+// Check that a path is carved downhill with a blended bank.
+func TestSurfaceCarve(t *testing.T) {
+	s := newSurface(16, 16, 1, 1, 1)
+	for x := range s.pts {
+		for y := range s.pts[x] {
+			s.pts[x][y].Height = float32(y) * 0.1 // slope rising in y.
+		}
+	}
+	s.pts[8][4].Height = 2 // bump on the path.
+	s.Carve([]float64{8, 2, 8, 12}, Carving{Width: 1, Falloff: 2, Depth: 0.5, Downhill: true, Tindex: 3})
+	if h := s.pts[8][10].Height; h != -0.3 || s.pts[8][10].Tindex != 3 {
+		t.Errorf("Expected channel below the start height, got %f", h)
+	}
+	if h := s.pts[8][4].Height; h != -0.3 {
+		t.Errorf("Expected bump to be cut, got %f", h)
+	}
+	bank, far := s.pts[10][10], s.pts[12][10]
+	if bank.Height <= 0.2 || bank.Height >= 1 || bank.Tindex != 0 || far.Height != 1 {
+		t.Errorf("Expected blended bank and untouched land, got %f %f", bank.Height, far.Height)
+	}
+}
+
+// Check the brush effect, falloff, and returned region.
+func TestSurfaceBrush(t *testing.T) {
+	s := newSurface(16, 16, 1, 1, 1)
+	x0, y0, x1, y1 := s.Brush(8, 8, Brush{Op: Raise, Radius: 1, Falloff: 2, Strength: 1})
+	if x0 != 5 || y0 != 5 || x1 != 11 || y1 != 11 {
+		t.Errorf("Expected region 5,5 to 11,11, got %d,%d to %d,%d", x0, y0, x1, y1)
+	}
+	if c, r, fade, out := s.pts[8][8].Height, s.pts[9][8].Height, s.pts[10][8].Height, s.pts[11][8].Height; c != 1 || r != 1 || fade != 0.5 || out != 0 {
+		t.Errorf("Expected full, faded, and untouched heights, got %f %f %f %f", c, r, fade, out)
+	}
+	s.Brush(8, 8, Brush{Op: Flatten, Radius: 3, Strength: 1})
+	if h := s.pts[10][8].Height; h != 1 {
+		t.Errorf("Expected flattened height 1, got %f", h)
+	}
+	s.pts[2][2].Height = 9
+	s.Brush(2, 2, Brush{Op: Smooth, Radius: 0, Strength: 1})
+	if h := s.pts[2][2].Height; h != 1 {
+		t.Errorf("Expected smoothed height 1, got %f", h)
+	}
+	s.Brush(0, 0, Brush{Op: Paint, Radius: 1, Tindex: 3, Blend: 0.5})
+	if p := s.pts[1][0]; p.Tindex != 3 || p.Blend != 0.5 || s.pts[1][1].Tindex != 0 {
+		t.Errorf("Expected painted points, got %v", s.pts[1][:2])
+	}
+}
+
+// Check that displaced surfaces keep the grid and only copy the heights.
+func TestSurfaceDisplace(t *testing.T) {
+	eng := newEngine(nil)
+	defer eng.Shutdown()
+	m := eng.Root().NewPov().NewModel("landd").NewMesh("water").(*model)
+	m.NewTex("atlas")
+	s := newSurface(3, 4, 1, 1, 2)
+	s.pts[1][2] = SurfacePoint{Height: 1, Tindex: 5, Blend: 1, Hole: true}
+	s.Displace(m, 0, 0)
+	if len(m.texs) != 2 || s.dtex != 1 || len(s.vb) != 3*4*2 || len(s.fb) != 2*3*6 {
+		t.Fatalf("Expected height map texture and grid, got %d %d %d", len(m.texs), len(s.vb), len(s.fb))
+	}
+	if r := m.Uniform("hrange"); len(r) != 2 || r[0] != 0 || r[1] != 2 {
+		t.Errorf("Expected height range 0 to 2, got %v", r)
+	}
+	if c := s.hmap.NRGBAAt(1, 2); c != (color.NRGBA{255, 255, 133, 255}) || s.hmap.NRGBAAt(0, 0) != (color.NRGBA{}) {
+		t.Errorf("Expected packed height, index, hole, and blend, got %v", c)
+	}
+	s.pts[1][2].Height = 0.5
+	s.Displace(m, 0, 0)
+	if len(m.texs) != 2 || m.texs[1].img != s.hmap || m.Uniform("hrange")[1] != 1 {
+		t.Errorf("Expected only the height map to change")
+	}
+}
+
+// Check that generating large surfaces in parallel
+// gives the same vertex data as generating them serially.
+func TestSurfaceParallel(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(0))
+	for _, indexed := range []bool{false, true} {
+		s, m := newSurface(257, 200, 1, 1, 1), newModel("surface").NewMesh("surface")
+		for x := range s.pts {
+			for y := range s.pts[x] {
+				s.pts[x][y].Height = float32(math.Sin(float64(x)*0.1) * math.Cos(float64(y)*0.2))
+			}
+		}
+		s.pts[100][50].Hole = true
+		s.SetIndexed(indexed)
+		s.SetTangents(true)
+		runtime.GOMAXPROCS(1)
+		s.Update(m, 0, 0)
+		vb, nb, gb := append([]float32{}, s.vb...), append([]float32{}, s.nb...), append([]float32{}, s.gb...)
+		runtime.GOMAXPROCS(4)
+		s.Update(m, 0, 0)
+		if !reflect.DeepEqual(vb, s.vb) || !reflect.DeepEqual(nb, s.nb) || !reflect.DeepEqual(gb, s.gb) {
+			t.Errorf("Indexed %t: expected the same parallel vertex data", indexed)
+		}
+	}
+}
+
+// Check that the collision mesh follows the rendered surface.
+func TestSurfaceCollisionMesh(t *testing.T) {
+	s := newSurface(5, 5, 1, 1, 2)
+	s.pts[2][1].Height = 1
+	s.pts[0][0].Hole = true
+	verts, faces := s.CollisionMesh()
+	if len(verts) != 5*5*3 || len(faces) != 15*6 || verts[(2*5+1)*3+2] != 2 {
+		t.Errorf("Expected 25 verticies and 30 triangles, got %d %d", len(verts)/3, len(faces)/3)
+	}
+	s.SetLod(1, 2, 1, 1, 1) // stitch the left edge to a coarser neighbour.
+	s.pts[0][2].Height = 1  // hidden by the stitching.
+	verts, faces = s.CollisionMesh()
+	if len(verts) != 3*3*3 || len(faces) != 3*6 || verts[1*3+2] != 0 {
+		t.Errorf("Expected 9 verticies, 6 triangles, and a stitched edge, got %d %d %f", len(verts)/3, len(faces)/3, verts[1*3+2])
+	}
+	if faces[0] != 1 || faces[1] != 4 || faces[2] != 2 {
+		t.Errorf("Expected the rendered quad split, got %v", faces[:3])
+	}
+}
+
+// Check that heights and normals follow the rendered triangles.
+func TestSurfaceHeightAt(t *testing.T) {
+	s := newSurface(3, 3, 1, 1, 2)
+	s.pts[1][0].Height = 1 // raise the x1,y0 corner of quad 0,0
+	if h := s.HeightAt(0.25, 0.25); h != 0.5 {
+		t.Errorf("Expected lower triangle height 0.5, got %f", h)
+	}
+	if h := s.HeightAt(0.75, 0.75); h != 0.5 {
+		t.Errorf("Expected upper triangle height 0.5, got %f", h)
+	}
+	if h := s.HeightAt(0.5, 0.5); h != 1 {
+		t.Errorf("Expected diagonal height 1, got %f", h)
+	}
+	if h := s.HeightAt(-1, 8); h != 0 {
+		t.Errorf("Expected clamped height 0, got %f", h)
+	}
+	nx, ny, nz := s.NormalAt(0.25, 0.25)
+	if math.Abs(nx+2/math.Sqrt(5)) > 1e-9 || ny != 0 || math.Abs(nz-1/math.Sqrt(5)) > 1e-9 {
+		t.Errorf("Expected normal tilted away from the raised corner, got %f %f %f", nx, ny, nz)
+	}
+	s.SetLod(1, 1, 1, 1, 1) // a single quad covers the surface.
+	if h := s.HeightAt(0.5, 0.5); h != 0 {
+		t.Errorf("Expected coarse triangle to skip the raised point, got %f", h)
+	}
+}
+
+// Check that large surfaces switch to 32 bit face indicies.
+func TestSurfaceWideFaces(t *testing.T) {
+	m := newModel("surface").NewMesh("surface").(*model)
+	s := newSurface(129, 129, 1, 1, 1) // 65536 verticies.
+	s.Update(m, 0, 0)
+	if faces := m.msh.faces; faces.Size() != uint32(faces.Len())*2 {
+		t.Errorf("Expected 16 bit indicies for %d verticies", len(s.vb)/3)
+	}
+	s = newSurface(130, 130, 1, 1, 1)
+	s.Update(m, 0, 0)
+	faces, last := m.msh.faces, s.fb[len(s.fb)-2]
+	if faces.Size() != uint32(faces.Len())*4 || faces.Len() != len(s.fb) || int(last) != len(s.vb)/3-1 {
+		t.Errorf("Expected 32 bit indicies for %d verticies, got %d bytes", len(s.vb)/3, faces.Size())
+	}
+}
+
+// triangles returns the vertex locations for each generated face.
+func triangles(s *surface) (tris [][9]float32) {
+	for cnt := 0; cnt+2 < len(s.fb); cnt += 3 {
+		tri := [9]float32{}
+		for v := 0; v < 3; v++ {
+			copy(tri[v*3:v*3+3], s.vb[int(s.fb[cnt+v])*3:])
+		}
+		tris = append(tris, tri)
+	}
+	return tris
+}
+
+// Surface baseline utilities
+// ============================================================================
+
+// fixedSurface creates and updates a surface using fixed heights,
+// textures, and blending so that the results are repeatable.
+func fixedSurface() *surface {
+	s := newSurface(9, 9, 2, 0.25, 4)
+	pts := s.Pts()
+	for x := range pts {
+		for y := range pts[x] {
+			fx, fy := float64(x), float64(y)
+			pts[x][y].Height = float32(math.Sin(fx*0.7) * math.Cos(fy*0.5) * 0.5)
+			pts[x][y].Tindex = (x + y) % 3
+			pts[x][y].Blend = float32(x) / 8
+		}
+	}
+	s.BakeAO(8)
+	s.Update(newModel("surface").NewMesh("surface"), 1, 2)
+	return s
+}
+
+// dumpSurface writes the most recently generated surface
+// buffers as lines of text.
+func dumpSurface(s *surface) []byte {
+	buf := &bytes.Buffer{}
+	dumpFloats(buf, "v", 3, s.vb)
+	dumpFloats(buf, "n", 3, s.nb)
+	dumpFloats(buf, "t", 4, s.tb)
+	dumpFloats(buf, "a", 1, s.ab)
+	for cnt := 0; cnt+2 < len(s.fb); cnt += 3 {
+		fmt.Fprintf(buf, "f %d %d %d\n", s.fb[cnt], s.fb[cnt+1], s.fb[cnt+2])
+	}
+	return buf.Bytes()
+}
+
+// dumpFloats writes one line for each span values.
+func dumpFloats(buf *bytes.Buffer, tag string, span int, vals []float32) {
+	for cnt := 0; cnt+span <= len(vals); cnt += span {
+		buf.WriteString(tag)
+		for _, v := range vals[cnt : cnt+span] {
+			buf.WriteString(" " + strconv.FormatFloat(float64(v), 'g', -1, 32))
+		}
+		buf.WriteString("\n")
+	}
+}
+
+// diffSurface compares dumped surface data allowing for small
+// floating point differences. The first different line is reported.
+func diffSurface(want, got []byte) error {
+	wantLines := bufio.NewScanner(bytes.NewReader(want))
+	gotLines := bufio.NewScanner(bytes.NewReader(got))
+	for line := 1; ; line++ {
+		wok, gok := wantLines.Scan(), gotLines.Scan()
+		if !wok || !gok {
+			if wok != gok {
+				return fmt.Errorf("Surface line %d: buffer sizes differ", line)
+			}
+			return nil
+		}
+		wf, gf := strings.Fields(wantLines.Text()), strings.Fields(gotLines.Text())
+		if len(wf) != len(gf) || len(wf) == 0 || wf[0] != gf[0] {
+			return fmt.Errorf("Surface line %d: expected %q, got %q", line, wantLines.Text(), gotLines.Text())
+		}
+		for cnt := 1; cnt < len(wf); cnt++ {
+			w, _ := strconv.ParseFloat(wf[cnt], 32)
+			g, _ := strconv.ParseFloat(gf[cnt], 32)
+			if math.Abs(w-g) > 0.00001 {
+				return fmt.Errorf("Surface line %d: expected %q, got %q", line, wantLines.Text(), gotLines.Text())
+			}
+		}
+	}
+}
+
+// rasterSurface draws the surface triangles looking straight down on
+// the surface using ppu pixels per unit. Each pixel is shaded using
+// the interpolated vertex normals, a fixed light direction, and the
+// interpolated ambient occlusion.
+func rasterSurface(s *surface, ppu int) *image.Gray {
+	sx, sy := len(s.pts)-1, len(s.pts[0])-1
+	img := image.NewGray(image.Rect(0, 0, sx*ppu, sy*ppu))
+	lx, ly, lz := 0.3, 0.8, 0.5 // light direction.
+	ll := math.Sqrt(lx*lx + ly*ly + lz*lz)
+	lx, ly, lz = lx/ll, ly/ll, lz/ll
+	vb, nb, ab, fb := s.vb, s.nb, s.ab, s.fb
+	for cnt := 0; cnt+2 < len(fb); cnt += 3 {
+		a0, a1, a2 := float64(ab[fb[cnt]]), float64(ab[fb[cnt+1]]), float64(ab[fb[cnt+2]])
+		i0, i1, i2 := int(fb[cnt])*3, int(fb[cnt+1])*3, int(fb[cnt+2])*3
+		x0, y0 := float64(vb[i0]), float64(vb[i0+1])
+		x1, y1 := float64(vb[i1]), float64(vb[i1+1])
+		x2, y2 := float64(vb[i2]), float64(vb[i2+1])
+		area := (x1-x0)*(y2-y0) - (x2-x0)*(y1-y0)
+		if area == 0 {
+			continue
+		}
+		minx := int(math.Min(x0, math.Min(x1, x2)) * float64(ppu))
+		maxx := int(math.Max(x0, math.Max(x1, x2)) * float64(ppu))
+		miny := int(math.Min(y0, math.Min(y1, y2)) * float64(ppu))
+		maxy := int(math.Max(y0, math.Max(y1, y2)) * float64(ppu))
+		for px := minx; px < maxx; px++ {
+			for py := miny; py < maxy; py++ {
+				cx, cy := (float64(px)+0.5)/float64(ppu), (float64(py)+0.5)/float64(ppu)
+				b1 := ((cx-x0)*(y2-y0) - (x2-x0)*(cy-y0)) / area
+				b2 := ((x1-x0)*(cy-y0) - (cx-x0)*(y1-y0)) / area
+				b0 := 1 - b1 - b2
+				if b0 < 0 || b1 < 0 || b2 < 0 {
+					continue // pixel is outside the triangle.
+				}
+				nx := b0*float64(nb[i0]) + b1*float64(nb[i1]) + b2*float64(nb[i2])
+				ny := b0*float64(nb[i0+1]) + b1*float64(nb[i1+1]) + b2*float64(nb[i2+1])
+				nz := b0*float64(nb[i0+2]) + b1*float64(nb[i1+2]) + b2*float64(nb[i2+2])
+				shade := math.Max(0, (nx*lx+ny*ly+nz*lz)/math.Sqrt(nx*nx+ny*ny+nz*nz))
+				shade *= b0*a0 + b1*a1 + b2*a2
+				img.SetGray(px, sy*ppu-1-py, color.Gray{Y: uint8(shade * 255)})
+			}
+		}
+	}
+	return img
+}
+
+// diffImage returns the number of pixels that differ by more than
+// the given tolerance. Images of different sizes are all different.
+func diffImage(want image.Image, got *image.Gray, tolerance int) (diffs int) {
+	if want.Bounds() != got.Bounds() {
+		return got.Bounds().Dx() * got.Bounds().Dy()
+	}
+	b := got.Bounds()
+	for x := b.Min.X; x < b.Max.X; x++ {
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			w := int(color.GrayModel.Convert(want.At(x, y)).(color.Gray).Y)
+			if g := int(got.GrayAt(x, y).Y); g-w > tolerance || w-g > tolerance {
+				diffs++
+			}
+		}
+	}
+	return diffs
+}
+
+// writePng saves the given image in PNG format.
+func writePng(name string, img image.Image) error {
+	file, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return png.Encode(file, img)
+}
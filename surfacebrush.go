@@ -0,0 +1,107 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"math"
+)
+
+// Brushes edit the surface points around a surface location, ie: for
+// in-game level editors. Points within the brush radius get the full
+// effect and points in the falloff band beyond the radius get a smoothly
+// reduced effect. Brush returns the changed points so that only that
+// region is regenerated. Applying a brush each update while a button is
+// held gives a continuous stroke:
+//     x0, y0, x1, y1 := s.Brush(x, y, vu.Brush{Op: vu.Raise, Radius: 2, Falloff: 3, Strength: 0.01})
+//     s.UpdateRegion(m, xoff, yoff, x0, y0, x1, y1)
+
+// Brush operations used by Brush.Op.
+const (
+	Raise   = iota // Add Strength to the heights.
+	Lower          // Subtract Strength from the heights.
+	Smooth         // Blend heights towards the average of their neighbours.
+	Flatten        // Blend heights towards the height at the brush center.
+	Paint          // Stamp the texture atlas index and blend.
+)
+
+// Brush describes one application of a terrain editing brush.
+type Brush struct {
+	Op       int     // Raise, Lower, Smooth, Flatten, or Paint.
+	Radius   float64 // Distance from the brush center, in points, with full effect.
+	Falloff  float64 // Distance beyond Radius, in points, where the effect fades out.
+	Strength float32 // Height change for Raise and Lower, otherwise a 0 to 1 blend.
+	Tindex   int     // Texture atlas index stamped by Paint.
+	Blend    float32 // Texture blend stamped by Paint.
+}
+
+// Brush implements Surface.
+func (s *surface) Brush(x, y float64, b Brush) (x0, y0, x1, y1 int) {
+	sx, sy := len(s.pts), len(s.pts[0])
+	reach := math.Max(0, b.Radius) + math.Max(0, b.Falloff)
+	x0, y0 = iclamp(int(math.Floor(x-reach)), 0, sx-1), iclamp(int(math.Floor(y-reach)), 0, sy-1)
+	x1, y1 = iclamp(int(math.Ceil(x+reach)), 0, sx-1), iclamp(int(math.Ceil(y+reach)), 0, sy-1)
+	amount := float32(math.Max(0, math.Min(1, float64(b.Strength))))
+	center := s.sample(x, y)
+	if b.Op == Smooth {
+		s.average(x0, y0, x1, y1)
+	}
+	for px := x0; px <= x1; px++ {
+		for py := y0; py <= y1; py++ {
+			w := b.weight(math.Hypot(float64(px)-x, float64(py)-y))
+			if w <= 0 {
+				continue
+			}
+			pt := &s.pts[px][py]
+			switch b.Op {
+			case Raise:
+				pt.Height += b.Strength * w
+			case Lower:
+				pt.Height -= b.Strength * w
+			case Smooth:
+				avg := s.avg[(px-x0)*(y1-y0+1)+py-y0]
+				pt.Height += (avg - pt.Height) * amount * w
+			case Flatten:
+				pt.Height += (center - pt.Height) * amount * w
+			case Paint:
+				if w >= 0.5 { // textures are not blended so stamp the closer half.
+					pt.Tindex, pt.Blend = b.Tindex, b.Blend
+				}
+			}
+		}
+	}
+	return x0, y0, x1, y1
+}
+
+// weight returns the brush effect, from 1 to 0, at the given
+// distance from the brush center. The falloff is a smooth step.
+func (b Brush) weight(dist float64) float32 {
+	switch {
+	case dist <= b.Radius:
+		return 1
+	case dist >= b.Radius+b.Falloff:
+		return 0
+	}
+	w := 1 - (dist-b.Radius)/b.Falloff
+	return float32(w * w * (3 - 2*w))
+}
+
+// average fills the scratch averages with the mean height of each
+// point, from x0, y0 to x1, y1, and its neighbours. The averages are
+// taken before any heights are changed so that the result does not
+// depend on the order the points are visited.
+func (s *surface) average(x0, y0, x1, y1 int) {
+	sx, sy := len(s.pts), len(s.pts[0])
+	s.avg = s.avg[:0]
+	for x := x0; x <= x1; x++ {
+		for y := y0; y <= y1; y++ {
+			sum, cnt := float32(0), float32(0)
+			for nx := imax(0, x-1); nx <= imin(sx-1, x+1); nx++ {
+				for ny := imax(0, y-1); ny <= imin(sy-1, y+1); ny++ {
+					sum, cnt = sum+s.pts[nx][ny].Height, cnt+1
+				}
+			}
+			s.avg = append(s.avg, sum/cnt)
+		}
+	}
+}
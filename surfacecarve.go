@@ -0,0 +1,109 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"math"
+)
+
+// Rivers and roads are carved into a surface along a path of surface
+// locations. The path height profile is sampled from the surface at each
+// path location and interpolated between them. Points within the carving
+// width are set to the profile, less any channel depth, and points in the
+// falloff band beyond the width are blended back to their original height.
+
+// Carving describes the shape and texture of a river or road
+// carved by Surface.Carve.
+type Carving struct {
+	Width    float64 // Distance from the path center, in points, that is fully carved.
+	Falloff  float64 // Distance beyond Width, in points, blended back to the original.
+	Depth    float32 // Channel depth at the path center. 0 flattens roads.
+	Downhill bool    // True to force the profile to never rise, ie: for rivers.
+	Tindex   int     // Texture atlas index stamped within Width. -1 to keep textures.
+	Blend    float32 // Texture blend stamped with Tindex.
+}
+
+// Carve implements Surface.
+func (s *surface) Carve(path []float64, c Carving) {
+	if len(path) < 4 || len(s.pts) == 0 {
+		return
+	}
+	sx, sy := len(s.pts), len(s.pts[0])
+
+	// sample the height profile before any heights change.
+	cnt := len(path) / 2
+	profile := make([]float32, cnt)
+	for at := 0; at < cnt; at++ {
+		profile[at] = s.sample(path[at*2], path[at*2+1])
+		if c.Downhill && at > 0 && profile[at] > profile[at-1] {
+			profile[at] = profile[at-1]
+		}
+	}
+
+	// only visit the points near the path.
+	reach := c.Width + math.Max(0, c.Falloff)
+	x0, y0, x1, y1 := math.MaxFloat64, math.MaxFloat64, -math.MaxFloat64, -math.MaxFloat64
+	for at := 0; at < cnt; at++ {
+		x0, x1 = math.Min(x0, path[at*2]), math.Max(x1, path[at*2])
+		y0, y1 = math.Min(y0, path[at*2+1]), math.Max(y1, path[at*2+1])
+	}
+	xmin, xmax := iclamp(int(x0-reach), 0, sx-1), iclamp(int(math.Ceil(x1+reach)), 0, sx-1)
+	ymin, ymax := iclamp(int(y0-reach), 0, sy-1), iclamp(int(math.Ceil(y1+reach)), 0, sy-1)
+	for x := xmin; x <= xmax; x++ {
+		for y := ymin; y <= ymax; y++ {
+			dist, target := math.MaxFloat64, float32(0)
+			for at := 0; at < cnt-1; at++ {
+				d, t := segment(float64(x), float64(y), path[at*2:at*2+4])
+				if d < dist {
+					dist, target = d, profile[at]+(profile[at+1]-profile[at])*float32(t)
+				}
+			}
+			if dist > reach {
+				continue
+			}
+			pt := &s.pts[x][y]
+			if dist <= c.Width {
+				if c.Width > 0 {
+					across := float32(dist / c.Width)
+					target -= c.Depth * (1 - across*across) // rounded channel.
+				}
+				pt.Height = target
+				if c.Tindex >= 0 {
+					pt.Tindex, pt.Blend = c.Tindex, c.Blend
+				}
+				continue
+			}
+			w := float32((dist - c.Width) / c.Falloff)
+			w = w * w * (3 - 2*w) // smooth step from the path to the original.
+			pt.Height = target + (pt.Height-target)*w
+		}
+	}
+}
+
+// sample returns the height at surface location x, y interpolated
+// from the four closest surface points.
+func (s *surface) sample(x, y float64) float32 {
+	sx, sy := len(s.pts), len(s.pts[0])
+	x = math.Max(0, math.Min(x, float64(sx-1)))
+	y = math.Max(0, math.Min(y, float64(sy-1)))
+	ix, iy := imin(int(x), sx-2), imin(int(y), sy-2)
+	if ix < 0 || iy < 0 {
+		return s.pts[0][0].Height // surface is a single row or column.
+	}
+	u, v := float32(x-float64(ix)), float32(y-float64(iy))
+	h00, h10 := s.pts[ix][iy].Height, s.pts[ix+1][iy].Height
+	h01, h11 := s.pts[ix][iy+1].Height, s.pts[ix+1][iy+1].Height
+	return h00*(1-u)*(1-v) + h10*u*(1-v) + h01*(1-u)*v + h11*u*v
+}
+
+// segment returns the distance from x, y to the line segment
+// ax, ay, bx, by and how far along the segment, from 0 to 1,
+// the closest point is.
+func segment(x, y float64, ab []float64) (dist, t float64) {
+	dx, dy := ab[2]-ab[0], ab[3]-ab[1]
+	if length := dx*dx + dy*dy; length > 0 {
+		t = math.Max(0, math.Min(1, ((x-ab[0])*dx+(y-ab[1])*dy)/length))
+	}
+	return math.Hypot(x-(ab[0]+t*dx), y-(ab[1]+t*dy)), t
+}
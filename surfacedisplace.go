@@ -0,0 +1,105 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/gazed/vu/render"
+)
+
+// Displaced surfaces keep a static grid mesh on the GPU and copy only
+// the surface points, as a height map texture, when the points change.
+// The vertex shader moves each grid vertex up to its height and finds
+// the normal from the neighbouring heights so that animated surfaces,
+// ie: water, don't rebuild and copy all their vertex data each update:
+//     m := pov.NewModel("landd").NewMesh("water").AddTex("atlas")
+//     s.Displace(m, 0, 0) // each update: copy the changed heights.
+// The height map holds the scaled height normalized to the height range
+// in 16 bits of red and green, the texture index in the low 7 bits of
+// blue with the high bit marking holes, and the texture blend in alpha.
+// See eg/source/landd.vsh for the shader uniforms.
+
+// Displace implements Surface. The grid mesh is only regenerated when
+// the surface size, or the model, changes.
+func (s *surface) Displace(m Model, xoff, yoff int) {
+	mod, ok := m.(*model)
+	if !ok {
+		return
+	}
+	sx, sy := len(s.pts), len(s.pts[0])
+	if mod != s.dmod {
+		s.dmod, s.dsize = mod, [2]int{}
+		s.dtex = len(mod.texs)
+		mod.texs = append(mod.texs, newTexture("displace"))
+	}
+	if s.dsize != [2]int{sx, sy} {
+		s.dsize = [2]int{sx, sy}
+		s.displaceGrid(mod)
+	}
+	var hmin, hmax float64
+	s.hmap, hmin, hmax = heightMap(s.pts, float64(s.scale), s.hmap)
+	mod.texs[s.dtex].set(s.hmap)
+	m.SetUniform("hrange", hmin, hmax)
+	m.SetUniform("spread", s.spread)
+	m.SetUniform("ratio", s.tratio)
+	m.SetUniform("offset", xoff, yoff)
+}
+
+// displaceGrid creates the static mesh of surface point locations.
+// The quads use the same split as the generated quads.
+func (s *surface) displaceGrid(m Model) {
+	sx, sy := len(s.pts), len(s.pts[0])
+	s.vb, s.fb = s.vb[:0], s.fb[:0]
+	for x := 0; x < sx; x++ {
+		for y := 0; y < sy; y++ {
+			s.vb = append(s.vb, float32(x), float32(y))
+		}
+	}
+	for x := 0; x < sx-1; x++ {
+		for y := 0; y < sy-1; y++ {
+			v0 := uint32(x*sy + y)
+			v1, v2, v3 := v0+uint32(sy), v0+1, v0+uint32(sy)+1
+			s.fb = append(s.fb, v0, v1, v2, v1, v3, v2)
+		}
+	}
+	m.InitMesh(0, 2, render.StaticDraw, false).SetMeshData(0, s.vb)
+	s.f16 = bindFaces(m, sx*sy, s.fb, s.f16)
+	s.qv = s.qv[:0] // generated vertex data is gone.
+}
+
+// heightMap copies the surface points into a height map image, reusing
+// img when it is the right size. Returns the image and the lowest and
+// highest scaled heights.
+func heightMap(pts [][]SurfacePoint, scale float64, img *image.NRGBA) (*image.NRGBA, float64, float64) {
+	hmin, hmax := math.MaxFloat64, -math.MaxFloat64
+	for x := range pts {
+		for y := range pts[x] {
+			h := float64(pts[x][y].Height) * scale
+			hmin, hmax = math.Min(hmin, h), math.Max(hmax, h)
+		}
+	}
+	span := hmax - hmin
+	if span == 0 {
+		span = 1 // flat land.
+	}
+	if img == nil || img.Rect.Dx() != len(pts) || img.Rect.Dy() != len(pts[0]) {
+		img = image.NewNRGBA(image.Rect(0, 0, len(pts), len(pts[0])))
+	}
+	for x := range pts {
+		for y := range pts[x] {
+			pt := pts[x][y]
+			v := uint16(math.Floor((float64(pt.Height)*scale-hmin)/span*65535 + 0.5))
+			b := uint8(iclamp(pt.Tindex, 0, 127))
+			if pt.Hole {
+				b |= 128
+			}
+			a := uint8(math.Floor(math.Max(0, math.Min(1, float64(pt.Blend)))*255 + 0.5))
+			img.SetNRGBA(x, y, color.NRGBA{uint8(v >> 8), uint8(v), b, a})
+		}
+	}
+	return img, hmin, hmax
+}
@@ -0,0 +1,94 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Saved surfaces let procedurally generated terrain be stored, versioned,
+// and reloaded instead of being regenerated each run. The little endian
+// format is a header followed by one record for each surface point:
+//    uint32 magic, uint32 sx, uint32 sy
+//    float32 height, int32 texture index, float32 blend, uint8 flags
+// Points are written in x then y order. The only flag is 1 for holes.
+
+// surfaceMagic identifies, and versions, saved surface data.
+const surfaceMagic uint32 = 0x76757331 // "vus1"
+
+// surfaceHole is the saved point flag for holes.
+const surfaceHole = 1
+
+// maxSurfaceSize is the largest saved surface side accepted by Load.
+const maxSurfaceSize = 1 << 15
+
+// Save implements Surface.
+func (s *surface) Save(w io.Writer) error {
+	buf := bufio.NewWriter(w)
+	sx, sy := len(s.pts), len(s.pts[0])
+	rec := make([]byte, 13) // one saved point.
+	binary.Write(buf, binary.LittleEndian, []uint32{surfaceMagic, uint32(sx), uint32(sy)})
+	for x := 0; x < sx; x++ {
+		for y := 0; y < sy; y++ {
+			pt := s.pts[x][y]
+			binary.LittleEndian.PutUint32(rec[0:], math.Float32bits(pt.Height))
+			binary.LittleEndian.PutUint32(rec[4:], uint32(int32(pt.Tindex)))
+			binary.LittleEndian.PutUint32(rec[8:], math.Float32bits(pt.Blend))
+			rec[12] = 0
+			if pt.Hole {
+				rec[12] = surfaceHole
+			}
+			buf.Write(rec)
+		}
+	}
+	if err := buf.Flush(); err != nil {
+		return fmt.Errorf("Surface.Save: %s", err)
+	}
+	return nil
+}
+
+// Load implements Surface. The surface is only changed
+// if all the saved points can be read. Points are read a row
+// at a time so that memory is only used for data that exists.
+func (s *surface) Load(r io.Reader) error {
+	hdr := make([]uint32, 3)
+	if err := binary.Read(r, binary.LittleEndian, hdr); err != nil {
+		return fmt.Errorf("Surface.Load: %s", err)
+	}
+	sx, sy := int(hdr[1]), int(hdr[2])
+	if hdr[0] != surfaceMagic || sx < 1 || sy < 1 || sx > maxSurfaceSize || sy > maxSurfaceSize {
+		return fmt.Errorf("Surface.Load: invalid surface data")
+	}
+	data := make([]byte, sy*13) // one row of saved points.
+	rows := [][]SurfacePoint{}
+	for x := 0; x < sx; x++ {
+		if _, err := io.ReadFull(r, data); err != nil {
+			return fmt.Errorf("Surface.Load: %s", err)
+		}
+		row := make([]SurfacePoint, sy)
+		for y := range row {
+			rec := data[y*13:]
+			pt := &row[y]
+			pt.Height = math.Float32frombits(binary.LittleEndian.Uint32(rec[0:]))
+			pt.Tindex = int(int32(binary.LittleEndian.Uint32(rec[4:])))
+			pt.Blend = math.Float32frombits(binary.LittleEndian.Uint32(rec[8:]))
+			pt.Hole = rec[12]&surfaceHole != 0
+		}
+		rows = append(rows, row)
+	}
+	if len(s.pts) != sx || len(s.pts[0]) != sy {
+		s.allocate(sx, sy)
+	}
+	for x, row := range rows {
+		s.pts[x] = row
+		for y := range s.ao[x] {
+			s.ao[x][y] = 1 // needs rebaking.
+		}
+	}
+	return nil
+}
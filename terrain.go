@@ -0,0 +1,345 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"math"
+	"sort"
+	"sync"
+)
+
+// Terrain pages fixed size Surface chunks in and out around a camera
+// so that worlds larger than a single Surface can be rendered. Each chunk
+// is a Surface with its own child Pov and Model. The application fills
+// the SurfacePoints for new chunks using a ChunkFiller callback:
+//     t := vu.NewTerrain(pov, "land", 64, filler)
+//     t.SetModel(func(m vu.Model) { m.AddTex("land") })
+//     t.Update(cam) // each update: load near chunks and release far ones.
+// Chunks lie in the x,y plane of the terrain Pov where chunk cx,cy starts
+// at x=cx*size, y=cy*size. The terrain Pov is expected to be a child of
+// the root so its location, rotation, and scale are world values.
+// There is no world edge. Endless terrain is created by generating the
+// chunk points from a world seed and the chunk location, see ChunkSeed,
+// on background goroutines, see SetWorkers.
+type Terrain interface {
+	SetRange(chunks int) Terrain // Chunks kept around the camera. Default 2.
+
+	// SetLod sets the coarsest level of detail, see Surface.SetLod, used
+	// for distant chunks. Chunks use one level coarser for each ring of
+	// chunks away from the camera, adjusted by Quality.LodBias.
+	// Default 0 is always full resolution.
+	SetLod(level int) Terrain
+
+	// SetSurface changes the NewSurface values used for new chunks.
+	// The defaults are spread 1, textureRatio 1, and scale 1.
+	SetSurface(spread int, textureRatio, scale float32) Terrain
+
+	// SetModel registers a callback that configures each new chunk
+	// model, ie: adding textures or materials.
+	SetModel(setup func(m Model)) Terrain
+
+	// SetWorkers fills new chunks using up to the given number of
+	// background goroutines, so that generating chunks doesn't slow
+	// down the updates. The ChunkFiller must then be safe to call from
+	// multiple goroutines. Filled chunks are added, and their meshes
+	// generated, by the following Update. Default 0 fills chunks as
+	// they are needed during Update.
+	SetWorkers(workers int) Terrain
+
+	// Update loads chunks that are within range of the camera
+	// and releases chunks that have moved out of range.
+	Update(cam Camera)
+	Chunk(cx, cy int) Surface // Loaded chunk or nil.
+	Chunks() int              // Number of loaded chunks.
+	Pending() int             // Number of chunks being filled by workers.
+}
+
+// ChunkFiller is the application callback that sets the heights and
+// textures for a new terrain chunk. Chunks have size+1 points along each
+// side so the last row and column match the first row and column of the
+// neighbouring chunks. The points start flat, fully lit, and without
+// holes, even when the chunk reuses the memory of a released chunk.
+type ChunkFiller func(cx, cy int, pts [][]SurfacePoint)
+
+// ChunkSeed combines a world seed and a chunk location into a seed
+// for the chunk. The same world seed and chunk always give the same
+// chunk seed while neighbouring chunks get unrelated seeds.
+func ChunkSeed(seed int64, cx, cy int) int64 {
+	h := uint64(seed)
+	for _, v := range [2]int{cx, cy} {
+		h ^= uint64(int64(v)) + 0x9E3779B97F4A7C15 + h<<6 + h>>2 // hash combine.
+		h ^= h >> 33                                             // mix, from MurmurHash3.
+		h *= 0xFF51AFD7ED558CCD
+		h ^= h >> 33
+	}
+	return int64(h)
+}
+
+// NewTerrain creates a terrain of size-by-size chunks that are
+// rendered as child Pov's of p using the given shader.
+func NewTerrain(p Pov, shader string, size int, fill ChunkFiller) Terrain {
+	return newTerrain(p, shader, size, fill)
+}
+
+// Terrain
+// =============================================================================
+// terrain implements Terrain.
+
+// terrain tracks the loaded chunks. Released surfaces are kept
+// so that their memory can be reused for new chunks.
+type terrain struct {
+	pov    *pov               // Terrain location, orientation, scale.
+	shader string             // Chunk model shader.
+	size   int                // Quads along each side of a chunk.
+	fill   ChunkFiller        // Application chunk creation callback.
+	setup  func(m Model)      // Optional chunk model configuration.
+	reach  int                // Chunks kept around the camera.
+	maxLod int                // Coarsest level of detail.
+	spread int                // NewSurface spread.
+	tratio float32            // NewSurface texture ratio.
+	scale  float32            // NewSurface height scale.
+	chunks map[chunkID]*chunk // Loaded chunks.
+	free   []*surface         // Released surfaces.
+	ids    []chunkID          // Scratch sorted chunk ids.
+
+	// background chunk filling.
+	workers int                // Maximum goroutines filling chunks.
+	pending map[chunkID]bool   // Chunks being filled.
+	slots   chan bool          // Limits the number of filling goroutines.
+	mutex   sync.Mutex         // Guards filled.
+	filled  map[chunkID]*chunk // Chunks filled by workers.
+}
+
+// chunk is one loaded terrain patch.
+type chunk struct {
+	pov *pov     // Chunk location relative to terrain.
+	s   *surface // Chunk height data.
+	lod [5]int   // Last level of detail and neighbour levels.
+}
+
+// chunkID identifies a terrain chunk by its terrain grid location.
+type chunkID struct{ x, y int }
+
+// chunkIDs sorts chunk ids by row and then column.
+type chunkIDs []chunkID
+
+func (c chunkIDs) Len() int      { return len(c) }
+func (c chunkIDs) Swap(i, j int) { c[i], c[j] = c[j], c[i] }
+func (c chunkIDs) Less(i, j int) bool {
+	return c[i].y < c[j].y || c[i].y == c[j].y && c[i].x < c[j].x
+}
+
+// newTerrain allocates and initializes a terrain.
+func newTerrain(p Pov, shader string, size int, fill ChunkFiller) *terrain {
+	t := &terrain{shader: shader, size: size, fill: fill}
+	t.pov, _ = p.(*pov)
+	t.reach, t.spread, t.tratio, t.scale = 2, 1, 1, 1
+	t.chunks = map[chunkID]*chunk{}
+	t.pending = map[chunkID]bool{}
+	t.filled = map[chunkID]*chunk{}
+	return t
+}
+
+// Implement Terrain.
+func (t *terrain) SetRange(chunks int) Terrain {
+	if chunks >= 0 {
+		t.reach = chunks
+	}
+	return t
+}
+func (t *terrain) SetLod(level int) Terrain {
+	if level >= 0 {
+		t.maxLod = level
+	}
+	return t
+}
+func (t *terrain) SetSurface(spread int, textureRatio, scale float32) Terrain {
+	t.spread, t.tratio, t.scale = spread, textureRatio, scale
+	t.free = t.free[:0] // surface settings changed.
+	return t
+}
+func (t *terrain) SetModel(setup func(m Model)) Terrain {
+	t.setup = setup
+	return t
+}
+func (t *terrain) SetWorkers(workers int) Terrain {
+	if workers >= 0 && len(t.pending) == 0 {
+		t.workers = workers
+		t.slots = make(chan bool, workers)
+	}
+	return t
+}
+func (t *terrain) Chunks() int  { return len(t.chunks) }
+func (t *terrain) Pending() int { return len(t.pending) }
+func (t *terrain) Chunk(cx, cy int) Surface {
+	if c, ok := t.chunks[chunkID{cx, cy}]; ok {
+		return c.s
+	}
+	return nil
+}
+
+// Update implements Terrain. Chunks are released one chunk beyond
+// the load range so that a camera moving back and forth across a
+// chunk edge doesn't continually reload chunks. Chunk meshes are only
+// regenerated when the chunk, or its neighbours, change detail level.
+func (t *terrain) Update(cam Camera) {
+	if t.pov == nil || cam == nil || t.size <= 0 {
+		return
+	}
+	cx, cy := t.center(cam)
+	t.collect(cx, cy)
+	for _, id := range t.sorted(t.chunks) {
+		if c := t.chunks[id]; abs(id.x-cx) > t.reach+1 || abs(id.y-cy) > t.reach+1 {
+			t.release(id, c)
+		}
+	}
+	for x := cx - t.reach; x <= cx+t.reach; x++ {
+		for y := cy - t.reach; y <= cy+t.reach; y++ {
+			if _, ok := t.chunks[chunkID{x, y}]; !ok && !t.pending[chunkID{x, y}] {
+				t.load(x, y)
+			}
+		}
+	}
+	for _, id := range t.sorted(t.chunks) {
+		c := t.chunks[id]
+		lod := [5]int{t.level(id, cx, cy, -1)}
+		lod[1] = t.level(chunkID{id.x - 1, id.y}, cx, cy, lod[0])
+		lod[2] = t.level(chunkID{id.x + 1, id.y}, cx, cy, lod[0])
+		lod[3] = t.level(chunkID{id.x, id.y - 1}, cx, cy, lod[0])
+		lod[4] = t.level(chunkID{id.x, id.y + 1}, cx, cy, lod[0])
+		if lod != c.lod {
+			c.lod = lod
+			c.s.SetLod(lod[0], lod[1], lod[2], lod[3], lod[4])
+			c.s.Update(c.pov.Model(), id.x*t.size, id.y*t.size)
+		}
+	}
+}
+
+// level returns the level of detail for the chunk at id given the
+// camera is over chunk cx, cy. Chunks that are not loaded get the
+// level given by missing.
+func (t *terrain) level(id chunkID, cx, cy, missing int) int {
+	if _, ok := t.chunks[id]; !ok && missing >= 0 {
+		return missing
+	}
+	ring := abs(id.x - cx)
+	if dy := abs(id.y - cy); dy > ring {
+		ring = dy
+	}
+	if ring = t.pov.eng.quality.Lod(ring); ring > t.maxLod {
+		return t.maxLod
+	}
+	return ring
+}
+
+// center returns the chunk under the camera.
+func (t *terrain) center(cam Camera) (cx, cy int) {
+	lx, ly, _ := localCam(t.pov, cam)
+	size := float64(t.size)
+	return int(math.Floor(lx / size)), int(math.Floor(ly / size))
+}
+
+// localCam returns the camera world location transformed into the
+// unscaled coordinates of Pov p. The Pov is expected to be a child
+// of the root so its transform holds world values.
+func localCam(p *pov, cam Camera) (lx, ly, lz float64) {
+	wx, wy, wz := cam.Location()
+	lx, ly, lz = p.at.InvS(wx, wy, wz)
+	if sx := p.scale.X; sx != 0 {
+		lx /= sx
+	}
+	if sy := p.scale.Y; sy != 0 {
+		ly /= sy
+	}
+	if sz := p.scale.Z; sz != 0 {
+		lz /= sz
+	}
+	return lx, ly, lz
+}
+
+// load creates the chunk at chunk grid location x, y. With workers
+// the chunk is filled in the background and added by a later Update.
+func (t *terrain) load(x, y int) {
+	c := &chunk{lod: [5]int{-1}} // force a mesh update.
+	if last := len(t.free) - 1; last >= 0 {
+		c.s, t.free = t.free[last], t.free[:last]
+		c.s.clear() // don't keep the old chunk values.
+	} else {
+		c.s = newSurface(t.size+1, t.size+1, t.spread, t.tratio, t.scale)
+	}
+	if t.workers > 0 && t.fill != nil {
+		id := chunkID{x, y}
+		t.pending[id] = true
+		go func() {
+			t.slots <- true
+			t.fill(x, y, c.s.pts)
+			<-t.slots
+			t.mutex.Lock()
+			t.filled[id] = c
+			t.mutex.Unlock()
+		}()
+		return
+	}
+	if t.fill != nil {
+		t.fill(x, y, c.s.pts)
+	}
+	t.attach(x, y, c)
+}
+
+// collect adds the chunks filled by workers. Chunks that are
+// no longer needed are released without creating their models.
+func (t *terrain) collect(cx, cy int) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	for _, id := range t.sorted(t.filled) {
+		c := t.filled[id]
+		delete(t.filled, id)
+		delete(t.pending, id)
+		if abs(id.x-cx) > t.reach+1 || abs(id.y-cy) > t.reach+1 {
+			t.free = append(t.free, c.s)
+			continue
+		}
+		t.attach(id.x, id.y, c)
+	}
+}
+
+// sorted returns the ids of the given chunks in chunkIDs order so
+// that chunks are loaded, released, and updated in a repeatable order.
+// The ids are in scratch memory that is valid until the next call.
+func (t *terrain) sorted(chunks map[chunkID]*chunk) []chunkID {
+	t.ids = t.ids[:0]
+	for id := range chunks {
+		t.ids = append(t.ids, id)
+	}
+	sort.Sort(chunkIDs(t.ids))
+	return t.ids
+}
+
+// attach creates the model for a filled chunk.
+func (t *terrain) attach(x, y int, c *chunk) {
+	c.pov = t.pov.NewPov().(*pov)
+	c.pov.SetLocation(float64(x*t.size), float64(y*t.size), 0)
+	m := c.pov.NewModel(t.shader)
+	if t.setup != nil {
+		t.setup(m)
+	}
+	m.NewMesh("terrain")
+	t.chunks[chunkID{x, y}] = c
+}
+
+// release disposes of the chunk rendering resources
+// and keeps the surface for reuse.
+func (t *terrain) release(id chunkID, c *chunk) {
+	c.pov.Dispose(PovNode)
+	t.free = append(t.free, c.s)
+	delete(t.chunks, id)
+}
+
+// abs returns the absolute value of integer v.
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
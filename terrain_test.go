@@ -0,0 +1,130 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"testing"
+	"time"
+)
+
+// Check that chunks are loaded around the camera
+// and released once the camera moves away.
+func TestTerrainPaging(t *testing.T) {
+	eng := newEngine(nil)
+	defer eng.Shutdown()
+	cam := eng.Root().NewPov().NewCam()
+	filled := map[chunkID]bool{}
+	fill := func(cx, cy int, pts [][]SurfacePoint) {
+		filled[chunkID{cx, cy}] = true
+		if len(pts) != 9 || len(pts[0]) != 9 {
+			t.Errorf("Expected 9x9 chunk points, got %dx%d", len(pts), len(pts[0]))
+		}
+	}
+	tr := newTerrain(eng.Root().NewPov().SetScale(2, 2, 1), "land", 8, fill)
+	tr.SetRange(1)
+	cam.SetLocation(20, 4, 10) // terrain 10, 2 is in chunk 1, 0
+	tr.Update(cam)
+	if tr.Chunks() != 9 || tr.Chunk(0, -1) == nil || tr.Chunk(2, 1) == nil || tr.Chunk(3, 0) != nil {
+		t.Errorf("Expected 9 chunks around 1, 0, got %d", tr.Chunks())
+	}
+
+	// moving one chunk keeps the old chunks within the release range.
+	cam.SetLocation(36, 4, 10) // chunk 2, 0
+	if tr.Update(cam); tr.Chunks() != 12 || len(filled) != 12 {
+		t.Errorf("Expected 12 chunks, got %d", tr.Chunks())
+	}
+
+	// moving far releases all the old chunks.
+	cam.SetLocation(-200, -200, 10)
+	if tr.Update(cam); tr.Chunks() != 9 || tr.Chunk(1, 0) != nil || len(tr.free) != 3 {
+		t.Errorf("Expected 9 new chunks, got %d", tr.Chunks())
+	}
+}
+
+// Check that chunk detail drops with distance from the camera
+// and that edges are stitched to coarser neighbours.
+func TestTerrainLod(t *testing.T) {
+	eng := newEngine(nil)
+	defer eng.Shutdown()
+	cam := eng.Root().NewPov().NewCam()
+	tr := newTerrain(eng.Root().NewPov(), "land", 8, nil)
+	tr.SetRange(3).SetLod(2)
+	cam.SetLocation(4, 4, 10) // chunk 0, 0
+	tr.Update(cam)
+	center, edge, far := tr.chunks[chunkID{0, 0}], tr.chunks[chunkID{1, 0}], tr.chunks[chunkID{3, 3}]
+	if center.lod != [5]int{0, 1, 1, 1, 1} {
+		t.Errorf("Expected full detail center with coarser neighbours, got %v", center.lod)
+	}
+	if edge.lod != [5]int{1, 0, 2, 1, 1} || far.lod[0] != 2 {
+		t.Errorf("Expected coarser distant chunks, got %v %v", edge.lod, far.lod)
+	}
+}
+
+// Check that reused chunk surfaces don't keep old values.
+func TestTerrainReuse(t *testing.T) {
+	eng := newEngine(nil)
+	defer eng.Shutdown()
+	cam := eng.Root().NewPov().NewCam()
+	tr := newTerrain(eng.Root().NewPov(), "land", 8, nil)
+	tr.SetRange(0)
+	cam.SetLocation(4, 4, 10)
+	tr.Update(cam)
+	s := tr.Chunk(0, 0).(*surface)
+	s.pts[2][3] = SurfacePoint{Height: 5, Tindex: 2, Blend: 0.5, Hole: true}
+	s.ao[2][3] = 0.25
+	cam.SetLocation(100, 4, 10) // release chunk 0, 0
+	tr.Update(cam)
+	if reused := tr.Chunk(12, 0); reused != s {
+		t.Fatalf("Expected released surface to be reused")
+	}
+	if s.pts[2][3] != (SurfacePoint{}) || s.ao[2][3] != 1 {
+		t.Errorf("Expected cleared surface, got %+v %f", s.pts[2][3], s.ao[2][3])
+	}
+}
+
+// Check that filled chunks are added in chunk order.
+func TestTerrainCollect(t *testing.T) {
+	eng := newEngine(nil)
+	defer eng.Shutdown()
+	tr := newTerrain(eng.Root().NewPov(), "land", 8, nil)
+	ids := []chunkID{{1, 1}, {0, 1}, {1, 0}, {0, 0}}
+	for _, id := range ids {
+		tr.filled[id] = &chunk{s: newSurface(9, 9, 1, 1, 1)}
+	}
+	tr.collect(0, 0)
+	last := uint64(0)
+	for _, id := range []chunkID{{0, 0}, {1, 0}, {0, 1}, {1, 1}} {
+		if eid := tr.chunks[id].pov.eid; eid <= last {
+			t.Errorf("Expected chunk %v after the previous chunk", id)
+		} else {
+			last = eid
+		}
+	}
+}
+
+// Check that workers fill the same chunks in the background.
+func TestTerrainWorkers(t *testing.T) {
+	eng := newEngine(nil)
+	defer eng.Shutdown()
+	cam := eng.Root().NewPov().NewCam()
+	fill := func(cx, cy int, pts [][]SurfacePoint) {
+		pts[1][2].Height = float32(ChunkSeed(42, cx, cy) % 1000)
+	}
+	tr := newTerrain(eng.Root().NewPov(), "land", 8, fill)
+	tr.SetRange(1).SetWorkers(2)
+	cam.SetLocation(-4, 4, 10) // chunk -1, 0
+	for cnt := 0; cnt < 1000 && (tr.Chunks() < 9 || tr.Pending() > 0); cnt++ {
+		tr.Update(cam)
+		time.Sleep(time.Millisecond)
+	}
+	if tr.Chunks() != 9 || tr.Pending() != 0 {
+		t.Fatalf("Expected 9 filled chunks, got %d %d", tr.Chunks(), tr.Pending())
+	}
+	if h := tr.Chunk(-2, 1).Pts()[1][2].Height; h != float32(ChunkSeed(42, -2, 1)%1000) || h == tr.Chunk(-1, 1).Pts()[1][2].Height {
+		t.Errorf("Expected chunk seeded heights, got %f", h)
+	}
+	if tr.chunks[chunkID{-1, 0}].lod[0] != 0 {
+		t.Errorf("Expected filled chunk meshes to be updated")
+	}
+}
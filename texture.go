@@ -5,6 +5,7 @@ package vu
 
 import (
 	"image"
+	"image/color"
 )
 
 // texture is an optional, but very common, part of a rendered Model.
@@ -20,11 +21,16 @@ type texture struct {
 	tid    uint32      // Graphics card texture identifier.
 	repeat bool        // Repeat the texture when UV greater than 1.
 	bound  bool        // False if the data needs rebinding.
+	gen    bool        // True if the application generates the image.
 	loaded bool        // True if data has been set.
 
 	// First face index and number of faces.
 	// Used for multiple uv textures for the same model.
 	f0, fn uint32 // Non-zero if texture only applies to particular faces.
+
+	// Cube map textures have six face images instead of img.
+	cube  bool          // True for cube map textures. See sky.go.
+	faces []image.Image // Cube faces: +X, -X, +Y, -Y, +Z, -Z.
 }
 
 // newTexture allocates space for a texture object.
@@ -32,6 +38,12 @@ func newTexture(name string) *texture {
 	return &texture{name: name, tag: tex + stringHash(name)<<32}
 }
 
+// newCube allocates space for a cube map texture. Cube maps are
+// cached separately from textures with the same name.
+func newCube(name string) *texture {
+	return &texture{name: name, tag: tex + stringHash(name+":cube")<<32, cube: true}
+}
+
 // label, aid, and bid are used to uniquely identify assets.
 func (t *texture) label() string { return t.name }                  // asset name
 func (t *texture) aid() uint64   { return t.tag }                   // asset type and name.
@@ -44,3 +56,39 @@ func (t *texture) set(img image.Image) {
 	t.loaded = true
 }
 func (t *texture) setRepeat(on bool) { t.repeat = on }
+
+// setFaces sets the cube map face images.
+func (t *texture) setFaces(faces []image.Image) {
+	t.faces = faces
+	t.bound = false
+	t.loaded = true
+}
+
+// shrink halves the image until its width and height are at most size
+// pixels. Each pixel is the average of the pixels it replaces. Images
+// that fit, or a size of 0, are returned unchanged.
+func shrink(img image.Image, size int) image.Image {
+	if size <= 0 {
+		return img
+	}
+	for b := img.Bounds(); b.Dx() > size || b.Dy() > size; b = img.Bounds() {
+		w, h := (b.Dx()+1)/2, (b.Dy()+1)/2
+		half := image.NewRGBA(image.Rect(0, 0, w, h))
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				var r, g, bl, a, cnt uint32
+				for sy := b.Min.Y + 2*y; sy < b.Min.Y+2*y+2 && sy < b.Max.Y; sy++ {
+					for sx := b.Min.X + 2*x; sx < b.Min.X+2*x+2 && sx < b.Max.X; sx++ {
+						cr, cg, cb, ca := img.At(sx, sy).RGBA() // premultiplied.
+						r, g, bl, a, cnt = r+cr, g+cg, bl+cb, a+ca, cnt+1
+					}
+				}
+				half.SetRGBA(x, y, color.RGBA{
+					R: uint8(r / cnt >> 8), G: uint8(g / cnt >> 8),
+					B: uint8(bl / cnt >> 8), A: uint8(a / cnt >> 8)})
+			}
+		}
+		img = half
+	}
+	return img
+}
@@ -0,0 +1,166 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"math"
+
+	"github.com/gazed/vu/device"
+	"github.com/gazed/vu/math/lin"
+)
+
+// Touch is one finger on a touchscreen or trackpad. The ID stays the
+// same while the finger is down. Locations are window pixels with the
+// origin at the bottom left. Trackpad touches are mapped onto the window.
+type Touch struct {
+	ID   int     // Unique while the touch is active.
+	X, Y float64 // Window location.
+}
+
+// Gesture is recognized from the touch points each update. Drags
+// follow the center of the touches. Pinch and Rotate need two touches
+// and are measured between the two oldest touches. Values are the
+// change since the last update so they can be applied directly, ie:
+//     zoom *= in.Gesture.Pinch
+//     angle += in.Gesture.Rotate
+type Gesture struct {
+	Touches int     // Number of active touches.
+	X, Y    float64 // Center of the touches.
+	Dx, Dy  float64 // Drag motion of the center.
+	Pinch   float64 // Change in touch spread, 1 for no change.
+	Rotate  float64 // Counterclockwise twist in degrees.
+	Tap     bool    // True on the update a short touch is lifted.
+	TapX    float64 // Location of the last tap.
+	TapY    float64 //   "
+}
+
+// Tap gesture limits. A tap is a single touch that is lifted before it
+// has been held too long or moved too far.
+const (
+	TapTicks = 15 // Most updates a tap touch can be held.
+	TapMove  = 10 // Most pixels a tap touch can move.
+)
+
+// Touch
+// =============================================================================
+// gestures recognizes Gesture from Touch.
+
+// gestures tracks the touch points between updates.
+type gestures struct {
+	prev  []Touch             // Touches from the last update.
+	start map[int]*touchStart // Where each touch started.
+	order []int               // Touch ids, oldest first.
+	tap   bool                // False once extra touches make it not a tap.
+	was   []Touch             // Scratch previous matched touches.
+	now   []Touch             // Scratch current matched touches.
+}
+
+// touchStart is the initial state of one touch.
+type touchStart struct {
+	x, y  float64 // Touch down location.
+	ticks int     // Updates the touch has been down.
+	moved bool    // True if moved too far to be a tap.
+}
+
+// update converts the latest touch points into a gesture. Drag, pinch,
+// and rotate are only measured for touches that were present in
+// the previous update so that new or lifted touches don't cause jumps.
+func (g *gestures) update(touches []Touch, gest *Gesture) {
+	if g.start == nil {
+		g.start = map[int]*touchStart{}
+	}
+	*gest = Gesture{Pinch: 1, TapX: gest.TapX, TapY: gest.TapY}
+	gest.Touches = len(touches)
+
+	// track touch starts and check for lifted taps.
+	for _, t := range touches {
+		ts, ok := g.start[t.ID]
+		if !ok {
+			ts = &touchStart{x: t.X, y: t.Y}
+			g.start[t.ID] = ts
+			g.order = append(g.order, t.ID)
+			g.tap = len(g.order) == 1
+		}
+		ts.ticks++
+		if math.Hypot(t.X-ts.x, t.Y-ts.y) > TapMove {
+			ts.moved = true
+		}
+	}
+	for cnt := 0; cnt < len(g.order); cnt++ {
+		id := g.order[cnt]
+		if _, ok := findTouch(touches, id); ok {
+			continue
+		}
+		ts := g.start[id]
+		if g.tap && len(touches) == 0 && !ts.moved && ts.ticks <= TapTicks {
+			gest.Tap, gest.TapX, gest.TapY = true, ts.x, ts.y
+		}
+		delete(g.start, id)
+		g.order = append(g.order[:cnt], g.order[cnt+1:]...)
+		cnt--
+	}
+
+	// measure the motion of the touches that are still down.
+	gest.X, gest.Y = touchCenter(touches)
+	was, now := g.matched(touches)
+	if len(now) > 0 {
+		px, py := touchCenter(was)
+		nx, ny := touchCenter(now)
+		gest.Dx, gest.Dy = nx-px, ny-py
+	}
+	if len(now) > 1 {
+		a0, a1 := was[0], was[1]
+		b0, b1 := now[0], now[1]
+		if d0 := math.Hypot(a1.X-a0.X, a1.Y-a0.Y); d0 > 0 {
+			gest.Pinch = math.Hypot(b1.X-b0.X, b1.Y-b0.Y) / d0
+		}
+		turn := math.Atan2(b1.Y-b0.Y, b1.X-b0.X) - math.Atan2(a1.Y-a0.Y, a1.X-a0.X)
+		gest.Rotate = lin.Deg(math.Atan2(math.Sin(turn), math.Cos(turn)))
+	}
+	g.prev = append(g.prev[:0], touches...)
+}
+
+// matched returns the previous and current locations of the touches
+// that are in both updates. Both are ordered oldest touch first.
+func (g *gestures) matched(touches []Touch) (was, now []Touch) {
+	g.was, g.now = g.was[:0], g.now[:0]
+	for _, id := range g.order {
+		p, pok := findTouch(g.prev, id)
+		t, tok := findTouch(touches, id)
+		if pok && tok {
+			g.was, g.now = append(g.was, p), append(g.now, t)
+		}
+	}
+	return g.was, g.now
+}
+
+// findTouch returns the touch with the given id.
+func findTouch(touches []Touch, id int) (t Touch, ok bool) {
+	for _, t = range touches {
+		if t.ID == id {
+			return t, true
+		}
+	}
+	return t, false
+}
+
+// touchCenter returns the average location of the touches.
+func touchCenter(touches []Touch) (x, y float64) {
+	for _, t := range touches {
+		x, y = x+t.X, y+t.Y
+	}
+	if n := float64(len(touches)); n > 0 {
+		x, y = x/n, y/n
+	}
+	return x, y
+}
+
+// convertTouches copies the device touches reusing the touch slice.
+func convertTouches(touches []Touch, dts []device.Touch) []Touch {
+	touches = touches[:0]
+	for _, dt := range dts {
+		touches = append(touches, Touch{ID: dt.ID, X: dt.X, Y: dt.Y})
+	}
+	return touches
+}
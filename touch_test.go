@@ -0,0 +1,60 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"testing"
+
+	"github.com/gazed/vu/math/lin"
+)
+
+func TestTapGesture(t *testing.T) {
+	g, gest := &gestures{}, &Gesture{}
+	g.update([]Touch{{ID: 7, X: 10, Y: 20}}, gest)
+	g.update([]Touch{{ID: 7, X: 12, Y: 21}}, gest)
+	if gest.Touches != 1 || gest.Dx != 2 || gest.Dy != 1 || gest.Tap {
+		t.Errorf("expected a one finger drag %+v", gest)
+	}
+	g.update([]Touch{}, gest)
+	if !gest.Tap || gest.TapX != 10 || gest.TapY != 20 || gest.Dx != 0 {
+		t.Errorf("expected a tap %+v", gest)
+	}
+
+	// held too long or moved too far is not a tap.
+	for cnt := 0; cnt <= TapTicks; cnt++ {
+		g.update([]Touch{{ID: 8, X: 10, Y: 20}}, gest)
+	}
+	if g.update(nil, gest); gest.Tap {
+		t.Errorf("expected long touch to not be a tap")
+	}
+	g.update([]Touch{{ID: 9, X: 10, Y: 20}}, gest)
+	g.update([]Touch{{ID: 9, X: 30, Y: 20}}, gest)
+	if g.update(nil, gest); gest.Tap {
+		t.Errorf("expected drag to not be a tap")
+	}
+}
+
+func TestPinchRotateGesture(t *testing.T) {
+	g, gest := &gestures{}, &Gesture{}
+	g.update([]Touch{{ID: 1, X: 0, Y: 0}, {ID: 2, X: 10, Y: 0}}, gest)
+	if gest.Pinch != 1 || gest.Rotate != 0 || gest.X != 5 {
+		t.Errorf("expected no motion on first touch %+v", gest)
+	}
+
+	// spread apart and twist a quarter turn counterclockwise.
+	g.update([]Touch{{ID: 1, X: 0, Y: 0}, {ID: 2, X: 0, Y: 20}}, gest)
+	if !lin.Aeq(gest.Pinch, 2) || !lin.Aeq(gest.Rotate, 90) {
+		t.Errorf("expected pinch and rotate %f %f", gest.Pinch, gest.Rotate)
+	}
+
+	// a new touch doesn't cause a jump. The oldest two are measured.
+	g.update([]Touch{{ID: 1, X: 0, Y: 0}, {ID: 2, X: 0, Y: 20}, {ID: 3, X: 90, Y: 90}}, gest)
+	if gest.Touches != 3 || gest.Pinch != 1 || gest.Rotate != 0 || gest.Dx != 0 || gest.Dy != 0 {
+		t.Errorf("expected new touch to be ignored %+v", gest)
+	}
+	g.update([]Touch{{ID: 2, X: 0, Y: 20}, {ID: 3, X: 90, Y: 90}}, gest)
+	if gest.Tap || gest.Pinch != 1 {
+		t.Errorf("expected lifted touch to be ignored %+v", gest)
+	}
+}
@@ -0,0 +1,224 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"math"
+	"time"
+
+	"github.com/gazed/vu/math/lin"
+)
+
+// Tweens change Pov transforms and Model colors over time, replacing
+// per update interpolation code in App.Update, ie:
+//     eng.Tween(door, time.Second).Location(0, 3, 0).Ease(vu.EaseInOut)
+//     eng.Tween(ghost, 2*time.Second).Alpha(0).Done(func() {
+//         ghost.Dispose(vu.PovNode)
+//     })
+//     eng.Tween(ball, time.Second).Scale(2, 2, 2).
+//         Then(ball, time.Second).Scale(1, 1, 1) // grow then shrink.
+// Tweens are updated each update after App.Update, starting the update
+// after they are created. Each tween starts from the values at the time
+// it starts, so sequenced tweens continue from where the previous tween
+// finished. A tween is stopped if its Pov is disposed.
+
+// Tween changes Pov and Model values over a period of time.
+// The changed values are set using the chainable methods.
+type Tween interface {
+	Location(x, y, z float64) Tween // Move to the local location.
+	Rotation(q *lin.Q) Tween        // Turn to the local rotation.
+	Scale(x, y, z float64) Tween    // Change to the per axis scale.
+	Color(r, g, b float64) Tween    // Change the model color.
+	Alpha(a float64) Tween          // Change the model transparency.
+	Ease(e Easing) Tween            // Default Linear.
+	Done(fn func()) Tween           // Called once the tween finishes.
+	Stop()                          // Stop without calling done.
+
+	// Then returns a new tween that starts when this tween finishes.
+	Then(p Pov, d time.Duration) Tween
+}
+
+// Easing maps the fraction of the tween time, from 0 to 1,
+// to the fraction of the value change, from 0 to 1.
+type Easing func(t float64) float64
+
+// Easing curves.
+var (
+	Linear    Easing = func(t float64) float64 { return t }
+	EaseIn    Easing = func(t float64) float64 { return t * t }
+	EaseOut   Easing = func(t float64) float64 { return t * (2 - t) }
+	EaseInOut Easing = func(t float64) float64 { return t * t * (3 - 2*t) }
+)
+
+// Tween values.
+const (
+	tLoc = 1 << iota
+	tRot
+	tScale
+	tColor
+	tAlpha
+)
+
+// tween implements Tween.
+type tween struct {
+	eng     *engine
+	p       *pov    // Changed Pov.
+	dur     float64 // Tween time in seconds.
+	at      float64 // Elapsed time in seconds.
+	ease    Easing  // Change over time.
+	vals    int     // Changing values.
+	done    func()  // Optional finished callback.
+	next    *tween  // Optional tween started when finished.
+	started bool    // True once start values are recorded.
+	stopped bool    // True if stopped or finished.
+
+	// start and end values.
+	loc0, loc1     lin.V3
+	rot0, rot1     lin.Q
+	scale0, scale1 lin.V3
+	col0, col1     [3]float64
+	a0, a1         float64
+}
+
+// newTween creates a tween which is not yet active.
+func newTween(eng *engine, p Pov, d time.Duration) *tween {
+	pv, _ := p.(*pov)
+	return &tween{eng: eng, p: pv, dur: d.Seconds(), ease: Linear}
+}
+
+// Implement Eng interface.
+func (eng *engine) Tween(p Pov, d time.Duration) Tween {
+	t := newTween(eng, p, d)
+	eng.added = append(eng.added, t)
+	return t
+}
+
+// Implement Tween.
+func (t *tween) Location(x, y, z float64) Tween {
+	t.vals |= tLoc
+	t.loc1.SetS(x, y, z)
+	return t
+}
+func (t *tween) Rotation(q *lin.Q) Tween {
+	t.vals |= tRot
+	t.rot1.Set(q)
+	return t
+}
+func (t *tween) Scale(x, y, z float64) Tween {
+	t.vals |= tScale
+	t.scale1.SetS(x, y, z)
+	return t
+}
+func (t *tween) Color(r, g, b float64) Tween {
+	t.vals |= tColor
+	t.col1 = [3]float64{r, g, b}
+	return t
+}
+func (t *tween) Alpha(a float64) Tween {
+	t.vals |= tAlpha
+	t.a1 = a
+	return t
+}
+func (t *tween) Ease(e Easing) Tween {
+	if e != nil {
+		t.ease = e
+	}
+	return t
+}
+func (t *tween) Done(fn func()) Tween {
+	t.done = fn
+	return t
+}
+func (t *tween) Stop() {
+	t.stopped = true
+	t.next = nil
+}
+func (t *tween) Then(p Pov, d time.Duration) Tween {
+	t.next = newTween(t.eng, p, d)
+	return t.next
+}
+
+// start records the starting values.
+func (t *tween) start() {
+	p := t.p
+	t.started = true
+	t.loc0.Set(p.at.Loc)
+	t.rot0.Set(p.at.Rot)
+	if t.rot0.Dot(&t.rot1) < 0 {
+		t.rot1.Scale(-1) // turn the short way.
+	}
+	t.scale0.Set(p.scale)
+	if m := p.Model(); m != nil {
+		t.col0[0], t.col0[1], t.col0[2] = m.Color()
+		t.a0 = m.Alpha()
+	}
+}
+
+// update moves the tween forward by the given seconds,
+// returning true if the tween has finished.
+func (t *tween) update(dts float64) bool {
+	if t.stopped || t.p == nil || t.eng.povs[t.p.eid] != t.p {
+		return true
+	}
+	if !t.started {
+		t.start()
+	}
+	t.at += dts
+	f := 1.0
+	if t.at < t.dur {
+		f = t.ease(t.at / t.dur)
+	}
+	p := t.p
+	if t.vals&tLoc != 0 {
+		p.at.Loc.Lerp(&t.loc0, &t.loc1, f)
+	}
+	if t.vals&tRot != 0 {
+		p.at.Rot.Nlerp(&t.rot0, &t.rot1, f)
+	}
+	if t.vals&tScale != 0 {
+		p.scale.Lerp(&t.scale0, &t.scale1, f)
+	}
+	if m := p.Model(); m != nil {
+		if t.vals&tColor != 0 {
+			c0, c1 := t.col0, t.col1
+			m.SetColor(lerp(c0[0], c1[0], f), lerp(c0[1], c1[1], f), lerp(c0[2], c1[2], f))
+		}
+		if t.vals&tAlpha != 0 {
+			m.SetAlpha(math.Max(0, math.Min(1, lerp(t.a0, t.a1, f))))
+		}
+	}
+	if t.at < t.dur {
+		return false
+	}
+	t.stopped = true
+	if t.done != nil {
+		t.done()
+	}
+	return true
+}
+
+// lerp returns the value the given fraction from a to b.
+func lerp(a, b, fraction float64) float64 { return a + (b-a)*fraction }
+
+// updateTweens moves the active tweens forward by the given seconds.
+// New tweens, and the sequenced tweens of finished tweens, are first
+// updated next update.
+func (eng *engine) updateTweens(dts float64) {
+	active := eng.tweens[:0]
+	for _, t := range eng.tweens {
+		if !t.update(dts) {
+			active = append(active, t)
+		} else if t.next != nil {
+			eng.added = append(eng.added, t.next)
+		}
+	}
+	for cnt := len(active); cnt < len(eng.tweens); cnt++ {
+		eng.tweens[cnt] = nil // release for garbage collection.
+	}
+	eng.tweens = append(active, eng.added...)
+	for cnt := range eng.added {
+		eng.added[cnt] = nil
+	}
+	eng.added = eng.added[:0]
+}
@@ -0,0 +1,47 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gazed/vu/math/lin"
+)
+
+// TestTween checks interpolation, sequencing, and completion.
+func TestTween(t *testing.T) {
+	eng := newEngine(nil)
+	defer eng.Shutdown()
+	p := eng.Root().NewPov()
+	done := 0
+	eng.Tween(p, 100*time.Millisecond).Location(10, 0, 0).
+		Then(p, 100*time.Millisecond).Scale(3, 3, 3).Done(func() { done++ })
+	eng.updateTweens(0) // start.
+	eng.updateTweens(0.05)
+	if x, _, _ := p.Location(); !lin.Aeq(x, 5) {
+		t.Errorf("Expected halfway at 5 got %f", x)
+	}
+	eng.updateTweens(0.05)
+	eng.updateTweens(0.05)
+	if x, _, _ := p.Location(); x != 10 {
+		t.Errorf("Expected to finish at 10 got %f", x)
+	}
+	if sx, _, _ := p.Scale(); !lin.Aeq(sx, 2) || done != 0 {
+		t.Errorf("Expected halfway scale 2 got %f", sx)
+	}
+	eng.updateTweens(0.05)
+	if sx, _, _ := p.Scale(); sx != 3 || done != 1 || len(eng.tweens) != 0 {
+		t.Errorf("Expected finished scale 3 got %f", sx)
+	}
+
+	// tweens stop when their Pov is disposed.
+	eng.Tween(p, time.Second).Location(0, 0, 0).Ease(EaseInOut)
+	eng.updateTweens(0)
+	p.Dispose(PovNode)
+	eng.updateTweens(0.1)
+	if len(eng.tweens) != 0 {
+		t.Errorf("Expected disposed tween to stop")
+	}
+}
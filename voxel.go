@@ -0,0 +1,497 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"math"
+
+	"github.com/gazed/vu/render"
+)
+
+// Voxels renders volumetric terrain that, unlike a Surface height map,
+// can have overhangs, caves, and ground that is dug away or built up.
+// The volume is a grid of density samples where samples above 0 are
+// solid and samples at or below 0 are air. Samples are grouped into
+// size-by-size-by-size chunks and each changed chunk is meshed, using
+// marching cubes, into its own child Pov and Model on Update, ie:
+//     v := vu.NewVoxels(pov, "voxel", 16)
+//     v.Sphere(0, 0, 0, 10, 1) // solid ball of material 1.
+//     v.Update()               // mesh the changed chunks.
+// Chunk meshes have vertex data at layout location 0, normals at 1, and
+// the material of the closest solid voxel at 2. Unset voxels are air.
+// See eg/source/voxel.vsh for the shader inputs.
+type Voxels interface {
+	Set(x, y, z int, density float32, material int) // Change one voxel.
+	At(x, y, z int) (density float32, material int) // Voxel values.
+
+	// Sphere adds solid ground of the given material, or removes ground
+	// when material is negative, for all voxels within radius of x, y, z.
+	// The sphere edge is smoothed so that the mesh is rounded.
+	Sphere(x, y, z, radius float64, material int)
+
+	// SetModel registers a callback that configures each new chunk
+	// model, ie: adding textures or materials.
+	SetModel(setup func(m Model)) Voxels
+	Update()     // Mesh chunks that changed since the last Update.
+	Chunks() int // Number of chunks holding voxels.
+}
+
+// NewVoxels creates a voxel volume of size-by-size-by-size chunks
+// that are rendered as child Pov's of p using the given shader.
+func NewVoxels(p Pov, shader string, size int) Voxels {
+	return newVoxels(p, shader, size)
+}
+
+// Voxels
+// =============================================================================
+// voxels implements Voxels.
+
+// voxels uses a sparse map of chunks so that only the parts of
+// the volume that have been set use memory.
+type voxels struct {
+	pov    *pov                    // Volume location, orientation, scale.
+	shader string                  // Chunk model shader.
+	size   int                     // Voxels along each side of a chunk.
+	setup  func(m Model)           // Optional chunk model configuration.
+	chunks map[voxelID]*voxelChunk // Chunks holding voxels.
+
+	// scratch meshing data. Reused each time a chunk is meshed.
+	grid []float32 // Chunk densities with a one voxel border.
+	mats []uint8   // Chunk materials with a one voxel border.
+	eb   []int32   // Vertex index for each grid edge. -1 if unused.
+	vb   []float32 // Scratch vertex buffer.
+	nb   []float32 // Scratch normal buffer.
+	mb   []float32 // Scratch material buffer.
+	fb   []uint32  // Scratch face buffer.
+	f16  []uint16  // Scratch face buffer for smaller meshes.
+}
+
+// voxelID identifies a chunk by its location in chunks.
+type voxelID struct{ x, y, z int }
+
+// voxelChunk holds the density and material for each chunk voxel.
+// The chunk mesh covers the cubes between voxels from the chunk
+// origin up to, and including, the first voxels of the next chunks.
+type voxelChunk struct {
+	density  []float32 // Voxel density. Above 0 is solid.
+	material []uint8   // Voxel material.
+	dirty    bool      // True if the mesh needs updating.
+	pov      *pov      // Chunk mesh. Nil until the first mesh.
+}
+
+// air is the density of unset voxels.
+const air = -1
+
+// newVoxels allocates and initializes a voxel volume.
+func newVoxels(p Pov, shader string, size int) *voxels {
+	v := &voxels{shader: shader, size: size}
+	v.pov, _ = p.(*pov)
+	if v.size < 2 {
+		v.size = 16
+	}
+	v.chunks = map[voxelID]*voxelChunk{}
+	span := v.size + 3 // samples needed for meshing and normals.
+	v.grid = make([]float32, span*span*span)
+	v.mats = make([]uint8, span*span*span)
+	v.eb = make([]int32, span*span*span*3)
+	return v
+}
+
+// Implement Voxels.
+func (v *voxels) SetModel(setup func(m Model)) Voxels {
+	v.setup = setup
+	return v
+}
+func (v *voxels) Chunks() int { return len(v.chunks) }
+
+// Set implements Voxels. Any chunk whose mesh uses the voxel
+// is marked for updating.
+func (v *voxels) Set(x, y, z int, density float32, material int) {
+	id, at := v.locate(x, y, z)
+	c := v.chunk(id)
+	c.density[at], c.material[at] = density, uint8(material)
+	xs, ys, zs := []int{id.x}, []int{id.y}, []int{id.z}
+	if x-id.x*v.size == 0 {
+		xs = append(xs, id.x-1)
+	}
+	if y-id.y*v.size == 0 {
+		ys = append(ys, id.y-1)
+	}
+	if z-id.z*v.size == 0 {
+		zs = append(zs, id.z-1)
+	}
+	for _, cx := range xs {
+		for _, cy := range ys {
+			for _, cz := range zs {
+				v.chunk(voxelID{cx, cy, cz}).dirty = true
+			}
+		}
+	}
+}
+
+// At implements Voxels.
+func (v *voxels) At(x, y, z int) (density float32, material int) {
+	id, at := v.locate(x, y, z)
+	if c, ok := v.chunks[id]; ok {
+		return c.density[at], int(c.material[at])
+	}
+	return air, 0
+}
+
+// Sphere implements Voxels. The density is the distance inside the
+// sphere surface, limited to 1 voxel, and is combined with the existing
+// density so that overlapping spheres merge.
+func (v *voxels) Sphere(x, y, z, radius float64, material int) {
+	reach := int(math.Ceil(radius)) + 1
+	cx, cy, cz := int(math.Floor(x)), int(math.Floor(y)), int(math.Floor(z))
+	for vx := cx - reach; vx <= cx+reach; vx++ {
+		for vy := cy - reach; vy <= cy+reach; vy++ {
+			for vz := cz - reach; vz <= cz+reach; vz++ {
+				dx, dy, dz := float64(vx)-x, float64(vy)-y, float64(vz)-z
+				inside := float32(radius - math.Sqrt(dx*dx+dy*dy+dz*dz))
+				if inside < -1 {
+					continue // outside the sphere and its smoothing.
+				}
+				inside = float32(math.Min(1, float64(inside)))
+				density, mat := v.At(vx, vy, vz)
+				switch {
+				case material >= 0 && inside > density:
+					v.Set(vx, vy, vz, inside, material)
+				case material < 0 && -inside < density:
+					v.Set(vx, vy, vz, -inside, mat)
+				}
+			}
+		}
+	}
+}
+
+// Update implements Voxels.
+func (v *voxels) Update() {
+	if v.pov == nil {
+		return
+	}
+	for id, c := range v.chunks {
+		if c.dirty {
+			c.dirty = false
+			v.mesh(id, c)
+		}
+	}
+}
+
+// locate returns the chunk holding voxel x, y, z and the
+// voxel index within the chunk.
+func (v *voxels) locate(x, y, z int) (id voxelID, at int) {
+	id = voxelID{floorDiv(x, v.size), floorDiv(y, v.size), floorDiv(z, v.size)}
+	lx, ly, lz := x-id.x*v.size, y-id.y*v.size, z-id.z*v.size
+	return id, (lx*v.size+ly)*v.size + lz
+}
+
+// chunk returns the chunk for the given id, creating an empty
+// chunk of air if necessary.
+func (v *voxels) chunk(id voxelID) *voxelChunk {
+	c, ok := v.chunks[id]
+	if !ok {
+		cnt := v.size * v.size * v.size
+		c = &voxelChunk{density: make([]float32, cnt), material: make([]uint8, cnt), dirty: true}
+		for i := range c.density {
+			c.density[i] = air
+		}
+		v.chunks[id] = c
+	}
+	return c
+}
+
+// mesh regenerates the chunk mesh. Chunks without any surface
+// are hidden instead of being given an empty mesh.
+func (v *voxels) mesh(id voxelID, c *voxelChunk) {
+	v.march(id)
+	if len(v.fb) == 0 {
+		if c.pov != nil {
+			c.pov.SetVisible(false)
+		}
+		return
+	}
+	if c.pov == nil {
+		c.pov = v.pov.NewPov().(*pov)
+		c.pov.SetLocation(float64(id.x*v.size), float64(id.y*v.size), float64(id.z*v.size))
+		m := c.pov.NewModel(v.shader)
+		if v.setup != nil {
+			v.setup(m)
+		}
+		m.NewMesh("voxel")
+	}
+	c.pov.SetVisible(true)
+	m := c.pov.Model()
+	m.InitMesh(0, 3, render.DynamicDraw, false).SetMeshData(0, v.vb)
+	m.InitMesh(1, 3, render.DynamicDraw, false).SetMeshData(1, v.nb)
+	m.InitMesh(2, 1, render.DynamicDraw, false).SetMeshData(2, v.mb)
+	v.f16 = bindFaces(m, len(v.vb)/3, v.fb, v.f16)
+}
+
+// march generates the chunk triangles into the scratch buffers.
+// The chunk voxels, plus a one voxel border for the normals, are
+// copied into the scratch grid. Vertices are shared between the
+// cubes that have the same edge.
+func (v *voxels) march(id voxelID) {
+	v.vb, v.nb, v.mb, v.fb = v.vb[:0], v.nb[:0], v.mb[:0], v.fb[:0]
+	span := v.size + 3
+	ox, oy, oz := id.x*v.size-1, id.y*v.size-1, id.z*v.size-1
+	for x := 0; x < span; x++ {
+		for y := 0; y < span; y++ {
+			for z := 0; z < span; z++ {
+				at := (x*span+y)*span + z
+				d, mat := v.At(ox+x, oy+y, oz+z)
+				v.grid[at], v.mats[at] = d, uint8(mat)
+			}
+		}
+	}
+	for i := range v.eb {
+		v.eb[i] = -1
+	}
+	for x := 1; x <= v.size; x++ {
+		for y := 1; y <= v.size; y++ {
+			for z := 1; z <= v.size; z++ {
+				index := 0
+				for corner := uint(0); corner < 8; corner++ {
+					cx, cy, cz := x+int(corner&1), y+int(corner>>1&1), z+int(corner>>2&1)
+					if v.grid[(cx*span+cy)*span+cz] > 0 {
+						index |= 1 << corner
+					}
+				}
+				for _, e := range mcCases[index] {
+					v.fb = append(v.fb, v.vertex(x, y, z, e))
+				}
+			}
+		}
+	}
+}
+
+// vertex returns the index of the vertex on cube edge e of the cube
+// at grid x, y, z, creating the vertex if necessary. The vertex is
+// placed where the density crosses 0 and the normal points from the
+// solid corner towards the air.
+func (v *voxels) vertex(x, y, z, e int) uint32 {
+	span := v.size + 3
+	a, b := mcEdges[e][0], mcEdges[e][1]
+	ax, ay, az := x+a&1, y+a>>1&1, z+a>>2&1
+	bx, by, bz := x+b&1, y+b>>1&1, z+b>>2&1
+	axis := 0
+	switch {
+	case by != ay:
+		axis = 1
+	case bz != az:
+		axis = 2
+	}
+	key := ((ax*span+ay)*span+az)*3 + axis
+	if v.eb[key] >= 0 {
+		return uint32(v.eb[key])
+	}
+	ia, ib := (ax*span+ay)*span+az, (bx*span+by)*span+bz
+	da, db := v.grid[ia], v.grid[ib]
+	t := da / (da - db)
+	px := float32(ax-1) + float32(bx-ax)*t
+	py := float32(ay-1) + float32(by-ay)*t
+	pz := float32(az-1) + float32(bz-az)*t
+	nax, nay, naz := v.gradient(ax, ay, az)
+	nbx, nby, nbz := v.gradient(bx, by, bz)
+	nx, ny, nz := nax+(nbx-nax)*t, nay+(nby-nay)*t, naz+(nbz-naz)*t
+	if length := float32(math.Sqrt(float64(nx*nx + ny*ny + nz*nz))); length > 0 {
+		nx, ny, nz = nx/length, ny/length, nz/length
+	}
+	mat := v.mats[ia]
+	if da <= 0 {
+		mat = v.mats[ib] // use the solid corner material.
+	}
+	index := int32(len(v.vb) / 3)
+	v.eb[key] = index
+	v.vb = append(v.vb, px, py, pz)
+	v.nb = append(v.nb, nx, ny, nz)
+	v.mb = append(v.mb, float32(mat))
+	return uint32(index)
+}
+
+// gradient returns the negative density slope at grid x, y, z
+// which points away from the solid voxels. The grid border
+// samples use one sided differences.
+func (v *voxels) gradient(x, y, z int) (gx, gy, gz float32) {
+	span := v.size + 3
+	d := func(x, y, z int) float32 {
+		return v.grid[(iclamp(x, 0, span-1)*span+iclamp(y, 0, span-1))*span+iclamp(z, 0, span-1)]
+	}
+	return d(x-1, y, z) - d(x+1, y, z), d(x, y-1, z) - d(x, y+1, z), d(x, y, z-1) - d(x, y, z+1)
+}
+
+// floorDiv returns a/b rounded down so that negative
+// voxel locations map to negative chunks.
+func floorDiv(a, b int) int {
+	if a < 0 {
+		return -((-a - 1) / b) - 1
+	}
+	return a / b
+}
+
+// Marching cubes
+// =============================================================================
+// The marching cubes triangles for each of the 256 combinations of solid
+// cube corners are generated instead of being copied from the usual
+// tables. Cube corner i is at offset x=i&1, y=i>>1&1, z=i>>2&1.
+// Surface crossings on each cube face are joined into line segments and
+// the segments are chained into loops that are triangulated as fans.
+// Faces with two solid corners on opposite corners always separate the
+// solid corners so that neighbouring cubes, which share the face, join
+// the same crossings and the meshes have no cracks. Based on:
+//    http://paulbourke.net/geometry/polygonise/
+
+// mcEdges are the corner pairs for each of the 12 cube edges.
+var mcEdges [12][2]int
+
+// mcCases are the triangle edge indicies for each corner combination.
+var mcCases [256][]int
+
+// mcFaces are the corners of each cube face in cyclic order.
+var mcFaces = [6][4]int{
+	{0, 2, 6, 4}, {1, 3, 7, 5}, // x faces
+	{0, 1, 5, 4}, {2, 3, 7, 6}, // y faces
+	{0, 1, 3, 2}, {4, 5, 7, 6}, // z faces
+}
+
+// init generates the marching cubes tables.
+func init() {
+	e := 0
+	for a := 0; a < 8; a++ {
+		for bit := uint(0); bit < 3; bit++ {
+			if b := a | 1<<bit; b != a {
+				mcEdges[e] = [2]int{a, b}
+				e++
+			}
+		}
+	}
+	for index := range mcCases {
+		mcCases[index] = mcTriangulate(index)
+	}
+}
+
+// mcEdge returns the edge between corners a and b.
+func mcEdge(a, b int) int {
+	for e, ab := range mcEdges {
+		if (ab[0] == a && ab[1] == b) || (ab[0] == b && ab[1] == a) {
+			return e
+		}
+	}
+	return -1
+}
+
+// mcTriangulate creates the triangles for the cube with the given solid
+// corners. Each loop is wound counter-clockwise when viewed from the air.
+func mcTriangulate(index int) (tris []int) {
+	solid := func(corner int) bool { return index&(1<<uint(corner)) != 0 }
+	links := [12][]int{} // crossing edges joined to each crossing edge.
+	link := func(a, b int) {
+		links[a] = append(links[a], b)
+		links[b] = append(links[b], a)
+	}
+	for _, face := range mcFaces {
+		cross := []int{}
+		for k := 0; k < 4; k++ {
+			if a, b := face[k], face[(k+1)%4]; solid(a) != solid(b) {
+				cross = append(cross, mcEdge(a, b))
+			}
+		}
+		switch {
+		case len(cross) == 2:
+			link(cross[0], cross[1])
+		case len(cross) == 4 && solid(face[0]):
+			link(cross[3], cross[0]) // separate solid corner 0 ...
+			link(cross[1], cross[2]) // ... from solid corner 2.
+		case len(cross) == 4:
+			link(cross[0], cross[1]) // separate solid corner 1 ...
+			link(cross[2], cross[3]) // ... from solid corner 3.
+		}
+	}
+	used := [12]bool{}
+	for start := range links {
+		if used[start] || len(links[start]) == 0 {
+			continue
+		}
+		loop, prev, at := []int{}, -1, start
+		for !used[at] {
+			used[at] = true
+			loop = append(loop, at)
+			next := links[at][0]
+			if next == prev {
+				next = links[at][1]
+			}
+			prev, at = at, next
+		}
+
+		// compare the loop normal (Newell's method) with the
+		// direction from the solid corners to the air corners.
+		var nx, ny, nz, ox, oy, oz float64
+		for i, e := range loop {
+			ax, ay, az := mcMid(e)
+			bx, by, bz := mcMid(loop[(i+1)%len(loop)])
+			nx += (ay - by) * (az + bz)
+			ny += (az - bz) * (ax + bx)
+			nz += (ax - bx) * (ay + by)
+			a, b := mcEdges[e][0], mcEdges[e][1]
+			if solid(b) {
+				a, b = b, a
+			}
+			ox += float64(b&1 - a&1)
+			oy += float64(b>>1&1 - a>>1&1)
+			oz += float64(b>>2&1 - a>>2&1)
+		}
+		if nx*ox+ny*oy+nz*oz < 0 {
+			for i, j := 0, len(loop)-1; i < j; i, j = i+1, j-1 {
+				loop[i], loop[j] = loop[j], loop[i]
+			}
+		}
+
+		// fan from a corner whose diagonals don't lie on a cube face,
+		// otherwise neighbouring cubes can repeat the same diagonal.
+		apex := 0
+		for i := range loop {
+			apex = i
+			for j := 2; j < len(loop)-1 && apex >= 0; j++ {
+				if mcFace(loop[i], loop[(i+j)%len(loop)]) {
+					apex = -1
+				}
+			}
+			if apex >= 0 {
+				break
+			}
+		}
+		if apex < 0 {
+			apex = 0
+		}
+		for i := 1; i < len(loop)-1; i++ {
+			tris = append(tris, loop[apex], loop[(apex+i)%len(loop)], loop[(apex+i+1)%len(loop)])
+		}
+	}
+	return tris
+}
+
+// mcFace returns true if edges a and b are on the same cube face.
+func mcFace(a, b int) bool {
+	for _, face := range mcFaces {
+		on := 0
+		for _, corner := range face {
+			for _, end := range append(mcEdges[a][:], mcEdges[b][:]...) {
+				if corner == end {
+					on++
+				}
+			}
+		}
+		if on == 4 {
+			return true
+		}
+	}
+	return false
+}
+
+// mcMid returns the middle of cube edge e.
+func mcMid(e int) (x, y, z float64) {
+	a, b := mcEdges[e][0], mcEdges[e][1]
+	return float64(a&1+b&1) * 0.5, float64(a>>1&1+b>>1&1) * 0.5, float64(a>>2&1+b>>2&1) * 0.5
+}
@@ -0,0 +1,75 @@
+// Copyright © 2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// Check that random voxels are meshed into closed surfaces
+// where each triangle edge is shared with one other triangle.
+func TestVoxelsClosed(t *testing.T) {
+	eng := newEngine(nil)
+	defer eng.Shutdown()
+	v := newVoxels(eng.Root().NewPov(), "voxel", 16)
+	random := rand.New(rand.NewSource(123))
+	for x := 1; x < 15; x++ {
+		for y := 1; y < 15; y++ {
+			for z := 1; z < 15; z++ {
+				v.Set(x, y, z, random.Float32()*2-1, 1)
+			}
+		}
+	}
+	if v.Chunks() != 1 {
+		t.Fatalf("Expected 1 chunk, got %d", v.Chunks())
+	}
+	v.march(voxelID{0, 0, 0})
+	if len(v.fb) == 0 {
+		t.Fatalf("Expected triangles")
+	}
+	edges := map[[2]uint32]int{}
+	for i := 0; i < len(v.fb); i += 3 {
+		for k := 0; k < 3; k++ {
+			edges[[2]uint32{v.fb[i+k], v.fb[i+(k+1)%3]}]++
+		}
+	}
+	for e, cnt := range edges {
+		if cnt != 1 || edges[[2]uint32{e[1], e[0]}] != 1 {
+			t.Fatalf("Expected matching opposite edge for %v", e)
+		}
+	}
+}
+
+// Check that a sphere faces outwards and that digging
+// and voxels on chunk edges update the neighbour chunks.
+func TestVoxelsSphere(t *testing.T) {
+	eng := newEngine(nil)
+	defer eng.Shutdown()
+	v := newVoxels(eng.Root().NewPov(), "voxel", 16)
+	v.Sphere(8, 8, 8, 5, 2)
+	v.Update()
+	c := v.chunks[voxelID{0, 0, 0}]
+	if c.pov == nil || c.dirty || len(v.mb) == 0 || v.mb[0] != 2 {
+		t.Fatalf("Expected meshed chunk of material 2")
+	}
+	for i := 0; i < len(v.fb); i += 3 {
+		a, b, c := v.fb[i]*3, v.fb[i+1]*3, v.fb[i+2]*3
+		e1 := [3]float32{v.vb[b] - v.vb[a], v.vb[b+1] - v.vb[a+1], v.vb[b+2] - v.vb[a+2]}
+		e2 := [3]float32{v.vb[c] - v.vb[a], v.vb[c+1] - v.vb[a+1], v.vb[c+2] - v.vb[a+2]}
+		nx, ny, nz := e1[1]*e2[2]-e1[2]*e2[1], e1[2]*e2[0]-e1[0]*e2[2], e1[0]*e2[1]-e1[1]*e2[0]
+		rx, ry, rz := v.vb[a]-8, v.vb[a+1]-8, v.vb[a+2]-8
+		if nx*rx+ny*ry+nz*rz < 0 || v.nb[a]*rx+v.nb[a+1]*ry+v.nb[a+2]*rz <= 0 {
+			t.Fatalf("Expected outward facing triangle %d", i/3)
+		}
+	}
+	v.Sphere(8, 8, 8, 6, -1) // dig it all away.
+	if v.Update(); c.pov.Visible() {
+		t.Errorf("Expected empty chunk to be hidden")
+	}
+	v.Set(32, 0, 0, 1, 0)
+	if v.Chunks() != 9 || !v.chunks[voxelID{1, -1, -1}].dirty {
+		t.Errorf("Expected edge voxel to dirty 8 chunks, got %d", v.Chunks())
+	}
+}
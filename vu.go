@@ -30,6 +30,7 @@ package vu
 
 import (
 	"fmt"
+	"image"
 	"log"
 	"os"
 	"runtime/debug"
@@ -52,6 +53,7 @@ func New(app App, name string, wx, wy, ww, wh int) (err error) {
 		return fmt.Errorf("No application. Shutting down.")
 	}
 	m.counts = map[uint32]*meshCount{}
+	m.mix = newMixer()
 
 	// initialize the os specific shell, graphics context, and input tracker.
 	name, wx, wy, ww, wh = m.vet(name, wx, wy, ww, wh)
@@ -63,6 +65,7 @@ func New(app App, name string, wx, wy, ww, wh int) (err error) {
 		m.shutdown()
 		return // failed to initialize audio layer
 	}
+	m.devices = true
 
 	// initialize the graphics layer.
 	m.gc = render.New()
@@ -123,6 +126,7 @@ const (
 	PovNoise // Sound attached to a Pov.
 	PovLight // Light attached to a Pov.
 	PovLayer // Render pass layer attached to a Pov.
+	PovComps // Application components attached to a Pov.
 )
 
 // vu
@@ -137,6 +141,7 @@ type machine struct {
 	gc     render.Renderer    // Graphics card interface layer.
 	dev    device.Device      // Os specific window and rendering context.
 	ac     audio.Audio        // Audio card interface layer.
+	mix    *mixer             // Applies mixer groups to played sounds.
 	input  *device.Pressed    // Latest user keyboard and mouse input.
 	frame1 []render.Draw      // Previous render frame.
 	frame0 []render.Draw      // Most recent render frame.
@@ -147,6 +152,9 @@ type machine struct {
 	// Counts keeps track of the number of faces and verticies for
 	// each successfully bound mesh.
 	counts map[uint32]*meshCount
+
+	// devices is true when the sound output devices need reporting.
+	devices bool
 }
 
 // run is the main thread. Only the main thread can interact with the
@@ -170,6 +178,8 @@ func (m *machine) run() {
 			switch t := req.(type) {
 			case *renderFrame:
 				m.render(t)
+			case *pickFrame:
+				t.reply <- m.pick(t)
 			case *bindData:
 				m.bind(t)
 			case *setColor:
@@ -178,16 +188,50 @@ func (m *machine) run() {
 				m.gc.Enable(t.attr, t.enable)
 			case *toggleScreen:
 				m.dev.ToggleFullScreen()
-			case *setVolume:
-				m.ac.SetGain(t.gain)
+			case *mixSet:
+				m.mix.set(m.ac, t)
 			case *setCursor:
 				m.dev.SetCursorAt(t.cx, t.cy)
 			case *showCursor:
 				m.dev.ShowCursor(t.enable)
+			case *cursorImage:
+				m.dev.SetCursor(t.img, t.hotx, t.hoty)
+			case *captureMouse:
+				m.dev.CaptureMouse(t.enable)
+			case *clipboard:
+				if t.reply != nil {
+					t.reply <- m.dev.Copy()
+				} else {
+					m.dev.Paste(t.text)
+				}
+			case *keyName:
+				t.reply <- m.dev.KeyName(t.key)
 			case *placeListener:
-				m.ac.PlaceListener(t.x, t.y, t.z)
+				m.ac.PlaceListener(t.at.x, t.at.y, t.at.z)
+				m.ac.OrientListener(t.fx, t.fy, t.fz, t.ux, t.uy, t.uz)
+				m.ac.SetListenerVelocity(t.at.vx, t.at.vy, t.at.vz)
 			case *playSound:
-				m.ac.PlaySound(t.sid, t.x, t.y, t.z)
+				m.mix.play(m.ac, t)
+			case *playControl:
+				m.mix.playback(m.ac, t)
+			case *soundEffect:
+				m.mix.effect(m.ac, t)
+			case *queueSound:
+				m.mix.queue(m.ac, t)
+			case *placeSound:
+				at := t.at
+				for _, sid := range t.sids {
+					m.ac.PlaceSound(sid, at.x, at.y, at.z, at.vx, at.vy, at.vz)
+				}
+			case *setAttenuation:
+				m.ac.SetAttenuation(t.model)
+			case *setDoppler:
+				m.ac.SetDoppler(t.factor, t.speed)
+			case *setAudioDevice:
+				if err := m.ac.SetDevice(t.name); err != nil {
+					log.Printf("machine: %s", err)
+				}
+				m.devices = true
 			case *releaseData:
 				m.release(t)
 			case nil:
@@ -238,6 +282,20 @@ func (m *machine) render(r *renderFrame) {
 	m.dev.SwapBuffers()
 }
 
+// pick renders the pick frame and returns the pick color of the
+// rendered pixel. The window is redrawn by the next render.
+func (m *machine) pick(p *pickFrame) uint32 {
+	m.gc.ClearFrame(p.fbo)
+	for _, drawing := range p.frame {
+		if drawing.Vao() > 0 {
+			m.setCounts(drawing)
+			m.gc.Render(drawing)
+		}
+	}
+	r, g, b, _ := m.gc.ReadPixel(p.fbo, 0, 0)
+	return uint32(r)<<16 | uint32(g)<<8 | uint32(b)
+}
+
 // refreshAppData gathers user input and returns it on request.
 // The underlying device layer collects input since last call.
 // Expected to be called once per update tick.
@@ -248,6 +306,12 @@ func (m *machine) refreshAppData(data *appData) {
 		m.gc.Viewport(data.state.W, data.state.H)
 	}
 	data.state.FullScreen = m.dev.IsFullScreen()
+	data.ended = m.mix.ended(m.ac, data.ended[:0])
+	m.mix.queued(m.ac, data.queue)
+	if m.ac.CheckDevice() || m.devices {
+		data.state.AudioDevice, data.state.AudioDevices = m.ac.Device(), m.ac.Devices()
+		m.devices = false
+	}
 	data.reply <- data       // return refreshed app data.
 	m.input = m.dev.Update() // get latest user input for next refresh.
 }
@@ -295,7 +359,12 @@ func (m *machine) bind(bd *bindData) {
 			bd.reply <- nil
 		}
 	case *texture:
-		err := m.gc.BindTexture(&d.tid, d.img, d.repeat)
+		var err error
+		if d.cube {
+			err = m.gc.BindCube(&d.tid, d.faces)
+		} else {
+			err = m.gc.BindTexture(&d.tid, d.img, d.repeat)
+		}
 		if err != nil {
 			bd.reply <- fmt.Errorf("Failed texture bind %s: %s", d.name, err)
 		} else {
@@ -309,7 +378,10 @@ func (m *machine) bind(bd *bindData) {
 			bd.reply <- nil
 		}
 	case *layer:
-		err := m.gc.BindFrame(d.attr, &d.bid, &d.tex.tid, &d.db)
+		if d.bid != 0 { // resizing a bound layer.
+			m.gc.ReleaseFrame(d.bid, d.tex.tid, d.db)
+		}
+		err := m.gc.BindFrame(d.attr, d.size, &d.bid, &d.tex.tid, &d.db)
 		if err != nil {
 			bd.reply <- fmt.Errorf("Failed bind framebuffer %s", err)
 		} else {
@@ -338,6 +410,7 @@ func (m *machine) release(rd *releaseData) {
 		m.gc.ReleaseTexture(d.tid)
 	case *sound:
 		m.ac.ReleaseSound(d.sid)
+		m.mix.release(m.ac, d.sid)
 	case *layer:
 		m.gc.ReleaseFrame(d.bid, d.tex.tid, d.db)
 		d.bid, d.tex.tid, d.db = 0, 0, 0
@@ -386,16 +459,20 @@ type msg interface{}
 // appData contains both user input and engine state passed to the
 // application. A single copy, owned by the engine, is created on startup.
 type appData struct {
-	input *Input        // Refreshed each update.
-	state *State        // Refreshed each update.
-	reply chan *appData // For syncing updates between machine and operator.
+	input *Input         // Refreshed each update.
+	state *State         // Refreshed each update.
+	ended []uint64       // Playbacks that finished since the last update.
+	queue map[uint64]int // Buffers queued on each stream sound.
+	reply chan *appData  // For syncing updates between machine and operator.
 }
 
 // newAppData expects to be called on startup for
 // updating and communicating user input and global state.
 func newAppData() *appData {
-	as := &appData{reply: make(chan *appData)}
-	as.input = &Input{Down: map[int]int{}}
+	as := &appData{reply: make(chan *appData), queue: map[uint64]int{}}
+	as.input = &Input{Down: map[int]int{}, Scan: map[int]int{}}
+	as.input.SetDeadZone(StickDeadZone, TriggerDeadZone)
+	as.input.SetTiming(DoubleTapTicks, HoldTicks)
 	as.state = &State{CullBacks: true, Blend: true}
 	as.state.setColor(0, 0, 0, 1)
 	return as
@@ -421,15 +498,48 @@ type renderFrame struct {
 	ut     uint64        // Counter for debugging.
 }
 
-// placeListener locates the sounds listener in world space.
+// placeListener locates and orients the sounds listener in world space.
 type placeListener struct {
-	x, y, z float64
+	at         soundAt // Location and velocity.
+	fx, fy, fz float64 // Facing direction.
+	ux, uy, uz float64 // Up direction.
 }
 
 // playSound plays the given sound at the given world location.
 type playSound struct {
-	sid     uint64
-	x, y, z float64
+	sid               uint64
+	pid               uint64  // Playback id.
+	at                soundAt // Location and velocity.
+	min, max, rolloff float64 // Distance attenuation.
+	relative          bool    // Placed relative to the listener.
+	mix               int     // Mixer group.
+	fx                soundFx // Effect and low-pass filter.
+	ctl               playCtl // Playback controls.
+}
+
+// playControl changes or stops a played sound.
+type playControl struct {
+	sid  uint64
+	ctl  playCtl
+	stop bool
+}
+
+// soundEffect changes the effects of played sounds.
+type soundEffect struct {
+	sids []uint64
+	fx   soundFx
+}
+
+// queueSound sends generated samples to a stream sound.
+type queueSound struct {
+	sid  uint64
+	data []byte // Samples in the stream format.
+}
+
+// placeSound moves played sounds to follow their Pov.
+type placeSound struct {
+	sids []uint64
+	at   soundAt // Location and velocity.
 }
 
 // state change messages. Engine to machine. Fire and forget.
@@ -438,11 +548,33 @@ type enableAttr struct {
 	enable bool
 }
 type setColor struct{ r, g, b, a float32 }
-type setVolume struct{ gain float64 }
+type setAttenuation struct{ model int }
+type setDoppler struct{ factor, speed float64 }
+type setAudioDevice struct{ name string }
 type setCursor struct{ cx, cy int }
 type showCursor struct{ enable bool }
+type captureMouse struct{ enable bool }
+
+// cursorImage replaces the OS cursor image. Nil restores the default.
+type cursorImage struct {
+	img        *image.NRGBA
+	hotx, hoty int
+}
+
+// clipboard reads the system clipboard when there is a reply channel.
+// Otherwise the text is put on the system clipboard.
+type clipboard struct {
+	text  string
+	reply chan string
+}
 type toggleScreen struct{}
 
+// keyName requests the keyboard layout label of a key location.
+type keyName struct {
+	key   int
+	reply chan string
+}
+
 // releaseData is used to request the removal a resources associated
 // with one of the following:
 //    bound and cached: *mesh, *shader, *texture, *sound, *noise,